@@ -1,38 +1,71 @@
 package browser
 
 import (
+	"archive/zip"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
 	"fmt"
+	"io"
+	"io/fs"
 	"os"
 	"path/filepath"
+	"strings"
 
-	"github.com/moond4rk/hackbrowserdata/browser/chromium"
 	"github.com/moond4rk/hackbrowserdata/filemanager"
 	"github.com/moond4rk/hackbrowserdata/log"
 	"github.com/moond4rk/hackbrowserdata/types"
-	"github.com/moond4rk/hackbrowserdata/utils/fileutil"
 )
 
 // Archivable is implemented by installations that can enumerate their decryption-relevant files for
-// cross-host transport (Chromium only).
+// cross-host transport. Each engine lays its files out under its own root (see types.ArchiveSource).
 type Archivable interface {
 	BrowserKey() string
-	ArchiveSources(categories []types.Category) []chromium.ArchiveSource
+	ArchiveSources(categories []types.Category) []types.ArchiveSource
 }
 
+// ArchiveOptions tunes WriteArchive. A zero cap means no limit.
+type ArchiveOptions struct {
+	ToolVersion  string // recorded in the manifest
+	MaxFileSize  int64  // bytes; larger files are skipped with a warning
+	MaxTotalSize int64  // bytes of file content; files that would overflow it are skipped with a warning
+}
+
+// errOverCap marks a file skipped for a size cap; the archive carries on without it.
+var errOverCap = errors.New("over size cap")
+
 // WriteArchive packs each browser's decryption-relevant files into a zip whose internal layout is
-// <browser-key>/<User Data layout>, so a restore can re-expand it and decrypt with a keys.json. Files
-// are staged through a locked-file session first because Windows holds exclusive SQLite locks. Returns
-// the number of source entries staged (a directory source counts once).
-func WriteArchive(browsers []Browser, categories []types.Category, outPath string) (int, error) {
+// <browser-key>/<engine layout>, so a restore can re-expand it and decrypt with a keys.json. Each file
+// is streamed from its source into its zip entry in chunks and hashed on the way into the root
+// manifest.json (see ArchiveManifest); only files Windows holds under an exclusive lock are staged
+// first, through the locked-file session. Returns the number of sources archived (a directory source
+// counts once).
+func WriteArchive(browsers []Browser, categories []types.Category, outPath string, opts ArchiveOptions) (n int, err error) {
 	session, err := filemanager.NewSession()
 	if err != nil {
 		return 0, err
 	}
 	defer session.Cleanup()
 
-	staging := session.TempDir()
+	out, err := os.Create(outPath)
+	if err != nil {
+		return 0, fmt.Errorf("create %s: %w", outPath, err)
+	}
+	a := &archiveWriter{
+		zw:       zip.NewWriter(out),
+		session:  session,
+		opts:     opts,
+		manifest: newArchiveManifest(opts.ToolVersion),
+		buf:      make([]byte, 1<<20),
+	}
+	defer func() {
+		if err != nil {
+			_ = out.Close()
+			_ = os.Remove(outPath)
+		}
+	}()
+
 	seen := make(map[string]bool)
-	count := 0
 	for _, b := range browsers {
 		archivable, ok := b.(Archivable)
 		if !ok {
@@ -46,23 +79,191 @@ func WriteArchive(browsers []Browser, categories []types.Category, outPath strin
 			}
 			seen[entry] = true
 
-			dst := filepath.Join(staging, key, filepath.FromSlash(src.LayoutRel))
-			if err := os.MkdirAll(filepath.Dir(dst), 0o755); err != nil {
-				log.Warnf("archive: %s: %v", entry, err)
-				continue
+			added, err := a.addSource(src, entry)
+			if err != nil {
+				return 0, err
 			}
-			if err := session.Acquire(src.AbsPath, dst, src.IsDir); err != nil {
-				log.Warnf("archive: acquire %s: %v", entry, err)
-				continue
+			if added {
+				n++
 			}
-			count++
 		}
 	}
-	if count == 0 {
+	if n == 0 {
 		return 0, fmt.Errorf("no decryption-relevant files found to archive")
 	}
-	if err := fileutil.ZipDir(outPath, staging); err != nil {
-		return 0, fmt.Errorf("write archive %s: %w", outPath, err)
+	if err := a.writeManifest(); err != nil {
+		return 0, err
+	}
+	if err := a.zw.Close(); err != nil {
+		return 0, fmt.Errorf("close zip %s: %w", outPath, err)
+	}
+	if err := out.Close(); err != nil {
+		return 0, fmt.Errorf("close %s: %w", outPath, err)
+	}
+	return n, nil
+}
+
+// archiveWriter streams sources into one zip and collects their manifest entries.
+type archiveWriter struct {
+	zw       *zip.Writer
+	session  *filemanager.Session
+	opts     ArchiveOptions
+	manifest ArchiveManifest
+	written  int64
+	buf      []byte
+}
+
+// addSource archives one source: a file with the -wal / -shm sidecars beside it, or every file of a
+// directory except lock files. Files that cannot be read or are over a cap are skipped with a warning;
+// the error is reserved for a zip that can no longer be written.
+func (a *archiveWriter) addSource(src types.ArchiveSource, entry string) (bool, error) {
+	if src.IsDir {
+		added := false
+		err := filepath.WalkDir(src.AbsPath, func(p string, d fs.DirEntry, err error) error {
+			if err != nil {
+				log.Warnf("archive: %s: %v", entry, err)
+				return nil
+			}
+			if d.IsDir() || strings.HasSuffix(strings.ToLower(p), "lock") {
+				return nil
+			}
+			rel, err := filepath.Rel(src.AbsPath, p)
+			if err != nil {
+				return err
+			}
+			ok, err := a.addFile(entry+"/"+filepath.ToSlash(rel), p)
+			added = added || ok
+			return err
+		})
+		return added, err
+	}
+
+	ok, err := a.addFile(entry, src.AbsPath)
+	if !ok || err != nil {
+		return false, err
+	}
+	for _, suffix := range []string{"-wal", "-shm"} {
+		if _, statErr := os.Stat(src.AbsPath + suffix); statErr == nil {
+			if _, err := a.addFile(entry+suffix, src.AbsPath+suffix); err != nil {
+				return true, err
+			}
+		}
+	}
+	return true, nil
+}
+
+// addFile streams path into the zip entry name, hashing it as it goes.
+func (a *archiveWriter) addFile(name, path string) (bool, error) {
+	info, err := os.Stat(path)
+	if err == nil {
+		err = a.checkCaps(info.Size())
+	}
+	if err != nil {
+		log.Warnf("archive: skip %s: %v", name, err)
+		return false, nil
+	}
+	f, err := a.session.Open(path)
+	if err != nil {
+		log.Warnf("archive: acquire %s: %v", name, err)
+		return false, nil
+	}
+	defer f.Close()
+
+	w, err := a.zw.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Deflate, Modified: info.ModTime()})
+	if err != nil {
+		return false, fmt.Errorf("archive: %s: %w", name, err)
+	}
+	h := sha256.New()
+	src := &sourceReader{r: f, left: a.allowance()}
+	size, err := io.CopyBuffer(io.MultiWriter(w, h), src, a.buf)
+	a.written += size
+	if err != nil && src.err == nil {
+		return false, fmt.Errorf("archive: %s: %w", name, err)
+	}
+	if err == nil && src.left == 0 && src.more() {
+		err = fmt.Errorf("%w: grew past %d bytes while being read", errOverCap, size)
+	}
+	if err != nil {
+		// The entry is half-written and a zip entry cannot be taken back, so the manifest marks it skipped.
+		log.Warnf("archive: skip %s after %d bytes: %v", name, size, err)
+		a.manifest.Skipped = append(a.manifest.Skipped, ManifestSkip{Path: name, Source: path, Reason: err.Error()})
+		return false, nil
+	}
+	a.manifest.Files = append(a.manifest.Files, ManifestEntry{
+		Path:       name,
+		Source:     path,
+		Size:       size,
+		ModTime:    info.ModTime().UTC(),
+		SHA256:     hex.EncodeToString(h.Sum(nil)),
+		LockedCopy: f.Locked,
+	})
+	return true, nil
+}
+
+func (a *archiveWriter) checkCaps(size int64) error {
+	if a.opts.MaxFileSize > 0 && size > a.opts.MaxFileSize {
+		return fmt.Errorf("%w: %d bytes, per-file cap %d", errOverCap, size, a.opts.MaxFileSize)
+	}
+	if a.opts.MaxTotalSize > 0 && a.written+size > a.opts.MaxTotalSize {
+		return fmt.Errorf("%w: %d bytes, %d of the %d-byte archive cap left",
+			errOverCap, size, a.opts.MaxTotalSize-a.written, a.opts.MaxTotalSize)
+	}
+	return nil
+}
+
+// allowance returns how many bytes the next file may stream under the caps, or -1 without caps.
+func (a *archiveWriter) allowance() int64 {
+	left := int64(-1)
+	if a.opts.MaxFileSize > 0 {
+		left = a.opts.MaxFileSize
+	}
+	if a.opts.MaxTotalSize > 0 && (left < 0 || a.opts.MaxTotalSize-a.written < left) {
+		left = a.opts.MaxTotalSize - a.written
+	}
+	return left
+}
+
+// sourceReader reads at most left bytes of r (no limit when left is negative) and keeps r's own read
+// error apart from the zip's write errors, which the copy reports the same way.
+type sourceReader struct {
+	r    io.Reader
+	left int64
+	err  error
+}
+
+func (s *sourceReader) Read(p []byte) (int, error) {
+	if s.left == 0 {
+		return 0, io.EOF
+	}
+	if s.left > 0 && int64(len(p)) > s.left {
+		p = p[:s.left]
+	}
+	n, err := s.r.Read(p)
+	if s.left > 0 {
+		s.left -= int64(n)
+	}
+	if err != nil && !errors.Is(err, io.EOF) {
+		s.err = err
+	}
+	return n, err
+}
+
+// more reports whether r still has bytes after the limit was reached.
+func (s *sourceReader) more() bool {
+	var b [1]byte
+	n, _ := io.ReadFull(s.r, b[:])
+	return n > 0
+}
+
+func (a *archiveWriter) writeManifest() error {
+	data, err := a.manifest.encode()
+	if err != nil {
+		return err
+	}
+	w, err := a.zw.Create(ManifestName)
+	if err != nil {
+		return fmt.Errorf("archive: %s: %w", ManifestName, err)
 	}
-	return count, nil
+	_, err = w.Write(data)
+	return err
 }
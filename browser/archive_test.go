@@ -1,11 +1,17 @@
 package browser
 
 import (
+	"archive/zip"
 	"os"
 	"path/filepath"
+	"reflect"
+	"runtime"
 	"testing"
 
 	"github.com/moond4rk/hackbrowserdata/browser/chromium"
+	"github.com/moond4rk/hackbrowserdata/browser/firefox"
+	"github.com/moond4rk/hackbrowserdata/browser/safari"
+	"github.com/moond4rk/hackbrowserdata/filemanager"
 	"github.com/moond4rk/hackbrowserdata/types"
 	"github.com/moond4rk/hackbrowserdata/utils/fileutil"
 )
@@ -34,7 +40,7 @@ func TestWriteArchive_RoundTrip(t *testing.T) {
 	}
 
 	zipPath := filepath.Join(t.TempDir(), "data.zip")
-	n, err := WriteArchive([]Browser{b}, []types.Category{types.History}, zipPath)
+	n, err := WriteArchive([]Browser{b}, []types.Category{types.History}, zipPath, ArchiveOptions{})
 	if err != nil {
 		t.Fatalf("WriteArchive: %v", err)
 	}
@@ -56,3 +62,172 @@ func TestWriteArchive_RoundTrip(t *testing.T) {
 		}
 	}
 }
+
+// TestWriteArchive_FirefoxSafariRestore archives a Firefox and a Safari installation and rebuilds both
+// engines from the extracted zip, each from its own layout under <key>/.
+func TestWriteArchive_FirefoxSafariRestore(t *testing.T) {
+	origin := t.TempDir()
+	profiles := filepath.Join(origin, "firefox-profiles")
+	makeFirefoxProfile(t, profiles, "a.default-release")
+	library := filepath.Join(origin, "Library")
+	mkFile(t, library, "Safari", "History.db")
+	mkFile(t, library, "Cookies", "Cookies.binarycookies")
+
+	ff, err := firefox.NewBrowser(types.BrowserConfig{Key: "firefox", Name: "Firefox", Kind: types.Firefox, UserDataDir: profiles})
+	if err != nil || ff == nil {
+		t.Fatalf("firefox.NewBrowser: b=%v err=%v", ff, err)
+	}
+	sf, err := safari.NewBrowser(types.BrowserConfig{
+		Key: "safari", Name: "Safari", Kind: types.Safari, UserDataDir: filepath.Join(library, "Safari"),
+	})
+	if err != nil || sf == nil {
+		t.Fatalf("safari.NewBrowser: b=%v err=%v", sf, err)
+	}
+
+	zipPath := filepath.Join(t.TempDir(), "data.zip")
+	if _, err := WriteArchive([]Browser{ff, sf}, []types.Category{types.Cookie, types.History}, zipPath, ArchiveOptions{}); err != nil {
+		t.Fatalf("WriteArchive: %v", err)
+	}
+	extracted := t.TempDir()
+	if err := fileutil.Unzip(zipPath, extracted); err != nil {
+		t.Fatalf("Unzip: %v", err)
+	}
+	for _, rel := range []string{
+		"firefox/a.default-release/key4.db",
+		"firefox/a.default-release/cookies.sqlite",
+		"safari/Safari/History.db",
+		"safari/Cookies/Cookies.binarycookies",
+	} {
+		if _, err := os.Stat(filepath.Join(extracted, filepath.FromSlash(rel))); err != nil {
+			t.Errorf("expected %s in archive layout: %v", rel, err)
+		}
+	}
+
+	dump := BuildDump([]Browser{ff, sf})
+	browsers, err := BuildFromDump(dump, extracted, "")
+	if err != nil {
+		t.Fatalf("BuildFromDump: %v", err)
+	}
+	want := map[string]string{
+		"firefox": filepath.Join(extracted, "firefox"),
+		"safari":  filepath.Join(extracted, "safari", "Safari"),
+	}
+	if len(browsers) != len(want) {
+		t.Fatalf("got %d browsers, want %d", len(browsers), len(want))
+	}
+	for _, b := range browsers {
+		if got := b.UserDataDir(); got != want[b.BrowserName()] {
+			t.Errorf("%s UserDataDir = %q, want %q", b.BrowserName(), got, want[b.BrowserName()])
+		}
+	}
+}
+
+// TestWriteArchive_Caps checks that files over the per-file or whole-archive cap are left out while
+// the rest is still archived and listed in the manifest.
+func TestWriteArchive_Caps(t *testing.T) {
+	origin := t.TempDir()
+	def := filepath.Join(origin, "Default")
+	if err := os.MkdirAll(def, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	for name, size := range map[string]int{"Preferences": 2, "History": 100, "Login Data": 40} {
+		if err := os.WriteFile(filepath.Join(def, name), make([]byte, size), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	b, err := chromium.NewBrowser(types.BrowserConfig{Key: "chrome", Name: "chrome", Kind: types.Chromium, UserDataDir: origin})
+	if err != nil || b == nil {
+		t.Fatalf("NewBrowser: b=%v err=%v", b, err)
+	}
+	categories := []types.Category{types.History, types.Password}
+
+	archived := func(opts ArchiveOptions) map[string]bool {
+		t.Helper()
+		zipPath := filepath.Join(t.TempDir(), "data.zip")
+		if _, err := WriteArchive([]Browser{b}, categories, zipPath, opts); err != nil {
+			t.Fatalf("WriteArchive: %v", err)
+		}
+		dir := t.TempDir()
+		if err := fileutil.Unzip(zipPath, dir); err != nil {
+			t.Fatalf("Unzip: %v", err)
+		}
+		m, problems, err := VerifyArchiveManifest(dir)
+		if err != nil || len(problems) > 0 {
+			t.Fatalf("VerifyArchiveManifest: %v %v", problems, err)
+		}
+		got := make(map[string]bool)
+		for _, e := range m.Files {
+			got[e.Path] = true
+		}
+		return got
+	}
+
+	got := archived(ArchiveOptions{MaxFileSize: 50})
+	if got["chrome/Default/History"] || !got["chrome/Default/Login Data"] || !got["chrome/Default/Preferences"] {
+		t.Errorf("per-file cap 50: archived %v", got)
+	}
+	got = archived(ArchiveOptions{MaxTotalSize: 60})
+	if len(got) != 2 || got["chrome/Default/History"] {
+		t.Errorf("total cap 60: archived %v, want Preferences and Login Data only", got)
+	}
+}
+
+// TestArchiveWriter_Skip checks that a source failing mid-stream, or growing past a cap after its size
+// was checked, is listed as skipped in the manifest instead of aborting the archive.
+func TestArchiveWriter_Skip(t *testing.T) {
+	session, err := filemanager.NewSession()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer session.Cleanup()
+
+	out := filepath.Join(t.TempDir(), "data.zip")
+	f, err := os.Create(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	a := &archiveWriter{
+		zw:       zip.NewWriter(f),
+		session:  session,
+		manifest: newArchiveManifest("test"),
+		buf:      make([]byte, 4),
+	}
+
+	// A directory stats and opens, then fails on the first read.
+	if ok, err := a.addFile("dir", t.TempDir()); ok || err != nil {
+		t.Errorf("addFile(dir) = %v, %v; want skipped without error", ok, err)
+	}
+	a.opts.MaxFileSize = 10
+	src := filepath.Join(t.TempDir(), "small")
+	if err := os.WriteFile(src, []byte("0123456789"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if ok, err := a.addFile("small", src); !ok || err != nil {
+		t.Errorf("addFile(small) = %v, %v; want archived", ok, err)
+	}
+	if runtime.GOOS == "linux" {
+		// procfs files stat as empty but read back far more than the 10-byte cap.
+		if ok, err := a.addFile("status", "/proc/self/status"); ok || err != nil {
+			t.Errorf("addFile(status) = %v, %v; want skipped without error", ok, err)
+		}
+	}
+
+	if len(a.manifest.Files) != 1 || a.manifest.Files[0].Path != "small" {
+		t.Errorf("files = %+v, want small only", a.manifest.Files)
+	}
+	skipped := make([]string, 0, len(a.manifest.Skipped))
+	for _, s := range a.manifest.Skipped {
+		skipped = append(skipped, s.Path)
+	}
+	want := []string{"dir"}
+	if runtime.GOOS == "linux" {
+		want = append(want, "status")
+	}
+	if !reflect.DeepEqual(skipped, want) {
+		t.Errorf("skipped = %v, want %v", skipped, want)
+	}
+	if a.written > 2*a.opts.MaxFileSize {
+		t.Errorf("written = %d, past the per-file cap", a.written)
+	}
+}
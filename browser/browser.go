@@ -24,9 +24,16 @@ type Browser interface {
 }
 
 type DiscoverOptions struct {
-	Name             string // "all"|"chrome"|"firefox"|...
-	ProfilePath      string // custom profile dir override
-	KeychainPassword string // macOS only — see browser_darwin.go
+	Name             string       // "all"|"chrome"|"firefox"|...
+	ProfilePath      string       // custom profile dir override
+	KeychainPassword string       // macOS login password; off macOS only for a copied Safari keychain
+	PortalSecretFile string       // Linux only — raw Flatpak portal secret for v12, see browser_linux.go
+	KeyringPassword  string       // Linux only — unlocks a locked Secret Service collection for v11
+	PrimaryPassword  string       // Firefox primary password (e.g. recovered by the crack command)
+	OperatorKeys     OperatorKeys // keys from --key / --key-command, tried before the platform's
+	RecoverDeleted   bool         // also carve deleted logins, cookies and history from SQLite free space
+	CacheBodyDir     string       // save decoded HTTP cache bodies here; "" = don't
+	FaviconDir       string       // save favicon images here; "" = don't
 }
 
 // browserInjector injects decryption credentials into a Browser; built per-platform by newCredentialInjector.
@@ -42,7 +49,20 @@ func DiscoverBrowsersWithKeys(opts DiscoverOptions) ([]Browser, error) {
 	inject := newCredentialInjector(opts)
 	for _, b := range browsers {
 		inject(b)
+		if ppr, ok := b.(PrimaryPasswordReceiver); ok && opts.PrimaryPassword != "" {
+			ppr.SetPrimaryPassword(opts.PrimaryPassword)
+		}
+		if dr, ok := b.(DeletedRowRecoverer); ok && opts.RecoverDeleted {
+			dr.SetRecoverDeleted(true)
+		}
+		if cb, ok := b.(CacheBodyCarver); ok && opts.CacheBodyDir != "" {
+			cb.SetCacheBodyDir(opts.CacheBodyDir)
+		}
+		if saver, ok := b.(FaviconSaver); ok && opts.FaviconDir != "" {
+			saver.SetFaviconDir(opts.FaviconDir)
+		}
 	}
+	opts.OperatorKeys.Apply(browsers)
 	return browsers, nil
 }
 
@@ -68,9 +88,13 @@ func discoverFromConfigs(configs []types.BrowserConfig, opts DiscoverOptions) ([
 		}
 
 		if opts.ProfilePath != "" && name != "all" {
-			if cfg.Kind == types.Firefox {
+			switch cfg.Kind {
+			case types.Firefox:
 				cfg.UserDataDir = filepath.Dir(filepath.Clean(opts.ProfilePath))
-			} else {
+			case types.Safari:
+				// Either the Safari dir itself or the copied ~/Library holding it.
+				cfg.UserDataDir = safari.RestoredUserDataDir(opts.ProfilePath)
+			default:
 				cfg.UserDataDir = opts.ProfilePath
 			}
 		}
@@ -94,16 +118,55 @@ func discoverFromConfigs(configs []types.BrowserConfig, opts DiscoverOptions) ([
 // BrowserKey/Kind expose the identity a portable dump needs to rebuild the engine off the platform table.
 type KeyManager interface {
 	SetRetrievers(masterkey.Retrievers)
+	Retrievers() masterkey.Retrievers
 	ExportKeys() (masterkey.MasterKeys, error)
 	BrowserKey() string
 	Kind() types.BrowserKind
 }
 
+// ProfileKeyManager is implemented by installations whose master keys are per profile (Firefox only):
+// keys are exported and supplied by profile name.
+type ProfileKeyManager interface {
+	ExportProfileKeys() (map[string][]byte, error)
+	SetProfileKeys(map[string][]byte)
+	BrowserKey() string
+	Kind() types.BrowserKind
+}
+
 // KeychainPasswordReceiver is implemented by installations that need the macOS login password (Safari only).
 type KeychainPasswordReceiver interface {
 	SetKeychainPassword(string)
 }
 
+// KeychainFileReceiver is implemented by installations that can read passwords from a copied
+// login.keychain-db instead of the live macOS Keychain (Safari only).
+type KeychainFileReceiver interface {
+	SetKeychainFile(string)
+}
+
+// PrimaryPasswordReceiver is implemented by installations whose profiles can be locked by a primary password (Firefox only).
+type PrimaryPasswordReceiver interface {
+	SetPrimaryPassword(string)
+}
+
+// DeletedRowRecoverer is implemented by installations that can carve deleted rows out of their SQLite
+// databases' free space (Chromium and Firefox).
+type DeletedRowRecoverer interface {
+	SetRecoverDeleted(bool)
+}
+
+// CacheBodyCarver is implemented by installations that can save the bodies their HTTP disk cache
+// holds (Chromium and Firefox).
+type CacheBodyCarver interface {
+	SetCacheBodyDir(string)
+}
+
+// FaviconSaver is implemented by installations that can save the icon images their favicon database
+// holds (Chromium and Firefox).
+type FaviconSaver interface {
+	SetFaviconDir(string)
+}
+
 // resolveGlobs expands UserDataDir glob patterns for Windows MSIX/UWP browsers whose package dirs carry a dynamic
 // publisher-hash suffix (e.g. "TheBrowserCompany.Arc_*"). A glob matching N dirs yields N configs.
 func resolveGlobs(configs []types.BrowserConfig) []types.BrowserConfig {
@@ -20,90 +20,91 @@ func platformBrowsers() []types.BrowserConfig {
 			Key:           "chrome",
 			Name:          chromeName,
 			Kind:          types.Chromium,
-			KeychainLabel: "Chrome",
-			UserDataDir:   homeDir + "/Library/Application Support/Google/Chrome",
+			KeychainLabel: macKeychainLabels["chrome"],
+			UserDataDir:   homeDir + "/" + macInstallDirs["chrome"],
 		},
 		{
 			Key:           "edge",
 			Name:          edgeName,
 			Kind:          types.Chromium,
-			KeychainLabel: "Microsoft Edge",
-			UserDataDir:   homeDir + "/Library/Application Support/Microsoft Edge",
+			KeychainLabel: macKeychainLabels["edge"],
+			UserDataDir:   homeDir + "/" + macInstallDirs["edge"],
 		},
 		{
 			Key:           "chromium",
 			Name:          chromiumName,
 			Kind:          types.Chromium,
-			KeychainLabel: "Chromium",
-			UserDataDir:   homeDir + "/Library/Application Support/Chromium",
+			KeychainLabel: macKeychainLabels["chromium"],
+			UserDataDir:   homeDir + "/" + macInstallDirs["chromium"],
 		},
 		{
 			Key:           "chrome-beta",
 			Name:          chromeBetaName,
 			Kind:          types.Chromium,
-			KeychainLabel: "Chrome",
-			UserDataDir:   homeDir + "/Library/Application Support/Google/Chrome Beta",
+			KeychainLabel: macKeychainLabels["chrome-beta"],
+			UserDataDir:   homeDir + "/" + macInstallDirs["chrome-beta"],
 		},
 		{
 			Key:           "opera",
 			Name:          operaName,
 			Kind:          types.ChromiumOpera,
-			KeychainLabel: "Opera",
-			UserDataDir:   homeDir + "/Library/Application Support/com.operasoftware.Opera",
+			KeychainLabel: macKeychainLabels["opera"],
+			UserDataDir:   homeDir + "/" + macInstallDirs["opera"],
 		},
 		{
 			Key:           "opera-gx",
 			Name:          operaGXName,
 			Kind:          types.ChromiumOpera,
-			KeychainLabel: "Opera",
-			UserDataDir:   homeDir + "/Library/Application Support/com.operasoftware.OperaGX",
+			KeychainLabel: macKeychainLabels["opera-gx"],
+			UserDataDir:   homeDir + "/" + macInstallDirs["opera-gx"],
 		},
 		{
 			Key:           "vivaldi",
 			Name:          vivaldiName,
 			Kind:          types.Chromium,
-			KeychainLabel: "Vivaldi",
-			UserDataDir:   homeDir + "/Library/Application Support/Vivaldi",
+			KeychainLabel: macKeychainLabels["vivaldi"],
+			UserDataDir:   homeDir + "/" + macInstallDirs["vivaldi"],
 		},
 		{
 			Key:           "coccoc",
 			Name:          coccocName,
 			Kind:          types.Chromium,
-			KeychainLabel: "CocCoc",
-			UserDataDir:   homeDir + "/Library/Application Support/Coccoc",
+			KeychainLabel: macKeychainLabels["coccoc"],
+			UserDataDir:   homeDir + "/" + macInstallDirs["coccoc"],
 		},
 		{
 			Key:           "brave",
 			Name:          braveName,
 			Kind:          types.Chromium,
-			KeychainLabel: "Brave",
-			UserDataDir:   homeDir + "/Library/Application Support/BraveSoftware/Brave-Browser",
+			KeychainLabel: macKeychainLabels["brave"],
+			UserDataDir:   homeDir + "/" + macInstallDirs["brave"],
 		},
 		{
 			Key:           "yandex",
 			Name:          yandexName,
 			Kind:          types.ChromiumYandex,
-			KeychainLabel: "Yandex",
-			UserDataDir:   homeDir + "/Library/Application Support/Yandex/YandexBrowser",
+			KeychainLabel: macKeychainLabels["yandex"],
+			UserDataDir:   homeDir + "/" + macInstallDirs["yandex"],
 		},
 		{
 			Key:           "arc",
 			Name:          arcName,
 			Kind:          types.Chromium,
-			KeychainLabel: "Arc",
-			UserDataDir:   homeDir + "/Library/Application Support/Arc/User Data",
+			KeychainLabel: macKeychainLabels["arc"],
+			UserDataDir:   homeDir + "/" + macInstallDirs["arc"],
 		},
 		{
 			Key:         "firefox",
 			Name:        firefoxName,
 			Kind:        types.Firefox,
-			UserDataDir: homeDir + "/Library/Application Support/Firefox/Profiles",
+			UserDataDir: homeDir + "/" + macInstallDirs["firefox"],
+			CacheDir:    homeDir + "/Library/Caches/Firefox/Profiles",
 		},
 		{
 			Key:         "safari",
 			Name:        safariName,
 			Kind:        types.Safari,
-			UserDataDir: homeDir + "/Library/Safari",
+			UserDataDir: homeDir + "/" + macInstallDirs["safari"],
 		},
 	}
 }
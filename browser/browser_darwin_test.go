@@ -0,0 +1,22 @@
+//go:build darwin
+
+package browser
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/moond4rk/hackbrowserdata/types"
+)
+
+// TestKeychainLabelsCoverPlatformTable checks every darwin Chromium browser finds its keychain label,
+// which a key missing from macKeychainLabels would leave empty.
+func TestKeychainLabelsCoverPlatformTable(t *testing.T) {
+	for _, cfg := range platformBrowsers() {
+		if cfg.Kind == types.Firefox || cfg.Kind == types.Safari {
+			continue
+		}
+		assert.NotEmpty(t, cfg.KeychainLabel, cfg.Key)
+	}
+}
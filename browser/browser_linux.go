@@ -13,67 +13,111 @@ func platformBrowsers() []types.BrowserConfig {
 			Key:           "chrome",
 			Name:          chromeName,
 			Kind:          types.Chromium,
-			KeychainLabel: "Chrome Safe Storage",
-			UserDataDir:   homeDir + "/.config/google-chrome",
+			KeychainLabel: linuxKeyringLabels["chrome"],
+			KWalletFolder: "Chrome Keys",
+			UserDataDir:   homeDir + "/" + linuxInstallDirs["chrome"],
 		},
 		{
 			Key:           "edge",
 			Name:          edgeName,
 			Kind:          types.Chromium,
-			KeychainLabel: "Chromium Safe Storage",
-			UserDataDir:   homeDir + "/.config/microsoft-edge",
+			KeychainLabel: linuxKeyringLabels["edge"],
+			KWalletFolder: "Chromium Keys",
+			UserDataDir:   homeDir + "/" + linuxInstallDirs["edge"],
 		},
 		{
 			Key:           "chromium",
 			Name:          chromiumName,
 			Kind:          types.Chromium,
-			KeychainLabel: "Chromium Safe Storage",
-			UserDataDir:   homeDir + "/.config/chromium",
+			KeychainLabel: linuxKeyringLabels["chromium"],
+			KWalletFolder: "Chromium Keys",
+			UserDataDir:   homeDir + "/" + linuxInstallDirs["chromium"],
 		},
 		{
 			Key:           "chrome-beta",
 			Name:          chromeBetaName,
 			Kind:          types.Chromium,
-			KeychainLabel: "Chrome Safe Storage",
-			UserDataDir:   homeDir + "/.config/google-chrome-beta",
+			KeychainLabel: linuxKeyringLabels["chrome-beta"],
+			KWalletFolder: "Chrome Keys",
+			UserDataDir:   homeDir + "/" + linuxInstallDirs["chrome-beta"],
 		},
 		{
 			Key:           "opera",
 			Name:          operaName,
 			Kind:          types.ChromiumOpera,
-			KeychainLabel: "Chromium Safe Storage",
-			UserDataDir:   homeDir + "/.config/opera",
+			KeychainLabel: linuxKeyringLabels["opera"],
+			KWalletFolder: "Chromium Keys",
+			UserDataDir:   homeDir + "/" + linuxInstallDirs["opera"],
 		},
 		{
 			Key:           "vivaldi",
 			Name:          vivaldiName,
 			Kind:          types.Chromium,
-			KeychainLabel: "Chrome Safe Storage",
-			UserDataDir:   homeDir + "/.config/vivaldi",
+			KeychainLabel: linuxKeyringLabels["vivaldi"],
+			KWalletFolder: "Chrome Keys",
+			UserDataDir:   homeDir + "/" + linuxInstallDirs["vivaldi"],
 		},
 		{
 			Key:           "brave",
 			Name:          braveName,
 			Kind:          types.Chromium,
-			KeychainLabel: "Brave Safe Storage",
-			UserDataDir:   homeDir + "/.config/BraveSoftware/Brave-Browser",
+			KeychainLabel: linuxKeyringLabels["brave"],
+			KWalletFolder: "Brave Keys",
+			UserDataDir:   homeDir + "/" + linuxInstallDirs["brave"],
+		},
+		{
+			Key:           "chrome-flatpak",
+			Name:          chromeFlatpakName,
+			Kind:          types.Chromium,
+			KeychainLabel: linuxKeyringLabels["chrome-flatpak"],
+			KWalletFolder: "Chrome Keys",
+			FlatpakAppID:  "com.google.Chrome",
+			UserDataDir:   homeDir + "/" + linuxInstallDirs["chrome-flatpak"],
+		},
+		{
+			Key:           "chromium-flatpak",
+			Name:          chromiumFlatpakName,
+			Kind:          types.Chromium,
+			KeychainLabel: linuxKeyringLabels["chromium-flatpak"],
+			KWalletFolder: "Chromium Keys",
+			FlatpakAppID:  "org.chromium.Chromium",
+			UserDataDir:   homeDir + "/" + linuxInstallDirs["chromium-flatpak"],
 		},
 		{
 			Key:         "firefox",
 			Name:        firefoxName,
 			Kind:        types.Firefox,
-			UserDataDir: homeDir + "/.mozilla/firefox",
+			UserDataDir: homeDir + "/" + linuxInstallDirs["firefox"],
+			CacheDir:    homeDir + "/.cache/mozilla/firefox",
+		},
+		{
+			Key:         "safari",
+			Name:        safariName,
+			Kind:        types.Safari,
+			UserDataDir: homeDir + "/" + linuxInstallDirs["safari"],
 		},
 	}
 }
 
-// newCredentialInjector wires the Linux Chromium retrievers: V10 ("peanuts" hardcoded) and V11 (D-Bus Secret Service),
-// run independently for mixed-cipher profiles. V20 is nil — App-Bound Encryption is Windows-only.
-func newCredentialInjector(_ DiscoverOptions) browserInjector {
+// newCredentialInjector wires the Linux Chromium retrievers: V10 ("peanuts" hardcoded), V11 (D-Bus Secret Service) and
+// V12 (Flatpak secret portal), run independently for mixed-cipher profiles. A keyring password unlocks a locked
+// collection without a prompt, and an operator-supplied portal secret file replaces the live portal call. V20 is nil —
+// App-Bound Encryption is Windows-only. Safari (a copied ~/Library) gets --keychain-pw for the copied login keychain
+// beside it; there is no prompt off macOS.
+func newCredentialInjector(opts DiscoverOptions) browserInjector {
 	retrievers := masterkey.DefaultRetrievers()
+	if opts.KeyringPassword != "" {
+		retrievers.V11 = masterkey.KeyringRetriever(opts.KeyringPassword)
+	}
+	if opts.PortalSecretFile != "" {
+		retrievers.V12 = &masterkey.SecretFileRetriever{Path: opts.PortalSecretFile}
+	}
 	return func(b Browser) {
 		if km, ok := b.(KeyManager); ok {
 			km.SetRetrievers(retrievers)
 		}
+		if kps, ok := b.(KeychainPasswordReceiver); ok && opts.KeychainPassword != "" {
+			kps.SetKeychainPassword(opts.KeychainPassword)
+		}
 	}
 }
@@ -0,0 +1,43 @@
+//go:build linux
+
+package browser
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/moond4rk/hackbrowserdata/types"
+)
+
+// TestFlatpakConfigsMatchAppDir pins every FlatpakAppID to the sandbox data dir Flatpak gives that app
+// (~/.var/app/<app-id>), so the v12 portal tier is only ever wired to the install it belongs to.
+func TestFlatpakConfigsMatchAppDir(t *testing.T) {
+	found := 0
+	for _, b := range platformBrowsers() {
+		if b.FlatpakAppID == "" {
+			if strings.Contains(b.UserDataDir, "/.var/app/") {
+				t.Errorf("%s lives in a Flatpak app dir but sets no FlatpakAppID", b.Key)
+			}
+			continue
+		}
+		found++
+		prefix := filepath.Join(homeDir, ".var", "app", b.FlatpakAppID) + "/"
+		if !strings.HasPrefix(b.UserDataDir, prefix) {
+			t.Errorf("%s: UserDataDir %q is outside %s", b.Key, b.UserDataDir, prefix)
+		}
+	}
+	if found == 0 {
+		t.Error("expected at least one Flatpak browser in the Linux table")
+	}
+}
+
+// TestKeyringLabelsCoverPlatformTable checks every linux Chromium browser finds its keyring label,
+// which a key missing from linuxKeyringLabels would leave empty.
+func TestKeyringLabelsCoverPlatformTable(t *testing.T) {
+	for _, b := range platformBrowsers() {
+		if b.Kind != types.Firefox && b.Kind != types.Safari && b.KeychainLabel == "" {
+			t.Errorf("%s: no label in linuxKeyringLabels", b.Key)
+		}
+	}
+}
@@ -65,6 +65,10 @@ func TestPickFromConfigs(t *testing.T) {
 	mkFile(t, firefoxDir, "abc123.default-release", "logins.json")
 	mkFile(t, firefoxDir, "abc123.default-release", "places.sqlite")
 
+	// --- fixtures: safari (a copied ~/Library) ---
+	libraryDir := t.TempDir()
+	mkFile(t, libraryDir, "Safari", "History.db")
+
 	// --- fixtures: yandex ---
 	yandexDir := t.TempDir()
 	mkFile(t, yandexDir, "Default", "Preferences")
@@ -175,6 +179,24 @@ func TestPickFromConfigs(t *testing.T) {
 				wantNames:    []string{"Firefox"},
 				wantProfiles: []string{"abc123.default-release"},
 			},
+			{
+				name: "safari accepts a copied Library",
+				configs: []types.BrowserConfig{
+					{Key: "safari", Name: "Safari", Kind: types.Safari, UserDataDir: "/wrong"},
+				},
+				opts:         DiscoverOptions{Name: "safari", ProfilePath: libraryDir},
+				wantNames:    []string{"Safari"},
+				wantProfiles: []string{"default"},
+			},
+			{
+				name: "safari accepts the Safari dir",
+				configs: []types.BrowserConfig{
+					{Key: "safari", Name: "Safari", Kind: types.Safari, UserDataDir: "/wrong"},
+				},
+				opts:         DiscoverOptions{Name: "safari", ProfilePath: filepath.Join(libraryDir, "Safari")},
+				wantNames:    []string{"Safari"},
+				wantProfiles: []string{"default"},
+			},
 			{
 				name: "ignored when name is all",
 				configs: []types.BrowserConfig{
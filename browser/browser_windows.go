@@ -14,130 +14,141 @@ func platformBrowsers() []types.BrowserConfig {
 			Name:        chromeName,
 			Kind:        types.Chromium,
 			WindowsABE:  true,
-			UserDataDir: homeDir + "/AppData/Local/Google/Chrome/User Data",
+			UserDataDir: homeDir + "/" + windowsInstallDirs["chrome"],
 		},
 		{
 			Key:         "edge",
 			Name:        edgeName,
 			Kind:        types.Chromium,
 			WindowsABE:  true,
-			UserDataDir: homeDir + "/AppData/Local/Microsoft/Edge/User Data",
+			UserDataDir: homeDir + "/" + windowsInstallDirs["edge"],
 		},
 		{
 			Key:         "chromium",
 			Name:        chromiumName,
 			Kind:        types.Chromium,
-			UserDataDir: homeDir + "/AppData/Local/Chromium/User Data",
+			UserDataDir: homeDir + "/" + windowsInstallDirs["chromium"],
 		},
 		{
 			Key:         "chrome-beta",
 			Name:        chromeBetaName,
 			Kind:        types.Chromium,
 			WindowsABE:  true,
-			UserDataDir: homeDir + "/AppData/Local/Google/Chrome Beta/User Data",
+			UserDataDir: homeDir + "/" + windowsInstallDirs["chrome-beta"],
 		},
 		{
 			Key:         "opera",
 			Name:        operaName,
 			Kind:        types.ChromiumOpera,
-			UserDataDir: homeDir + "/AppData/Roaming/Opera Software/Opera Stable",
+			UserDataDir: homeDir + "/" + windowsInstallDirs["opera"],
 		},
 		{
 			Key:         "opera-gx",
 			Name:        operaGXName,
 			Kind:        types.ChromiumOpera,
-			UserDataDir: homeDir + "/AppData/Roaming/Opera Software/Opera GX Stable",
+			UserDataDir: homeDir + "/" + windowsInstallDirs["opera-gx"],
 		},
 		{
 			Key:         "vought",
 			Name:        voughtName,
 			Kind:        types.ChromiumOpera,
-			UserDataDir: homeDir + "/AppData/Roaming/Browser from Vought",
+			UserDataDir: homeDir + "/" + windowsInstallDirs["vought"],
 		},
 		{
 			Key:         "vivaldi",
 			Name:        vivaldiName,
 			Kind:        types.Chromium,
-			UserDataDir: homeDir + "/AppData/Local/Vivaldi/User Data",
+			UserDataDir: homeDir + "/" + windowsInstallDirs["vivaldi"],
 		},
 		{
 			Key:         "coccoc",
 			Name:        coccocName,
 			Kind:        types.Chromium,
 			WindowsABE:  true,
-			UserDataDir: homeDir + "/AppData/Local/CocCoc/Browser/User Data",
+			UserDataDir: homeDir + "/" + windowsInstallDirs["coccoc"],
 		},
 		{
 			Key:         "brave",
 			Name:        braveName,
 			Kind:        types.Chromium,
 			WindowsABE:  true,
-			UserDataDir: homeDir + "/AppData/Local/BraveSoftware/Brave-Browser/User Data",
+			UserDataDir: homeDir + "/" + windowsInstallDirs["brave"],
 		},
 		{
 			Key:         "yandex",
 			Name:        yandexName,
 			Kind:        types.ChromiumYandex,
-			UserDataDir: homeDir + "/AppData/Local/Yandex/YandexBrowser/User Data",
+			UserDataDir: homeDir + "/" + windowsInstallDirs["yandex"],
 		},
 		{
 			Key:         "360x",
 			Name:        speed360XName,
 			Kind:        types.Chromium,
-			UserDataDir: homeDir + "/AppData/Local/360ChromeX/Chrome/User Data",
+			UserDataDir: homeDir + "/" + windowsInstallDirs["360x"],
 		},
 		{
 			Key:         "360",
 			Name:        speed360Name,
 			Kind:        types.Chromium,
-			UserDataDir: homeDir + "/AppData/Local/360chrome/Chrome/User Data",
+			UserDataDir: homeDir + "/" + windowsInstallDirs["360"],
 		},
 		{
 			Key:         "qq",
 			Name:        qqName,
 			Kind:        types.Chromium,
-			UserDataDir: homeDir + "/AppData/Local/Tencent/QQBrowser/User Data",
+			UserDataDir: homeDir + "/" + windowsInstallDirs["qq"],
 		},
 		{
 			Key:         "dc",
 			Name:        dcName,
 			Kind:        types.Chromium,
-			UserDataDir: homeDir + "/AppData/Local/DCBrowser/User Data",
+			UserDataDir: homeDir + "/" + windowsInstallDirs["dc"],
 		},
 		{
 			Key:         "sogou",
 			Name:        sogouName,
 			Kind:        types.Chromium,
-			UserDataDir: homeDir + "/AppData/Local/Sogou/SogouExplorer/User Data",
+			UserDataDir: homeDir + "/" + windowsInstallDirs["sogou"],
 		},
 		{
 			Key:         "arc",
 			Name:        arcName,
 			Kind:        types.Chromium,
-			UserDataDir: homeDir + "/AppData/Local/Packages/TheBrowserCompany.Arc_*/LocalCache/Local/Arc/User Data",
+			UserDataDir: homeDir + "/" + windowsInstallDirs["arc"],
 		},
 		{
 			Key:         "duckduckgo",
 			Name:        duckduckgoName,
 			Kind:        types.Chromium,
-			UserDataDir: homeDir + "/AppData/Local/Packages/DuckDuckGo.DesktopBrowser_*/LocalState/EBWebView",
+			UserDataDir: homeDir + "/" + windowsInstallDirs["duckduckgo"],
 		},
 		{
 			Key:         "firefox",
 			Name:        firefoxName,
 			Kind:        types.Firefox,
-			UserDataDir: homeDir + "/AppData/Roaming/Mozilla/Firefox/Profiles",
+			UserDataDir: homeDir + "/" + windowsInstallDirs["firefox"],
+			CacheDir:    homeDir + "/AppData/Local/Mozilla/Firefox/Profiles",
+		},
+		{
+			Key:         "safari",
+			Name:        safariName,
+			Kind:        types.Safari,
+			UserDataDir: homeDir + "/" + windowsInstallDirs["safari"],
 		},
 	}
 }
 
 // newCredentialInjector wires the Windows Chromium retrievers: v10 (DPAPI) and v20 (ABE). The two tiers are orthogonal
 // — a pre-127-upgraded profile carries v20 cookies alongside v10 passwords — so both run independently, not as a chain.
-func newCredentialInjector(_ DiscoverOptions) browserInjector {
+// Safari (a copied ~/Library) gets --keychain-pw for the copied login keychain beside it.
+func newCredentialInjector(opts DiscoverOptions) browserInjector {
 	retrievers := masterkey.DefaultRetrievers()
 	return func(b Browser) {
 		if km, ok := b.(KeyManager); ok {
 			km.SetRetrievers(retrievers)
 		}
+		if kps, ok := b.(KeychainPasswordReceiver); ok && opts.KeychainPassword != "" {
+			kps.SetKeychainPassword(opts.KeychainPassword)
+		}
 	}
 }
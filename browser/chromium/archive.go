@@ -1,6 +1,7 @@
 package chromium
 
 import (
+	"io/fs"
 	"path"
 	"path/filepath"
 
@@ -8,14 +9,6 @@ import (
 	"github.com/moond4rk/hackbrowserdata/utils/fileutil"
 )
 
-// ArchiveSource is one decryption-relevant file or directory plus its path inside the browser's
-// User Data tree (forward-slash), so an archive can be re-expanded into a working profile layout.
-type ArchiveSource struct {
-	AbsPath   string
-	LayoutRel string
-	IsDir     bool
-}
-
 // installationFiles live at the User Data root (shared across profiles); archived for fidelity even
 // though keys.json-based restore does not read them.
 var installationFiles = []string{"Local State"}
@@ -23,12 +16,12 @@ var installationFiles = []string{"Local State"}
 // ArchiveSources lists the files an archive must capture for the given categories: the User Data root
 // files (Local State), every resolved category source per profile, plus each profile's Preferences
 // marker so a restore can rediscover the profile. LayoutRel is forward-slash, relative to the root.
-func (b *Browser) ArchiveSources(categories []types.Category) []ArchiveSource {
-	var out []ArchiveSource
+func (b *Browser) ArchiveSources(categories []types.Category) []types.ArchiveSource {
+	var out []types.ArchiveSource
 	for _, name := range installationFiles {
 		abs := filepath.Join(b.cfg.UserDataDir, name)
 		if fileutil.FileExists(abs) {
-			out = append(out, ArchiveSource{AbsPath: abs, LayoutRel: name, IsDir: false})
+			out = append(out, types.ArchiveSource{AbsPath: abs, LayoutRel: name, IsDir: false})
 		}
 	}
 	for _, p := range b.profiles {
@@ -41,7 +34,7 @@ func (b *Browser) ArchiveSources(categories []types.Category) []ArchiveSource {
 		for _, marker := range profileMarkers {
 			abs := filepath.Join(p.profileDir, marker)
 			if fileutil.FileExists(abs) {
-				out = append(out, ArchiveSource{
+				out = append(out, types.ArchiveSource{
 					AbsPath:   abs,
 					LayoutRel: path.Join(profileRel, marker),
 					IsDir:     false,
@@ -53,7 +46,17 @@ func (b *Browser) ArchiveSources(categories []types.Category) []ArchiveSource {
 			if !ok {
 				continue
 			}
-			out = append(out, ArchiveSource{
+			for _, m := range rp.members {
+				out = append(out, types.ArchiveSource{
+					AbsPath:   filepath.Join(rp.absPath, m),
+					LayoutRel: path.Join(profileRel, m),
+					IsDir:     false,
+				})
+			}
+			if len(rp.members) > 0 {
+				continue
+			}
+			out = append(out, types.ArchiveSource{
 				AbsPath:   rp.absPath,
 				LayoutRel: path.Join(profileRel, rp.rel),
 				IsDir:     rp.isDir,
@@ -62,3 +65,52 @@ func (b *Browser) ArchiveSources(categories []types.Category) []ArchiveSource {
 	}
 	return out
 }
+
+// StagePaths lists what discovery and extraction of categories read from the installation at the slash
+// path root in fsys: the User Data root files, and in the root (a flat layout) and each profile subdir
+// the profile markers and the first existing source of each category. It lets an installation be copied
+// out of a disk image without its caches, crash dumps and other bulk. Paths are slash paths in fsys.
+func StagePaths(fsys fs.FS, root string, kind types.BrowserKind, categories []types.Category) []string {
+	var out []string
+	seen := make(map[string]bool)
+	add := func(p string) bool {
+		if _, err := fs.Stat(fsys, p); err != nil {
+			return false
+		}
+		if !seen[p] {
+			seen[p] = true
+			out = append(out, p)
+		}
+		return true
+	}
+	for _, name := range installationFiles {
+		add(path.Join(root, name))
+	}
+	dirs := []string{root}
+	entries, _ := fs.ReadDir(fsys, root)
+	for _, e := range entries {
+		if e.IsDir() && !isSkippedDir(e.Name()) {
+			dirs = append(dirs, path.Join(root, e.Name()))
+		}
+	}
+	sources := sourcesForKind(kind)
+	for _, dir := range dirs {
+		for _, marker := range profileMarkers {
+			add(path.Join(dir, marker))
+		}
+		for _, cat := range categories {
+			for _, sp := range sources[cat] {
+				found := false
+				for _, rel := range sp.paths() {
+					if add(path.Join(dir, rel)) {
+						found = true
+					}
+				}
+				if found {
+					break
+				}
+			}
+		}
+	}
+	return out
+}
@@ -5,6 +5,7 @@ import (
 	"path/filepath"
 	"strings"
 	"testing"
+	"testing/fstest"
 
 	"github.com/moond4rk/hackbrowserdata/types"
 )
@@ -86,3 +87,28 @@ func TestArchiveSources_FlatLayoutNoExtraLevel(t *testing.T) {
 		t.Errorf("expected History at archive root, got %+v", srcs)
 	}
 }
+
+func TestStagePaths(t *testing.T) {
+	udd := "Users/alice/AppData/Local/Google/Chrome/User Data"
+	fsys := fstest.MapFS{
+		udd + "/Local State":                     {Data: []byte("{}")},
+		udd + "/Default/Preferences":             {Data: []byte("{}")},
+		udd + "/Default/Network/Cookies":         {Data: []byte("cookies")},
+		udd + "/Default/Cookies":                 {Data: []byte("stale cookies")},
+		udd + "/Default/Shortcuts":               {Data: []byte("shortcuts")},
+		udd + "/Default/Top Sites":               {Data: []byte("top sites")},
+		udd + "/Default/Cache/Cache_Data/index":  {Data: []byte("cache")},
+		udd + "/Guest Profile/Preferences":       {Data: []byte("{}")},
+		udd + "/Crashpad/reports/dump.dmp":       {Data: []byte("dump")},
+		udd + "/Default/Service Worker/Database": {Data: []byte("sw")},
+	}
+
+	got := StagePaths(fsys, udd, types.Chromium, []types.Category{types.Cookie, types.Omnibox})
+	want := []string{"Local State", "Default/Preferences", "Default/Network/Cookies", "Default/Shortcuts", "Default/Top Sites"}
+	for i := range want {
+		want[i] = udd + "/" + want[i]
+	}
+	if strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Errorf("StagePaths = %q, want %q", got, want)
+	}
+}
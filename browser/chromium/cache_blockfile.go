@@ -0,0 +1,225 @@
+package chromium
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// Blockfile cache layout, see net/disk_cache/blockfile/disk_format.h and addr.h.
+const (
+	blockIndexMagic      = 0xc103cac3
+	blockFileMagic       = 0xc104cac3
+	blockIndexHeaderSize = 368 // IndexHeader including its LruData
+	blockIndexTableLen   = 0x10000
+	blockFileHeaderSize  = 8192
+	blockEntrySize       = 256 // EntryStore
+	blockEntryKeyOffset  = 96
+	blockEntryStreams    = 4
+
+	addrInitialized = 1 << 31
+	addrExternal    = 0 // an f_ file of its own
+)
+
+// blockSizes is the block size of each block file type an address can name: rankings, 256 B, 1 KiB
+// and 4 KiB blocks.
+var blockSizes = map[uint32]int{1: 36, 2: 256, 3: 1024, 4: 4096}
+
+// readBlockfileCache reads the entries of a blockfile cache and calls visit with each while the cache
+// files are open. The index is a hash table of addresses of entries, each chaining to the next with the
+// same hash; an entry holds its key (inline, or at an address of its own when long) and the address and
+// size of each stream. Addresses point into the data_N block files or at a separate f_XXXXXX file.
+func readBlockfileCache(dir string, index []byte, visit func(cacheRecord)) error {
+	if len(index) < blockIndexHeaderSize {
+		return errors.New("index too short")
+	}
+	tableLen := int(binary.LittleEndian.Uint32(index[28:]))
+	if tableLen == 0 {
+		tableLen = blockIndexTableLen
+	}
+	if n := (len(index) - blockIndexHeaderSize) / 4; tableLen > n {
+		tableLen = n
+	}
+
+	files := blockFiles{dir: dir, blocks: make(map[string]*os.File)}
+	defer files.close()
+	seen := make(map[uint32]bool)
+	var errs []error
+	for i := 0; i < tableLen; i++ {
+		addr := binary.LittleEndian.Uint32(index[blockIndexHeaderSize+4*i:])
+		for addr != 0 && !seen[addr] {
+			seen[addr] = true
+			entry, err := files.read(addr, -1)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("entry %08x: %w", addr, err))
+				break
+			}
+			if len(entry) < blockEntrySize {
+				errs = append(errs, fmt.Errorf("entry %08x: short", addr))
+				break
+			}
+			r, err := files.entry(entry)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("entry %08x: %w", addr, err))
+			} else {
+				r.name = fmt.Sprintf("%08x", addr)
+				visit(r)
+			}
+			addr = binary.LittleEndian.Uint32(entry[4:])
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// blockFiles reads the cache's block files, each opened once, and its external files, opened on each
+// read.
+type blockFiles struct {
+	dir    string
+	blocks map[string]*os.File
+}
+
+func (f *blockFiles) close() {
+	for _, b := range f.blocks {
+		b.Close()
+	}
+}
+
+// entry decodes an EntryStore: key length at 32, the long key's address at 36, then the four stream
+// sizes and addresses; a short key follows the fixed fields. The body is left in its file for the
+// record's readBody.
+func (f *blockFiles) entry(e []byte) (cacheRecord, error) {
+	keyLen := int(int32(binary.LittleEndian.Uint32(e[32:])))
+	if keyLen < 0 {
+		return cacheRecord{}, errors.New("bad key length")
+	}
+	var key []byte
+	if longKey := binary.LittleEndian.Uint32(e[36:]); longKey != 0 {
+		k, err := f.read(longKey, keyLen)
+		if err != nil {
+			return cacheRecord{}, fmt.Errorf("key: %w", err)
+		}
+		key = k
+	} else {
+		if blockEntryKeyOffset+keyLen > len(e) {
+			return cacheRecord{}, errors.New("key runs past the entry")
+		}
+		key = e[blockEntryKeyOffset : blockEntryKeyOffset+keyLen]
+	}
+
+	var streams [blockEntryStreams]*io.SectionReader
+	for i := range streams {
+		size := int(int32(binary.LittleEndian.Uint32(e[40+4*i:])))
+		addr := binary.LittleEndian.Uint32(e[56+4*i:])
+		if size <= 0 || addr == 0 {
+			continue
+		}
+		s, err := f.section(addr, size)
+		if err != nil {
+			return cacheRecord{}, fmt.Errorf("stream %d: %w", i, err)
+		}
+		streams[i] = s
+	}
+	r := cacheRecord{key: string(key)}
+	if streams[0] != nil {
+		info, err := readSection(streams[0])
+		if err != nil {
+			return cacheRecord{}, fmt.Errorf("stream 0: %w", err)
+		}
+		r.info = info
+	}
+	if body := streams[1]; body != nil {
+		r.bodySize = body.Size()
+		r.readBody = func() ([]byte, error) { return readSection(body) }
+	}
+	return r, nil
+}
+
+// read returns size bytes at addr, or all the blocks addr spans when size is negative.
+func (f *blockFiles) read(addr uint32, size int) ([]byte, error) {
+	s, err := f.section(addr, size)
+	if err != nil {
+		return nil, err
+	}
+	return readSection(s)
+}
+
+// section locates size bytes at addr, or all the blocks addr spans when size is negative. An address
+// holds whether it is initialized, the file type, and for block files the number of blocks, the file
+// number and the first block.
+func (f *blockFiles) section(addr uint32, size int) (*io.SectionReader, error) {
+	if addr&addrInitialized == 0 {
+		return nil, errors.New("address not initialized")
+	}
+	typ := addr >> 28 & 0x7
+	if typ == addrExternal {
+		path := filepath.Join(f.dir, fmt.Sprintf("f_%06x", addr&0x0fffffff))
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, err
+		}
+		n := info.Size()
+		if size >= 0 && int64(size) < n {
+			n = int64(size)
+		}
+		return io.NewSectionReader(externalFile(path), 0, n), nil
+	}
+
+	blockSize, ok := blockSizes[typ]
+	if !ok {
+		return nil, fmt.Errorf("unsupported file type %d", typ)
+	}
+	file, fileSize, err := f.blockFile(fmt.Sprintf("data_%d", addr>>16&0xff))
+	if err != nil {
+		return nil, err
+	}
+	span := int(addr>>24&0x3+1) * blockSize
+	if size < 0 || size > span {
+		size = span
+	}
+	off := int64(blockFileHeaderSize) + int64(addr&0xffff)*int64(blockSize)
+	if off+int64(size) > fileSize {
+		return nil, errors.New("address past the end of its file")
+	}
+	return io.NewSectionReader(file, off, int64(size)), nil
+}
+
+// blockFile opens a block file, checking its magic number, and returns it with its size.
+func (f *blockFiles) blockFile(name string) (*os.File, int64, error) {
+	file, ok := f.blocks[name]
+	if !ok {
+		var err error
+		if file, err = os.Open(filepath.Join(f.dir, name)); err != nil {
+			return nil, 0, err
+		}
+		magic := make([]byte, 4)
+		if _, err := file.ReadAt(magic, 0); err != nil || binary.LittleEndian.Uint32(magic) != blockFileMagic {
+			file.Close()
+			return nil, 0, fmt.Errorf("%s: not a block file", name)
+		}
+		f.blocks[name] = file
+	}
+	info, err := file.Stat()
+	if err != nil {
+		return nil, 0, err
+	}
+	if info.Size() < blockFileHeaderSize {
+		return nil, 0, fmt.Errorf("%s: not a block file", name)
+	}
+	return file, info.Size(), nil
+}
+
+// externalFile reads an f_ file, opening it on each read so that a cache with thousands of them does
+// not hold as many open.
+type externalFile string
+
+func (e externalFile) ReadAt(p []byte, off int64) (int, error) {
+	f, err := os.Open(string(e))
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+	return f.ReadAt(p, off)
+}
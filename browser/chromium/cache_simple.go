@@ -0,0 +1,126 @@
+package chromium
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Simple Cache entry file layout, see net/disk_cache/simple/simple_entry_format.h.
+const (
+	simpleInitialMagic    = 0xfcfb6d1ba7725c30
+	simpleFinalMagic      = 0xf4fa6f45970d41d8
+	simpleFileHeaderSize  = 24 // magic, version, key length, key hash, padding
+	simpleFileEOFSize     = 24 // magic, flags, data crc, stream 0 size, padding
+	simpleEOFHasKeySHA256 = 1 << 1
+	simpleKeySHA256Size   = 32
+	simpleEntryFileSuffix = "_0"
+	simpleEntryHashLen    = 16
+)
+
+// readSimpleCache reads the entries of a Simple Cache, one "<hash>_0" file each, and calls visit with
+// each while its file is open. The "<hash>_1" file holds stream 2, which HTTP responses do not use, and
+// the index only speeds up lookups, so neither is read. An entry file that does not parse is skipped
+// and reported.
+func readSimpleCache(dir string, visit func(cacheRecord)) error {
+	files, err := os.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+	var errs []error
+	for _, f := range files {
+		name := f.Name()
+		hash := strings.TrimSuffix(name, simpleEntryFileSuffix)
+		if f.IsDir() || len(name) != simpleEntryHashLen+len(simpleEntryFileSuffix) || hash == name ||
+			strings.Trim(hash, "0123456789abcdef") != "" {
+			continue
+		}
+		if err := visitSimpleEntry(filepath.Join(dir, name), hash, visit); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func visitSimpleEntry(path, hash string, visit func(cacheRecord)) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	r, err := parseSimpleEntry(f, info.Size())
+	if err != nil {
+		return err
+	}
+	r.name = hash
+	visit(r)
+	return nil
+}
+
+// parseSimpleEntry decodes a "_0" entry file: the header and key, stream 1 and its EOF record, then
+// stream 0, the key's SHA-256 when flagged, and the EOF record of stream 0, which holds its size. The
+// streams are found from the end of the file, since only stream 0's size is recorded. Stream 1 is
+// left in the file for the record's readBody.
+func parseSimpleEntry(f io.ReaderAt, size int64) (cacheRecord, error) {
+	if size < simpleFileHeaderSize+2*simpleFileEOFSize {
+		return cacheRecord{}, errors.New("too short for an entry")
+	}
+	header, err := readSection(io.NewSectionReader(f, 0, simpleFileHeaderSize))
+	if err != nil {
+		return cacheRecord{}, err
+	}
+	if binary.LittleEndian.Uint64(header) != simpleInitialMagic {
+		return cacheRecord{}, errors.New("bad entry magic")
+	}
+	keyEnd := simpleFileHeaderSize + int64(binary.LittleEndian.Uint32(header[12:]))
+	if keyEnd > size-2*simpleFileEOFSize {
+		return cacheRecord{}, errors.New("key runs past the file")
+	}
+
+	eof0, err := readSection(io.NewSectionReader(f, size-simpleFileEOFSize, simpleFileEOFSize))
+	if err != nil {
+		return cacheRecord{}, err
+	}
+	if binary.LittleEndian.Uint64(eof0) != simpleFinalMagic {
+		return cacheRecord{}, errors.New("bad stream 0 EOF magic")
+	}
+	end0 := size - simpleFileEOFSize
+	if binary.LittleEndian.Uint32(eof0[8:])&simpleEOFHasKeySHA256 != 0 {
+		end0 -= simpleKeySHA256Size
+	}
+	start0 := end0 - int64(binary.LittleEndian.Uint32(eof0[16:]))
+	eof1 := start0 - simpleFileEOFSize
+	if eof1 < keyEnd {
+		return cacheRecord{}, errors.New("stream 0 size runs past the key")
+	}
+	magic1, err := readSection(io.NewSectionReader(f, eof1, 8))
+	if err != nil {
+		return cacheRecord{}, err
+	}
+	if binary.LittleEndian.Uint64(magic1) != simpleFinalMagic {
+		return cacheRecord{}, errors.New("bad stream 1 EOF magic")
+	}
+	key, err := readSection(io.NewSectionReader(f, simpleFileHeaderSize, keyEnd-simpleFileHeaderSize))
+	if err != nil {
+		return cacheRecord{}, err
+	}
+	info, err := readSection(io.NewSectionReader(f, start0, end0-start0))
+	if err != nil {
+		return cacheRecord{}, err
+	}
+	body := io.NewSectionReader(f, keyEnd, eof1-keyEnd)
+	return cacheRecord{
+		key:      string(key),
+		info:     info,
+		bodySize: body.Size(),
+		readBody: func() ([]byte, error) { return readSection(body) },
+	}, nil
+}
@@ -54,6 +54,33 @@ func NewBrowser(cfg types.BrowserConfig) (*Browser, error) {
 // Extract; unused tiers stay nil.
 func (b *Browser) SetRetrievers(r masterkey.Retrievers) { b.retrievers = r }
 
+// Retrievers returns the retrievers set by SetRetrievers.
+func (b *Browser) Retrievers() masterkey.Retrievers { return b.retrievers }
+
+// SetRecoverDeleted makes Extract also carve deleted logins, cookies and history out of the free space
+// of each profile's databases; recovered entries are flagged and follow the live ones.
+func (b *Browser) SetRecoverDeleted(on bool) {
+	for _, p := range b.profiles {
+		p.recoverDeleted = on
+	}
+}
+
+// SetCacheBodyDir makes Extract save the decoded body of each HTTP cache entry under dir, in a
+// <browser key>/<profile> subdirectory; the entry's BodyFile names the file.
+func (b *Browser) SetCacheBodyDir(dir string) {
+	for _, p := range b.profiles {
+		p.cacheBodyDir = filepath.Join(dir, b.cfg.Key, p.name())
+	}
+}
+
+// SetFaviconDir makes Extract save each favicon's image under dir, in a <browser key>/<profile>
+// subdirectory; the entry's IconFile names the file.
+func (b *Browser) SetFaviconDir(dir string) {
+	for _, p := range b.profiles {
+		p.faviconDir = filepath.Join(dir, b.cfg.Key, p.name())
+	}
+}
+
 func (b *Browser) BrowserName() string     { return b.cfg.Name }
 func (b *Browser) BrowserKey() string      { return b.cfg.Key }
 func (b *Browser) UserDataDir() string     { return b.cfg.UserDataDir }
@@ -95,6 +122,8 @@ func (b *Browser) CountEntries(categories []types.Category) ([]types.CountResult
 
 // ExportKeys derives the master keys without extracting. Returns the tiers that succeeded plus a
 // joined error for those that failed — partial results matter (a v20-only failure keeps the v10 key).
+// Candidates are checked against values sampled from the profiles' Login Data and Cookies, so a stale
+// or wrong keyring item is passed over rather than silently yielding empty passwords.
 func (b *Browser) ExportKeys() (masterkey.MasterKeys, error) {
 	session, err := filemanager.NewSession()
 	if err != nil {
@@ -102,7 +131,11 @@ func (b *Browser) ExportKeys() (masterkey.MasterKeys, error) {
 	}
 	defer session.Cleanup()
 
-	return masterkey.NewMasterKeys(b.retrievers, b.buildHints(session))
+	keys, reports, err := masterkey.ValidateMasterKeys(b.retrievers, b.buildHints(session), b.keySamples(session))
+	for _, r := range reports {
+		log.Infof("%s: master key %s", b.BrowserName(), r)
+	}
+	return keys, err
 }
 
 // masterKeys derives and caches the installation's keys exactly once (sync.Once), so a failure is
@@ -140,6 +173,8 @@ func (b *Browser) buildHints(session *filemanager.Session) masterkey.Hints {
 		KeychainLabel:  b.cfg.KeychainLabel,
 		WindowsABEKey:  abeKey,
 		LocalStatePath: localStateDst,
+		FlatpakAppID:   b.cfg.FlatpakAppID,
+		KWalletFolder:  b.cfg.KWalletFolder,
 	}
 }
 
@@ -210,9 +245,10 @@ func isProfileDir(dir string) bool {
 func hasAnySource(sources map[types.Category][]sourcePath, dir string) bool {
 	for _, candidates := range sources {
 		for _, sp := range candidates {
-			abs := filepath.Join(dir, sp.rel)
-			if _, err := os.Stat(abs); err == nil {
-				return true
+			for _, rel := range sp.paths() {
+				if _, err := os.Stat(filepath.Join(dir, rel)); err == nil {
+					return true
+				}
 			}
 		}
 	}
@@ -225,6 +261,23 @@ type resolvedPath struct {
 	absPath string
 	rel     string
 	isDir   bool
+	members []string // for a group: the files found, relative to absPath (the profile dir)
+}
+
+// databases lists the absolute paths of the files a source is made of: a group's members, or the
+// file itself; a directory source has none.
+func (rp resolvedPath) databases() []string {
+	if len(rp.members) > 0 {
+		dbs := make([]string, len(rp.members))
+		for i, m := range rp.members {
+			dbs[i] = filepath.Join(rp.absPath, filepath.FromSlash(m))
+		}
+		return dbs
+	}
+	if rp.isDir {
+		return nil
+	}
+	return []string{rp.absPath}
 }
 
 // resolveSourcePaths checks which sources actually exist in profileDir.
@@ -233,6 +286,13 @@ func resolveSourcePaths(sources map[types.Category][]sourcePath, profileDir stri
 	resolved := make(map[types.Category]resolvedPath)
 	for cat, candidates := range sources {
 		for _, sp := range candidates {
+			if len(sp.members) > 0 {
+				if members := existingFiles(profileDir, sp.members); len(members) > 0 {
+					resolved[cat] = resolvedPath{absPath: profileDir, isDir: true, members: members}
+					break
+				}
+				continue
+			}
 			abs := filepath.Join(profileDir, sp.rel)
 			info, err := os.Stat(abs)
 			if err != nil {
@@ -247,6 +307,17 @@ func resolveSourcePaths(sources map[types.Category][]sourcePath, profileDir stri
 	return resolved
 }
 
+// existingFiles returns the slash-relative paths in rels that are regular files under dir.
+func existingFiles(dir string, rels []string) []string {
+	var found []string
+	for _, rel := range rels {
+		if info, err := os.Stat(filepath.Join(dir, rel)); err == nil && !info.IsDir() {
+			found = append(found, rel)
+		}
+	}
+	return found
+}
+
 // isSkippedDir returns true for directory names that should never be
 // treated as browser profiles.
 func isSkippedDir(name string) bool {
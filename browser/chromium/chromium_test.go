@@ -1,6 +1,7 @@
 package chromium
 
 import (
+	"encoding/hex"
 	"os"
 	"path/filepath"
 	"testing"
@@ -314,9 +315,10 @@ func assertCategories(t *testing.T, nameMap map[string]*profile, wantCats map[st
 		for _, wantFile := range wantFiles {
 			found := false
 			for _, rp := range p.sourcePaths {
-				if filepath.Base(rp.absPath) == wantFile {
-					found = true
-					break
+				for _, path := range append([]string{rp.absPath}, rp.databases()...) {
+					if filepath.Base(path) == wantFile {
+						found = true
+					}
 				}
 			}
 			assert.True(t, found, "profile %s should have %s", profileName, wantFile)
@@ -370,7 +372,7 @@ func TestSourcesForKind(t *testing.T) {
 	chromium := sourcesForKind(types.Chromium)
 	yandex := sourcesForKind(types.ChromiumYandex)
 
-	assert.Equal(t, "Login Data", chromium[types.Password][0].rel)
+	assert.Equal(t, []string{"Login Data", "Login Data For Account"}, chromium[types.Password][0].paths())
 	assert.Equal(t, "Ya Passman Data", yandex[types.Password][0].rel)
 	// Yandex inherits non-overridden categories
 	assert.Equal(t, chromium[types.History][0].rel, yandex[types.History][0].rel)
@@ -657,7 +659,7 @@ func TestCountEntries_NoRetrieverNeeded(t *testing.T) {
 	dir := t.TempDir()
 	mkFile(dir, "Default", "Preferences")
 	// Login Data normally needs master key to extract, but CountEntries skips decryption.
-	installFile(t, filepath.Join(dir, "Default"), setupLoginDB(t), "Login Data")
+	installFile(t, filepath.Join(dir, "Default"), filepath.Join(setupLoginDB(t), loginDataFile), "Login Data")
 
 	b, err := NewBrowser(types.BrowserConfig{
 		Name: "Test", Kind: types.Chromium, UserDataDir: dir,
@@ -726,3 +728,35 @@ func TestTimeEpoch_OutOfJSONRangeReturnsZero(t *testing.T) {
 	require.NoError(t, err)
 	assert.JSONEq(t, `"0001-01-01T00:00:00Z"`, string(jsonBytes))
 }
+
+// TestExportKeys_ValidatesAgainstLogins: with a stale key ahead of the right one in the chain,
+// ExportKeys keeps the key that decrypts the profile's Login Data and Cookies samples.
+func TestExportKeys_ValidatesAgainstLogins(t *testing.T) {
+	dir := t.TempDir()
+	mkFile(dir, "Default", "Preferences")
+	profileDir := filepath.Join(dir, "Default")
+	pwd := hex.EncodeToString(sealChromiumCBC(t, testAESKey, "s3cret"))
+	installFile(t, profileDir, createTestDB(t, "Login Data", loginsSchema,
+		insertLogin("https://a.com", "", "alice", pwd, 13340000000000000),
+		insertLogin("https://b.com", "", "bob", pwd, 13340000000000001),
+	), "Login Data")
+	installFile(t, profileDir, createTestDB(t, "Cookies", cookiesSchema,
+		insertCookie("sid", ".a.com", "/", hex.EncodeToString(sealChromiumCBC(t, testAESKey, "cookie")), 0, 0, 1, 1),
+	), "Cookies")
+
+	b, err := NewBrowser(types.BrowserConfig{Name: "Test", Kind: types.Chromium, UserDataDir: dir})
+	require.NoError(t, err)
+	require.NotNil(t, b)
+	b.SetRetrievers(masterkey.Retrievers{
+		V10: masterkey.NewChain(&mockRetriever{key: []byte("stale-key-16byte")}, &mockRetriever{key: testAESKey}),
+	})
+
+	mk, err := b.ExportKeys()
+	require.NoError(t, err)
+	assert.Equal(t, testAESKey, mk.V10)
+
+	results, err := b.Extract([]types.Category{types.Password})
+	require.NoError(t, err)
+	require.Len(t, results[0].Data.Passwords, 2)
+	assert.Equal(t, "s3cret", results[0].Data.Passwords[0].Password)
+}
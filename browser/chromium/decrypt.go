@@ -12,6 +12,7 @@ import (
 //
 //   - v10 → masterKeys.V10 (Windows DPAPI / macOS Keychain / Linux peanuts kV10Key)
 //   - v11 → masterKeys.V11 (Linux keyring kV11Key; nil on Windows/macOS — Chromium doesn't emit v11 there)
+//   - v12 → masterKeys.V12 (Linux Flatpak secret-portal key; nil elsewhere)
 //   - v20 → masterKeys.V20 (Windows ABE; nil on non-Windows — Chromium doesn't emit v20 there)
 //
 // A single profile can carry mixed prefixes (Chrome 127+ upgrades on Windows; Linux session-mode
@@ -41,10 +42,9 @@ func decryptValue(masterKeys masterkey.MasterKeys, ciphertext []byte) ([]byte, e
 		// v20 is cross-platform AES-GCM (Chrome 127+ ABE); same wire layout as Windows v10.
 		return crypto.DecryptChromiumGCM(masterKeys.V20, ciphertext)
 	case crypto.CipherV12:
-		// Chromium's SecretPortalKeyProvider (Flatpak / xdg-desktop-portal) — HKDF-SHA256 +
-		// AES-256-GCM with a secret retrieved via org.freedesktop.portal.Desktop. Recognized here
-		// to surface an actionable "known gap" error rather than the generic "unsupported" one.
-		return nil, fmt.Errorf("unsupported cipher version v12 (Chromium SecretPortal / Flatpak; not yet implemented)")
+		// v12 is Linux-only AES-256-GCM (Flatpak SecretPortalKeyProvider); the V12 tier already holds
+		// the HKDF-derived key, so it decrypts exactly like v20.
+		return crypto.DecryptChromiumGCM(masterKeys.V12, ciphertext)
 	case crypto.CipherDPAPI:
 		return crypto.DecryptDPAPI(ciphertext)
 	default:
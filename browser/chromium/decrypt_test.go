@@ -109,3 +109,21 @@ func TestDecryptValue_V10_V11_SlotSeparation(t *testing.T) {
 		require.Error(t, err, "v11 with V10's key must fail")
 	})
 }
+
+// TestDecryptValue_V12 routes a Flatpak secret-portal ciphertext to the V12 slot, independent of
+// the CBC tiers a non-Flatpak session of the same browser may have left behind.
+func TestDecryptValue_V12(t *testing.T) {
+	key := crypto.DeriveSecretPortalKey([]byte("portal-secret"))
+	nonce := bytes.Repeat([]byte{0x12}, 12)
+	plaintext := []byte("password-from-flatpak")
+	sealed, err := crypto.AESGCMEncrypt(key, nonce, plaintext)
+	require.NoError(t, err)
+	v12Ciphertext := append([]byte("v12"), append(nonce, sealed...)...)
+
+	got, err := decryptValue(masterkey.MasterKeys{V10: testAESKey, V12: key}, v12Ciphertext)
+	require.NoError(t, err)
+	assert.Equal(t, plaintext, got)
+
+	_, err = decryptValue(masterkey.MasterKeys{V10: testAESKey}, v12Ciphertext)
+	require.Error(t, err, "v12 must not fall back to another tier")
+}
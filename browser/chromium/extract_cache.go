@@ -0,0 +1,218 @@
+package chromium
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/moond4rk/hackbrowserdata/log"
+	"github.com/moond4rk/hackbrowserdata/types"
+	"github.com/moond4rk/hackbrowserdata/utils/cacheutil"
+)
+
+// cacheRecord is one cache entry as the disk cache stores it: the key, stream 0 (the pickled
+// HttpResponseInfo) and the size of stream 1 (the body, still content-encoded). The body stays on
+// disk until readBody is called, which only works while the record is being visited, so a cache is
+// never held in memory whole. name is unique within the cache and names the carved body.
+type cacheRecord struct {
+	name     string
+	key      string
+	info     []byte
+	bodySize int64
+	readBody func() ([]byte, error) // nil when there is no body
+}
+
+// extractCache reads the HTTP disk cache at path — a Simple Cache or a blockfile cache, told apart by
+// the index file. When bodies is non-nil each non-empty body is read, decoded and saved through it,
+// one entry at a time.
+func extractCache(path string, bodies *cacheutil.BodyWriter) ([]types.CacheEntry, error) {
+	var entries []types.CacheEntry
+	err := readCache(path, func(r cacheRecord) {
+		entry := types.CacheEntry{URL: cacheKeyURL(r.key), Size: r.bodySize}
+		resp, ok := parseResponseInfo(r.info)
+		if ok {
+			entry.Status = resp.status
+			entry.ContentType = resp.header.Get("Content-Type")
+			entry.ResponseTime = timeEpoch(resp.responseTime)
+		}
+		if bodies != nil && r.bodySize > 0 {
+			entry.BodyFile = saveCacheBody(bodies, r, entry.ContentType, resp.header.Get("Content-Encoding"))
+		}
+		entries = append(entries, entry)
+	})
+	if len(entries) == 0 {
+		return nil, err
+	}
+	if err != nil {
+		log.Debugf("cache %s: %v", path, err)
+	}
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].ResponseTime.After(entries[j].ResponseTime)
+	})
+	return entries, nil
+}
+
+// saveCacheBody reads a record's body and saves it through bodies, returning the file it went to.
+func saveCacheBody(bodies *cacheutil.BodyWriter, r cacheRecord, contentType, encoding string) string {
+	body, err := r.readBody()
+	if err != nil {
+		log.Debugf("cache body %s: %v", r.key, err)
+		return ""
+	}
+	file, err := bodies.Write(r.name, contentType, encoding, body)
+	if err != nil {
+		log.Debugf("cache body %s: %v", r.key, err)
+	}
+	return file
+}
+
+func countCache(path string) (int, error) {
+	var count int
+	err := readCache(path, func(cacheRecord) { count++ })
+	if count == 0 {
+		return 0, err
+	}
+	return count, nil
+}
+
+// readCache dispatches on the cache's format: a blockfile cache's index starts with its magic number,
+// a Simple Cache is read from its entry files whatever state its index is in.
+func readCache(dir string, visit func(cacheRecord)) error {
+	index, err := os.ReadFile(filepath.Join(dir, "index"))
+	if err == nil && len(index) >= 4 && binary.LittleEndian.Uint32(index) == blockIndexMagic {
+		return readBlockfileCache(dir, index, visit)
+	}
+	return readSimpleCache(dir, visit)
+}
+
+// readSection reads all of s.
+func readSection(s *io.SectionReader) ([]byte, error) {
+	b := make([]byte, s.Size())
+	if _, err := io.ReadFull(s, b); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+// cacheKeyURL returns the URL a cache key names. With the cache partitioned by site the key carries a
+// "1/0/" prefix and the top-frame and frame sites before the URL ("_dk_<site> <site> <url>"); the URL
+// is the last field.
+func cacheKeyURL(key string) string {
+	if i := strings.LastIndexByte(key, ' '); i >= 0 {
+		return key[i+1:]
+	}
+	for {
+		prefix, rest, ok := strings.Cut(key, "/")
+		if !ok || prefix == "" || strings.Trim(prefix, "0123456789") != "" {
+			return key
+		}
+		key = rest
+	}
+}
+
+// HttpResponseInfo flags, see net/http/http_response_info.cc.
+const (
+	responseInfoVersionMask    = 0xff
+	responseInfoMinVersion     = 1
+	responseInfoMaxVersion     = 3
+	responseInfoHasExtraFlags  = 1 << 31
+	responseExtraOriginalTime  = 1 << 2
+	pickleHeaderSize           = 4 // payload size
+	responseHeadersMaxLen      = 1 << 20
+	responseHeadersStatusStart = "HTTP/"
+)
+
+// responseInfo is what extractCache uses of a cached HttpResponseInfo.
+type responseInfo struct {
+	status       int
+	header       http.Header
+	responseTime int64 // base::Time, μs since 1601
+}
+
+// parseResponseInfo decodes a pickled HttpResponseInfo: a flags word (version and feature bits),
+// extra flags when flagged, the request and response times, the original response time when flagged,
+// then the raw headers as a length-prefixed string whose lines end in NUL. Should a newer layout add
+// fields before the headers, the raw headers are found by their status line instead.
+func parseResponseInfo(b []byte) (responseInfo, bool) {
+	p := pickleReader{b: b, off: pickleHeaderSize}
+	flags, ok := p.uint32()
+	if !ok {
+		return responseInfo{}, false
+	}
+	version := flags & responseInfoVersionMask
+	if version < responseInfoMinVersion || version > responseInfoMaxVersion {
+		return responseInfo{}, false
+	}
+	var extra uint32
+	if flags&responseInfoHasExtraFlags != 0 {
+		extra, _ = p.uint32()
+	}
+	_, _ = p.int64() // request time
+	responseTime, ok := p.int64()
+	if !ok {
+		return responseInfo{}, false
+	}
+	if extra&responseExtraOriginalTime != 0 {
+		_, _ = p.int64()
+	}
+
+	raw, ok := p.string()
+	if !ok || !strings.HasPrefix(raw, responseHeadersStatusStart) {
+		i := bytes.Index(b, []byte(responseHeadersStatusStart))
+		if i < 0 {
+			return responseInfo{}, false
+		}
+		end := bytes.Index(b[i:], []byte{0, 0})
+		if end < 0 {
+			end = len(b) - i
+		}
+		raw = string(b[i : i+end])
+	}
+	status, header := cacheutil.ParseHeaders(raw)
+	return responseInfo{status: status, header: header, responseTime: responseTime}, true
+}
+
+// pickleReader reads a base::Pickle payload: little-endian values, each starting on a 4-byte boundary.
+type pickleReader struct {
+	b   []byte
+	off int
+}
+
+func (p *pickleReader) next(n int) ([]byte, bool) {
+	if n < 0 || p.off+n > len(p.b) {
+		return nil, false
+	}
+	v := p.b[p.off : p.off+n]
+	p.off += (n + 3) &^ 3
+	return v, true
+}
+
+func (p *pickleReader) uint32() (uint32, bool) {
+	v, ok := p.next(4)
+	if !ok {
+		return 0, false
+	}
+	return binary.LittleEndian.Uint32(v), true
+}
+
+func (p *pickleReader) int64() (int64, bool) {
+	v, ok := p.next(8)
+	if !ok {
+		return 0, false
+	}
+	return int64(binary.LittleEndian.Uint64(v)), true
+}
+
+func (p *pickleReader) string() (string, bool) {
+	n, ok := p.uint32()
+	if !ok || n > responseHeadersMaxLen {
+		return "", false
+	}
+	v, ok := p.next(int(n))
+	return string(v), ok
+}
@@ -0,0 +1,272 @@
+package chromium
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/moond4rk/hackbrowserdata/utils/cacheutil"
+)
+
+// cacheResponseTime is 2024-01-02 03:04:05 UTC as a Chromium base::Time.
+const cacheResponseTime = (1704164645 + 11644473600) * 1_000_000
+
+// testResponseInfo pickles an HttpResponseInfo as HttpResponseInfo::Persist does, with extra flags when
+// extra is set.
+func testResponseInfo(headers string, extra bool) []byte {
+	var p []byte
+	put32 := func(v uint32) { p = binary.LittleEndian.AppendUint32(p, v) }
+	flags := uint32(3) | 1<<8 // version 3, a certificate (not written here, it follows the headers)
+	if extra {
+		flags |= responseInfoHasExtraFlags
+	}
+	put32(flags)
+	if extra {
+		put32(responseExtraOriginalTime)
+	}
+	p = binary.LittleEndian.AppendUint64(p, cacheResponseTime-1000) // request time
+	p = binary.LittleEndian.AppendUint64(p, cacheResponseTime)
+	if extra {
+		p = binary.LittleEndian.AppendUint64(p, cacheResponseTime-5000)
+	}
+	put32(uint32(len(headers)))
+	p = append(p, headers...)
+	for len(p)%4 != 0 {
+		p = append(p, 0)
+	}
+	return append(binary.LittleEndian.AppendUint32(nil, uint32(len(p))), p...)
+}
+
+// testSimpleEntry lays out a Simple Cache "_0" entry file.
+func testSimpleEntry(key string, info, body []byte, keySHA bool) []byte {
+	le := binary.LittleEndian
+	f := le.AppendUint64(nil, simpleInitialMagic)
+	f = le.AppendUint32(f, 5)
+	f = le.AppendUint32(f, uint32(len(key)))
+	f = le.AppendUint32(f, 0)
+	f = le.AppendUint32(f, 0) // padding
+	f = append(f, key...)
+	f = append(f, body...)
+	eof := func(flags uint32, stream0Size int) {
+		f = le.AppendUint64(f, simpleFinalMagic)
+		f = le.AppendUint32(f, flags)
+		f = le.AppendUint32(f, 0)
+		f = le.AppendUint32(f, uint32(stream0Size))
+		f = le.AppendUint32(f, 0)
+	}
+	eof(0, 0)
+	f = append(f, info...)
+	var flags uint32
+	if keySHA {
+		f = append(f, bytes.Repeat([]byte{0xaa}, simpleKeySHA256Size)...)
+		flags = simpleEOFHasKeySHA256
+	}
+	eof(flags, len(info))
+	return f
+}
+
+func gzipped(t *testing.T, s string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	_, err := zw.Write([]byte(s))
+	require.NoError(t, err)
+	require.NoError(t, zw.Close())
+	return buf.Bytes()
+}
+
+const (
+	testHTMLHeaders = "HTTP/1.1 200 OK\x00content-type: text/html; charset=utf-8\x00content-encoding: gzip\x00\x00"
+	testPNGHeaders  = "HTTP/1.1 404 Not Found\x00Content-Type: image/png\x00\x00"
+)
+
+func createSimpleCache(t *testing.T, htmlBody []byte) string {
+	t.Helper()
+	dir := filepath.Join(t.TempDir(), "Cache_Data")
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "index-dir"), 0o755))
+	files := map[string][]byte{
+		"index": binary.LittleEndian.AppendUint64(nil, 0x656e74657220796f),
+		"00112233445566aa_0": testSimpleEntry("1/0/_dk_https://example.com https://example.com https://example.com/page",
+			testResponseInfo(testHTMLHeaders, true), htmlBody, true),
+		"00112233445566bb_0": testSimpleEntry("https://example.com/missing.png",
+			testResponseInfo(testPNGHeaders, false), nil, false),
+		"00112233445566bb_1": {0x01},        // stream 2, not read
+		"00112233445566cc_0": []byte("bad"), // skipped
+	}
+	for name, data := range files {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), data, 0o644))
+	}
+	return dir
+}
+
+// blockAddr builds a blockfile cache address for a block file.
+func blockAddr(fileType, file, start, blocks uint32) uint32 {
+	return addrInitialized | fileType<<28 | (blocks-1)<<24 | file<<16 | start
+}
+
+// createBlockfileCache lays out a blockfile cache: an index whose table points at two chained entries
+// in data_1, the first with its headers in data_1 and its body in an external f_ file, the second
+// with a long key in data_2.
+func createBlockfileCache(t *testing.T, htmlBody []byte) string {
+	t.Helper()
+	le := binary.LittleEndian
+	dir := filepath.Join(t.TempDir(), "Cache")
+	require.NoError(t, os.MkdirAll(dir, 0o755))
+
+	blockFile := func(blockSize, blocks int) []byte {
+		f := make([]byte, blockFileHeaderSize+blockSize*blocks)
+		le.PutUint32(f, blockFileMagic)
+		return f
+	}
+	data1 := blockFile(256, 16)
+	data2 := blockFile(1024, 4)
+
+	entry1 := blockAddr(2, 1, 1, 1)
+	entry2 := blockAddr(2, 1, 2, 1)
+	info1 := blockAddr(2, 1, 4, 2)
+	body1 := addrInitialized | uint32(5) // f_000005
+	longKey := blockAddr(3, 2, 0, 1)
+
+	writeEntry := func(addr uint32, next uint32, key string, long uint32, info uint32, infoLen int, body uint32, bodyLen int) {
+		e := data1[blockFileHeaderSize+int(addr&0xffff)*256:]
+		le.PutUint32(e[4:], next)
+		le.PutUint32(e[32:], uint32(len(key)))
+		le.PutUint32(e[36:], long)
+		if long == 0 {
+			copy(e[blockEntryKeyOffset:], key)
+		}
+		le.PutUint32(e[40:], uint32(infoLen))
+		le.PutUint32(e[44:], uint32(bodyLen))
+		le.PutUint32(e[56:], info)
+		le.PutUint32(e[60:], body)
+	}
+	infoBytes := testResponseInfo(testHTMLHeaders, false)
+	copy(data1[blockFileHeaderSize+4*256:], infoBytes)
+	writeEntry(entry1, entry2, "https://example.com/page", 0, info1, len(infoBytes), body1, len(htmlBody))
+
+	key2 := "https://example.com/" + string(bytes.Repeat([]byte("a"), 300)) + ".png"
+	copy(data2[blockFileHeaderSize:], key2)
+	writeEntry(entry2, 0, key2, longKey, 0, 0, 0, 0)
+
+	index := make([]byte, blockIndexHeaderSize+4*16)
+	le.PutUint32(index, blockIndexMagic)
+	le.PutUint32(index[4:], 0x20001)
+	le.PutUint32(index[28:], 16)
+	le.PutUint32(index[blockIndexHeaderSize+4*3:], entry1)
+	le.PutUint32(index[blockIndexHeaderSize+4*7:], entry1) // a loop back must not repeat the chain
+
+	for name, data := range map[string][]byte{
+		"index": index, "data_0": blockFile(36, 1), "data_1": data1, "data_2": data2, "f_000005": htmlBody,
+	} {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), data, 0o644))
+	}
+	return dir
+}
+
+func TestExtractCache(t *testing.T) {
+	html := "<html>cached</html>"
+	tests := []struct {
+		name   string
+		create func(*testing.T, []byte) string
+	}{
+		{"simple cache", createSimpleCache},
+		{"blockfile", createBlockfileCache},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := tt.create(t, gzipped(t, html))
+			bodyDir := filepath.Join(t.TempDir(), "bodies")
+
+			got, err := extractCache(dir, cacheutil.NewBodyWriter(bodyDir))
+			require.NoError(t, err)
+			require.Len(t, got, 2)
+
+			page := got[0]
+			assert.Equal(t, "https://example.com/page", page.URL)
+			assert.Equal(t, 200, page.Status)
+			assert.Equal(t, "text/html; charset=utf-8", page.ContentType)
+			assert.Equal(t, int64(len(gzipped(t, html))), page.Size, "the body as stored")
+			assert.Equal(t, "2024-01-02T03:04:05Z", page.ResponseTime.Format("2006-01-02T15:04:05Z"))
+			require.NotEmpty(t, page.BodyFile)
+			assert.Equal(t, ".html", filepath.Ext(page.BodyFile))
+			body, err := os.ReadFile(page.BodyFile)
+			require.NoError(t, err)
+			assert.Equal(t, html, string(body), "decoded")
+
+			other := got[1]
+			assert.Contains(t, other.URL, "https://example.com/")
+			assert.Empty(t, other.BodyFile, "no body to save")
+
+			count, err := countCache(dir)
+			require.NoError(t, err)
+			assert.Equal(t, 2, count)
+		})
+	}
+}
+
+func TestExtractCache_NoBodies(t *testing.T) {
+	got, err := extractCache(createSimpleCache(t, []byte("plain")), nil)
+	require.NoError(t, err)
+	require.Len(t, got, 2)
+	for _, e := range got {
+		assert.Empty(t, e.BodyFile)
+	}
+}
+
+func TestParseSimpleEntry(t *testing.T) {
+	info := testResponseInfo(testPNGHeaders, false)
+	entry := testSimpleEntry("https://example.com/a.png", info, []byte("body"), false)
+
+	r, err := parseSimpleEntry(bytes.NewReader(entry), int64(len(entry)))
+	require.NoError(t, err)
+	assert.Equal(t, "https://example.com/a.png", r.key)
+	assert.Equal(t, info, r.info)
+	assert.Equal(t, int64(4), r.bodySize)
+	body, err := r.readBody()
+	require.NoError(t, err)
+	assert.Equal(t, "body", string(body))
+
+	_, err = parseSimpleEntry(bytes.NewReader(entry), int64(len(entry))-1)
+	assert.Error(t, err, "cut short")
+	_, err = parseSimpleEntry(bytes.NewReader([]byte("short")), 5)
+	assert.Error(t, err)
+}
+
+func TestParseResponseInfo(t *testing.T) {
+	for _, extra := range []bool{false, true} {
+		resp, ok := parseResponseInfo(testResponseInfo(testPNGHeaders, extra))
+		require.True(t, ok)
+		assert.Equal(t, 404, resp.status)
+		assert.Equal(t, "image/png", resp.header.Get("Content-Type"))
+		assert.Equal(t, int64(cacheResponseTime), resp.responseTime)
+	}
+
+	// An unknown field before the headers: found by the status line instead.
+	info := testResponseInfo(testPNGHeaders, false)
+	shifted := append(append(append([]byte{}, info[:24]...), 0, 0, 0, 0), info[24:]...)
+	resp, ok := parseResponseInfo(shifted)
+	require.True(t, ok)
+	assert.Equal(t, 404, resp.status)
+
+	_, ok = parseResponseInfo([]byte{1, 2})
+	assert.False(t, ok)
+}
+
+func TestCacheKeyURL(t *testing.T) {
+	tests := []struct {
+		key, want string
+	}{
+		{"https://example.com/a", "https://example.com/a"},
+		{"1/0/https://example.com/a", "https://example.com/a"},
+		{"1/0/_dk_https://example.com https://example.com https://example.com/a", "https://example.com/a"},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, cacheKeyURL(tt.key))
+	}
+}
@@ -4,7 +4,9 @@ import (
 	"bytes"
 	"crypto/sha256"
 	"database/sql"
+	"fmt"
 	"sort"
+	"strings"
 
 	"github.com/moond4rk/hackbrowserdata/masterkey"
 	"github.com/moond4rk/hackbrowserdata/types"
@@ -37,17 +39,6 @@ func extractCookies(masterKeys masterkey.MasterKeys, path string) ([]types.Cooki
 
 			value, _ := decryptValue(masterKeys, encryptedValue)
 			value = stripCookieHash(value, host)
-			sameSiteStr := "unspecified"
-			switch sameSite {
-			case 0:
-				sameSiteStr = "none"
-			case 1:
-				sameSiteStr = "lax"
-			case 2:
-				sameSiteStr = "strict"
-			case -1:
-				// not specified by Set-Cookie
-			}
 			return types.CookieEntry{
 				Name:         name,
 				Host:         host,
@@ -59,7 +50,7 @@ func extractCookies(masterKeys masterkey.MasterKeys, path string) ([]types.Cooki
 				IsPersistent: isPersistent != 0,
 				ExpireAt:     timeEpoch(expireAt),
 				CreatedAt:    timeEpoch(createdAt),
-				SameSite:     sameSiteStr,
+				SameSite:     sameSiteName(sameSite),
 			}, nil
 		})
 	if err != nil {
@@ -72,6 +63,54 @@ func extractCookies(masterKeys masterkey.MasterKeys, path string) ([]types.Cooki
 	return cookies, nil
 }
 
+// recoverCookies carves deleted cookies out of the cookies table's free space (see sqliteutil.Carve)
+// and the row versions wal superseded (see sqliteutil.WAL), leaving out any that duplicate a live entry.
+func recoverCookies(
+	masterKeys masterkey.MasterKeys, path string, wal *sqliteutil.WAL, live []types.CookieEntry,
+) ([]types.CookieEntry, error) {
+	carved, err := sqliteutil.CarveRows(path, false, wal, "cookies", live,
+		func(r sqliteutil.Record) (types.CookieEntry, error) {
+			host := r.Text("host_key")
+			if host == "" || strings.ContainsAny(host, " /\x00") || r.Int("creation_utc") <= 0 {
+				return types.CookieEntry{}, fmt.Errorf("not a cookie: host %q", host)
+			}
+			value, _ := decryptValue(masterKeys, r.Blob("encrypted_value"))
+			value = stripCookieHash(value, host)
+			return types.CookieEntry{
+				Name:         r.Text("name"),
+				Host:         host,
+				Path:         r.Text("path"),
+				Value:        string(value),
+				IsSecure:     r.Int("is_secure") != 0,
+				IsHTTPOnly:   r.Int("is_httponly") != 0,
+				HasExpire:    r.Int("has_expires") != 0,
+				IsPersistent: r.Int("is_persistent") != 0,
+				ExpireAt:     timeEpoch(r.Int("expires_utc")),
+				CreatedAt:    timeEpoch(r.Int("creation_utc")),
+				SameSite:     sameSiteName(int(r.Int("samesite"))),
+			}, nil
+		})
+	cookies := make([]types.CookieEntry, 0, len(carved))
+	for _, c := range carved {
+		c.Item.Recovered, c.Item.RecoveredFrom = true, c.Origin
+		cookies = append(cookies, c.Item)
+	}
+	return cookies, err
+}
+
+// sameSiteName names a cookies.samesite value.
+func sameSiteName(sameSite int) string {
+	switch sameSite {
+	case 0:
+		return "none"
+	case 1:
+		return "lax"
+	case 2:
+		return "strict"
+	}
+	return "unspecified" // -1: not specified by Set-Cookie
+}
+
 func countCookies(path string) (int, error) {
 	return sqliteutil.CountRows(path, false, countCookieQuery)
 }
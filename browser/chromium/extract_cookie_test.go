@@ -41,6 +41,28 @@ func TestExtractCookies(t *testing.T) {
 	assert.True(t, got[1].IsHTTPOnly)
 }
 
+func TestRecoverCookies(t *testing.T) {
+	path := createTestDB(t, "Cookies", cookiesSchema,
+		insertCookie("session", ".old.com", "/", "", 13340000000000000, 13350000000000000, 1, 1),
+		insertCookie("tracker", ".ads.example", "/px", "", 13345000000000000, 13355000000000000, 0, 1),
+		`DELETE FROM cookies WHERE host_key = '.ads.example'`,
+	)
+	live, err := extractCookies(masterkey.MasterKeys{}, path)
+	require.NoError(t, err)
+
+	got, err := recoverCookies(masterkey.MasterKeys{}, path, nil, live)
+	require.NoError(t, err)
+	require.Len(t, got, 1)
+	assert.Equal(t, "tracker", got[0].Name)
+	assert.Equal(t, ".ads.example", got[0].Host)
+	assert.Equal(t, "/px", got[0].Path)
+	assert.False(t, got[0].IsSecure)
+	assert.True(t, got[0].IsHTTPOnly)
+	assert.Equal(t, "none", got[0].SameSite)
+	assert.Equal(t, timeEpoch(13345000000000000), got[0].CreatedAt)
+	assert.True(t, got[0].Recovered)
+}
+
 func TestCountCookies(t *testing.T) {
 	path := setupCookieDB(t)
 
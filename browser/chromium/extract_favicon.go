@@ -0,0 +1,112 @@
+package chromium
+
+import (
+	"database/sql"
+	"net/http"
+	"sort"
+	"strconv"
+
+	"github.com/moond4rk/hackbrowserdata/log"
+	"github.com/moond4rk/hackbrowserdata/types"
+	"github.com/moond4rk/hackbrowserdata/utils/cacheutil"
+	"github.com/moond4rk/hackbrowserdata/utils/sqliteutil"
+)
+
+// A page maps to an icon in icon_mapping; the icon keeps one bitmap per size in favicon_bitmaps.
+const (
+	defaultFaviconQuery = `SELECT icon_mapping.page_url, icon_mapping.icon_id, favicons.url,
+		COALESCE(favicon_bitmaps.id, 0), COALESCE(favicon_bitmaps.width, 0),
+		COALESCE(favicon_bitmaps.last_updated, 0), COALESCE(favicon_bitmaps.last_requested, 0),
+		favicon_bitmaps.image_data
+		FROM icon_mapping
+		JOIN favicons ON favicons.id = icon_mapping.icon_id
+		LEFT JOIN favicon_bitmaps ON favicon_bitmaps.icon_id = favicons.id`
+	countFaviconQuery = `SELECT COUNT(*) FROM (SELECT DISTINCT icon_mapping.page_url, icon_mapping.icon_id
+		FROM icon_mapping JOIN favicons ON favicons.id = icon_mapping.icon_id)`
+)
+
+// faviconRow is one bitmap of a page's icon.
+type faviconRow struct {
+	pageURL, iconURL string
+	iconID, bitmapID int64
+	width            int
+	lastUpdated      int64
+	lastRequested    int64
+	imageData        []byte
+}
+
+// extractFavicons reads the Favicons database, one entry per page and icon: the icon's bitmaps are
+// folded together, keeping their latest times. When images is non-nil the largest bitmap of each icon
+// is saved through it, named after the bitmap's row id.
+func extractFavicons(path string, images *cacheutil.BodyWriter) ([]types.FaviconEntry, error) {
+	rows, err := sqliteutil.QueryRows(path, false, defaultFaviconQuery,
+		func(rows *sql.Rows) (faviconRow, error) {
+			var r faviconRow
+			err := rows.Scan(&r.pageURL, &r.iconID, &r.iconURL, &r.bitmapID, &r.width,
+				&r.lastUpdated, &r.lastRequested, &r.imageData)
+			return r, err
+		})
+	if err != nil {
+		return nil, err
+	}
+
+	type key struct {
+		pageURL string
+		iconID  int64
+	}
+	var (
+		order   []key
+		folded  = make(map[key]*faviconRow)
+		largest = make(map[int64]*faviconRow) // by icon id
+	)
+	for i := range rows {
+		r := &rows[i]
+		k := key{r.pageURL, r.iconID}
+		f, ok := folded[k]
+		if !ok {
+			order = append(order, k)
+			f = &faviconRow{pageURL: r.pageURL, iconURL: r.iconURL, iconID: r.iconID}
+			folded[k] = f
+		}
+		if r.lastUpdated > f.lastUpdated {
+			f.lastUpdated = r.lastUpdated
+		}
+		if r.lastRequested > f.lastRequested {
+			f.lastRequested = r.lastRequested
+		}
+		if l, ok := largest[r.iconID]; len(r.imageData) > 0 && (!ok || r.width > l.width) {
+			largest[r.iconID] = r
+		}
+	}
+
+	files := make(map[int64]string)
+	if images != nil {
+		for id, r := range largest {
+			file, err := images.Write(strconv.FormatInt(r.bitmapID, 10), http.DetectContentType(r.imageData), "", r.imageData)
+			if err != nil {
+				log.Debugf("favicon image %s: %v", r.iconURL, err)
+			}
+			files[id] = file
+		}
+	}
+
+	favicons := make([]types.FaviconEntry, 0, len(order))
+	for _, k := range order {
+		f := folded[k]
+		favicons = append(favicons, types.FaviconEntry{
+			PageURL:       f.pageURL,
+			IconURL:       f.iconURL,
+			LastUpdated:   timeEpoch(f.lastUpdated),
+			LastRequested: timeEpoch(f.lastRequested),
+			IconFile:      files[f.iconID],
+		})
+	}
+	sort.SliceStable(favicons, func(i, j int) bool {
+		return favicons[i].LastUpdated.After(favicons[j].LastUpdated)
+	})
+	return favicons, nil
+}
+
+func countFavicons(path string) (int, error) {
+	return sqliteutil.CountRows(path, false, countFaviconQuery)
+}
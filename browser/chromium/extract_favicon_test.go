@@ -0,0 +1,71 @@
+package chromium
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/moond4rk/hackbrowserdata/utils/cacheutil"
+)
+
+const (
+	testPNGHex = "89504e470d0a1a0a0000000d49484452"
+	testICOHex = "0000010001001010000001002000"
+)
+
+func setupFaviconsDB(t *testing.T) string {
+	t.Helper()
+	return createTestDB(t, "Favicons", faviconsSchema,
+		insertFavicon(1, "https://example.com/favicon.ico"),
+		insertFavicon(2, "https://go.dev/favicon.png"),
+		insertFaviconBitmap(10, 1, 16, 13350000000000000, 13370000000000000, testICOHex),
+		insertFaviconBitmap(11, 1, 32, 13360000000000000, 0, testPNGHex),
+		insertIconMapping("https://example.com/a", 1),
+		insertIconMapping("https://example.com/b", 1),
+		insertIconMapping("https://go.dev/", 2),
+	)
+}
+
+func TestExtractFavicons(t *testing.T) {
+	path := setupFaviconsDB(t)
+	imageDir := filepath.Join(t.TempDir(), "favicons")
+
+	got, err := extractFavicons(path, cacheutil.NewBodyWriter(imageDir))
+	require.NoError(t, err)
+	require.Len(t, got, 3)
+
+	for _, e := range got[:2] {
+		assert.Equal(t, "https://example.com/favicon.ico", e.IconURL)
+		assert.Equal(t, timeEpoch(13360000000000000), e.LastUpdated, "the latest of the bitmaps")
+		assert.Equal(t, timeEpoch(13370000000000000), e.LastRequested)
+		assert.Equal(t, filepath.Join(imageDir, "11.png"), e.IconFile, "the largest bitmap")
+	}
+	assert.ElementsMatch(t, []string{"https://example.com/a", "https://example.com/b"}, []string{got[0].PageURL, got[1].PageURL})
+	data, err := os.ReadFile(got[0].IconFile)
+	require.NoError(t, err)
+	assert.Equal(t, "\x89PNG\r\n\x1a\n", string(data[:8]))
+
+	goDev := got[2]
+	assert.Equal(t, "https://go.dev/", goDev.PageURL)
+	assert.Equal(t, "https://go.dev/favicon.png", goDev.IconURL)
+	assert.True(t, goDev.LastUpdated.IsZero(), "no bitmap stored")
+	assert.Empty(t, goDev.IconFile)
+}
+
+func TestExtractFavicons_NoImages(t *testing.T) {
+	got, err := extractFavicons(setupFaviconsDB(t), nil)
+	require.NoError(t, err)
+	require.Len(t, got, 3)
+	for _, e := range got {
+		assert.Empty(t, e.IconFile)
+	}
+}
+
+func TestCountFavicons(t *testing.T) {
+	count, err := countFavicons(setupFaviconsDB(t))
+	require.NoError(t, err)
+	assert.Equal(t, 3, count)
+}
@@ -1,6 +1,7 @@
 package chromium
 
 import (
+	"sort"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -34,6 +35,32 @@ func TestExtractHistories(t *testing.T) {
 	assert.False(t, got[0].LastVisit.IsZero())
 }
 
+func TestRecoverHistories(t *testing.T) {
+	path := createTestDB(t, "History", urlsSchema,
+		insertURL("https://github.com", "GitHub", 100, 13370000000000000),
+		insertURL("https://cleared.example/a", "Cleared A", 3, 13360000000000000),
+		insertURL("https://go.dev", "Go Dev", 50, 13360000000000000),
+		insertURL("https://cleared.example/b", "Cleared B", 7, 13350000000000000),
+		`DELETE FROM urls WHERE url LIKE 'https://cleared.example/%'`,
+	)
+	live, err := extractHistories(path)
+	require.NoError(t, err)
+	require.Len(t, live, 2)
+
+	got, err := recoverHistories(path, nil, live)
+	require.NoError(t, err)
+	require.Len(t, got, 2)
+	sort.Slice(got, func(i, j int) bool { return got[i].URL < got[j].URL })
+	assert.Equal(t, "https://cleared.example/a", got[0].URL)
+	assert.Equal(t, "Cleared A", got[0].Title)
+	assert.Equal(t, 3, got[0].VisitCount)
+	assert.Equal(t, timeEpoch(13360000000000000), got[0].LastVisit)
+	assert.Equal(t, "https://cleared.example/b", got[1].URL)
+	for _, h := range got {
+		assert.True(t, h.Recovered)
+	}
+}
+
 func TestCountHistories(t *testing.T) {
 	path := setupHistoryDB(t)
 
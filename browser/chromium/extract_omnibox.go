@@ -0,0 +1,128 @@
+package chromium
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/moond4rk/hackbrowserdata/types"
+	"github.com/moond4rk/hackbrowserdata/utils/sqliteutil"
+)
+
+// The databases the omnibox learns from, read together as the Omnibox group.
+const (
+	omniboxShortcuts = "Shortcuts"
+	omniboxTopSites  = "Top Sites"
+	omniboxPredictor = "Network Action Predictor"
+)
+
+const (
+	defaultShortcutsQuery = `SELECT COALESCE(text, ''), COALESCE(fill_into_edit, ''), COALESCE(url, ''),
+		COALESCE(description, ''), last_access_time, number_of_hits FROM omni_box_shortcuts`
+	defaultTopSitesQuery  = `SELECT url, url_rank, COALESCE(title, '') FROM top_sites`
+	defaultPredictorQuery = `SELECT user_text, url, number_of_hits, number_of_misses FROM network_action_predictor`
+	countShortcutsQuery   = `SELECT COUNT(*) FROM omni_box_shortcuts`
+	countTopSitesQuery    = `SELECT COUNT(*) FROM top_sites`
+	countPredictorQuery   = `SELECT COUNT(*) FROM network_action_predictor`
+)
+
+// extractOmnibox reads the omnibox databases copied into dir — shortcuts by last access, then top sites
+// by rank, then predictor entries by hits. A missing database is skipped; one that fails to read is
+// reported while the others are still returned.
+func extractOmnibox(dir string) ([]types.OmniboxEntry, error) {
+	var (
+		entries []types.OmniboxEntry
+		errs    []error
+	)
+	for _, read := range []struct {
+		name string
+		fn   func(string) ([]types.OmniboxEntry, error)
+	}{
+		{omniboxShortcuts, extractShortcuts},
+		{omniboxTopSites, extractTopSites},
+		{omniboxPredictor, extractPredictor},
+	} {
+		path := filepath.Join(dir, read.name)
+		if _, err := os.Stat(path); err != nil {
+			continue
+		}
+		got, err := read.fn(path)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", read.name, err))
+			continue
+		}
+		entries = append(entries, got...)
+	}
+	return entries, errors.Join(errs...)
+}
+
+func extractShortcuts(path string) ([]types.OmniboxEntry, error) {
+	shortcuts, err := sqliteutil.QueryRows(path, false, defaultShortcutsQuery,
+		func(rows *sql.Rows) (types.OmniboxEntry, error) {
+			e := types.OmniboxEntry{Source: "shortcuts"}
+			var lastAccess int64
+			if err := rows.Scan(&e.Text, &e.FillIntoEdit, &e.URL, &e.Title, &lastAccess, &e.Hits); err != nil {
+				return types.OmniboxEntry{}, err
+			}
+			e.LastAccess = timeEpoch(lastAccess)
+			return e, nil
+		})
+	sort.SliceStable(shortcuts, func(i, j int) bool {
+		return shortcuts[i].LastAccess.After(shortcuts[j].LastAccess)
+	})
+	return shortcuts, err
+}
+
+func extractTopSites(path string) ([]types.OmniboxEntry, error) {
+	sites, err := sqliteutil.QueryRows(path, false, defaultTopSitesQuery,
+		func(rows *sql.Rows) (types.OmniboxEntry, error) {
+			e := types.OmniboxEntry{Source: "top_sites"}
+			err := rows.Scan(&e.URL, &e.Rank, &e.Title)
+			return e, err
+		})
+	sort.SliceStable(sites, func(i, j int) bool {
+		return sites[i].Rank < sites[j].Rank
+	})
+	return sites, err
+}
+
+func extractPredictor(path string) ([]types.OmniboxEntry, error) {
+	predictions, err := sqliteutil.QueryRows(path, false, defaultPredictorQuery,
+		func(rows *sql.Rows) (types.OmniboxEntry, error) {
+			e := types.OmniboxEntry{Source: "network_action_predictor"}
+			err := rows.Scan(&e.Text, &e.URL, &e.Hits, &e.Misses)
+			return e, err
+		})
+	sort.SliceStable(predictions, func(i, j int) bool {
+		return predictions[i].Hits > predictions[j].Hits
+	})
+	return predictions, err
+}
+
+// countOmnibox sums the rows of the omnibox databases copied into dir.
+func countOmnibox(dir string) (int, error) {
+	var (
+		total int
+		errs  []error
+	)
+	for _, c := range []struct{ name, query string }{
+		{omniboxShortcuts, countShortcutsQuery},
+		{omniboxTopSites, countTopSitesQuery},
+		{omniboxPredictor, countPredictorQuery},
+	} {
+		path := filepath.Join(dir, c.name)
+		if _, err := os.Stat(path); err != nil {
+			continue
+		}
+		n, err := sqliteutil.CountRows(path, false, c.query)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", c.name, err))
+			continue
+		}
+		total += n
+	}
+	return total, errors.Join(errs...)
+}
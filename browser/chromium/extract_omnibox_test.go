@@ -0,0 +1,128 @@
+package chromium
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/moond4rk/hackbrowserdata/filemanager"
+	"github.com/moond4rk/hackbrowserdata/types"
+)
+
+// setupOmniboxDir lays out a profile dir with the omnibox databases; a nil names installs all three.
+func setupOmniboxDir(t *testing.T, names ...string) string {
+	t.Helper()
+	dbs := map[string]string{
+		omniboxShortcuts: createTestDB(t, omniboxShortcuts, shortcutsSchema,
+			insertShortcut("gith", "github.com", "https://github.com/", "GitHub", 13370000000000000, 12),
+			insertShortcut("go", "go.dev", "https://go.dev/", "Go", 13360000000000000, 3),
+		),
+		omniboxTopSites: createTestDB(t, omniboxTopSites, topSitesSchema,
+			insertTopSite("https://example.com/", 1, "Example"),
+			insertTopSite("https://github.com/", 0, "GitHub"),
+		),
+		omniboxPredictor: createTestDB(t, omniboxPredictor, networkActionPredictorSchema,
+			insertPrediction("ex", "https://example.com/", 2, 1),
+			insertPrediction("secret", "https://secret.example/", 9, 0),
+		),
+	}
+	if len(names) == 0 {
+		names = []string{omniboxShortcuts, omniboxTopSites, omniboxPredictor}
+	}
+	dir := t.TempDir()
+	for _, name := range names {
+		installFile(t, dir, dbs[name], name)
+	}
+	return dir
+}
+
+func TestExtractOmnibox(t *testing.T) {
+	got, err := extractOmnibox(setupOmniboxDir(t))
+	require.NoError(t, err)
+	require.Len(t, got, 6)
+
+	var sources []string
+	for _, e := range got {
+		sources = append(sources, e.Source)
+	}
+	assert.Equal(t, []string{
+		"shortcuts", "shortcuts", "top_sites", "top_sites", "network_action_predictor", "network_action_predictor",
+	}, sources)
+
+	shortcut := got[0]
+	assert.Equal(t, "gith", shortcut.Text)
+	assert.Equal(t, "github.com", shortcut.FillIntoEdit)
+	assert.Equal(t, "https://github.com/", shortcut.URL)
+	assert.Equal(t, "GitHub", shortcut.Title)
+	assert.Equal(t, 12, shortcut.Hits)
+	assert.Equal(t, timeEpoch(13370000000000000), shortcut.LastAccess)
+
+	assert.Equal(t, "https://github.com/", got[2].URL, "top sites by rank")
+	assert.Equal(t, 0, got[2].Rank)
+	assert.Equal(t, "Example", got[3].Title)
+
+	prediction := got[4]
+	assert.Equal(t, "secret", prediction.Text, "predictions by hits")
+	assert.Equal(t, "https://secret.example/", prediction.URL)
+	assert.Equal(t, 9, prediction.Hits)
+	assert.Equal(t, 1, got[5].Misses)
+
+	count, err := countOmnibox(setupOmniboxDir(t))
+	require.NoError(t, err)
+	assert.Equal(t, 6, count)
+}
+
+func TestExtractOmnibox_Partial(t *testing.T) {
+	dir := setupOmniboxDir(t, omniboxPredictor)
+	require.NoError(t, os.WriteFile(filepath.Join(dir, omniboxTopSites), []byte("not a database"), 0o644))
+
+	got, err := extractOmnibox(dir)
+	require.Error(t, err, "the unreadable Top Sites")
+	require.Len(t, got, 2, "the predictor entries still returned")
+	for _, e := range got {
+		assert.Equal(t, "network_action_predictor", e.Source)
+	}
+
+	count, err := countOmnibox(setupOmniboxDir(t, omniboxTopSites))
+	require.NoError(t, err)
+	assert.Equal(t, 2, count)
+}
+
+func TestOmniboxGroup(t *testing.T) {
+	udd := t.TempDir()
+	profileDir := filepath.Join(udd, "Default")
+	require.NoError(t, os.MkdirAll(profileDir, 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(profileDir, "Preferences"), []byte("{}"), 0o644))
+	src := setupOmniboxDir(t, omniboxShortcuts, omniboxPredictor)
+	for _, name := range []string{omniboxShortcuts, omniboxPredictor} {
+		installFile(t, profileDir, filepath.Join(src, name), name)
+	}
+
+	resolved := resolveSourcePaths(chromiumSources, profileDir)
+	rp, ok := resolved[types.Omnibox]
+	require.True(t, ok)
+	assert.Equal(t, []string{omniboxShortcuts, omniboxPredictor}, rp.members, "only the files present")
+
+	session, err := filemanager.NewSession()
+	require.NoError(t, err)
+	defer session.Cleanup()
+	p := &profile{profileDir: profileDir, sourcePaths: resolved}
+	paths := p.acquireFiles(session, []types.Category{types.Omnibox})
+	require.Contains(t, paths, types.Omnibox)
+	assert.Equal(t, 4, p.countCategory(types.Omnibox, paths[types.Omnibox]))
+
+	b, err := NewBrowser(types.BrowserConfig{Key: "chrome", Name: "Chrome", Kind: types.Chromium, UserDataDir: udd})
+	require.NoError(t, err)
+	var layout []string
+	for _, s := range b.ArchiveSources([]types.Category{types.Omnibox}) {
+		layout = append(layout, s.LayoutRel)
+	}
+	assert.ElementsMatch(t, []string{"Default/Preferences", "Default/Shortcuts", "Default/Network Action Predictor"}, layout)
+
+	empty := t.TempDir()
+	assert.NotContains(t, resolveSourcePaths(chromiumSources, empty), types.Omnibox)
+	assert.False(t, hasAnySource(chromiumSources, empty), "a group does not match its bare directory")
+}
@@ -3,7 +3,12 @@ package chromium
 import (
 	"database/sql"
 	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
 	"sort"
+	"strconv"
+	"strings"
 
 	"github.com/moond4rk/hackbrowserdata/crypto"
 	"github.com/moond4rk/hackbrowserdata/log"
@@ -12,43 +17,178 @@ import (
 	"github.com/moond4rk/hackbrowserdata/utils/sqliteutil"
 )
 
+// The login databases read together as the Password group: the profile's own, and the one Chrome
+// keeps for passwords a signed-in user saves to the Google account only.
 const (
-	defaultLoginQuery = `SELECT origin_url, username_value, password_value, date_created FROM logins`
-	countLoginQuery   = `SELECT COUNT(*) FROM logins`
+	loginDataFile        = "Login Data"
+	accountLoginDataFile = "Login Data For Account"
+)
+
+// loginStores names the store of each login database, as LoginEntry.Store reports it.
+var loginStores = []struct{ file, store string }{
+	{loginDataFile, "profile"},
+	{accountLoginDataFile, "account"},
+}
+
+const (
+	defaultLoginQuery  = `SELECT * FROM logins`
+	countLoginQuery    = `SELECT COUNT(*) FROM logins`
+	loginNotesQuery    = `SELECT parent_id, value FROM password_notes ORDER BY id`
+	insecureLoginQuery = `SELECT parent_id, insecurity_type FROM insecure_credentials ORDER BY insecurity_type`
 
 	yandexLoginQuery = `SELECT origin_url, username_element, username_value,
 		password_element, password_value, signon_realm, date_created FROM logins`
 )
 
-func extractPasswords(masterKeys masterkey.MasterKeys, path string) ([]types.LoginEntry, error) {
-	return extractPasswordsWithQuery(masterKeys, path, defaultLoginQuery)
+// insecurityTypes names the insecure_credentials.insecurity_type values (password_manager::InsecureType).
+var insecurityTypes = map[int64]string{0: "leaked", 1: "phished", 2: "weak", 3: "reused"}
+
+// extractPasswords reads the login databases copied into dir, newest first. A missing database is
+// skipped; one that fails to read is reported while the other's logins are still returned.
+func extractPasswords(masterKeys masterkey.MasterKeys, dir string) ([]types.LoginEntry, error) {
+	var (
+		logins []types.LoginEntry
+		errs   []error
+	)
+	for _, s := range loginStores {
+		path := filepath.Join(dir, s.file)
+		if _, err := os.Stat(path); err != nil {
+			continue
+		}
+		got, err := extractLogins(masterKeys, path, s.store)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", s.file, err))
+			continue
+		}
+		logins = append(logins, got...)
+	}
+
+	sort.SliceStable(logins, func(i, j int) bool {
+		return logins[i].CreatedAt.After(logins[j].CreatedAt)
+	})
+	return logins, errors.Join(errs...)
 }
 
-func extractPasswordsWithQuery(masterKeys masterkey.MasterKeys, path, query string) ([]types.LoginEntry, error) {
-	logins, err := sqliteutil.QueryRows(path, false, query,
-		func(rows *sql.Rows) (types.LoginEntry, error) {
-			var url, username string
-			var pwd []byte
-			var created int64
-			if err := rows.Scan(&url, &username, &pwd, &created); err != nil {
-				return types.LoginEntry{}, err
-			}
-			password, _ := decryptValue(masterKeys, pwd)
-			return types.LoginEntry{
-				URL:       url,
-				Username:  username,
-				Password:  string(password),
-				CreatedAt: timeEpoch(created),
-			}, nil
-		})
+// extractLogins reads one login database. Rows are read by column name, so a database from before a
+// column was added still reads; notes and insecure-credential flags come from tables of their own,
+// which older databases lack.
+func extractLogins(masterKeys masterkey.MasterKeys, path, store string) ([]types.LoginEntry, error) {
+	records, err := sqliteutil.QueryRows(path, false, defaultLoginQuery, sqliteutil.ScanRecord)
 	if err != nil {
 		return nil, err
 	}
+	notes := loginNotes(masterKeys, path)
+	insecure := insecureLogins(path)
+	logins := make([]types.LoginEntry, 0, len(records))
+	for _, r := range records {
+		login := loginFromRecord(masterKeys, r, store)
+		id := r.Int("id")
+		login.Note = strings.Join(notes[id], "\n")
+		login.Insecure = strings.Join(insecure[id], ",")
+		logins = append(logins, login)
+	}
+	return logins, nil
+}
 
-	sort.Slice(logins, func(i, j int) bool {
-		return logins[i].CreatedAt.After(logins[j].CreatedAt)
+// loginFromRecord converts a logins row, live or carved, decrypting its password.
+func loginFromRecord(masterKeys masterkey.MasterKeys, r sqliteutil.Record, store string) types.LoginEntry {
+	password, _ := decryptValue(masterKeys, r.Blob("password_value"))
+	return types.LoginEntry{
+		URL:               r.Text("origin_url"),
+		Username:          r.Text("username_value"),
+		Password:          string(password),
+		CreatedAt:         timeEpoch(r.Int("date_created")),
+		ActionURL:         r.Text("action_url"),
+		SignonRealm:       r.Text("signon_realm"),
+		LastUsedAt:        timeEpoch(r.Int("date_last_used")),
+		PasswordChangedAt: timeEpoch(r.Int("date_password_modified")),
+		TimesUsed:         int(r.Int("times_used")),
+		BlockedByUser:     r.Int("blacklisted_by_user") != 0,
+		Store:             store,
+	}
+}
+
+// loginNotes decrypts the notes attached to logins, by login id.
+func loginNotes(masterKeys masterkey.MasterKeys, path string) map[int64][]string {
+	notes := make(map[int64][]string)
+	err := sqliteutil.QuerySQLite(path, false, loginNotesQuery, func(rows *sql.Rows) error {
+		var parent int64
+		var value []byte
+		if err := rows.Scan(&parent, &value); err != nil {
+			return err
+		}
+		if note, _ := decryptValue(masterKeys, value); len(note) > 0 {
+			notes[parent] = append(notes[parent], string(note))
+		}
+		return nil
 	})
-	return logins, nil
+	if err != nil {
+		log.Debugf("password notes %s: %v", path, err)
+	}
+	return notes
+}
+
+// insecureLogins names the problems the password checkup found with each login, by login id.
+func insecureLogins(path string) map[int64][]string {
+	insecure := make(map[int64][]string)
+	err := sqliteutil.QuerySQLite(path, false, insecureLoginQuery, func(rows *sql.Rows) error {
+		var parent, typ int64
+		if err := rows.Scan(&parent, &typ); err != nil {
+			return err
+		}
+		name, ok := insecurityTypes[typ]
+		if !ok {
+			name = strconv.FormatInt(typ, 10)
+		}
+		insecure[parent] = append(insecure[parent], name)
+		return nil
+	})
+	if err != nil {
+		log.Debugf("insecure credentials %s: %v", path, err)
+	}
+	return insecure
+}
+
+// recoverPasswords carves deleted logins out of the logins table's free space (see sqliteutil.Carve)
+// of each login database copied into dir, and the row versions its log in wals superseded (see
+// sqliteutil.WAL), leaving out any that duplicate a live entry. Their passwords decrypt like live ones.
+func recoverPasswords(
+	masterKeys masterkey.MasterKeys, dir string, wals map[string]*sqliteutil.WAL, live []types.LoginEntry,
+) ([]types.LoginEntry, error) {
+	var (
+		logins []types.LoginEntry
+		errs   []error
+	)
+	for _, s := range loginStores {
+		path := filepath.Join(dir, s.file)
+		if _, err := os.Stat(path); err != nil {
+			continue
+		}
+		// A carved row has no notes or flags, which live in other tables: compare without them.
+		var storeLive []types.LoginEntry
+		for _, l := range live {
+			if l.Store == s.store {
+				l.Note, l.Insecure = "", ""
+				storeLive = append(storeLive, l)
+			}
+		}
+		store := s.store
+		carved, err := sqliteutil.CarveRows(path, false, wals[path], "logins", storeLive,
+			func(r sqliteutil.Record) (types.LoginEntry, error) {
+				if url := r.Text("origin_url"); !sqliteutil.LooksLikeURL(url) {
+					return types.LoginEntry{}, fmt.Errorf("not a url: %q", url)
+				}
+				return loginFromRecord(masterKeys, r, store), nil
+			})
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", s.file, err))
+		}
+		for _, c := range carved {
+			c.Item.Recovered, c.Item.RecoveredFrom = true, c.Origin
+			logins = append(logins, c.Item)
+		}
+	}
+	return logins, errors.Join(errs...)
 }
 
 // extractYandexPasswords walks Ya Passman Data.
@@ -95,6 +235,27 @@ func extractYandexPasswords(masterKeys masterkey.MasterKeys, path string) ([]typ
 	return logins, nil
 }
 
-func countPasswords(path string) (int, error) {
+func countYandexPasswords(path string) (int, error) {
 	return sqliteutil.CountRows(path, false, countLoginQuery)
 }
+
+// countPasswords sums the logins of the login databases copied into dir.
+func countPasswords(dir string) (int, error) {
+	var (
+		total int
+		errs  []error
+	)
+	for _, s := range loginStores {
+		path := filepath.Join(dir, s.file)
+		if _, err := os.Stat(path); err != nil {
+			continue
+		}
+		n, err := sqliteutil.CountRows(path, false, countLoginQuery)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", s.file, err))
+			continue
+		}
+		total += n
+	}
+	return total, errors.Join(errs...)
+}
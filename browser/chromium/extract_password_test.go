@@ -3,6 +3,7 @@ package chromium
 import (
 	"bytes"
 	"crypto/sha1"
+	"encoding/hex"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -11,18 +12,25 @@ import (
 	"github.com/moond4rk/hackbrowserdata/masterkey"
 )
 
-func setupLoginDB(t *testing.T) string {
+// setupLoginDB lays out the login databases in a directory, as the Password group is acquired: Login
+// Data, and Login Data For Account when account is set.
+func setupLoginDB(t *testing.T, account ...string) string {
 	t.Helper()
-	return createTestDB(t, "Login Data", loginsSchema,
+	dir := t.TempDir()
+	installFile(t, dir, createTestDB(t, loginDataFile, loginsSchema,
 		insertLogin("https://old.com", "https://old.com/login", "alice", "", 13340000000000000),
 		insertLogin("https://new.com", "https://new.com/login", "bob", "", 13360000000000000),
-	)
+	), loginDataFile)
+	if len(account) > 0 {
+		installFile(t, dir, createTestDB(t, accountLoginDataFile, loginsSchema, account...), accountLoginDataFile)
+	}
+	return dir
 }
 
 func TestExtractPasswords(t *testing.T) {
-	path := setupLoginDB(t)
+	dir := setupLoginDB(t)
 
-	got, err := extractPasswords(masterkey.MasterKeys{}, path)
+	got, err := extractPasswords(masterkey.MasterKeys{}, dir)
 	require.NoError(t, err)
 	require.Len(t, got, 2)
 
@@ -33,22 +41,107 @@ func TestExtractPasswords(t *testing.T) {
 	// Verify field mapping
 	assert.Equal(t, "bob", got[0].Username)
 	assert.False(t, got[0].CreatedAt.IsZero())
+	assert.Equal(t, "https://new.com/login", got[0].ActionURL)
+	assert.Equal(t, "https://new.com", got[0].SignonRealm)
+	assert.Equal(t, "profile", got[0].Store)
 	// Password is empty because masterKey is nil (decrypt returns empty)
 	assert.Empty(t, got[0].Password)
 }
 
+func TestExtractPasswords_Metadata(t *testing.T) {
+	note := hex.EncodeToString(sealChromiumCBC(t, testAESKey, "recovery codes in the safe"))
+	dir := t.TempDir()
+	installFile(t, dir, createTestDB(t, loginDataFile,
+		loginsSchema+";"+passwordNotesSchema+";"+insecureCredentialsSchema,
+		insertLoginMetadata("https://bank.example", "https://bank.example/auth", "alice",
+			13340000000000000, 13360000000000000, 13350000000000000, 7, 0),
+		insertLoginMetadata("https://never.example", "", "", 13330000000000000, 0, 0, 0, 1),
+		insertPasswordNote(1, note),
+		insertInsecureCredential(1, 2),
+		insertInsecureCredential(1, 0),
+	), loginDataFile)
+	installFile(t, dir, createTestDB(t, accountLoginDataFile, loginsSchema,
+		insertLogin("https://synced.example", "", "carol", "", 13370000000000000),
+	), accountLoginDataFile)
+
+	got, err := extractPasswords(masterkey.MasterKeys{V10: testAESKey}, dir)
+	require.NoError(t, err)
+	require.Len(t, got, 3)
+
+	account := got[0]
+	assert.Equal(t, "https://synced.example", account.URL)
+	assert.Equal(t, "account", account.Store)
+
+	bank := got[1]
+	assert.Equal(t, "profile", bank.Store)
+	assert.Equal(t, "https://bank.example/auth", bank.ActionURL)
+	assert.Equal(t, timeEpoch(13360000000000000), bank.LastUsedAt)
+	assert.Equal(t, timeEpoch(13350000000000000), bank.PasswordChangedAt)
+	assert.Equal(t, 7, bank.TimesUsed)
+	assert.False(t, bank.BlockedByUser)
+	assert.Equal(t, "recovery codes in the safe", bank.Note)
+	assert.Equal(t, "leaked,weak", bank.Insecure)
+
+	never := got[2]
+	assert.True(t, never.BlockedByUser)
+	assert.True(t, never.LastUsedAt.IsZero())
+	assert.Empty(t, never.Note)
+	assert.Empty(t, never.Insecure)
+}
+
+func TestExtractPasswords_OldSchema(t *testing.T) {
+	dir := t.TempDir()
+	installFile(t, dir, createTestDB(t, loginDataFile, `CREATE TABLE logins (
+		origin_url VARCHAR NOT NULL,
+		username_value VARCHAR,
+		password_value BLOB,
+		signon_realm VARCHAR NOT NULL,
+		date_created INTEGER NOT NULL
+	)`,
+		`INSERT INTO logins VALUES ('https://a.com', 'alice', x'', 'https://a.com', 13340000000000000)`,
+	), loginDataFile)
+
+	got, err := extractPasswords(masterkey.MasterKeys{}, dir)
+	require.NoError(t, err)
+	require.Len(t, got, 1)
+	assert.Equal(t, "alice", got[0].Username)
+	assert.Zero(t, got[0].TimesUsed, "a column the database predates")
+}
+
+func TestRecoverPasswords(t *testing.T) {
+	dir := setupLoginDB(t,
+		insertLogin("https://old.com", "https://old.com/login", "alice", "", 13340000000000000),
+		insertLogin("https://gone.example", "https://gone.example/login", "carol", "", 13350000000000000),
+		`DELETE FROM logins WHERE username_value = 'carol'`,
+	)
+	live, err := extractPasswords(masterkey.MasterKeys{}, dir)
+	require.NoError(t, err)
+	require.Len(t, live, 3)
+
+	got, err := recoverPasswords(masterkey.MasterKeys{}, dir, nil, live)
+	require.NoError(t, err)
+	require.Len(t, got, 1, "the live account login is not recovered as the profile's")
+	assert.Equal(t, "https://gone.example", got[0].URL)
+	assert.Equal(t, "carol", got[0].Username)
+	assert.Equal(t, "https://gone.example/login", got[0].ActionURL)
+	assert.Equal(t, timeEpoch(13350000000000000), got[0].CreatedAt)
+	assert.Equal(t, "account", got[0].Store)
+	assert.True(t, got[0].Recovered)
+}
+
 func TestCountPasswords(t *testing.T) {
-	path := setupLoginDB(t)
+	dir := setupLoginDB(t, insertLogin("https://synced.example", "", "carol", "", 13370000000000000))
 
-	count, err := countPasswords(path)
+	count, err := countPasswords(dir)
 	require.NoError(t, err)
-	assert.Equal(t, 2, count)
+	assert.Equal(t, 3, count, "both stores")
 }
 
 func TestCountPasswords_Empty(t *testing.T) {
-	path := createTestDB(t, "Login Data", loginsSchema)
+	dir := t.TempDir()
+	installFile(t, dir, createTestDB(t, loginDataFile, loginsSchema), loginDataFile)
 
-	count, err := countPasswords(path)
+	count, err := countPasswords(dir)
 	require.NoError(t, err)
 	assert.Equal(t, 0, count)
 }
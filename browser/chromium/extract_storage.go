@@ -9,8 +9,11 @@ import (
 	"unicode/utf16"
 
 	"github.com/syndtr/goleveldb/leveldb"
+	"github.com/syndtr/goleveldb/leveldb/opt"
 
+	"github.com/moond4rk/hackbrowserdata/log"
 	"github.com/moond4rk/hackbrowserdata/types"
+	"github.com/moond4rk/hackbrowserdata/utils/leveldbutil"
 )
 
 // Chromium localStorage LevelDB key prefixes and string format bytes.
@@ -27,27 +30,88 @@ const (
 const maxLocalStorageValueLength = 2048
 
 func extractLocalStorage(path string) ([]types.StorageEntry, error) {
+	records, err := readStorage(path)
+	if err != nil {
+		return nil, err
+	}
+	var entries []types.StorageEntry
+	for _, r := range records {
+		entry, ok := parseLocalStorageEntry(r.Key, r.Value)
+		if !ok {
+			continue
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+// recoverLocalStorage returns the values the LevelDB's journals and tables still hold for keys that
+// were overwritten or deleted since (see leveldbutil.ReadDir), leaving out any that duplicate a live
+// entry.
+func recoverLocalStorage(path string, live []types.StorageEntry) ([]types.StorageEntry, error) {
+	records, err := leveldbutil.ReadDir(path)
+	seen := storageSet(live)
+	var entries []types.StorageEntry
+	for _, r := range records {
+		if r.Deleted {
+			continue
+		}
+		entry, ok := parseLocalStorageEntry(r.Key, r.Value)
+		if !ok || seen[entry] {
+			continue
+		}
+		seen[entry] = true
+		entry.Recovered, entry.RecoveredFrom = true, r.Origin()
+		entries = append(entries, entry)
+	}
+	return entries, err
+}
+
+// readStorage returns the live key/value pairs of the LevelDB at path. The database is opened
+// read-only, so its journals and obsolete tables stay as they were copied for recoverLocalStorage and
+// recoverSessionStorage. One that goleveldb cannot open or read through — corrupted, or copied while
+// the browser was writing it — is read raw instead (see leveldbutil.Latest).
+func readStorage(path string) ([]leveldbutil.Record, error) {
 	if _, err := os.Stat(path); err != nil {
 		return nil, fmt.Errorf("leveldb path %q: %w", path, err)
 	}
-	db, err := leveldb.OpenFile(path, nil)
+	records, err := readLevelDB(path)
+	if err == nil {
+		return records, nil
+	}
+	raw, rawErr := leveldbutil.ReadDir(path)
+	if len(raw) == 0 {
+		return nil, err
+	}
+	log.Debugf("leveldb %s: %v; read raw files instead (%v)", path, err, rawErr)
+	return leveldbutil.Latest(raw), nil
+}
+
+func readLevelDB(path string) ([]leveldbutil.Record, error) {
+	db, err := leveldb.OpenFile(path, &opt.Options{ReadOnly: true})
 	if err != nil {
 		return nil, err
 	}
 	defer db.Close()
 
-	var entries []types.StorageEntry
+	var records []leveldbutil.Record
 	iter := db.NewIterator(nil, nil)
 	defer iter.Release()
-
 	for iter.Next() {
-		entry, ok := parseLocalStorageEntry(iter.Key(), iter.Value())
-		if !ok {
-			continue
-		}
-		entries = append(entries, entry)
+		records = append(records, leveldbutil.Record{
+			Key:   append([]byte{}, iter.Key()...),
+			Value: append([]byte{}, iter.Value()...),
+		})
+	}
+	return records, iter.Error()
+}
+
+func storageSet(entries []types.StorageEntry) map[types.StorageEntry]bool {
+	seen := make(map[types.StorageEntry]bool, len(entries))
+	for _, e := range entries {
+		seen[e] = true
 	}
-	return entries, iter.Error()
+	return seen
 }
 
 // parseLocalStorageEntry classifies a LevelDB key/value pair and decodes it.
@@ -158,21 +222,51 @@ func decodeLocalStorageValue(value []byte) string {
 //	map-<map_id>-<key_name>  → <value>     (actual data, UTF-16 LE)
 //	next-map-id / version                  (metadata, skipped)
 func extractSessionStorage(path string) ([]types.StorageEntry, error) {
-	if _, err := os.Stat(path); err != nil {
-		return nil, fmt.Errorf("leveldb path %q: %w", path, err)
-	}
-	db, err := leveldb.OpenFile(path, nil)
+	records, err := readStorage(path)
 	if err != nil {
 		return nil, err
 	}
-	defer db.Close()
+	originByMapID := sessionOrigins(records)
+	var entries []types.StorageEntry
+	for _, r := range records {
+		if entry, ok := parseSessionStorageEntry(r.Key, r.Value, originByMapID); ok {
+			entries = append(entries, entry)
+		}
+	}
+	return entries, nil
+}
 
-	// Pass 1: build map_id → origin lookup from namespace entries.
-	// Key: "namespace-<guid>-<origin>", Value: "<map_id>" (ASCII digits).
+// recoverSessionStorage is recoverLocalStorage for session storage. Origins are resolved through every
+// namespace entry the files hold, so a map whose tab was closed keeps its origin.
+func recoverSessionStorage(path string, live []types.StorageEntry) ([]types.StorageEntry, error) {
+	records, err := leveldbutil.ReadDir(path)
+	var values []leveldbutil.Record
+	for _, r := range records {
+		if !r.Deleted {
+			values = append(values, r)
+		}
+	}
+	originByMapID := sessionOrigins(values)
+	seen := storageSet(live)
+	var entries []types.StorageEntry
+	for _, r := range values {
+		entry, ok := parseSessionStorageEntry(r.Key, r.Value, originByMapID)
+		if !ok || seen[entry] {
+			continue
+		}
+		seen[entry] = true
+		entry.Recovered, entry.RecoveredFrom = true, r.Origin()
+		entries = append(entries, entry)
+	}
+	return entries, err
+}
+
+// sessionOrigins builds the map_id → origin lookup from namespace entries.
+// Key: "namespace-<guid>-<origin>", Value: "<map_id>" (ASCII digits).
+func sessionOrigins(records []leveldbutil.Record) map[string]string {
 	originByMapID := make(map[string]string)
-	iter := db.NewIterator(nil, nil)
-	for iter.Next() {
-		key := string(iter.Key())
+	for _, r := range records {
+		key := string(r.Key)
 		if !strings.HasPrefix(key, "namespace-") {
 			continue
 		}
@@ -183,46 +277,38 @@ func extractSessionStorage(path string) ([]types.StorageEntry, error) {
 		if origin == "" {
 			continue
 		}
-		mapID := string(iter.Value())
-		originByMapID[mapID] = origin
-	}
-	iter.Release()
-	if err := iter.Error(); err != nil {
-		return nil, fmt.Errorf("read namespace entries: %w", err)
+		mapID := string(r.Value)
+		if _, ok := originByMapID[mapID]; !ok {
+			originByMapID[mapID] = origin
+		}
 	}
+	return originByMapID
+}
 
-	// Pass 2: read map entries and resolve origins.
-	var entries []types.StorageEntry
-	iter2 := db.NewIterator(nil, nil)
-	defer iter2.Release()
-
+// parseSessionStorageEntry decodes a map entry, resolving its origin through originByMapID.
+// Returns false for every other key.
+func parseSessionStorageEntry(key, value []byte, originByMapID map[string]string) (types.StorageEntry, bool) {
 	mapPrefix := []byte("map-")
-	for iter2.Next() {
-		key := iter2.Key()
-		if !bytes.HasPrefix(key, mapPrefix) {
-			continue
-		}
-		rest := key[len(mapPrefix):] // "<map_id>-<key_name>"
-		sep := bytes.IndexByte(rest, '-')
-		if sep < 0 {
-			continue
-		}
-		mapID := string(rest[:sep])
-		keyName := string(rest[sep+1:])
-
-		origin := originByMapID[mapID]
-		if origin == "" {
-			origin = mapID // fallback to map_id if namespace not found
-		}
+	if !bytes.HasPrefix(key, mapPrefix) {
+		return types.StorageEntry{}, false
+	}
+	rest := key[len(mapPrefix):] // "<map_id>-<key_name>"
+	sep := bytes.IndexByte(rest, '-')
+	if sep < 0 {
+		return types.StorageEntry{}, false
+	}
+	mapID := string(rest[:sep])
+	keyName := string(rest[sep+1:])
 
-		value := decodeSessionStorageValue(iter2.Value())
-		entries = append(entries, types.StorageEntry{
-			URL:   origin,
-			Key:   keyName,
-			Value: value,
-		})
+	origin := originByMapID[mapID]
+	if origin == "" {
+		origin = mapID // fallback to map_id if namespace not found
 	}
-	return entries, iter2.Error()
+	return types.StorageEntry{
+		URL:   origin,
+		Key:   keyName,
+		Value: decodeSessionStorageValue(value),
+	}, true
 }
 
 // extractNamespaceOrigin extracts the origin from a namespace key.
@@ -239,50 +325,31 @@ func extractNamespaceOrigin(key string) string {
 }
 
 func countLocalStorage(path string) (int, error) {
-	if _, err := os.Stat(path); err != nil {
-		return 0, err
-	}
-	db, err := leveldb.OpenFile(path, nil)
+	records, err := readStorage(path)
 	if err != nil {
 		return 0, err
 	}
-	defer db.Close()
-
 	var count int
-	iter := db.NewIterator(nil, nil)
-	defer iter.Release()
-
-	for iter.Next() {
-		if _, ok := parseLocalStorageEntry(iter.Key(), iter.Value()); ok {
+	for _, r := range records {
+		if _, ok := parseLocalStorageEntry(r.Key, r.Value); ok {
 			count++
 		}
 	}
-	return count, iter.Error()
+	return count, nil
 }
 
 func countSessionStorage(path string) (int, error) {
-	if _, err := os.Stat(path); err != nil {
-		return 0, err
-	}
-	db, err := leveldb.OpenFile(path, nil)
+	records, err := readStorage(path)
 	if err != nil {
 		return 0, err
 	}
-	defer db.Close()
-
 	var count int
-	iter := db.NewIterator(nil, nil)
-	defer iter.Release()
-
-	mapPrefix := []byte("map-")
-	for iter.Next() {
-		if bytes.HasPrefix(iter.Key(), mapPrefix) {
-			if sep := bytes.IndexByte(iter.Key()[len(mapPrefix):], '-'); sep >= 0 {
-				count++
-			}
+	for _, r := range records {
+		if _, ok := parseSessionStorageEntry(r.Key, r.Value, nil); ok {
+			count++
 		}
 	}
-	return count, iter.Error()
+	return count, nil
 }
 
 // decodeSessionStorageValue decodes a session storage value.
@@ -2,11 +2,16 @@ package chromium
 
 import (
 	"encoding/binary"
+	"os"
+	"path/filepath"
 	"testing"
 	"unicode/utf16"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"github.com/syndtr/goleveldb/leveldb"
+
+	"github.com/moond4rk/hackbrowserdata/types"
 )
 
 // ---------------------------------------------------------------------------
@@ -227,6 +232,76 @@ func TestExtractSessionStorage(t *testing.T) {
 	assert.Equal(t, "abc123", byKey["https://example.com//token"])
 }
 
+func TestExtractLocalStorage_RawFallback(t *testing.T) {
+	dir := setupLocalStorageLevelDB(t)
+	require.NoError(t, os.Remove(filepath.Join(dir, "CURRENT"))) // goleveldb refuses the directory
+
+	got, err := extractLocalStorage(dir)
+	require.NoError(t, err)
+	assert.Len(t, got, 4, "read from the journal instead")
+}
+
+// ---------------------------------------------------------------------------
+// recoverLocalStorage / recoverSessionStorage
+// ---------------------------------------------------------------------------
+
+// editLevelDB reopens a LevelDB made by createTestLevelDB and applies puts, then deletes.
+func editLevelDB(t *testing.T, dir string, puts map[string]string, deletes ...string) {
+	t.Helper()
+	db, err := leveldb.OpenFile(dir, nil)
+	require.NoError(t, err)
+	for k, v := range puts {
+		require.NoError(t, db.Put([]byte(k), []byte(v), nil))
+	}
+	for _, k := range deletes {
+		require.NoError(t, db.Delete([]byte(k), nil))
+	}
+	require.NoError(t, db.Close())
+}
+
+func TestRecoverLocalStorage(t *testing.T) {
+	dir := setupLocalStorageLevelDB(t)
+	tokenKey := string(append([]byte("_https://example.com\x00"), testEncodeLatin1("token")...))
+	otherKey := string(append([]byte("_https://example.com\x00"), testEncodeUTF16("テスト")...))
+	editLevelDB(t, dir, map[string]string{tokenKey: string(testEncodeLatin1("rotated"))}, otherKey)
+
+	live, err := extractLocalStorage(dir)
+	require.NoError(t, err)
+	require.Len(t, live, 3)
+	got, err := recoverLocalStorage(dir, live)
+	require.NoError(t, err)
+
+	byKey := map[string]types.StorageEntry{}
+	for _, e := range got {
+		byKey[e.Key] = e
+	}
+	require.Len(t, byKey, 2, "only the overwritten and the deleted value")
+	assert.Equal(t, "abc123", byKey["token"].Value)
+	assert.Equal(t, "データ", byKey["テスト"].Value)
+	assert.True(t, byKey["token"].Recovered)
+	assert.Contains(t, byKey["token"].RecoveredFrom, " seq ")
+}
+
+func TestRecoverSessionStorage(t *testing.T) {
+	dir := setupSessionStorageLevelDB(t)
+	// closing the tab drops its namespace along with its map
+	editLevelDB(t, dir, nil,
+		"namespace-abcd1234_5678_9abc_def0_111111111111-https://example.com/",
+		"map-101-token",
+	)
+
+	live, err := extractSessionStorage(dir)
+	require.NoError(t, err)
+	require.Len(t, live, 1)
+	got, err := recoverSessionStorage(dir, live)
+	require.NoError(t, err)
+	require.Len(t, got, 1)
+	assert.Equal(t, "https://example.com/", got[0].URL, "origin resolved from the deleted namespace entry")
+	assert.Equal(t, "token", got[0].Key)
+	assert.Equal(t, "abc123", got[0].Value)
+	assert.True(t, got[0].Recovered)
+}
+
 // ---------------------------------------------------------------------------
 // countLocalStorage
 // ---------------------------------------------------------------------------
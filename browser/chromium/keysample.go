@@ -0,0 +1,73 @@
+package chromium
+
+import (
+	"database/sql"
+	"fmt"
+	"path/filepath"
+
+	"github.com/moond4rk/hackbrowserdata/filemanager"
+	"github.com/moond4rk/hackbrowserdata/log"
+	"github.com/moond4rk/hackbrowserdata/masterkey"
+	"github.com/moond4rk/hackbrowserdata/types"
+	"github.com/moond4rk/hackbrowserdata/utils/sqliteutil"
+)
+
+// keySamplesPerTier caps how many values of each cipher prefix are read from one database.
+const keySamplesPerTier = 8
+
+// keySampleSources are the encrypted columns sampled to validate master keys; every Chromium build
+// seals them with the installation key.
+var keySampleSources = []struct {
+	category types.Category
+	table    string
+	column   string
+}{
+	{types.Password, "logins", "password_value"},
+	{types.Cookie, "cookies", "encrypted_value"},
+}
+
+var keySamplePrefixes = []string{"v10", "v11", "v12", "v20"}
+
+// keySamples reads up to keySamplesPerTier values per cipher prefix from each profile's login databases
+// and Cookies, copied into session first. Unreadable databases just contribute nothing.
+func (b *Browser) keySamples(session *filemanager.Session) masterkey.Samples {
+	var samples masterkey.Samples
+	for i, p := range b.profiles {
+		for _, src := range keySampleSources {
+			rp, ok := p.sourcePaths[src.category]
+			if !ok {
+				continue
+			}
+			for j, db := range rp.databases() {
+				dst := filepath.Join(session.TempDir(), fmt.Sprintf("keysample-%d-%s-%d", i, src.category, j))
+				if err := session.Acquire(db, dst, false); err != nil {
+					log.Debugf("acquire %s for key validation: %v", db, err)
+					continue
+				}
+				samples = append(samples, sampleValues(dst, src.table, src.column, p.label())...)
+			}
+		}
+	}
+	return samples
+}
+
+// sampleValues reads up to keySamplesPerTier values of column per cipher prefix from the database at
+// path.
+func sampleValues(path, table, column, label string) [][]byte {
+	var values [][]byte
+	for _, prefix := range keySamplePrefixes {
+		query := fmt.Sprintf("SELECT %s FROM %s WHERE substr(%s, 1, 3) = CAST('%s' AS BLOB) LIMIT %d",
+			column, table, column, prefix, keySamplesPerTier)
+		got, err := sqliteutil.QueryRows(path, false, query, func(rows *sql.Rows) ([]byte, error) {
+			var v []byte
+			err := rows.Scan(&v)
+			return v, err
+		})
+		if err != nil {
+			log.Debugf("sample %s for key validation: %v", label, err)
+			break
+		}
+		values = append(values, got...)
+	}
+	return values
+}
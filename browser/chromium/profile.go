@@ -1,12 +1,17 @@
 package chromium
 
 import (
+	"errors"
+	"fmt"
+	"os"
 	"path/filepath"
 
 	"github.com/moond4rk/hackbrowserdata/filemanager"
 	"github.com/moond4rk/hackbrowserdata/log"
 	"github.com/moond4rk/hackbrowserdata/masterkey"
 	"github.com/moond4rk/hackbrowserdata/types"
+	"github.com/moond4rk/hackbrowserdata/utils/cacheutil"
+	"github.com/moond4rk/hackbrowserdata/utils/sqliteutil"
 )
 
 // profile is one Chromium profile under an installation — the leaf extraction
@@ -17,6 +22,10 @@ type profile struct {
 	kind        types.BrowserKind
 	extractors  map[types.Category]categoryExtractor
 	sourcePaths map[types.Category]resolvedPath
+
+	recoverDeleted bool   // also carve deleted rows, see recoverCategory
+	cacheBodyDir   string // save decoded cache bodies here; "" = don't
+	faviconDir     string // save favicon images here; "" = don't
 }
 
 func (p *profile) name() string {
@@ -80,7 +89,7 @@ func (p *profile) acquireFiles(session *filemanager.Session, categories []types.
 			continue
 		}
 		dst := filepath.Join(session.TempDir(), cat.String())
-		if err := session.Acquire(rp.absPath, dst, rp.isDir); err != nil {
+		if err := acquire(session, rp, dst); err != nil {
 			log.Debugf("acquire %s: %v", cat, err)
 			continue
 		}
@@ -89,6 +98,31 @@ func (p *profile) acquireFiles(session *filemanager.Session, categories []types.
 	return tempPaths
 }
 
+// acquire copies one resolved source to dst: a file or directory as is, a group's files into dst as a
+// directory. A group fails only when none of its files could be copied.
+func acquire(session *filemanager.Session, rp resolvedPath, dst string) error {
+	if len(rp.members) == 0 {
+		return session.Acquire(rp.absPath, dst, rp.isDir)
+	}
+	var errs []error
+	for _, m := range rp.members {
+		target := filepath.Join(dst, filepath.FromSlash(m))
+		if err := os.MkdirAll(filepath.Dir(target), 0o700); err != nil {
+			return err
+		}
+		if err := session.Acquire(filepath.Join(rp.absPath, m), target, false); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", m, err))
+		}
+	}
+	if len(errs) == len(rp.members) {
+		return errors.Join(errs...)
+	}
+	if len(errs) > 0 {
+		log.Debugf("acquire %s: %v", dst, errors.Join(errs...))
+	}
+	return nil
+}
+
 // extractCategory calls the appropriate extract function for a category. A custom
 // extractor (registered via extractorsForKind) takes precedence over the switch.
 func (p *profile) extractCategory(data *types.BrowserData, cat types.Category, masterKeys masterkey.MasterKeys, path string) {
@@ -99,6 +133,10 @@ func (p *profile) extractCategory(data *types.BrowserData, cat types.Category, m
 		return
 	}
 
+	var wals map[string]*sqliteutil.WAL
+	if p.recoverDeleted {
+		wals = p.readWALs(cat, path)
+	}
 	var err error
 	switch cat {
 	case types.Password:
@@ -119,9 +157,108 @@ func (p *profile) extractCategory(data *types.BrowserData, cat types.Category, m
 		data.LocalStorage, err = extractLocalStorage(path)
 	case types.SessionStorage:
 		data.SessionStorage, err = extractSessionStorage(path)
+	case types.Cache:
+		data.Caches, err = extractCache(path, p.cacheBodies())
+	case types.Favicon:
+		data.Favicons, err = extractFavicons(path, p.faviconImages())
+	case types.Omnibox:
+		data.Omnibox, err = extractOmnibox(path)
 	}
 	if err != nil {
 		log.Debugf("extract %s for %s: %v", cat, p.label(), err)
+		return
+	}
+	if p.recoverDeleted {
+		p.recoverCategory(data, cat, masterKeys, path, wals)
+	}
+}
+
+// cacheBodies returns the writer extractCache saves response bodies with, or nil when they are not
+// carved.
+func (p *profile) cacheBodies() *cacheutil.BodyWriter {
+	if p.cacheBodyDir == "" {
+		return nil
+	}
+	return cacheutil.NewBodyWriter(p.cacheBodyDir)
+}
+
+// faviconImages returns the writer extractFavicons saves icon images with, or nil when they are not
+// saved.
+func (p *profile) faviconImages() *cacheutil.BodyWriter {
+	if p.faviconDir == "" {
+		return nil
+	}
+	return cacheutil.NewBodyWriter(p.faviconDir)
+}
+
+// readWALs reads the write-ahead logs of the category's databases when recoverCategory carves them,
+// by database path: the login databases copied into path for passwords, path itself otherwise.
+// Opening a database checkpoints and deletes its log, so this runs before the live rows are read.
+func (p *profile) readWALs(cat types.Category, path string) map[string]*sqliteutil.WAL {
+	var dbs []string
+	switch cat {
+	case types.Password:
+		for _, s := range loginStores {
+			dbs = append(dbs, filepath.Join(path, s.file))
+		}
+	case types.Cookie, types.History:
+		dbs = []string{path}
+	default:
+		return nil
+	}
+	wals := make(map[string]*sqliteutil.WAL)
+	for _, db := range dbs {
+		if _, err := os.Stat(db); err != nil {
+			continue
+		}
+		wal, err := sqliteutil.ReadWAL(db)
+		if err != nil {
+			log.Debugf("read %s wal for %s: %v", cat, p.label(), err)
+		}
+		wals[db] = wal
+	}
+	return wals
+}
+
+// recoverCategory appends the rows carved out of the free space of the category's database —
+// passwords, cookies and history — and the row versions its write-ahead log superseded after the
+// live ones. For local and session storage it appends the overwritten and deleted values the
+// LevelDB's journals and tables still hold.
+func (p *profile) recoverCategory(
+	data *types.BrowserData, cat types.Category, masterKeys masterkey.MasterKeys, path string,
+	wals map[string]*sqliteutil.WAL,
+) {
+	var n int
+	var err error
+	switch cat {
+	case types.Password:
+		var logins []types.LoginEntry
+		logins, err = recoverPasswords(masterKeys, path, wals, data.Passwords)
+		data.Passwords, n = append(data.Passwords, logins...), len(logins)
+	case types.Cookie:
+		var cookies []types.CookieEntry
+		cookies, err = recoverCookies(masterKeys, path, wals[path], data.Cookies)
+		data.Cookies, n = append(data.Cookies, cookies...), len(cookies)
+	case types.History:
+		var histories []types.HistoryEntry
+		histories, err = recoverHistories(path, wals[path], data.Histories)
+		data.Histories, n = append(data.Histories, histories...), len(histories)
+	case types.LocalStorage:
+		var storage []types.StorageEntry
+		storage, err = recoverLocalStorage(path, data.LocalStorage)
+		data.LocalStorage, n = append(data.LocalStorage, storage...), len(storage)
+	case types.SessionStorage:
+		var storage []types.StorageEntry
+		storage, err = recoverSessionStorage(path, data.SessionStorage)
+		data.SessionStorage, n = append(data.SessionStorage, storage...), len(storage)
+	default:
+		return
+	}
+	if err != nil {
+		log.Debugf("recover %s for %s: %v", cat, p.label(), err)
+	}
+	if n > 0 {
+		log.Infof("%s: recovered %d deleted %s entries", p.label(), n, cat)
 	}
 }
 
@@ -131,7 +268,11 @@ func (p *profile) countCategory(cat types.Category, path string) int {
 	var err error
 	switch cat {
 	case types.Password:
-		count, err = countPasswords(path)
+		if p.kind == types.ChromiumYandex {
+			count, err = countYandexPasswords(path)
+		} else {
+			count, err = countPasswords(path)
+		}
 	case types.Cookie:
 		count, err = countCookies(path)
 	case types.History:
@@ -156,6 +297,12 @@ func (p *profile) countCategory(cat types.Category, path string) int {
 		count, err = countLocalStorage(path)
 	case types.SessionStorage:
 		count, err = countSessionStorage(path)
+	case types.Cache:
+		count, err = countCache(path)
+	case types.Favicon:
+		count, err = countFavicons(path)
+	case types.Omnibox:
+		count, err = countOmnibox(path)
 	}
 	if err != nil {
 		log.Debugf("count %s for %s: %v", cat, p.label(), err)
@@ -8,8 +8,9 @@ import (
 // sourcePath describes a single candidate location for browser data,
 // relative to the profile directory.
 type sourcePath struct {
-	rel   string // relative path from profileDir, e.g. "Network/Cookies"
-	isDir bool   // true for directory targets (LevelDB, Session Storage)
+	rel     string   // relative path from profileDir, e.g. "Network/Cookies"
+	isDir   bool     // true for directory targets (LevelDB, Session Storage)
+	members []string // for a group: the files read together, relative to profileDir
 }
 
 // rel stays slash-canonical (e.g. "Network/Cookies"); filepath.Join converts at resolve time, and
@@ -17,11 +18,23 @@ type sourcePath struct {
 func file(rel string) sourcePath { return sourcePath{rel: rel, isDir: false} }
 func dir(rel string) sourcePath  { return sourcePath{rel: rel, isDir: true} }
 
+// group is a set of files one category reads together, copied into a directory of their own. A
+// profile has the group when it has any of them.
+func group(rels ...string) sourcePath { return sourcePath{isDir: true, members: rels} }
+
+// paths lists the slash-relative paths a source is made of.
+func (sp sourcePath) paths() []string {
+	if len(sp.members) > 0 {
+		return sp.members
+	}
+	return []string{sp.rel}
+}
+
 // chromiumSources defines the standard Chromium file layout.
 // Each category maps to one or more candidate paths tried in priority order;
 // the first existing path wins.
 var chromiumSources = map[types.Category][]sourcePath{
-	types.Password:       {file("Login Data")},
+	types.Password:       {group(loginDataFile, accountLoginDataFile)},
 	types.Cookie:         {file("Network/Cookies"), file("Cookies")},
 	types.History:        {file("History")},
 	types.Download:       {file("History")},
@@ -30,6 +43,9 @@ var chromiumSources = map[types.Category][]sourcePath{
 	types.Extension:      {file("Secure Preferences")},
 	types.LocalStorage:   {dir("Local Storage/leveldb")},
 	types.SessionStorage: {dir("Session Storage")},
+	types.Cache:          {dir("Cache/Cache_Data"), dir("Cache")},
+	types.Favicon:        {file("Favicons")},
+	types.Omnibox:        {group(omniboxShortcuts, omniboxTopSites, omniboxPredictor)},
 }
 
 // sourcesForKind returns the source mapping for a browser kind.
@@ -1,6 +1,7 @@
 package chromium
 
 import (
+	"bytes"
 	"database/sql"
 	"fmt"
 	"os"
@@ -10,6 +11,8 @@ import (
 	"github.com/stretchr/testify/require"
 	"github.com/syndtr/goleveldb/leveldb"
 	_ "modernc.org/sqlite"
+
+	"github.com/moond4rk/hackbrowserdata/crypto"
 )
 
 // ---------------------------------------------------------------------------
@@ -20,6 +23,14 @@ import (
 // testAESKey is a 16-byte AES-128 key for constructing test ciphertext.
 var testAESKey = []byte("0123456789abcdef")
 
+// sealChromiumCBC encrypts plaintext the way macOS/Linux Chromium stores v10 values.
+func sealChromiumCBC(t *testing.T, key []byte, plaintext string) []byte {
+	t.Helper()
+	ct, err := crypto.AESCBCEncrypt(key, bytes.Repeat([]byte{0x20}, 16), []byte(plaintext))
+	require.NoError(t, err)
+	return append([]byte("v10"), ct...)
+}
+
 // ---------------------------------------------------------------------------
 // Real Chrome table schemas — extracted via `sqlite3 <db> ".schema <table>"`.
 // Using complete schemas ensures our SQL queries work against real browser data.
@@ -61,6 +72,25 @@ const loginsSchema = `CREATE TABLE logins (
 	UNIQUE (origin_url, username_element, username_value, password_element, signon_realm)
 )`
 
+const passwordNotesSchema = `CREATE TABLE password_notes (
+	id INTEGER PRIMARY KEY AUTOINCREMENT NOT NULL,
+	parent_id INTEGER NOT NULL REFERENCES logins ON UPDATE CASCADE ON DELETE CASCADE DEFERRABLE INITIALLY DEFERRED,
+	key VARCHAR NOT NULL,
+	value BLOB,
+	date_created INTEGER NOT NULL,
+	confidential INTEGER,
+	UNIQUE (parent_id, key)
+)`
+
+const insecureCredentialsSchema = `CREATE TABLE insecure_credentials (
+	parent_id INTEGER REFERENCES logins ON UPDATE CASCADE ON DELETE CASCADE DEFERRABLE INITIALLY DEFERRED,
+	insecurity_type INTEGER NOT NULL,
+	create_time INTEGER NOT NULL,
+	is_muted INTEGER NOT NULL DEFAULT 0,
+	trigger_notification_from_backend INTEGER NOT NULL DEFAULT 0,
+	UNIQUE (parent_id, insecurity_type)
+)`
+
 const cookiesSchema = `CREATE TABLE cookies (
 	creation_utc INTEGER NOT NULL,
 	host_key TEXT NOT NULL,
@@ -139,6 +169,58 @@ const creditCardsSchema = `CREATE TABLE credit_cards (
 	nickname VARCHAR
 )`
 
+const faviconsSchema = `CREATE TABLE icon_mapping (
+	id INTEGER PRIMARY KEY,
+	page_url LONGVARCHAR NOT NULL,
+	icon_id INTEGER,
+	page_url_type INTEGER DEFAULT 0
+);
+CREATE TABLE favicons (
+	id INTEGER PRIMARY KEY,
+	url LONGVARCHAR NOT NULL,
+	icon_type INTEGER DEFAULT 1
+);
+CREATE TABLE favicon_bitmaps (
+	id INTEGER PRIMARY KEY,
+	icon_id INTEGER NOT NULL,
+	last_updated INTEGER DEFAULT 0,
+	image_data BLOB,
+	width INTEGER DEFAULT 0,
+	height INTEGER DEFAULT 0,
+	last_requested INTEGER DEFAULT 0
+)`
+
+const shortcutsSchema = `CREATE TABLE omni_box_shortcuts (
+	id VARCHAR PRIMARY KEY,
+	text VARCHAR,
+	fill_into_edit VARCHAR,
+	url VARCHAR,
+	contents VARCHAR,
+	contents_class VARCHAR,
+	description VARCHAR,
+	description_class VARCHAR,
+	transition INTEGER,
+	type INTEGER,
+	keyword VARCHAR,
+	last_access_time INTEGER,
+	number_of_hits INTEGER
+)`
+
+const topSitesSchema = `CREATE TABLE top_sites (
+	url LONGVARCHAR PRIMARY KEY,
+	url_rank INTEGER,
+	title LONGVARCHAR,
+	redirects LONGVARCHAR
+)`
+
+const networkActionPredictorSchema = `CREATE TABLE network_action_predictor (
+	id TEXT PRIMARY KEY,
+	user_text TEXT,
+	url TEXT,
+	number_of_hits INTEGER,
+	number_of_misses INTEGER
+)`
+
 // ---------------------------------------------------------------------------
 // INSERT helpers — each returns one SQL statement with only the fields
 // our extract functions care about; other NOT NULL columns get defaults.
@@ -154,6 +236,34 @@ func insertLogin(originURL, actionURL, username, pwdHex string, dateCreated int6
 	)
 }
 
+// insertLoginMetadata inserts a login with the columns beyond insertLogin's: the action URL, when it
+// was last used and had its password changed, how often it was used, and whether saving was refused.
+func insertLoginMetadata(originURL, actionURL, username string, dateCreated, lastUsed, passwordModified int64,
+	timesUsed, blocked int,
+) string {
+	return fmt.Sprintf(
+		`INSERT INTO logins (origin_url, action_url, username_element, username_value,
+		 password_element, password_value, submit_element, signon_realm, date_created,
+		 blacklisted_by_user, scheme, times_used, date_last_used, date_password_modified)
+		 VALUES ('%s', '%s', '', '%s', '', x'', '', '%s', %d, %d, 0, %d, %d, %d)`,
+		originURL, actionURL, username, originURL, dateCreated, blocked, timesUsed, lastUsed, passwordModified,
+	)
+}
+
+func insertPasswordNote(parentID int, valueHex string) string {
+	return fmt.Sprintf(
+		`INSERT INTO password_notes (parent_id, key, value, date_created) VALUES (%d, '', x'%s', 0)`,
+		parentID, valueHex,
+	)
+}
+
+func insertInsecureCredential(parentID, insecurityType int) string {
+	return fmt.Sprintf(
+		`INSERT INTO insecure_credentials (parent_id, insecurity_type, create_time) VALUES (%d, %d, 0)`,
+		parentID, insecurityType,
+	)
+}
+
 func insertCookie(name, host, path, encValueHex string, creationUTC, expiresUTC int64, secure, httpOnly int) string {
 	return fmt.Sprintf(
 		`INSERT INTO cookies (creation_utc, host_key, top_frame_site_key, name, value,
@@ -184,6 +294,43 @@ func insertDownload(targetPath, tabURL, mimeType string, totalBytes, startTime,
 	)
 }
 
+func insertIconMapping(pageURL string, iconID int) string {
+	return fmt.Sprintf(`INSERT INTO icon_mapping (page_url, icon_id) VALUES ('%s', %d)`, pageURL, iconID)
+}
+
+func insertFavicon(id int, url string) string {
+	return fmt.Sprintf(`INSERT INTO favicons (id, url) VALUES (%d, '%s')`, id, url)
+}
+
+func insertFaviconBitmap(id, iconID, width int, lastUpdated, lastRequested int64, imageHex string) string {
+	return fmt.Sprintf(
+		`INSERT INTO favicon_bitmaps (id, icon_id, last_updated, image_data, width, height, last_requested)
+		 VALUES (%d, %d, %d, x'%s', %d, %d, %d)`,
+		id, iconID, lastUpdated, imageHex, width, width, lastRequested,
+	)
+}
+
+func insertShortcut(text, fillIntoEdit, url, description string, lastAccess int64, hits int) string {
+	return fmt.Sprintf(
+		`INSERT INTO omni_box_shortcuts (id, text, fill_into_edit, url, contents, contents_class,
+		 description, description_class, transition, type, keyword, last_access_time, number_of_hits)
+		 VALUES ('%s', '%s', '%s', '%s', '%s', '', '%s', '', 1, 0, '', %d, %d)`,
+		text+url, text, fillIntoEdit, url, fillIntoEdit, description, lastAccess, hits,
+	)
+}
+
+func insertTopSite(url string, rank int, title string) string {
+	return fmt.Sprintf(`INSERT INTO top_sites (url, url_rank, title, redirects) VALUES ('%s', %d, '%s', '%s')`, url, rank, title, url)
+}
+
+func insertPrediction(userText, url string, hits, misses int) string {
+	return fmt.Sprintf(
+		`INSERT INTO network_action_predictor (id, user_text, url, number_of_hits, number_of_misses)
+		 VALUES ('%s', '%s', '%s', %d, %d)`,
+		userText+url, userText, url, hits, misses,
+	)
+}
+
 func insertCreditCard(name string, month, year int, encNumberHex, nickName, address string) string {
 	return fmt.Sprintf(
 		`INSERT INTO credit_cards (guid, name_on_card, expiration_month, expiration_year, card_number_encrypted, nickname, billing_address_id)
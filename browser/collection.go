@@ -0,0 +1,305 @@
+package browser
+
+import (
+	"fmt"
+	"io/fs"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/moond4rk/hackbrowserdata/log"
+	"github.com/moond4rk/hackbrowserdata/types"
+	"github.com/moond4rk/hackbrowserdata/utils/fileutil"
+)
+
+// windowsInstallDirs, macInstallDirs and linuxInstallDirs are each key's install dir relative to the
+// home dir on that OS. The platform tables build their UserDataDir from them, and installTails from all
+// three, so collection matching cannot drift from discovery. A "*" segment matches one path component.
+var windowsInstallDirs = map[string]string{
+	"chrome":      "AppData/Local/Google/Chrome/User Data",
+	"edge":        "AppData/Local/Microsoft/Edge/User Data",
+	"chromium":    "AppData/Local/Chromium/User Data",
+	"chrome-beta": "AppData/Local/Google/Chrome Beta/User Data",
+	"opera":       "AppData/Roaming/Opera Software/Opera Stable",
+	"opera-gx":    "AppData/Roaming/Opera Software/Opera GX Stable",
+	"vought":      "AppData/Roaming/Browser from Vought",
+	"vivaldi":     "AppData/Local/Vivaldi/User Data",
+	"coccoc":      "AppData/Local/CocCoc/Browser/User Data",
+	"brave":       "AppData/Local/BraveSoftware/Brave-Browser/User Data",
+	"yandex":      "AppData/Local/Yandex/YandexBrowser/User Data",
+	"360x":        "AppData/Local/360ChromeX/Chrome/User Data",
+	"360":         "AppData/Local/360chrome/Chrome/User Data",
+	"qq":          "AppData/Local/Tencent/QQBrowser/User Data",
+	"dc":          "AppData/Local/DCBrowser/User Data",
+	"sogou":       "AppData/Local/Sogou/SogouExplorer/User Data",
+	"arc":         "AppData/Local/Packages/TheBrowserCompany.Arc_*/LocalCache/Local/Arc/User Data",
+	"duckduckgo":  "AppData/Local/Packages/DuckDuckGo.DesktopBrowser_*/LocalState/EBWebView",
+	"firefox":     "AppData/Roaming/Mozilla/Firefox/Profiles",
+	"safari":      "Library/Safari",
+}
+
+var macInstallDirs = map[string]string{
+	"chrome":      "Library/Application Support/Google/Chrome",
+	"edge":        "Library/Application Support/Microsoft Edge",
+	"chromium":    "Library/Application Support/Chromium",
+	"chrome-beta": "Library/Application Support/Google/Chrome Beta",
+	"opera":       "Library/Application Support/com.operasoftware.Opera",
+	"opera-gx":    "Library/Application Support/com.operasoftware.OperaGX",
+	"vivaldi":     "Library/Application Support/Vivaldi",
+	"coccoc":      "Library/Application Support/Coccoc",
+	"brave":       "Library/Application Support/BraveSoftware/Brave-Browser",
+	"yandex":      "Library/Application Support/Yandex/YandexBrowser",
+	"arc":         "Library/Application Support/Arc/User Data",
+	"firefox":     "Library/Application Support/Firefox/Profiles",
+	"safari":      "Library/Safari",
+}
+
+var linuxInstallDirs = map[string]string{
+	"chrome":           ".config/google-chrome",
+	"edge":             ".config/microsoft-edge",
+	"chromium":         ".config/chromium",
+	"chrome-beta":      ".config/google-chrome-beta",
+	"opera":            ".config/opera",
+	"vivaldi":          ".config/vivaldi",
+	"brave":            ".config/BraveSoftware/Brave-Browser",
+	"chrome-flatpak":   ".var/app/com.google.Chrome/config/google-chrome",
+	"chromium-flatpak": ".var/app/org.chromium.Chromium/config/chromium",
+	"firefox":          ".mozilla/firefox",
+	"safari":           "Library/Safari",
+}
+
+// installTail is one install path, relative to the home dir, that names the installation found there.
+type installTail struct {
+	key  string
+	tail string
+}
+
+// installTails are the install paths of every platform table, so an installation found deep inside a
+// forensic collection or on a disk image can be named by its browser key whatever OS it came from.
+// Matching is case-insensitive; Safari is matched at the Library its data sits in.
+var installTails = buildInstallTails(windowsInstallDirs, macInstallDirs, linuxInstallDirs)
+
+// buildInstallTails merges the per-OS install dirs, longest tail first so the most specific one wins,
+// then by key and tail for a stable order.
+func buildInstallTails(tables ...map[string]string) []installTail {
+	seen := make(map[installTail]bool)
+	var tails []installTail
+	for _, table := range tables {
+		for key, dir := range table {
+			if key == "safari" {
+				dir = path.Dir(dir)
+			}
+			t := installTail{key: key, tail: dir}
+			if !seen[t] {
+				seen[t] = true
+				tails = append(tails, t)
+			}
+		}
+	}
+	sort.Slice(tails, func(i, j int) bool {
+		ni, nj := strings.Count(tails[i].tail, "/"), strings.Count(tails[j].tail, "/")
+		if ni != nj {
+			return ni > nj
+		}
+		if tails[i].key != tails[j].key {
+			return tails[i].key < tails[j].key
+		}
+		return tails[i].tail < tails[j].tail
+	})
+	return tails
+}
+
+// maxCollectionDepth bounds the walk for installations inside a collection; the deepest install path
+// (an MSIX package dir) sits about a dozen components below a collector's drive folder.
+const maxCollectionDepth = 20
+
+// collectionRoot is one installation found inside a forensic collection.
+type collectionRoot struct {
+	key  string
+	kind types.BrowserKind
+	dir  string
+}
+
+// findCollectionRoots finds the installations inside a forensic collection at dataDir: a KAPE target
+// folder (C/Users/<user>/...), an extracted Velociraptor collection (uploads/auto/C%3A/Users/...,
+// components URL-encoded) or an extracted home-dir tarball. Chromium data at an unknown path (often an
+// Electron app) is skipped with a hint.
+func findCollectionRoots(dataDir string) []collectionRoot {
+	roots, unknown := findInstallations(os.DirFS(dataDir))
+	for _, dir := range unknown {
+		log.Infof("restore: unrecognized Chromium data at %s; restore it on its own with --data-dir and -b <browser>",
+			filepath.Join(dataDir, filepath.FromSlash(dir)))
+	}
+	for i := range roots {
+		roots[i].dir = filepath.Join(dataDir, filepath.FromSlash(roots[i].dir))
+	}
+	return roots
+}
+
+// findInstallations walks fsys for browser installations. A dir with a Local State is a Chromium User
+// Data, one of Firefox profiles a Firefox installation, and a Library holding Safari data a Safari one;
+// each is named by matching its decoded path against installTails, and installations of the same
+// browser for different users are told apart as <key>@<user>. Root dirs are slash paths within fsys;
+// Chromium data whose path matches no tail is returned apart.
+func findInstallations(fsys fs.FS) (roots []collectionRoot, unknown []string) {
+	_ = fs.WalkDir(fsys, ".", func(p string, d fs.DirEntry, err error) error {
+		if err != nil || !d.IsDir() {
+			return nil
+		}
+		if p != "." && strings.Count(p, "/")+1 > maxCollectionDepth {
+			return fs.SkipDir
+		}
+		kind, found := installationKind(fsys, p)
+		if !found {
+			return nil
+		}
+		key, ok := installKey(decodeCollectionPath(p), kind)
+		switch {
+		case ok:
+			if kind == types.Chromium {
+				kind = kindForKey(key)
+			}
+			roots = append(roots, collectionRoot{key: key, kind: kind, dir: p})
+		case kind == types.Chromium:
+			unknown = append(unknown, p)
+		}
+		if kind == types.Safari {
+			return nil // a Library also holds Chromium and Firefox installations
+		}
+		return fs.SkipDir
+	})
+	return disambiguateRoots(roots), unknown
+}
+
+// installationKind reports which engine's installation the slash path dir in fsys holds, if any.
+func installationKind(fsys fs.FS, dir string) (types.BrowserKind, bool) {
+	switch {
+	case fileExistsFS(fsys, path.Join(dir, "Local State")):
+		return types.Chromium, true
+	case isFirefoxProfilesFS(fsys, dir):
+		return types.Firefox, true
+	case isSafariLibraryFS(fsys, dir):
+		return types.Safari, true
+	}
+	return 0, false
+}
+
+// decodeCollectionPath turns a slash path relative to the collection root into one with each
+// component URL-decoded, as Velociraptor stores them ("C%3A" for "C:").
+func decodeCollectionPath(rel string) string {
+	parts := strings.Split(rel, "/")
+	for i, part := range parts {
+		if decoded, err := url.PathUnescape(part); err == nil {
+			parts[i] = decoded
+		}
+	}
+	return strings.Join(parts, "/")
+}
+
+// installKey names the installation at the decoded path by the install tail it ends with.
+func installKey(decoded string, kind types.BrowserKind) (string, bool) {
+	parts := strings.Split(strings.ToLower(decoded), "/")
+	for _, t := range installTails {
+		if (kind == types.Safari) != (t.key == "safari") || (kind == types.Firefox) != (t.key == "firefox") {
+			continue
+		}
+		tail := strings.Split(strings.ToLower(t.tail), "/")
+		if len(tail) > len(parts) {
+			continue
+		}
+		match := true
+		for i, seg := range tail {
+			if ok, _ := path.Match(seg, parts[len(parts)-len(tail)+i]); !ok {
+				match = false
+				break
+			}
+		}
+		if match {
+			return t.key, true
+		}
+	}
+	return "", false
+}
+
+// disambiguateRoots keeps a browser's key when one installation has it and names each of several as
+// <key>@<user>, the user being the component after Users/ or home/ (or a counter when there is none).
+func disambiguateRoots(roots []collectionRoot) []collectionRoot {
+	count := make(map[string]int)
+	for _, r := range roots {
+		count[r.key]++
+	}
+	seen := make(map[string]int)
+	for i, r := range roots {
+		if count[r.key] < 2 {
+			continue
+		}
+		seen[r.key]++
+		user := collectionUser(decodeCollectionPath(r.dir))
+		if user == "" {
+			user = fmt.Sprint(seen[r.key])
+		}
+		roots[i].key = r.key + "@" + user
+	}
+	sort.Slice(roots, func(i, j int) bool { return roots[i].key < roots[j].key })
+	return roots
+}
+
+func collectionUser(decoded string) string {
+	parts := strings.Split(decoded, "/")
+	for i := 0; i+1 < len(parts); i++ {
+		if p := strings.ToLower(parts[i]); p == "users" || p == "home" {
+			return strings.ToLower(parts[i+1])
+		}
+	}
+	return ""
+}
+
+// collectionDirs maps the keys of the installations findCollectionRoots finds to their dirs.
+func collectionDirs(dataDir string) map[string]string {
+	dirs := make(map[string]string)
+	for _, r := range findCollectionRoots(dataDir) {
+		dirs[r.key] = r.dir
+	}
+	return dirs
+}
+
+// isInstallRoot reports whether dir is itself one installation's data root.
+func isInstallRoot(dir string) bool {
+	return fileutil.FileExists(filepath.Join(dir, "Local State")) || isFirefoxProfiles(dir) || isSafariLibrary(dir)
+}
+
+// isFirefoxProfilesFS is isFirefoxProfiles for the slash path dir within fsys.
+func isFirefoxProfilesFS(fsys fs.FS, dir string) bool {
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return false
+	}
+	for _, e := range entries {
+		if !e.IsDir() {
+			continue
+		}
+		for _, name := range firefoxKeyDBs {
+			if fileExistsFS(fsys, path.Join(dir, e.Name(), name)) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// isSafariLibraryFS is isSafariLibrary for the slash path dir within fsys.
+func isSafariLibraryFS(fsys fs.FS, dir string) bool {
+	if fileExistsFS(fsys, path.Join(dir, "Safari", "History.db")) {
+		return true
+	}
+	info, err := fs.Stat(fsys, path.Join(dir, "Containers", "com.apple.Safari"))
+	return err == nil && info.IsDir()
+}
+
+func fileExistsFS(fsys fs.FS, name string) bool {
+	info, err := fs.Stat(fsys, name)
+	return err == nil && !info.IsDir()
+}
@@ -0,0 +1,114 @@
+package browser
+
+import (
+	"path"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/moond4rk/hackbrowserdata/masterkey"
+	"github.com/moond4rk/hackbrowserdata/types"
+)
+
+// makeChromiumInstall lays out a User Data root with a Local State and one profile.
+func makeChromiumInstall(t *testing.T, root string) {
+	t.Helper()
+	makeUserData(t, root, testProfileDefault)
+	mkFile(t, root, "Local State")
+}
+
+func TestFindCollectionRoots(t *testing.T) {
+	dataDir := t.TempDir()
+	// KAPE target folder: drive letter as a plain dir.
+	alice := filepath.Join(dataDir, "kape", "C", "Users", "alice")
+	makeChromiumInstall(t, filepath.Join(alice, "AppData", "Local", "Google", "Chrome", "User Data"))
+	makeFirefoxProfile(t, filepath.Join(alice, "AppData", "Roaming", "Mozilla", "Firefox", "Profiles"), "x.default")
+	makeChromiumInstall(t, filepath.Join(alice, "AppData", "Roaming", "Slack")) // Electron, not a browser
+	// Velociraptor collection: URL-encoded components.
+	bob := filepath.Join(dataDir, "uploads", "auto", "C%3A", "Users", "bob")
+	makeChromiumInstall(t, filepath.Join(bob, "AppData", "Local", "Google", "Chrome", "User Data"))
+	makeChromiumInstall(t, filepath.Join(bob, "AppData", "Local", "Packages", "TheBrowserCompany.Arc_ttt",
+		"LocalCache", "Local", "Arc", "User Data"))
+	makeChromiumInstall(t, filepath.Join(bob, "AppData", "Roaming", "Opera%20Software", "Opera%20Stable"))
+	// Home-dir tarballs from Linux and macOS.
+	makeChromiumInstall(t, filepath.Join(dataDir, "home", "carol", ".config", "BraveSoftware", "Brave-Browser"))
+	library := filepath.Join(dataDir, "Users", "dave", "Library")
+	mkFile(t, library, "Safari", "History.db")
+	makeChromiumInstall(t, filepath.Join(library, "Application Support", "Microsoft Edge"))
+
+	got := make(map[string]string)
+	kinds := make(map[string]types.BrowserKind)
+	for _, r := range findCollectionRoots(dataDir) {
+		got[r.key], kinds[r.key] = r.dir, r.kind
+	}
+	assert.Equal(t, map[string]string{
+		"chrome@alice": filepath.Join(alice, "AppData", "Local", "Google", "Chrome", "User Data"),
+		"chrome@bob":   filepath.Join(bob, "AppData", "Local", "Google", "Chrome", "User Data"),
+		"firefox":      filepath.Join(alice, "AppData", "Roaming", "Mozilla", "Firefox", "Profiles"),
+		"arc": filepath.Join(bob, "AppData", "Local", "Packages", "TheBrowserCompany.Arc_ttt",
+			"LocalCache", "Local", "Arc", "User Data"),
+		"opera":  filepath.Join(bob, "AppData", "Roaming", "Opera%20Software", "Opera%20Stable"),
+		"brave":  filepath.Join(dataDir, "home", "carol", ".config", "BraveSoftware", "Brave-Browser"),
+		"safari": library,
+		"edge":   filepath.Join(library, "Application Support", "Microsoft Edge"),
+	}, got)
+	assert.Equal(t, types.ChromiumOpera, kinds["opera"])
+	assert.Equal(t, types.Safari, kinds["safari"])
+}
+
+func TestBuildFromDump_Collection(t *testing.T) {
+	dataDir := t.TempDir()
+	chrome := filepath.Join(dataDir, "uploads", "auto", "C%3A", "Users", "alice", "AppData", "Local", "Google", "Chrome", "User Data")
+	makeChromiumInstall(t, chrome)
+	profiles := filepath.Join(dataDir, "uploads", "auto", "C%3A", "Users", "alice", "AppData", "Roaming", "Mozilla", "Firefox", "Profiles")
+	makeFirefoxProfile(t, profiles, "x.default")
+
+	dump := masterkey.Dump{Vaults: []masterkey.Vault{
+		{Browser: "chrome", Kind: "chromium", Keys: masterkey.MasterKeys{V10: []byte("k")}},
+		{Browser: "edge", Kind: "chromium"},
+	}}
+	browsers, err := BuildFromDump(dump, dataDir, "")
+	require.NoError(t, err)
+	require.Len(t, browsers, 1, "edge has no data in the collection")
+	assert.Equal(t, chrome, browsers[0].UserDataDir())
+
+	browsers, err = BuildKeyless(dataDir, "")
+	require.NoError(t, err)
+	require.Len(t, browsers, 1)
+	assert.Equal(t, profiles, browsers[0].UserDataDir())
+}
+
+// TestInstallTailsMatchPlatformTable keeps the cross-OS install paths in step with this platform's table.
+func TestInstallTailsMatchPlatformTable(t *testing.T) {
+	for _, b := range platformBrowsers() {
+		tail := strings.TrimPrefix(filepath.ToSlash(b.UserDataDir), filepath.ToSlash(homeDir)+"/")
+		if b.Kind == types.Safari {
+			tail = path.Dir(tail) // collections are matched at the Library root
+		}
+		found := false
+		for _, it := range installTails {
+			if it.key == b.Key && it.tail == tail {
+				found = true
+			}
+		}
+		assert.True(t, found, "%s: no installTails entry for %q", b.Key, tail)
+	}
+
+	// Every OS's install dir, found inside a collection, is named by its own key.
+	for _, table := range []map[string]string{windowsInstallDirs, macInstallDirs, linuxInstallDirs} {
+		for key, dir := range table {
+			kind := types.Chromium
+			switch key {
+			case "firefox":
+				kind = types.Firefox
+			case "safari":
+				kind, dir = types.Safari, path.Dir(dir)
+			}
+			got, ok := installKey("C/Users/alice/"+dir, kind)
+			assert.True(t, ok && got == key, "%s: %q matched as %q", key, dir, got)
+		}
+	}
+}
@@ -8,24 +8,26 @@ import (
 var homeDir, _ = os.UserHomeDir()
 
 const (
-	chromeName     = "Chrome"
-	chromeBetaName = "Chrome Beta"
-	chromiumName   = "Chromium"
-	edgeName       = "Microsoft Edge"
-	braveName      = "Brave"
-	operaName      = "Opera"
-	operaGXName    = "OperaGX"
-	voughtName     = "Browser from Vought"
-	vivaldiName    = "Vivaldi"
-	coccocName     = "CocCoc"
-	yandexName     = "Yandex"
-	firefoxName    = "Firefox"
-	speed360Name   = "360 Speed"
-	speed360XName  = "360 Speed X"
-	qqName         = "QQ"
-	dcName         = "DC"
-	sogouName      = "Sogou"
-	arcName        = "Arc"
-	duckduckgoName = "DuckDuckGo"
-	safariName     = "Safari"
+	chromeName          = "Chrome"
+	chromeBetaName      = "Chrome Beta"
+	chromeFlatpakName   = "Chrome Flatpak"
+	chromiumName        = "Chromium"
+	chromiumFlatpakName = "Chromium Flatpak"
+	edgeName            = "Microsoft Edge"
+	braveName           = "Brave"
+	operaName           = "Opera"
+	operaGXName         = "OperaGX"
+	voughtName          = "Browser from Vought"
+	vivaldiName         = "Vivaldi"
+	coccocName          = "CocCoc"
+	yandexName          = "Yandex"
+	firefoxName         = "Firefox"
+	speed360Name        = "360 Speed"
+	speed360XName       = "360 Speed X"
+	qqName              = "QQ"
+	dcName              = "DC"
+	sogouName           = "Sogou"
+	arcName             = "Arc"
+	duckduckgoName      = "DuckDuckGo"
+	safariName          = "Safari"
 )
@@ -0,0 +1,23 @@
+package browser
+
+import (
+	"github.com/moond4rk/hackbrowserdata/crypto/dpapi"
+	"github.com/moond4rk/hackbrowserdata/masterkey"
+)
+
+// BuildFromDPAPI reconstructs Chromium engines from copied Windows data without a dump: each
+// installation's v10 key is unwrapped from its own Local State with user master keys unlocked offline.
+// dataDir takes BuildFromDump's two layouts — per-key subdirs (an archive, or several User Data copies
+// named by browser key), or one browser's User Data, which filter must name. Only the Local State
+// location matters, so the browser key just labels the output and picks the engine kind. v20
+// (App-Bound) values stay encrypted: their key is bound to the source host and needs dumpkeys there.
+func BuildFromDPAPI(ring *dpapi.KeyRing, dataDir, filter string) ([]Browser, error) {
+	dump, err := keylessVaults(dataDir, filter)
+	if err != nil {
+		return nil, err
+	}
+	retriever := &masterkey.OfflineDPAPIRetriever{KeyRing: ring}
+	return buildFromVaults(dump, dataDir, filter, nil, func(masterkey.Vault) masterkey.Retrievers {
+		return masterkey.Retrievers{V10: retriever}
+	})
+}
@@ -0,0 +1,97 @@
+package browser
+
+import (
+	"bytes"
+	"encoding/base64"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/moond4rk/hackbrowserdata/crypto/dpapi"
+	"github.com/moond4rk/hackbrowserdata/types"
+)
+
+const testDPAPIGUID = "0f1e2d3c-4b5a-6978-8796-a5b4c3d2e1f0"
+
+// makeWindowsUserData lays out a User Data copy whose Local State wraps chromeKey in a DPAPI blob
+// sealed under userKey, as Chrome on Windows writes it.
+func makeWindowsUserData(t *testing.T, root string, userKey, chromeKey []byte) {
+	t.Helper()
+	makeUserData(t, root, testProfileDefault)
+	blob, err := dpapi.Protect(testDPAPIGUID, userKey, chromeKey)
+	require.NoError(t, err)
+	encrypted := base64.StdEncoding.EncodeToString(append([]byte("DPAPI"), blob...))
+	localState := `{"os_crypt":{"encrypted_key":"` + encrypted + `"}}`
+	require.NoError(t, os.WriteFile(filepath.Join(root, "Local State"), []byte(localState), 0o600))
+}
+
+func testKeyRing(userKey []byte) *dpapi.KeyRing {
+	ring := dpapi.NewKeyRing()
+	ring.Add(testDPAPIGUID, userKey)
+	return ring
+}
+
+func TestBuildFromDPAPI_RawUserData(t *testing.T) {
+	userKey := bytes.Repeat([]byte{0x11}, 64)
+	chromeKey := bytes.Repeat([]byte{0xc0}, 32)
+	dataDir := t.TempDir()
+	makeWindowsUserData(t, dataDir, userKey, chromeKey)
+
+	browsers, err := BuildFromDPAPI(testKeyRing(userKey), dataDir, "chrome")
+	require.NoError(t, err)
+	require.Len(t, browsers, 1)
+	assert.Equal(t, dataDir, browsers[0].UserDataDir())
+
+	km, ok := browsers[0].(KeyManager)
+	require.True(t, ok)
+	keys, err := km.ExportKeys()
+	require.NoError(t, err)
+	assert.Equal(t, chromeKey, keys.V10)
+	assert.Nil(t, keys.V20, "App-Bound keys cannot be recovered offline")
+
+	_, err = BuildFromDPAPI(testKeyRing(userKey), dataDir, "")
+	assert.ErrorContains(t, err, "-b <browser>")
+}
+
+func TestBuildFromDPAPI_PerBrowserSubdirs(t *testing.T) {
+	userKey := bytes.Repeat([]byte{0x22}, 64)
+	dataDir := t.TempDir()
+	makeWindowsUserData(t, filepath.Join(dataDir, "chrome"), userKey, bytes.Repeat([]byte{0xc1}, 32))
+	makeWindowsUserData(t, filepath.Join(dataDir, "opera"), userKey, bytes.Repeat([]byte{0x0b}, 32))
+	require.NoError(t, os.MkdirAll(filepath.Join(dataDir, "notes"), 0o755))
+
+	browsers, err := BuildFromDPAPI(testKeyRing(userKey), dataDir, "")
+	require.NoError(t, err)
+	assert.Len(t, browsers, 2)
+
+	opera, err := BuildFromDPAPI(testKeyRing(userKey), dataDir, "opera")
+	require.NoError(t, err)
+	require.Len(t, opera, 1)
+	km, ok := opera[0].(KeyManager)
+	require.True(t, ok)
+	assert.Equal(t, types.ChromiumOpera, km.Kind())
+
+	_, err = BuildFromDPAPI(testKeyRing(userKey), dataDir, "edge")
+	assert.ErrorContains(t, err, "have: chrome, opera")
+}
+
+func TestBuildFromDPAPI_NoLocalState(t *testing.T) {
+	dataDir := t.TempDir()
+	makeUserData(t, filepath.Join(dataDir, "chrome"), testProfileDefault)
+
+	_, err := BuildFromDPAPI(dpapi.NewKeyRing(), dataDir, "")
+	assert.ErrorContains(t, err, "no Local State")
+
+	_, err = BuildFromDPAPI(dpapi.NewKeyRing(), filepath.Join(dataDir, "missing"), "")
+	assert.ErrorContains(t, err, "does not exist")
+}
+
+func TestKindForKey(t *testing.T) {
+	assert.Equal(t, types.ChromiumOpera, kindForKey("opera-gx"))
+	assert.Equal(t, types.ChromiumOpera, kindForKey("vought"))
+	assert.Equal(t, types.ChromiumYandex, kindForKey("yandex"))
+	assert.Equal(t, types.Chromium, kindForKey("edge"))
+}
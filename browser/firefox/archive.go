@@ -0,0 +1,75 @@
+package firefox
+
+import (
+	"io/fs"
+	"path"
+	"path/filepath"
+
+	"github.com/moond4rk/hackbrowserdata/types"
+	"github.com/moond4rk/hackbrowserdata/utils/fileutil"
+)
+
+// ArchiveSources lists the files an archive must capture for the given categories, laid out under the
+// Profiles dir: each profile's key database (key4.db, or legacy key3.db) so passwords restore without a
+// key dump, plus every resolved category source. LayoutRel is forward-slash, relative to the root.
+func (b *Browser) ArchiveSources(categories []types.Category) []types.ArchiveSource {
+	var out []types.ArchiveSource
+	for _, p := range b.profiles {
+		profileRel := p.name()
+		for _, name := range keyDBFiles {
+			abs := filepath.Join(p.profileDir, name)
+			if fileutil.FileExists(abs) {
+				out = append(out, types.ArchiveSource{AbsPath: abs, LayoutRel: path.Join(profileRel, name)})
+			}
+		}
+		for _, cat := range categories {
+			rp, ok := p.sourcePaths[cat]
+			if !ok {
+				continue
+			}
+			out = append(out, types.ArchiveSource{
+				AbsPath:   rp.absPath,
+				LayoutRel: path.Join(profileRel, filepath.ToSlash(rp.rel)),
+				IsDir:     rp.isDir,
+			})
+		}
+	}
+	return out
+}
+
+// StagePaths lists what discovery and extraction of categories read from the Profiles dir at the slash
+// path root in fsys: each profile's key database and the first existing source of each category. It
+// lets an installation be copied out of a disk image without its caches and other bulk. Paths are
+// slash paths in fsys.
+func StagePaths(fsys fs.FS, root string, categories []types.Category) []string {
+	var out []string
+	seen := make(map[string]bool)
+	add := func(p string) bool {
+		if _, err := fs.Stat(fsys, p); err != nil {
+			return false
+		}
+		if !seen[p] {
+			seen[p] = true
+			out = append(out, p)
+		}
+		return true
+	}
+	entries, _ := fs.ReadDir(fsys, root)
+	for _, e := range entries {
+		if !e.IsDir() {
+			continue
+		}
+		dir := path.Join(root, e.Name())
+		for _, name := range keyDBFiles {
+			add(path.Join(dir, name))
+		}
+		for _, cat := range categories {
+			for _, sp := range firefoxSources[cat] {
+				if add(path.Join(dir, filepath.ToSlash(sp.rel))) {
+					break
+				}
+			}
+		}
+	}
+	return out
+}
@@ -0,0 +1,52 @@
+package firefox
+
+import (
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/moond4rk/hackbrowserdata/types"
+)
+
+func TestArchiveSources(t *testing.T) {
+	root := t.TempDir()
+	mkFile(root, "a.default-release", "key4.db")
+	mkFile(root, "a.default-release", "logins.json")
+	mkFile(root, "a.default-release", "cookies.sqlite")
+	mkFile(root, "b.legacy", "key3.db")
+	mkFile(root, "b.legacy", "signons.sqlite")
+
+	b, err := NewBrowser(types.BrowserConfig{Key: "firefox", Name: "Firefox", Kind: types.Firefox, UserDataDir: root})
+	require.NoError(t, err)
+	require.NotNil(t, b)
+
+	var rels []string
+	for _, s := range b.ArchiveSources([]types.Category{types.Password}) {
+		assert.False(t, s.IsDir)
+		assert.Equal(t, filepath.Join(root, filepath.FromSlash(s.LayoutRel)), s.AbsPath)
+		rels = append(rels, s.LayoutRel)
+	}
+	assert.ElementsMatch(t, []string{
+		"a.default-release/key4.db", "a.default-release/logins.json",
+		"b.legacy/key3.db", "b.legacy/signons.sqlite",
+	}, rels, "key databases always travel; cookies only when asked for")
+}
+
+func TestStagePaths(t *testing.T) {
+	profiles := "home/alice/.mozilla/firefox"
+	fsys := fstest.MapFS{
+		profiles + "/a.default-release/key4.db":             {Data: []byte("key4")},
+		profiles + "/a.default-release/logins.json":         {Data: []byte("{}")},
+		profiles + "/a.default-release/cookies.sqlite":      {Data: []byte("cookies")},
+		profiles + "/a.default-release/cache2/entries/ABCD": {Data: []byte("cache")},
+		profiles + "/b.legacy/key3.db":                      {Data: []byte("key3")},
+		profiles + "/b.legacy/signons.sqlite":               {Data: []byte("signons")},
+	}
+	assert.Equal(t, []string{
+		profiles + "/a.default-release/key4.db", profiles + "/a.default-release/logins.json",
+		profiles + "/b.legacy/key3.db", profiles + "/b.legacy/signons.sqlite",
+	}, StagePaths(fsys, profiles, []types.Category{types.Password}))
+}
@@ -0,0 +1,170 @@
+package firefox
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/moond4rk/hackbrowserdata/log"
+	"github.com/moond4rk/hackbrowserdata/types"
+	"github.com/moond4rk/hackbrowserdata/utils/cacheutil"
+)
+
+// cache2 entry file layout, see netwerk/cache2/CacheFileMetadata.h.
+const (
+	cacheEntriesDir        = "entries"
+	cacheEntryNameLen      = 40 // hex SHA-1 of the key
+	cacheChunkSize         = 256 * 1024
+	cacheMetadataHeaderLen = 32 // version, fetch count, last fetched, last modified, frecency, expiration, key size, flags
+	cacheMetadataV1HdrLen  = 28 // no flags
+)
+
+// cacheEntry is the part of a cache2 entry file extractCache uses.
+type cacheEntry struct {
+	key          string
+	fetchCount   uint32
+	lastModified uint32 // seconds since the Unix epoch
+	elements     map[string]string
+	body         []byte
+}
+
+// extractCache reads the entry files of a cache2 directory. When bodies is non-nil each non-empty body
+// is decoded and saved through it, named after its entry file.
+func extractCache(path string, bodies *cacheutil.BodyWriter) ([]types.CacheEntry, error) {
+	names, err := cacheEntryNames(path)
+	if err != nil {
+		return nil, err
+	}
+	var (
+		entries []types.CacheEntry
+		errs    []error
+	)
+	for _, name := range names {
+		data, err := os.ReadFile(filepath.Join(path, cacheEntriesDir, name))
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		e, err := parseCacheEntry(data)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", name, err))
+			continue
+		}
+		status, header := cacheutil.ParseHeaders(e.elements["response-head"])
+		entry := types.CacheEntry{
+			URL:          cacheKeyURL(e.key),
+			Status:       status,
+			ContentType:  header.Get("Content-Type"),
+			Size:         int64(len(e.body)),
+			ResponseTime: firefoxSeconds(int64(e.lastModified)),
+			FetchCount:   int(e.fetchCount),
+		}
+		if bodies != nil && len(e.body) > 0 {
+			file, err := bodies.Write(name, entry.ContentType, header.Get("Content-Encoding"), e.body)
+			if err != nil {
+				log.Debugf("cache body %s: %v", entry.URL, err)
+			}
+			entry.BodyFile = file
+		}
+		entries = append(entries, entry)
+	}
+	if len(errs) > 0 {
+		log.Debugf("cache %s: %v", path, errors.Join(errs...))
+	}
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].ResponseTime.After(entries[j].ResponseTime)
+	})
+	return entries, nil
+}
+
+func countCache(path string) (int, error) {
+	names, err := cacheEntryNames(path)
+	return len(names), err
+}
+
+// cacheEntryNames lists the entry files of a cache2 directory, each named by the SHA-1 of its key.
+func cacheEntryNames(path string) ([]string, error) {
+	files, err := os.ReadDir(filepath.Join(path, cacheEntriesDir))
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, f := range files {
+		name := f.Name()
+		if !f.IsDir() && len(name) == cacheEntryNameLen && strings.Trim(name, "0123456789ABCDEFabcdef") == "" {
+			names = append(names, name)
+		}
+	}
+	return names, nil
+}
+
+// parseCacheEntry decodes an entry file: the body, then the metadata, whose offset the file's last four
+// bytes hold. The metadata starts with a checksum of itself and one per 256 KiB chunk of the body,
+// then a header, the NUL-terminated key, and NUL-separated name/value elements up to the offset.
+// All integers are big-endian. Alternative data (e.g. compiled script) is stored after the body, at
+// the offset its alt-data element names, and is left out.
+func parseCacheEntry(data []byte) (cacheEntry, error) {
+	if len(data) < 4 {
+		return cacheEntry{}, errors.New("too short for an entry")
+	}
+	be := binary.BigEndian
+	metaOff := int(be.Uint32(data[len(data)-4:]))
+	chunks := (metaOff + cacheChunkSize - 1) / cacheChunkSize
+	hdr := metaOff + 4 + 2*chunks
+	if metaOff < 0 || hdr+cacheMetadataV1HdrLen > len(data)-4 {
+		return cacheEntry{}, errors.New("metadata offset out of range")
+	}
+	version := be.Uint32(data[hdr:])
+	e := cacheEntry{
+		fetchCount:   be.Uint32(data[hdr+4:]),
+		lastModified: be.Uint32(data[hdr+12:]),
+		elements:     make(map[string]string),
+		body:         data[:metaOff],
+	}
+	keySize := int(be.Uint32(data[hdr+24:]))
+	keyStart := hdr + cacheMetadataV1HdrLen
+	if version >= 2 {
+		keyStart = hdr + cacheMetadataHeaderLen
+	}
+	if keySize < 0 || keyStart+keySize+1 > len(data)-4 {
+		return cacheEntry{}, errors.New("key runs past the metadata")
+	}
+	e.key = string(data[keyStart : keyStart+keySize])
+
+	fields := bytes.Split(data[keyStart+keySize+1:len(data)-4], []byte{0})
+	for i := 0; i+1 < len(fields); i += 2 {
+		e.elements[string(fields[i])] = string(fields[i+1])
+	}
+	if off, ok := altDataOffset(e.elements["alt-data"]); ok && off < len(e.body) {
+		e.body = e.body[:off]
+	}
+	return e, nil
+}
+
+// altDataOffset reads the body length out of an alt-data element, "<version>;<offset>,<type>".
+func altDataOffset(v string) (int, bool) {
+	_, rest, ok := strings.Cut(v, ";")
+	if !ok {
+		return 0, false
+	}
+	off, _, _ := strings.Cut(rest, ",")
+	n, err := strconv.Atoi(off)
+	return n, err == nil && n >= 0
+}
+
+// cacheKeyURL returns the URL a cache key names. The key is a comma-separated list of tags (origin
+// attributes, "a" for anonymous, ...) ending with ':' and the URL, which may itself contain commas.
+func cacheKeyURL(key string) string {
+	for i := 0; i < len(key); i++ {
+		if key[i] == ':' && (i == 0 || key[i-1] == ',') {
+			return key[i+1:]
+		}
+	}
+	return key
+}
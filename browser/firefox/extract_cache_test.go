@@ -0,0 +1,151 @@
+package firefox
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/moond4rk/hackbrowserdata/types"
+	"github.com/moond4rk/hackbrowserdata/utils/cacheutil"
+)
+
+// cacheModified is 2024-01-02 03:04:05 UTC.
+const cacheModified = 1704164645
+
+// testCacheEntry lays out a cache2 entry file: the body, the metadata (checksums, header, key and
+// elements), and the metadata offset.
+func testCacheEntry(key string, body []byte, fetchCount uint32, elements ...string) []byte {
+	be := binary.BigEndian
+	f := append([]byte{}, body...)
+	f = be.AppendUint32(f, 0) // metadata checksum
+	for i := 0; i < (len(body)+cacheChunkSize-1)/cacheChunkSize; i++ {
+		f = be.AppendUint16(f, 0)
+	}
+	for _, v := range []uint32{3, fetchCount, cacheModified + 60, cacheModified, 0, 0, uint32(len(key)), 0} {
+		f = be.AppendUint32(f, v)
+	}
+	f = append(append(f, key...), 0)
+	for _, e := range elements {
+		f = append(append(f, e...), 0)
+	}
+	return be.AppendUint32(f, uint32(len(body)))
+}
+
+func createTestCache(t *testing.T, files map[string][]byte) string {
+	t.Helper()
+	dir := filepath.Join(t.TempDir(), cacheDirName)
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, cacheEntriesDir), 0o755))
+	for name, data := range files {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, cacheEntriesDir, name), data, 0o644))
+	}
+	return dir
+}
+
+func TestExtractCache(t *testing.T) {
+	var gz bytes.Buffer
+	zw := gzip.NewWriter(&gz)
+	_, err := zw.Write([]byte("<html>cached</html>"))
+	require.NoError(t, err)
+	require.NoError(t, zw.Close())
+	script := append([]byte("var a = 1;"), bytes.Repeat([]byte{0xee}, 16)...) // compiled script after the source
+
+	dir := createTestCache(t, map[string][]byte{
+		"0123456789ABCDEF0123456789ABCDEF01234567": testCacheEntry(
+			"O^partitionKey=%28https%2Cexample.com%29,a,:https://example.com/page?a=1,2",
+			gz.Bytes(), 4,
+			"request-method", "GET",
+			"response-head", "HTTP/2 200 \r\ncontent-type: text/html\r\ncontent-encoding: gzip\r\n",
+		),
+		"89ABCDEF0123456789ABCDEF0123456789ABCDEF": testCacheEntry(
+			":https://example.com/app.js", script, 1,
+			"response-head", "HTTP/1.1 200 OK\r\nContent-Type: text/javascript\r\n",
+			"alt-data", "1;10,javascript/moz-script-bytecode",
+		),
+		"not-an-entry": []byte("ignored"),
+		"FEDCBA9876543210FEDCBA9876543210FEDCBA98": []byte("bad"),
+	})
+	bodyDir := filepath.Join(t.TempDir(), "bodies")
+
+	got, err := extractCache(dir, cacheutil.NewBodyWriter(bodyDir))
+	require.NoError(t, err)
+	require.Len(t, got, 2, "the damaged entry is skipped")
+
+	byURL := map[string]types.CacheEntry{}
+	for _, e := range got {
+		byURL[e.URL] = e
+	}
+	page := byURL["https://example.com/page?a=1,2"]
+	assert.Equal(t, 200, page.Status)
+	assert.Equal(t, "text/html", page.ContentType)
+	assert.Equal(t, int64(gz.Len()), page.Size)
+	assert.Equal(t, 4, page.FetchCount)
+	assert.Equal(t, int64(cacheModified), page.ResponseTime.Unix())
+	body, err := os.ReadFile(page.BodyFile)
+	require.NoError(t, err)
+	assert.Equal(t, "<html>cached</html>", string(body))
+
+	js := byURL["https://example.com/app.js"]
+	assert.Equal(t, int64(10), js.Size, "alternative data left out")
+	body, err = os.ReadFile(js.BodyFile)
+	require.NoError(t, err)
+	assert.Equal(t, "var a = 1;", string(body))
+
+	count, err := countCache(dir)
+	require.NoError(t, err)
+	assert.Equal(t, 3, count, "entry files, damaged or not")
+}
+
+func TestParseCacheEntry_Empty(t *testing.T) {
+	e, err := parseCacheEntry(testCacheEntry(":https://example.com/", nil, 0, "response-head", "HTTP/1.1 304 Not Modified\r\n"))
+	require.NoError(t, err)
+	assert.Equal(t, ":https://example.com/", e.key)
+	assert.Empty(t, e.body)
+	assert.Equal(t, "HTTP/1.1 304 Not Modified\r\n", e.elements["response-head"])
+
+	_, err = parseCacheEntry([]byte{0, 0, 1, 0})
+	assert.Error(t, err)
+}
+
+func TestCacheKeyURL(t *testing.T) {
+	tests := []struct {
+		key, want string
+	}{
+		{":https://example.com/", "https://example.com/"},
+		{"a,:https://example.com/x,y", "https://example.com/x,y"},
+		{"O^userContextId=1,:http://example.com/", "http://example.com/"},
+		{"no url", "no url"},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, cacheKeyURL(tt.key))
+	}
+}
+
+func TestResolveCacheDir(t *testing.T) {
+	root := t.TempDir()
+	profiles := filepath.Join(root, "Profiles")
+	caches := filepath.Join(root, "Caches")
+	mkFile(profiles, "a1.default", "places.sqlite")
+	mkDir(caches, "a1.default", cacheDirName, cacheEntriesDir)
+	mkFile(profiles, "b2.default", "places.sqlite")
+	mkDir(profiles, "b2.default", cacheDirName)
+
+	b, err := NewBrowser(types.BrowserConfig{Name: "Firefox", Kind: types.Firefox, UserDataDir: profiles, CacheDir: caches})
+	require.NoError(t, err)
+	require.Len(t, b.profiles, 2)
+	for _, p := range b.profiles {
+		rp, ok := p.sourcePaths[types.Cache]
+		require.True(t, ok, p.name())
+		assert.Equal(t, cacheDirName, rp.rel)
+		if p.name() == "a1.default" {
+			assert.Equal(t, filepath.Join(caches, "a1.default", cacheDirName), rp.absPath, "the local cache dir")
+		} else {
+			assert.Equal(t, filepath.Join(profiles, "b2.default", cacheDirName), rp.absPath, "a cache collected into the profile")
+		}
+	}
+}
@@ -2,7 +2,9 @@ package firefox
 
 import (
 	"database/sql"
+	"fmt"
 	"sort"
+	"strings"
 
 	"github.com/moond4rk/hackbrowserdata/types"
 	"github.com/moond4rk/hackbrowserdata/utils/sqliteutil"
@@ -28,17 +30,6 @@ func extractCookies(path string) ([]types.CookieEntry, error) {
 				return types.CookieEntry{}, err
 			}
 			hasExpire := expiry > 0
-			sameSiteStr := "unspecified"
-			switch sameSite {
-			case 0:
-				sameSiteStr = "none"
-			case 1:
-				sameSiteStr = "lax"
-			case 2:
-				sameSiteStr = "strict"
-			case 256:
-				// not specified by Set-Cookie
-			}
 			return types.CookieEntry{
 				Name:         name,
 				Host:         host,
@@ -50,7 +41,7 @@ func extractCookies(path string) ([]types.CookieEntry, error) {
 				IsPersistent: hasExpire,
 				ExpireAt:     firefoxSeconds(expiry),
 				CreatedAt:    firefoxMicros(createdAt),
-				SameSite:     sameSiteStr,
+				SameSite:     sameSiteName(sameSite),
 			}, nil
 		})
 	if err != nil {
@@ -63,6 +54,52 @@ func extractCookies(path string) ([]types.CookieEntry, error) {
 	return cookies, nil
 }
 
+// recoverCookies carves deleted cookies out of the moz_cookies table's free space (see
+// sqliteutil.Carve) and the row versions wal superseded (see sqliteutil.WAL), leaving out any that
+// duplicate a live entry.
+func recoverCookies(path string, wal *sqliteutil.WAL, live []types.CookieEntry) ([]types.CookieEntry, error) {
+	carved, err := sqliteutil.CarveRows(path, true, wal, "moz_cookies", live,
+		func(r sqliteutil.Record) (types.CookieEntry, error) {
+			host := r.Text("host")
+			if host == "" || strings.ContainsAny(host, " /\x00") || r.Int("creationTime") <= 0 {
+				return types.CookieEntry{}, fmt.Errorf("not a cookie: host %q", host)
+			}
+			expiry := r.Int("expiry")
+			return types.CookieEntry{
+				Name:         r.Text("name"),
+				Host:         host,
+				Path:         r.Text("path"),
+				Value:        r.Text("value"),
+				IsSecure:     r.Int("isSecure") != 0,
+				IsHTTPOnly:   r.Int("isHttpOnly") != 0,
+				HasExpire:    expiry > 0,
+				IsPersistent: expiry > 0,
+				ExpireAt:     firefoxSeconds(expiry),
+				CreatedAt:    firefoxMicros(r.Int("creationTime")),
+				SameSite:     sameSiteName(int(r.Int("sameSite"))),
+			}, nil
+		})
+	cookies := make([]types.CookieEntry, 0, len(carved))
+	for _, c := range carved {
+		c.Item.Recovered, c.Item.RecoveredFrom = true, c.Origin
+		cookies = append(cookies, c.Item)
+	}
+	return cookies, err
+}
+
+// sameSiteName names a moz_cookies.sameSite value.
+func sameSiteName(sameSite int) string {
+	switch sameSite {
+	case 0:
+		return "none"
+	case 1:
+		return "lax"
+	case 2:
+		return "strict"
+	}
+	return "unspecified" // 256: not specified by Set-Cookie
+}
+
 func countCookies(path string) (int, error) {
 	return sqliteutil.CountRows(path, true, firefoxCountCookieQuery)
 }
@@ -39,6 +39,27 @@ func TestExtractCookies(t *testing.T) {
 	assert.True(t, got[1].IsHTTPOnly)
 }
 
+func TestRecoverCookies(t *testing.T) {
+	path := createTestDB(t, "cookies.sqlite", []string{mozCookiesSchema},
+		insertMozCookie("session", "abc123", ".example.com", "/", 1700000000000000, 1800000000, 1, 1),
+		insertMozCookie("sid", "gone-value", ".cleared.example", "/app", 1705000000000000, 1805000000, 0, 1),
+		`DELETE FROM moz_cookies WHERE host = '.cleared.example'`,
+	)
+	live, err := extractCookies(path)
+	require.NoError(t, err)
+
+	got, err := recoverCookies(path, nil, live)
+	require.NoError(t, err)
+	require.Len(t, got, 1)
+	assert.Equal(t, "sid", got[0].Name)
+	assert.Equal(t, "gone-value", got[0].Value)
+	assert.Equal(t, ".cleared.example", got[0].Host)
+	assert.Equal(t, "/app", got[0].Path)
+	assert.True(t, got[0].HasExpire)
+	assert.Equal(t, firefoxSeconds(1805000000), got[0].ExpireAt)
+	assert.True(t, got[0].Recovered)
+}
+
 func TestCountCookies(t *testing.T) {
 	path := setupMozCookieDB(t)
 
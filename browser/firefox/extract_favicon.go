@@ -0,0 +1,100 @@
+package firefox
+
+import (
+	"database/sql"
+	"net/http"
+	"sort"
+	"strconv"
+
+	"github.com/moond4rk/hackbrowserdata/log"
+	"github.com/moond4rk/hackbrowserdata/types"
+	"github.com/moond4rk/hackbrowserdata/utils/cacheutil"
+	"github.com/moond4rk/hackbrowserdata/utils/sqliteutil"
+)
+
+// moz_icons keeps one row per icon URL and size; moz_icons_to_pages links them to moz_pages_w_icons.
+const (
+	firefoxFaviconQuery = `SELECT moz_pages_w_icons.page_url, moz_icons.id, moz_icons.icon_url,
+		moz_icons.width, moz_icons.expire_ms, moz_icons.data
+		FROM moz_icons_to_pages
+		JOIN moz_pages_w_icons ON moz_pages_w_icons.id = moz_icons_to_pages.page_id
+		JOIN moz_icons ON moz_icons.id = moz_icons_to_pages.icon_id`
+	firefoxCountFaviconQuery = `SELECT COUNT(*) FROM (SELECT DISTINCT moz_icons_to_pages.page_id, moz_icons.icon_url
+		FROM moz_icons_to_pages JOIN moz_icons ON moz_icons.id = moz_icons_to_pages.icon_id)`
+)
+
+// faviconRow is one size of a page's icon.
+type faviconRow struct {
+	pageURL, iconURL string
+	iconID           int64
+	width            int
+	expireMs         int64
+	data             []byte
+}
+
+// extractFavicons reads favicons.sqlite, one entry per page and icon URL: the icon's sizes are folded
+// together, keeping the latest expiry. When images is non-nil the largest size of each icon is saved
+// through it, named after its moz_icons row id.
+func extractFavicons(path string, images *cacheutil.BodyWriter) ([]types.FaviconEntry, error) {
+	rows, err := sqliteutil.QueryRows(path, true, firefoxFaviconQuery,
+		func(rows *sql.Rows) (faviconRow, error) {
+			var r faviconRow
+			err := rows.Scan(&r.pageURL, &r.iconID, &r.iconURL, &r.width, &r.expireMs, &r.data)
+			return r, err
+		})
+	if err != nil {
+		return nil, err
+	}
+
+	type key struct{ pageURL, iconURL string }
+	var (
+		order   []key
+		folded  = make(map[key]*faviconRow)
+		largest = make(map[string]*faviconRow) // by icon URL
+	)
+	for i := range rows {
+		r := &rows[i]
+		k := key{r.pageURL, r.iconURL}
+		f, ok := folded[k]
+		if !ok {
+			order = append(order, k)
+			f = &faviconRow{pageURL: r.pageURL, iconURL: r.iconURL}
+			folded[k] = f
+		}
+		if r.expireMs > f.expireMs {
+			f.expireMs = r.expireMs
+		}
+		if l, ok := largest[r.iconURL]; len(r.data) > 0 && (!ok || r.width > l.width) {
+			largest[r.iconURL] = r
+		}
+	}
+
+	files := make(map[string]string)
+	if images != nil {
+		for iconURL, r := range largest {
+			file, err := images.Write(strconv.FormatInt(r.iconID, 10), http.DetectContentType(r.data), "", r.data)
+			if err != nil {
+				log.Debugf("favicon image %s: %v", iconURL, err)
+			}
+			files[iconURL] = file
+		}
+	}
+
+	favicons := make([]types.FaviconEntry, 0, len(order))
+	for _, k := range order {
+		favicons = append(favicons, types.FaviconEntry{
+			PageURL:   k.pageURL,
+			IconURL:   k.iconURL,
+			ExpiresAt: firefoxMillis(folded[k].expireMs),
+			IconFile:  files[k.iconURL],
+		})
+	}
+	sort.SliceStable(favicons, func(i, j int) bool {
+		return favicons[i].ExpiresAt.After(favicons[j].ExpiresAt)
+	})
+	return favicons, nil
+}
+
+func countFavicons(path string) (int, error) {
+	return sqliteutil.CountRows(path, true, firefoxCountFaviconQuery)
+}
@@ -0,0 +1,65 @@
+package firefox
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/moond4rk/hackbrowserdata/utils/cacheutil"
+)
+
+var testPNG = []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR")
+
+func setupFaviconsDB(t *testing.T) string {
+	t.Helper()
+	return createTestDB(t, "favicons.sqlite",
+		[]string{mozIconsSchema, mozPagesWIconsSchema, mozIconsToPagesSchema},
+		insertMozIcon(1, "https://example.com/favicon.png", 16, 1704164645000, []byte("\x00\x00\x01\x00small")),
+		insertMozIcon(2, "https://example.com/favicon.png", 32, 1704164646000, testPNG),
+		insertMozIcon(3, "https://go.dev/icon.svg", 65535, 1700000000000, nil),
+		insertMozPageWIcon(1, "https://example.com/a"),
+		insertMozPageWIcon(2, "https://go.dev/"),
+		insertMozIconToPage(1, 1),
+		insertMozIconToPage(1, 2),
+		insertMozIconToPage(2, 3),
+	)
+}
+
+func TestExtractFavicons(t *testing.T) {
+	path := setupFaviconsDB(t)
+	imageDir := filepath.Join(t.TempDir(), "favicons")
+
+	got, err := extractFavicons(path, cacheutil.NewBodyWriter(imageDir))
+	require.NoError(t, err)
+	require.Len(t, got, 2, "the sizes of an icon are folded together")
+
+	page := got[0]
+	assert.Equal(t, "https://example.com/a", page.PageURL)
+	assert.Equal(t, "https://example.com/favicon.png", page.IconURL)
+	assert.Equal(t, firefoxMillis(1704164646000), page.ExpiresAt, "the latest expiry")
+	assert.True(t, page.LastUpdated.IsZero())
+	assert.Equal(t, filepath.Join(imageDir, "2.png"), page.IconFile, "the largest size")
+	data, err := os.ReadFile(page.IconFile)
+	require.NoError(t, err)
+	assert.Equal(t, testPNG, data)
+
+	goDev := got[1]
+	assert.Equal(t, "https://go.dev/", goDev.PageURL)
+	assert.Empty(t, goDev.IconFile, "no image stored")
+
+	count, err := countFavicons(path)
+	require.NoError(t, err)
+	assert.Equal(t, 2, count)
+}
+
+func TestExtractFavicons_NoImages(t *testing.T) {
+	got, err := extractFavicons(setupFaviconsDB(t), nil)
+	require.NoError(t, err)
+	require.Len(t, got, 2)
+	for _, e := range got {
+		assert.Empty(t, e.IconFile)
+	}
+}
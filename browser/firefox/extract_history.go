@@ -2,6 +2,7 @@ package firefox
 
 import (
 	"database/sql"
+	"fmt"
 	"sort"
 
 	"github.com/moond4rk/hackbrowserdata/types"
@@ -40,6 +41,31 @@ func extractHistories(path string) ([]types.HistoryEntry, error) {
 	return histories, nil
 }
 
+// recoverHistories carves deleted rows out of the moz_places table's free space (see
+// sqliteutil.Carve) and the row versions wal superseded (see sqliteutil.WAL), leaving out any that
+// duplicate a live entry.
+func recoverHistories(path string, wal *sqliteutil.WAL, live []types.HistoryEntry) ([]types.HistoryEntry, error) {
+	carved, err := sqliteutil.CarveRows(path, true, wal, "moz_places", live,
+		func(r sqliteutil.Record) (types.HistoryEntry, error) {
+			url := r.Text("url")
+			if !sqliteutil.LooksLikeURL(url) {
+				return types.HistoryEntry{}, fmt.Errorf("not a url: %q", url)
+			}
+			return types.HistoryEntry{
+				URL:        url,
+				Title:      r.Text("title"),
+				VisitCount: int(r.Int("visit_count")),
+				LastVisit:  firefoxMicros(r.Int("last_visit_date")),
+			}, nil
+		})
+	histories := make([]types.HistoryEntry, 0, len(carved))
+	for _, c := range carved {
+		c.Item.Recovered, c.Item.RecoveredFrom = true, c.Origin
+		histories = append(histories, c.Item)
+	}
+	return histories, err
+}
+
 func countHistories(path string) (int, error) {
 	return sqliteutil.CountRows(path, true, firefoxCountHistoryQuery)
 }
@@ -1,10 +1,14 @@
 package firefox
 
 import (
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+
+	"github.com/moond4rk/hackbrowserdata/types"
+	"github.com/moond4rk/hackbrowserdata/utils/sqliteutil"
 )
 
 func setupMozHistoryDB(t *testing.T) string {
@@ -34,6 +38,55 @@ func TestExtractHistories(t *testing.T) {
 	assert.False(t, got[0].LastVisit.IsZero())
 }
 
+func TestRecoverHistories(t *testing.T) {
+	path := createTestDB(t, "places.sqlite", []string{mozPlacesSchema},
+		insertMozPlace(1, "https://github.com", "GitHub", 100, 1700000000000000),
+		insertMozPlace(2, "https://cleared.example/", "Cleared", 4, 1705000000000000),
+		insertMozPlace(3, "https://go.dev", "Go", 50, 1710000000000000),
+		`DELETE FROM moz_places WHERE id = 2`,
+	)
+	live, err := extractHistories(path)
+	require.NoError(t, err)
+
+	got, err := recoverHistories(path, nil, live)
+	require.NoError(t, err)
+	require.Len(t, got, 1)
+	assert.Equal(t, "https://cleared.example/", got[0].URL)
+	assert.Equal(t, "Cleared", got[0].Title)
+	assert.Equal(t, 4, got[0].VisitCount)
+	assert.Equal(t, firefoxMicros(1705000000000000), got[0].LastVisit)
+	assert.True(t, got[0].Recovered)
+}
+
+func TestRecoverHistories_WAL(t *testing.T) {
+	path := createWALTestDB(t, "places.sqlite", []string{mozPlacesSchema},
+		insertMozPlace(1, "https://github.com", "GitHub", 100, 1700000000000000),
+		insertMozPlace(2, "https://cleared.example/", "Cleared", 4, 1705000000000000),
+		`UPDATE moz_places SET title = 'GitHub · Build software', visit_count = 101 WHERE id = 1`,
+		`DELETE FROM moz_places WHERE id = 2`,
+	)
+	wal, err := sqliteutil.ReadWAL(path)
+	require.NoError(t, err)
+	require.NotNil(t, wal)
+	live, err := extractHistories(path)
+	require.NoError(t, err)
+	require.Len(t, live, 1)
+
+	got, err := recoverHistories(path, wal, live)
+	require.NoError(t, err)
+	byTitle := make(map[string]types.HistoryEntry)
+	for _, h := range got {
+		byTitle[h.Title] = h
+	}
+	require.Contains(t, byTitle, "GitHub", "the version the update superseded")
+	assert.Equal(t, 100, byTitle["GitHub"].VisitCount)
+	assert.True(t, strings.HasPrefix(byTitle["GitHub"].RecoveredFrom, "wal commit "), byTitle["GitHub"].RecoveredFrom)
+	assert.Contains(t, byTitle, "Cleared", "the deleted row")
+	for _, h := range got {
+		assert.True(t, h.Recovered)
+	}
+}
+
 func TestCountHistories(t *testing.T) {
 	path := setupMozHistoryDB(t)
 
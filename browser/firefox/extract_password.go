@@ -1,16 +1,26 @@
 package firefox
 
 import (
+	"database/sql"
 	"encoding/base64"
 	"fmt"
 	"os"
 	"sort"
+	"strings"
 
 	"github.com/tidwall/gjson"
 
 	"github.com/moond4rk/hackbrowserdata/crypto"
 	"github.com/moond4rk/hackbrowserdata/log"
 	"github.com/moond4rk/hackbrowserdata/types"
+	"github.com/moond4rk/hackbrowserdata/utils/sqliteutil"
+)
+
+const (
+	signonsLoginQuery = `SELECT hostname, formSubmitURL, encryptedUsername, encryptedPassword,
+		timeCreated FROM moz_logins`
+	signonsSampleQuery = `SELECT encryptedUsername, encryptedPassword FROM moz_logins LIMIT 5`
+	signonsCountQuery  = `SELECT COUNT(*) FROM moz_logins`
 )
 
 func countPasswords(path string) (int, error) {
@@ -80,3 +90,98 @@ func extractPasswords(masterKey []byte, path string) ([]types.LoginEntry, error)
 	})
 	return logins, nil
 }
+
+// extractSignons reads logins from the legacy signons.sqlite moz_logins table.
+// The encrypted columns hold the same base64 ASN1 PBE blobs as logins.json.
+func extractSignons(masterKey []byte, path string) ([]types.LoginEntry, error) {
+	var decryptFails int
+	var lastErr error
+	logins, err := sqliteutil.QueryRows(path, true, signonsLoginQuery,
+		func(rows *sql.Rows) (types.LoginEntry, error) {
+			var (
+				hostname, encUser, encPwd string
+				formSubmitURL             sql.NullString
+				createdAt                 sql.NullInt64
+			)
+			if err := rows.Scan(&hostname, &formSubmitURL, &encUser, &encPwd, &createdAt); err != nil {
+				return types.LoginEntry{}, err
+			}
+			url := formSubmitURL.String
+			if url == "" {
+				url = hostname
+			}
+
+			user, err := decryptSignonField(encUser, masterKey)
+			if err != nil {
+				decryptFails++
+				lastErr = err
+			}
+			pwd, err := decryptSignonField(encPwd, masterKey)
+			if err != nil {
+				decryptFails++
+				lastErr = err
+			}
+			return types.LoginEntry{
+				URL:       url,
+				Username:  string(user),
+				Password:  string(pwd),
+				CreatedAt: firefoxMillis(createdAt.Int64),
+			}, nil
+		})
+	if err != nil {
+		return nil, err
+	}
+	if decryptFails > 0 {
+		log.Debugf("decrypt firefox signons fields: %d failed: %v", decryptFails, lastErr)
+	}
+
+	sort.Slice(logins, func(i, j int) bool {
+		return logins[i].CreatedAt.After(logins[j].CreatedAt)
+	})
+	return logins, nil
+}
+
+func countSignons(path string) (int, error) {
+	return sqliteutil.CountRows(path, true, signonsCountQuery)
+}
+
+// obfuscatedPrefix marks a signons.sqlite value stored with encType 0 — plain
+// base64 rather than PBE ciphertext, written by very old Firefox builds.
+const obfuscatedPrefix = "~"
+
+// decryptSignonField decodes one moz_logins value, which is either PBE
+// ciphertext like logins.json or a "~"-prefixed base64 plaintext.
+func decryptSignonField(encoded string, masterKey []byte) ([]byte, error) {
+	if strings.HasPrefix(encoded, obfuscatedPrefix) {
+		return base64.StdEncoding.DecodeString(strings.TrimPrefix(encoded, obfuscatedPrefix))
+	}
+	return decryptPBE(encoded, masterKey)
+}
+
+// sampleEncryptedSignons extracts up to 5 PBE-encrypted logins from
+// signons.sqlite as test samples for master key validation.
+func sampleEncryptedSignons(path string) []encryptedLogin {
+	samples, err := sqliteutil.QueryRows(path, true, signonsSampleQuery,
+		func(rows *sql.Rows) (encryptedLogin, error) {
+			var encUser, encPwd string
+			if err := rows.Scan(&encUser, &encPwd); err != nil {
+				return encryptedLogin{}, err
+			}
+			if strings.HasPrefix(encUser, obfuscatedPrefix) || strings.HasPrefix(encPwd, obfuscatedPrefix) {
+				return encryptedLogin{}, fmt.Errorf("obfuscated login is not a key sample")
+			}
+			userRaw, err := base64.StdEncoding.DecodeString(encUser)
+			if err != nil {
+				return encryptedLogin{}, err
+			}
+			pwdRaw, err := base64.StdEncoding.DecodeString(encPwd)
+			if err != nil {
+				return encryptedLogin{}, err
+			}
+			return encryptedLogin{username: userRaw, password: pwdRaw}, nil
+		})
+	if err != nil {
+		log.Debugf("sample signons.sqlite: %v", err)
+	}
+	return samples
+}
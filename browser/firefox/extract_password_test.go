@@ -130,3 +130,46 @@ func TestExtractPasswords_EmptyLogins(t *testing.T) {
 	require.NoError(t, err)
 	assert.Empty(t, got)
 }
+
+func TestExtractSignons(t *testing.T) {
+	encB64 := loginPBEBase64(t)
+	path := createTestDB(t, signonsFile, []string{mozLoginsSchema},
+		insertMozLogin("https://a.example", "https://a.example/login", encB64, encB64, 1300000000000),
+		insertMozLogin("https://b.example", "", "~"+base64.StdEncoding.EncodeToString([]byte("bob")),
+			"~"+base64.StdEncoding.EncodeToString([]byte("secret")), 1400000000000),
+	)
+
+	got, err := extractSignons(testGlobalSalt, path)
+	require.NoError(t, err)
+	require.Len(t, got, 2)
+
+	// Sorted newest first; the obfuscated (encType 0) row decodes without a key.
+	assert.Equal(t, "https://b.example", got[0].URL)
+	assert.Equal(t, "bob", got[0].Username)
+	assert.Equal(t, "secret", got[0].Password)
+
+	assert.Equal(t, "https://a.example/login", got[1].URL)
+	assert.Equal(t, "Hello, World!", got[1].Username)
+	assert.Equal(t, "Hello, World!", got[1].Password)
+}
+
+func TestCountSignons(t *testing.T) {
+	path := createTestDB(t, signonsFile, []string{mozLoginsSchema},
+		insertMozLogin("https://a.example", "", "x", "y", 1),
+		insertMozLogin("https://b.example", "", "x", "y", 2),
+	)
+	count, err := countSignons(path)
+	require.NoError(t, err)
+	assert.Equal(t, 2, count)
+}
+
+func TestSampleEncryptedSignons_SkipsObfuscated(t *testing.T) {
+	encB64 := loginPBEBase64(t)
+	path := createTestDB(t, signonsFile, []string{mozLoginsSchema},
+		insertMozLogin("https://a.example", "", "~Ym9i", "~c2VjcmV0", 1),
+		insertMozLogin("https://b.example", "", encB64, encB64, 2),
+	)
+	samples := sampleEncryptedSignons(path)
+	require.Len(t, samples, 1)
+	assert.True(t, tryDecryptLogins(testGlobalSalt, samples))
+}
@@ -12,7 +12,8 @@ import (
 
 // Browser is one Firefox installation: the Profiles directory holding one or
 // more profiles. Firefox keys are per-profile (each profile's key4.db), so the
-// installation does not implement KeyManager.
+// installation exports and accepts them by profile name rather than
+// implementing KeyManager.
 type Browser struct {
 	cfg      types.BrowserConfig
 	profiles []*profile
@@ -29,6 +30,7 @@ func NewBrowser(cfg types.BrowserConfig) (*Browser, error) {
 		if len(sourcePaths) == 0 {
 			continue
 		}
+		resolveCacheDir(sourcePaths, cfg.CacheDir, profileDir)
 		profiles = append(profiles, &profile{
 			profileDir:  profileDir,
 			browserName: cfg.Name,
@@ -41,8 +43,10 @@ func NewBrowser(cfg types.BrowserConfig) (*Browser, error) {
 	return &Browser{cfg: cfg, profiles: profiles}, nil
 }
 
-func (b *Browser) BrowserName() string { return b.cfg.Name }
-func (b *Browser) UserDataDir() string { return b.cfg.UserDataDir }
+func (b *Browser) BrowserName() string     { return b.cfg.Name }
+func (b *Browser) UserDataDir() string     { return b.cfg.UserDataDir }
+func (b *Browser) BrowserKey() string      { return b.cfg.Key }
+func (b *Browser) Kind() types.BrowserKind { return b.cfg.Kind }
 
 // Profiles returns the identity of every profile in this installation.
 func (b *Browser) Profiles() []types.Profile {
@@ -77,39 +81,108 @@ func (b *Browser) CountEntries(categories []types.Category) ([]types.CountResult
 	return results, nil
 }
 
-// retrieveMasterKey reads key4.db and derives the master key using NSS.
-// If loginsPath is non-empty, the derived key is validated against actual
-// login data to ensure the correct candidate is selected.
-func retrieveMasterKey(key4Path, loginsPath string) ([]byte, error) {
-	k4, err := readKey4DB(key4Path)
+// SetPrimaryPassword supplies the primary password protecting this installation's profiles. Profiles
+// without one still open: key derivation falls back to the empty password when the check fails.
+func (b *Browser) SetPrimaryPassword(password string) {
+	for _, p := range b.profiles {
+		p.primaryPassword = password
+	}
+}
+
+// SetRecoverDeleted makes Extract also carve deleted cookies and history out of the free space of each
+// profile's databases; recovered entries are flagged and follow the live ones.
+func (b *Browser) SetRecoverDeleted(on bool) {
+	for _, p := range b.profiles {
+		p.recoverDeleted = on
+	}
+}
+
+// SetCacheBodyDir makes Extract save the decoded body of each HTTP cache entry under dir, in a
+// <browser key>/<profile> subdirectory; the entry's BodyFile names the file.
+func (b *Browser) SetCacheBodyDir(dir string) {
+	for _, p := range b.profiles {
+		p.cacheBodyDir = filepath.Join(dir, b.cfg.Key, p.name())
+	}
+}
+
+// SetFaviconDir makes Extract save each favicon's image under dir, in a <browser key>/<profile>
+// subdirectory; the entry's IconFile names the file.
+func (b *Browser) SetFaviconDir(dir string) {
+	for _, p := range b.profiles {
+		p.faviconDir = filepath.Join(dir, b.cfg.Key, p.name())
+	}
+}
+
+// ExportProfileKeys derives every profile's master key, by profile name, unlocking with the primary
+// password where one is set. Profiles whose key can't be derived are left out and their errors joined,
+// so a locked sibling doesn't discard the keys that did derive.
+func (b *Browser) ExportProfileKeys() (map[string][]byte, error) {
+	keys := make(map[string][]byte)
+	var errs []error
+	for _, p := range b.profiles {
+		key, err := p.exportKey()
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", p.name(), err))
+			continue
+		}
+		if key != nil {
+			keys[p.name()] = key
+		}
+	}
+	return keys, errors.Join(errs...)
+}
+
+// SetProfileKeys supplies master keys by profile name, e.g. from a key dump made on another host. A
+// profile with no key here, or whose key decrypts none of its logins, still derives one from its own
+// key database.
+func (b *Browser) SetProfileKeys(keys map[string][]byte) {
+	for _, p := range b.profiles {
+		p.masterKey = keys[p.name()]
+	}
+}
+
+// retrieveMasterKey opens the NSS key database at keyDBPath (key4.db, or legacy key3.db) and derives
+// the master key, unlocking it with password (empty = no primary password). If samples is non-empty,
+// each candidate is validated against the encrypted logins to ensure the correct candidate is selected.
+func retrieveMasterKey(keyDBPath, password string, samples []encryptedLogin) ([]byte, error) {
+	store, err := openKeyStore(keyDBPath)
 	if err != nil {
 		return nil, err
 	}
 
-	keys, err := k4.deriveKeys()
+	keys, err := store.deriveKeys([]byte(password))
+	if password != "" && errors.Is(err, errPasswordCheck) {
+		// The password is applied installation-wide; a sibling profile without one still opens.
+		keys, err = store.deriveKeys(nil)
+	}
+	if errors.Is(err, errPasswordCheck) && password == "" {
+		return nil, fmt.Errorf("%w: profile is protected by a primary password", err)
+	}
 	if err != nil {
 		return nil, err
 	}
 	if len(keys) == 0 {
-		return nil, errors.New("no valid master key candidates in key4.db")
+		return nil, fmt.Errorf("no valid master key candidates in %s", filepath.Base(keyDBPath))
 	}
 
 	// No logins to validate against — return the first derived key.
-	if loginsPath == "" {
+	if len(samples) == 0 {
 		return keys[0], nil
 	}
 
 	// Validate against actual login data.
-	if key := validateKeyWithLogins(keys, loginsPath); key != nil {
+	if key := validateKeyWithLogins(keys, samples); key != nil {
 		return key, nil
 	}
 
 	return nil, fmt.Errorf("derived %d key(s) but none could decrypt logins", len(keys))
 }
 
-// resolvedPath holds the absolute path and type for a discovered source.
+// resolvedPath holds the absolute path, the slash-relative source path, and the type of a discovered
+// source. rel is retained so a category with several candidate files knows which format it resolved to.
 type resolvedPath struct {
 	absPath string
+	rel     string
 	isDir   bool
 }
 
@@ -159,7 +232,7 @@ func resolveSourcePaths(sources map[types.Category][]sourcePath, profileDir stri
 				continue
 			}
 			if sp.isDir == info.IsDir() {
-				resolved[cat] = resolvedPath{abs, sp.isDir}
+				resolved[cat] = resolvedPath{absPath: abs, rel: sp.rel, isDir: sp.isDir}
 				break
 			}
 		}
@@ -167,11 +240,24 @@ func resolveSourcePaths(sources map[types.Category][]sourcePath, profileDir stri
 	return resolved
 }
 
+// resolveCacheDir looks for the profile's HTTP cache under cacheRoot, in the directory named like the
+// profile, when the profile itself holds none. It keeps the in-profile rel, so an archive lays the cache
+// out inside the profile, where a restore finds it.
+func resolveCacheDir(resolved map[types.Category]resolvedPath, cacheRoot, profileDir string) {
+	if _, ok := resolved[types.Cache]; ok || cacheRoot == "" {
+		return
+	}
+	abs := filepath.Join(cacheRoot, filepath.Base(profileDir), cacheDirName)
+	if info, err := os.Stat(abs); err == nil && info.IsDir() {
+		resolved[types.Cache] = resolvedPath{absPath: abs, rel: cacheDirName, isDir: true}
+	}
+}
+
 // Firefox uses three timestamp units. Helpers emit UTC and return the zero
 // time.Time for non-positive or out-of-JSON-range input.
 //
 //   - firefoxMicros: PRTime (μs since Unix epoch) — moz_* tables.
-//   - firefoxMillis: Date.now() (ms) — logins.json, download endTime.
+//   - firefoxMillis: Date.now() (ms) — logins.json, download endTime, moz_icons.expire_ms.
 //   - firefoxSeconds: seconds — moz_cookies.expiry only.
 func firefoxMicros(us int64) time.Time {
 	if us <= 0 {
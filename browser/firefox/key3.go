@@ -0,0 +1,227 @@
+package firefox
+
+import (
+	"bytes"
+	"encoding/asn1"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+
+	"github.com/moond4rk/hackbrowserdata/crypto"
+	"github.com/moond4rk/hackbrowserdata/log"
+)
+
+// key3DB holds the parsed contents of the legacy key3.db NSS key storage (Firefox < 58).
+//
+// key3.db is a Berkeley DB 1.85 hash file rather than SQLite. The entries relevant to us are:
+//   - "global-salt":    salt used as PBE decryption input (same role as key4.db metaData.item1)
+//   - "password-check": header + entry salt + 16-byte 3DES-encrypted "password-check" marker
+//   - nssKeyTypeTag:    header + entry salt + nickname + privateKeyPBE-wrapped PKCS#8 private key
+//
+// Reference: https://searchfox.org/mozilla-central/source/security/nss/lib/softoken/legacydb/keydb.c
+type key3DB struct {
+	globalSalt    []byte
+	passwordCheck []byte
+	privateKey    []byte
+}
+
+const (
+	key3GlobalSaltKey    = "global-salt"
+	key3PasswordCheckKey = "password-check"
+)
+
+// readKey3DB opens key3.db and extracts the entries needed for master key derivation.
+func readKey3DB(path string) (*key3DB, error) {
+	entries, err := readBerkeleyHash(path)
+	if err != nil {
+		return nil, fmt.Errorf("read key3.db: %w", err)
+	}
+
+	record := &key3DB{
+		globalSalt:    entries[key3GlobalSaltKey],
+		passwordCheck: entries[key3PasswordCheckKey],
+		privateKey:    entries[string(nssKeyTypeTag)],
+	}
+	if record.globalSalt == nil {
+		return nil, errors.New("key3.db has no global-salt entry")
+	}
+	if record.privateKey == nil {
+		return nil, errors.New("key3.db has no private key entry")
+	}
+	return record, nil
+}
+
+// deriveKeys verifies the password-check entry, then decrypts the single 3DES master key key3.db holds.
+func (k *key3DB) deriveKeys(password []byte) ([][]byte, error) {
+	secret := pbeSecret(k.globalSalt, password)
+	if k.passwordCheck == nil {
+		log.Debugf("key3.db has no password-check entry, skipping verification")
+	} else if err := k.verifyPasswordCheck(secret); err != nil {
+		return nil, err
+	}
+	key, err := k.decryptPrivateKey(secret)
+	if err != nil {
+		return nil, err
+	}
+	return [][]byte{key}, nil
+}
+
+func (k *key3DB) checkPassword(password []byte) error {
+	if k.passwordCheck == nil {
+		return errors.New("key3.db has no password-check entry")
+	}
+	return k.verifyPasswordCheck(pbeSecret(k.globalSalt, password))
+}
+
+// verifyPasswordCheck decrypts the password-check marker. Its layout is
+// [version(1)][saltLen(1)][oidLen(1)][entrySalt][...][ciphertext(16)], and the marker is stored
+// without the ASN1 envelope key4.db uses, so it is rebuilt via crypto.NewPrivateKeyPBE.
+func (k *key3DB) verifyPasswordCheck(secret []byte) error {
+	const markerLen = 16
+	if len(k.passwordCheck) < 3 {
+		return errors.New("password-check entry too short")
+	}
+	saltLen := int(k.passwordCheck[1])
+	if len(k.passwordCheck) < 3+saltLen+markerLen {
+		return errors.New("password-check entry too short")
+	}
+	entrySalt := k.passwordCheck[3 : 3+saltLen]
+	encrypted := k.passwordCheck[len(k.passwordCheck)-markerLen:]
+
+	plain, err := crypto.NewPrivateKeyPBE(entrySalt, encrypted).Decrypt(secret)
+	if err != nil || !bytes.Equal(plain, []byte(key3PasswordCheckKey)) {
+		return errPasswordCheck
+	}
+	return nil
+}
+
+// privateKeyInfo is the PKCS#8 envelope NSS stores the master key in once the PBE layer is removed.
+type privateKeyInfo struct {
+	Version    int
+	Algorithm  asn1.RawValue
+	PrivateKey []byte
+}
+
+// decryptPrivateKey unwraps the private key entry: [version(1)][saltLen(1)][nickLen(1)][salt][nick]
+// followed by a DER privateKeyPBE. The decrypted PKCS#8 body is an RSA-shaped SEQUENCE whose fourth
+// INTEGER carries the 24-byte 3DES key used for logins.
+func (k *key3DB) decryptPrivateKey(secret []byte) ([]byte, error) {
+	if len(k.privateKey) < 3 {
+		return nil, errors.New("private key entry too short")
+	}
+	headerLen := 3 + int(k.privateKey[1]) + int(k.privateKey[2])
+	if len(k.privateKey) <= headerLen {
+		return nil, errors.New("private key entry too short")
+	}
+
+	pbe, err := crypto.NewASN1PBE(k.privateKey[headerLen:])
+	if err != nil {
+		return nil, fmt.Errorf("parse private key: %w", err)
+	}
+	plain, err := pbe.Decrypt(secret)
+	if err != nil {
+		return nil, fmt.Errorf("decrypt private key: %w", err)
+	}
+
+	var info privateKeyInfo
+	if _, err := asn1.Unmarshal(plain, &info); err != nil {
+		return nil, fmt.Errorf("parse pkcs8 key: %w", err)
+	}
+	var fields []asn1.RawValue
+	if _, err := asn1.Unmarshal(info.PrivateKey, &fields); err != nil {
+		return nil, fmt.Errorf("parse private key body: %w", err)
+	}
+	if len(fields) < 4 || fields[3].Tag != asn1.TagInteger {
+		return nil, fmt.Errorf("unexpected private key body with %d fields", len(fields))
+	}
+
+	// INTEGER encoding drops leading zeros and may add a sign byte; normalize to a 3DES key length.
+	raw := new(big.Int).SetBytes(fields[3].Bytes).Bytes()
+	if len(raw) > key3KeySize {
+		return nil, fmt.Errorf("derived key too long: %d bytes", len(raw))
+	}
+	key := make([]byte, key3KeySize)
+	copy(key[key3KeySize-len(raw):], raw)
+	return key, nil
+}
+
+// key3KeySize is the 3DES key length every key3.db-era login is encrypted with.
+const key3KeySize = 24
+
+// Berkeley DB 1.85 hash constants. The header is always stored big-endian; bucket pages use the byte
+// order recorded in the header's lorder field.
+const (
+	bdbHashMagic   = 0x061561
+	bdbLittleOrder = 1234
+	bdbBigOrder    = 4321
+	bdbHeaderSize  = 0x44
+	// A data offset below bdbRealKey marks an overflow/partial pair rather than an inline one.
+	bdbRealKey = 4
+)
+
+// readBerkeleyHash returns every inline key/data pair stored in a Berkeley DB 1.85 hash file. key3.db
+// is small enough that its pairs never spill onto overflow pages, so those are skipped.
+func readBerkeleyHash(path string) (map[string][]byte, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < bdbHeaderSize {
+		return nil, errors.New("file too short for a hash header")
+	}
+	if magic := binary.BigEndian.Uint32(data[0:4]); magic != bdbHashMagic {
+		return nil, fmt.Errorf("not a Berkeley DB hash file (magic %#x)", magic)
+	}
+
+	var order binary.ByteOrder
+	switch lorder := binary.BigEndian.Uint32(data[8:12]); lorder {
+	case bdbLittleOrder:
+		order = binary.LittleEndian
+	case bdbBigOrder:
+		order = binary.BigEndian
+	default:
+		return nil, fmt.Errorf("unknown byte order %d", lorder)
+	}
+	pageSize := int(binary.BigEndian.Uint32(data[12:16]))
+	if pageSize < bdbHeaderSize || pageSize > len(data) {
+		return nil, fmt.Errorf("invalid page size %d", pageSize)
+	}
+	nkeys := int(binary.BigEndian.Uint32(data[56:60]))
+	headerPages := int(binary.BigEndian.Uint32(data[60:64]))
+	if headerPages < 1 {
+		headerPages = 1
+	}
+
+	entries := make(map[string][]byte, nkeys)
+	for off := headerPages * pageSize; off+pageSize <= len(data) && len(entries) < nkeys; off += pageSize {
+		readBerkeleyPage(data[off:off+pageSize], order, entries)
+	}
+	return entries, nil
+}
+
+// readBerkeleyPage decodes one bucket page. The page starts with a uint16 count n followed by n uint16
+// offsets alternating key/data; pairs are packed from the end of the page downward, so each key runs
+// up to the previous pair's data offset (or the page end) and its data runs up to the key offset.
+func readBerkeleyPage(page []byte, order binary.ByteOrder, entries map[string][]byte) {
+	n := int(order.Uint16(page[0:2]))
+	if n == 0 || n%2 != 0 || 2+2*n > len(page) {
+		return
+	}
+	end := len(page)
+	for i := 0; i < n; i += 2 {
+		keyOff := int(order.Uint16(page[2+2*i:]))
+		dataOff := int(order.Uint16(page[4+2*i:]))
+		if dataOff < bdbRealKey {
+			return
+		}
+		if dataOff >= keyOff || keyOff > end {
+			return
+		}
+		value := make([]byte, keyOff-dataOff)
+		copy(value, page[dataOff:keyOff])
+		entries[string(page[keyOff:end])] = value
+		end = dataOff
+	}
+}
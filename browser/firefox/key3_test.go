@@ -0,0 +1,239 @@
+package firefox
+
+import (
+	"bytes"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/binary"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/moond4rk/hackbrowserdata/crypto"
+	"github.com/moond4rk/hackbrowserdata/types"
+)
+
+var (
+	key3GlobalSalt = []byte("key3-global-salt-0123")
+	// A leading zero byte exercises the INTEGER → fixed-length key normalization.
+	key3MasterKey = append([]byte{0x00}, bytes.Repeat([]byte{0x5a}, 23)...)
+
+	oidPBEWithSHA1And3DES = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 12, 5, 1, 3}
+	oidRSAEncryption      = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 1, 1}
+	oidDESEDE3CBC         = asn1.ObjectIdentifier{1, 2, 840, 113549, 3, 7}
+)
+
+// writeBerkeleyHash writes a minimal single-bucket Berkeley DB 1.85 hash file.
+func writeBerkeleyHash(t *testing.T, path string, entries map[string][]byte) {
+	t.Helper()
+	const pageSize = 1024
+
+	header := make([]byte, pageSize)
+	binary.BigEndian.PutUint32(header[0:], bdbHashMagic)
+	binary.BigEndian.PutUint32(header[4:], 2) // version
+	binary.BigEndian.PutUint32(header[8:], bdbLittleOrder)
+	binary.BigEndian.PutUint32(header[12:], pageSize)
+	binary.BigEndian.PutUint32(header[56:], uint32(len(entries)))
+	binary.BigEndian.PutUint32(header[60:], 1) // header pages
+
+	keys := make([]string, 0, len(entries))
+	for k := range entries {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	page := make([]byte, pageSize)
+	binary.LittleEndian.PutUint16(page[0:], uint16(2*len(keys)))
+	end := pageSize
+	for i, k := range keys {
+		keyOff := end - len(k)
+		copy(page[keyOff:], k)
+		dataOff := keyOff - len(entries[k])
+		copy(page[dataOff:], entries[k])
+		binary.LittleEndian.PutUint16(page[2+4*i:], uint16(keyOff))
+		binary.LittleEndian.PutUint16(page[4+4*i:], uint16(dataOff))
+		end = dataOff
+	}
+	require.Greater(t, end, 2+4*len(keys), "fixture entries overflow the bucket page")
+
+	require.NoError(t, os.WriteFile(path, append(header, page...), 0o644))
+}
+
+type testPrivateKeyPBE struct {
+	AlgoAttr struct {
+		asn1.ObjectIdentifier
+		SaltAttr struct {
+			EntrySalt []byte
+			KeyLen    int
+		}
+	}
+	Encrypted []byte
+}
+
+// buildKey3Entries produces the global-salt, password-check and private key entries for masterKey.
+func buildKey3Entries(t *testing.T, masterKey []byte) map[string][]byte {
+	t.Helper()
+	checkSalt := []byte("check-entry-salt-0001")
+	checkCipher, err := crypto.NewPrivateKeyPBE(checkSalt, nil).Encrypt(key3GlobalSalt, []byte(key3PasswordCheckKey))
+	require.NoError(t, err)
+	passwordCheck := append([]byte{0x03, byte(len(checkSalt)), 0x00}, checkSalt...)
+	passwordCheck = append(passwordCheck, checkCipher...)
+
+	body, err := asn1.Marshal(struct {
+		Version         int
+		Modulus         *big.Int
+		PublicExponent  int
+		PrivateExponent *big.Int
+	}{0, big.NewInt(0x1234), 0, new(big.Int).SetBytes(masterKey)})
+	require.NoError(t, err)
+	algo, err := asn1.Marshal(struct {
+		Algorithm  asn1.ObjectIdentifier
+		Parameters asn1.RawValue
+	}{oidRSAEncryption, asn1.NullRawValue})
+	require.NoError(t, err)
+	pkcs8, err := asn1.Marshal(privateKeyInfo{Algorithm: asn1.RawValue{FullBytes: algo}, PrivateKey: body})
+	require.NoError(t, err)
+
+	keySalt := []byte("private-key-salt-0002")
+	var pbe testPrivateKeyPBE
+	pbe.AlgoAttr.ObjectIdentifier = oidPBEWithSHA1And3DES
+	pbe.AlgoAttr.SaltAttr.EntrySalt = keySalt
+	pbe.AlgoAttr.SaltAttr.KeyLen = 1
+	pbe.Encrypted, err = crypto.NewPrivateKeyPBE(keySalt, nil).Encrypt(key3GlobalSalt, pkcs8)
+	require.NoError(t, err)
+	wrapped, err := asn1.Marshal(pbe)
+	require.NoError(t, err)
+
+	nick := []byte("Key Database Key")
+	privateKey := append([]byte{0x03, byte(len(keySalt)), byte(len(nick))}, keySalt...)
+	privateKey = append(privateKey, nick...)
+	privateKey = append(privateKey, wrapped...)
+
+	return map[string][]byte{
+		"Version":             {0x03},
+		key3GlobalSaltKey:     key3GlobalSalt,
+		key3PasswordCheckKey:  passwordCheck,
+		string(nssKeyTypeTag): privateKey,
+	}
+}
+
+func createTestKey3DB(t *testing.T, dir string) string {
+	t.Helper()
+	path := filepath.Join(dir, key3DBFile)
+	writeBerkeleyHash(t, path, buildKey3Entries(t, key3MasterKey))
+	return path
+}
+
+// encryptLegacyLogin seals plaintext as a base64 3DES credentialPBE blob, as key3-era Firefox wrote it.
+func encryptLegacyLogin(t *testing.T, key []byte, plaintext string) string {
+	t.Helper()
+	iv := []byte("legacyiv")
+	ct, err := crypto.DES3Encrypt(key, iv, []byte(plaintext))
+	require.NoError(t, err)
+	raw, err := asn1.Marshal(struct {
+		KeyCheck []byte
+		Algo     struct {
+			asn1.ObjectIdentifier
+			IV []byte
+		}
+		Encrypted []byte
+	}{
+		KeyCheck: nssKeyTypeTag,
+		Algo: struct {
+			asn1.ObjectIdentifier
+			IV []byte
+		}{oidDESEDE3CBC, iv},
+		Encrypted: ct,
+	})
+	require.NoError(t, err)
+	return base64.StdEncoding.EncodeToString(raw)
+}
+
+func TestReadBerkeleyHash(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "hash.db")
+	writeBerkeleyHash(t, path, map[string][]byte{
+		"alpha": []byte("one"),
+		"beta":  {0x00, 0x01, 0x02},
+	})
+
+	entries, err := readBerkeleyHash(path)
+	require.NoError(t, err)
+	assert.Equal(t, []byte("one"), entries["alpha"])
+	assert.Equal(t, []byte{0x00, 0x01, 0x02}, entries["beta"])
+}
+
+func TestReadBerkeleyHash_NotHashFile(t *testing.T) {
+	path := createTestJSON(t, key3DBFile, `{"not":"a berkeley db file","padding":"................................................"}`)
+	_, err := readBerkeleyHash(path)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "not a Berkeley DB hash file")
+}
+
+func TestKey3DB_DeriveKeys(t *testing.T) {
+	k3, err := readKey3DB(createTestKey3DB(t, t.TempDir()))
+	require.NoError(t, err)
+
+	keys, err := k3.deriveKeys(nil)
+	require.NoError(t, err)
+	require.Len(t, keys, 1)
+	assert.Equal(t, key3MasterKey, keys[0])
+}
+
+func TestKey3DB_PasswordCheckMismatch(t *testing.T) {
+	entries := buildKey3Entries(t, key3MasterKey)
+	entries[key3GlobalSaltKey] = []byte("some-other-global-salt")
+	path := filepath.Join(t.TempDir(), key3DBFile)
+	writeBerkeleyHash(t, path, entries)
+
+	k3, err := readKey3DB(path)
+	require.NoError(t, err)
+	_, err = k3.deriveKeys(nil)
+	require.Error(t, err)
+}
+
+func TestKey3DB_MissingPrivateKey(t *testing.T) {
+	entries := buildKey3Entries(t, key3MasterKey)
+	delete(entries, string(nssKeyTypeTag))
+	path := filepath.Join(t.TempDir(), key3DBFile)
+	writeBerkeleyHash(t, path, entries)
+
+	_, err := readKey3DB(path)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "no private key entry")
+}
+
+// TestExtract_LegacyProfile runs a key3.db + signons.sqlite profile through discovery and extraction.
+func TestExtract_LegacyProfile(t *testing.T) {
+	root := t.TempDir()
+	profileDir := filepath.Join(root, "legacy.default")
+	mkDir(profileDir)
+	createTestKey3DB(t, profileDir)
+	installFile(t, profileDir, createTestDB(t, signonsFile, []string{mozLoginsSchema},
+		insertMozLogin("https://legacy.example", "",
+			encryptLegacyLogin(t, key3MasterKey, "alice"),
+			encryptLegacyLogin(t, key3MasterKey, "hunter2"), 1300000000000),
+	), signonsFile)
+
+	b, err := NewBrowser(types.BrowserConfig{Name: "Firefox", Kind: types.Firefox, UserDataDir: root})
+	require.NoError(t, err)
+	require.NotNil(t, b)
+
+	counts, err := b.CountEntries([]types.Category{types.Password})
+	require.NoError(t, err)
+	require.Len(t, counts, 1)
+	assert.Equal(t, 1, counts[0].Counts[types.Password])
+
+	results, err := b.Extract([]types.Category{types.Password})
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+	require.Len(t, results[0].Data.Passwords, 1)
+	got := results[0].Data.Passwords[0]
+	assert.Equal(t, "https://legacy.example", got.URL)
+	assert.Equal(t, "alice", got.Username)
+	assert.Equal(t, "hunter2", got.Password)
+}
@@ -7,6 +7,7 @@ import (
 	"errors"
 	"fmt"
 	"os"
+	"path/filepath"
 
 	"github.com/tidwall/gjson"
 	_ "modernc.org/sqlite"
@@ -40,6 +41,52 @@ type privateKey struct {
 // See: https://searchfox.org/mozilla-central/source/security/nss/lib/softoken/pkcs11i.h
 var nssKeyTypeTag = []byte{248, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 1}
 
+// nssKeyStore is a parsed NSS key database that can produce master key candidates. password is the
+// profile's primary password; Firefox's default (no primary password) is empty.
+type nssKeyStore interface {
+	// checkPassword verifies password against the password-check entry without decrypting any key.
+	checkPassword(password []byte) error
+	deriveKeys(password []byte) ([][]byte, error)
+}
+
+// errPasswordCheck means the password-check entry did not decrypt to its marker: the profile has a
+// primary password and the supplied one (empty by default) is wrong.
+var errPasswordCheck = errors.New("password check verification failed")
+
+// pbeSecret builds the NSS PBE input for a primary password. Both NSS derivations start from
+// SHA1(globalSalt || password), so appending the password to the global salt threads it through the
+// crypto package's PBE code unchanged.
+func pbeSecret(globalSalt, password []byte) []byte {
+	secret := make([]byte, 0, len(globalSalt)+len(password))
+	secret = append(secret, globalSalt...)
+	return append(secret, password...)
+}
+
+const (
+	key4DBFile = "key4.db"
+	key3DBFile = "key3.db"
+)
+
+// keyDBFiles lists the NSS key databases in priority order. Firefox 58+ migrates
+// key3.db to key4.db on first run, so key3.db is only read when key4.db is absent.
+var keyDBFiles = []string{key4DBFile, key3DBFile}
+
+// openKeyStore parses the key database at path, choosing the format by file name.
+func openKeyStore(path string) (nssKeyStore, error) {
+	if filepath.Base(path) == key3DBFile {
+		k3, err := readKey3DB(path)
+		if err != nil {
+			return nil, err
+		}
+		return k3, nil
+	}
+	k4, err := readKey4DB(path)
+	if err != nil {
+		return nil, err
+	}
+	return k4, nil
+}
+
 // readKey4DB opens key4.db and parses it into a structured key4DB.
 func readKey4DB(path string) (*key4DB, error) {
 	db, err := sql.Open("sqlite", path)
@@ -81,8 +128,9 @@ func readKey4DB(path string) (*key4DB, error) {
 
 // deriveKeys verifies the database integrity via the password-check marker,
 // then decrypts all valid master key candidates.
-func (k *key4DB) deriveKeys() ([][]byte, error) {
-	if err := k.verifyPasswordCheck(); err != nil {
+func (k *key4DB) deriveKeys(password []byte) ([][]byte, error) {
+	secret := pbeSecret(k.globalSalt, password)
+	if err := k.verifyPasswordCheck(secret); err != nil {
 		return nil, err
 	}
 
@@ -91,7 +139,7 @@ func (k *key4DB) deriveKeys() ([][]byte, error) {
 		if !bytes.Equal(pk.typeTag, nssKeyTypeTag) {
 			continue
 		}
-		key, err := k.decryptPrivateKey(pk)
+		key, err := k.decryptPrivateKey(pk, secret)
 		if err != nil {
 			log.Debugf("decrypt nss private key: %v", err)
 			continue
@@ -101,30 +149,33 @@ func (k *key4DB) deriveKeys() ([][]byte, error) {
 	return keys, nil
 }
 
+func (k *key4DB) checkPassword(password []byte) error {
+	return k.verifyPasswordCheck(pbeSecret(k.globalSalt, password))
+}
+
 // verifyPasswordCheck decrypts the password-check marker from metaData
-// to confirm the database is valid and accessible.
-func (k *key4DB) verifyPasswordCheck() error {
+// to confirm the database is valid and the PBE secret is right. A wrong
+// primary password usually surfaces as a padding error, so decrypt failures
+// count as a failed check too.
+func (k *key4DB) verifyPasswordCheck(secret []byte) error {
 	pbe, err := crypto.NewASN1PBE(k.passwordCheck)
 	if err != nil {
 		return fmt.Errorf("parse password check: %w", err)
 	}
-	plain, err := pbe.Decrypt(k.globalSalt)
-	if err != nil {
-		return fmt.Errorf("decrypt password check: %w", err)
-	}
-	if !bytes.Contains(plain, []byte("password-check")) {
-		return errors.New("password check verification failed")
+	plain, err := pbe.Decrypt(secret)
+	if err != nil || !bytes.Contains(plain, []byte("password-check")) {
+		return errPasswordCheck
 	}
 	return nil
 }
 
-// decryptPrivateKey decrypts a single master key candidate using the global salt.
-func (k *key4DB) decryptPrivateKey(pk privateKey) ([]byte, error) {
+// decryptPrivateKey decrypts a single master key candidate using the PBE secret.
+func (k *key4DB) decryptPrivateKey(pk privateKey, secret []byte) ([]byte, error) {
 	pbe, err := crypto.NewASN1PBE(pk.encrypted)
 	if err != nil {
 		return nil, fmt.Errorf("parse private key: %w", err)
 	}
-	derivedKey, err := pbe.Decrypt(k.globalSalt)
+	derivedKey, err := pbe.Decrypt(secret)
 	if err != nil {
 		return nil, fmt.Errorf("decrypt private key: %w", err)
 	}
@@ -143,17 +194,9 @@ type encryptedLogin struct {
 	password []byte // PBE-encrypted password blob
 }
 
-// validateKeyWithLogins reads logins.json and returns the first key that
-// can successfully decrypt an actual login entry. Returns nil if no key matches.
-func validateKeyWithLogins(keys [][]byte, loginsPath string) []byte {
-	raw, err := os.ReadFile(loginsPath)
-	if err != nil {
-		return nil
-	}
-	samples := sampleEncryptedLogins(raw)
-	if len(samples) == 0 {
-		return nil
-	}
+// validateKeyWithLogins returns the first key that can successfully decrypt an
+// actual login entry. Returns nil if no key matches.
+func validateKeyWithLogins(keys [][]byte, samples []encryptedLogin) []byte {
 	for _, key := range keys {
 		if tryDecryptLogins(key, samples) {
 			return key
@@ -162,6 +205,22 @@ func validateKeyWithLogins(keys [][]byte, loginsPath string) []byte {
 	return nil
 }
 
+// loadLoginSamples reads up to 5 encrypted logins from the acquired password
+// source, which is logins.json or, for legacy profiles, signons.sqlite.
+func loadLoginSamples(path string, signons bool) []encryptedLogin {
+	if path == "" {
+		return nil
+	}
+	if signons {
+		return sampleEncryptedSignons(path)
+	}
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+	return sampleEncryptedLogins(raw)
+}
+
 // sampleEncryptedLogins extracts up to 5 encrypted login entries from
 // logins.json as test samples for master key validation.
 func sampleEncryptedLogins(raw []byte) []encryptedLogin {
@@ -0,0 +1,66 @@
+package firefox
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+
+	"github.com/moond4rk/hackbrowserdata/filemanager"
+	"github.com/moond4rk/hackbrowserdata/utils/fileutil"
+)
+
+// ErrNoKeyDB is returned by NewPasswordChecker when the profile has neither key4.db nor key3.db.
+var ErrNoKeyDB = errors.New("no key4.db or key3.db in profile")
+
+// PasswordChecker tests primary-password candidates against one profile's NSS password-check entry.
+// The key database is read into memory once, so Check touches no files and is safe for concurrent use.
+type PasswordChecker struct {
+	store  nssKeyStore
+	keyDB  string
+	locked bool
+}
+
+// NewPasswordChecker loads profileDir's key database (key4.db, falling back to legacy key3.db) through
+// a temp copy, so a running Firefox holding the file does not block it.
+func NewPasswordChecker(profileDir string) (*PasswordChecker, error) {
+	session, err := filemanager.NewSession()
+	if err != nil {
+		return nil, err
+	}
+	defer session.Cleanup()
+
+	for _, name := range keyDBFiles {
+		src := filepath.Join(profileDir, name)
+		if !fileutil.FileExists(src) {
+			continue
+		}
+		dst := filepath.Join(session.TempDir(), name)
+		if err := session.Acquire(src, dst, false); err != nil {
+			return nil, fmt.Errorf("acquire %s: %w", name, err)
+		}
+		store, err := openKeyStore(dst)
+		if err != nil {
+			return nil, err
+		}
+		c := &PasswordChecker{store: store, keyDB: name}
+		switch err := store.checkPassword(nil); {
+		case errors.Is(err, errPasswordCheck):
+			c.locked = true
+		case err != nil:
+			return nil, err
+		}
+		return c, nil
+	}
+	return nil, fmt.Errorf("%s: %w", profileDir, ErrNoKeyDB)
+}
+
+// KeyDB names the key database the checker loaded ("key4.db" or "key3.db").
+func (c *PasswordChecker) KeyDB() string { return c.keyDB }
+
+// Locked reports whether the profile has a primary password; an unlocked profile needs no cracking.
+func (c *PasswordChecker) Locked() bool { return c.locked }
+
+// Check reports whether password unlocks the profile's key database.
+func (c *PasswordChecker) Check(password string) bool {
+	return c.store.checkPassword([]byte(password)) == nil
+}
@@ -0,0 +1,266 @@
+package firefox
+
+import (
+	"encoding/asn1"
+	"encoding/base64"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/moond4rk/hackbrowserdata/crypto"
+	"github.com/moond4rk/hackbrowserdata/types"
+)
+
+var (
+	oidPBES2      = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 5, 13}
+	oidPBKDF2     = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 5, 12}
+	oidHMACSHA256 = asn1.ObjectIdentifier{1, 2, 840, 113549, 2, 9}
+	oidAES256CBC  = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 1, 42}
+
+	key4GlobalSalt = []byte("key4-global-salt-01234")
+	key4MasterKey  = []byte("0123456789abcdef0123456789abcdef")
+)
+
+// testPBES2 mirrors the PBES2 (PBKDF2-SHA256 + AES-256-CBC) envelope modern key4.db uses for both
+// the password-check marker and the nssPrivate key entries.
+type testPBES2 struct {
+	Algo struct {
+		asn1.ObjectIdentifier
+		Params struct {
+			KDF struct {
+				asn1.ObjectIdentifier
+				Params struct {
+					Salt       []byte
+					Iterations int
+					KeyLen     int
+					PRF        struct{ asn1.ObjectIdentifier }
+				}
+			}
+			Cipher struct {
+				asn1.ObjectIdentifier
+				IV []byte
+			}
+		}
+	}
+	Encrypted []byte
+}
+
+// sealPBES2 encrypts plaintext under the NSS PBE secret for (globalSalt, password).
+func sealPBES2(t *testing.T, password string, entrySalt, plaintext []byte) []byte {
+	t.Helper()
+	var pbe testPBES2
+	pbe.Algo.ObjectIdentifier = oidPBES2
+	pbe.Algo.Params.KDF.ObjectIdentifier = oidPBKDF2
+	pbe.Algo.Params.KDF.Params.Salt = entrySalt
+	pbe.Algo.Params.KDF.Params.Iterations = 1
+	pbe.Algo.Params.KDF.Params.KeyLen = 32
+	pbe.Algo.Params.KDF.Params.PRF.ObjectIdentifier = oidHMACSHA256
+	pbe.Algo.Params.Cipher.ObjectIdentifier = oidAES256CBC
+	pbe.Algo.Params.Cipher.IV = []byte("fourteen-bytes")
+
+	template, err := asn1.Marshal(pbe)
+	require.NoError(t, err)
+	parsed, err := crypto.NewASN1PBE(template)
+	require.NoError(t, err)
+	pbe.Encrypted, err = parsed.Encrypt(pbeSecret(key4GlobalSalt, []byte(password)), plaintext)
+	require.NoError(t, err)
+	sealed, err := asn1.Marshal(pbe)
+	require.NoError(t, err)
+	return sealed
+}
+
+// createTestKey4DB writes a key4.db in dir whose master key is key4MasterKey, locked by password.
+func createTestKey4DB(t *testing.T, dir, password string) {
+	t.Helper()
+	check := sealPBES2(t, password, []byte("check-salt-0123456789abcdef01234"), []byte("password-check\x02\x02"))
+	private := sealPBES2(t, password, []byte("key-salt-0123456789abcdef0123456"), key4MasterKey)
+	installFile(t, dir, createTestDB(t, key4DBFile,
+		[]string{
+			`CREATE TABLE metaData (id TEXT PRIMARY KEY, item1 BLOB, item2 BLOB)`,
+			`CREATE TABLE nssPrivate (a11 BLOB, a102 BLOB)`,
+		},
+		sqlInsertBlobs(`INSERT INTO metaData (id, item1, item2) VALUES ('password', %s, %s)`, key4GlobalSalt, check),
+		sqlInsertBlobs(`INSERT INTO nssPrivate (a11, a102) VALUES (%s, %s)`, private, nssKeyTypeTag),
+	), key4DBFile)
+}
+
+// encryptAESLogin seals plaintext as a base64 AES-256-CBC credentialPBE blob (Firefox 144+).
+func encryptAESLogin(t *testing.T, key []byte, plaintext string) string {
+	t.Helper()
+	iv := []byte("0123456789abcdef")
+	ct, err := crypto.AESCBCEncrypt(key, iv, []byte(plaintext))
+	require.NoError(t, err)
+	raw, err := asn1.Marshal(struct {
+		KeyCheck []byte
+		Algo     struct {
+			asn1.ObjectIdentifier
+			IV []byte
+		}
+		Encrypted []byte
+	}{
+		KeyCheck: nssKeyTypeTag,
+		Algo: struct {
+			asn1.ObjectIdentifier
+			IV []byte
+		}{oidAES256CBC, iv},
+		Encrypted: ct,
+	})
+	require.NoError(t, err)
+	return base64.StdEncoding.EncodeToString(raw)
+}
+
+func TestPasswordChecker_Key4(t *testing.T) {
+	dir := t.TempDir()
+	createTestKey4DB(t, dir, "correct horse")
+
+	c, err := NewPasswordChecker(dir)
+	require.NoError(t, err)
+	assert.Equal(t, key4DBFile, c.KeyDB())
+	assert.True(t, c.Locked())
+	assert.False(t, c.Check(""))
+	assert.False(t, c.Check("wrong"))
+	assert.True(t, c.Check("correct horse"))
+}
+
+func TestPasswordChecker_Unlocked(t *testing.T) {
+	dir := t.TempDir()
+	createTestKey4DB(t, dir, "")
+
+	c, err := NewPasswordChecker(dir)
+	require.NoError(t, err)
+	assert.False(t, c.Locked())
+	assert.True(t, c.Check(""))
+}
+
+func TestPasswordChecker_Key3(t *testing.T) {
+	dir := t.TempDir()
+	createTestKey3DB(t, dir)
+
+	c, err := NewPasswordChecker(dir)
+	require.NoError(t, err)
+	assert.Equal(t, key3DBFile, c.KeyDB())
+	assert.False(t, c.Locked(), "the key3 fixture has no primary password")
+	assert.False(t, c.Check("anything"))
+}
+
+func TestPasswordChecker_NoKeyDB(t *testing.T) {
+	_, err := NewPasswordChecker(t.TempDir())
+	require.ErrorIs(t, err, ErrNoKeyDB)
+}
+
+func TestRetrieveMasterKey_PrimaryPassword(t *testing.T) {
+	dir := t.TempDir()
+	createTestKey4DB(t, dir, "s3cret")
+	path := filepath.Join(dir, key4DBFile)
+
+	_, err := retrieveMasterKey(path, "", nil)
+	require.ErrorIs(t, err, errPasswordCheck)
+	assert.Contains(t, err.Error(), "primary password")
+
+	key, err := retrieveMasterKey(path, "s3cret", nil)
+	require.NoError(t, err)
+	assert.Equal(t, key4MasterKey, key)
+}
+
+// TestRetrieveMasterKey_PasswordFallsBackToEmpty: a primary password is applied to every profile of
+// an installation, so profiles without one must still unlock.
+func TestRetrieveMasterKey_PasswordFallsBackToEmpty(t *testing.T) {
+	dir := t.TempDir()
+	createTestKey4DB(t, dir, "")
+
+	key, err := retrieveMasterKey(filepath.Join(dir, key4DBFile), "some-other-profile-password", nil)
+	require.NoError(t, err)
+	assert.Equal(t, key4MasterKey, key)
+}
+
+func TestExtract_PrimaryPassword(t *testing.T) {
+	root := t.TempDir()
+	profileDir := filepath.Join(root, "locked.default-release")
+	mkDir(profileDir)
+	createTestKey4DB(t, profileDir, "s3cret")
+	installFile(t, profileDir, createTestJSON(t, "logins.json", `{"logins":[{
+		"hostname":"https://locked.example",
+		"encryptedUsername":"`+encryptAESLogin(t, key4MasterKey, "bob")+`",
+		"encryptedPassword":"`+encryptAESLogin(t, key4MasterKey, "pa55")+`",
+		"timeCreated":1700000000000}]}`), "logins.json")
+
+	b, err := NewBrowser(types.BrowserConfig{Name: "Firefox", Kind: types.Firefox, UserDataDir: root})
+	require.NoError(t, err)
+	require.NotNil(t, b)
+
+	results, err := b.Extract([]types.Category{types.Password})
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+	require.Len(t, results[0].Data.Passwords, 1)
+	assert.Empty(t, results[0].Data.Passwords[0].Password, "locked profile must not decrypt without the password")
+
+	b.SetPrimaryPassword("s3cret")
+	results, err = b.Extract([]types.Category{types.Password})
+	require.NoError(t, err)
+	require.Len(t, results[0].Data.Passwords, 1)
+	assert.Equal(t, "bob", results[0].Data.Passwords[0].Username)
+	assert.Equal(t, "pa55", results[0].Data.Passwords[0].Password)
+}
+
+// loginsJSON is a logins.json with one login sealed under key.
+func loginsJSON(t *testing.T, key []byte) string {
+	t.Helper()
+	return createTestJSON(t, "logins.json", `{"logins":[{
+		"hostname":"https://locked.example",
+		"encryptedUsername":"`+encryptAESLogin(t, key, "bob")+`",
+		"encryptedPassword":"`+encryptAESLogin(t, key, "pa55")+`",
+		"timeCreated":1700000000000}]}`)
+}
+
+func TestExportProfileKeys(t *testing.T) {
+	root := t.TempDir()
+	for _, p := range []struct{ name, password string }{{"a.default", ""}, {"b.locked", "s3cret"}} {
+		dir := filepath.Join(root, p.name)
+		mkDir(dir)
+		createTestKey4DB(t, dir, p.password)
+		installFile(t, dir, loginsJSON(t, key4MasterKey), "logins.json")
+	}
+	b, err := NewBrowser(types.BrowserConfig{Key: "firefox", Name: "Firefox", Kind: types.Firefox, UserDataDir: root})
+	require.NoError(t, err)
+	require.NotNil(t, b)
+
+	keys, err := b.ExportProfileKeys()
+	require.ErrorIs(t, err, errPasswordCheck)
+	assert.Contains(t, err.Error(), "b.locked")
+	assert.Equal(t, map[string][]byte{"a.default": key4MasterKey}, keys, "the unlocked sibling is still exported")
+
+	b.SetPrimaryPassword("s3cret")
+	keys, err = b.ExportProfileKeys()
+	require.NoError(t, err)
+	assert.Equal(t, map[string][]byte{"a.default": key4MasterKey, "b.locked": key4MasterKey}, keys)
+}
+
+func TestSetProfileKeys(t *testing.T) {
+	root := t.TempDir()
+	dumped := filepath.Join(root, "dumped.default")
+	mkDir(dumped)
+	installFile(t, dumped, loginsJSON(t, key4MasterKey), "logins.json")
+	stale := filepath.Join(root, "stale.default")
+	mkDir(stale)
+	createTestKey4DB(t, stale, "")
+	installFile(t, stale, loginsJSON(t, key4MasterKey), "logins.json")
+
+	b, err := NewBrowser(types.BrowserConfig{Name: "Firefox", Kind: types.Firefox, UserDataDir: root})
+	require.NoError(t, err)
+	require.NotNil(t, b)
+	// dumped.default has no key database at all; stale.default's dumped key is wrong, so its key4.db decides.
+	b.SetProfileKeys(map[string][]byte{
+		"dumped.default": key4MasterKey,
+		"stale.default":  []byte("ffffffffffffffffffffffffffffffff"),
+	})
+
+	results, err := b.Extract([]types.Category{types.Password})
+	require.NoError(t, err)
+	require.Len(t, results, 2)
+	for _, r := range results {
+		require.Len(t, r.Data.Passwords, 1, r.Profile.Name)
+		assert.Equal(t, "pa55", r.Data.Passwords[0].Password, r.Profile.Name)
+	}
+}
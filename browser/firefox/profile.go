@@ -7,15 +7,22 @@ import (
 	"github.com/moond4rk/hackbrowserdata/filemanager"
 	"github.com/moond4rk/hackbrowserdata/log"
 	"github.com/moond4rk/hackbrowserdata/types"
+	"github.com/moond4rk/hackbrowserdata/utils/cacheutil"
 	"github.com/moond4rk/hackbrowserdata/utils/fileutil"
+	"github.com/moond4rk/hackbrowserdata/utils/sqliteutil"
 )
 
 // profile is one Firefox profile — the leaf extraction unit. Unlike Chromium,
 // each Firefox profile owns its own master key (derived from its key4.db).
 type profile struct {
-	profileDir  string
-	browserName string
-	sourcePaths map[types.Category]resolvedPath
+	profileDir      string
+	browserName     string
+	sourcePaths     map[types.Category]resolvedPath
+	primaryPassword string // NSS primary password; empty = Firefox default (none)
+	masterKey       []byte // key from a restored dump; tried before the key database
+	recoverDeleted  bool   // also carve deleted rows, see recoverCategory
+	cacheBodyDir    string // save decoded cache bodies here; "" = don't
+	faviconDir      string // save favicon images here; "" = don't
 }
 
 func (p *profile) name() string {
@@ -55,6 +62,19 @@ func (p *profile) extract(categories []types.Category) *types.BrowserData {
 	return data
 }
 
+// exportKey derives the profile's master key the way extract does, for a portable key dump. A profile
+// without a key database yields (nil, nil).
+func (p *profile) exportKey() ([]byte, error) {
+	session, err := filemanager.NewSession()
+	if err != nil {
+		return nil, err
+	}
+	defer session.Cleanup()
+
+	tempPaths := p.acquireFiles(session, []types.Category{types.Password})
+	return p.getMasterKey(session, tempPaths)
+}
+
 func (p *profile) count(categories []types.Category) map[types.Category]int {
 	session, err := filemanager.NewSession()
 	if err != nil {
@@ -93,30 +113,54 @@ func (p *profile) acquireFiles(session *filemanager.Session, categories []types.
 }
 
 // getMasterKey retrieves the Firefox master encryption key from this profile's
-// key4.db. The key is derived via NSS ASN1 PBE decryption (platform-agnostic).
-// If logins.json was already acquired by acquireFiles, the derived key is
-// validated by attempting to decrypt an actual login entry.
+// key4.db, falling back to the legacy key3.db. The key is derived via NSS ASN1
+// PBE decryption (platform-agnostic). If the password source was already
+// acquired by acquireFiles, the derived key is validated by attempting to
+// decrypt an actual login entry. A key supplied by SetProfileKeys is used
+// as-is unless it fails that check, in which case the key database decides.
 func (p *profile) getMasterKey(session *filemanager.Session, tempPaths map[types.Category]string) ([]byte, error) {
-	key4Src := filepath.Join(p.profileDir, "key4.db")
-	if !fileutil.FileExists(key4Src) {
-		return nil, nil
+	// The password source is already acquired by acquireFiles; reuse it
+	// for master key validation if available.
+	samples := loadLoginSamples(tempPaths[types.Password], p.usesSignons())
+	if p.masterKey != nil {
+		if len(samples) == 0 || validateKeyWithLogins([][]byte{p.masterKey}, samples) != nil {
+			return p.masterKey, nil
+		}
+		log.Debugf("supplied key for %s decrypts no login, deriving from the key database", p.label())
 	}
-	key4Dst := filepath.Join(session.TempDir(), "key4.db")
-	if err := session.Acquire(key4Src, key4Dst, false); err != nil {
-		return nil, fmt.Errorf("acquire key4.db: %w", err)
+	for _, name := range keyDBFiles {
+		src := filepath.Join(p.profileDir, name)
+		if !fileutil.FileExists(src) {
+			continue
+		}
+		dst := filepath.Join(session.TempDir(), name)
+		if err := session.Acquire(src, dst, false); err != nil {
+			return nil, fmt.Errorf("acquire %s: %w", name, err)
+		}
+		return retrieveMasterKey(dst, p.primaryPassword, samples)
 	}
+	return nil, nil
+}
 
-	// logins.json is already acquired by acquireFiles as the Password source;
-	// reuse it for master key validation if available.
-	loginsPath := tempPaths[types.Password]
-	return retrieveMasterKey(key4Dst, loginsPath)
+// usesSignons reports whether the password source resolved to the legacy
+// signons.sqlite instead of logins.json.
+func (p *profile) usesSignons() bool {
+	return p.sourcePaths[types.Password].rel == signonsFile
 }
 
 func (p *profile) extractCategory(data *types.BrowserData, cat types.Category, masterKey []byte, path string) {
+	var wal *sqliteutil.WAL
+	if p.recoverDeleted {
+		wal = p.readWAL(cat, path)
+	}
 	var err error
 	switch cat {
 	case types.Password:
-		data.Passwords, err = extractPasswords(masterKey, path)
+		if p.usesSignons() {
+			data.Passwords, err = extractSignons(masterKey, path)
+		} else {
+			data.Passwords, err = extractPasswords(masterKey, path)
+		}
 	case types.Cookie:
 		data.Cookies, err = extractCookies(path)
 	case types.History:
@@ -129,11 +173,78 @@ func (p *profile) extractCategory(data *types.BrowserData, cat types.Category, m
 		data.Extensions, err = extractExtensions(path)
 	case types.LocalStorage:
 		data.LocalStorage, err = extractLocalStorage(path)
-	case types.CreditCard, types.SessionStorage:
-		// Firefox does not support CreditCard or SessionStorage extraction.
+	case types.Cache:
+		data.Caches, err = extractCache(path, p.cacheBodies())
+	case types.Favicon:
+		data.Favicons, err = extractFavicons(path, p.faviconImages())
+	case types.CreditCard, types.SessionStorage, types.Omnibox:
+		// Firefox does not support CreditCard, SessionStorage or Omnibox extraction.
 	}
 	if err != nil {
 		log.Debugf("extract %s for %s: %v", cat, p.label(), err)
+		return
+	}
+	if p.recoverDeleted {
+		p.recoverCategory(data, cat, path, wal)
+	}
+}
+
+// cacheBodies returns the writer extractCache saves response bodies with, or nil when they are not
+// carved.
+func (p *profile) cacheBodies() *cacheutil.BodyWriter {
+	if p.cacheBodyDir == "" {
+		return nil
+	}
+	return cacheutil.NewBodyWriter(p.cacheBodyDir)
+}
+
+// faviconImages returns the writer extractFavicons saves icon images with, or nil when they are not
+// saved.
+func (p *profile) faviconImages() *cacheutil.BodyWriter {
+	if p.faviconDir == "" {
+		return nil
+	}
+	return cacheutil.NewBodyWriter(p.faviconDir)
+}
+
+// readWAL reads the write-ahead log of the category's database when recoverCategory carves it.
+// Opening the database checkpoints and deletes the log, so this runs before the live rows are read.
+func (p *profile) readWAL(cat types.Category, path string) *sqliteutil.WAL {
+	switch cat {
+	case types.Cookie, types.History:
+	default:
+		return nil
+	}
+	wal, err := sqliteutil.ReadWAL(path)
+	if err != nil {
+		log.Debugf("read %s wal for %s: %v", cat, p.label(), err)
+	}
+	return wal
+}
+
+// recoverCategory appends the rows carved out of the free space of the category's database — cookies
+// and history — and the row versions its write-ahead log superseded after the live ones. Logins are
+// left out: current profiles keep them in logins.json.
+func (p *profile) recoverCategory(data *types.BrowserData, cat types.Category, path string, wal *sqliteutil.WAL) {
+	var n int
+	var err error
+	switch cat {
+	case types.Cookie:
+		var cookies []types.CookieEntry
+		cookies, err = recoverCookies(path, wal, data.Cookies)
+		data.Cookies, n = append(data.Cookies, cookies...), len(cookies)
+	case types.History:
+		var histories []types.HistoryEntry
+		histories, err = recoverHistories(path, wal, data.Histories)
+		data.Histories, n = append(data.Histories, histories...), len(histories)
+	default:
+		return
+	}
+	if err != nil {
+		log.Debugf("recover %s for %s: %v", cat, p.label(), err)
+	}
+	if n > 0 {
+		log.Infof("%s: recovered %d deleted %s entries", p.label(), n, cat)
 	}
 }
 
@@ -142,7 +253,11 @@ func (p *profile) countCategory(cat types.Category, path string) int {
 	var err error
 	switch cat {
 	case types.Password:
-		count, err = countPasswords(path)
+		if p.usesSignons() {
+			count, err = countSignons(path)
+		} else {
+			count, err = countPasswords(path)
+		}
 	case types.Cookie:
 		count, err = countCookies(path)
 	case types.History:
@@ -155,8 +270,12 @@ func (p *profile) countCategory(cat types.Category, path string) int {
 		count, err = countExtensions(path)
 	case types.LocalStorage:
 		count, err = countLocalStorage(path)
-	case types.CreditCard, types.SessionStorage:
-		// Firefox does not support CreditCard or SessionStorage.
+	case types.Cache:
+		count, err = countCache(path)
+	case types.Favicon:
+		count, err = countFavicons(path)
+	case types.CreditCard, types.SessionStorage, types.Omnibox:
+		// Firefox does not support CreditCard, SessionStorage or Omnibox.
 	}
 	if err != nil {
 		log.Debugf("count %s for %s: %v", cat, p.label(), err)
@@ -14,17 +14,29 @@ type sourcePath struct {
 }
 
 func file(rel string) sourcePath { return sourcePath{rel: filepath.FromSlash(rel), isDir: false} }
+func dir(rel string) sourcePath  { return sourcePath{rel: filepath.FromSlash(rel), isDir: true} }
+
+// signonsFile is the SQLite password store used before Firefox 32 migrated logins
+// to logins.json; long-lived profiles may still carry it without a logins.json.
+const signonsFile = "signons.sqlite"
 
 // firefoxSources defines the Firefox file layout.
 // Each category maps to one or more candidate paths tried in priority order;
 // the first existing path wins.
-// Firefox does not support SessionStorage or CreditCard extraction.
+// Firefox does not support SessionStorage, CreditCard or Omnibox extraction.
 var firefoxSources = map[types.Category][]sourcePath{
-	types.Password:     {file("logins.json")},
+	types.Password:     {file("logins.json"), file(signonsFile)},
 	types.Cookie:       {file("cookies.sqlite")},
 	types.History:      {file("places.sqlite")},
 	types.Download:     {file("places.sqlite")},
 	types.Bookmark:     {file("places.sqlite")},
 	types.Extension:    {file("extensions.json")},
 	types.LocalStorage: {file("webappsstore.sqlite")},
+	types.Cache:        {dir(cacheDirName)},
+	types.Favicon:      {file("favicons.sqlite")},
 }
+
+// cacheDirName is the HTTP cache directory. Firefox keeps it in the profile's local counterpart (see
+// types.BrowserConfig.CacheDir) rather than the profile itself, except in a copied or archived
+// profile, where it was collected into the profile.
+const cacheDirName = "cache2"
@@ -93,6 +93,49 @@ const webappsstore2Schema = `CREATE TABLE webappsstore2 (
 	value TEXT
 )`
 
+// mozLoginsSchema is the legacy signons.sqlite login table (schema v5, Firefox 4-31).
+const mozLoginsSchema = `CREATE TABLE moz_logins (
+	id INTEGER PRIMARY KEY,
+	hostname TEXT NOT NULL,
+	httpRealm TEXT,
+	formSubmitURL TEXT,
+	usernameField TEXT NOT NULL,
+	passwordField TEXT NOT NULL,
+	encryptedUsername TEXT NOT NULL,
+	encryptedPassword TEXT NOT NULL,
+	guid TEXT,
+	encType INTEGER,
+	timeCreated INTEGER,
+	timeLastUsed INTEGER,
+	timePasswordChanged INTEGER,
+	timesUsed INTEGER
+)`
+
+// favicons.sqlite tables (Firefox 55+).
+const (
+	mozIconsSchema = `CREATE TABLE moz_icons (
+	id INTEGER PRIMARY KEY,
+	icon_url TEXT NOT NULL,
+	fixed_icon_url_hash INTEGER NOT NULL,
+	width INTEGER NOT NULL DEFAULT 0,
+	root INTEGER NOT NULL DEFAULT 0,
+	color INTEGER,
+	expire_ms INTEGER NOT NULL DEFAULT 0,
+	data BLOB
+)`
+	mozPagesWIconsSchema = `CREATE TABLE moz_pages_w_icons (
+	id INTEGER PRIMARY KEY,
+	page_url TEXT NOT NULL,
+	page_url_hash INTEGER NOT NULL
+)`
+	mozIconsToPagesSchema = `CREATE TABLE moz_icons_to_pages (
+	page_id INTEGER NOT NULL,
+	icon_id INTEGER NOT NULL,
+	expire_ms INTEGER NOT NULL DEFAULT 0,
+	PRIMARY KEY (page_id, icon_id)
+) WITHOUT ROWID`
+)
+
 // ---------------------------------------------------------------------------
 // INSERT helpers
 // ---------------------------------------------------------------------------
@@ -129,6 +172,40 @@ func insertMozAnno(placeID int, content string, dateAdded int64) string {
 	)
 }
 
+func insertMozLogin(hostname, formSubmitURL, encUser, encPwd string, timeCreated int64) string {
+	return fmt.Sprintf(
+		`INSERT INTO moz_logins (hostname, formSubmitURL, usernameField, passwordField,
+		 encryptedUsername, encryptedPassword, encType, timeCreated)
+		 VALUES ('%s', '%s', 'user', 'pass', '%s', '%s', 1, %d)`,
+		hostname, formSubmitURL, encUser, encPwd, timeCreated,
+	)
+}
+
+// sqlInsertBlobs fills each %s in format with a blob as an x'…' hex literal.
+func sqlInsertBlobs(format string, blobs ...[]byte) string {
+	args := make([]any, len(blobs))
+	for i, b := range blobs {
+		args[i] = fmt.Sprintf("x'%x'", b)
+	}
+	return fmt.Sprintf(format, args...)
+}
+
+func insertMozIcon(id int, iconURL string, width int, expireMs int64, data []byte) string {
+	return fmt.Sprintf(
+		`INSERT INTO moz_icons (id, icon_url, fixed_icon_url_hash, width, expire_ms, data)
+		 VALUES (%d, '%s', 0, %d, %d, x'%x')`,
+		id, iconURL, width, expireMs, data,
+	)
+}
+
+func insertMozPageWIcon(id int, pageURL string) string {
+	return fmt.Sprintf(`INSERT INTO moz_pages_w_icons (id, page_url, page_url_hash) VALUES (%d, '%s', 0)`, id, pageURL)
+}
+
+func insertMozIconToPage(pageID, iconID int) string {
+	return fmt.Sprintf(`INSERT INTO moz_icons_to_pages (page_id, icon_id) VALUES (%d, %d)`, pageID, iconID)
+}
+
 func insertWebappsstore(originKey, key, value string) string {
 	return fmt.Sprintf(
 		`INSERT INTO webappsstore2 (originAttributes, originKey, scope, key, value)
@@ -167,6 +244,29 @@ func createTestDB(t *testing.T, name string, schemas []string, inserts ...string
 	return path
 }
 
+// createWALTestDB is createTestDB for a database in WAL mode, copied with its -wal and -shm while
+// still open, the way a profile is acquired from a running Firefox: the copy's log holds every commit.
+func createWALTestDB(t *testing.T, name string, schemas []string, stmts ...string) string {
+	t.Helper()
+	src := filepath.Join(t.TempDir(), name)
+	db, err := sql.Open("sqlite", src)
+	require.NoError(t, err)
+	defer db.Close()
+	db.SetMaxOpenConns(1)
+
+	for _, stmt := range append(append([]string{"PRAGMA journal_mode=WAL", "PRAGMA wal_autocheckpoint=0"}, schemas...), stmts...) {
+		_, err = db.Exec(stmt)
+		require.NoError(t, err)
+	}
+	path := filepath.Join(t.TempDir(), name)
+	for _, suffix := range []string{"", "-wal", "-shm"} {
+		data, err := os.ReadFile(src + suffix)
+		require.NoError(t, err)
+		require.NoError(t, os.WriteFile(path+suffix, data, 0o644))
+	}
+	return path
+}
+
 func createTestJSON(t *testing.T, name, content string) string {
 	t.Helper()
 	path := filepath.Join(t.TempDir(), name)
@@ -0,0 +1,100 @@
+package browser
+
+import (
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+
+	"github.com/moond4rk/hackbrowserdata/browser/chromium"
+	"github.com/moond4rk/hackbrowserdata/browser/firefox"
+	"github.com/moond4rk/hackbrowserdata/filemanager"
+	"github.com/moond4rk/hackbrowserdata/log"
+	"github.com/moond4rk/hackbrowserdata/types"
+)
+
+// safariLibraryParts are the parts of a ~/Library a Safari restore reads: the Safari dir, cookies, the
+// sandbox container and the login keychain beside them.
+var safariLibraryParts = []string{"Safari", "Cookies", "Containers/com.apple.Safari", "Keychains"}
+
+// imageHomes are where a volume keeps its users' home dirs: Windows and macOS, Linux, and the volume
+// root itself, which is where a separate /home partition keeps them (and where /root sits on a root
+// volume). Installations are looked for below them only, at the paths installTails give, so a root
+// dir counts only when an install path exists beneath it.
+var imageHomes = []string{"Users/*", "home/*", "*"}
+
+// StageInstallations copies the browser installations found in fsys — a volume read out of a disk
+// image — into dest under the same relative paths, so dest then restores as a collection. Only what
+// each engine reads for categories is copied (see chromium.StagePaths and firefox.StagePaths), and a
+// Safari Library only as far as Safari reads it. Files that cannot be read are skipped with a warning.
+// Returns the staged installation dirs.
+func StageInstallations(fsys fs.FS, dest string, categories []types.Category) ([]string, error) {
+	session, err := filemanager.NewSession()
+	if err != nil {
+		return nil, err
+	}
+	defer session.Cleanup()
+
+	var staged []string
+	for _, r := range findImageInstallations(fsys) {
+		for _, p := range stagePaths(fsys, r, categories) {
+			info, err := fs.Stat(fsys, p)
+			if err != nil {
+				continue
+			}
+			dst := filepath.Join(dest, filepath.FromSlash(p))
+			if err := os.MkdirAll(filepath.Dir(dst), 0o700); err != nil {
+				return nil, err
+			}
+			if err := session.AcquireFS(fsys, p, dst, info.IsDir()); err != nil {
+				log.Warnf("image: stage %s: %v", p, err)
+			}
+		}
+		staged = append(staged, r.dir)
+	}
+	return staged, nil
+}
+
+// findImageInstallations looks for installations at the install paths below each home dir of a
+// volume, rather than walking all of it as findInstallations does for a collection.
+func findImageInstallations(fsys fs.FS) []collectionRoot {
+	var roots []collectionRoot
+	seen := make(map[string]bool)
+	for _, pattern := range imageHomes {
+		homes, _ := fs.Glob(fsys, pattern)
+		for _, home := range homes {
+			for _, t := range installTails {
+				dirs, _ := fs.Glob(fsys, path.Join(home, t.tail))
+				for _, dir := range dirs {
+					kind, ok := installationKind(fsys, dir)
+					if !ok || seen[dir] || (kind == types.Safari) != (t.key == "safari") ||
+						(kind == types.Firefox) != (t.key == "firefox") {
+						continue
+					}
+					seen[dir] = true
+					if kind == types.Chromium {
+						kind = kindForKey(t.key)
+					}
+					roots = append(roots, collectionRoot{key: t.key, kind: kind, dir: dir})
+				}
+			}
+		}
+	}
+	return disambiguateRoots(roots)
+}
+
+// stagePaths lists the slash paths in fsys an installation is staged from.
+func stagePaths(fsys fs.FS, r collectionRoot, categories []types.Category) []string {
+	switch r.kind {
+	case types.Firefox:
+		return firefox.StagePaths(fsys, r.dir, categories)
+	case types.Safari:
+		parts := make([]string, 0, len(safariLibraryParts))
+		for _, p := range safariLibraryParts {
+			parts = append(parts, path.Join(r.dir, p))
+		}
+		return parts
+	default:
+		return chromium.StagePaths(fsys, r.dir, r.kind, categories)
+	}
+}
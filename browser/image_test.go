@@ -0,0 +1,137 @@
+package browser
+
+import (
+	"encoding/binary"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/moond4rk/hackbrowserdata/diskimage"
+	"github.com/moond4rk/hackbrowserdata/types"
+)
+
+func TestStageInstallations(t *testing.T) {
+	chrome := "Users/alice/AppData/Local/Google/Chrome/User Data"
+	firefox := "Users/alice/AppData/Roaming/Mozilla/Firefox/Profiles"
+	library := "Users/dave/Library"
+	volume := fstest.MapFS{
+		chrome + "/Local State":                              {Data: []byte(`{"os_crypt":{}}`)},
+		chrome + "/Default/Preferences":                      {Data: []byte("{}")},
+		chrome + "/Default/History":                          {Data: []byte("history")},
+		chrome + "/Default/History-wal":                      {Data: []byte("wal")},
+		chrome + "/Default/Cache/Cache_Data/index":           {Data: []byte("cache")},
+		chrome + "/Default/Code Cache/js/index":              {Data: []byte("code cache")},
+		chrome + "/SingletonLock":                            {Data: []byte("")},
+		firefox + "/x.default/key4.db":                       {Data: []byte("key4")},
+		firefox + "/x.default/places.sqlite":                 {Data: []byte("places")},
+		firefox + "/x.default/cache2/entries/ABCD":           {Data: []byte("cache")},
+		library + "/Safari/History.db":                       {Data: []byte("safari")},
+		library + "/Mail/V10/message.emlx":                   {Data: []byte("not browser data")},
+		"Users/alice/AppData/Roaming/Slack/Local State":      {Data: []byte("{}")},
+		"Windows/System32/config/SOFTWARE":                   {Data: []byte("hive")},
+		"backup/Users/bob/.config/google-chrome/Local State": {Data: []byte("{}")},
+	}
+
+	dest := t.TempDir()
+	staged, err := StageInstallations(volume, dest, []types.Category{types.History, types.Password})
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{chrome, firefox, library}, staged)
+
+	at := func(p string) string { return filepath.Join(dest, filepath.FromSlash(p)) }
+	assert.FileExists(t, at(chrome+"/Local State"))
+	assert.FileExists(t, at(chrome+"/Default/Preferences"))
+	assert.FileExists(t, at(chrome+"/Default/History"))
+	assert.FileExists(t, at(chrome+"/Default/History-wal"))
+	assert.NoDirExists(t, at(chrome+"/Default/Cache"), "a category that was not asked for")
+	assert.NoDirExists(t, at(chrome+"/Default/Code Cache"), "no category reads it")
+	assert.NoFileExists(t, at(chrome+"/SingletonLock"))
+	assert.FileExists(t, at(firefox+"/x.default/key4.db"))
+	assert.FileExists(t, at(firefox+"/x.default/places.sqlite"))
+	assert.NoDirExists(t, at(firefox+"/x.default/cache2"))
+	assert.FileExists(t, at(library+"/Safari/History.db"))
+	assert.NoDirExists(t, at(library+"/Mail"), "only the parts of a Library Safari reads are staged")
+	assert.NoDirExists(t, at("Users/alice/AppData/Roaming/Slack"))
+	assert.NoDirExists(t, at("Windows"))
+	assert.NoDirExists(t, at("backup"), "only home dirs are searched")
+
+	got := make(map[string]string)
+	for _, r := range findCollectionRoots(dest) {
+		got[r.key] = r.dir
+	}
+	assert.Equal(t, map[string]string{"chrome": at(chrome), "firefox": at(firefox), "safari": at(library)}, got)
+}
+
+// TestStageInstallations_SeparateHome stages a two-partition Linux disk whose /home is its own volume,
+// so user dirs sit at that volume's root rather than below home/.
+func TestStageInstallations_SeparateHome(t *testing.T) {
+	rootVol := mkExtVolume(t, map[string]string{
+		"root/.mozilla/firefox/r.default/key4.db": "key4",
+		"etc/hostname": "laptop",
+	})
+	homeVol := mkExtVolume(t, map[string]string{
+		"alice/.config/google-chrome/Local State":         `{"os_crypt":{}}`,
+		"alice/.config/google-chrome/Default/Preferences": "{}",
+		"alice/.config/google-chrome/Default/History":     "history",
+		"lost+found/.config/unrelated":                    "x",
+	})
+	disk := make([]byte, 512+len(rootVol)+len(homeVol))
+	for i, v := range []struct{ start, size int }{{1, len(rootVol) / 512}, {1 + len(rootVol)/512, len(homeVol) / 512}} {
+		e := disk[446+16*i:]
+		e[4] = 0x83
+		binary.LittleEndian.PutUint32(e[8:], uint32(v.start))
+		binary.LittleEndian.PutUint32(e[12:], uint32(v.size))
+	}
+	disk[510], disk[511] = 0x55, 0xaa
+	copy(disk[512:], rootVol)
+	copy(disk[512+len(rootVol):], homeVol)
+	path := filepath.Join(t.TempDir(), "disk.raw")
+	require.NoError(t, os.WriteFile(path, disk, 0o600))
+
+	img, err := diskimage.Open(path)
+	require.NoError(t, err)
+	defer img.Close()
+	parts, err := img.Partitions()
+	require.NoError(t, err)
+	require.Len(t, parts, 2)
+	dest := t.TempDir()
+	var staged []string
+	for _, p := range parts {
+		vol, err := img.OpenVolume(p)
+		require.NoError(t, err)
+		roots, err := StageInstallations(vol.FS, filepath.Join(dest, vol.Name()), []types.Category{types.History})
+		require.NoError(t, err)
+		for _, r := range roots {
+			staged = append(staged, vol.Name()+"/"+r)
+		}
+	}
+	assert.ElementsMatch(t, []string{"p1-ext/root/.mozilla/firefox", "p2-ext/alice/.config/google-chrome"}, staged)
+	assert.FileExists(t, filepath.Join(dest, "p2-ext", "alice", ".config", "google-chrome", "Default", "History"))
+}
+
+// mkExtVolume builds an ext4 volume holding files with mke2fs -d, skipping the test where e2fsprogs
+// is missing.
+func mkExtVolume(t *testing.T, files map[string]string) []byte {
+	t.Helper()
+	mke2fs, err := exec.LookPath("mke2fs")
+	if err != nil {
+		t.Skip("mke2fs not installed")
+	}
+	src := t.TempDir()
+	for name, data := range files {
+		p := filepath.Join(src, filepath.FromSlash(name))
+		require.NoError(t, os.MkdirAll(filepath.Dir(p), 0o755))
+		require.NoError(t, os.WriteFile(p, []byte(data), 0o644))
+	}
+	img := filepath.Join(t.TempDir(), "vol.img")
+	if out, err := exec.Command(mke2fs, "-q", "-F", "-t", "ext4", "-d", src, img, "8M").CombinedOutput(); err != nil {
+		t.Skipf("mke2fs -d: %v: %s", err, out)
+	}
+	data, err := os.ReadFile(img)
+	require.NoError(t, err)
+	return data
+}
@@ -0,0 +1,33 @@
+package browser
+
+import (
+	"github.com/moond4rk/hackbrowserdata/masterkey"
+)
+
+// BuildFromKeychain reconstructs Chromium engines from copied macOS data without a dump: each
+// installation's v10 key is derived from its "<label> Safe Storage" item in a copied login.keychain-db,
+// unlocked with the user's login password. dataDir takes BuildFromDPAPI's layouts; the browser key
+// picks the keychain label, so a subdir named by an unknown key finds no secret. Safari found alongside
+// reads its passwords from the same keychain.
+func BuildFromKeychain(keychainPath, password, dataDir, filter string) ([]Browser, error) {
+	dump, err := keylessVaults(dataDir, filter)
+	if err != nil {
+		return nil, err
+	}
+	retriever := &masterkey.KeychainFileRetriever{Path: keychainPath, Password: password}
+	browsers, err := buildFromVaults(dump, dataDir, filter, macKeychainLabels, func(masterkey.Vault) masterkey.Retrievers {
+		return masterkey.Retrievers{V10: retriever}
+	})
+	if err != nil {
+		return nil, err
+	}
+	for _, b := range browsers {
+		if kf, ok := b.(KeychainFileReceiver); ok {
+			kf.SetKeychainFile(keychainPath)
+		}
+		if kp, ok := b.(KeychainPasswordReceiver); ok {
+			kp.SetKeychainPassword(password)
+		}
+	}
+	return browsers, nil
+}
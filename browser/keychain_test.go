@@ -0,0 +1,48 @@
+package browser
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBuildFromKeychain(t *testing.T) {
+	dataDir := t.TempDir()
+	root := filepath.Join(dataDir, "brave")
+	makeUserData(t, root, testProfileDefault)
+	require.NoError(t, os.WriteFile(filepath.Join(root, "Local State"), []byte(`{}`), 0o600))
+
+	keychain := filepath.Join(t.TempDir(), "login.keychain-db")
+	browsers, err := BuildFromKeychain(keychain, "hunter2", dataDir, "")
+	require.NoError(t, err)
+	require.Len(t, browsers, 1)
+	assert.Equal(t, root, browsers[0].UserDataDir())
+
+	km, ok := browsers[0].(KeyManager)
+	require.True(t, ok)
+	keys, err := km.ExportKeys()
+	assert.ErrorContains(t, err, "read keychain", "the keychain is opened lazily, on the first key lookup")
+	assert.Nil(t, keys.V10)
+
+	_, err = BuildFromKeychain(keychain, "hunter2", dataDir, "chrome")
+	assert.ErrorContains(t, err, "have: brave")
+
+	// Safari in the same image reads its passwords from the same copied keychain.
+	library := filepath.Join(dataDir, "safari")
+	mkFile(t, library, "Safari", "History.db")
+	browsers, err = BuildFromKeychain(keychain, "hunter2", dataDir, "safari")
+	require.NoError(t, err)
+	require.Len(t, browsers, 1)
+	assert.Equal(t, filepath.Join(library, "Safari"), browsers[0].UserDataDir())
+	_, ok = browsers[0].(KeychainFileReceiver)
+	assert.True(t, ok)
+}
+
+func TestMacKeychainLabels(t *testing.T) {
+	assert.Equal(t, "Microsoft Edge", macKeychainLabels["edge"])
+	assert.Equal(t, "Opera", macKeychainLabels["opera-gx"])
+	assert.Empty(t, macKeychainLabels["firefox"])
+}
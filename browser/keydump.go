@@ -6,44 +6,61 @@ import (
 	"path/filepath"
 	"strings"
 
+	"github.com/moond4rk/hackbrowserdata/browser/safari"
 	"github.com/moond4rk/hackbrowserdata/log"
 	"github.com/moond4rk/hackbrowserdata/masterkey"
 	"github.com/moond4rk/hackbrowserdata/types"
 )
 
-// BuildDump exports one Vault per installation (Firefox/Safari, lacking KeyManager, are skipped).
-// Partial results are kept — a Chrome 127+ profile mixes v10+v20, so a v20-only failure must not
-// discard a usable v10 key.
+// BuildDump exports one Vault per installation: installation-wide keys for Chromium (KeyManager),
+// per-profile keys for Firefox (ProfileKeyManager), and a key-less vault for Safari so restore still
+// rebuilds it from an archive. Partial results are kept — a Chrome 127+ profile mixes v10+v20, so a
+// v20-only failure must not discard a usable v10 key, and one locked Firefox profile must not discard
+// its siblings. A Chromium installation with no key at all is left out.
 func BuildDump(browsers []Browser) masterkey.Dump {
 	dump := masterkey.NewDump()
 	for _, b := range browsers {
-		km, ok := b.(KeyManager)
-		if !ok {
+		var (
+			v         masterkey.Vault
+			kind      types.BrowserKind
+			hasKeys   bool
+			needsKeys bool
+			err       error
+		)
+		switch km := b.(type) {
+		case KeyManager:
+			v.Browser, kind = km.BrowserKey(), km.Kind()
+			v.Keys, err = km.ExportKeys()
+			hasKeys, needsKeys = v.Keys.HasAny(), true
+		case ProfileKeyManager:
+			// Kept even without keys: restore falls back to each profile's copied key4.db.
+			v.Browser, kind = km.BrowserKey(), km.Kind()
+			v.ProfileKeys, err = km.ExportProfileKeys()
+			hasKeys = len(v.ProfileKeys) > 0
+		case *safari.Browser:
+			// Restores without any key: its passwords stay in the macOS Keychain, so the vault only
+			// names the installation and engine.
+			v.Browser, kind = km.BrowserKey(), km.Kind()
+		default:
 			continue
 		}
-		mk, err := km.ExportKeys()
 		if err != nil {
 			status := "partial"
-			if !mk.HasAny() {
+			if !hasKeys {
 				status = "failed"
 			}
 			log.Warnf("dump-keys: %s %s: %v", b.BrowserName(), status, err)
 		}
-		if !mk.HasAny() {
+		if needsKeys && !hasKeys {
 			continue
 		}
-		kind, err := kindToDump(km.Kind())
-		if err != nil {
+		if v.Kind, err = kindToDump(kind); err != nil {
 			log.Warnf("dump-keys: %s: %v", b.BrowserName(), err)
 			continue
 		}
-		dump.Vaults = append(dump.Vaults, masterkey.Vault{
-			Browser:     km.BrowserKey(),
-			Kind:        kind,
-			UserDataDir: b.UserDataDir(),
-			Profiles:    profileNames(b),
-			Keys:        mk,
-		})
+		v.UserDataDir = b.UserDataDir()
+		v.Profiles = profileNames(b)
+		dump.Vaults = append(dump.Vaults, v)
 	}
 	return dump
 }
@@ -57,15 +74,32 @@ func profileNames(b Browser) []string {
 	return names
 }
 
-// BuildFromDump reconstructs Chromium engines straight from a dump's vaults, rooted at copied data
-// instead of the local platform table — this is what lets an analyst host decrypt a browser its OS
-// never installs. filter is a browser key ("" or "all" = every vault); a filter matching no vault is
-// an error rather than silent empty output.
+// BuildFromDump reconstructs Chromium and Firefox engines straight from a dump's vaults, rooted at
+// copied data instead of the local platform table — this is what lets an analyst host decrypt a
+// browser its OS never installs. A Firefox profile without a dumped key falls back to its copied
+// key4.db. filter is a browser key ("" or "all" = every vault); a filter matching no vault is an error
+// rather than silent empty output.
 //
-// Data layout is resolved two ways. When dataDir holds per-key subdirs (the archive layout), each
-// vault is rooted at dataDir/<key>. Otherwise dataDir is treated as one browser's User Data (a
-// hand-copied folder), which is unambiguous only for a single vault — so filter must pick one.
+// Data layout is resolved three ways. When dataDir holds per-key subdirs (the archive layout), each
+// vault is rooted at dataDir/<key>. When it is a forensic collection (KAPE, Velociraptor, a home-dir
+// tarball), each vault is rooted at the installation found for its key (see findCollectionRoots).
+// Otherwise dataDir is treated as one browser's User Data (a hand-copied folder), which is unambiguous
+// only for a single vault — so filter must pick one.
 func BuildFromDump(dump masterkey.Dump, dataDir, filter string) ([]Browser, error) {
+	return buildFromVaults(dump, dataDir, filter, nil, func(v masterkey.Vault) masterkey.Retrievers {
+		return retrieversFromKeys(v.Keys)
+	})
+}
+
+// vaultRetrievers picks the master-key retrievers for one restored vault.
+type vaultRetrievers func(masterkey.Vault) masterkey.Retrievers
+
+// buildFromVaults is BuildFromDump with the per-vault retrievers supplied by the caller, so restores
+// that unwrap keys themselves (offline DPAPI, copied keychains and keyrings) share the layout
+// resolution. labels maps a browser key to the KeychainLabel those retrievers look up.
+func buildFromVaults(
+	dump masterkey.Dump, dataDir, filter string, labels map[string]string, retrievers vaultRetrievers,
+) ([]Browser, error) {
 	filter = strings.ToLower(filter)
 	if filter == "all" {
 		filter = ""
@@ -87,7 +121,11 @@ func BuildFromDump(dump masterkey.Dump, dataDir, filter string) ([]Browser, erro
 	}
 
 	archiveLayout := isArchiveLayout(dataDir, selected)
-	if !archiveLayout && len(selected) > 1 {
+	var collected map[string]string
+	if !archiveLayout && !isInstallRoot(dataDir) {
+		collected = collectionDirs(dataDir)
+	}
+	if !archiveLayout && len(collected) == 0 && len(selected) > 1 {
 		return nil, fmt.Errorf("--data-dir %q has no per-browser subdir but keys has %d browsers; "+
 			"point it at the archive root, or use -b <browser> for one browser's User Data (have: %s)",
 			dataDir, len(selected), vaultKeys(dump))
@@ -96,7 +134,13 @@ func BuildFromDump(dump masterkey.Dump, dataDir, filter string) ([]Browser, erro
 	var browsers []Browser
 	for _, v := range selected {
 		root := dataDir
-		if archiveLayout {
+		switch {
+		case len(collected) > 0:
+			if root = collected[strings.ToLower(v.Browser)]; root == "" {
+				log.Warnf("restore: %s has no data in the collection at %s, skipping", v.Browser, dataDir)
+				continue
+			}
+		case archiveLayout:
 			root = filepath.Join(dataDir, strings.ToLower(v.Browser))
 			if !dirExists(root) {
 				log.Warnf("restore: %s has no data under %s, skipping", v.Browser, root)
@@ -108,11 +152,16 @@ func BuildFromDump(dump masterkey.Dump, dataDir, filter string) ([]Browser, erro
 			log.Warnf("restore: %s: %v", v.Browser, err)
 			continue
 		}
+		if kind == types.Safari {
+			root = safari.RestoredUserDataDir(root)
+		}
+		key := strings.ToLower(v.Browser)
 		cfg := types.BrowserConfig{
-			Key:         strings.ToLower(v.Browser),
-			Name:        v.Browser,
-			Kind:        kind,
-			UserDataDir: root,
+			Key:           key,
+			Name:          v.Browser,
+			Kind:          kind,
+			KeychainLabel: labels[key],
+			UserDataDir:   root,
 		}
 		b, err := newBrowser(cfg)
 		if err != nil {
@@ -124,7 +173,10 @@ func BuildFromDump(dump masterkey.Dump, dataDir, filter string) ([]Browser, erro
 			continue
 		}
 		if km, ok := b.(KeyManager); ok {
-			km.SetRetrievers(retrieversFromKeys(v.Keys))
+			km.SetRetrievers(retrievers(v))
+		}
+		if pkm, ok := b.(ProfileKeyManager); ok && len(v.ProfileKeys) > 0 {
+			pkm.SetProfileKeys(v.ProfileKeys)
 		}
 		browsers = append(browsers, b)
 	}
@@ -170,13 +222,16 @@ func retrieversFromKeys(mk masterkey.MasterKeys) masterkey.Retrievers {
 	return masterkey.Retrievers{
 		V10: maybeStaticRetriever(mk.V10),
 		V11: maybeStaticRetriever(mk.V11),
+		V12: maybeStaticRetriever(mk.V12),
 		V20: maybeStaticRetriever(mk.V20),
 	}
 }
 
 // dumpableKinds are the engine kinds a vault may carry; kindToDump/kindFromDump translate to and from
 // the wire form via BrowserKind.String(), keeping the vocabulary single-sourced in the types enum.
-var dumpableKinds = []types.BrowserKind{types.Chromium, types.ChromiumYandex, types.ChromiumOpera}
+var dumpableKinds = []types.BrowserKind{
+	types.Chromium, types.ChromiumYandex, types.ChromiumOpera, types.Firefox, types.Safari,
+}
 
 func kindToDump(k types.BrowserKind) (string, error) {
 	for _, dk := range dumpableKinds {
@@ -9,6 +9,7 @@ import (
 	"strings"
 	"testing"
 
+	"github.com/moond4rk/hackbrowserdata/browser/safari"
 	"github.com/moond4rk/hackbrowserdata/masterkey"
 	"github.com/moond4rk/hackbrowserdata/types"
 )
@@ -59,6 +60,8 @@ func (m *mockChromiumBrowser) SetRetrievers(r masterkey.Retrievers) {
 	m.receivedRetrievers = r
 }
 
+func (m *mockChromiumBrowser) Retrievers() masterkey.Retrievers { return m.receivedRetrievers }
+
 func (m *mockChromiumBrowser) ExportKeys() (masterkey.MasterKeys, error) {
 	m.calls++
 	return m.keys, m.exportErr
@@ -73,6 +76,20 @@ func (m *mockChromiumBrowser) BrowserKey() string {
 
 func (m *mockChromiumBrowser) Kind() types.BrowserKind { return m.kind }
 
+// mockFirefoxBrowser is an installation with per-profile keys.
+type mockFirefoxBrowser struct {
+	mockBrowser
+	keys      map[string][]byte
+	exportErr error
+}
+
+func (m *mockFirefoxBrowser) ExportProfileKeys() (map[string][]byte, error) {
+	return m.keys, m.exportErr
+}
+func (m *mockFirefoxBrowser) SetProfileKeys(keys map[string][]byte) { m.keys = keys }
+func (m *mockFirefoxBrowser) BrowserKey() string                    { return strings.ToLower(m.name) }
+func (m *mockFirefoxBrowser) Kind() types.BrowserKind               { return types.Firefox }
+
 func TestBuildDump_Empty(t *testing.T) {
 	dump := BuildDump(nil)
 	if dump.Version != masterkey.DumpVersion {
@@ -151,6 +168,71 @@ func TestBuildDump_SkipsNonKeyManager(t *testing.T) {
 	}
 }
 
+// keyedMockBrowser names its installation and engine but manages no keys.
+type keyedMockBrowser struct {
+	mockBrowser
+	key  string
+	kind types.BrowserKind
+}
+
+func (m *keyedMockBrowser) BrowserKey() string      { return m.key }
+func (m *keyedMockBrowser) Kind() types.BrowserKind { return m.kind }
+
+func TestBuildDump_KeylessOnlySafari(t *testing.T) {
+	library := t.TempDir()
+	mkFile(t, library, "Safari", "History.db")
+	sf, err := safari.NewBrowser(types.BrowserConfig{
+		Key: "safari", Name: "Safari", Kind: types.Safari, UserDataDir: filepath.Join(library, "Safari"),
+	})
+	if err != nil || sf == nil {
+		t.Fatalf("safari.NewBrowser: b=%v err=%v", sf, err)
+	}
+	chromium := &keyedMockBrowser{
+		mockBrowser: mockBrowser{name: chromeName, userDataDir: "/chrome", profiles: []string{testProfileDefault}},
+		key:         "chrome",
+		kind:        types.Chromium,
+	}
+
+	dump := BuildDump([]Browser{chromium, sf})
+	if len(dump.Vaults) != 1 {
+		t.Fatalf("Vaults len = %d, want 1 (an installation without keys is not a keyless one)", len(dump.Vaults))
+	}
+	if v := dump.Vaults[0]; v.Browser != "safari" || v.Keys.HasAny() {
+		t.Errorf("vault = %+v, want a key-less safari vault", v)
+	}
+}
+
+func TestBuildDump_FirefoxProfileKeys(t *testing.T) {
+	ff := &mockFirefoxBrowser{
+		mockBrowser: mockBrowser{name: "Firefox", userDataDir: "/ff", profiles: []string{"a.default", "b.locked"}},
+		keys:        map[string][]byte{"a.default": []byte("key-a")},
+		exportErr:   errors.New("b.locked: profile is protected by a primary password"),
+	}
+	empty := &mockFirefoxBrowser{mockBrowser: mockBrowser{name: "LibreWolf", userDataDir: "/lw"}}
+
+	dump := BuildDump([]Browser{ff, empty})
+
+	if len(dump.Vaults) != 2 {
+		t.Fatalf("Vaults len = %d, want 2 (a key-less Firefox still restores from key4.db)", len(dump.Vaults))
+	}
+	if lw := dump.Vaults[1]; lw.Browser != "librewolf" || lw.ProfileKeys != nil {
+		t.Errorf("key-less vault = %+v, want librewolf without keys", lw)
+	}
+	v := dump.Vaults[0]
+	if v.Browser != "firefox" || v.Kind != "firefox" {
+		t.Errorf("vault = %s/%s, want firefox/firefox", v.Browser, v.Kind)
+	}
+	if string(v.ProfileKeys["a.default"]) != "key-a" || len(v.ProfileKeys) != 1 {
+		t.Errorf("ProfileKeys = %v, want only a.default (locked sibling must not discard it)", v.ProfileKeys)
+	}
+	if v.Keys.HasAny() {
+		t.Errorf("Keys = %+v, want none for Firefox", v.Keys)
+	}
+	if len(v.Profiles) != 2 {
+		t.Errorf("Profiles = %v, want both", v.Profiles)
+	}
+}
+
 func TestBuildDump_SkipsExportError(t *testing.T) {
 	good := &mockChromiumBrowser{
 		mockBrowser: mockBrowser{name: chromeName, userDataDir: "/chrome", profiles: []string{testProfileDefault}},
@@ -230,7 +312,7 @@ func TestBuildDump_PartialKeys(t *testing.T) {
 }
 
 func TestKindDumpRoundTrip(t *testing.T) {
-	for _, k := range []types.BrowserKind{types.Chromium, types.ChromiumYandex, types.ChromiumOpera} {
+	for _, k := range []types.BrowserKind{types.Chromium, types.ChromiumYandex, types.ChromiumOpera, types.Firefox, types.Safari} {
 		s, err := kindToDump(k)
 		if err != nil {
 			t.Fatalf("kindToDump(%d): %v", k, err)
@@ -240,8 +322,8 @@ func TestKindDumpRoundTrip(t *testing.T) {
 			t.Errorf("round trip %d -> %q -> %d (err %v)", k, s, got, err)
 		}
 	}
-	if _, err := kindToDump(types.Firefox); err == nil {
-		t.Error("kindToDump(Firefox) should error")
+	if _, err := kindToDump(types.BrowserKind(999)); err == nil {
+		t.Error("kindToDump(unknown) should error")
 	}
 	if _, err := kindFromDump("nope"); err == nil {
 		t.Error("kindFromDump(nope) should error")
@@ -265,6 +347,16 @@ func TestRetrieversFromKeys(t *testing.T) {
 	}
 }
 
+func TestRetrieversFromKeys_V12(t *testing.T) {
+	r := retrieversFromKeys(masterkey.MasterKeys{V12: []byte("k12")})
+	if r.V12 == nil {
+		t.Fatal("V12 retriever should be set from a non-empty key")
+	}
+	if got, _ := r.V12.RetrieveKey(masterkey.Hints{}); string(got) != "k12" {
+		t.Errorf("V12 key = %q, want k12", got)
+	}
+}
+
 // makeUserData writes a minimal Chromium profile tree: a Preferences marker plus History (a real
 // extraction source, so the profile resolves) under each named profile dir.
 func makeUserData(t *testing.T, root string, profiles ...string) {
@@ -321,6 +413,49 @@ func TestBuildFromDump_ForeignKindNoPlatformTable(t *testing.T) {
 	}
 }
 
+// makeFirefoxProfile writes a minimal Firefox profile: a key database plus a cookie store so it resolves.
+func makeFirefoxProfile(t *testing.T, root, profile string) {
+	t.Helper()
+	for _, f := range []string{"key4.db", "cookies.sqlite"} {
+		mkFile(t, root, profile, f)
+	}
+}
+
+func TestBuildFromDump_Firefox(t *testing.T) {
+	dataDir := t.TempDir()
+	makeUserData(t, filepath.Join(dataDir, "chrome"), testProfileDefault)
+	makeFirefoxProfile(t, filepath.Join(dataDir, "firefox"), "a.default")
+	makeFirefoxProfile(t, filepath.Join(dataDir, "firefox"), "b.default")
+	dump := masterkey.Dump{Vaults: []masterkey.Vault{
+		{Browser: "chrome", Kind: "chromium", Keys: masterkey.MasterKeys{V10: []byte("c")}},
+		{Browser: "firefox", Kind: "firefox", ProfileKeys: map[string][]byte{"a.default": []byte("key-a")}},
+	}}
+
+	browsers, err := BuildFromDump(dump, dataDir, "firefox")
+	if err != nil {
+		t.Fatalf("BuildFromDump: %v", err)
+	}
+	if len(browsers) != 1 {
+		t.Fatalf("got %d browsers, want 1", len(browsers))
+	}
+	pkm, ok := browsers[0].(ProfileKeyManager)
+	if !ok {
+		t.Fatalf("%T is not a ProfileKeyManager", browsers[0])
+	}
+	if pkm.Kind() != types.Firefox {
+		t.Errorf("Kind = %v, want Firefox", pkm.Kind())
+	}
+	// a.default has no logins to check against, so its dumped key is used as-is; b.default falls back to
+	// its copied key4.db, which this fixture leaves unreadable.
+	keys, err := pkm.ExportProfileKeys()
+	if err == nil {
+		t.Error("b.default: want an error from the placeholder key4.db")
+	}
+	if string(keys["a.default"]) != "key-a" {
+		t.Errorf("a.default key = %q, want the dumped key", keys["a.default"])
+	}
+}
+
 func TestBuildFromDump_RawSingleBrowser(t *testing.T) {
 	dataDir := t.TempDir()
 	makeUserData(t, dataDir, testProfileDefault)
@@ -0,0 +1,177 @@
+package browser
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/moond4rk/hackbrowserdata/log"
+	"github.com/moond4rk/hackbrowserdata/masterkey"
+	"github.com/moond4rk/hackbrowserdata/types"
+	"github.com/moond4rk/hackbrowserdata/utils/fileutil"
+)
+
+// keylessVaults lists the installations under dataDir as key-less vaults, for restores that recover keys
+// from the image itself rather than a dump. A Local State at the root is one browser's User Data (named
+// by filter); a dir of Firefox profiles or a copied ~/Library holding Safari data at the root is one
+// Firefox or Safari installation (named by filter, default "firefox" / "safari"). Otherwise every subdir
+// holding one of these is one; failing that, dataDir is searched as a forensic collection (see
+// findCollectionRoots).
+func keylessVaults(dataDir, filter string) (masterkey.Dump, error) {
+	dump := masterkey.NewDump()
+	if !dirExists(dataDir) {
+		return dump, fmt.Errorf("data dir %q does not exist", dataDir)
+	}
+
+	filter = strings.ToLower(filter)
+	named := filter != "" && filter != "all"
+	switch {
+	case fileutil.FileExists(filepath.Join(dataDir, "Local State")):
+		if !named {
+			return dump, fmt.Errorf("--data-dir %q is one browser's User Data; name the browser with -b <browser>", dataDir)
+		}
+		dump.Vaults = append(dump.Vaults, keylessVault(filter, kindForKey(filter)))
+		return dump, nil
+	case isFirefoxProfiles(dataDir), isSafariLibrary(dataDir):
+		kind := types.Firefox
+		if isSafariLibrary(dataDir) {
+			kind = types.Safari
+		}
+		key := filter
+		if !named {
+			key = kind.String()
+		}
+		dump.Vaults = append(dump.Vaults, keylessVault(key, kind))
+		return dump, nil
+	}
+
+	entries, err := os.ReadDir(dataDir)
+	if err != nil {
+		return dump, err
+	}
+	for _, e := range entries {
+		if !e.IsDir() {
+			continue
+		}
+		key, dir := strings.ToLower(e.Name()), filepath.Join(dataDir, e.Name())
+		switch {
+		case fileutil.FileExists(filepath.Join(dir, "Local State")):
+			dump.Vaults = append(dump.Vaults, keylessVault(key, kindForKey(key)))
+		case isFirefoxProfiles(dir):
+			dump.Vaults = append(dump.Vaults, keylessVault(key, types.Firefox))
+		case isSafariLibrary(dir):
+			dump.Vaults = append(dump.Vaults, keylessVault(key, types.Safari))
+		}
+	}
+	if len(dump.Vaults) == 0 {
+		for _, r := range findCollectionRoots(dataDir) {
+			dump.Vaults = append(dump.Vaults, keylessVault(r.key, r.kind))
+		}
+	}
+	if len(dump.Vaults) == 0 {
+		return dump, fmt.Errorf("no Local State, Firefox profile or Safari data under %q: point --data-dir at a User Data "+
+			"or Firefox Profiles dir, a copied ~/Library, a dir of them, or a KAPE / Velociraptor collection", dataDir)
+	}
+	if named && !hasVault(dump, filter) {
+		return dump, fmt.Errorf("no %s data under %q (have: %s)", filter, dataDir, vaultKeys(dump))
+	}
+	return dump, nil
+}
+
+// firefoxKeyDBs are the NSS key databases that mark a Firefox profile dir.
+var firefoxKeyDBs = []string{"key4.db", "key3.db"}
+
+// isFirefoxProfiles reports whether dir is a Firefox Profiles dir: some subdir holds a key database.
+func isFirefoxProfiles(dir string) bool {
+	return isFirefoxProfilesFS(os.DirFS(dir), ".")
+}
+
+// isSafariLibrary reports whether dir is a copied ~/Library holding Safari data: a Safari dir with its
+// History.db, or the Safari sandbox container.
+func isSafariLibrary(dir string) bool {
+	return isSafariLibraryFS(os.DirFS(dir), ".")
+}
+
+// BuildKeyless restores the installations under dataDir that need no key source — Firefox, whose
+// key4.db is copied with each profile, and Safari, whose non-password data is unencrypted. Chromium data
+// found alongside needs keys and is skipped with a warning.
+func BuildKeyless(dataDir, filter string) ([]Browser, error) {
+	dump, err := keylessVaults(dataDir, filter)
+	if err != nil {
+		return nil, err
+	}
+	var keyless []masterkey.Vault
+	for _, v := range dump.Vaults {
+		if v.Kind != types.Firefox.String() && v.Kind != types.Safari.String() {
+			log.Warnf("restore: %s needs keys (--keys, --dpapi-dir, --keychain-file, --keyring or --key), skipping", v.Browser)
+			continue
+		}
+		keyless = append(keyless, v)
+	}
+	if len(keyless) == 0 {
+		return nil, fmt.Errorf("no Firefox or Safari data under %q, and no key source given "+
+			"(--keys, --dpapi-dir, --keychain-file, --keyring or --key / --key-command)", dataDir)
+	}
+	dump.Vaults = keyless
+	return buildFromVaults(dump, dataDir, filter, nil, func(masterkey.Vault) masterkey.Retrievers {
+		return masterkey.Retrievers{}
+	})
+}
+
+func hasVault(dump masterkey.Dump, key string) bool {
+	for _, v := range dump.Vaults {
+		if v.Browser == key {
+			return true
+		}
+	}
+	return false
+}
+
+func keylessVault(key string, kind types.BrowserKind) masterkey.Vault {
+	name, _ := kindToDump(kind)
+	return masterkey.Vault{Browser: key, Kind: name}
+}
+
+// kindForKey maps a browser key to its engine kind for restores that carry no dump to say so. Only
+// Opera's and Yandex's forks differ from stock Chromium.
+func kindForKey(key string) types.BrowserKind {
+	switch {
+	case strings.HasPrefix(key, "opera"), key == "vought":
+		return types.ChromiumOpera
+	case key == "yandex":
+		return types.ChromiumYandex
+	default:
+		return types.Chromium
+	}
+}
+
+// macKeychainLabels is each key's macOS keychain account ("<label> Safe Storage"). The darwin platform
+// table reads its labels from here, so a copied login.keychain-db is matched off-platform the same way.
+var macKeychainLabels = map[string]string{
+	"chrome":      "Chrome",
+	"chrome-beta": "Chrome",
+	"edge":        "Microsoft Edge",
+	"chromium":    "Chromium",
+	"opera":       "Opera",
+	"opera-gx":    "Opera",
+	"vivaldi":     "Vivaldi",
+	"coccoc":      "CocCoc",
+	"brave":       "Brave",
+	"yandex":      "Yandex",
+	"arc":         "Arc",
+}
+
+// linuxKeyringLabels is each key's Linux secret-store label. The linux platform table reads its labels
+// from here, so keyring files copied off an image are matched off-platform the same way.
+var linuxKeyringLabels = map[string]string{
+	"chrome":           "Chrome Safe Storage",
+	"chrome-beta":      "Chrome Safe Storage",
+	"chrome-flatpak":   "Chrome Safe Storage",
+	"vivaldi":          "Chrome Safe Storage",
+	"chromium":         "Chromium Safe Storage",
+	"chromium-flatpak": "Chromium Safe Storage",
+	"edge":             "Chromium Safe Storage",
+	"opera":            "Chromium Safe Storage",
+	"brave":            "Brave Safe Storage",
+}
@@ -0,0 +1,54 @@
+package browser
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/moond4rk/hackbrowserdata/types"
+)
+
+func TestKeylessVaults_Firefox(t *testing.T) {
+	dataDir := t.TempDir()
+	makeUserData(t, filepath.Join(dataDir, "chrome"), testProfileDefault)
+	mkFile(t, dataDir, "chrome", "Local State")
+	makeFirefoxProfile(t, filepath.Join(dataDir, "firefox-esr"), "x.default-esr")
+
+	dump, err := keylessVaults(dataDir, "")
+	require.NoError(t, err)
+	require.Len(t, dump.Vaults, 2)
+	assert.Equal(t, "chrome", dump.Vaults[0].Browser)
+	assert.Equal(t, "chromium", dump.Vaults[0].Kind)
+	assert.Equal(t, "firefox-esr", dump.Vaults[1].Browser)
+	assert.Equal(t, "firefox", dump.Vaults[1].Kind, "the layout, not the key, says Firefox")
+
+	// A Profiles dir at the root is one Firefox installation, named by -b or "firefox".
+	root := filepath.Join(dataDir, "firefox-esr")
+	dump, err = keylessVaults(root, "")
+	require.NoError(t, err)
+	require.Len(t, dump.Vaults, 1)
+	assert.Equal(t, "firefox", dump.Vaults[0].Browser)
+	dump, err = keylessVaults(root, "librewolf")
+	require.NoError(t, err)
+	assert.Equal(t, "librewolf", dump.Vaults[0].Browser)
+}
+
+func TestBuildKeyless(t *testing.T) {
+	dataDir := t.TempDir()
+	makeUserData(t, filepath.Join(dataDir, "chrome"), testProfileDefault)
+	mkFile(t, dataDir, "chrome", "Local State")
+	makeFirefoxProfile(t, filepath.Join(dataDir, "firefox"), "a.default-release")
+
+	browsers, err := BuildKeyless(dataDir, "")
+	require.NoError(t, err)
+	require.Len(t, browsers, 1, "chrome needs keys and is skipped")
+	pkm, ok := browsers[0].(ProfileKeyManager)
+	require.True(t, ok)
+	assert.Equal(t, types.Firefox, pkm.Kind())
+	assert.Equal(t, filepath.Join(dataDir, "firefox"), browsers[0].UserDataDir())
+
+	_, err = BuildKeyless(filepath.Join(dataDir, "chrome"), "chrome")
+	assert.ErrorContains(t, err, "no Firefox or Safari data")
+}
@@ -0,0 +1,22 @@
+package browser
+
+import (
+	"github.com/moond4rk/hackbrowserdata/masterkey"
+)
+
+// BuildFromKeyring reconstructs Chromium engines from copied Linux data without a dump: v11 keys come
+// from the "<Browser> Safe Storage" secrets in GNOME Keyring or KWallet files copied off the same
+// image, unlocked with the user's login password, and v10 is Chromium's fixed "peanuts" key. dataDir
+// takes BuildFromDPAPI's layouts; the browser key picks the secret's label. Flatpak v12 keys live
+// behind the secret portal and still need the portal secret from the origin.
+func BuildFromKeyring(keyringFiles []string, password, dataDir, filter string) ([]Browser, error) {
+	dump, err := keylessVaults(dataDir, filter)
+	if err != nil {
+		return nil, err
+	}
+	v10 := &masterkey.PosixRetriever{}
+	v11 := &masterkey.KeyringFileRetriever{Paths: keyringFiles, Password: password}
+	return buildFromVaults(dump, dataDir, filter, linuxKeyringLabels, func(masterkey.Vault) masterkey.Retrievers {
+		return masterkey.Retrievers{V10: v10, V11: v11}
+	})
+}
@@ -0,0 +1,28 @@
+package browser
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBuildFromKeyring(t *testing.T) {
+	dataDir := t.TempDir()
+	makeUserData(t, dataDir, testProfileDefault)
+	require.NoError(t, os.WriteFile(filepath.Join(dataDir, "Local State"), []byte(`{}`), 0o600))
+
+	missing := filepath.Join(t.TempDir(), "login.keyring")
+	browsers, err := BuildFromKeyring([]string{missing}, "hunter2", dataDir, "chromium")
+	require.NoError(t, err)
+	require.Len(t, browsers, 1)
+
+	km, ok := browsers[0].(KeyManager)
+	require.True(t, ok)
+	keys, err := km.ExportKeys()
+	assert.ErrorContains(t, err, "no keyring file could be opened")
+	assert.Len(t, keys.V10, 16, "v10 is the fixed peanuts key, needing no keyring")
+	assert.Nil(t, keys.V11)
+}
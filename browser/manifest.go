@@ -0,0 +1,134 @@
+package browser
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/moond4rk/hackbrowserdata/masterkey"
+)
+
+// ManifestName is the archive-root entry describing every other entry.
+const ManifestName = "manifest.json"
+
+const ManifestVersion = "1"
+
+// ArchiveManifest records what an archive collected, from where and when, so a restore can show the
+// data was not altered in transit.
+type ArchiveManifest struct {
+	Version     string          `json:"version"`
+	CreatedAt   time.Time       `json:"created_at"`
+	Host        masterkey.Host  `json:"host"`
+	ToolVersion string          `json:"tool_version,omitempty"`
+	Files       []ManifestEntry `json:"files"`
+	Skipped     []ManifestSkip  `json:"skipped,omitempty"`
+}
+
+// ManifestEntry is one archived file. Path is the forward-slash archive entry (<browser-key>/<layout>),
+// Source the absolute path it was read from; ModTime is the source's, Size and SHA256 those of the
+// bytes streamed into the archive. LockedCopy marks files read through the Windows locked-file fallback.
+type ManifestEntry struct {
+	Path       string    `json:"path"`
+	Source     string    `json:"source"`
+	Size       int64     `json:"size"`
+	ModTime    time.Time `json:"mtime"`
+	SHA256     string    `json:"sha256"`
+	LockedCopy bool      `json:"locked_copy,omitempty"`
+}
+
+// ManifestSkip is an entry the archive holds only part of: the source failed to read mid-stream or grew
+// past a size cap while it was copied, after its zip entry was already under way. A restore drops it.
+type ManifestSkip struct {
+	Path   string `json:"path"`
+	Source string `json:"source"`
+	Reason string `json:"reason"`
+}
+
+func newArchiveManifest(toolVersion string) ArchiveManifest {
+	return ArchiveManifest{
+		Version:     ManifestVersion,
+		CreatedAt:   time.Now().UTC(),
+		Host:        masterkey.CurrentHost(),
+		ToolVersion: toolVersion,
+		Files:       []ManifestEntry{},
+	}
+}
+
+func (m ArchiveManifest) encode() ([]byte, error) {
+	sort.Slice(m.Files, func(i, j int) bool { return m.Files[i].Path < m.Files[j].Path })
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("encode manifest: %w", err)
+	}
+	return append(data, '\n'), nil
+}
+
+// VerifyArchiveManifest checks an extracted archive at dir against its manifest.json and returns the
+// manifest with one line per problem: a listed file that is missing or whose size or SHA-256 differs,
+// or a file the manifest lists neither as archived nor as skipped. The error wraps fs.ErrNotExist when dir has no manifest.
+func VerifyArchiveManifest(dir string) (ArchiveManifest, []string, error) {
+	var m ArchiveManifest
+	data, err := os.ReadFile(filepath.Join(dir, ManifestName))
+	if err != nil {
+		return m, nil, err
+	}
+	if err := json.Unmarshal(data, &m); err != nil {
+		return m, nil, fmt.Errorf("parse %s: %w", ManifestName, err)
+	}
+	if m.Version != ManifestVersion {
+		return m, nil, fmt.Errorf("unsupported manifest version %q (this build reads %q)", m.Version, ManifestVersion)
+	}
+
+	var problems []string
+	listed := make(map[string]bool, len(m.Files))
+	for _, e := range m.Files {
+		listed[e.Path] = true
+		size, sum, err := hashFile(filepath.Join(dir, filepath.FromSlash(e.Path)))
+		switch {
+		case err != nil:
+			problems = append(problems, fmt.Sprintf("%s: %v", e.Path, err))
+		case size != e.Size:
+			problems = append(problems, fmt.Sprintf("%s: size %d, manifest says %d", e.Path, size, e.Size))
+		case sum != e.SHA256:
+			problems = append(problems, fmt.Sprintf("%s: sha256 %s, manifest says %s", e.Path, sum, e.SHA256))
+		}
+	}
+	for _, e := range m.Skipped {
+		listed[e.Path] = true
+	}
+	err = filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		rel, err := filepath.Rel(dir, p)
+		if err != nil {
+			return err
+		}
+		if rel = filepath.ToSlash(rel); rel != ManifestName && !listed[rel] {
+			problems = append(problems, rel+": not in manifest")
+		}
+		return nil
+	})
+	return m, problems, err
+}
+
+func hashFile(path string) (int64, string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, "", err
+	}
+	defer f.Close()
+	h := sha256.New()
+	n, err := io.Copy(h, f)
+	if err != nil {
+		return 0, "", fmt.Errorf("hash %s: %w", path, err)
+	}
+	return n, hex.EncodeToString(h.Sum(nil)), nil
+}
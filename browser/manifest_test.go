@@ -0,0 +1,64 @@
+package browser
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/moond4rk/hackbrowserdata/browser/chromium"
+	"github.com/moond4rk/hackbrowserdata/types"
+	"github.com/moond4rk/hackbrowserdata/utils/fileutil"
+)
+
+func TestArchiveManifest(t *testing.T) {
+	origin := t.TempDir()
+	mkFile(t, origin, "Default", "Preferences")
+	mkFile(t, origin, "Local State")
+	history := filepath.Join(origin, "Default", "History")
+	require.NoError(t, os.WriteFile(history, []byte("hist"), 0o600))
+	require.NoError(t, os.WriteFile(history+"-wal", []byte("wal"), 0o600))
+
+	b, err := chromium.NewBrowser(types.BrowserConfig{Key: "chrome", Name: "chrome", Kind: types.Chromium, UserDataDir: origin})
+	require.NoError(t, err)
+	zipPath := filepath.Join(t.TempDir(), "data.zip")
+	_, err = WriteArchive([]Browser{b}, []types.Category{types.History}, zipPath, ArchiveOptions{ToolVersion: "v1.2.3"})
+	require.NoError(t, err)
+
+	dir := t.TempDir()
+	require.NoError(t, fileutil.Unzip(zipPath, dir))
+	m, problems, err := VerifyArchiveManifest(dir)
+	require.NoError(t, err)
+	assert.Empty(t, problems)
+	assert.Equal(t, "v1.2.3", m.ToolVersion)
+	assert.NotEmpty(t, m.Host.OS)
+
+	byPath := make(map[string]ManifestEntry)
+	for _, e := range m.Files {
+		byPath[e.Path] = e
+	}
+	sum := sha256.Sum256([]byte("hist"))
+	hist := byPath["chrome/Default/History"]
+	assert.Equal(t, history, hist.Source)
+	assert.Equal(t, int64(4), hist.Size)
+	assert.Equal(t, hex.EncodeToString(sum[:]), hist.SHA256)
+	assert.False(t, hist.ModTime.IsZero())
+	assert.False(t, hist.LockedCopy)
+	assert.Equal(t, history+"-wal", byPath["chrome/Default/History-wal"].Source, "sidecars are listed too")
+	assert.Contains(t, byPath, "chrome/Local State")
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "chrome", "Default", "History"), []byte("HIST"), 0o600))
+	require.NoError(t, os.Remove(filepath.Join(dir, "chrome", "Local State")))
+	mkFile(t, dir, "chrome", "Default", "Cookies")
+	_, problems, err = VerifyArchiveManifest(dir)
+	require.NoError(t, err)
+	assert.Len(t, problems, 3)
+	assert.Contains(t, problems, "chrome/Default/Cookies: not in manifest")
+
+	_, _, err = VerifyArchiveManifest(t.TempDir())
+	assert.ErrorIs(t, err, os.ErrNotExist)
+}
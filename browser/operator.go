@@ -0,0 +1,61 @@
+package browser
+
+import (
+	"github.com/moond4rk/hackbrowserdata/log"
+	"github.com/moond4rk/hackbrowserdata/masterkey"
+)
+
+// OperatorKeys are master keys the analyst supplies instead of the platform recovering them: per-tier
+// keys for named browsers (--key) and a program that prints them (--key-command). They sit ahead of
+// whatever retrievers a browser already has, so every subcommand that decrypts can use them.
+type OperatorKeys struct {
+	Keys    masterkey.ManualKeys
+	Command string
+}
+
+func (o OperatorKeys) IsZero() bool {
+	return len(o.Keys) == 0 && o.Command == ""
+}
+
+// retrievers returns the operator's sources for one browser key: manual keys, then the command.
+func (o OperatorKeys) retrievers(key string) masterkey.Retrievers {
+	r := retrieversFromKeys(o.Keys.For(key))
+	if o.Command != "" {
+		r = masterkey.KeyCommandRetrievers(o.Command, key).Prefer(r)
+	}
+	return r
+}
+
+// Apply puts the operator's keys ahead of each Chromium installation's own retrievers. Keys named for
+// a browser that is not among browsers are reported, since they would otherwise be silently unused.
+func (o OperatorKeys) Apply(browsers []Browser) {
+	if o.IsZero() {
+		return
+	}
+	used := make(map[string]bool)
+	for _, b := range browsers {
+		km, ok := b.(KeyManager)
+		if !ok {
+			continue
+		}
+		used[km.BrowserKey()] = true
+		km.SetRetrievers(km.Retrievers().Prefer(o.retrievers(km.BrowserKey())))
+	}
+	for key := range o.Keys {
+		if key != "" && !used[key] {
+			log.Warnf("--key for %s: no such Chromium browser in this run", key)
+		}
+	}
+}
+
+// BuildFromOperatorKeys restores copied data with operator-supplied keys alone — no dump, no key store.
+// dataDir is laid out as for the key-less restores: one User Data dir named by filter, or a dir of them.
+func BuildFromOperatorKeys(o OperatorKeys, dataDir, filter string) ([]Browser, error) {
+	dump, err := keylessVaults(dataDir, filter)
+	if err != nil {
+		return nil, err
+	}
+	return buildFromVaults(dump, dataDir, filter, nil, func(v masterkey.Vault) masterkey.Retrievers {
+		return o.retrievers(v.Browser)
+	})
+}
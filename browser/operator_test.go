@@ -0,0 +1,59 @@
+package browser
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/moond4rk/hackbrowserdata/masterkey"
+)
+
+func TestBuildFromOperatorKeys(t *testing.T) {
+	dataDir := t.TempDir()
+	for _, key := range []string{"chrome", "edge"} {
+		makeUserData(t, filepath.Join(dataDir, key), testProfileDefault)
+		require.NoError(t, os.WriteFile(filepath.Join(dataDir, key, "Local State"), []byte(`{}`), 0o600))
+	}
+	keys := make(masterkey.ManualKeys)
+	require.NoError(t, keys.Set("chrome:v10=00112233445566778899aabbccddeeff"))
+	require.NoError(t, keys.Set("v20=000102030405060708090a0b0c0d0e0f000102030405060708090a0b0c0d0e0f"))
+
+	browsers, err := BuildFromOperatorKeys(OperatorKeys{Keys: keys}, dataDir, "")
+	require.NoError(t, err)
+	require.Len(t, browsers, 2)
+	for _, b := range browsers {
+		km, ok := b.(KeyManager)
+		require.True(t, ok)
+		mk, err := km.ExportKeys()
+		require.NoError(t, err)
+		assert.Len(t, mk.V20, 32, "%s gets the all-browser v20 key", km.BrowserKey())
+		if km.BrowserKey() == "chrome" {
+			assert.Len(t, mk.V10, 16)
+		} else {
+			assert.Nil(t, mk.V10)
+		}
+	}
+}
+
+func TestOperatorKeys_Apply(t *testing.T) {
+	dataDir := t.TempDir()
+	makeUserData(t, dataDir, testProfileDefault)
+	browsers, err := BuildFromDump(masterkey.Dump{Vaults: []masterkey.Vault{{
+		Browser: "chrome", Kind: "chromium", Keys: masterkey.MasterKeys{V10: []byte("dumped-key-16byt"), V11: []byte("dumped-v11-key16")},
+	}}}, dataDir, "chrome")
+	require.NoError(t, err)
+	require.Len(t, browsers, 1)
+
+	keys := make(masterkey.ManualKeys)
+	require.NoError(t, keys.Set("chrome:v10=00112233445566778899aabbccddeeff"))
+	OperatorKeys{Keys: keys}.Apply(browsers)
+
+	mk, err := browsers[0].(KeyManager).ExportKeys()
+	require.NoError(t, err)
+	assert.Equal(t, []byte{0x00, 0x11, 0x22, 0x33, 0x44, 0x55, 0x66, 0x77, 0x88, 0x99, 0xaa, 0xbb, 0xcc, 0xdd, 0xee, 0xff}, mk.V10,
+		"the operator's key is tried before the dump's")
+	assert.Equal(t, []byte("dumped-v11-key16"), mk.V11, "tiers without an operator key keep their retriever")
+}
@@ -0,0 +1,60 @@
+package safari
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/moond4rk/hackbrowserdata/types"
+	"github.com/moond4rk/hackbrowserdata/utils/fileutil"
+)
+
+// ArchiveSources lists the files an archive must capture for the given categories, laid out under
+// ~/Library (the parent of the Safari dir) because Safari spreads one profile across Safari/,
+// Cookies/ and the Containers sandbox. SafariTabs.db is always included so a restore rediscovers the
+// named profiles. Passwords live in the macOS Keychain and are not archived. LayoutRel is
+// forward-slash, relative to the root.
+func (b *Browser) ArchiveSources(categories []types.Category) []types.ArchiveSource {
+	root := filepath.Dir(b.cfg.UserDataDir)
+	var out []types.ArchiveSource
+	add := func(abs string, isDir bool) {
+		rel, err := filepath.Rel(root, abs)
+		if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return
+		}
+		out = append(out, types.ArchiveSource{AbsPath: abs, LayoutRel: filepath.ToSlash(rel), IsDir: isDir})
+	}
+
+	for _, p := range b.profiles {
+		if p.ctx.isDefault() {
+			if tabs := filepath.Join(p.ctx.container, filepath.FromSlash(safariTabsDBRelPath)); fileutil.FileExists(tabs) {
+				add(tabs, false)
+			}
+		}
+		for _, cat := range categories {
+			if cat == types.Extension && p.ctx.isDefault() {
+				for _, sub := range []string{safariAppExtensionsSubdir, safariWebExtensionsSubdir} {
+					plist := filepath.Join(p.ctx.container, safariExtensionsSubdir, sub, safariExtensionsPlistFile)
+					if fileutil.FileExists(plist) {
+						add(plist, false)
+					}
+				}
+				continue
+			}
+			if rp, ok := p.sourcePaths[cat]; ok {
+				add(rp.absPath, rp.isDir)
+			}
+		}
+	}
+	return out
+}
+
+// RestoredUserDataDir maps a restored archive root (a copied ~/Library) to the Safari dir NewBrowser
+// expects; a root that already is the Safari dir is returned as-is.
+func RestoredUserDataDir(root string) string {
+	dir := filepath.Join(root, "Safari")
+	if info, err := os.Stat(dir); err == nil && info.IsDir() {
+		return dir
+	}
+	return root
+}
@@ -0,0 +1,47 @@
+package safari
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/moond4rk/hackbrowserdata/types"
+)
+
+func TestArchiveSources(t *testing.T) {
+	library := t.TempDir()
+	container := filepath.Join(library, "Containers", "com.apple.Safari", "Data", "Library")
+	mkFile(t, library, "Safari", "History.db")
+	mkFile(t, library, "Safari", "Bookmarks.plist")
+	mkFile(t, library, "Cookies", "Cookies.binarycookies")
+	mkFile(t, container, "Safari", "SafariTabs.db")
+	mkFile(t, container, "Safari", "WebExtensions", "Extensions.plist")
+	mkFile(t, container, "WebKit", "WebsiteData", "Default", "origin")
+
+	b, err := NewBrowser(types.BrowserConfig{Key: "safari", Name: "Safari", Kind: types.Safari, UserDataDir: filepath.Join(library, "Safari")})
+	require.NoError(t, err)
+	require.NotNil(t, b)
+
+	got := make(map[string]bool)
+	for _, s := range b.ArchiveSources(types.AllCategories) {
+		assert.Equal(t, filepath.Join(library, filepath.FromSlash(s.LayoutRel)), s.AbsPath)
+		got[s.LayoutRel] = s.IsDir
+	}
+	assert.Equal(t, map[string]bool{
+		"Safari/History.db":             false,
+		"Safari/Bookmarks.plist":        false,
+		"Cookies/Cookies.binarycookies": false,
+		"Containers/com.apple.Safari/Data/Library/Safari/SafariTabs.db":                  false,
+		"Containers/com.apple.Safari/Data/Library/Safari/WebExtensions/Extensions.plist": false,
+		"Containers/com.apple.Safari/Data/Library/WebKit/WebsiteData/Default":            true,
+	}, got)
+}
+
+func TestRestoredUserDataDir(t *testing.T) {
+	library := t.TempDir()
+	mkFile(t, library, "Safari", "History.db")
+	assert.Equal(t, filepath.Join(library, "Safari"), RestoredUserDataDir(library))
+	assert.Equal(t, filepath.Join(library, "Safari"), RestoredUserDataDir(filepath.Join(library, "Safari")))
+}
@@ -11,8 +11,8 @@ import (
 	"github.com/moond4rk/hackbrowserdata/types"
 )
 
-func extractPasswords(keychainPassword string) ([]types.LoginEntry, error) {
-	passwords, err := getInternetPasswords(keychainPassword)
+func extractPasswords(kc keychain) ([]types.LoginEntry, error) {
+	passwords, err := getInternetPasswords(kc)
 	if err != nil {
 		return nil, err
 	}
@@ -37,32 +37,32 @@ func extractPasswords(keychainPassword string) ([]types.LoginEntry, error) {
 	return logins, nil
 }
 
-func countPasswords(keychainPassword string) (int, error) {
-	passwords, err := extractPasswords(keychainPassword)
+func countPasswords(kc keychain) (int, error) {
+	passwords, err := extractPasswords(kc)
 	if err != nil {
 		return 0, err
 	}
 	return len(passwords), nil
 }
 
-// getInternetPasswords reads InternetPassword records straight from the macOS login keychain (Safari owns its own key
-// path, separate from the masterkey package). TryUnlock always runs — even without a password — so a locked keychain
-// still yields metadata-only records (URL, account, blank password) instead of failing with ErrLocked.
-func getInternetPasswords(keychainPassword string) ([]keychainbreaker.InternetPassword, error) {
-	kc, err := keychainbreaker.Open()
+// getInternetPasswords reads InternetPassword records from the login keychain (Safari owns its own key path, separate
+// from the masterkey package). TryUnlock always runs — even without a password — so a locked keychain still yields
+// metadata-only records (URL, account, blank password) instead of failing with ErrLocked.
+func getInternetPasswords(kc keychain) ([]keychainbreaker.InternetPassword, error) {
+	k, err := kc.open()
 	if err != nil {
 		return nil, fmt.Errorf("open keychain: %w", err)
 	}
 
 	var unlockOpts []keychainbreaker.UnlockOption
-	if keychainPassword != "" {
-		unlockOpts = append(unlockOpts, keychainbreaker.WithPassword(keychainPassword))
+	if kc.password != "" {
+		unlockOpts = append(unlockOpts, keychainbreaker.WithPassword(kc.password))
 	}
-	if err := kc.TryUnlock(unlockOpts...); err != nil {
+	if err := k.TryUnlock(unlockOpts...); err != nil {
 		log.Debugf("keychain unlock detail: %v", err)
 	}
 
-	passwords, err := kc.InternetPasswords()
+	passwords, err := k.InternetPasswords()
 	if err != nil {
 		return nil, fmt.Errorf("extract internet passwords: %w", err)
 	}
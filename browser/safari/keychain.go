@@ -0,0 +1,48 @@
+package safari
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/moond4rk/keychainbreaker"
+
+	"github.com/moond4rk/hackbrowserdata/utils/fileutil"
+)
+
+// loginKeychainRelPath is the login keychain's place under ~/Library.
+const loginKeychainRelPath = "Keychains/login.keychain-db"
+
+// errNoLiveKeychain means passwords were asked for off macOS without a copied keychain to read.
+var errNoLiveKeychain = errors.New("no macOS keychain on this system; copy ~/Library/Keychains/login.keychain-db with the data")
+
+// keychain is where Safari's passwords are read from: a login.keychain-db file, or the live keychain
+// when file is empty (macOS only), unlocked with password.
+type keychain struct {
+	file     string
+	password string
+}
+
+// loginKeychain picks the keychain for this installation: the file set by SetKeychainFile, else the
+// login.keychain-db beside the Safari dir when the ~/Library tree carries one, else the live keychain.
+func (b *Browser) loginKeychain() keychain {
+	kc := keychain{file: b.keychainFile, password: b.keychainPassword}
+	if kc.file == "" {
+		if path := filepath.Join(filepath.Dir(b.cfg.UserDataDir), filepath.FromSlash(loginKeychainRelPath)); fileutil.FileExists(path) {
+			kc.file = path
+		}
+	}
+	return kc
+}
+
+func (kc keychain) open() (*keychainbreaker.Keychain, error) {
+	if kc.file == "" {
+		return openLiveKeychain()
+	}
+	data, err := os.ReadFile(kc.file)
+	if err != nil {
+		return nil, fmt.Errorf("read %s: %w", kc.file, err)
+	}
+	return keychainbreaker.Open(keychainbreaker.WithBytes(data))
+}
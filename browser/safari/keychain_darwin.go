@@ -0,0 +1,10 @@
+//go:build darwin
+
+package safari
+
+import "github.com/moond4rk/keychainbreaker"
+
+// openLiveKeychain opens the current user's login keychain.
+func openLiveKeychain() (*keychainbreaker.Keychain, error) {
+	return keychainbreaker.Open()
+}
@@ -0,0 +1,10 @@
+//go:build !darwin
+
+package safari
+
+import "github.com/moond4rk/keychainbreaker"
+
+// openLiveKeychain fails off macOS: there is no live keychain, only copied ones.
+func openLiveKeychain() (*keychainbreaker.Keychain, error) {
+	return nil, errNoLiveKeychain
+}
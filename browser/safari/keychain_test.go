@@ -0,0 +1,37 @@
+package safari
+
+import (
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/moond4rk/hackbrowserdata/types"
+)
+
+func TestLoginKeychain(t *testing.T) {
+	library := t.TempDir()
+	safariDir := filepath.Join(library, "Safari")
+	mkFile(t, safariDir, "History.db")
+
+	b := &Browser{cfg: types.BrowserConfig{UserDataDir: safariDir}, keychainPassword: "pw"}
+	assert.Equal(t, keychain{password: "pw"}, b.loginKeychain(), "no copied keychain: use the live one")
+
+	mkFile(t, library, "Keychains", "login.keychain-db")
+	assert.Equal(t, keychain{file: filepath.Join(library, "Keychains", "login.keychain-db"), password: "pw"}, b.loginKeychain())
+
+	b.SetKeychainFile("/evidence/login.keychain-db")
+	assert.Equal(t, "/evidence/login.keychain-db", b.loginKeychain().file)
+}
+
+func TestKeychainOpen(t *testing.T) {
+	_, err := keychain{file: filepath.Join(t.TempDir(), "missing.keychain-db")}.open()
+	require.Error(t, err)
+
+	if runtime.GOOS != "darwin" {
+		_, err = keychain{}.open()
+		assert.ErrorIs(t, err, errNoLiveKeychain)
+	}
+}
@@ -26,7 +26,7 @@ func (p *profile) dir() string {
 	return filepath.Join(p.ctx.container, "Safari", "Profiles", p.ctx.uuidUpper)
 }
 
-func (p *profile) extract(categories []types.Category, keychainPassword string) *types.BrowserData {
+func (p *profile) extract(categories []types.Category, kc keychain) *types.BrowserData {
 	session, err := filemanager.NewSession()
 	if err != nil {
 		log.Debugf("new session for %s: %v", p.label(), err)
@@ -41,7 +41,7 @@ func (p *profile) extract(categories []types.Category, keychainPassword string)
 		// Keychain is user-scope, not per-profile — attribute only to default to avoid duplicates.
 		if cat == types.Password {
 			if p.ctx.isDefault() {
-				p.extractCategory(data, cat, "", keychainPassword)
+				p.extractCategory(data, cat, "", kc)
 			}
 			continue
 		}
@@ -49,7 +49,7 @@ func (p *profile) extract(categories []types.Category, keychainPassword string)
 		// and are read in-place; attribute to default only until per-profile layouts are verified.
 		if cat == types.Extension {
 			if p.ctx.isDefault() {
-				p.extractCategory(data, cat, "", keychainPassword)
+				p.extractCategory(data, cat, "", kc)
 			}
 			continue
 		}
@@ -57,12 +57,12 @@ func (p *profile) extract(categories []types.Category, keychainPassword string)
 		if !ok {
 			continue
 		}
-		p.extractCategory(data, cat, path, keychainPassword)
+		p.extractCategory(data, cat, path, kc)
 	}
 	return data
 }
 
-func (p *profile) count(categories []types.Category, keychainPassword string) map[types.Category]int {
+func (p *profile) count(categories []types.Category, kc keychain) map[types.Category]int {
 	session, err := filemanager.NewSession()
 	if err != nil {
 		log.Debugf("new session for %s: %v", p.label(), err)
@@ -76,13 +76,13 @@ func (p *profile) count(categories []types.Category, keychainPassword string) ma
 	for _, cat := range categories {
 		if cat == types.Password {
 			if p.ctx.isDefault() {
-				counts[cat] = p.countCategory(cat, "", keychainPassword)
+				counts[cat] = p.countCategory(cat, "", kc)
 			}
 			continue
 		}
 		if cat == types.Extension {
 			if p.ctx.isDefault() {
-				counts[cat] = p.countCategory(cat, "", keychainPassword)
+				counts[cat] = p.countCategory(cat, "", kc)
 			}
 			continue
 		}
@@ -90,7 +90,7 @@ func (p *profile) count(categories []types.Category, keychainPassword string) ma
 		if !ok {
 			continue
 		}
-		counts[cat] = p.countCategory(cat, path, keychainPassword)
+		counts[cat] = p.countCategory(cat, path, kc)
 	}
 	return counts
 }
@@ -112,11 +112,11 @@ func (p *profile) acquireFiles(session *filemanager.Session, categories []types.
 	return tempPaths
 }
 
-func (p *profile) extractCategory(data *types.BrowserData, cat types.Category, path, keychainPassword string) {
+func (p *profile) extractCategory(data *types.BrowserData, cat types.Category, path string, kc keychain) {
 	var err error
 	switch cat {
 	case types.Password:
-		data.Passwords, err = extractPasswords(keychainPassword)
+		data.Passwords, err = extractPasswords(kc)
 	case types.History:
 		data.Histories, err = extractHistories(path)
 	case types.Cookie:
@@ -137,12 +137,12 @@ func (p *profile) extractCategory(data *types.BrowserData, cat types.Category, p
 	}
 }
 
-func (p *profile) countCategory(cat types.Category, path, keychainPassword string) int {
+func (p *profile) countCategory(cat types.Category, path string, kc keychain) int {
 	var count int
 	var err error
 	switch cat {
 	case types.Password:
-		count, err = countPasswords(keychainPassword)
+		count, err = countPasswords(kc)
 	case types.History:
 		count, err = countHistories(path)
 	case types.Cookie:
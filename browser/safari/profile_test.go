@@ -16,7 +16,7 @@ func TestCountCategory(t *testing.T) {
 			insertHistoryItem(1, "https://example.com", "example.com", 1),
 		)
 		p := &profile{}
-		assert.Equal(t, 1, p.countCategory(types.History, path, ""))
+		assert.Equal(t, 1, p.countCategory(types.History, path, keychain{}))
 	})
 
 	t.Run("Cookie", func(t *testing.T) {
@@ -25,7 +25,7 @@ func TestCountCategory(t *testing.T) {
 			{domain: ".go.dev", name: "b", path: "/", value: "2", expires: 2000000000.0, creation: 700000000.0},
 		})
 		p := &profile{}
-		assert.Equal(t, 2, p.countCategory(types.Cookie, path, ""))
+		assert.Equal(t, 2, p.countCategory(types.Cookie, path, keychain{}))
 	})
 
 	t.Run("Bookmark", func(t *testing.T) {
@@ -37,7 +37,7 @@ func TestCountCategory(t *testing.T) {
 			},
 		})
 		p := &profile{}
-		assert.Equal(t, 2, p.countCategory(types.Bookmark, path, ""))
+		assert.Equal(t, 2, p.countCategory(types.Bookmark, path, keychain{}))
 	})
 
 	t.Run("Download", func(t *testing.T) {
@@ -47,7 +47,7 @@ func TestCountCategory(t *testing.T) {
 			},
 		})
 		p := &profile{}
-		assert.Equal(t, 1, p.countCategory(types.Download, path, ""))
+		assert.Equal(t, 1, p.countCategory(types.Download, path, keychain{}))
 	})
 
 	t.Run("LocalStorage", func(t *testing.T) {
@@ -56,13 +56,13 @@ func TestCountCategory(t *testing.T) {
 			"https://go.dev":      {{Key: "theme", Value: "dark"}},
 		})
 		p := &profile{}
-		assert.Equal(t, 3, p.countCategory(types.LocalStorage, dir, ""))
+		assert.Equal(t, 3, p.countCategory(types.LocalStorage, dir, keychain{}))
 	})
 
 	t.Run("UnsupportedCategory", func(t *testing.T) {
 		p := &profile{}
-		assert.Equal(t, 0, p.countCategory(types.CreditCard, "unused", ""))
-		assert.Equal(t, 0, p.countCategory(types.SessionStorage, "unused", ""))
+		assert.Equal(t, 0, p.countCategory(types.CreditCard, "unused", keychain{}))
+		assert.Equal(t, 0, p.countCategory(types.SessionStorage, "unused", keychain{}))
 	})
 }
 
@@ -77,7 +77,7 @@ func TestExtractCategory(t *testing.T) {
 		)
 		p := &profile{}
 		data := &types.BrowserData{}
-		p.extractCategory(data, types.History, path, "")
+		p.extractCategory(data, types.History, path, keychain{})
 
 		require.Len(t, data.Histories, 2)
 		// Sorted by visit count descending
@@ -94,7 +94,7 @@ func TestExtractCategory(t *testing.T) {
 		})
 		p := &profile{}
 		data := &types.BrowserData{}
-		p.extractCategory(data, types.Cookie, path, "")
+		p.extractCategory(data, types.Cookie, path, keychain{})
 
 		require.Len(t, data.Cookies, 1)
 		assert.Equal(t, ".example.com", data.Cookies[0].Host)
@@ -112,7 +112,7 @@ func TestExtractCategory(t *testing.T) {
 		})
 		p := &profile{}
 		data := &types.BrowserData{}
-		p.extractCategory(data, types.Bookmark, path, "")
+		p.extractCategory(data, types.Bookmark, path, keychain{})
 
 		require.Len(t, data.Bookmarks, 1)
 		assert.Equal(t, "GitHub", data.Bookmarks[0].Name)
@@ -127,7 +127,7 @@ func TestExtractCategory(t *testing.T) {
 		})
 		p := &profile{}
 		data := &types.BrowserData{}
-		p.extractCategory(data, types.Download, path, "")
+		p.extractCategory(data, types.Download, path, keychain{})
 
 		require.Len(t, data.Downloads, 1)
 		assert.Equal(t, "https://example.com/file.zip", data.Downloads[0].URL)
@@ -140,7 +140,7 @@ func TestExtractCategory(t *testing.T) {
 		})
 		p := &profile{}
 		data := &types.BrowserData{}
-		p.extractCategory(data, types.LocalStorage, dir, "")
+		p.extractCategory(data, types.LocalStorage, dir, keychain{})
 
 		require.Len(t, data.LocalStorage, 1)
 		assert.Equal(t, "https://github.com", data.LocalStorage[0].URL)
@@ -151,7 +151,7 @@ func TestExtractCategory(t *testing.T) {
 	t.Run("UnsupportedCategory", func(t *testing.T) {
 		p := &profile{}
 		data := &types.BrowserData{}
-		p.extractCategory(data, types.CreditCard, "unused", "")
+		p.extractCategory(data, types.CreditCard, "unused", keychain{})
 		assert.Empty(t, data.CreditCards)
 	})
 }
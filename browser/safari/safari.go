@@ -8,17 +8,24 @@ import (
 )
 
 // Browser is one Safari installation, holding the default profile and any named
-// profiles. Passwords come from the shared macOS Keychain; the login password is
-// set on the installation and threaded to each profile at extract time.
+// profiles. Passwords come from the shared macOS login keychain; the login password
+// (and, off the live system, the keychain file) is set on the installation and
+// threaded to each profile at extract time. Everything else is plain files, so a
+// copied ~/Library tree extracts on any OS.
 type Browser struct {
 	cfg              types.BrowserConfig
 	keychainPassword string
+	keychainFile     string
 	profiles         []*profile
 }
 
 // SetKeychainPassword sets the macOS login password used to unlock the Keychain.
 func (b *Browser) SetKeychainPassword(password string) { b.keychainPassword = password }
 
+// SetKeychainFile points password extraction at a copied login.keychain-db instead of the one beside
+// the Safari dir or the live keychain.
+func (b *Browser) SetKeychainFile(path string) { b.keychainFile = path }
+
 // NewBrowser returns the Safari installation with one profile per Safari profile
 // that has resolvable data, or nil if none. Named profiles are enumerated from
 // SafariTabs.db.
@@ -41,8 +48,10 @@ func NewBrowser(cfg types.BrowserConfig) (*Browser, error) {
 	return &Browser{cfg: cfg, profiles: profiles}, nil
 }
 
-func (b *Browser) BrowserName() string { return b.cfg.Name }
-func (b *Browser) UserDataDir() string { return b.cfg.UserDataDir }
+func (b *Browser) BrowserName() string     { return b.cfg.Name }
+func (b *Browser) BrowserKey() string      { return b.cfg.Key }
+func (b *Browser) UserDataDir() string     { return b.cfg.UserDataDir }
+func (b *Browser) Kind() types.BrowserKind { return b.cfg.Kind }
 
 // Profiles returns the identity of every Safari profile in this installation.
 func (b *Browser) Profiles() []types.Profile {
@@ -53,13 +62,13 @@ func (b *Browser) Profiles() []types.Profile {
 	return out
 }
 
-// Extract extracts every profile, threading the installation's keychain password.
+// Extract extracts every profile, threading the installation's login keychain.
 func (b *Browser) Extract(categories []types.Category) ([]types.ExtractResult, error) {
 	results := make([]types.ExtractResult, 0, len(b.profiles))
 	for _, p := range b.profiles {
 		results = append(results, types.ExtractResult{
 			Profile: types.Profile{Name: p.ctx.name, Dir: p.dir()},
-			Data:    p.extract(categories, b.keychainPassword),
+			Data:    p.extract(categories, b.loginKeychain()),
 		})
 	}
 	return results, nil
@@ -71,7 +80,7 @@ func (b *Browser) CountEntries(categories []types.Category) ([]types.CountResult
 	for _, p := range b.profiles {
 		results = append(results, types.CountResult{
 			Profile: types.Profile{Name: p.ctx.name, Dir: p.dir()},
-			Counts:  p.count(categories, b.keychainPassword),
+			Counts:  p.count(categories, b.loginKeychain()),
 		})
 	}
 	return results, nil
@@ -1,6 +1,11 @@
 package main
 
 import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
 	"github.com/spf13/cobra"
 
 	"github.com/moond4rk/hackbrowserdata/browser"
@@ -12,14 +17,25 @@ func archiveCmd() *cobra.Command {
 		browserName string
 		category    string
 		outputPath  string
+		maxFileSize string
+		maxSize     string
 	)
 
 	cmd := &cobra.Command{
 		Use:   "archive",
 		Short: "Pack decryption-relevant profile files into a zip for cross-host restore",
 		Example: `  hack-browser-data archive
-  hack-browser-data archive -b chrome -c cookie -o chrome-cookies.zip`,
+  hack-browser-data archive -b chrome -c cookie -o chrome-cookies.zip
+  hack-browser-data archive --max-file-size 256M --max-size 2G`,
 		RunE: func(cmd *cobra.Command, args []string) error {
+			opts := browser.ArchiveOptions{}
+			var err error
+			if opts.MaxFileSize, err = parseByteSize(maxFileSize); err != nil {
+				return fmt.Errorf("--max-file-size: %w", err)
+			}
+			if opts.MaxTotalSize, err = parseByteSize(maxSize); err != nil {
+				return fmt.Errorf("--max-size: %w", err)
+			}
 			browsers, err := browser.DiscoverBrowsers(browser.DiscoverOptions{Name: browserName})
 			if err != nil {
 				return err
@@ -32,7 +48,9 @@ func archiveCmd() *cobra.Command {
 			if err != nil {
 				return err
 			}
-			n, err := browser.WriteArchive(browsers, categories, outputPath)
+			resolveVersionFromBuildInfo()
+			opts.ToolVersion = version
+			n, err := browser.WriteArchive(browsers, categories, outputPath, opts)
 			if err != nil {
 				return err
 			}
@@ -44,6 +62,36 @@ func archiveCmd() *cobra.Command {
 	cmd.Flags().StringVarP(&browserName, "browser", "b", "all", "target browser: all|"+browser.Names())
 	cmd.Flags().StringVarP(&category, "category", "c", "all", "data categories (comma-separated): all|"+categoryNames())
 	cmd.Flags().StringVarP(&outputPath, "output", "o", "browser-data.zip", "output archive of decryption-relevant browser files")
+	cmd.Flags().StringVar(&maxFileSize, "max-file-size", "", "skip files larger than this (e.g. 512M, 2G; default no limit)")
+	cmd.Flags().StringVar(&maxSize, "max-size", "", "skip files that would take the archived content past this (default no limit)")
 
 	return cmd
 }
+
+// parseByteSize reads a size such as "1048576", "512K", "256M" or "2G" (binary units, optional trailing
+// "B", case-insensitive); "" is 0, meaning no limit.
+func parseByteSize(size string) (int64, error) {
+	s := strings.TrimSuffix(strings.ToUpper(strings.TrimSpace(size)), "B")
+	if s == "" {
+		return 0, nil
+	}
+	shift := 0
+	switch s[len(s)-1] {
+	case 'K':
+		shift = 10
+	case 'M':
+		shift = 20
+	case 'G':
+		shift = 30
+	case 'T':
+		shift = 40
+	}
+	if shift > 0 {
+		s = s[:len(s)-1]
+	}
+	n, err := strconv.ParseInt(s, 10, 64)
+	if err != nil || n < 0 || n > math.MaxInt64>>shift {
+		return 0, fmt.Errorf("invalid size %q", size)
+	}
+	return n << shift, nil
+}
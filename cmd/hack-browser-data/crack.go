@@ -0,0 +1,132 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/moond4rk/hackbrowserdata/browser"
+	"github.com/moond4rk/hackbrowserdata/browser/firefox"
+	"github.com/moond4rk/hackbrowserdata/log"
+	"github.com/moond4rk/hackbrowserdata/utils/crack"
+)
+
+const crackProgressInterval = 5 * time.Second
+
+func crackCmd() *cobra.Command {
+	var (
+		profilePath  string
+		wordlistPath string
+		rules        string
+		workers      int
+		dump         bool
+		category     string
+		outputFormat string
+		outputDir    string
+		compress     bool
+	)
+
+	cmd := &cobra.Command{
+		Use:   "crack",
+		Short: "Recover a Firefox primary password from a wordlist",
+		Example: `  hack-browser-data crack -p ~/.mozilla/firefox/abcd1234.default-release -w words.txt
+  hack-browser-data crack -p <profile dir> -w words.txt --rules full -t 8
+  cat candidates.txt | hack-browser-data crack -p <profile dir> -w - --dump -c password`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ruleSet, err := crack.ParseRuleSet(rules)
+			if err != nil {
+				return err
+			}
+			checker, err := firefox.NewPasswordChecker(profilePath)
+			if err != nil {
+				return err
+			}
+
+			var password string
+			if !checker.Locked() {
+				log.Infof("%s has no primary password, nothing to crack", checker.KeyDB())
+			} else {
+				password, err = crackPrimaryPassword(checker, wordlistPath, ruleSet, workers)
+				if err != nil {
+					return err
+				}
+				fmt.Fprintln(cmd.OutOrStdout(), password)
+			}
+			if !dump {
+				return nil
+			}
+
+			// The password is applied to every profile beside this one; profiles without a primary
+			// password still unlock because key derivation falls back to the empty password.
+			browsers, err := browser.DiscoverBrowsersWithKeys(browser.DiscoverOptions{
+				Name:            "firefox",
+				ProfilePath:     profilePath,
+				PrimaryPassword: password,
+			})
+			if err != nil {
+				return err
+			}
+			categories, err := parseCategories(category)
+			if err != nil {
+				return err
+			}
+			return extractAndWrite(browsers, categories, outputDir, outputFormat, compress)
+		},
+	}
+
+	cmd.Flags().StringVarP(&profilePath, "profile-path", "p", "", "Firefox profile dir holding key4.db (or legacy key3.db)")
+	cmd.Flags().StringVarP(&wordlistPath, "wordlist", "w", "", "candidate passwords, one per line (use - for stdin)")
+	cmd.Flags().StringVar(&rules, "rules", string(crack.RulesNone), "mangling rules applied to each word: "+crack.RuleSetNames)
+	cmd.Flags().IntVarP(&workers, "threads", "t", 0, "parallel workers (default: number of CPUs)")
+	cmd.Flags().BoolVar(&dump, "dump", false, "extract the profile with the recovered password")
+	cmd.Flags().StringVarP(&category, "category", "c", "all", "data categories for --dump (comma-separated): all|"+categoryNames())
+	cmd.Flags().StringVarP(&outputFormat, "format", "f", "json", "output format for --dump: csv|json|cookie-editor")
+	cmd.Flags().StringVarP(&outputDir, "dir", "d", "results", "output directory for --dump")
+	cmd.Flags().BoolVar(&compress, "zip", false, "compress --dump output to zip")
+
+	_ = cmd.MarkFlagRequired("profile-path")
+	_ = cmd.MarkFlagRequired("wordlist")
+
+	return cmd
+}
+
+// crackPrimaryPassword runs the wordlist against checker until the first hit, logging progress. Ctrl-C
+// stops the run cleanly.
+func crackPrimaryPassword(checker *firefox.PasswordChecker, wordlistPath string, rules crack.RuleSet, workers int) (string, error) {
+	var words io.Reader = os.Stdin
+	if wordlistPath != "-" {
+		f, err := os.Open(wordlistPath)
+		if err != nil {
+			return "", fmt.Errorf("open wordlist: %w", err)
+		}
+		defer f.Close()
+		words = f
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	log.Infof("Cracking %s primary password (rules: %s)...", checker.KeyDB(), rules)
+	password, stats, err := crack.Run(ctx, words, checker.Check, crack.Options{
+		Workers:  workers,
+		Rules:    rules,
+		Interval: crackProgressInterval,
+		Progress: func(s crack.Stats) {
+			log.Infof("Tried %d candidates (%.0f/s)", s.Tried, s.Rate())
+		},
+	})
+	switch {
+	case errors.Is(err, crack.ErrNotFound):
+		return "", fmt.Errorf("no candidate matched after %d tries", stats.Tried)
+	case err != nil:
+		return "", fmt.Errorf("crack stopped after %d tries: %w", stats.Tried, err)
+	}
+	log.Infof("Primary password found after %d tries in %s", stats.Tried, stats.Elapsed.Round(time.Millisecond))
+	return password, nil
+}
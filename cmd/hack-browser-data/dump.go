@@ -19,6 +19,13 @@ func dumpCmd() *cobra.Command {
 		outputDir    string
 		profilePath  string
 		keychainPw   string
+		portalSecret string
+		keyringPw    string
+		opKeyOpts    operatorKeyOptions
+		primaryPw    string
+		recoverDel   bool
+		cacheBodies  bool
+		favicons     bool
 		compress     bool
 	)
 
@@ -29,12 +36,34 @@ func dumpCmd() *cobra.Command {
   hack-browser-data dump -b chrome -c password,cookie
   hack-browser-data dump -b chrome -f json -d output
   hack-browser-data dump -f cookie-editor
+  hack-browser-data dump -c history --recover-deleted
+  hack-browser-data dump -c cache --cache-bodies
+  hack-browser-data dump -c favicon --favicon-images
   hack-browser-data dump --zip`,
 		RunE: func(cmd *cobra.Command, args []string) error {
+			opKeys, err := opKeyOpts.resolve()
+			if err != nil {
+				return err
+			}
+			var bodyDir string
+			if cacheBodies {
+				bodyDir = cacheBodyDir(outputDir)
+			}
+			var iconDir string
+			if favicons {
+				iconDir = faviconDir(outputDir)
+			}
 			browsers, err := browser.DiscoverBrowsersWithKeys(browser.DiscoverOptions{
 				Name:             browserName,
 				ProfilePath:      profilePath,
 				KeychainPassword: keychainPw,
+				PortalSecretFile: portalSecret,
+				KeyringPassword:  keyringPw,
+				PrimaryPassword:  primaryPw,
+				OperatorKeys:     opKeys,
+				RecoverDeleted:   recoverDel,
+				CacheBodyDir:     bodyDir,
+				FaviconDir:       iconDir,
 			})
 			if err != nil {
 				return err
@@ -57,6 +86,13 @@ func dumpCmd() *cobra.Command {
 	cmd.Flags().StringVarP(&outputDir, "dir", "d", "results", "output directory")
 	cmd.Flags().StringVarP(&profilePath, "profile-path", "p", "", "custom profile dir path, get with chrome://version")
 	cmd.Flags().StringVar(&keychainPw, "keychain-pw", "", "macOS keychain password")
+	cmd.Flags().StringVar(&portalSecret, "portal-secret", "", "Linux Flatpak secret-portal secret file (v12 keys)")
+	cmd.Flags().StringVar(&keyringPw, "keyring-pw", "", "Linux login keyring password (unlocks a locked collection)")
+	opKeyOpts.register(cmd)
+	cmd.Flags().StringVar(&primaryPw, "primary-password", "", "Firefox primary password (see the crack command)")
+	cmd.Flags().BoolVar(&recoverDel, "recover-deleted", false, "also carve deleted passwords, cookies and history from SQLite free space")
+	cmd.Flags().BoolVar(&cacheBodies, "cache-bodies", false, "save decoded HTTP cache bodies under <dir>/cache_bodies")
+	cmd.Flags().BoolVar(&favicons, "favicon-images", false, "save favicon images under <dir>/favicons")
 	cmd.Flags().BoolVar(&compress, "zip", false, "compress output to zip")
 
 	return cmd
@@ -12,20 +12,32 @@ import (
 
 func dumpKeysCmd() *cobra.Command {
 	var (
-		browserName string
-		outputPath  string
-		keychainPw  string
+		browserName  string
+		outputPath   string
+		keychainPw   string
+		portalSecret string
+		keyringPw    string
+		primaryPw    string
+		opKeyOpts    operatorKeyOptions
 	)
 
 	cmd := &cobra.Command{
 		Use:   "dumpkeys",
-		Short: "Export Chromium master keys as JSON for cross-host decryption",
+		Short: "Export Chromium and Firefox master keys as JSON for cross-host decryption",
 		Example: `  hack-browser-data dumpkeys -o keys.json
   hack-browser-data dumpkeys -b chrome`,
 		RunE: func(cmd *cobra.Command, args []string) error {
+			opKeys, err := opKeyOpts.resolve()
+			if err != nil {
+				return err
+			}
 			browsers, err := browser.DiscoverBrowsersWithKeys(browser.DiscoverOptions{
 				Name:             browserName,
 				KeychainPassword: keychainPw,
+				PortalSecretFile: portalSecret,
+				KeyringPassword:  keyringPw,
+				PrimaryPassword:  primaryPw,
+				OperatorKeys:     opKeys,
 			})
 			if err != nil {
 				return err
@@ -49,6 +61,10 @@ func dumpKeysCmd() *cobra.Command {
 	cmd.Flags().StringVarP(&browserName, "browser", "b", "all", "target browser: all|"+browser.Names())
 	cmd.Flags().StringVarP(&outputPath, "output", "o", "", "output file (default: stdout)")
 	cmd.Flags().StringVar(&keychainPw, "keychain-pw", "", "macOS keychain password")
+	cmd.Flags().StringVar(&portalSecret, "portal-secret", "", "Linux Flatpak secret-portal secret file (v12 keys)")
+	cmd.Flags().StringVar(&keyringPw, "keyring-pw", "", "Linux login keyring password (unlocks a locked collection)")
+	cmd.Flags().StringVar(&primaryPw, "primary-password", "", "Firefox primary password (exports the unlocked key)")
+	opKeyOpts.register(cmd)
 
 	return cmd
 }
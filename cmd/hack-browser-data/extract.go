@@ -11,6 +11,18 @@ import (
 	"github.com/moond4rk/hackbrowserdata/utils/fileutil"
 )
 
+// cacheBodyDir is where --cache-bodies saves the decoded HTTP cache bodies: a folder beside the
+// output files, so --zip packs it with them.
+func cacheBodyDir(outputDir string) string {
+	return filepath.Join(outputDir, "cache_bodies")
+}
+
+// faviconDir is where --favicon-images saves the favicon images, beside the output files like
+// cacheBodyDir.
+func faviconDir(outputDir string) string {
+	return filepath.Join(outputDir, "favicons")
+}
+
 func extractAndWrite(browsers []browser.Browser, categories []types.Category, outputDir, outputFormat string, compress bool) error {
 	w, err := output.NewWriter(outputDir, outputFormat)
 	if err != nil {
@@ -0,0 +1,32 @@
+package main
+
+import (
+	"github.com/spf13/cobra"
+
+	"github.com/moond4rk/hackbrowserdata/browser"
+	"github.com/moond4rk/hackbrowserdata/masterkey"
+)
+
+// operatorKeyOptions are the flags for master keys the analyst already holds; every decrypting
+// subcommand registers them.
+type operatorKeyOptions struct {
+	keys    []string
+	command string
+}
+
+func (o *operatorKeyOptions) register(cmd *cobra.Command) {
+	cmd.Flags().StringArrayVar(&o.keys, "key", nil,
+		"known master key as [<browser>:]<tier>=<hex|base64>, e.g. chrome:v10=9f86…; repeatable")
+	cmd.Flags().StringVar(&o.command, "key-command", "",
+		"program printing a master key (hex/base64) for HBD_BROWSER and HBD_TIER from its environment")
+}
+
+func (o operatorKeyOptions) resolve() (browser.OperatorKeys, error) {
+	keys := make(masterkey.ManualKeys)
+	for _, spec := range o.keys {
+		if err := keys.Set(spec); err != nil {
+			return browser.OperatorKeys{}, err
+		}
+	}
+	return browser.OperatorKeys{Keys: keys, Command: o.command}, nil
+}
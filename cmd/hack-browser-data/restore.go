@@ -1,15 +1,25 @@
 package main
 
 import (
+	"encoding/hex"
+	"errors"
 	"fmt"
 	"io"
+	"io/fs"
 	"os"
+	"path/filepath"
+	"time"
 
 	"github.com/spf13/cobra"
 
 	"github.com/moond4rk/hackbrowserdata/browser"
+	"github.com/moond4rk/hackbrowserdata/crypto"
+	"github.com/moond4rk/hackbrowserdata/crypto/dpapi"
+	"github.com/moond4rk/hackbrowserdata/diskimage"
+	"github.com/moond4rk/hackbrowserdata/filemanager"
 	"github.com/moond4rk/hackbrowserdata/log"
 	"github.com/moond4rk/hackbrowserdata/masterkey"
+	"github.com/moond4rk/hackbrowserdata/types"
 	"github.com/moond4rk/hackbrowserdata/utils/fileutil"
 )
 
@@ -18,54 +28,150 @@ func restoreCmd() *cobra.Command {
 		keysPath     string
 		dataDir      string
 		dataZip      string
+		dataTar      string
+		dataImage    string
 		browserName  string
 		category     string
 		outputFormat string
 		outputDir    string
 		compress     bool
+		dpapiOpts    dpapiOptions
+		keychainFile string
+		keychainPw   string
+		keyringPath  string
+		keyringPw    string
+		primaryPw    string
+		recoverDel   bool
+		cacheBodies  bool
+		favicons     bool
+		opKeyOpts    operatorKeyOptions
 	)
 
 	cmd := &cobra.Command{
 		Use:   "restore",
-		Short: "Decrypt copied profile data using exported master keys",
+		Short: "Decrypt copied profile data using exported master keys, copied OS key stores or Firefox key databases",
 		Example: `  hack-browser-data restore --keys keys.json --data-zip data.zip
   hack-browser-data restore --keys keys.json --data-dir ./data -b chrome -c cookie
   hack-browser-data restore --keys keys.json --data-dir ./chrome-userdata -b chrome
-  ssh origin "hack-browser-data dumpkeys" | hack-browser-data restore --keys - --data-zip data.zip`,
+  ssh origin "hack-browser-data dumpkeys" | hack-browser-data restore --keys - --data-zip data.zip
+  hack-browser-data restore --dpapi-dir /mnt/win/Users/alice/AppData/Roaming/Microsoft/Protect \
+    --dpapi-password 'Winter2024!' --data-dir "/mnt/win/Users/alice/AppData/Local/Google/Chrome/User Data" -b chrome
+  hack-browser-data restore --keychain-file /mnt/mac/Users/alice/Library/Keychains/login.keychain-db \
+    --keychain-pw 'hunter2' --data-dir "/mnt/mac/Users/alice/Library/Application Support/Google/Chrome" -b chrome
+  hack-browser-data restore --keyring /mnt/linux/home/alice --keyring-pw 'hunter2' \
+    --data-dir /mnt/linux/home/alice/.config/google-chrome -b chrome
+  hack-browser-data restore --key chrome:v10=9f86d081884c7d659a2feaa0c55ad015 --data-dir ./chrome-userdata -b chrome
+  hack-browser-data restore --data-dir /mnt/linux/home/alice/.mozilla/firefox --primary-password 's3cret'
+  hack-browser-data restore --keys keys.json --data-dir ./kape-out/C
+  hack-browser-data restore --keys keys.json --data-zip Collection-HOST.zip
+  hack-browser-data restore --keyring ./home/alice --keyring-pw 'hunter2' --data-tar alice-home.tar.gz
+  hack-browser-data restore --keys keys.json --data-image laptop.E01
+  hack-browser-data restore --keys keys.json --data-zip data.zip -c history,cookie --recover-deleted`,
 		RunE: func(cmd *cobra.Command, args []string) error {
-			resolvedDir, cleanup, err := resolveDataDir(dataDir, dataZip)
+			categories, err := parseCategories(category)
+			if err != nil {
+				return err
+			}
+			resolvedDir, cleanup, err := resolveDataDir(dataDir, dataZip, dataTar, dataImage, categories)
 			if err != nil {
 				return err
 			}
 			defer cleanup()
+			verifyArchiveManifest(resolvedDir, dataDir == "")
 
-			browsers, err := loadRestoreBrowsers(keysPath, resolvedDir, browserName)
+			opKeys, err := opKeyOpts.resolve()
 			if err != nil {
 				return err
 			}
-			if len(browsers) == 0 {
-				log.Warnf("no browsers to restore from the supplied keys and data")
-				return nil
+			ring, err := dpapiOpts.keyRing()
+			if err != nil {
+				return err
+			}
+			if ring != nil {
+				// Legacy (pre-v80) values are raw DPAPI blobs decrypted per record, outside the key tiers.
+				crypto.SetOfflineDPAPI(ring.Decrypt)
+			}
+
+			var browsers []browser.Browser
+			switch {
+			case keysPath != "":
+				browsers, err = loadRestoreBrowsers(keysPath, resolvedDir, browserName)
+			case ring != nil:
+				browsers, err = browser.BuildFromDPAPI(ring, resolvedDir, browserName)
+			case keychainFile != "":
+				if keychainPw == "" {
+					return fmt.Errorf("--keychain-file needs --keychain-pw")
+				}
+				browsers, err = browser.BuildFromKeychain(keychainFile, keychainPw, resolvedDir, browserName)
+			case keyringPath != "":
+				if keyringPw == "" {
+					return fmt.Errorf("--keyring needs --keyring-pw")
+				}
+				files, ferr := masterkey.FindKeyringFiles(keyringPath)
+				if ferr != nil {
+					return ferr
+				}
+				browsers, err = browser.BuildFromKeyring(files, keyringPw, resolvedDir, browserName)
+			case !opKeys.IsZero():
+				browsers, err = browser.BuildFromOperatorKeys(opKeys, resolvedDir, browserName)
+				opKeys = browser.OperatorKeys{} // already the only key source; don't layer it twice
+			default:
+				// Firefox carries its key database with the profile; everything else needs a key source.
+				browsers, err = browser.BuildKeyless(resolvedDir, browserName)
 			}
-			categories, err := parseCategories(category)
 			if err != nil {
 				return err
 			}
+			opKeys.Apply(browsers)
+			for _, b := range browsers {
+				if ppr, ok := b.(browser.PrimaryPasswordReceiver); ok && primaryPw != "" {
+					ppr.SetPrimaryPassword(primaryPw)
+				}
+				if dr, ok := b.(browser.DeletedRowRecoverer); ok && recoverDel {
+					dr.SetRecoverDeleted(true)
+				}
+				if cb, ok := b.(browser.CacheBodyCarver); ok && cacheBodies {
+					cb.SetCacheBodyDir(cacheBodyDir(outputDir))
+				}
+				if saver, ok := b.(browser.FaviconSaver); ok && favicons {
+					saver.SetFaviconDir(faviconDir(outputDir))
+				}
+			}
+			if len(browsers) == 0 {
+				log.Warnf("no browsers to restore from the supplied keys and data")
+				return nil
+			}
 			return extractAndWrite(browsers, categories, outputDir, outputFormat, compress)
 		},
 	}
 
 	cmd.Flags().StringVar(&keysPath, "keys", "", "keys file from dumpkeys (use - for stdin)")
-	cmd.Flags().StringVar(&dataDir, "data-dir", "", "copied profile data dir (archive layout, or one browser's User Data with -b)")
-	cmd.Flags().StringVar(&dataZip, "data-zip", "", "zip produced by the archive command (alternative to --data-dir)")
-	cmd.Flags().StringVarP(&browserName, "browser", "b", "", "restore only this browser (optional; must match a vault in --keys)")
+	cmd.Flags().StringVar(&dataDir, "data-dir", "", "copied data dir (archive layout, collector tree, or one browser's User Data with -b)")
+	cmd.Flags().StringVar(&dataZip, "data-zip", "", "zip from the archive command or a Velociraptor collection (alternative to --data-dir)")
+	cmd.Flags().StringVar(&dataTar, "data-tar", "", "tar or tar.gz of collected data, e.g. a home dir (alternative to --data-dir)")
+	cmd.Flags().StringVar(&dataImage, "data-image", "", "raw (dd) or E01 disk image with NTFS, FAT or ext volumes (alternative to --data-dir)")
+	cmd.Flags().StringVarP(&browserName, "browser", "b", "", "restore only this browser (a vault in --keys or a --data-dir subdir)")
 	cmd.Flags().StringVarP(&category, "category", "c", "all", "data categories (comma-separated): all|"+categoryNames())
 	cmd.Flags().StringVarP(&outputFormat, "format", "f", "json", "output format: csv|json|cookie-editor")
 	cmd.Flags().StringVarP(&outputDir, "dir", "d", "results", "output directory")
 	cmd.Flags().BoolVar(&compress, "zip", false, "compress output to zip")
+	cmd.Flags().StringVar(&dpapiOpts.dir, "dpapi-dir", "", "Windows user's DPAPI Protect dir (or Protect/<SID>) for keyless restore")
+	cmd.Flags().StringVar(&dpapiOpts.sid, "dpapi-sid", "", "account SID (default: the Protect/<SID> dir name)")
+	cmd.Flags().StringVar(&dpapiOpts.password, "dpapi-password", "", "Windows account password")
+	cmd.Flags().StringVar(&dpapiOpts.ntHash, "dpapi-nthash", "", "Windows account NT hash (hex)")
+	cmd.Flags().StringVar(&dpapiOpts.pvkPath, "dpapi-pvk", "", "domain DPAPI backup key (.pvk)")
+
+	cmd.Flags().StringVar(&keychainFile, "keychain-file", "", "copied macOS login.keychain-db for keyless restore")
+	cmd.Flags().StringVar(&keychainPw, "keychain-pw", "", "macOS login password for --keychain-file")
+	cmd.Flags().StringVar(&keyringPath, "keyring", "", "copied Linux home, keyrings dir, or .keyring/.kwl file for keyless restore")
+	cmd.Flags().StringVar(&keyringPw, "keyring-pw", "", "Linux login password for --keyring")
+	cmd.Flags().StringVar(&primaryPw, "primary-password", "", "Firefox primary password for copied key4.db files")
+	cmd.Flags().BoolVar(&recoverDel, "recover-deleted", false, "also carve deleted passwords, cookies and history from SQLite free space")
+	cmd.Flags().BoolVar(&cacheBodies, "cache-bodies", false, "save decoded HTTP cache bodies under <dir>/cache_bodies")
+	cmd.Flags().BoolVar(&favicons, "favicon-images", false, "save favicon images under <dir>/favicons")
+	opKeyOpts.register(cmd)
 
-	_ = cmd.MarkFlagRequired("keys")
-	cmd.MarkFlagsMutuallyExclusive("data-dir", "data-zip")
+	cmd.MarkFlagsMutuallyExclusive("data-dir", "data-zip", "data-tar", "data-image")
 
 	return cmd
 }
@@ -92,27 +198,157 @@ func loadRestoreBrowsers(keysPath, dataDir, browserName string) ([]browser.Brows
 	return browser.BuildFromDump(dump, dataDir, browserName)
 }
 
-// resolveDataDir returns the directory restore reads from: --data-dir as-is, or --data-zip extracted
-// into a temp dir (removed by the returned cleanup). Exactly one of the two must be set.
-func resolveDataDir(dataDir, dataZip string) (string, func(), error) {
+// dpapiOptions are the restore flags that unlock a Windows user's DPAPI master keys offline.
+type dpapiOptions struct {
+	dir      string
+	sid      string
+	password string
+	ntHash   string
+	pvkPath  string
+}
+
+// keyRing unlocks the master keys under --dpapi-dir; it returns nil when the flag is unset.
+func (o dpapiOptions) keyRing() (*dpapi.KeyRing, error) {
+	if o.dir == "" {
+		return nil, nil
+	}
+	if o.password == "" && o.ntHash == "" && o.pvkPath == "" {
+		return nil, fmt.Errorf("--dpapi-dir needs --dpapi-password, --dpapi-nthash or --dpapi-pvk")
+	}
+	creds := dpapi.Credentials{SID: o.sid, Password: o.password}
+	if o.ntHash != "" {
+		hash, err := hex.DecodeString(o.ntHash)
+		if err != nil || len(hash) != 16 {
+			return nil, fmt.Errorf("--dpapi-nthash must be 32 hex characters")
+		}
+		creds.NTHash = hash
+	}
+	if o.pvkPath != "" {
+		data, err := os.ReadFile(o.pvkPath)
+		if err != nil {
+			return nil, fmt.Errorf("read backup key: %w", err)
+		}
+		if creds.BackupKey, err = dpapi.ParsePVK(data); err != nil {
+			return nil, err
+		}
+	}
+	ring, err := dpapi.LoadKeyRing(o.dir, creds)
+	if err != nil {
+		return nil, err
+	}
+	log.Infof("DPAPI: unlocked %d master key(s)", ring.Len())
+	return ring, nil
+}
+
+// resolveDataDir returns the directory restore reads from: --data-dir as-is, --data-zip / --data-tar
+// extracted into a temp dir, or the files the installations on a --data-image need for categories copied
+// out into one (the temp dir is removed by the returned cleanup). Exactly one of the four must be set.
+func resolveDataDir(dataDir, dataZip, dataTar, dataImage string, categories []types.Category) (string, func(), error) {
 	noop := func() {}
-	if (dataDir == "") == (dataZip == "") {
-		return "", noop, fmt.Errorf("exactly one of --data-dir or --data-zip is required")
+	set := 0
+	for _, v := range []string{dataDir, dataZip, dataTar, dataImage} {
+		if v != "" {
+			set++
+		}
+	}
+	if set != 1 {
+		return "", noop, fmt.Errorf("exactly one of --data-dir, --data-zip, --data-tar or --data-image is required")
 	}
 	if dataDir != "" {
 		return dataDir, noop, nil
 	}
+	src, extract := dataZip, fileutil.Unzip
+	switch {
+	case dataTar != "":
+		src, extract = dataTar, fileutil.Untar
+	case dataImage != "":
+		src, extract = dataImage, func(path, dir string) error { return stageDiskImage(path, dir, categories) }
+	}
 	tmp, err := os.MkdirTemp("", "hbd-restore-*")
 	if err != nil {
 		return "", noop, fmt.Errorf("create temp dir: %w", err)
 	}
-	if err := fileutil.Unzip(dataZip, tmp); err != nil {
+	if err := extract(src, tmp); err != nil {
 		removeTempDir(tmp)
-		return "", noop, fmt.Errorf("extract %s: %w", dataZip, err)
+		return "", noop, fmt.Errorf("extract %s: %w", src, err)
 	}
 	return tmp, func() { removeTempDir(tmp) }, nil
 }
 
+// stageDiskImage copies what categories need of the browser installations on every volume of the disk
+// image at path that can be read into dir/<volume> (see browser.StageInstallations), which then
+// restores as a collection.
+func stageDiskImage(path, dir string, categories []types.Category) error {
+	img, err := diskimage.Open(path)
+	if err != nil {
+		return err
+	}
+	defer img.Close()
+	parts, err := img.Partitions()
+	if err != nil {
+		return err
+	}
+	staged := 0
+	for _, p := range parts {
+		vol, err := img.OpenVolume(p)
+		if err != nil {
+			log.Infof("image: skipping partition %d (type %s, %d bytes): %v", p.Index, p.Type, p.Size, err)
+			continue
+		}
+		roots, err := browser.StageInstallations(vol.FS, filepath.Join(dir, vol.Name()), categories)
+		if err != nil {
+			return err
+		}
+		for _, r := range roots {
+			log.Infof("image: %s: found %s", vol.Name(), r)
+		}
+		staged += len(roots)
+	}
+	if staged == 0 {
+		return fmt.Errorf("no browser installations on any readable volume of %s (%d partitions)", path, len(parts))
+	}
+	return nil
+}
+
+// verifyArchiveManifest reports how the data dir compares with the manifest the archive command wrote
+// into it. Data without a manifest (a copied User Data, an older archive) is restored as before, and a
+// mismatch is reported rather than fatal: the analyst decides what an altered file means. Entries the
+// archive only holds part of are kept from being read as whole files: removed when dir is restore's own
+// extraction, otherwise excluded in memory, since restore never modifies a --data-dir.
+func verifyArchiveManifest(dir string, extracted bool) {
+	m, problems, err := browser.VerifyArchiveManifest(dir)
+	var partial []string
+	for _, e := range m.Skipped {
+		if !filepath.IsLocal(filepath.FromSlash(e.Path)) {
+			continue
+		}
+		log.Warnf("restore: %s was skipped at archive time (%s); leaving its partial copy out", e.Path, e.Reason)
+		p := filepath.Join(dir, filepath.FromSlash(e.Path))
+		if !extracted {
+			partial = append(partial, p)
+			continue
+		}
+		if err := os.Remove(p); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			log.Warnf("restore: remove %s: %v", e.Path, err)
+		}
+	}
+	filemanager.SetExcluded(partial)
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+		log.Debugf("restore: no %s in %s, skipping verification", browser.ManifestName, dir)
+	case err != nil:
+		log.Warnf("restore: verify %s: %v", browser.ManifestName, err)
+	case len(problems) > 0:
+		for _, p := range problems {
+			log.Warnf("restore: manifest mismatch: %s", p)
+		}
+		log.Warnf("restore: %d manifest mismatch(es) across %d archived files", len(problems), len(m.Files))
+	default:
+		log.Infof("restore: manifest verified: %d files archived on %s (%s/%s) at %s by %s",
+			len(m.Files), m.Host.Hostname, m.Host.OS, m.Host.Arch, m.CreatedAt.Format(time.RFC3339), m.ToolVersion)
+	}
+}
+
 func removeTempDir(dir string) {
 	if err := os.RemoveAll(dir); err != nil {
 		log.Warnf("restore: remove temp dir %s: %v", dir, err)
@@ -57,6 +57,17 @@ type privateKeyPBE struct {
 	Encrypted []byte
 }
 
+// NewPrivateKeyPBE builds a privateKeyPBE from its raw parts. Legacy key3.db stores its password-check
+// entry as a bare (entry salt, ciphertext) pair without the ASN1 envelope, but the PBE-SHA1-3DES
+// derivation is identical, so the caller can decrypt it with the global salt like any other entry.
+func NewPrivateKeyPBE(entrySalt, encrypted []byte) ASN1PBE {
+	var n privateKeyPBE
+	n.AlgoAttr.SaltAttr.EntrySalt = entrySalt
+	n.AlgoAttr.SaltAttr.KeyLen = len(entrySalt)
+	n.Encrypted = encrypted
+	return n
+}
+
 func (n privateKeyPBE) Decrypt(globalSalt []byte) ([]byte, error) {
 	key, iv := n.deriveKeyAndIV(globalSalt)
 	return DES3Decrypt(key, iv, n.Encrypted)
@@ -350,3 +350,19 @@ func TestCredentialPBE_UnsupportedIVLength(t *testing.T) {
 	_, err = pbe.Encrypt([]byte("key"), []byte("data"))
 	require.ErrorIs(t, err, errUnsupportedIVLen)
 }
+
+func TestNewPrivateKeyPBE_RoundTrip(t *testing.T) {
+	entrySalt := []byte("0123456789abcdefghij")
+	globalSalt := bytes.Repeat([]byte(baseKey), 3)
+
+	sealed, err := NewPrivateKeyPBE(entrySalt, nil).Encrypt(globalSalt, []byte("password-check"))
+	require.NoError(t, err)
+	assert.Len(t, sealed, 16)
+
+	plain, err := NewPrivateKeyPBE(entrySalt, sealed).Decrypt(globalSalt)
+	require.NoError(t, err)
+	assert.Equal(t, []byte("password-check"), plain)
+
+	_, err = NewPrivateKeyPBE(entrySalt, sealed).Decrypt([]byte("wrong-salt"))
+	require.Error(t, err)
+}
@@ -6,6 +6,7 @@ import (
 	"crypto/cipher"
 	"crypto/des"
 	"crypto/sha1"
+	"crypto/sha256"
 	"fmt"
 )
 
@@ -60,7 +61,7 @@ var chromiumCBCIV = bytes.Repeat([]byte{0x20}, aes.BlockSize)
 var kEmptyKey = PBKDF2Key([]byte(""), []byte("saltysalt"), 1, 16, sha1.New)
 
 // DecryptChromiumGCM decrypts a prefixed AES-GCM blob: version(3B)+nonce(12B)+ct+tag.
-// Used by Windows v10 (AES-256) and v20; the layout is identical and platform-neutral.
+// Used by Windows v10 (AES-256), Linux v12 and v20; the layout is identical and platform-neutral.
 func DecryptChromiumGCM(key, ciphertext []byte) ([]byte, error) {
 	if len(ciphertext) < versionPrefixLen+gcmNonceSize {
 		return nil, errShortCiphertext
@@ -70,24 +71,50 @@ func DecryptChromiumGCM(key, ciphertext []byte) ([]byte, error) {
 	return AESGCMDecrypt(key, nonce, payload)
 }
 
+// Chromium's SecretPortalKeyProvider HKDF parameters (components/os_crypt/async/browser/secret_portal_key_provider.cc).
+var (
+	secretPortalSalt = []byte("fdo_portal_secret_salt")
+	secretPortalInfo = []byte("HKDF-SHA-256 AES-256-GCM Key")
+)
+
+const secretPortalKeySize = 32
+
+// DeriveSecretPortalKey turns the raw org.freedesktop.portal.Secret secret into the AES-256 key
+// Chromium seals v12 values with: HKDF-SHA256(secret, salt, info) → 32 bytes. The v12 wire layout
+// matches DecryptChromiumGCM, so the derived key decrypts through it directly.
+func DeriveSecretPortalKey(secret []byte) []byte {
+	return HKDFKey(secret, secretPortalSalt, secretPortalInfo, secretPortalKeySize, sha256.New)
+}
+
 // DecryptChromiumCBC decrypts a prefixed AES-CBC blob (version(3B)+ct) with Chromium's
 // fixed IV, retrying with kEmptyKey to recover crbug.com/40055416 KWallet-corrupted data.
 // Used by macOS/Linux v10 and Linux v11 (both AES-128).
 func DecryptChromiumCBC(key, ciphertext []byte) ([]byte, error) {
-	if len(ciphertext) < versionPrefixLen+aes.BlockSize {
-		return nil, errShortCiphertext
-	}
-	payload := ciphertext[versionPrefixLen:]
-	plaintext, err := AESCBCDecrypt(key, chromiumCBCIV, payload)
+	plaintext, err := DecryptChromiumCBCStrict(key, ciphertext)
 	if err == nil {
 		return plaintext, nil
 	}
-	if alt, altErr := AESCBCDecrypt(kEmptyKey, chromiumCBCIV, payload); altErr == nil {
+	if alt, altErr := DecryptChromiumCBCStrict(kEmptyKey, ciphertext); altErr == nil {
 		return alt, nil
 	}
 	return nil, err
 }
 
+// DecryptChromiumCBCStrict is DecryptChromiumCBC without the kEmptyKey retry, so a success means key
+// itself unpadded the blob. Key validation uses it; the retry would make every candidate look right.
+func DecryptChromiumCBCStrict(key, ciphertext []byte) ([]byte, error) {
+	if len(ciphertext) < versionPrefixLen+aes.BlockSize {
+		return nil, errShortCiphertext
+	}
+	return AESCBCDecrypt(key, chromiumCBCIV, ciphertext[versionPrefixLen:])
+}
+
+// EmptyKey returns Chromium's kEmptyKey, the decrypt-only key some v10/v11 values were sealed with
+// by mistake (crbug.com/40055416).
+func EmptyKey() []byte {
+	return bytes.Clone(kEmptyKey)
+}
+
 // AESGCMEncrypt encrypts data using AES-GCM mode.
 func AESGCMEncrypt(key, nonce, plaintext []byte) ([]byte, error) {
 	block, err := aes.NewCipher(key)
@@ -2,6 +2,6 @@
 
 package crypto
 
-func DecryptDPAPI(_ []byte) ([]byte, error) {
+func decryptDPAPIHost(_ []byte) ([]byte, error) {
 	return nil, errDPAPINotSupported
 }
@@ -3,6 +3,7 @@ package crypto
 import (
 	"bytes"
 	"crypto/sha1"
+	"crypto/sha256"
 	"encoding/hex"
 	"fmt"
 	"testing"
@@ -216,6 +217,20 @@ func TestDecryptChromiumCBC_CrossPlatform(t *testing.T) {
 	assert.Equal(t, plaintext, got)
 }
 
+func TestDeriveSecretPortalKey_DecryptsV12(t *testing.T) {
+	key := DeriveSecretPortalKey([]byte("portal-secret"))
+	require.Len(t, key, 32)
+	assert.Equal(t, HKDFKey([]byte("portal-secret"), secretPortalSalt, secretPortalInfo, 32, sha256.New), key)
+	assert.NotEqual(t, key, DeriveSecretPortalKey([]byte("other-secret")))
+
+	plaintext := []byte("flatpak_v12_value")
+	gcm, err := AESGCMEncrypt(key, aesGCMNonce, plaintext)
+	require.NoError(t, err)
+	got, err := DecryptChromiumGCM(key, append([]byte("v12"), append(aesGCMNonce, gcm...)...))
+	require.NoError(t, err)
+	assert.Equal(t, plaintext, got)
+}
+
 // TestKEmptyKey_MatchesChromium pins the runtime-derived kEmptyKey to Chromium's
 // reference bytes in os_crypt_linux.cc; now cross-platform since kEmptyKey is
 // defined for every GOOS.
@@ -249,3 +264,13 @@ func TestDecryptChromium_ShortCiphertext(t *testing.T) {
 	_, err = DecryptChromiumCBC(aesKey, []byte("v11short"))
 	require.ErrorIs(t, err, errShortCiphertext)
 }
+
+func TestDecryptDPAPI_Offline(t *testing.T) {
+	t.Cleanup(func() { SetOfflineDPAPI(nil) })
+	SetOfflineDPAPI(func(blob []byte) ([]byte, error) {
+		return append([]byte("opened:"), blob...), nil
+	})
+	got, err := DecryptDPAPI([]byte("blob"))
+	require.NoError(t, err)
+	assert.Equal(t, []byte("opened:blob"), got)
+}
@@ -0,0 +1,198 @@
+package dpapi
+
+import (
+	"bytes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"strings"
+)
+
+// blobProviderLen is the dwVersion + guidProvider prefix that the blob signature does not cover.
+const blobProviderLen = 4 + 16
+
+// Blob is a parsed DPAPI_BLOB, the output of CryptProtectData.
+type Blob struct {
+	MasterKeyGUID string // GUID of the master key that sealed this blob
+
+	cipherAlg uint32
+	salt      []byte
+	hashAlg   uint32
+	hmacKey   []byte // HMac2Key: the signature's per-blob salt
+	data      []byte
+	sign      []byte
+	signed    []byte // bytes covered by the signature
+}
+
+// ParseBlob parses a DPAPI blob. Chromium stores these raw (pre-v80 values) or after a "DPAPI" prefix
+// (Local State os_crypt.encrypted_key); callers strip the prefix first.
+func ParseBlob(data []byte) (*Blob, error) {
+	r := &reader{buf: data}
+	r.bytes(blobProviderLen)
+	r.u32() // dwMasterKeyVersion
+	guid := r.bytes(16)
+	r.u32()      // dwFlags
+	r.lenBytes() // szDescription
+	b := &Blob{cipherAlg: r.u32()}
+	r.u32() // dwAlgCryptLen
+	b.salt = r.lenBytes()
+	r.lenBytes() // HMacKey (unused by CryptUnprotectData)
+	b.hashAlg = r.u32()
+	r.u32() // dwAlgHashLen
+	b.hmacKey = r.lenBytes()
+	b.data = r.lenBytes()
+	signedEnd := r.off
+	b.sign = r.lenBytes()
+	if r.err != nil {
+		return nil, fmt.Errorf("parse DPAPI blob: %w", r.err)
+	}
+	b.MasterKeyGUID = formatGUID(guid)
+	b.signed = data[blobProviderLen:signedEnd]
+	return b, nil
+}
+
+// Decrypt opens the blob with its 64-byte master key. entropy is the optional secondary entropy
+// passed to CryptProtectData (nil for Chromium).
+func (b *Blob) Decrypt(masterKey, entropy []byte) ([]byte, error) {
+	newHash, err := hashFor(b.hashAlg)
+	if err != nil {
+		return nil, err
+	}
+	alg, err := cipherFor(b.cipherAlg)
+	if err != nil {
+		return nil, err
+	}
+	keyHash := sha1.Sum(masterKey)
+
+	sig := hmac.New(newHash, keyHash[:])
+	sig.Write(b.hmacKey)
+	sig.Write(entropy)
+	sig.Write(b.signed)
+	if !hmac.Equal(sig.Sum(nil), b.sign) {
+		return nil, errBadSignature
+	}
+
+	session := hmac.New(newHash, keyHash[:])
+	session.Write(b.salt)
+	session.Write(entropy)
+	key := expandSessionKey(session.Sum(nil), newHash().BlockSize(), alg.keyLen, newHash)
+
+	plain, err := cbcDecrypt(alg, key[:alg.keyLen], make([]byte, alg.blockSize), b.data)
+	if err != nil {
+		return nil, err
+	}
+	return unpad(plain, alg.blockSize)
+}
+
+// expandSessionKey is CryptDeriveKey: a session key shorter than the cipher key is stretched to
+// H(ipad ^ key) || H(opad ^ key). DES parity bits are left alone — Go's DES ignores them.
+func expandSessionKey(session []byte, blockSize, keyLen int, newHash func() hash.Hash) []byte {
+	if len(session) >= keyLen {
+		return session
+	}
+	ipad := make([]byte, blockSize)
+	opad := make([]byte, blockSize)
+	for i := range ipad {
+		var k byte
+		if i < len(session) {
+			k = session[i]
+		}
+		ipad[i] = k ^ 0x36
+		opad[i] = k ^ 0x5c
+	}
+	inner := newHash()
+	inner.Write(ipad)
+	outer := newHash()
+	outer.Write(opad)
+	return append(inner.Sum(nil), outer.Sum(nil)...)
+}
+
+// Protect is the inverse of Decrypt: it seals plaintext under masterKey the way CryptProtectData does
+// on Windows 10 (AES-256, SHA-512), referencing the key by guid. It exists to build fixtures for
+// offline restores and tests; nothing in the extraction path writes DPAPI blobs.
+func Protect(guid string, masterKey, plaintext []byte) ([]byte, error) {
+	return protect(guid, masterKey, plaintext, nil, calgSHA512, calgAES256)
+}
+
+func protect(guid string, masterKey, plaintext, entropy []byte, hashAlg, cipherAlg uint32) ([]byte, error) {
+	newHash, err := hashFor(hashAlg)
+	if err != nil {
+		return nil, err
+	}
+	alg, err := cipherFor(cipherAlg)
+	if err != nil {
+		return nil, err
+	}
+	rawGUID, err := parseGUID(guid)
+	if err != nil {
+		return nil, err
+	}
+	random := make([]byte, 16+32+32)
+	if _, err := rand.Read(random); err != nil {
+		return nil, err
+	}
+	provider, salt, hmacKey := random[:16], random[16:48], random[48:]
+	keyHash := sha1.Sum(masterKey)
+
+	session := hmac.New(newHash, keyHash[:])
+	session.Write(salt)
+	session.Write(entropy)
+	key := expandSessionKey(session.Sum(nil), newHash().BlockSize(), alg.keyLen, newHash)
+	block, err := alg.newBlock(key[:alg.keyLen])
+	if err != nil {
+		return nil, err
+	}
+	pad := alg.blockSize - len(plaintext)%alg.blockSize
+	data := append(append([]byte{}, plaintext...), bytes.Repeat([]byte{byte(pad)}, pad)...)
+	cipher.NewCBCEncrypter(block, make([]byte, alg.blockSize)).CryptBlocks(data, data)
+
+	le32 := binary.LittleEndian.AppendUint32
+	field := func(b, v []byte) []byte { return append(le32(b, uint32(len(v))), v...) }
+	out := le32(nil, 1)
+	out = append(out, provider...)
+	out = le32(out, 1)
+	out = append(out, rawGUID...)
+	out = le32(out, 0)
+	out = field(out, nil) // description
+	out = le32(out, cipherAlg)
+	out = le32(out, uint32(alg.keyLen*8))
+	out = field(out, salt)
+	out = field(out, nil)
+	out = le32(out, hashAlg)
+	out = le32(out, uint32(newHash().Size()*8))
+	out = field(out, hmacKey)
+	out = field(out, data)
+
+	sig := hmac.New(newHash, keyHash[:])
+	sig.Write(hmacKey)
+	sig.Write(entropy)
+	sig.Write(out[blobProviderLen:])
+	return field(out, sig.Sum(nil)), nil
+}
+
+// parseGUID is the inverse of formatGUID.
+func parseGUID(guid string) ([]byte, error) {
+	raw, err := hex.DecodeString(strings.ReplaceAll(guid, "-", ""))
+	if err != nil || len(raw) != 16 || !isGUID(guid) {
+		return nil, fmt.Errorf("dpapi: invalid GUID %q", guid)
+	}
+	out := make([]byte, 16)
+	binary.LittleEndian.PutUint32(out[0:], binary.BigEndian.Uint32(raw[0:]))
+	binary.LittleEndian.PutUint16(out[4:], binary.BigEndian.Uint16(raw[4:]))
+	binary.LittleEndian.PutUint16(out[6:], binary.BigEndian.Uint16(raw[6:]))
+	copy(out[8:], raw[8:])
+	return out, nil
+}
+
+func unpad(plain []byte, blockSize int) ([]byte, error) {
+	n := int(plain[len(plain)-1])
+	if n == 0 || n > blockSize || n > len(plain) {
+		return nil, fmt.Errorf("dpapi: invalid padding")
+	}
+	return plain[:len(plain)-n], nil
+}
@@ -0,0 +1,79 @@
+package dpapi
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBlob_DecryptAlgorithms(t *testing.T) {
+	tests := []struct {
+		name      string
+		hashAlg   uint32
+		cipherAlg uint32
+	}{
+		{"win10 sha512 aes256", calgSHA512, calgAES256},
+		{"legacy sha1 3des", calgSHA1, calg3DES},
+	}
+	plaintext := []byte("chromium os_crypt key material!!")
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			masterKey := randBytes(t, masterKeySize)
+			blob, err := ParseBlob(sealBlob(t, testGUID, masterKey, plaintext, nil, tt.hashAlg, tt.cipherAlg))
+			require.NoError(t, err)
+			assert.Equal(t, testGUID, blob.MasterKeyGUID)
+
+			got, err := blob.Decrypt(masterKey, nil)
+			require.NoError(t, err)
+			assert.Equal(t, plaintext, got)
+
+			_, err = blob.Decrypt(randBytes(t, masterKeySize), nil)
+			assert.ErrorIs(t, err, errBadSignature)
+		})
+	}
+}
+
+func TestBlob_DecryptEntropy(t *testing.T) {
+	masterKey := randBytes(t, masterKeySize)
+	entropy := []byte("secondary entropy")
+	blob, err := ParseBlob(sealBlob(t, testGUID, masterKey, []byte("v"), entropy, calgSHA512, calgAES256))
+	require.NoError(t, err)
+
+	got, err := blob.Decrypt(masterKey, entropy)
+	require.NoError(t, err)
+	assert.Equal(t, []byte("v"), got)
+
+	_, err = blob.Decrypt(masterKey, nil)
+	assert.ErrorIs(t, err, errBadSignature)
+}
+
+func TestParseBlob_Truncated(t *testing.T) {
+	data := sealBlob(t, testGUID, randBytes(t, masterKeySize), []byte("v"), nil, calgSHA512, calgAES256)
+	for _, n := range []int{0, 10, 40, len(data) - 1} {
+		_, err := ParseBlob(data[:n])
+		assert.ErrorIs(t, err, errTruncated, "len %d", n)
+	}
+}
+
+func TestProtect_RoundTrip(t *testing.T) {
+	masterKey := randBytes(t, masterKeySize)
+	data, err := Protect(testGUID, masterKey, []byte("sealed"))
+	require.NoError(t, err)
+
+	ring := NewKeyRing()
+	ring.Add(testGUID, masterKey)
+	got, err := ring.Decrypt(data)
+	require.NoError(t, err)
+	assert.Equal(t, []byte("sealed"), got)
+
+	_, err = Protect("not-a-guid", masterKey, nil)
+	assert.Error(t, err)
+}
+
+func TestFormatGUID(t *testing.T) {
+	raw, err := parseGUID(testGUID)
+	require.NoError(t, err)
+	assert.Equal(t, []byte{0xd4, 0xc3, 0xb2, 0xa1, 0x02, 0x01, 0x04, 0x03}, raw[:8])
+	assert.Equal(t, testGUID, formatGUID(raw))
+}
@@ -0,0 +1,75 @@
+package dpapi
+
+import (
+	"crypto/hmac"
+	"crypto/rsa"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/binary"
+	"unicode/utf16"
+
+	"github.com/moond4rk/hackbrowserdata/crypto"
+)
+
+// Credentials unlock a user's master-key files. Set any combination of Password, NTHash and
+// BackupKey; every candidate they yield is tried against each file. SID is required for the
+// password and NT-hash paths and ignored by the domain backup key.
+type Credentials struct {
+	SID       string          // account SID, e.g. "S-1-5-21-…-1001" (the Protect subdir name)
+	Password  string          // account logon password
+	NTHash    []byte          // 16-byte NT hash, for when only the hash is known (domain accounts, pass-the-hash)
+	BackupKey *rsa.PrivateKey // domain DPAPI backup key (see ParsePVK)
+}
+
+// preKeys returns the HMAC-SHA1 pre-keys Windows derives from the account secret, one per scheme:
+// SHA1(password) for local accounts, the NT hash for domain accounts, and the PBKDF2-hardened NT
+// hash Windows 10 1607+ uses for Protected Users. The right one is whichever passes the master-key
+// HMAC, so all of them are returned.
+func (c Credentials) preKeys() [][]byte {
+	if c.SID == "" {
+		return nil
+	}
+	var hashes [][]byte
+	var ntHashes [][]byte
+	if c.Password != "" {
+		pw := utf16LE(c.Password)
+		sum := sha1.Sum(pw)
+		nt := md4Sum(pw)
+		hashes = append(hashes, sum[:])
+		ntHashes = append(ntHashes, nt[:])
+	}
+	if len(c.NTHash) > 0 {
+		ntHashes = append(ntHashes, c.NTHash)
+	}
+
+	sid := utf16LE(c.SID)
+	for _, nt := range ntHashes {
+		hashes = append(hashes, nt, protectedUserHash(nt, sid))
+	}
+
+	sidZ := utf16LE(c.SID + "\x00")
+	keys := make([][]byte, 0, len(hashes))
+	for _, h := range hashes {
+		mac := hmac.New(sha1.New, h)
+		mac.Write(sidZ)
+		keys = append(keys, mac.Sum(nil))
+	}
+	return keys
+}
+
+// protectedUserHash is the Protected Users hardening of the NT hash:
+// PBKDF2-SHA256(PBKDF2-SHA256(nt, sid, 10000), sid, 1) truncated to 16 bytes.
+func protectedUserHash(nt, sid []byte) []byte {
+	k := crypto.PBKDF2Key(nt, sid, 10000, sha256.Size, sha256.New)
+	return crypto.PBKDF2Key(k, sid, 1, sha256.Size, sha256.New)[:16]
+}
+
+// utf16LE encodes s as little-endian UTF-16 without a BOM, the encoding Windows hashes.
+func utf16LE(s string) []byte {
+	units := utf16.Encode([]rune(s))
+	out := make([]byte, 0, len(units)*2)
+	for _, u := range units {
+		out = binary.LittleEndian.AppendUint16(out, u)
+	}
+	return out
+}
@@ -0,0 +1,155 @@
+package dpapi
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// KeyRing maps master-key GUIDs to decrypted 64-byte master keys.
+type KeyRing struct {
+	keys map[string][]byte
+}
+
+// NewKeyRing returns an empty key ring.
+func NewKeyRing() *KeyRing {
+	return &KeyRing{keys: make(map[string][]byte)}
+}
+
+// Add registers a decrypted master key under its GUID.
+func (k *KeyRing) Add(guid string, masterKey []byte) {
+	k.keys[strings.ToLower(guid)] = masterKey
+}
+
+// Len reports how many master keys the ring holds.
+func (k *KeyRing) Len() int { return len(k.keys) }
+
+// Decrypt parses a DPAPI blob and opens it with the master key it names; it has the same shape as
+// crypto.DecryptDPAPI so it can stand in for the host API.
+func (k *KeyRing) Decrypt(data []byte) ([]byte, error) {
+	blob, err := ParseBlob(data)
+	if err != nil {
+		return nil, err
+	}
+	masterKey, ok := k.keys[blob.MasterKeyGUID]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", errUnknownMasterKey, blob.MasterKeyGUID)
+	}
+	return blob.Decrypt(masterKey, nil)
+}
+
+// LoadKeyRing decrypts every master-key file in a user's Protect/<SID> directory. dir may also be the
+// Protect directory itself when it holds exactly one SID subdirectory. An empty creds.SID is taken
+// from the directory name. Files that no credential opens are skipped; it is an error only when none
+// open.
+func LoadKeyRing(dir string, creds Credentials) (*KeyRing, error) {
+	dir, err := resolveSIDDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	if creds.SID == "" {
+		creds.SID = filepath.Base(dir)
+	}
+	preKeys := creds.preKeys()
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	ring := NewKeyRing()
+	var errs []error
+	for _, e := range entries {
+		if e.IsDir() || !isGUID(e.Name()) {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(dir, e.Name()))
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		f, err := ParseMasterKeyFile(data)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", e.Name(), err))
+			continue
+		}
+		key, err := unlock(f, preKeys, creds)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", e.Name(), err))
+			continue
+		}
+		ring.Add(f.GUID, key)
+	}
+	if ring.Len() == 0 {
+		if len(errs) == 0 {
+			return nil, fmt.Errorf("no master key files in %s", dir)
+		}
+		return nil, fmt.Errorf("no master key in %s could be decrypted: %w", dir, errors.Join(errs...))
+	}
+	return ring, nil
+}
+
+// unlock tries each password-derived pre-key, then the domain backup key.
+func unlock(f *MasterKeyFile, preKeys [][]byte, creds Credentials) ([]byte, error) {
+	err := errBadCredentials
+	if f.masterKey != nil {
+		for _, pk := range preKeys {
+			key, e := f.Decrypt(pk)
+			if e == nil {
+				return key, nil
+			}
+			err = e
+		}
+	}
+	if creds.BackupKey != nil && f.domainKey != nil {
+		return f.DecryptWithBackupKey(creds.BackupKey)
+	}
+	return nil, err
+}
+
+// resolveSIDDir descends from a Protect directory into its only SID subdirectory.
+func resolveSIDDir(dir string) (string, error) {
+	if strings.HasPrefix(filepath.Base(dir), "S-1-") {
+		return dir, nil
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return "", err
+	}
+	var sids []string
+	for _, e := range entries {
+		if e.IsDir() && strings.HasPrefix(e.Name(), "S-1-") {
+			sids = append(sids, e.Name())
+		}
+	}
+	switch len(sids) {
+	case 0:
+		return dir, nil
+	case 1:
+		return filepath.Join(dir, sids[0]), nil
+	default:
+		return "", fmt.Errorf("%s holds several SID directories (%s); point at one", dir, strings.Join(sids, ", "))
+	}
+}
+
+// isGUID reports whether name has the 8-4-4-4-12 hex shape of a master-key file name, which filters
+// out the Preferred and CREDHIST files that share the directory.
+func isGUID(name string) bool {
+	if len(name) != 36 {
+		return false
+	}
+	for i, c := range name {
+		switch i {
+		case 8, 13, 18, 23:
+			if c != '-' {
+				return false
+			}
+		default:
+			if !strings.ContainsRune("0123456789abcdefABCDEF", c) {
+				return false
+			}
+		}
+	}
+	return true
+}
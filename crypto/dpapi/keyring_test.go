@@ -0,0 +1,78 @@
+package dpapi
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeProtectDir lays out Protect/<SID>/ with one master key sealed under password, plus the
+// Preferred and CREDHIST files Windows keeps beside it. It returns the Protect dir.
+func writeProtectDir(t *testing.T, password string, masterKey []byte) string {
+	t.Helper()
+	protect := filepath.Join(t.TempDir(), "Protect")
+	sidDir := filepath.Join(protect, testSID)
+	require.NoError(t, os.MkdirAll(sidDir, 0o755))
+
+	preKey := Credentials{SID: testSID, Password: password}.preKeys()[0]
+	file := sealMasterKeyFile(t, testGUID, preKey, masterKey, calgSHA512, calgAES256, nil)
+	require.NoError(t, os.WriteFile(filepath.Join(sidDir, testGUID), file, 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(sidDir, "Preferred"), make([]byte, 24), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(sidDir, "CREDHIST"), make([]byte, 24), 0o600))
+	return protect
+}
+
+func TestLoadKeyRing_DecryptsBlob(t *testing.T) {
+	masterKey := randBytes(t, masterKeySize)
+	protect := writeProtectDir(t, "Winter2024!", masterKey)
+	blob := sealBlob(t, testGUID, masterKey, []byte("secret"), nil, calgSHA512, calgAES256)
+
+	for _, dir := range []string{protect, filepath.Join(protect, testSID)} {
+		ring, err := LoadKeyRing(dir, Credentials{Password: "Winter2024!"})
+		require.NoError(t, err, "dir %s", dir)
+		assert.Equal(t, 1, ring.Len())
+
+		got, err := ring.Decrypt(blob)
+		require.NoError(t, err)
+		assert.Equal(t, []byte("secret"), got)
+	}
+}
+
+func TestLoadKeyRing_WrongCredentials(t *testing.T) {
+	protect := writeProtectDir(t, "Winter2024!", randBytes(t, masterKeySize))
+
+	_, err := LoadKeyRing(protect, Credentials{Password: "Summer2024!"})
+	require.Error(t, err)
+	assert.ErrorIs(t, err, errBadCredentials)
+
+	_, err = LoadKeyRing(t.TempDir(), Credentials{SID: testSID, Password: "x"})
+	assert.ErrorContains(t, err, "no master key files")
+}
+
+func TestLoadKeyRing_SeveralSIDs(t *testing.T) {
+	protect := writeProtectDir(t, "pw", randBytes(t, masterKeySize))
+	require.NoError(t, os.Mkdir(filepath.Join(protect, "S-1-5-18"), 0o755))
+
+	_, err := LoadKeyRing(protect, Credentials{Password: "pw"})
+	assert.ErrorContains(t, err, "several SID directories")
+}
+
+func TestKeyRing_UnknownMasterKey(t *testing.T) {
+	ring := NewKeyRing()
+	ring.Add("00000000-0000-0000-0000-000000000000", randBytes(t, masterKeySize))
+
+	blob := sealBlob(t, testGUID, randBytes(t, masterKeySize), []byte("v"), nil, calgSHA512, calgAES256)
+	_, err := ring.Decrypt(blob)
+	assert.ErrorIs(t, err, errUnknownMasterKey)
+}
+
+func TestIsGUID(t *testing.T) {
+	assert.True(t, isGUID(testGUID))
+	assert.True(t, isGUID("A1B2C3D4-0102-0304-0506-0708090A0B0C"))
+	assert.False(t, isGUID("Preferred"))
+	assert.False(t, isGUID("a1b2c3d4x0102-0304-0506-0708090a0b0c"))
+	assert.False(t, isGUID("g1b2c3d4-0102-0304-0506-0708090a0b0c"))
+}
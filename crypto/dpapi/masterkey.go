@@ -0,0 +1,177 @@
+package dpapi
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/rsa"
+	"encoding/binary"
+	"fmt"
+	"strings"
+	"unicode/utf16"
+
+	"github.com/moond4rk/hackbrowserdata/crypto"
+)
+
+// masterKeySize is the length of every decrypted DPAPI master key.
+const masterKeySize = 64
+
+// MasterKeyFile is one parsed Protect/<SID>/<GUID> file. Only the sections offline decryption can use
+// are kept: the password-protected master key and the domain-backup copy.
+type MasterKeyFile struct {
+	GUID string // lower-case GUID string, matching the blobs that reference this key
+
+	masterKey *passwordKey // nil when the file carries no master-key section
+	domainKey []byte       // RSA-encrypted secret; nil on local accounts
+}
+
+// passwordKey is the MASTERKEY section: the 64-byte key sealed under a key derived from the user's pre-key.
+type passwordKey struct {
+	salt      []byte
+	rounds    uint32
+	hashAlg   uint32
+	cipherAlg uint32
+	data      []byte
+}
+
+// ParseMasterKeyFile parses a master-key file: a 128-byte header followed by the master-key, local
+// backup, CREDHIST and domain-key sections, in that order.
+func ParseMasterKeyFile(data []byte) (*MasterKeyFile, error) {
+	r := &reader{buf: data}
+	r.u32()    // dwVersion
+	r.bytes(8) // reserved
+	rawGUID := r.bytes(72)
+	r.bytes(12) // unused, unused, dwFlags
+	masterLen := r.u64()
+	backupLen := r.u64()
+	credHistLen := r.u64()
+	domainLen := r.u64()
+
+	master := r.bytes(int(masterLen))
+	r.bytes(int(backupLen))
+	r.bytes(int(credHistLen))
+	domain := r.bytes(int(domainLen))
+	if r.err != nil {
+		return nil, fmt.Errorf("parse master key file: %w", r.err)
+	}
+
+	f := &MasterKeyFile{GUID: decodeUTF16GUID(rawGUID)}
+	if len(master) > 0 {
+		mk, err := parsePasswordKey(master)
+		if err != nil {
+			return nil, err
+		}
+		f.masterKey = mk
+	}
+	if len(domain) > 0 {
+		dk, err := parseDomainKey(domain)
+		if err != nil {
+			return nil, err
+		}
+		f.domainKey = dk
+	}
+	return f, nil
+}
+
+func parsePasswordKey(section []byte) (*passwordKey, error) {
+	r := &reader{buf: section}
+	r.u32() // dwVersion
+	k := &passwordKey{
+		salt:      r.bytes(16),
+		rounds:    r.u32(),
+		hashAlg:   r.u32(),
+		cipherAlg: r.u32(),
+	}
+	k.data = r.bytes(len(section) - r.off)
+	if r.err != nil {
+		return nil, fmt.Errorf("parse master key section: %w", r.err)
+	}
+	return k, nil
+}
+
+// parseDomainKey returns the DOMAINKEY section's RSA-encrypted secret.
+func parseDomainKey(section []byte) ([]byte, error) {
+	r := &reader{buf: section}
+	r.u32() // dwVersion
+	secretLen := r.u32()
+	r.u32()     // cbAccessCheck
+	r.bytes(16) // guidKey: the backup key that sealed this secret
+	secret := r.bytes(int(secretLen))
+	if r.err != nil {
+		return nil, fmt.Errorf("parse domain key section: %w", r.err)
+	}
+	return secret, nil
+}
+
+// Decrypt unseals the master key with a pre-key from Credentials. It returns errBadCredentials when
+// the embedded HMAC does not verify.
+func (f *MasterKeyFile) Decrypt(preKey []byte) ([]byte, error) {
+	k := f.masterKey
+	if k == nil {
+		return nil, fmt.Errorf("master key %s has no password-protected section", f.GUID)
+	}
+	newHash, err := hashFor(k.hashAlg)
+	if err != nil {
+		return nil, err
+	}
+	alg, err := cipherFor(k.cipherAlg)
+	if err != nil {
+		return nil, err
+	}
+	derived := crypto.PBKDF2Key(preKey, k.salt, int(k.rounds), alg.keyLen+alg.blockSize, newHash)
+	plain, err := cbcDecrypt(alg, derived[:alg.keyLen], derived[alg.keyLen:], k.data)
+	if err != nil {
+		return nil, err
+	}
+
+	// Plaintext layout: hmacSalt(16) | hmac(hashLen) | … | masterKey(64), the key flush with the end.
+	hashLen := newHash().Size()
+	if len(plain) < 16+hashLen+masterKeySize {
+		return nil, errTruncated
+	}
+	hmacSalt := plain[:16]
+	want := plain[16 : 16+hashLen]
+	key := plain[len(plain)-masterKeySize:]
+
+	mac := hmac.New(newHash, preKey)
+	mac.Write(hmacSalt)
+	check := hmac.New(newHash, mac.Sum(nil))
+	check.Write(key)
+	if !hmac.Equal(check.Sum(nil), want) {
+		return nil, errBadCredentials
+	}
+	return bytes.Clone(key), nil
+}
+
+// DecryptWithBackupKey unseals the master key from its domain-backup section using the domain's
+// DPAPI backup private key. The secret is stored byte-reversed (CryptoAPI little-endian) and decrypts
+// to DPAPI_DOMAIN_RSA_MASTER_KEY: cbMasterKey, cbSuppKey, then the key bytes.
+func (f *MasterKeyFile) DecryptWithBackupKey(priv *rsa.PrivateKey) ([]byte, error) {
+	if f.domainKey == nil {
+		return nil, fmt.Errorf("master key %s has no domain backup section", f.GUID)
+	}
+	ct := make([]byte, len(f.domainKey))
+	for i, b := range f.domainKey {
+		ct[len(ct)-1-i] = b
+	}
+	plain, err := rsa.DecryptPKCS1v15(nil, priv, ct)
+	if err != nil {
+		return nil, fmt.Errorf("domain backup key: %w", err)
+	}
+	if len(plain) < 8 {
+		return nil, errTruncated
+	}
+	keyLen := int(binary.LittleEndian.Uint32(plain[0:4]))
+	if keyLen != masterKeySize || len(plain) < 8+keyLen {
+		return nil, fmt.Errorf("domain backup key: unexpected master key length %d", keyLen)
+	}
+	return bytes.Clone(plain[8 : 8+keyLen]), nil
+}
+
+// decodeUTF16GUID decodes the header's fixed 36-character UTF-16 GUID.
+func decodeUTF16GUID(raw []byte) string {
+	units := make([]uint16, 0, len(raw)/2)
+	for i := 0; i+1 < len(raw); i += 2 {
+		units = append(units, binary.LittleEndian.Uint16(raw[i:]))
+	}
+	return strings.ToLower(strings.TrimRight(string(utf16.Decode(units)), "\x00"))
+}
@@ -0,0 +1,84 @@
+package dpapi
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMasterKeyFile_DecryptAlgorithms(t *testing.T) {
+	tests := []struct {
+		name      string
+		hashAlg   uint32
+		cipherAlg uint32
+	}{
+		{"win10 sha512 aes256", calgSHA512, calgAES256},
+		{"xp hmac-sha1 3des", calgHMAC, calg3DES},
+		{"sha1 aes128", calgSHA1, calgAES128},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			creds := Credentials{SID: testSID, Password: "Winter2024!"}
+			preKey := creds.preKeys()[0]
+			masterKey := randBytes(t, masterKeySize)
+
+			f, err := ParseMasterKeyFile(sealMasterKeyFile(t, testGUID, preKey, masterKey, tt.hashAlg, tt.cipherAlg, nil))
+			require.NoError(t, err)
+			assert.Equal(t, testGUID, f.GUID)
+
+			got, err := f.Decrypt(preKey)
+			require.NoError(t, err)
+			assert.Equal(t, masterKey, got)
+
+			_, err = f.Decrypt(Credentials{SID: testSID, Password: "wrong"}.preKeys()[0])
+			assert.ErrorIs(t, err, errBadCredentials)
+		})
+	}
+}
+
+func TestMasterKeyFile_DecryptWithBackupKey(t *testing.T) {
+	priv, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	masterKey := randBytes(t, masterKeySize)
+	preKey := Credentials{SID: testSID, Password: "unknown"}.preKeys()[0]
+
+	f, err := ParseMasterKeyFile(sealMasterKeyFile(t, testGUID, preKey, masterKey, calgSHA512, calgAES256, &priv.PublicKey))
+	require.NoError(t, err)
+	got, err := f.DecryptWithBackupKey(priv)
+	require.NoError(t, err)
+	assert.Equal(t, masterKey, got)
+
+	local, err := ParseMasterKeyFile(sealMasterKeyFile(t, testGUID, preKey, masterKey, calgSHA512, calgAES256, nil))
+	require.NoError(t, err)
+	_, err = local.DecryptWithBackupKey(priv)
+	assert.Error(t, err, "a local account has no domain backup section")
+}
+
+func TestParseMasterKeyFile_Truncated(t *testing.T) {
+	preKey := Credentials{SID: testSID, Password: "pw"}.preKeys()[0]
+	data := sealMasterKeyFile(t, testGUID, preKey, randBytes(t, masterKeySize), calgSHA512, calgAES256, nil)
+
+	_, err := ParseMasterKeyFile(data[:100])
+	require.ErrorIs(t, err, errTruncated)
+	_, err = ParseMasterKeyFile(data[:len(data)-1])
+	require.ErrorIs(t, err, errTruncated)
+}
+
+func TestCredentials_PreKeys(t *testing.T) {
+	assert.Empty(t, Credentials{Password: "pw"}.preKeys(), "password schemes need the SID")
+
+	// Password: SHA1 (local), NT hash (domain), hardened NT hash (Protected Users).
+	pw := Credentials{SID: testSID, Password: "password"}.preKeys()
+	assert.Len(t, pw, 3)
+	for _, k := range pw {
+		assert.Len(t, k, 20)
+	}
+
+	// The NT hash alone reproduces the password's two NT-derived pre-keys.
+	nt := md4Sum(utf16LE("password"))
+	hashOnly := Credentials{SID: testSID, NTHash: nt[:]}.preKeys()
+	assert.Equal(t, pw[1:], hashOnly)
+}
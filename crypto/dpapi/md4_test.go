@@ -0,0 +1,33 @@
+package dpapi
+
+import (
+	"encoding/hex"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMD4Sum_RFC1320(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"", "31d6cfe0d16ae931b73c59d7e0c089c0"},
+		{"a", "bde52cb31de33e46245e05fbdbd6fb24"},
+		{"abc", "a448017aaf21d8525fc10ae87aa6729d"},
+		{"message digest", "d9130a8164549fe818874806e1c7014b"},
+		{"abcdefghijklmnopqrstuvwxyz", "d79e1c308aa5bbcdeea8ed63df412da9"},
+		{
+			"12345678901234567890123456789012345678901234567890123456789012345678901234567890",
+			"e33b4ddc9c38f2199c3e7b164fcc0536",
+		},
+	}
+	for _, tt := range tests {
+		sum := md4Sum([]byte(tt.in))
+		assert.Equal(t, tt.want, hex.EncodeToString(sum[:]), "MD4(%q)", tt.in)
+	}
+}
+
+func TestMD4Sum_NTHash(t *testing.T) {
+	sum := md4Sum(utf16LE("password"))
+	assert.Equal(t, "8846f7eaee8fb117ad06bdd830b7586c", hex.EncodeToString(sum[:]))
+}
@@ -0,0 +1,79 @@
+package dpapi
+
+import (
+	"crypto/rsa"
+	"fmt"
+	"math/big"
+)
+
+const (
+	pvkMagic        = 0xb0b5f11e
+	rsa2Magic       = 0x32415352 // "RSA2"
+	privateKeyBlob  = 0x07
+	pvkHeaderLength = 24
+)
+
+// ParsePVK loads a domain DPAPI backup key exported as an unencrypted .pvk file (mimikatz
+// "lsadump::backupkeys /export", impacket dpapi.py backupkeys). The file is a PVK header followed by a
+// CryptoAPI PRIVATEKEYBLOB, whose integers are little-endian.
+func ParsePVK(data []byte) (*rsa.PrivateKey, error) {
+	r := &reader{buf: data}
+	magic := r.u32()
+	r.u32() // reserved
+	r.u32() // keyspec
+	encrypted := r.u32()
+	saltLen := r.u32()
+	r.u32() // cbPvk
+	if r.err != nil {
+		return nil, fmt.Errorf("parse PVK: %w", r.err)
+	}
+	if magic != pvkMagic {
+		return nil, fmt.Errorf("parse PVK: bad magic 0x%x", magic)
+	}
+	if encrypted != 0 {
+		return nil, fmt.Errorf("parse PVK: password-protected PVK files are not supported")
+	}
+	r.bytes(int(saltLen))
+
+	blobType := r.bytes(1)
+	r.bytes(7) // bVersion, reserved, aiKeyAlg
+	rsaMagic := r.u32()
+	bitLen := int(r.u32())
+	pubExp := r.u32()
+	if r.err != nil {
+		return nil, fmt.Errorf("parse PVK: %w", r.err)
+	}
+	if blobType[0] != privateKeyBlob || rsaMagic != rsa2Magic {
+		return nil, fmt.Errorf("parse PVK: not an RSA PRIVATEKEYBLOB")
+	}
+
+	full, half := bitLen/8, bitLen/16
+	n := leInt(r.bytes(full))
+	p := leInt(r.bytes(half))
+	q := leInt(r.bytes(half))
+	r.bytes(half * 3) // exponent1, exponent2, coefficient: recomputed by Precompute
+	d := leInt(r.bytes(full))
+	if r.err != nil {
+		return nil, fmt.Errorf("parse PVK: %w", r.err)
+	}
+
+	key := &rsa.PrivateKey{
+		PublicKey: rsa.PublicKey{N: n, E: int(pubExp)},
+		D:         d,
+		Primes:    []*big.Int{p, q},
+	}
+	if err := key.Validate(); err != nil {
+		return nil, fmt.Errorf("parse PVK: %w", err)
+	}
+	key.Precompute()
+	return key, nil
+}
+
+// leInt decodes a little-endian unsigned integer.
+func leInt(b []byte) *big.Int {
+	be := make([]byte, len(b))
+	for i, v := range b {
+		be[len(b)-1-i] = v
+	}
+	return new(big.Int).SetBytes(be)
+}
@@ -0,0 +1,75 @@
+package dpapi
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/binary"
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// marshalPVK writes priv as an unencrypted PVK file holding a PRIVATEKEYBLOB.
+func marshalPVK(priv *rsa.PrivateKey) []byte {
+	bits := priv.N.BitLen()
+	full, half := bits/8, bits/16
+
+	var out []byte
+	out = le32(out, pvkMagic)
+	out = le32(out, 0)
+	out = le32(out, 1) // AT_KEYEXCHANGE
+	out = le32(out, 0) // unencrypted
+	out = le32(out, 0) // no salt
+	out = le32(out, uint32(8+12+full*2+half*5))
+	out = append(out, privateKeyBlob, 2, 0, 0)
+	out = binary.LittleEndian.AppendUint32(out, 0xa400) // CALG_RSA_KEYX
+	out = le32(out, rsa2Magic)
+	out = le32(out, uint32(bits))
+	out = le32(out, uint32(priv.E))
+	for _, v := range []struct {
+		n    *big.Int
+		size int
+	}{
+		{priv.N, full}, {priv.Primes[0], half}, {priv.Primes[1], half},
+		{priv.Precomputed.Dp, half}, {priv.Precomputed.Dq, half}, {priv.Precomputed.Qinv, half},
+		{priv.D, full},
+	} {
+		be := v.n.FillBytes(make([]byte, v.size))
+		for i := len(be) - 1; i >= 0; i-- {
+			out = append(out, be[i])
+		}
+	}
+	return out
+}
+
+func TestParsePVK_RoundTrip(t *testing.T) {
+	priv, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	got, err := ParsePVK(marshalPVK(priv))
+	require.NoError(t, err)
+	assert.Equal(t, 0, priv.N.Cmp(got.N))
+	assert.Equal(t, 0, priv.D.Cmp(got.D))
+	assert.Equal(t, priv.E, got.E)
+}
+
+func TestParsePVK_Rejects(t *testing.T) {
+	priv, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	data := marshalPVK(priv)
+
+	bad := append([]byte{}, data...)
+	bad[0] ^= 0xff
+	_, err = ParsePVK(bad)
+	assert.ErrorContains(t, err, "bad magic")
+
+	encrypted := append([]byte{}, data...)
+	binary.LittleEndian.PutUint32(encrypted[12:], 1)
+	_, err = ParsePVK(encrypted)
+	assert.ErrorContains(t, err, "password-protected")
+
+	_, err = ParsePVK(data[:len(data)-10])
+	assert.ErrorIs(t, err, errTruncated)
+}
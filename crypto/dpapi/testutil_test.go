@@ -0,0 +1,111 @@
+package dpapi
+
+import (
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/binary"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/moond4rk/hackbrowserdata/crypto"
+)
+
+const (
+	testSID  = "S-1-5-21-1004336348-1177238915-682003330-1001"
+	testGUID = "a1b2c3d4-0102-0304-0506-0708090a0b0c"
+)
+
+// sealMasterKeyFile builds a Protect/<SID>/<GUID> file the way Windows writes one, sealing masterKey
+// under preKey and, when backupKey is set, adding a domain-backup section for it.
+func sealMasterKeyFile(t *testing.T, guid string, preKey, masterKey []byte, hashAlg, cipherAlg uint32, backupKey *rsa.PublicKey) []byte {
+	t.Helper()
+	newHash, err := hashFor(hashAlg)
+	require.NoError(t, err)
+	alg, err := cipherFor(cipherAlg)
+	require.NoError(t, err)
+
+	salt := randBytes(t, 16)
+	const rounds = 100
+	derived := crypto.PBKDF2Key(preKey, salt, rounds, alg.keyLen+alg.blockSize, newHash)
+
+	hmacSalt := randBytes(t, 16)
+	mac := hmac.New(newHash, preKey)
+	mac.Write(hmacSalt)
+	check := hmac.New(newHash, mac.Sum(nil))
+	check.Write(masterKey)
+
+	plain := append(append([]byte{}, hmacSalt...), check.Sum(nil)...)
+	for (len(plain)+len(masterKey))%alg.blockSize != 0 {
+		plain = append(plain, 0)
+	}
+	plain = append(plain, masterKey...)
+	ct := cbcSeal(t, alg, derived[:alg.keyLen], derived[alg.keyLen:], plain)
+
+	var section []byte
+	section = le32(section, 2)
+	section = append(section, salt...)
+	section = le32(section, rounds)
+	section = le32(section, hashAlg)
+	section = le32(section, cipherAlg)
+	section = append(section, ct...)
+
+	var domain []byte
+	if backupKey != nil {
+		secret := le32(nil, masterKeySize)
+		secret = le32(secret, 0)
+		secret = append(secret, masterKey...)
+		enc, err := rsa.EncryptPKCS1v15(rand.Reader, backupKey, secret)
+		require.NoError(t, err)
+		reversed := make([]byte, len(enc))
+		for i, b := range enc {
+			reversed[len(enc)-1-i] = b
+		}
+		domain = le32(domain, 2)
+		domain = le32(domain, uint32(len(reversed)))
+		domain = le32(domain, 0)
+		domain = append(domain, make([]byte, 16)...)
+		domain = append(domain, reversed...)
+	}
+
+	var file []byte
+	file = le32(file, 2)
+	file = append(file, make([]byte, 8)...)
+	file = append(file, utf16LE(guid)...)
+	file = append(file, make([]byte, 12)...)
+	file = binary.LittleEndian.AppendUint64(file, uint64(len(section)))
+	file = binary.LittleEndian.AppendUint64(file, 0)
+	file = binary.LittleEndian.AppendUint64(file, 0)
+	file = binary.LittleEndian.AppendUint64(file, uint64(len(domain)))
+	file = append(file, section...)
+	return append(file, domain...)
+}
+
+// sealBlob is CryptProtectData with explicit algorithms: plaintext sealed under masterKey, referencing it by guid.
+func sealBlob(t *testing.T, guid string, masterKey, plaintext, entropy []byte, hashAlg, cipherAlg uint32) []byte {
+	t.Helper()
+	blob, err := protect(guid, masterKey, plaintext, entropy, hashAlg, cipherAlg)
+	require.NoError(t, err)
+	return blob
+}
+
+func cbcSeal(t *testing.T, alg cipherAlg, key, iv, plain []byte) []byte {
+	t.Helper()
+	block, err := alg.newBlock(key)
+	require.NoError(t, err)
+	out := make([]byte, len(plain))
+	cipher.NewCBCEncrypter(block, iv[:alg.blockSize]).CryptBlocks(out, plain)
+	return out
+}
+
+func randBytes(t *testing.T, n int) []byte {
+	t.Helper()
+	b := make([]byte, n)
+	_, err := rand.Read(b)
+	require.NoError(t, err)
+	return b
+}
+
+func le32(b []byte, v uint32) []byte { return binary.LittleEndian.AppendUint32(b, v) }
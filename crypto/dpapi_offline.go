@@ -0,0 +1,20 @@
+package crypto
+
+// offlineDPAPI, when set, opens DPAPI blobs in place of the host API — used when restoring a Windows
+// image whose master keys were unlocked offline (crypto/dpapi).
+var offlineDPAPI func([]byte) ([]byte, error)
+
+// SetOfflineDPAPI routes DecryptDPAPI through decrypt (e.g. dpapi.KeyRing.Decrypt) for the rest of the
+// process; nil restores the host API.
+func SetOfflineDPAPI(decrypt func([]byte) ([]byte, error)) {
+	offlineDPAPI = decrypt
+}
+
+// DecryptDPAPI decrypts a Windows DPAPI blob: through the offline key ring when one is set, otherwise
+// through the host's CryptUnprotectData (Windows only; other platforms return an error).
+func DecryptDPAPI(ciphertext []byte) ([]byte, error) {
+	if offlineDPAPI != nil {
+		return offlineDPAPI(ciphertext)
+	}
+	return decryptDPAPIHost(ciphertext)
+}
@@ -0,0 +1,31 @@
+package crypto
+
+import (
+	"crypto/hmac"
+	"hash"
+)
+
+// HKDFKey derives keyLen bytes from secret per RFC 5869 (HKDF-Extract then HKDF-Expand) using the
+// supplied hash function. A nil salt is replaced by a hash-length run of zeros, as the RFC specifies.
+// keyLen must not exceed 255 hash lengths.
+func HKDFKey(secret, salt, info []byte, keyLen int, h func() hash.Hash) []byte {
+	if salt == nil {
+		salt = make([]byte, h().Size())
+	}
+	extractor := hmac.New(h, salt)
+	extractor.Write(secret)
+	prk := extractor.Sum(nil)
+
+	expander := hmac.New(h, prk)
+	out := make([]byte, 0, keyLen+expander.Size())
+	var prev []byte
+	for counter := byte(1); len(out) < keyLen; counter++ {
+		expander.Reset()
+		expander.Write(prev)
+		expander.Write(info)
+		expander.Write([]byte{counter})
+		prev = expander.Sum(nil)
+		out = append(out, prev...)
+	}
+	return out[:keyLen]
+}
@@ -0,0 +1,48 @@
+package crypto
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Test vectors from RFC 5869 Appendix A (HKDF with SHA-256).
+// https://www.rfc-editor.org/rfc/rfc5869
+func TestHKDFKey_RFC5869(t *testing.T) {
+	ikm := bytes.Repeat([]byte{0x0b}, 22)
+	tests := []struct {
+		name string
+		salt string
+		info string
+		want string
+	}{
+		{
+			name: "basic",
+			salt: "000102030405060708090a0b0c",
+			info: "f0f1f2f3f4f5f6f7f8f9",
+			want: "3cb25f25faacd57a90434f64d0362f2a2d2d0a90cf1a5a4c5db02d56ecc4c5bf34007208d5b887185865",
+		},
+		{
+			name: "zero-length salt and info",
+			want: "8da4e775a563c18f715f802a063c5a31b8a11f5c5ee1879ec3454e5f3c738d2d9d201395faa4b61a96c8",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			salt, _ := hex.DecodeString(tt.salt)
+			info, _ := hex.DecodeString(tt.info)
+			got := HKDFKey(ikm, salt, info, 42, sha256.New)
+			assert.Equal(t, tt.want, hex.EncodeToString(got))
+		})
+	}
+}
+
+func TestHKDFKey_NilSaltMatchesZeroSalt(t *testing.T) {
+	secret := []byte("secret")
+	assert.Equal(t,
+		HKDFKey(secret, make([]byte, sha256.Size), nil, 32, sha256.New),
+		HKDFKey(secret, nil, nil, 32, sha256.New))
+}
@@ -0,0 +1,91 @@
+package keyring
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+const blowfishBlockSize = 8
+
+// blowfish is the Blowfish block cipher, decrypt direction only, which is all KWallet needs. Only
+// x/crypto ships it, so it is carried here rather than pulling that module in for one cipher.
+type blowfish struct {
+	p [18]uint32
+	s [4][256]uint32
+}
+
+// newBlowfish runs the key schedule for a 1..56 byte key.
+func newBlowfish(key []byte) (*blowfish, error) {
+	if len(key) == 0 || len(key) > 56 {
+		return nil, fmt.Errorf("blowfish: invalid key size %d", len(key))
+	}
+	c := &blowfish{p: blowfishP, s: blowfishS}
+	j := 0
+	for i := range c.p {
+		var word uint32
+		for k := 0; k < 4; k++ {
+			word = word<<8 | uint32(key[j])
+			j = (j + 1) % len(key)
+		}
+		c.p[i] ^= word
+	}
+	var l, r uint32
+	for i := 0; i < len(c.p); i += 2 {
+		l, r = c.encryptBlock(l, r)
+		c.p[i], c.p[i+1] = l, r
+	}
+	for box := range c.s {
+		for i := 0; i < 256; i += 2 {
+			l, r = c.encryptBlock(l, r)
+			c.s[box][i], c.s[box][i+1] = l, r
+		}
+	}
+	return c, nil
+}
+
+func (c *blowfish) f(x uint32) uint32 {
+	return ((c.s[0][x>>24] + c.s[1][x>>16&0xff]) ^ c.s[2][x>>8&0xff]) + c.s[3][x&0xff]
+}
+
+func (c *blowfish) encryptBlock(l, r uint32) (uint32, uint32) {
+	for i := 0; i < 16; i += 2 {
+		l ^= c.p[i]
+		r ^= c.f(l)
+		r ^= c.p[i+1]
+		l ^= c.f(r)
+	}
+	return r ^ c.p[17], l ^ c.p[16]
+}
+
+func (c *blowfish) decryptBlock(l, r uint32) (uint32, uint32) {
+	for i := 16; i > 0; i -= 2 {
+		l ^= c.p[i+1]
+		r ^= c.f(l)
+		r ^= c.p[i]
+		l ^= c.f(r)
+	}
+	return r ^ c.p[0], l ^ c.p[1]
+}
+
+// decrypt decrypts src in place, in CBC mode with a zero IV, or block by block (ECB) when ecb is set.
+func (c *blowfish) decrypt(src []byte, ecb bool) error {
+	if len(src)%blowfishBlockSize != 0 {
+		return fmt.Errorf("blowfish: ciphertext is not a multiple of the block size")
+	}
+	var prev [blowfishBlockSize]byte
+	for off := 0; off < len(src); off += blowfishBlockSize {
+		block := src[off : off+blowfishBlockSize]
+		var next [blowfishBlockSize]byte
+		copy(next[:], block)
+		l, r := c.decryptBlock(binary.BigEndian.Uint32(block), binary.BigEndian.Uint32(block[4:]))
+		binary.BigEndian.PutUint32(block, l)
+		binary.BigEndian.PutUint32(block[4:], r)
+		if !ecb {
+			for i := range block {
+				block[i] ^= prev[i]
+			}
+		}
+		prev = next
+	}
+	return nil
+}
@@ -0,0 +1,74 @@
+package keyring
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Schneier's published test vectors.
+func TestBlowfish_Vectors(t *testing.T) {
+	tests := []struct{ key, plain, cipher string }{
+		{"0000000000000000", "0000000000000000", "4ef997456198dd78"},
+		{"ffffffffffffffff", "ffffffffffffffff", "51866fd5b85ecb8a"},
+		{"0123456789abcdef", "1111111111111111", "61f9c3802281b096"},
+		{"fedcba9876543210", "0123456789abcdef", "0aceab0fc6a0a28d"},
+	}
+	for _, tt := range tests {
+		c, err := newBlowfish(mustHex(t, tt.key))
+		require.NoError(t, err)
+		plain := mustHex(t, tt.plain)
+		l, r := c.encryptBlock(binary.BigEndian.Uint32(plain), binary.BigEndian.Uint32(plain[4:]))
+		got := binary.BigEndian.AppendUint32(binary.BigEndian.AppendUint32(nil, l), r)
+		assert.Equal(t, tt.cipher, hex.EncodeToString(got), tt.key)
+
+		require.NoError(t, c.decrypt(got, true))
+		assert.Equal(t, tt.plain, hex.EncodeToString(got))
+	}
+}
+
+func TestBlowfish_CBCRoundTrip(t *testing.T) {
+	c, err := newBlowfish([]byte("a 56-byte key is the longest Blowfish accepts, exactly."))
+	require.NoError(t, err)
+	plain := []byte("sixteen bytes!!!and sixteen more")
+	ct := blowfishSeal(t, c, plain, false)
+	assert.NotEqual(t, plain[8:16], ct[8:16])
+	require.NoError(t, c.decrypt(ct, false))
+	assert.Equal(t, plain, ct)
+
+	assert.Error(t, c.decrypt(make([]byte, 7), false))
+	_, err = newBlowfish(make([]byte, 57))
+	assert.Error(t, err)
+}
+
+// blowfishSeal encrypts plain (a whole number of blocks) in CBC with a zero IV, or ECB.
+func blowfishSeal(t *testing.T, c *blowfish, plain []byte, ecb bool) []byte {
+	t.Helper()
+	require.Zero(t, len(plain)%blowfishBlockSize)
+	out := make([]byte, len(plain))
+	var prev [blowfishBlockSize]byte
+	for off := 0; off < len(plain); off += blowfishBlockSize {
+		var in [blowfishBlockSize]byte
+		copy(in[:], plain[off:])
+		if !ecb {
+			for i := range in {
+				in[i] ^= prev[i]
+			}
+		}
+		l, r := c.encryptBlock(binary.BigEndian.Uint32(in[:]), binary.BigEndian.Uint32(in[4:]))
+		binary.BigEndian.PutUint32(out[off:], l)
+		binary.BigEndian.PutUint32(out[off+4:], r)
+		copy(prev[:], out[off:off+blowfishBlockSize])
+	}
+	return out
+}
+
+func mustHex(t *testing.T, s string) []byte {
+	t.Helper()
+	b, err := hex.DecodeString(s)
+	require.NoError(t, err)
+	return b
+}
@@ -0,0 +1,41 @@
+package keyring
+
+import (
+	"crypto/sha1"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseGnomeKeyring(t *testing.T) {
+	items := []Secret{
+		{Label: "Chrome Safe Storage", Value: []byte("c2VjcmV0LWNocm9tZQ==")},
+		{Label: "Brave Safe Storage", Value: []byte("YnJhdmU=")},
+	}
+	data := sealGnomeKeyring(t, "hunter2", items)
+
+	secrets, err := ParseGnomeKeyring(data, "hunter2")
+	require.NoError(t, err)
+	assert.Equal(t, items, secrets)
+
+	_, err = ParseGnomeKeyring(data, "wrong")
+	assert.ErrorIs(t, err, errBadPassword)
+
+	_, err = ParseGnomeKeyring(data[:len(data)-40], "hunter2")
+	assert.ErrorIs(t, err, errTruncated)
+
+	_, err = ParseGnomeKeyring([]byte("[keyring]\ndisplay-name=login\n"), "hunter2")
+	assert.ErrorIs(t, err, errUnknownFormat)
+}
+
+func TestSymkeySimple_SpansDigests(t *testing.T) {
+	// SHA-1 yields 20 bytes, so a 16-byte key and IV take a second chained digest.
+	key, iv := symkeySimple(sha1.New, []byte("pw"), []byte("saltsalt"), 3, 16, 16)
+	assert.Len(t, key, 16)
+	assert.Len(t, iv, 16)
+	key2, iv2 := symkeySimple(sha1.New, []byte("pw"), []byte("saltsalt"), 3, 16, 16)
+	assert.Equal(t, key, key2)
+	assert.Equal(t, iv, iv2)
+	assert.NotEqual(t, key, iv)
+}
@@ -0,0 +1,33 @@
+package keyring
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOpen(t *testing.T) {
+	dir := t.TempDir()
+	gnome := filepath.Join(dir, "login.keyring")
+	require.NoError(t, os.WriteFile(gnome, sealGnomeKeyring(t, "pw", testWalletEntries[:1]), 0o600))
+	secrets, err := Open(gnome, "pw")
+	require.NoError(t, err)
+	assert.Equal(t, "Chrome Safe Storage", secrets[0].Label)
+
+	salt := bytes.Repeat([]byte{0x01}, 56)
+	wallet := filepath.Join(dir, "kdewallet.kwl")
+	require.NoError(t, os.WriteFile(wallet, sealKWallet(t, "pw", salt, false, testWalletEntries), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "kdewallet.salt"), salt, 0o600))
+	secrets, err = Open(wallet, "pw")
+	require.NoError(t, err)
+	assert.Len(t, secrets, 2)
+
+	other := filepath.Join(dir, "notes.txt")
+	require.NoError(t, os.WriteFile(other, []byte("hello"), 0o600))
+	_, err = Open(other, "pw")
+	assert.ErrorIs(t, err, errUnknownFormat)
+}
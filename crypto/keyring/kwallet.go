@@ -0,0 +1,150 @@
+package keyring
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"crypto/sha512"
+	"encoding/binary"
+	"fmt"
+
+	"github.com/moond4rk/hackbrowserdata/crypto"
+)
+
+// kwalletMagic opens every KWallet file (kwalletbackend.cc).
+var kwalletMagic = []byte("KWALLET\n\r\x00\r\n")
+
+// Header bytes after the magic: version major/minor, cipher and hash.
+const (
+	kwalletCipherBlowfishECB = 0
+	kwalletCipherBlowfishCBC = 3
+	kwalletHashSHA1          = 0
+	kwalletHashPBKDF2SHA512  = 2
+
+	kwalletPBKDF2Iterations = 50000
+	kwalletKeySize          = 56
+
+	// Wallet entry types (KWallet::Wallet::EntryType).
+	kwalletPassword = 1
+	kwalletStream   = 2
+	kwalletMap      = 3
+)
+
+// ParseKWallet decrypts a KDE wallet (kdewallet.kwl). Wallets at minor version 1 key Blowfish with
+// PBKDF2-SHA512 over the password and the 56-byte salt from kdewallet.salt; older ones use KWallet's
+// own iterated-SHA1 password hash, and salt may be nil. The payload carries a SHA-1 of itself, which
+// is how a wrong password is told apart. Password entries are returned as UTF-8; maps and streams as
+// their raw serialized bytes.
+func ParseKWallet(data, salt []byte, password string) ([]Secret, error) {
+	if !bytes.HasPrefix(data, kwalletMagic) {
+		return nil, errUnknownFormat
+	}
+	r := &reader{buf: data, off: len(kwalletMagic)}
+	header := r.bytes(4)
+	numFolders := r.u32()
+	for i := uint32(0); i < numFolders && r.err == nil; i++ {
+		r.bytes(16) // folder name MD5
+		r.bytes(16 * int(r.u32()))
+	}
+	if r.err != nil {
+		return nil, fmt.Errorf("parse KWallet: %w", r.err)
+	}
+	major, minor, cipherID, hashID := header[0], header[1], header[2], header[3]
+	if major != 0 || minor > 1 ||
+		(cipherID != kwalletCipherBlowfishECB && cipherID != kwalletCipherBlowfishCBC) ||
+		(hashID != kwalletHashSHA1 && hashID != kwalletHashPBKDF2SHA512) {
+		return nil, fmt.Errorf("parse KWallet: unsupported format %v", header)
+	}
+
+	var key []byte
+	if minor == 1 {
+		if len(salt) == 0 {
+			return nil, fmt.Errorf("parse KWallet: wallet needs its .salt file")
+		}
+		key = crypto.PBKDF2Key([]byte(password), salt, kwalletPBKDF2Iterations, kwalletKeySize, sha512.New)
+	} else {
+		key = kwalletPasswordHash([]byte(password))
+	}
+	bf, err := newBlowfish(key)
+	if err != nil {
+		return nil, err
+	}
+	plain := bytes.Clone(data[r.off:])
+	if err := bf.decrypt(plain, cipherID == kwalletCipherBlowfishECB); err != nil {
+		return nil, fmt.Errorf("parse KWallet: %w", err)
+	}
+
+	// One block of random data, a big-endian payload size, the payload, padding, then SHA-1(payload).
+	if len(plain) < blowfishBlockSize+4+sha1.Size {
+		return nil, fmt.Errorf("parse KWallet: %w", errTruncated)
+	}
+	size := int(binary.BigEndian.Uint32(plain[blowfishBlockSize:]))
+	start := blowfishBlockSize + 4
+	if size < 0 || size > len(plain)-start-sha1.Size {
+		return nil, errBadPassword
+	}
+	payload := plain[start : start+size]
+	sum := sha1.Sum(payload)
+	if !bytes.Equal(sum[:], plain[len(plain)-sha1.Size:]) {
+		return nil, errBadPassword
+	}
+	return parseKWalletEntries(payload)
+}
+
+func parseKWalletEntries(payload []byte) ([]Secret, error) {
+	var secrets []Secret
+	r := &reader{buf: payload}
+	for !r.atEnd() {
+		folder := utf16BE(r.lenBytes())
+		n := r.u32()
+		for i := uint32(0); i < n && r.err == nil; i++ {
+			label := utf16BE(r.lenBytes())
+			entryType := r.u32()
+			value := r.lenBytes()
+			switch entryType {
+			case kwalletPassword:
+				// A password is itself a serialized QString.
+				vr := &reader{buf: value}
+				value = []byte(utf16BE(vr.lenBytes()))
+			case kwalletStream, kwalletMap:
+				value = bytes.Clone(value)
+			default:
+				return nil, fmt.Errorf("parse KWallet: entry %q has unknown type %d", label, entryType)
+			}
+			secrets = append(secrets, Secret{Folder: folder, Label: label, Value: value})
+		}
+	}
+	if r.err != nil {
+		return nil, fmt.Errorf("parse KWallet: %w", r.err)
+	}
+	return secrets, nil
+}
+
+// kwalletPasswordHash is KWallet's pre-PBKDF2 key (password2hash): each 16-byte chunk of the password
+// (the last one taking the remainder) is SHA-1'd 2000 times, and the chunk digests are concatenated
+// into a Blowfish key of at most 56 bytes.
+func kwalletPasswordHash(password []byte) []byte {
+	chunk := func(start, end int) []byte {
+		if end > len(password) {
+			end = len(password)
+		}
+		sum := sha1.Sum(password[start:end])
+		for i := 1; i < 2000; i++ {
+			sum = sha1.Sum(sum[:])
+		}
+		return sum[:]
+	}
+	switch n := len(password); {
+	case n <= 16:
+		return chunk(0, 16)
+	case n <= 32:
+		return append(chunk(0, 16), chunk(16, 32)...)
+	case n <= 48:
+		return append(append(chunk(0, 16), chunk(16, 32)...), chunk(32, 48)[:16]...)
+	default:
+		var key []byte
+		for _, c := range [][]byte{chunk(0, 16), chunk(16, 32), chunk(32, 48), chunk(48, n)} {
+			key = append(key, c[:14]...)
+		}
+		return key
+	}
+}
@@ -0,0 +1,45 @@
+package keyring
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testWalletEntries = []Secret{
+	{Folder: "Chrome Keys", Label: "Chrome Safe Storage", Value: []byte("Y2hyb21lLXNlY3JldA==")},
+	{Folder: "Chromium Keys", Label: "Chromium Safe Storage", Value: []byte("Y2hyb21pdW0=")},
+}
+
+func TestParseKWallet_PBKDF2(t *testing.T) {
+	salt := bytes.Repeat([]byte{0x5a}, 56)
+	data := sealKWallet(t, "hunter2", salt, false, testWalletEntries)
+
+	secrets, err := ParseKWallet(data, salt, "hunter2")
+	require.NoError(t, err)
+	assert.Equal(t, testWalletEntries, secrets)
+
+	_, err = ParseKWallet(data, salt, "wrong")
+	assert.ErrorIs(t, err, errBadPassword)
+
+	_, err = ParseKWallet(data, nil, "hunter2")
+	assert.ErrorContains(t, err, ".salt")
+}
+
+func TestParseKWallet_LegacyHash(t *testing.T) {
+	for _, ecb := range []bool{false, true} {
+		password := "a passphrase longer than fifty-six bytes, to fill every hash chunk"
+		data := sealKWallet(t, password, nil, ecb, testWalletEntries)
+		secrets, err := ParseKWallet(data, nil, password)
+		require.NoError(t, err, "ecb=%v", ecb)
+		assert.Equal(t, testWalletEntries, secrets)
+	}
+}
+
+func TestKWalletPasswordHash_Lengths(t *testing.T) {
+	for n, want := range map[int]int{0: 20, 16: 20, 17: 40, 32: 40, 33: 56, 48: 56, 49: 56, 80: 56} {
+		assert.Len(t, kwalletPasswordHash(bytes.Repeat([]byte{'x'}, n)), want, "password length %d", n)
+	}
+}
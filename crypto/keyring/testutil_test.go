@@ -0,0 +1,145 @@
+package keyring
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/binary"
+	"testing"
+	"unicode/utf16"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/moond4rk/hackbrowserdata/crypto"
+)
+
+// sealGnomeKeyring writes a binary .keyring the way gnome-keyring-daemon does: a hashed-attribute
+// item index in the clear, and the items themselves sealed under password.
+func sealGnomeKeyring(t *testing.T, password string, items []Secret) []byte {
+	t.Helper()
+	salt := []byte("8bytesal")
+	const iterations = 1000
+
+	var out []byte
+	out = append(out, gnomeMagic...)
+	out = append(out, 0, 0, 0, 0)
+	out = be32Bytes(out, []byte("login"))
+	out = append(out, make([]byte, 16)...) // ctime, mtime
+	out = be32(out, 0)                     // flags
+	out = be32(out, 0)                     // lock timeout
+	out = be32(out, iterations)
+	out = append(out, salt...)
+	out = append(out, make([]byte, 16)...)
+	out = be32(out, uint32(len(items)))
+	for i := range items {
+		out = be32(out, uint32(i+1))
+		out = be32(out, 0)
+		out = be32(out, 2)
+		out = be32Bytes(out, []byte("application"))
+		out = be32(out, 0)
+		out = be32Bytes(out, []byte("5d41402abc4b2a76b9719d911017c592"))
+		out = be32Bytes(out, []byte("xdg:schema"))
+		out = be32(out, gnomeAttrUint32)
+		out = be32(out, 7)
+	}
+
+	var plain []byte
+	for _, it := range items {
+		plain = be32Bytes(plain, []byte(it.Label))
+		plain = be32Bytes(plain, it.Value)
+		plain = append(plain, make([]byte, 16)...)
+		plain = be32(plain, 0xffffffff) // null reserved string
+		plain = append(plain, make([]byte, 16)...)
+		plain = be32(plain, 1)
+		plain = be32Bytes(plain, []byte("application"))
+		plain = be32(plain, 0)
+		plain = be32Bytes(plain, []byte("chrome"))
+		plain = be32(plain, 1) // one ACL entry
+		plain = be32(plain, 7)
+		plain = be32Bytes(plain, []byte("Chrome"))
+		plain = be32Bytes(plain, []byte("/opt/google/chrome/chrome"))
+		plain = be32(plain, 0xffffffff)
+		plain = be32(plain, 0)
+	}
+	for (md5.Size+len(plain))%aes.BlockSize != 0 {
+		plain = append(plain, 0)
+	}
+	sum := md5.Sum(plain)
+	plain = append(sum[:], plain...)
+
+	key, iv := symkeySimple(sha256.New, []byte(password), salt, iterations, 16, aes.BlockSize)
+	block, err := aes.NewCipher(key)
+	require.NoError(t, err)
+	ct := make([]byte, len(plain))
+	cipher.NewCBCEncrypter(block, iv).CryptBlocks(ct, plain)
+	return be32Bytes(out, ct)
+}
+
+// sealKWallet writes a .kwl holding Password entries, keyed with PBKDF2 when salt is set (minor
+// version 1) and with the legacy SHA-1 hash otherwise.
+func sealKWallet(t *testing.T, password string, salt []byte, ecb bool, entries []Secret) []byte {
+	t.Helper()
+	var payload []byte
+	byFolder := map[string][]Secret{}
+	var folders []string
+	for _, e := range entries {
+		if _, ok := byFolder[e.Folder]; !ok {
+			folders = append(folders, e.Folder)
+		}
+		byFolder[e.Folder] = append(byFolder[e.Folder], e)
+	}
+	for _, f := range folders {
+		payload = be32Bytes(payload, qString(f))
+		payload = be32(payload, uint32(len(byFolder[f])))
+		for _, e := range byFolder[f] {
+			payload = be32Bytes(payload, qString(e.Label))
+			payload = be32(payload, kwalletPassword)
+			payload = be32Bytes(payload, be32Bytes(nil, qString(string(e.Value))))
+		}
+	}
+
+	plain := append([]byte("randblk!"), be32(nil, uint32(len(payload)))...)
+	plain = append(plain, payload...)
+	for (len(plain)+sha1.Size)%blowfishBlockSize != 0 {
+		plain = append(plain, 'p')
+	}
+	sum := sha1.Sum(payload)
+	plain = append(plain, sum[:]...)
+
+	minor, hashID, cipherID := byte(0), byte(kwalletHashSHA1), byte(kwalletCipherBlowfishCBC)
+	key := kwalletPasswordHash([]byte(password))
+	if salt != nil {
+		minor, hashID = 1, kwalletHashPBKDF2SHA512
+		key = crypto.PBKDF2Key([]byte(password), salt, kwalletPBKDF2Iterations, kwalletKeySize, sha512.New)
+	}
+	if ecb {
+		cipherID = kwalletCipherBlowfishECB
+	}
+	bf, err := newBlowfish(key)
+	require.NoError(t, err)
+
+	out := append([]byte{}, kwalletMagic...)
+	out = append(out, 0, minor, cipherID, hashID)
+	out = be32(out, uint32(len(folders)))
+	for range folders {
+		out = append(out, make([]byte, 16)...)
+		out = be32(out, 1)
+		out = append(out, make([]byte, 16)...)
+	}
+	return append(out, blowfishSeal(t, bf, plain, ecb)...)
+}
+
+func qString(s string) []byte {
+	var b []byte
+	for _, u := range utf16.Encode([]rune(s)) {
+		b = binary.BigEndian.AppendUint16(b, u)
+	}
+	return b
+}
+
+func be32(b []byte, v uint32) []byte { return binary.BigEndian.AppendUint32(b, v) }
+
+func be32Bytes(b, v []byte) []byte { return append(be32(b, uint32(len(v))), v...) }
@@ -15,9 +15,8 @@ const (
 	CipherV20 CipherVersion = "v20"
 
 	// CipherV12 is Chromium's SecretPortalKeyProvider (Flatpak / xdg-desktop-portal) tier —
-	// HKDF-SHA256 + AES-256-GCM with a secret retrieved via org.freedesktop.portal.Desktop.
-	// Recognized by DetectVersion so decryptValue can emit a known-gap error rather than a
-	// generic "unsupported cipher version" message; not yet implemented.
+	// AES-256-GCM with a key HKDF-SHA256-derived from the org.freedesktop.portal.Secret secret
+	// (see DeriveSecretPortalKey). Linux-only; same wire layout as Windows v10 / v20.
 	CipherV12 CipherVersion = "v12"
 
 	// CipherDPAPI is pre-Chrome 80 raw DPAPI encryption (no version prefix).
@@ -0,0 +1,248 @@
+package diskimage
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+var (
+	ewfSignature  = []byte("EVF\x09\x0d\x0a\xff\x00")
+	ewf2Signature = []byte("EVF2\x0d\x0a\x81\x00")
+)
+
+const (
+	ewfFileHeaderLen = 13
+	ewfSectionLen    = 76
+	ewfTableHeadLen  = 24
+)
+
+// ewfChunk locates one chunk of the imaged disk inside a segment file.
+type ewfChunk struct {
+	file       int
+	offset     int64
+	size       int64 // stored bytes, checksum included for an uncompressed chunk
+	compressed bool
+}
+
+// ewfReader serves the disk bytes of an EWF (E01) image: the media is split into fixed-size chunks,
+// each stored zlib-compressed or raw with an Adler-32 trailer, and located by the table sections of
+// the segment files. The last chunk read is kept decoded, since reads arrive in runs.
+type ewfReader struct {
+	files     []*os.File
+	chunks    []ewfChunk
+	chunkSize int64
+	size      int64
+
+	mu    sync.Mutex
+	index int
+	data  []byte
+}
+
+// openEWF opens path as the first segment of an EWF image and follows its segments.
+func openEWF(path string) (*Image, error) {
+	r := &ewfReader{index: -1}
+	img := &Image{r: r, format: "ewf"}
+	fail := func(err error) (*Image, error) {
+		_ = img.Close()
+		return nil, err
+	}
+	for seg := 1; ; seg++ {
+		name := path
+		if seg > 1 {
+			name = ewfSegmentPath(path, seg)
+		}
+		f, err := os.Open(name)
+		if err != nil {
+			return fail(fmt.Errorf("ewf segment %d: %w", seg, err))
+		}
+		img.files = append(img.files, f)
+		r.files = img.files
+		last, err := r.readSegment(len(img.files)-1, f)
+		if err != nil {
+			return fail(fmt.Errorf("ewf %s: %w", filepath.Base(name), err))
+		}
+		if last {
+			break
+		}
+	}
+	if r.chunkSize == 0 {
+		return fail(fmt.Errorf("ewf %s: no volume section", filepath.Base(path)))
+	}
+	if int64(len(r.chunks))*r.chunkSize < r.size {
+		return fail(fmt.Errorf("ewf %s: %d chunks cannot hold %d bytes", filepath.Base(path), len(r.chunks), r.size))
+	}
+	img.size = r.size
+	return img, nil
+}
+
+// ewfSegmentPath names segment n after the first: .E02 … .E99, then .EAA … .EZZ, .FAA and on, keeping
+// the case of the first segment's extension.
+func ewfSegmentPath(first string, n int) string {
+	ext := filepath.Ext(first)
+	var next string
+	if n < 100 {
+		next = fmt.Sprintf("%c%02d", 'E', n)
+	} else {
+		n -= 100
+		next = string([]byte{byte('E' + n/(26*26)), byte('A' + n/26%26), byte('A' + n%26)})
+	}
+	if len(ext) > 1 && ext[1] >= 'a' && ext[1] <= 'z' {
+		next = strings.ToLower(next)
+	}
+	return strings.TrimSuffix(first, ext) + "." + next
+}
+
+// readSegment walks the section chain of one segment file, collecting the media geometry and chunk
+// tables. It reports whether the segment is the last ("done" rather than "next").
+func (r *ewfReader) readSegment(file int, f *os.File) (bool, error) {
+	head := make([]byte, ewfFileHeaderLen)
+	if _, err := f.ReadAt(head, 0); err != nil {
+		return false, err
+	}
+	if !bytes.Equal(head[:8], ewfSignature) {
+		return false, fmt.Errorf("not an EWF segment")
+	}
+	var sectorsEnd int64
+	desc := make([]byte, ewfSectionLen)
+	for off := int64(ewfFileHeaderLen); ; {
+		if _, err := f.ReadAt(desc, off); err != nil {
+			return false, fmt.Errorf("section at %d: %w", off, err)
+		}
+		kind := string(bytes.TrimRight(desc[:16], "\x00"))
+		next := int64(binary.LittleEndian.Uint64(desc[16:]))
+		size := int64(binary.LittleEndian.Uint64(desc[24:]))
+		switch kind {
+		case "volume", "disk":
+			if err := r.readVolume(f, off+ewfSectionLen); err != nil {
+				return false, err
+			}
+		case "sectors":
+			sectorsEnd = off + size
+		case "table":
+			if err := r.readTable(file, f, off+ewfSectionLen, sectorsEnd); err != nil {
+				return false, err
+			}
+		case "next":
+			return false, nil
+		case "done":
+			return true, nil
+		}
+		if next <= off {
+			return false, fmt.Errorf("section %q at %d: chain ends without a next or done section", kind, off)
+		}
+		off = next
+	}
+}
+
+func (r *ewfReader) readVolume(f *os.File, off int64) error {
+	v := make([]byte, 24)
+	if _, err := f.ReadAt(v, off); err != nil {
+		return fmt.Errorf("volume section: %w", err)
+	}
+	sectorsPerChunk := int64(binary.LittleEndian.Uint32(v[8:]))
+	bytesPerSector := int64(binary.LittleEndian.Uint32(v[12:]))
+	sectors := int64(binary.LittleEndian.Uint64(v[16:]))
+	if sectorsPerChunk == 0 || bytesPerSector == 0 {
+		return fmt.Errorf("volume section: %d sectors per chunk of %d bytes", sectorsPerChunk, bytesPerSector)
+	}
+	r.chunkSize = sectorsPerChunk * bytesPerSector
+	r.size = sectors * bytesPerSector
+	return nil
+}
+
+// readTable appends the chunks one table section lists. Entry offsets are relative to the table's
+// base offset, with the top bit flagging a compressed chunk; a chunk runs to the next entry, and the
+// last one to the end of the sectors section before the table.
+func (r *ewfReader) readTable(file int, f *os.File, off, sectorsEnd int64) error {
+	head := make([]byte, ewfTableHeadLen)
+	if _, err := f.ReadAt(head, off); err != nil {
+		return fmt.Errorf("table section: %w", err)
+	}
+	count := int(binary.LittleEndian.Uint32(head))
+	base := int64(binary.LittleEndian.Uint64(head[8:]))
+	entries := make([]byte, 4*count)
+	if _, err := f.ReadAt(entries, off+ewfTableHeadLen); err != nil {
+		return fmt.Errorf("table entries: %w", err)
+	}
+	for i := 0; i < count; i++ {
+		v := binary.LittleEndian.Uint32(entries[4*i:])
+		c := ewfChunk{file: file, offset: base + int64(v&0x7fffffff), compressed: v&0x80000000 != 0}
+		end := sectorsEnd
+		if i+1 < count {
+			end = base + int64(binary.LittleEndian.Uint32(entries[4*i+4:])&0x7fffffff)
+		}
+		c.size = end - c.offset
+		if c.size <= 0 {
+			return fmt.Errorf("table entry %d: chunk at %d ends at %d", i, c.offset, end)
+		}
+		r.chunks = append(r.chunks, c)
+	}
+	return nil
+}
+
+func (r *ewfReader) ReadAt(p []byte, off int64) (int, error) {
+	if off < 0 {
+		return 0, errors.New("ewf: negative offset")
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	n := 0
+	for n < len(p) {
+		if off >= r.size {
+			return n, io.EOF
+		}
+		index := int(off / r.chunkSize)
+		if err := r.load(index); err != nil {
+			return n, err
+		}
+		within := off - int64(index)*r.chunkSize
+		if within >= int64(len(r.data)) {
+			return n, io.ErrUnexpectedEOF
+		}
+		c := copy(p[n:], r.data[within:])
+		if rest := r.size - off; int64(c) > rest {
+			c = int(rest)
+		}
+		n += c
+		off += int64(c)
+	}
+	return n, nil
+}
+
+// load decodes chunk index into r.data unless it is already there.
+func (r *ewfReader) load(index int) error {
+	if index == r.index {
+		return nil
+	}
+	c := r.chunks[index]
+	stored := make([]byte, c.size)
+	if _, err := r.files[c.file].ReadAt(stored, c.offset); err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("ewf chunk %d: %w", index, err)
+	}
+	var data []byte
+	if c.compressed {
+		zr, err := zlib.NewReader(bytes.NewReader(stored))
+		if err != nil {
+			return fmt.Errorf("ewf chunk %d: %w", index, err)
+		}
+		data, err = io.ReadAll(io.LimitReader(zr, r.chunkSize))
+		if err != nil {
+			return fmt.Errorf("ewf chunk %d: %w", index, err)
+		}
+	} else {
+		data = stored
+		if int64(len(data)) > r.chunkSize {
+			data = data[:r.chunkSize] // drop the Adler-32 trailer
+		}
+	}
+	r.index, r.data = index, data
+	return nil
+}
@@ -0,0 +1,380 @@
+package diskimage
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"io/fs"
+	"time"
+)
+
+const (
+	extMagic     = 0xef53
+	extRootInode = 2
+
+	extIncompat64Bit  = 0x80
+	extIncompatMetaBG = 0x10
+	extRoCompatSparse = 0x01
+
+	extFlagExtents  = 0x80000
+	extFlagHugeFile = 0x40000
+	extFlagInline   = 0x10000000
+
+	extExtentMagic = 0xf30a
+	extMaxDepth    = 5
+	// s_log_block_size past 6 would make blocks larger than the 64 KiB ext allows.
+	extMaxLogBlockSize = 6
+)
+
+// extVolume reads ext2, ext3 and ext4: inodes through the group descriptor table, file data through
+// extent trees or the classic block map, directories as linear entries (hashed directories keep their
+// entries linear too). Journal replay is not attempted, so a volume imaged while mounted is read as it
+// last reached the disk.
+type extVolume struct {
+	r              io.ReaderAt
+	blockSize      int64
+	inodeSize      int64
+	inodesPerGroup uint32
+	blocksPerGroup uint32
+	firstDataBlock uint32
+	descSize       int64
+	groups         uint32
+	metaBG         bool
+	firstMetaBG    uint32
+	sparse         bool
+}
+
+// extInode is the part of an inode the reader needs.
+type extInode struct {
+	mode      uint16
+	size      int64
+	allocated int64 // bytes of blocks allocated to the inode, from i_blocks
+	mtime     time.Time
+	flags     uint32
+	block     []byte // i_block, 60 bytes
+}
+
+func openExt(r io.ReaderAt) (driver, error) {
+	sb := make([]byte, 1024)
+	if _, err := r.ReadAt(sb, 1024); err != nil {
+		return nil, err
+	}
+	le := binary.LittleEndian
+	if le.Uint32(sb[24:]) > extMaxLogBlockSize {
+		return nil, fmt.Errorf("ext: bad superblock")
+	}
+	v := &extVolume{
+		r:              r,
+		blockSize:      1024 << le.Uint32(sb[24:]),
+		inodesPerGroup: le.Uint32(sb[40:]),
+		blocksPerGroup: le.Uint32(sb[32:]),
+		firstDataBlock: le.Uint32(sb[20:]),
+		inodeSize:      128,
+		descSize:       32,
+		firstMetaBG:    le.Uint32(sb[260:]),
+	}
+	if le.Uint32(sb[76:]) > 0 {
+		v.inodeSize = int64(le.Uint16(sb[88:]))
+	}
+	incompat := le.Uint32(sb[96:])
+	if incompat&extIncompat64Bit != 0 && le.Uint16(sb[254:]) >= 64 {
+		v.descSize = int64(le.Uint16(sb[254:]))
+	}
+	v.metaBG = incompat&extIncompatMetaBG != 0
+	v.sparse = le.Uint32(sb[100:])&extRoCompatSparse != 0
+	blocks := uint64(le.Uint32(sb[4:]))
+	if incompat&extIncompat64Bit != 0 {
+		blocks |= uint64(le.Uint32(sb[336:])) << 32
+	}
+	if v.blocksPerGroup == 0 || v.inodesPerGroup == 0 || v.inodeSize < 128 || v.descSize > v.blockSize {
+		return nil, fmt.Errorf("ext: bad superblock")
+	}
+	v.groups = uint32((blocks - uint64(v.firstDataBlock) + uint64(v.blocksPerGroup) - 1) / uint64(v.blocksPerGroup))
+	return v, nil
+}
+
+func (v *extVolume) foldCase() bool { return false }
+
+func (v *extVolume) root() (node, error) {
+	ino, err := v.inode(extRootInode)
+	if err != nil {
+		return node{}, err
+	}
+	return v.node("", extRootInode, ino), nil
+}
+
+func (v *extVolume) node(name string, num uint32, ino extInode) node {
+	n := node{name: name, ref: uint64(num), size: ino.size, mtime: ino.mtime, mode: fs.FileMode(ino.mode & 0o777)}
+	switch ino.mode & 0xf000 {
+	case 0x4000:
+		n.mode |= fs.ModeDir
+		n.size = 0
+	case 0xa000:
+		n.mode |= fs.ModeSymlink
+	case 0x8000:
+	default:
+		n.mode |= fs.ModeIrregular
+	}
+	return n
+}
+
+// descriptorOffset locates group g's descriptor. With meta_bg, the groups past s_first_meta_bg keep
+// their descriptors in the first group of their meta group, after its superblock backup if it has one.
+func (v *extVolume) descriptorOffset(g uint32) int64 {
+	perBlock := uint32(v.blockSize / v.descSize)
+	table := int64(v.firstDataBlock) + 1
+	if v.metaBG && g/perBlock >= v.firstMetaBG {
+		first := g / perBlock * perBlock
+		table = int64(v.firstDataBlock) + int64(first)*int64(v.blocksPerGroup)
+		if v.hasSuperblock(first) {
+			table++
+		}
+		return table*v.blockSize + int64(g%perBlock)*v.descSize
+	}
+	return table*v.blockSize + int64(g)*v.descSize
+}
+
+// hasSuperblock reports whether group g holds a superblock backup: every group without sparse_super,
+// otherwise groups 0, 1 and the powers of 3, 5 and 7.
+func (v *extVolume) hasSuperblock(g uint32) bool {
+	if !v.sparse || g <= 1 {
+		return true
+	}
+	for _, base := range []uint32{3, 5, 7} {
+		n := base
+		for n < g {
+			n *= base
+		}
+		if n == g {
+			return true
+		}
+	}
+	return false
+}
+
+// inodeOffset locates inode num through its group's descriptor.
+func (v *extVolume) inodeOffset(num uint32) (int64, error) {
+	if num == 0 {
+		return 0, fmt.Errorf("ext: inode 0")
+	}
+	g := (num - 1) / v.inodesPerGroup
+	if g >= v.groups {
+		return 0, fmt.Errorf("ext: inode %d out of range", num)
+	}
+	desc := make([]byte, v.descSize)
+	if _, err := v.r.ReadAt(desc, v.descriptorOffset(g)); err != nil {
+		return 0, fmt.Errorf("ext: group %d descriptor: %w", g, err)
+	}
+	table := int64(binary.LittleEndian.Uint32(desc[8:]))
+	if v.descSize >= 64 {
+		table |= int64(binary.LittleEndian.Uint32(desc[40:])) << 32
+	}
+	return table*v.blockSize + int64((num-1)%v.inodesPerGroup)*v.inodeSize, nil
+}
+
+func (v *extVolume) inode(num uint32) (extInode, error) {
+	off, err := v.inodeOffset(num)
+	if err != nil {
+		return extInode{}, err
+	}
+	b := make([]byte, 128)
+	if _, err := v.r.ReadAt(b, off); err != nil {
+		return extInode{}, fmt.Errorf("ext: inode %d: %w", num, err)
+	}
+	le := binary.LittleEndian
+	ino := extInode{
+		mode:      le.Uint16(b[0:]),
+		size:      int64(le.Uint32(b[4:])) | int64(le.Uint32(b[108:]))<<32,
+		allocated: int64(le.Uint32(b[28:])) | int64(le.Uint16(b[116:]))<<32,
+		mtime:     time.Unix(int64(le.Uint32(b[16:])), 0).UTC(),
+		flags:     le.Uint32(b[32:]),
+		block:     b[40:100],
+	}
+	// i_blocks counts 512-byte sectors, or filesystem blocks for a huge file.
+	if ino.flags&extFlagHugeFile != 0 {
+		ino.allocated *= v.blockSize
+	} else {
+		ino.allocated *= 512
+	}
+	return ino, nil
+}
+
+// data returns a reader over an inode's content.
+func (v *extVolume) data(num uint32, ino extInode) (io.ReaderAt, error) {
+	switch {
+	case ino.flags&extFlagInline != 0:
+		// Only the 60 bytes of i_block; longer inline data continues in the system.data xattr.
+		if ino.size > int64(len(ino.block)) {
+			return nil, fmt.Errorf("ext: inode %d: inline data beyond i_block is not supported", num)
+		}
+		return bytes.NewReader(ino.block), nil
+	case ino.mode&0xf000 == 0xa000 && ino.size < 60 && ino.flags&extFlagExtents == 0:
+		return bytes.NewReader(ino.block), nil // fast symlink
+	case ino.flags&extFlagExtents != 0:
+		var extents []extent
+		if err := v.extentTree(ino.block, 0, &extents); err != nil {
+			return nil, fmt.Errorf("ext: inode %d: %w", num, err)
+		}
+		return &extentReader{r: v.r, extents: extents}, nil
+	default:
+		extents, err := v.blockMap(ino)
+		if err != nil {
+			return nil, fmt.Errorf("ext: inode %d: %w", num, err)
+		}
+		return &extentReader{r: v.r, extents: extents}, nil
+	}
+}
+
+// extentTree walks an extent tree node into extents; uninitialized extents are left as holes.
+func (v *extVolume) extentTree(b []byte, depth int, extents *[]extent) error {
+	le := binary.LittleEndian
+	if len(b) < 12 || le.Uint16(b) != extExtentMagic {
+		return fmt.Errorf("bad extent header")
+	}
+	if depth > extMaxDepth {
+		return fmt.Errorf("extent tree too deep")
+	}
+	entries := int(le.Uint16(b[2:]))
+	if 12+12*entries > len(b) {
+		return fmt.Errorf("extent node overflows")
+	}
+	leaf := le.Uint16(b[6:]) == 0
+	for i := 0; i < entries; i++ {
+		e := b[12+12*i:]
+		if leaf {
+			length := int64(le.Uint16(e[4:]))
+			if length > 32768 {
+				continue // uninitialized: reads as zeros
+			}
+			start := int64(le.Uint16(e[6:]))<<32 | int64(le.Uint32(e[8:]))
+			*extents = appendExtent(*extents, extent{
+				logical: int64(le.Uint32(e)) * v.blockSize,
+				phys:    start * v.blockSize,
+				length:  length * v.blockSize,
+			})
+			continue
+		}
+		child := make([]byte, v.blockSize)
+		leafBlock := int64(le.Uint32(e[4:])) | int64(le.Uint16(e[8:]))<<32
+		if _, err := v.r.ReadAt(child, leafBlock*v.blockSize); err != nil {
+			return err
+		}
+		if err := v.extentTree(child, depth+1, extents); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// blockMap reads the ext2/3 direct, indirect, double- and triple-indirect block pointers.
+func (v *extVolume) blockMap(ino extInode) ([]extent, error) {
+	blocks := (ino.size + v.blockSize - 1) / v.blockSize
+	var extents []extent
+	var logical int64
+	add := func(b uint32) {
+		if b != 0 {
+			extents = appendExtent(extents, extent{logical: logical * v.blockSize, phys: int64(b) * v.blockSize, length: v.blockSize})
+		}
+		logical++
+	}
+	var walk func(block uint32, level int) error
+	walk = func(block uint32, level int) error {
+		perBlock := v.blockSize / 4
+		span := int64(1)
+		for i := 0; i < level; i++ {
+			span *= perBlock
+		}
+		if block == 0 {
+			logical += span * perBlock
+			return nil
+		}
+		b := make([]byte, v.blockSize)
+		if _, err := v.r.ReadAt(b, int64(block)*v.blockSize); err != nil {
+			return err
+		}
+		for i := int64(0); i < perBlock && logical < blocks; i++ {
+			p := binary.LittleEndian.Uint32(b[4*i:])
+			if level == 0 {
+				add(p)
+			} else if err := walk(p, level-1); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	for i := 0; i < 12 && logical < blocks; i++ {
+		add(binary.LittleEndian.Uint32(ino.block[4*i:]))
+	}
+	for level := 0; level < 3 && logical < blocks; level++ {
+		if err := walk(binary.LittleEndian.Uint32(ino.block[48+4*level:]), level); err != nil {
+			return nil, err
+		}
+	}
+	return extents, nil
+}
+
+func (v *extVolume) readDir(dir node) ([]node, error) {
+	ino, err := v.inode(uint32(dir.ref))
+	if err != nil {
+		return nil, err
+	}
+	r, err := v.data(uint32(dir.ref), ino)
+	if err != nil {
+		return nil, err
+	}
+	// The size is taken from disk; a directory cannot hold more than the blocks allocated to it, and
+	// inline data is already limited to i_block.
+	size := ino.size
+	if ino.flags&extFlagInline == 0 && size > ino.allocated {
+		size = ino.allocated
+	}
+	raw := make([]byte, size)
+	if _, err := r.ReadAt(raw, 0); err != nil {
+		return nil, err
+	}
+	if ino.flags&extFlagInline != 0 && len(raw) >= 4 {
+		raw = raw[4:] // the parent's inode number stands in for ".."
+	}
+
+	var nodes []node
+	le := binary.LittleEndian
+	for off := 0; off+8 <= len(raw); {
+		num := le.Uint32(raw[off:])
+		recLen := int(le.Uint16(raw[off+4:]))
+		nameLen := int(raw[off+6])
+		if recLen < 8 || off+recLen > len(raw) {
+			// A corrupt entry spoils the rest of its block only.
+			next := (off/int(v.blockSize) + 1) * int(v.blockSize)
+			if ino.flags&extFlagInline != 0 || next <= off {
+				break
+			}
+			off = next
+			continue
+		}
+		if num != 0 && 8+nameLen <= recLen {
+			name := string(raw[off+8 : off+8+nameLen])
+			if name != "." && name != ".." {
+				child, err := v.inode(num)
+				if err != nil {
+					return nil, err
+				}
+				nodes = append(nodes, v.node(name, num, child))
+			}
+		}
+		off += recLen
+	}
+	return nodes, nil
+}
+
+func (v *extVolume) open(file node) (node, io.ReaderAt, error) {
+	ino, err := v.inode(uint32(file.ref))
+	if err != nil {
+		return file, nil, err
+	}
+	r, err := v.data(uint32(file.ref), ino)
+	if err != nil {
+		return file, nil, err
+	}
+	return v.node(file.name, uint32(file.ref), ino), r, nil
+}
@@ -0,0 +1,90 @@
+package diskimage
+
+import (
+	"bytes"
+	"encoding/binary"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// mkExt builds an ext volume from files with mke2fs -d, skipping the test where e2fsprogs is missing.
+func mkExt(t *testing.T, fsType, blockSize string, files map[string][]byte) []byte {
+	t.Helper()
+	mke2fs, err := exec.LookPath("mke2fs")
+	if err != nil {
+		t.Skip("mke2fs not installed")
+	}
+	src := t.TempDir()
+	for p, content := range files {
+		full := filepath.Join(src, filepath.FromSlash(p))
+		require.NoError(t, os.MkdirAll(filepath.Dir(full), 0o755))
+		require.NoError(t, os.WriteFile(full, content, 0o644))
+	}
+	img := filepath.Join(t.TempDir(), "ext.img")
+	out, err := exec.Command(mke2fs, "-q", "-F", "-t", fsType, "-b", blockSize, "-d", src, img, "8M").CombinedOutput()
+	if err != nil {
+		t.Skipf("mke2fs -d: %v: %s", err, out)
+	}
+	data, err := os.ReadFile(img)
+	require.NoError(t, err)
+	return data
+}
+
+func TestExt(t *testing.T) {
+	files := map[string][]byte{
+		"home/bob/.config/google-chrome/Local State":         []byte(`{"os_crypt":{}}`),
+		"home/bob/.config/google-chrome/Default/Login Data":  pattern(300_000, 5), // past the double-indirect block with 1 KiB blocks
+		"home/bob/.mozilla/firefox/abcd.default/logins.json": []byte(`{"logins":[]}`),
+		"empty": {},
+	}
+	for _, tt := range []struct {
+		name      string
+		fsType    string
+		blockSize string
+	}{
+		{"ext2 block map", "ext2", "1024"},
+		{"ext3", "ext3", "4096"},
+		{"ext4 extents", "ext4", "4096"},
+		{"ext4 1k blocks", "ext4", "1024"},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			img := mkExt(t, tt.fsType, tt.blockSize, files)
+			r := bytes.NewReader(img)
+			parts, err := Partitions(r, int64(len(img)))
+			require.NoError(t, err)
+			require.Len(t, parts, 1)
+			vol, err := OpenVolume(r, parts[0])
+			require.NoError(t, err)
+			assert.Equal(t, "ext", vol.Filesystem)
+
+			checkTree(t, vol.FS, files)
+
+			_, err = vol.FS.Open("HOME/bob/.config/google-chrome/Local State")
+			assert.Error(t, err, "ext names are case-sensitive")
+		})
+	}
+}
+
+func TestExtCorrupt(t *testing.T) {
+	files := map[string][]byte{"home/bob/notes.txt": []byte("notes")}
+	img := mkExt(t, "ext4", "4096", files)
+
+	// A root directory claiming an enormous size is read up to the blocks allocated to it.
+	d, err := openExt(bytes.NewReader(img))
+	require.NoError(t, err)
+	off, err := d.(*extVolume).inodeOffset(extRootInode)
+	require.NoError(t, err)
+	binary.LittleEndian.PutUint32(img[off+108:], 1<<30)
+	vol, err := OpenVolume(bytes.NewReader(img), Partition{Size: int64(len(img))})
+	require.NoError(t, err)
+	checkTree(t, vol.FS, files)
+
+	binary.LittleEndian.PutUint32(img[1024+24:], 60) // s_log_block_size
+	_, err = openExt(bytes.NewReader(img))
+	assert.ErrorContains(t, err, "bad superblock")
+}
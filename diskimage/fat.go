@@ -0,0 +1,313 @@
+package diskimage
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"io/fs"
+	"strings"
+	"time"
+	"unicode/utf16"
+)
+
+// fatVolume reads FAT12, FAT16 and FAT32. Directories are cluster chains of 32-byte entries, with long
+// names spread over the VFAT entries before each short one; the FAT12/16 root is a fixed region after
+// the FATs, which ref 0 stands for.
+type fatVolume struct {
+	r           io.ReaderAt
+	bits        int // 12, 16 or 32
+	clusterSize int64
+	fatOffset   int64
+	rootOffset  int64 // FAT12/16
+	rootSize    int64
+	dataOffset  int64
+	clusters    uint32 // data clusters; valid numbers are 2 … clusters+1
+	rootCluster uint32 // FAT32
+}
+
+const (
+	fatAttrVolume = 0x08
+	fatAttrDir    = 0x10
+	fatAttrLFN    = 0x0f
+)
+
+// isFATBoot checks a boot sector's BPB for FAT geometry.
+func isFATBoot(b []byte) bool {
+	if b[510] != 0x55 || b[511] != 0xaa || (b[0] != 0xeb && b[0] != 0xe9) {
+		return false
+	}
+	bps := binary.LittleEndian.Uint16(b[11:])
+	spc := b[13]
+	return (bps == 512 || bps == 1024 || bps == 2048 || bps == 4096) &&
+		spc != 0 && spc&(spc-1) == 0 && binary.LittleEndian.Uint16(b[14:]) != 0 && b[16] != 0
+}
+
+func openFAT(r io.ReaderAt) (driver, error) {
+	b := make([]byte, 512)
+	if _, err := r.ReadAt(b, 0); err != nil {
+		return nil, err
+	}
+	bps := int64(binary.LittleEndian.Uint16(b[11:]))
+	spc := int64(b[13])
+	reserved := int64(binary.LittleEndian.Uint16(b[14:]))
+	fats := int64(b[16])
+	rootEntries := int64(binary.LittleEndian.Uint16(b[17:]))
+	total := int64(binary.LittleEndian.Uint16(b[19:]))
+	if total == 0 {
+		total = int64(binary.LittleEndian.Uint32(b[32:]))
+	}
+	fatSize := int64(binary.LittleEndian.Uint16(b[22:]))
+	if fatSize == 0 {
+		fatSize = int64(binary.LittleEndian.Uint32(b[36:]))
+	}
+	rootSectors := (rootEntries*32 + bps - 1) / bps
+	dataStart := reserved + fats*fatSize + rootSectors
+	if fatSize == 0 || total <= dataStart {
+		return nil, fmt.Errorf("fat: bad geometry")
+	}
+	v := &fatVolume{
+		r:           r,
+		clusterSize: bps * spc,
+		fatOffset:   reserved * bps,
+		rootOffset:  (reserved + fats*fatSize) * bps,
+		rootSize:    rootSectors * bps,
+		dataOffset:  dataStart * bps,
+		clusters:    uint32((total - dataStart) / spc),
+	}
+	switch {
+	case v.clusters < 4085:
+		v.bits = 12
+	case v.clusters < 65525:
+		v.bits = 16
+	default:
+		v.bits = 32
+		v.rootCluster = binary.LittleEndian.Uint32(b[44:])
+	}
+	return v, nil
+}
+
+func (v *fatVolume) foldCase() bool { return true }
+
+func (v *fatVolume) root() (node, error) {
+	return node{ref: uint64(v.rootCluster), mode: fs.ModeDir | 0o555}, nil
+}
+
+// next returns the FAT entry for cluster c.
+func (v *fatVolume) next(c uint32) (uint32, error) {
+	var b [4]byte
+	switch v.bits {
+	case 12:
+		if _, err := v.r.ReadAt(b[:2], v.fatOffset+int64(c)+int64(c)/2); err != nil {
+			return 0, err
+		}
+		n := uint32(binary.LittleEndian.Uint16(b[:]))
+		if c&1 == 1 {
+			return n >> 4, nil
+		}
+		return n & 0xfff, nil
+	case 16:
+		if _, err := v.r.ReadAt(b[:2], v.fatOffset+2*int64(c)); err != nil {
+			return 0, err
+		}
+		return uint32(binary.LittleEndian.Uint16(b[:])), nil
+	default:
+		if _, err := v.r.ReadAt(b[:], v.fatOffset+4*int64(c)); err != nil {
+			return 0, err
+		}
+		return binary.LittleEndian.Uint32(b[:]) & 0x0fffffff, nil
+	}
+}
+
+// chain follows the cluster chain from first into extents.
+func (v *fatVolume) chain(first uint32) ([]extent, error) {
+	var extents []extent
+	var logical int64
+	for c, n := first, uint32(0); ; n++ {
+		if c < 2 || c >= v.clusters+2 {
+			return nil, fmt.Errorf("fat: cluster %d out of range", c)
+		}
+		if n > v.clusters {
+			return nil, fmt.Errorf("fat: cluster chain from %d loops", first)
+		}
+		extents = appendExtent(extents, extent{logical: logical, phys: v.dataOffset + int64(c-2)*v.clusterSize, length: v.clusterSize})
+		logical += v.clusterSize
+		next, err := v.next(c)
+		if err != nil {
+			return nil, err
+		}
+		if next >= v.endOfChain() {
+			return extents, nil
+		}
+		c = next
+	}
+}
+
+func (v *fatVolume) endOfChain() uint32 {
+	switch v.bits {
+	case 12:
+		return 0xff8
+	case 16:
+		return 0xfff8
+	default:
+		return 0x0ffffff8
+	}
+}
+
+func (v *fatVolume) readDir(dir node) ([]node, error) {
+	var raw []byte
+	if dir.ref == 0 {
+		raw = make([]byte, v.rootSize)
+		if _, err := v.r.ReadAt(raw, v.rootOffset); err != nil {
+			return nil, err
+		}
+	} else {
+		extents, err := v.chain(uint32(dir.ref))
+		if err != nil {
+			return nil, err
+		}
+		raw = make([]byte, extents[len(extents)-1].logical+extents[len(extents)-1].length)
+		if _, err := (&extentReader{r: v.r, extents: extents}).ReadAt(raw, 0); err != nil {
+			return nil, err
+		}
+	}
+	// A corrupt entry pointing back at this directory, its parent or the root would make the tree a loop.
+	parent, ok := fatParent(raw)
+	if !ok {
+		parent = dir.ref
+	}
+	nodes := parseFATDir(raw)
+	kept := nodes[:0]
+	for _, n := range nodes {
+		if n.mode.IsDir() && (n.ref == dir.ref || n.ref == parent || n.ref == 0 || n.ref == uint64(v.rootCluster)) {
+			continue
+		}
+		kept = append(kept, n)
+	}
+	return kept, nil
+}
+
+// fatParent returns the first cluster a directory's ".." entry names (0 for the root), or false for a
+// directory without one.
+func fatParent(raw []byte) (uint64, bool) {
+	if len(raw) < 64 || string(raw[32:43]) != "..         " {
+		return 0, false
+	}
+	return fatCluster(raw[32:64]), true
+}
+
+// fatCluster returns a directory entry's first cluster.
+func fatCluster(e []byte) uint64 {
+	return uint64(binary.LittleEndian.Uint16(e[20:]))<<16 | uint64(binary.LittleEndian.Uint16(e[26:]))
+}
+
+// parseFATDir decodes directory entries, using the long name when its checksum matches the short entry.
+func parseFATDir(raw []byte) []node {
+	var nodes []node
+	var lfn []uint16
+	var lfnSum byte
+	for i := 0; i+32 <= len(raw); i += 32 {
+		e := raw[i : i+32]
+		switch {
+		case e[0] == 0x00:
+			return nodes
+		case e[0] == 0xe5:
+			lfn = nil
+			continue
+		case e[11]&0x3f == fatAttrLFN:
+			seq := int(e[0] & 0x1f)
+			if e[0]&0x40 != 0 {
+				lfn, lfnSum = make([]uint16, 13*seq), e[13]
+			}
+			if lfn == nil || seq == 0 || 13*seq > len(lfn) || e[13] != lfnSum {
+				lfn = nil
+				continue
+			}
+			part := lfn[13*(seq-1):]
+			for j, off := range []int{1, 3, 5, 7, 9, 14, 16, 18, 20, 22, 24, 28, 30} {
+				part[j] = binary.LittleEndian.Uint16(e[off:])
+			}
+			continue
+		case e[11]&fatAttrVolume != 0:
+			lfn = nil
+			continue
+		}
+		name := shortName(e)
+		if lfn != nil && lfnChecksum(e[:11]) == lfnSum {
+			name = longName(lfn)
+		}
+		lfn = nil
+		if name == "." || name == ".." {
+			continue
+		}
+		n := node{
+			name:  name,
+			ref:   fatCluster(e),
+			size:  int64(binary.LittleEndian.Uint32(e[28:])),
+			mode:  0o444,
+			mtime: fatTime(binary.LittleEndian.Uint16(e[24:]), binary.LittleEndian.Uint16(e[22:])),
+		}
+		if e[11]&fatAttrDir != 0 {
+			n.mode, n.size = fs.ModeDir|0o555, 0
+		}
+		nodes = append(nodes, n)
+	}
+	return nodes
+}
+
+// shortName renders an 8.3 entry, honouring the lowercase flags Windows NT sets.
+func shortName(e []byte) string {
+	base := strings.TrimRight(string(e[:8]), " ")
+	ext := strings.TrimRight(string(e[8:11]), " ")
+	if base != "" && base[0] == 0x05 {
+		base = "\xe5" + base[1:]
+	}
+	if e[12]&0x08 != 0 {
+		base = strings.ToLower(base)
+	}
+	if e[12]&0x10 != 0 {
+		ext = strings.ToLower(ext)
+	}
+	if ext == "" {
+		return base
+	}
+	return base + "." + ext
+}
+
+func longName(u []uint16) string {
+	for i, c := range u {
+		if c == 0 || c == 0xffff {
+			u = u[:i]
+			break
+		}
+	}
+	return string(utf16.Decode(u))
+}
+
+func lfnChecksum(name []byte) byte {
+	var sum byte
+	for _, c := range name {
+		sum = (sum&1)<<7 + sum>>1 + c
+	}
+	return sum
+}
+
+// fatTime decodes a DOS date and time, which carry no zone; they are read as UTC.
+func fatTime(date, tm uint16) time.Time {
+	if date == 0 {
+		return time.Time{}
+	}
+	return time.Date(int(date>>9)+1980, time.Month(date>>5&0xf), int(date&0x1f),
+		int(tm>>11), int(tm>>5&0x3f), int(tm&0x1f)*2, 0, time.UTC)
+}
+
+func (v *fatVolume) open(file node) (node, io.ReaderAt, error) {
+	if file.size == 0 {
+		return file, bytes.NewReader(nil), nil
+	}
+	extents, err := v.chain(uint32(file.ref))
+	if err != nil {
+		return file, nil, err
+	}
+	return file, &extentReader{r: v.r, extents: extents}, nil
+}
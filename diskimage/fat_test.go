@@ -0,0 +1,316 @@
+package diskimage
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf16"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fatImage builds a FAT12/16/32 volume holding files (slash paths; directories are implied), with long
+// names where a name is not a plain 8.3 one. Clusters are handed out with a gap after each, so every
+// multi-cluster file and directory is fragmented.
+type fatImage struct {
+	bits        int
+	img         []byte
+	fat         []uint32
+	next        uint32
+	clusterSize int
+	dataOffset  int
+	rootOffset  int
+}
+
+var fatTestTime = time.Date(2024, 5, 6, 12, 34, 56, 0, time.UTC)
+
+func buildFAT(t *testing.T, bits int, files map[string][]byte) []byte {
+	t.Helper()
+	const bps = 512
+	clusters := map[int]int{12: 2000, 16: 8000, 32: 70000}[bits]
+	reserved, rootEntries := 1, 512
+	if bits == 32 {
+		reserved, rootEntries = 32, 0
+	}
+	fatSectors := ((clusters+2)*bits/8 + bps) / bps
+	rootSectors := rootEntries * 32 / bps
+	total := reserved + 2*fatSectors + rootSectors + clusters
+	f := &fatImage{
+		bits:        bits,
+		img:         make([]byte, total*bps),
+		fat:         make([]uint32, clusters+2),
+		next:        2,
+		clusterSize: bps,
+		rootOffset:  (reserved + 2*fatSectors) * bps,
+		dataOffset:  (reserved + 2*fatSectors + rootSectors) * bps,
+	}
+
+	b := f.img
+	copy(b, []byte{0xeb, 0x3c, 0x90})
+	copy(b[3:], "MSWIN4.1")
+	binary.LittleEndian.PutUint16(b[11:], bps)
+	b[13] = 1
+	binary.LittleEndian.PutUint16(b[14:], uint16(reserved))
+	b[16] = 2
+	binary.LittleEndian.PutUint16(b[17:], uint16(rootEntries))
+	b[21] = 0xf8
+	if total < 65536 && bits != 32 {
+		binary.LittleEndian.PutUint16(b[19:], uint16(total))
+	} else {
+		binary.LittleEndian.PutUint32(b[32:], uint32(total))
+	}
+	if bits == 32 {
+		binary.LittleEndian.PutUint32(b[36:], uint32(fatSectors))
+	} else {
+		binary.LittleEndian.PutUint16(b[22:], uint16(fatSectors))
+	}
+	b[510], b[511] = 0x55, 0xaa
+
+	tree := make(map[string][]string)
+	for p := range files {
+		for child := p; child != "."; child = path.Dir(child) {
+			parent := path.Dir(child)
+			if !containsString(tree[parent], child) {
+				tree[parent] = append(tree[parent], child)
+			}
+		}
+	}
+	root := f.writeDir(t, ".", tree, files, 0)
+	if bits == 32 {
+		binary.LittleEndian.PutUint32(b[44:], root)
+	}
+
+	fatStart := reserved * bps
+	f.fat[0], f.fat[1] = 0x0ffffff8, 0x0fffffff
+	for c, v := range f.fat {
+		switch bits {
+		case 12:
+			off := fatStart + c*3/2
+			cur := binary.LittleEndian.Uint16(b[off:])
+			if c&1 == 1 {
+				cur = cur&0x000f | uint16(v&0xfff)<<4
+			} else {
+				cur = cur&0xf000 | uint16(v&0xfff)
+			}
+			binary.LittleEndian.PutUint16(b[off:], cur)
+		case 16:
+			binary.LittleEndian.PutUint16(b[fatStart+2*c:], uint16(v))
+		default:
+			binary.LittleEndian.PutUint32(b[fatStart+4*c:], v&0x0fffffff)
+		}
+	}
+	copy(b[fatStart+fatSectors*bps:], b[fatStart:fatStart+fatSectors*bps])
+	return b
+}
+
+// alloc stores data in a fresh cluster chain and returns its first cluster (0 for no data).
+func (f *fatImage) alloc(t *testing.T, data []byte) uint32 {
+	var chain []uint32
+	for off := 0; off < len(data); off += f.clusterSize {
+		require.Less(t, int(f.next), len(f.fat), "fat test image full")
+		chain = append(chain, f.next)
+		copy(f.img[f.dataOffset+int(f.next-2)*f.clusterSize:], data[off:])
+		f.next += 2
+	}
+	for i, c := range chain {
+		if i+1 < len(chain) {
+			f.fat[c] = chain[i+1]
+		} else {
+			f.fat[c] = 0x0fffffff
+		}
+	}
+	if len(chain) == 0 {
+		return 0
+	}
+	return chain[0]
+}
+
+// writeDir writes dir's children, then dir itself, returning its first cluster (0 for a FAT12/16 root).
+func (f *fatImage) writeDir(t *testing.T, dir string, tree map[string][]string, files map[string][]byte, parent uint32) uint32 {
+	children := tree[dir]
+	sort.Strings(children)
+	var entries []byte
+	if dir != "." {
+		entries = append(entries, fatEntry(".          ", 0, fatAttrDir, 0, 0)...)
+		entries = append(entries, fatEntry("..         ", 0, fatAttrDir, parent, 0)...)
+	} else {
+		entries = append(entries, fatEntry("TESTVOL    ", 0, fatAttrVolume, 0, 0)...)
+	}
+	deleted := fatEntry("GONE    TXT", 0, 0, 0, 0)
+	deleted[0] = 0xe5
+	entries = append(entries, deleted...)
+
+	// A directory's own cluster must be known before its children are written, for their "..".
+	self := uint32(0)
+	if dir != "." || f.bits == 32 {
+		self = f.next
+		f.next += 2
+	}
+	for i, child := range children {
+		name := path.Base(child)
+		short := fmt.Sprintf("F%06d    ", i)
+		if upper := strings.ToUpper(name); upper == name && len(name) <= 8 && !strings.Contains(name, ".") {
+			short = fmt.Sprintf("%-11s", name)
+		} else {
+			entries = append(entries, lfnEntries(name, short)...)
+		}
+		if data, ok := files[child]; ok {
+			entries = append(entries, fatEntry(short, 0, 0x20, f.alloc(t, data), uint32(len(data)))...)
+		} else {
+			first := f.writeDir(t, child, tree, files, self)
+			entries = append(entries, fatEntry(short, 0, fatAttrDir, first, 0)...)
+		}
+	}
+	if self == 0 {
+		copy(f.img[f.rootOffset:], entries)
+		return 0
+	}
+	// the first cluster was reserved above; the rest of the directory goes after the children
+	copy(f.img[f.dataOffset+int(self-2)*f.clusterSize:], entries)
+	f.fat[self] = 0x0fffffff
+	if len(entries) > f.clusterSize {
+		f.fat[self] = f.alloc(t, entries[f.clusterSize:])
+	}
+	return self
+}
+
+func fatEntry(short string, lower byte, attr byte, cluster, size uint32) []byte {
+	e := make([]byte, 32)
+	copy(e, short)
+	e[11] = attr
+	e[12] = lower
+	binary.LittleEndian.PutUint16(e[20:], uint16(cluster>>16))
+	binary.LittleEndian.PutUint16(e[22:], uint16(fatTestTime.Hour()<<11|fatTestTime.Minute()<<5|fatTestTime.Second()/2))
+	binary.LittleEndian.PutUint16(e[24:], uint16((fatTestTime.Year()-1980)<<9|int(fatTestTime.Month())<<5|fatTestTime.Day()))
+	binary.LittleEndian.PutUint16(e[26:], uint16(cluster))
+	binary.LittleEndian.PutUint32(e[28:], size)
+	return e
+}
+
+// lfnEntries returns the VFAT entries for name, last part first as they are stored.
+func lfnEntries(name, short string) []byte {
+	u := utf16.Encode([]rune(name))
+	u = append(u, 0)
+	for len(u)%13 != 0 {
+		u = append(u, 0xffff)
+	}
+	sum := lfnChecksum([]byte(short))
+	var out []byte
+	for seq := len(u) / 13; seq >= 1; seq-- {
+		e := make([]byte, 32)
+		e[0] = byte(seq)
+		if seq == len(u)/13 {
+			e[0] |= 0x40
+		}
+		e[11], e[13] = fatAttrLFN, sum
+		for j, off := range []int{1, 3, 5, 7, 9, 14, 16, 18, 20, 22, 24, 28, 30} {
+			binary.LittleEndian.PutUint16(e[off:], u[13*(seq-1)+j])
+		}
+		out = append(out, e...)
+	}
+	return out
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+func TestFAT(t *testing.T) {
+	files := map[string][]byte{
+		"Users/alice/AppData/Local/Google/Chrome/User Data/Local State":        []byte(`{"os_crypt":{}}`),
+		"Users/alice/AppData/Local/Google/Chrome/User Data/Default/Login Data": pattern(5000, 1),
+		"README":   []byte("plain 8.3 name"),
+		"empty.db": {},
+	}
+	for _, bits := range []int{12, 16, 32} {
+		t.Run(fmt.Sprintf("FAT%d", bits), func(t *testing.T) {
+			img := buildFAT(t, bits, files)
+			r := bytes.NewReader(img)
+			parts, err := Partitions(r, int64(len(img)))
+			require.NoError(t, err)
+			require.Len(t, parts, 1, "a volume without a partition table is one whole-disk partition")
+
+			vol, err := OpenVolume(r, parts[0])
+			require.NoError(t, err)
+			assert.Equal(t, "fat", vol.Filesystem)
+			assert.Equal(t, "disk-fat", vol.Name())
+			assert.Equal(t, bits, vol.FS.(*imageFS).d.(*fatVolume).bits)
+			checkTree(t, vol.FS, files)
+
+			info, err := fs.Stat(vol.FS, "users/ALICE/appdata/local/google/chrome/user data/local state")
+			require.NoError(t, err, "FAT names match case-insensitively")
+			assert.Equal(t, fatTestTime, info.ModTime())
+		})
+	}
+}
+
+// TestFATDirectoryLoop plants directory entries pointing back at the directory itself, its parent, the
+// root and a grandparent; a walk must still finish and list each real file once.
+func TestFATDirectoryLoop(t *testing.T) {
+	files := map[string][]byte{"A/B/C/f.txt": []byte("data"), "top.txt": []byte("top")}
+	for _, bits := range []int{16, 32} {
+		t.Run(fmt.Sprintf("FAT%d", bits), func(t *testing.T) {
+			img := buildFAT(t, bits, files)
+			vol, err := OpenVolume(bytes.NewReader(img), Partition{})
+			require.NoError(t, err)
+			fsys := vol.FS.(*imageFS)
+			v := fsys.d.(*fatVolume)
+			ref := func(name string) uint32 {
+				n, err := fsys.dir("test", name)
+				require.NoError(t, err)
+				return uint32(n.ref)
+			}
+			a, b, c := ref("A"), ref("A/B"), ref("A/B/C")
+			plant := func(dir uint32, short string, target uint32) {
+				cluster := img[v.dataOffset+int64(dir-2)*v.clusterSize:][:v.clusterSize]
+				for off := 0; off+32 <= len(cluster); off += 32 {
+					if cluster[off] == 0 {
+						copy(cluster[off:], fatEntry(short, 0, fatAttrDir, target, 0))
+						return
+					}
+				}
+				t.Fatalf("no free entry in cluster %d", dir)
+			}
+			plant(b, "SELF       ", b)
+			plant(b, "UP         ", a)
+			plant(b, "ROOT       ", v.rootCluster)
+			plant(c, "GRAND      ", a)
+
+			vol, err = OpenVolume(bytes.NewReader(img), Partition{})
+			require.NoError(t, err)
+			var got []string
+			err = fs.WalkDir(vol.FS, ".", func(p string, d fs.DirEntry, err error) error {
+				if err != nil {
+					assert.ErrorIs(t, err, errDirLoop, p)
+					return nil
+				}
+				if !d.IsDir() {
+					got = append(got, p)
+				}
+				return nil
+			})
+			require.NoError(t, err)
+			assert.ElementsMatch(t, []string{"A/B/C/f.txt", "top.txt"}, got)
+
+			entries, err := fs.ReadDir(vol.FS, "A/B")
+			require.NoError(t, err)
+			require.Len(t, entries, 1, "entries naming B itself, its parent or the root are dropped")
+			_, err = fs.ReadDir(vol.FS, "A/B/C/GRAND")
+			assert.ErrorIs(t, err, errDirLoop)
+			_, err = vol.FS.Open("A/B/C/GRAND")
+			assert.ErrorIs(t, err, errDirLoop)
+		})
+	}
+}
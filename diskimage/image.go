@@ -0,0 +1,85 @@
+// Package diskimage reads browser data straight out of a disk image without mounting it. An image is a
+// raw (dd) dump or an EnCase (E01) evidence file; its MBR or GPT partitions are listed, and NTFS, FAT
+// and ext2/3/4 volumes are opened read-only as an fs.FS that the rest of the tool walks like a
+// directory. Nothing is ever written to the image.
+package diskimage
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+)
+
+// Image is an opened disk image: a byte-addressable view of the whole disk, whatever the container.
+type Image struct {
+	r      io.ReaderAt
+	size   int64
+	format string
+	files  []*os.File
+}
+
+// ErrUnsupportedFilesystem is returned by OpenVolume for a partition whose filesystem is recognized but
+// cannot be read (APFS, exFAT) or is not recognized at all.
+var ErrUnsupportedFilesystem = errors.New("unsupported filesystem")
+
+// Open opens the image at path: an EnCase E01 (its E02, E03, … segments are found beside it) when the
+// file starts with the EWF signature, a raw dump otherwise.
+func Open(path string) (*Image, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	sig := make([]byte, len(ewfSignature))
+	if _, err := f.ReadAt(sig, 0); err == nil {
+		switch {
+		case bytes.Equal(sig, ewfSignature):
+			_ = f.Close()
+			return openEWF(path)
+		case bytes.Equal(sig, ewf2Signature):
+			_ = f.Close()
+			return nil, fmt.Errorf("%s: EWF2 (Ex01) images are not supported; convert to E01 or raw", path)
+		}
+	}
+	info, err := f.Stat()
+	if err != nil {
+		_ = f.Close()
+		return nil, err
+	}
+	return &Image{r: f, size: info.Size(), format: "raw", files: []*os.File{f}}, nil
+}
+
+// ReadAt reads from the disk the image holds, at offset off from its first sector.
+func (img *Image) ReadAt(p []byte, off int64) (int, error) {
+	return img.r.ReadAt(p, off)
+}
+
+// Size is the size of the imaged disk in bytes.
+func (img *Image) Size() int64 {
+	return img.size
+}
+
+// Format is "raw" or "ewf".
+func (img *Image) Format() string {
+	return img.format
+}
+
+// Close closes every file of the image.
+func (img *Image) Close() error {
+	var errs []error
+	for _, f := range img.files {
+		errs = append(errs, f.Close())
+	}
+	return errors.Join(errs...)
+}
+
+// Partitions lists the image's partitions (see Partitions).
+func (img *Image) Partitions() ([]Partition, error) {
+	return Partitions(img, img.size)
+}
+
+// OpenVolume opens the filesystem on partition p.
+func (img *Image) OpenVolume(p Partition) (*Volume, error) {
+	return OpenVolume(io.NewSectionReader(img, p.Start, p.Size), p)
+}
@@ -0,0 +1,276 @@
+package diskimage
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"hash/adler32"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	volumeAFiles = map[string][]byte{"Users/alice/NTUSER.DAT": pattern(1500, 7)}
+	volumeBFiles = map[string][]byte{"data/Local State": []byte(`{"os_crypt":{}}`)}
+)
+
+// mbrDisk lays out two FAT volumes: a primary partition and a logical one inside an extended partition.
+func mbrDisk(t *testing.T) []byte {
+	a := buildFAT(t, 12, volumeAFiles)
+	b := buildFAT(t, 12, volumeBFiles)
+	aStart, aLen := 1, len(a)/512
+	extStart, bLen := aStart+aLen, len(b)/512
+	disk := make([]byte, (extStart+1+bLen)*512)
+	putMBREntry(disk, 0, 0x06, aStart, aLen)
+	putMBREntry(disk, 1, mbrExtendedLBA, extStart, 1+bLen)
+	disk[510], disk[511] = 0x55, 0xaa
+	ebr := disk[extStart*512:]
+	putMBREntry(ebr, 0, 0x01, 1, bLen) // relative to this EBR
+	ebr[510], ebr[511] = 0x55, 0xaa
+	copy(disk[aStart*512:], a)
+	copy(disk[(extStart+1)*512:], b)
+	return disk
+}
+
+func putMBREntry(sector []byte, slot int, kind byte, lba, sectors int) {
+	e := sector[446+16*slot:]
+	e[4] = kind
+	binary.LittleEndian.PutUint32(e[8:], uint32(lba))
+	binary.LittleEndian.PutUint32(e[12:], uint32(sectors))
+}
+
+// gptDisk puts a FAT volume and an APFS container behind a protective MBR.
+func gptDisk(t *testing.T) []byte {
+	a := buildFAT(t, 16, volumeAFiles)
+	aStart, aLen := 34, len(a)/512
+	apfsStart := aStart + aLen
+	disk := make([]byte, (apfsStart+8)*512)
+	putMBREntry(disk, 0, mbrProtective, 1, len(disk)/512-1)
+	disk[510], disk[511] = 0x55, 0xaa
+
+	h := disk[512:]
+	copy(h, "EFI PART")
+	binary.LittleEndian.PutUint64(h[72:], 2)
+	binary.LittleEndian.PutUint32(h[80:], 128)
+	binary.LittleEndian.PutUint32(h[84:], 128)
+	putGPTEntry(disk[2*512:], "A2A0D0EB-E5B9-3344-87C0-68B6B72699C7", aStart, apfsStart-1, "Basic data partition")
+	putGPTEntry(disk[2*512+128:], "EF57347C-0000-AA11-AA11-00306543ECAC", apfsStart, apfsStart+7, "")
+	copy(disk[aStart*512:], a)
+	copy(disk[apfsStart*512+32:], "NXSB")
+	return disk
+}
+
+// putGPTEntry writes an entry whose type GUID is given in on-disk byte order.
+func putGPTEntry(e []byte, typeBytes string, first, last int, name string) {
+	guid, _ := hex.DecodeString(strings.ReplaceAll(typeBytes, "-", ""))
+	copy(e, guid)
+	e[16] = 1 // unique GUID
+	binary.LittleEndian.PutUint64(e[32:], uint64(first))
+	binary.LittleEndian.PutUint64(e[40:], uint64(last))
+	for i, c := range name {
+		binary.LittleEndian.PutUint16(e[56+2*i:], uint16(c))
+	}
+}
+
+func TestPartitions_MBR(t *testing.T) {
+	disk := mbrDisk(t)
+	parts, err := Partitions(bytes.NewReader(disk), int64(len(disk)))
+	require.NoError(t, err)
+	require.Len(t, parts, 2)
+	assert.Equal(t, 1, parts[0].Index)
+	assert.Equal(t, "0x06", parts[0].Type)
+	assert.Equal(t, int64(512), parts[0].Start)
+	assert.Equal(t, 5, parts[1].Index, "logical partitions are numbered after the four primary slots")
+	assert.Equal(t, "0x01", parts[1].Type)
+}
+
+func TestPartitions_GPT(t *testing.T) {
+	disk := gptDisk(t)
+	parts, err := Partitions(bytes.NewReader(disk), int64(len(disk)))
+	require.NoError(t, err)
+	require.Len(t, parts, 2)
+	assert.Equal(t, "EBD0A0A2-B9E5-4433-87C0-68B6B72699C7", parts[0].Type)
+	assert.Equal(t, "Basic data partition", parts[0].Name)
+	assert.Equal(t, int64(34*512), parts[0].Start)
+
+	r := bytes.NewReader(disk)
+	vol, err := OpenVolume(io.NewSectionReader(r, parts[0].Start, parts[0].Size), parts[0])
+	require.NoError(t, err)
+	assert.Equal(t, "p1-fat", vol.Name())
+	checkTree(t, vol.FS, volumeAFiles)
+
+	_, err = OpenVolume(io.NewSectionReader(r, parts[1].Start, parts[1].Size), parts[1])
+	assert.True(t, errors.Is(err, ErrUnsupportedFilesystem))
+	assert.Contains(t, err.Error(), "apfs")
+}
+
+func TestPartitions_GPTBadHeader(t *testing.T) {
+	for _, tt := range []struct {
+		name             string
+		count, entrySize uint32
+	}{
+		{"entry size not a power of two", 128, 200},
+		{"huge entries", 4, 1 << 31},
+		{"entry array too large", 1 << 20, 128},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			disk := gptDisk(t)
+			binary.LittleEndian.PutUint32(disk[512+80:], tt.count)
+			binary.LittleEndian.PutUint32(disk[512+84:], tt.entrySize)
+			_, err := gptPartitions(bytes.NewReader(disk), int64(len(disk)))
+			assert.ErrorContains(t, err, "gpt:")
+		})
+	}
+}
+
+func TestOpen_Raw(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "disk.dd")
+	require.NoError(t, os.WriteFile(path, mbrDisk(t), 0o600))
+	img, err := Open(path)
+	require.NoError(t, err)
+	defer img.Close()
+	assert.Equal(t, "raw", img.Format())
+	checkImage(t, img)
+}
+
+func TestOpen_E01(t *testing.T) {
+	disk := mbrDisk(t)
+	for _, ext := range []string{".E01", ".e01"} {
+		t.Run(ext, func(t *testing.T) {
+			first := writeE01(t, filepath.Join(t.TempDir(), "disk"+ext), disk, 64*512, 20)
+			img, err := Open(first)
+			require.NoError(t, err)
+			defer img.Close()
+			assert.Equal(t, "ewf", img.Format())
+			assert.Greater(t, len(img.files), 1, "the image spans several segments")
+			assert.Equal(t, int64(len(disk)), img.Size())
+
+			got := make([]byte, len(disk))
+			_, err = img.ReadAt(got, 0)
+			require.NoError(t, err)
+			assert.True(t, bytes.Equal(disk, got))
+			checkImage(t, img)
+		})
+	}
+}
+
+func TestEWFSegmentPath(t *testing.T) {
+	assert.Equal(t, "/x/case.E02", ewfSegmentPath("/x/case.E01", 2))
+	assert.Equal(t, "/x/case.E99", ewfSegmentPath("/x/case.E01", 99))
+	assert.Equal(t, "/x/case.EAA", ewfSegmentPath("/x/case.E01", 100))
+	assert.Equal(t, "/x/case.EAB", ewfSegmentPath("/x/case.E01", 101))
+	assert.Equal(t, "/x/case.FAA", ewfSegmentPath("/x/case.E01", 100+26*26))
+	assert.Equal(t, "/x/case.e10", ewfSegmentPath("/x/case.e01", 10))
+}
+
+// checkImage opens both volumes of an mbrDisk image.
+func checkImage(t *testing.T, img *Image) {
+	parts, err := img.Partitions()
+	require.NoError(t, err)
+	require.Len(t, parts, 2)
+	for i, want := range []map[string][]byte{volumeAFiles, volumeBFiles} {
+		vol, err := img.OpenVolume(parts[i])
+		require.NoError(t, err)
+		checkTree(t, vol.FS, want)
+	}
+}
+
+// writeE01 writes disk as an EWF image in segments of at most perSegment chunks of chunkSize bytes,
+// storing even chunks zlib-compressed and odd ones raw with their Adler-32, and returns the first
+// segment's path.
+func writeE01(t *testing.T, first string, disk []byte, chunkSize, perSegment int) string {
+	t.Helper()
+	chunks := (len(disk) + chunkSize - 1) / chunkSize
+	for seg, start := 1, 0; start < chunks; seg++ {
+		end := start + perSegment
+		if end > chunks {
+			end = chunks
+		}
+		var f bytes.Buffer
+		f.Write(ewfSignature)
+		f.WriteByte(1)
+		_ = binary.Write(&f, binary.LittleEndian, uint16(seg))
+		f.Write([]byte{0, 0})
+
+		if seg == 1 {
+			v := make([]byte, 1052)
+			binary.LittleEndian.PutUint32(v[4:], uint32(chunks))
+			binary.LittleEndian.PutUint32(v[8:], uint32(chunkSize/512))
+			binary.LittleEndian.PutUint32(v[12:], 512)
+			binary.LittleEndian.PutUint64(v[16:], uint64(len(disk)/512))
+			writeEWFSection(&f, "volume", v)
+		}
+
+		sectorsAt := f.Len()
+		var data []byte
+		var offsets []uint32
+		for i := start; i < end; i++ {
+			chunkEnd := (i + 1) * chunkSize
+			if chunkEnd > len(disk) {
+				chunkEnd = len(disk)
+			}
+			chunk := disk[i*chunkSize : chunkEnd]
+			offset := uint32(ewfSectionLen + len(data)) // relative to the table's base
+			if i%2 == 0 {
+				var z bytes.Buffer
+				zw := zlib.NewWriter(&z)
+				_, _ = zw.Write(chunk)
+				require.NoError(t, zw.Close())
+				data = append(data, z.Bytes()...)
+				offset |= 0x80000000
+			} else {
+				data = append(data, chunk...)
+				data = binary.LittleEndian.AppendUint32(data, adler32.Checksum(chunk))
+			}
+			offsets = append(offsets, offset)
+		}
+		writeEWFSection(&f, "sectors", data)
+
+		table := make([]byte, ewfTableHeadLen)
+		binary.LittleEndian.PutUint32(table, uint32(len(offsets)))
+		binary.LittleEndian.PutUint64(table[8:], uint64(sectorsAt))
+		for _, o := range offsets {
+			table = binary.LittleEndian.AppendUint32(table, o)
+		}
+		table = append(table, 0, 0, 0, 0)
+		writeEWFSection(&f, "table", table)
+		writeEWFSection(&f, "table2", table)
+		if end == chunks {
+			writeEWFSection(&f, "done", nil)
+		} else {
+			writeEWFSection(&f, "next", nil)
+		}
+
+		path := first
+		if seg > 1 {
+			path = ewfSegmentPath(first, seg)
+		}
+		require.NoError(t, os.WriteFile(path, f.Bytes(), 0o600))
+		start = end
+	}
+	return first
+}
+
+func writeEWFSection(f *bytes.Buffer, kind string, data []byte) {
+	d := make([]byte, ewfSectionLen)
+	copy(d, kind)
+	at := f.Len()
+	size := ewfSectionLen + len(data)
+	next := at + size
+	if kind == "done" || kind == "next" {
+		next = at
+	}
+	binary.LittleEndian.PutUint64(d[16:], uint64(next))
+	binary.LittleEndian.PutUint64(d[24:], uint64(size))
+	binary.LittleEndian.PutUint32(d[72:], adler32.Checksum(d[:72]))
+	f.Write(d)
+	f.Write(data)
+}
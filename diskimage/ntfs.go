@@ -0,0 +1,508 @@
+package diskimage
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"io/fs"
+	"sort"
+	"time"
+)
+
+const (
+	ntfsAttrStandardInfo = 0x10
+	ntfsAttrList         = 0x20
+	ntfsAttrData         = 0x80
+	ntfsAttrIndexRoot    = 0x90
+	ntfsAttrIndexAlloc   = 0xa0
+	ntfsAttrEnd          = 0xffffffff
+
+	ntfsRecordInUse = 0x01
+
+	ntfsAttrCompressed = 0x0001
+	ntfsAttrEncrypted  = 0x4000
+
+	ntfsIndexSubnode = 0x01
+	ntfsIndexLast    = 0x02
+
+	ntfsNamespaceDOS = 2
+	ntfsRootRecord   = 5
+	// Records below 16 are the volume's metadata files ($MFT, $LogFile, …); listings leave them out.
+	ntfsFirstUserRecord = 16
+	ntfsMaxIndexDepth   = 32
+	// An $ATTRIBUTE_LIST is capped at 256 KiB by NTFS itself.
+	ntfsMaxAttrList = 256 << 10
+)
+
+// ntfsVolume reads NTFS: every file is a record in the $MFT, directories are B-trees of $FILE_NAME keys
+// in $INDEX_ROOT / $INDEX_ALLOCATION, and data is resident in the record or spread over runs of
+// clusters. Compressed and EFS-encrypted streams are reported rather than read.
+type ntfsVolume struct {
+	r           io.ReaderAt
+	clusterSize int64
+	recordSize  int64
+	indexSize   int64
+	mft         io.ReaderAt
+}
+
+// ntfsAttr is one attribute of a file record.
+type ntfsAttr struct {
+	kind     uint32
+	name     string
+	flags    uint16
+	resident []byte // value of a resident attribute
+	// non-resident attributes
+	nonResident bool
+	startVCN    int64
+	dataSize    int64
+	runs        []byte
+}
+
+func openNTFS(r io.ReaderAt) (driver, error) {
+	b := make([]byte, 512)
+	if _, err := r.ReadAt(b, 0); err != nil {
+		return nil, err
+	}
+	bps := int64(binary.LittleEndian.Uint16(b[11:]))
+	spc := int64(b[13])
+	if spc > 0x80 {
+		spc = 1 << (256 - spc)
+	}
+	v := &ntfsVolume{r: r, clusterSize: bps * spc}
+	v.recordSize = ntfsUnitSize(int8(b[0x40]), v.clusterSize)
+	v.indexSize = ntfsUnitSize(int8(b[0x44]), v.clusterSize)
+	if bps == 0 || v.clusterSize == 0 || v.recordSize < 512 || v.recordSize > 1<<16 || v.indexSize < 512 {
+		return nil, fmt.Errorf("ntfs: bad boot sector")
+	}
+
+	// $MFT describes itself: record 0's $DATA runs map the rest of the table. Its base record is read
+	// first, so that an $ATTRIBUTE_LIST can then be followed into the extension records it names.
+	mftStart := int64(binary.LittleEndian.Uint64(b[0x30:])) * v.clusterSize
+	v.mft = io.NewSectionReader(r, mftStart, v.recordSize)
+	rec, err := v.record(0)
+	if err != nil {
+		return nil, err
+	}
+	if v.mft, _, err = v.stream(parseAttributes(rec), ntfsAttrData, ""); err != nil {
+		return nil, fmt.Errorf("ntfs: $MFT: %w", err)
+	}
+	attrs, err := v.attributes(0)
+	if err != nil {
+		return nil, fmt.Errorf("ntfs: $MFT: %w", err)
+	}
+	mft, _, err := v.stream(attrs, ntfsAttrData, "")
+	if err != nil {
+		return nil, fmt.Errorf("ntfs: $MFT: %w", err)
+	}
+	v.mft = mft
+	return v, nil
+}
+
+// ntfsUnitSize decodes a boot-sector size field: clusters when positive, 2^-n bytes when negative.
+func ntfsUnitSize(n int8, clusterSize int64) int64 {
+	if n > 0 {
+		return int64(n) * clusterSize
+	}
+	return 1 << uint(-n)
+}
+
+func (v *ntfsVolume) foldCase() bool { return true }
+
+func (v *ntfsVolume) root() (node, error) {
+	return v.stat(node{ref: ntfsRootRecord})
+}
+
+// record reads MFT record num and applies its update sequence.
+func (v *ntfsVolume) record(num uint64) ([]byte, error) {
+	b := make([]byte, v.recordSize)
+	if _, err := v.mft.ReadAt(b, int64(num)*v.recordSize); err != nil {
+		return nil, fmt.Errorf("ntfs: record %d: %w", num, err)
+	}
+	if !bytes.Equal(b[:4], []byte("FILE")) {
+		return nil, fmt.Errorf("ntfs: record %d: bad signature", num)
+	}
+	if err := ntfsFixup(b); err != nil {
+		return nil, fmt.Errorf("ntfs: record %d: %w", num, err)
+	}
+	return b, nil
+}
+
+// ntfsFixup restores the last two bytes of every 512-byte stride of a FILE or INDX block from the
+// update sequence array, checking each against the sequence number.
+func ntfsFixup(b []byte) error {
+	off := int(binary.LittleEndian.Uint16(b[4:]))
+	count := int(binary.LittleEndian.Uint16(b[6:]))
+	if count == 0 || off+2*count > len(b) || (count-1)*512 > len(b) {
+		return fmt.Errorf("bad update sequence")
+	}
+	usn := b[off : off+2]
+	for i := 1; i < count; i++ {
+		end := i*512 - 2
+		if !bytes.Equal(b[end:end+2], usn) {
+			return fmt.Errorf("torn write in stride %d", i)
+		}
+		copy(b[end:end+2], b[off+2*i:off+2*i+2])
+	}
+	return nil
+}
+
+// parseAttributes decodes a record's attributes.
+func parseAttributes(rec []byte) []ntfsAttr {
+	le := binary.LittleEndian
+	var attrs []ntfsAttr
+	for off := int(le.Uint16(rec[20:])); off+16 <= len(rec); {
+		a := rec[off:]
+		kind := le.Uint32(a)
+		length := int(le.Uint32(a[4:]))
+		if kind == ntfsAttrEnd || length < 16 || off+length > len(rec) {
+			break
+		}
+		a = a[:length]
+		attr := ntfsAttr{kind: kind, flags: le.Uint16(a[12:])}
+		if n := int(a[9]); n > 0 {
+			start := int(le.Uint16(a[10:]))
+			if start+2*n <= length {
+				attr.name = decodeUTF16(a[start : start+2*n])
+			}
+		}
+		if a[8] == 0 && length >= 24 {
+			size := int64(le.Uint32(a[16:]))
+			start := int64(le.Uint16(a[20:]))
+			if start+size <= int64(length) {
+				attr.resident = a[start : start+size]
+			}
+		} else if length >= 64 {
+			attr.nonResident = true
+			attr.startVCN = int64(le.Uint64(a[16:]))
+			attr.dataSize = int64(le.Uint64(a[48:]))
+			if start := int(le.Uint16(a[32:])); start < length {
+				attr.runs = a[start:]
+			}
+		}
+		attrs = append(attrs, attr)
+		off += length
+	}
+	return attrs
+}
+
+// attributes returns the attributes of record num, following an $ATTRIBUTE_LIST into the extension
+// records a heavily fragmented or hard-linked file spills into.
+func (v *ntfsVolume) attributes(num uint64) ([]ntfsAttr, error) {
+	rec, err := v.record(num)
+	if err != nil {
+		return nil, err
+	}
+	if binary.LittleEndian.Uint16(rec[22:])&ntfsRecordInUse == 0 {
+		return nil, fmt.Errorf("ntfs: record %d is not in use", num)
+	}
+	attrs := parseAttributes(rec)
+	var list []byte
+	for _, a := range attrs {
+		if a.kind == ntfsAttrList {
+			if a.nonResident {
+				if a.dataSize < 0 || a.dataSize > ntfsMaxAttrList {
+					return nil, fmt.Errorf("ntfs: record %d: attribute list of %d bytes", num, a.dataSize)
+				}
+				r, _, err := v.runReader([]ntfsAttr{a})
+				if err != nil {
+					return nil, err
+				}
+				list = make([]byte, a.dataSize)
+				if _, err := r.ReadAt(list, 0); err != nil {
+					return nil, err
+				}
+			} else {
+				list = a.resident
+			}
+		}
+	}
+	if list == nil {
+		return attrs, nil
+	}
+	seen := map[uint64]bool{num: true}
+	for off := 0; off+26 <= len(list); {
+		length := int(binary.LittleEndian.Uint16(list[off+4:]))
+		ext := binary.LittleEndian.Uint64(list[off+16:]) & 0xffffffffffff
+		if length < 26 {
+			break
+		}
+		off += length
+		if seen[ext] {
+			continue
+		}
+		seen[ext] = true
+		extRec, err := v.record(ext)
+		if err != nil {
+			return nil, err
+		}
+		attrs = append(attrs, parseAttributes(extRec)...)
+	}
+	return attrs, nil
+}
+
+// stream returns a reader over the named attribute of the given kind and its size.
+func (v *ntfsVolume) stream(attrs []ntfsAttr, kind uint32, name string) (io.ReaderAt, int64, error) {
+	var parts []ntfsAttr
+	for _, a := range attrs {
+		if a.kind != kind || a.name != name {
+			continue
+		}
+		if a.flags&ntfsAttrCompressed != 0 {
+			return nil, 0, fmt.Errorf("ntfs: compressed data is not supported")
+		}
+		if a.flags&ntfsAttrEncrypted != 0 {
+			return nil, 0, fmt.Errorf("ntfs: EFS-encrypted data is not supported")
+		}
+		if !a.nonResident {
+			return bytes.NewReader(a.resident), int64(len(a.resident)), nil
+		}
+		parts = append(parts, a)
+	}
+	if len(parts) == 0 {
+		return nil, 0, fs.ErrNotExist
+	}
+	return v.runReader(parts)
+}
+
+// runReader maps the runs of a non-resident attribute, possibly split over several records by VCN
+// range, into extents; the size comes from the piece starting at VCN 0.
+func (v *ntfsVolume) runReader(parts []ntfsAttr) (io.ReaderAt, int64, error) {
+	sort.Slice(parts, func(i, j int) bool { return parts[i].startVCN < parts[j].startVCN })
+	var extents []extent
+	var size int64
+	for _, a := range parts {
+		if a.startVCN == 0 {
+			size = a.dataSize
+		}
+		var err error
+		if extents, err = v.decodeRuns(extents, a.runs, a.startVCN); err != nil {
+			return nil, 0, err
+		}
+	}
+	return &extentReader{r: v.r, extents: extents}, size, nil
+}
+
+// decodeRuns decodes a runlist: each run is a header byte giving the byte widths of a length and of a
+// signed LCN delta from the previous run; a run without an LCN is sparse.
+func (v *ntfsVolume) decodeRuns(extents []extent, runs []byte, vcn int64) ([]extent, error) {
+	var lcn int64
+	for i := 0; i < len(runs) && runs[i] != 0; {
+		lenBytes, offBytes := int(runs[i]&0x0f), int(runs[i]>>4)
+		i++
+		if lenBytes == 0 || lenBytes > 8 || offBytes > 8 || i+lenBytes+offBytes > len(runs) {
+			return nil, fmt.Errorf("ntfs: bad runlist")
+		}
+		length := leInt(runs[i:i+lenBytes], false)
+		i += lenBytes
+		phys := int64(-1)
+		if offBytes > 0 {
+			lcn += leInt(runs[i:i+offBytes], true)
+			phys = lcn * v.clusterSize
+			i += offBytes
+		}
+		extents = appendExtent(extents, extent{logical: vcn * v.clusterSize, phys: phys, length: length * v.clusterSize})
+		vcn += length
+	}
+	return extents, nil
+}
+
+// leInt decodes a little-endian integer of up to 8 bytes, sign-extending it when signed.
+func leInt(b []byte, signed bool) int64 {
+	var n uint64
+	for i := len(b) - 1; i >= 0; i-- {
+		n = n<<8 | uint64(b[i])
+	}
+	if signed && len(b) < 8 && b[len(b)-1]&0x80 != 0 {
+		n |= ^uint64(0) << (8 * uint(len(b)))
+	}
+	return int64(n)
+}
+
+// stat builds a node from the record's own metadata: the $STANDARD_INFORMATION modification time and
+// the unnamed $DATA size.
+func (v *ntfsVolume) stat(n node) (node, error) {
+	attrs, err := v.attributes(n.ref)
+	if err != nil {
+		return n, err
+	}
+	return v.statAttrs(n, attrs), nil
+}
+
+func (v *ntfsVolume) statAttrs(n node, attrs []ntfsAttr) node {
+	n.mode = 0o444
+	for _, a := range attrs {
+		switch {
+		case a.kind == ntfsAttrStandardInfo && len(a.resident) >= 16:
+			n.mtime = ntfsTime(binary.LittleEndian.Uint64(a.resident[8:]))
+		case a.kind == ntfsAttrIndexRoot && a.name == "$I30":
+			n.mode = fs.ModeDir | 0o555
+		case a.kind == ntfsAttrData && a.name == "":
+			if a.nonResident && a.startVCN == 0 {
+				n.size = a.dataSize
+			} else if !a.nonResident {
+				n.size = int64(len(a.resident))
+			}
+		}
+	}
+	if n.mode.IsDir() {
+		n.size = 0
+	}
+	return n
+}
+
+// ntfsTime converts a FILETIME (100 ns ticks since 1601) to time.Time.
+func ntfsTime(ft uint64) time.Time {
+	if ft == 0 {
+		return time.Time{}
+	}
+	const epochDelta = 116444736000000000 // 1601 to 1970 in ticks
+	ticks := int64(ft) - epochDelta
+	return time.Unix(ticks/1e7, ticks%1e7*100).UTC()
+}
+
+func (v *ntfsVolume) readDir(dir node) ([]node, error) {
+	attrs, err := v.attributes(dir.ref)
+	if err != nil {
+		return nil, err
+	}
+	var rootAttr []byte
+	for _, a := range attrs {
+		if a.kind == ntfsAttrIndexRoot && a.name == "$I30" {
+			rootAttr = a.resident
+		}
+	}
+	if len(rootAttr) < 32 {
+		return nil, fmt.Errorf("ntfs: record %d has no directory index", dir.ref)
+	}
+	var alloc io.ReaderAt
+	if r, _, err := v.stream(attrs, ntfsAttrIndexAlloc, "$I30"); err == nil {
+		alloc = r
+	}
+
+	w := &ntfsIndexWalk{v: v, dir: dir.ref, alloc: alloc, seen: make(map[ntfsName]bool), visited: make(map[int64]bool)}
+	if err := w.entries(rootAttr[16:], 0); err != nil {
+		return nil, err
+	}
+	// The size and times in an index key are only refreshed now and then; a file's own record has the
+	// real ones.
+	for i, n := range w.nodes {
+		if n.mode.IsDir() {
+			continue
+		}
+		if w.nodes[i], err = v.stat(n); err != nil {
+			return nil, err
+		}
+	}
+	return w.nodes, nil
+}
+
+// ntfsIndexWalk collects the $FILE_NAME keys of one directory's index, visiting every node of its
+// B-tree once.
+type ntfsIndexWalk struct {
+	v       *ntfsVolume
+	dir     uint64
+	alloc   io.ReaderAt
+	nodes   []node
+	seen    map[ntfsName]bool
+	visited map[int64]bool
+}
+
+type ntfsName struct {
+	ref  uint64
+	name string
+}
+
+// entries reads the index entries under an index header (the one in $INDEX_ROOT or an INDX block's).
+func (w *ntfsIndexWalk) entries(header []byte, depth int) error {
+	le := binary.LittleEndian
+	if depth > ntfsMaxIndexDepth {
+		return fmt.Errorf("ntfs: index too deep")
+	}
+	start := int(le.Uint32(header))
+	end := int(le.Uint32(header[4:]))
+	if end > len(header) {
+		end = len(header)
+	}
+	for off := start; off+16 <= end; {
+		e := header[off:]
+		length := int(le.Uint16(e[8:]))
+		keyLen := int(le.Uint16(e[10:]))
+		flags := le.Uint32(e[12:])
+		if length < 16 || off+length > end {
+			return fmt.Errorf("ntfs: bad index entry")
+		}
+		if flags&ntfsIndexSubnode != 0 {
+			if err := w.subnode(int64(le.Uint64(e[length-8:])), depth); err != nil {
+				return err
+			}
+		}
+		if flags&ntfsIndexLast != 0 {
+			return nil
+		}
+		if keyLen >= 66 && 16+keyLen <= length {
+			w.add(le.Uint64(e)&0xffffffffffff, e[16:16+keyLen])
+		}
+		off += length
+	}
+	return nil
+}
+
+// subnode reads the INDX block at vcn, counted in clusters or, for index blocks smaller than a cluster,
+// in 512-byte units.
+func (w *ntfsIndexWalk) subnode(vcn int64, depth int) error {
+	if w.alloc == nil {
+		return fmt.Errorf("ntfs: index subnode without an allocation")
+	}
+	if w.visited[vcn] {
+		return nil
+	}
+	w.visited[vcn] = true
+	unit := w.v.clusterSize
+	if w.v.indexSize < w.v.clusterSize {
+		unit = 512
+	}
+	b := make([]byte, w.v.indexSize)
+	if _, err := w.alloc.ReadAt(b, vcn*unit); err != nil {
+		return fmt.Errorf("ntfs: index block %d: %w", vcn, err)
+	}
+	if !bytes.Equal(b[:4], []byte("INDX")) {
+		return fmt.Errorf("ntfs: index block %d: bad signature", vcn)
+	}
+	if err := ntfsFixup(b); err != nil {
+		return fmt.Errorf("ntfs: index block %d: %w", vcn, err)
+	}
+	return w.entries(b[24:], depth+1)
+}
+
+// add records one $FILE_NAME key, leaving out DOS 8.3 aliases, metadata files and a corrupt key naming
+// the directory itself. A record indexed under two names (a hard link) is listed once per name.
+func (w *ntfsIndexWalk) add(ref uint64, key []byte) {
+	nameLen := int(key[64])
+	if key[65] == ntfsNamespaceDOS || ref < ntfsFirstUserRecord || ref == w.dir || 66+2*nameLen > len(key) {
+		return
+	}
+	name := decodeUTF16(key[66 : 66+2*nameLen])
+	if w.seen[ntfsName{ref, name}] {
+		return
+	}
+	w.seen[ntfsName{ref, name}] = true
+	le := binary.LittleEndian
+	n := node{name: name, ref: ref, mode: 0o444, mtime: ntfsTime(le.Uint64(key[24:])), size: int64(le.Uint64(key[48:]))}
+	if le.Uint32(key[56:])&0x10000000 != 0 {
+		n.mode, n.size = fs.ModeDir|0o555, 0
+	}
+	w.nodes = append(w.nodes, n)
+}
+
+func (v *ntfsVolume) open(file node) (node, io.ReaderAt, error) {
+	attrs, err := v.attributes(file.ref)
+	if err != nil {
+		return file, nil, err
+	}
+	r, _, err := v.stream(attrs, ntfsAttrData, "")
+	if err != nil {
+		return file, nil, err
+	}
+	return v.statAttrs(file, attrs), r, nil
+}
@@ -0,0 +1,361 @@
+package diskimage
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io/fs"
+	"testing"
+	"time"
+	"unicode/utf16"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// ntfsImage builds a small NTFS volume: 4 KiB clusters, 1 KiB records and a 64-record $MFT split over
+// two runs, so records past the first run are only found through $MFT's own runlist.
+type ntfsImage struct {
+	img []byte
+}
+
+const (
+	ntfsTestCluster = 4096
+	ntfsTestRecord  = 1024
+	ntfsTestIndex   = 4096
+)
+
+var ntfsTestTime = time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+
+// ntfsRun is one run of clusters; lcn -1 is sparse.
+type ntfsRun struct{ lcn, length int64 }
+
+// mftRuns put records 0-31 at cluster 4 and 32-63 at cluster 100.
+var mftRuns = []ntfsRun{{4, 8}, {100, 8}}
+
+func newNTFSImage() *ntfsImage {
+	n := &ntfsImage{img: make([]byte, 512*ntfsTestCluster)}
+	b := n.img
+	copy(b, []byte{0xeb, 0x52, 0x90})
+	copy(b[3:], "NTFS    ")
+	binary.LittleEndian.PutUint16(b[11:], 512)
+	b[13] = 8
+	b[21] = 0xf8
+	binary.LittleEndian.PutUint64(b[40:], uint64(len(b)/512-1))
+	binary.LittleEndian.PutUint64(b[48:], uint64(mftRuns[0].lcn))
+	b[0x40] = 0xf6 // 2^10-byte records
+	b[0x44] = 1    // one-cluster index blocks
+	b[510], b[511] = 0x55, 0xaa
+
+	n.writeRecord(0, ntfsRecordInUse,
+		ntfsResident(ntfsAttrStandardInfo, "", ntfsStdInfo()),
+		ntfsNonResident(ntfsAttrData, "", mftRuns, 64*ntfsTestRecord))
+	return n
+}
+
+// recordOffset maps a record number through mftRuns.
+func recordOffset(num int) int {
+	perRun := 8 * ntfsTestCluster / ntfsTestRecord
+	run := mftRuns[num/perRun]
+	return int(run.lcn)*ntfsTestCluster + num%perRun*ntfsTestRecord
+}
+
+func (n *ntfsImage) writeRecord(num int, flags uint16, attrs ...[]byte) {
+	r := make([]byte, ntfsTestRecord)
+	copy(r, "FILE")
+	binary.LittleEndian.PutUint16(r[4:], 48)
+	binary.LittleEndian.PutUint16(r[6:], ntfsTestRecord/512+1)
+	binary.LittleEndian.PutUint16(r[16:], 1)
+	binary.LittleEndian.PutUint16(r[20:], 56)
+	binary.LittleEndian.PutUint16(r[22:], flags)
+	off := 56
+	for _, a := range attrs {
+		off += copy(r[off:], a)
+	}
+	binary.LittleEndian.PutUint32(r[off:], ntfsAttrEnd)
+	binary.LittleEndian.PutUint32(r[24:], uint32(off+8))
+	binary.LittleEndian.PutUint32(r[28:], ntfsTestRecord)
+	applyUSA(r, 48)
+	copy(n.img[recordOffset(num):], r)
+}
+
+// applyUSA protects a block the way NTFS writes it: the last two bytes of each 512-byte stride move to
+// the update sequence array and are replaced by the sequence number.
+func applyUSA(b []byte, usaOffset int) {
+	binary.LittleEndian.PutUint16(b[usaOffset:], 0x0007)
+	for i := 1; i <= len(b)/512; i++ {
+		end := i*512 - 2
+		copy(b[usaOffset+2*i:], b[end:end+2])
+		binary.LittleEndian.PutUint16(b[end:], 0x0007)
+	}
+}
+
+func align8(n int) int { return (n + 7) &^ 7 }
+
+func ntfsResident(kind uint32, name string, value []byte) []byte {
+	u := utf16.Encode([]rune(name))
+	valueOff := align8(24 + 2*len(u))
+	a := make([]byte, align8(valueOff+len(value)))
+	binary.LittleEndian.PutUint32(a, kind)
+	binary.LittleEndian.PutUint32(a[4:], uint32(len(a)))
+	a[9] = byte(len(u))
+	binary.LittleEndian.PutUint16(a[10:], 24)
+	for i, c := range u {
+		binary.LittleEndian.PutUint16(a[24+2*i:], c)
+	}
+	binary.LittleEndian.PutUint32(a[16:], uint32(len(value)))
+	binary.LittleEndian.PutUint16(a[20:], uint16(valueOff))
+	copy(a[valueOff:], value)
+	return a
+}
+
+func ntfsNonResident(kind uint32, name string, runs []ntfsRun, size int64) []byte {
+	u := utf16.Encode([]rune(name))
+	runsOff := align8(64 + 2*len(u))
+	runlist := encodeRuns(runs)
+	a := make([]byte, align8(runsOff+len(runlist)))
+	var clusters int64
+	for _, r := range runs {
+		clusters += r.length
+	}
+	binary.LittleEndian.PutUint32(a, kind)
+	binary.LittleEndian.PutUint32(a[4:], uint32(len(a)))
+	a[8] = 1
+	a[9] = byte(len(u))
+	binary.LittleEndian.PutUint16(a[10:], 64)
+	for i, c := range u {
+		binary.LittleEndian.PutUint16(a[64+2*i:], c)
+	}
+	binary.LittleEndian.PutUint64(a[24:], uint64(clusters-1))
+	binary.LittleEndian.PutUint16(a[32:], uint16(runsOff))
+	binary.LittleEndian.PutUint64(a[40:], uint64(clusters*ntfsTestCluster))
+	binary.LittleEndian.PutUint64(a[48:], uint64(size))
+	binary.LittleEndian.PutUint64(a[56:], uint64(size))
+	copy(a[runsOff:], runlist)
+	return a
+}
+
+func encodeRuns(runs []ntfsRun) []byte {
+	var out []byte
+	var prev int64
+	for _, r := range runs {
+		length := leBytes(r.length)
+		var delta []byte
+		if r.lcn >= 0 {
+			delta = leBytes(r.lcn - prev)
+			prev = r.lcn
+		}
+		out = append(out, byte(len(length))|byte(len(delta))<<4)
+		out = append(out, length...)
+		out = append(out, delta...)
+	}
+	return append(out, 0)
+}
+
+// leBytes is the shortest little-endian two's-complement encoding of v.
+func leBytes(v int64) []byte {
+	for n := 1; n <= 8; n++ {
+		if lo, hi := int64(-1)<<(8*n-1), int64(1)<<(8*n-1); n == 8 || (v >= lo && v < hi) {
+			b := make([]byte, 8)
+			binary.LittleEndian.PutUint64(b, uint64(v))
+			return b[:n]
+		}
+	}
+	return nil
+}
+
+func ntfsFiletime(t time.Time) uint64 {
+	return uint64(t.UnixNano()/100) + 116444736000000000
+}
+
+func ntfsStdInfo() []byte {
+	v := make([]byte, 48)
+	binary.LittleEndian.PutUint64(v[8:], ntfsFiletime(ntfsTestTime))
+	return v
+}
+
+// ntfsFileName is a $FILE_NAME value; namespace 1 is Win32, 2 DOS.
+func ntfsFileName(parent uint64, name string, namespace byte, dir bool, size int64) []byte {
+	u := utf16.Encode([]rune(name))
+	v := make([]byte, 66+2*len(u))
+	binary.LittleEndian.PutUint64(v, parent|1<<48)
+	binary.LittleEndian.PutUint64(v[24:], ntfsFiletime(ntfsTestTime))
+	binary.LittleEndian.PutUint64(v[48:], uint64(size))
+	if dir {
+		binary.LittleEndian.PutUint32(v[56:], 0x10000000)
+	}
+	v[64], v[65] = byte(len(u)), namespace
+	for i, c := range u {
+		binary.LittleEndian.PutUint16(v[66+2*i:], c)
+	}
+	return v
+}
+
+// ntfsIndexEntry is an index entry for record ref with a $FILE_NAME key; subnode >= 0 points into the
+// index allocation. A nil key makes the closing entry.
+func ntfsIndexEntry(ref uint64, key []byte, subnode int64) []byte {
+	length := align8(16 + len(key))
+	var flags uint32
+	if subnode >= 0 {
+		length += 8
+		flags |= ntfsIndexSubnode
+	}
+	if key == nil {
+		flags |= ntfsIndexLast
+	}
+	e := make([]byte, length)
+	binary.LittleEndian.PutUint64(e, ref|1<<48)
+	binary.LittleEndian.PutUint16(e[8:], uint16(length))
+	binary.LittleEndian.PutUint16(e[10:], uint16(len(key)))
+	binary.LittleEndian.PutUint32(e[12:], flags)
+	copy(e[16:], key)
+	if subnode >= 0 {
+		binary.LittleEndian.PutUint64(e[length-8:], uint64(subnode))
+	}
+	return e
+}
+
+// ntfsIndexHeader prefixes entries with an index header; large marks a root with subnodes.
+func ntfsIndexHeader(entries []byte, size int, large bool) []byte {
+	h := make([]byte, 16)
+	binary.LittleEndian.PutUint32(h, 16)
+	binary.LittleEndian.PutUint32(h[4:], uint32(16+len(entries)))
+	binary.LittleEndian.PutUint32(h[8:], uint32(size))
+	if large {
+		h[12] = 1
+	}
+	return append(h, entries...)
+}
+
+func ntfsIndexRoot(entries []byte, large bool) []byte {
+	v := make([]byte, 16)
+	binary.LittleEndian.PutUint32(v, 0x30)
+	binary.LittleEndian.PutUint32(v[4:], 1)
+	binary.LittleEndian.PutUint32(v[8:], ntfsTestIndex)
+	v[12] = 1
+	return ntfsResident(ntfsAttrIndexRoot, "$I30", append(v, ntfsIndexHeader(entries, 16+len(entries), large)...))
+}
+
+// writeIndexBlock writes an INDX block holding entries at cluster lcn.
+func (n *ntfsImage) writeIndexBlock(lcn int64, vcn int64, entries []byte) {
+	b := make([]byte, ntfsTestIndex)
+	copy(b, "INDX")
+	binary.LittleEndian.PutUint16(b[4:], 40)
+	binary.LittleEndian.PutUint16(b[6:], ntfsTestIndex/512+1)
+	binary.LittleEndian.PutUint64(b[16:], uint64(vcn))
+	h := ntfsIndexHeader(entries, ntfsTestIndex-24, false)
+	binary.LittleEndian.PutUint32(h, 40) // entries follow the update sequence array
+	binary.LittleEndian.PutUint32(h[4:], uint32(40+len(entries)))
+	copy(b[24:], h[:16])
+	copy(b[64:], entries)
+	applyUSA(b, 40)
+	copy(n.img[lcn*ntfsTestCluster:], b)
+}
+
+func (n *ntfsImage) writeClusters(lcn int64, data []byte) {
+	copy(n.img[lcn*ntfsTestCluster:], data)
+}
+
+func TestNTFS(t *testing.T) {
+	const (
+		users   = 16
+		alice   = 17
+		notes   = 18
+		program = 20
+		big     = 40 // in the $MFT's second run
+	)
+	notesData := []byte("resident data lives in the record")
+	bigData := pattern(5*ntfsTestCluster+100, 3)
+	// big.bin: two clusters at 200, a sparse cluster, three clusters at 150 (a negative LCN delta)
+	for i := range bigData[2*ntfsTestCluster : 3*ntfsTestCluster] {
+		bigData[2*ntfsTestCluster+i] = 0
+	}
+
+	n := newNTFSImage()
+	// The root keeps its entries in an INDX block; a DOS alias and a metadata file are listed too.
+	rootBlock := append(append(append(
+		ntfsIndexEntry(4, ntfsFileName(ntfsRootRecord, "$AttrDef", 3, false, 0), -1),
+		ntfsIndexEntry(users, ntfsFileName(ntfsRootRecord, "USERS~1", ntfsNamespaceDOS, true, 0), -1)...),
+		ntfsIndexEntry(users, ntfsFileName(ntfsRootRecord, "Users", 1, true, 0), -1)...),
+		ntfsIndexEntry(0, nil, -1)...)
+	n.writeIndexBlock(300, 0, rootBlock)
+	n.writeRecord(ntfsRootRecord, ntfsRecordInUse|0x02,
+		ntfsResident(ntfsAttrStandardInfo, "", ntfsStdInfo()),
+		ntfsIndexRoot(ntfsIndexEntry(0, nil, 0), true),
+		ntfsNonResident(ntfsAttrIndexAlloc, "$I30", []ntfsRun{{300, 1}}, ntfsTestIndex))
+
+	n.writeRecord(users, ntfsRecordInUse|0x02,
+		ntfsResident(ntfsAttrStandardInfo, "", ntfsStdInfo()),
+		ntfsIndexRoot(append(
+			ntfsIndexEntry(alice, ntfsFileName(users, "alice", 1, true, 0), -1),
+			ntfsIndexEntry(0, nil, -1)...), false))
+	n.writeRecord(alice, ntfsRecordInUse|0x02,
+		ntfsResident(ntfsAttrStandardInfo, "", ntfsStdInfo()),
+		ntfsIndexRoot(append(append(append(
+			ntfsIndexEntry(notes, ntfsFileName(alice, "notes.txt", 3, false, 0), -1),
+			ntfsIndexEntry(big, ntfsFileName(alice, "big.bin", 1, false, 0), -1)...),
+			ntfsIndexEntry(program, ntfsFileName(alice, "Program Files", 1, true, 0), -1)...),
+			ntfsIndexEntry(0, nil, -1)...), false))
+	n.writeRecord(notes, ntfsRecordInUse,
+		ntfsResident(ntfsAttrStandardInfo, "", ntfsStdInfo()),
+		ntfsResident(ntfsAttrData, "", notesData),
+		ntfsResident(ntfsAttrData, "Zone.Identifier", []byte("[ZoneTransfer]")))
+	n.writeClusters(200, bigData[:2*ntfsTestCluster])
+	n.writeClusters(150, bigData[3*ntfsTestCluster:])
+	n.writeRecord(big, ntfsRecordInUse,
+		ntfsResident(ntfsAttrStandardInfo, "", ntfsStdInfo()),
+		ntfsNonResident(ntfsAttrData, "", []ntfsRun{{200, 2}, {-1, 1}, {150, 3}}, int64(len(bigData))))
+	n.writeRecord(program, ntfsRecordInUse|0x02,
+		ntfsResident(ntfsAttrStandardInfo, "", ntfsStdInfo()),
+		ntfsIndexRoot(ntfsIndexEntry(0, nil, -1), false))
+
+	r := bytes.NewReader(n.img)
+	vol, err := OpenVolume(r, Partition{Size: int64(len(n.img))})
+	require.NoError(t, err)
+	assert.Equal(t, "ntfs", vol.Filesystem)
+	checkTree(t, vol.FS, map[string][]byte{
+		"Users/alice/notes.txt": notesData,
+		"Users/alice/big.bin":   bigData,
+	})
+
+	entries, err := fs.ReadDir(vol.FS, ".")
+	require.NoError(t, err)
+	require.Len(t, entries, 1, "metadata files and DOS aliases are left out")
+	assert.Equal(t, "Users", entries[0].Name())
+
+	info, err := fs.Stat(vol.FS, "users/ALICE/Big.bin")
+	require.NoError(t, err, "NTFS names match case-insensitively")
+	assert.Equal(t, int64(len(bigData)), info.Size(), "the size comes from $DATA, not the stale $FILE_NAME copy")
+	assert.Equal(t, ntfsTestTime, info.ModTime())
+}
+
+func TestNTFSFixupTornWrite(t *testing.T) {
+	b := make([]byte, 1024)
+	copy(b, "FILE")
+	binary.LittleEndian.PutUint16(b[4:], 48)
+	binary.LittleEndian.PutUint16(b[6:], 3)
+	applyUSA(b, 48)
+	require.NoError(t, ntfsFixup(append([]byte(nil), b...)))
+
+	b[1022] = 0 // a stride written without its sequence number
+	assert.Error(t, ntfsFixup(b))
+}
+
+func TestNTFSMalformedRecord(t *testing.T) {
+	n := newNTFSImage()
+	short := ntfsResident(ntfsAttrData, "", []byte("data"))[:16]
+	binary.LittleEndian.PutUint32(short[4:], 16) // too short to hold the value's size and offset
+	n.writeRecord(16, ntfsRecordInUse, short)
+	n.writeRecord(17, ntfsRecordInUse,
+		ntfsNonResident(ntfsAttrList, "", []ntfsRun{{300, 1}}, 1<<60))
+
+	d, err := openNTFS(bytes.NewReader(n.img))
+	require.NoError(t, err)
+	v := d.(*ntfsVolume)
+	attrs, err := v.attributes(16)
+	require.NoError(t, err)
+	require.Len(t, attrs, 1)
+	assert.Nil(t, attrs[0].resident)
+
+	_, err = v.attributes(17)
+	assert.ErrorContains(t, err, "attribute list", "the list's size is not trusted")
+}
@@ -0,0 +1,182 @@
+package diskimage
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"unicode/utf16"
+)
+
+// Partition is one partition of a disk image. A disk without a partition table is listed as a single
+// partition 0 covering the whole image.
+type Partition struct {
+	Index int    // 1-based position in the table (logical MBR partitions follow the primaries)
+	Start int64  // byte offset from the start of the disk
+	Size  int64  // bytes
+	Type  string // MBR type byte ("0x07") or GPT type GUID
+	Name  string // GPT partition name
+}
+
+const (
+	mbrExtendedCHS = 0x05
+	mbrExtendedLBA = 0x0f
+	mbrExtendedLNX = 0x85
+	mbrProtective  = 0xee
+
+	maxLogicalPartitions = 128
+	// The GPT entry array is 16 KiB in practice (128 entries of 128 bytes); a header asking for more
+	// than this is corrupt.
+	gptMaxEntryArray = 1 << 20
+)
+
+// Partitions reads the partition table of the disk r holds: a GPT (behind its protective MBR) or an MBR
+// with its extended partitions' logical ones. Empty slots are left out. A disk whose first sector is a
+// filesystem boot sector, or that has no table, yields one whole-disk partition.
+func Partitions(r io.ReaderAt, size int64) ([]Partition, error) {
+	whole := []Partition{{Start: 0, Size: size}}
+	if kind, _ := detectFS(io.NewSectionReader(r, 0, size)); kind != "" {
+		return whole, nil
+	}
+	mbr := make([]byte, 512)
+	if _, err := r.ReadAt(mbr, 0); err != nil {
+		return nil, fmt.Errorf("read mbr: %w", err)
+	}
+	if mbr[510] != 0x55 || mbr[511] != 0xaa {
+		return whole, nil
+	}
+	for i := 0; i < 4; i++ {
+		if mbr[446+16*i+4] == mbrProtective {
+			if parts, err := gptPartitions(r, size); err == nil {
+				return parts, nil
+			}
+		}
+	}
+	parts, err := mbrPartitions(r, mbr, size)
+	if err != nil {
+		return nil, err
+	}
+	if len(parts) == 0 {
+		return whole, nil
+	}
+	return parts, nil
+}
+
+func mbrPartitions(r io.ReaderAt, mbr []byte, size int64) ([]Partition, error) {
+	const sector = 512
+	var parts []Partition
+	var extended []int64
+	for i := 0; i < 4; i++ {
+		e := mbr[446+16*i:]
+		kind := e[4]
+		start := int64(binary.LittleEndian.Uint32(e[8:])) * sector
+		length := int64(binary.LittleEndian.Uint32(e[12:])) * sector
+		switch {
+		case kind == 0 || length == 0:
+			continue
+		case kind == mbrExtendedCHS || kind == mbrExtendedLBA || kind == mbrExtendedLNX:
+			extended = append(extended, start)
+			continue
+		}
+		parts = append(parts, Partition{Index: i + 1, Start: start, Size: clampSize(start, length, size), Type: fmt.Sprintf("0x%02x", kind)})
+	}
+
+	// Each extended partition chains EBRs: the first entry is a logical partition relative to its EBR,
+	// the second the next EBR relative to the extended partition.
+	index := 5
+	for _, base := range extended {
+		ebr := make([]byte, 512)
+		for off, n := base, 0; n < maxLogicalPartitions; n++ {
+			if _, err := r.ReadAt(ebr, off); err != nil {
+				return nil, fmt.Errorf("read ebr at %d: %w", off, err)
+			}
+			if ebr[510] != 0x55 || ebr[511] != 0xaa {
+				break
+			}
+			if kind := ebr[446+4]; kind != 0 {
+				start := off + int64(binary.LittleEndian.Uint32(ebr[446+8:]))*sector
+				length := int64(binary.LittleEndian.Uint32(ebr[446+12:])) * sector
+				parts = append(parts, Partition{Index: index, Start: start, Size: clampSize(start, length, size), Type: fmt.Sprintf("0x%02x", kind)})
+				index++
+			}
+			next := int64(binary.LittleEndian.Uint32(ebr[462+8:])) * sector
+			if next == 0 {
+				break
+			}
+			off = base + next
+		}
+	}
+	return parts, nil
+}
+
+// gptPartitions reads the GPT header at LBA 1, trying 512- and 4096-byte sectors.
+func gptPartitions(r io.ReaderAt, size int64) ([]Partition, error) {
+	head := make([]byte, 92)
+	for _, sector := range []int64{512, 4096} {
+		if _, err := r.ReadAt(head, sector); err != nil || !bytes.Equal(head[:8], []byte("EFI PART")) {
+			continue
+		}
+		entriesLBA := int64(binary.LittleEndian.Uint64(head[72:]))
+		count := int64(binary.LittleEndian.Uint32(head[80:]))
+		entrySize := int64(binary.LittleEndian.Uint32(head[84:]))
+		if entrySize < 128 || entrySize&(entrySize-1) != 0 || count*entrySize > gptMaxEntryArray {
+			return nil, fmt.Errorf("gpt: %d entries of %d bytes", count, entrySize)
+		}
+		table := make([]byte, count*entrySize)
+		if _, err := r.ReadAt(table, entriesLBA*sector); err != nil {
+			return nil, fmt.Errorf("gpt entries: %w", err)
+		}
+		var parts []Partition
+		for i := int64(0); i < count; i++ {
+			e := table[i*entrySize:]
+			if bytes.Equal(e[:16], make([]byte, 16)) {
+				continue
+			}
+			first := int64(binary.LittleEndian.Uint64(e[32:]))
+			last := int64(binary.LittleEndian.Uint64(e[40:]))
+			if last < first {
+				continue
+			}
+			start := first * sector
+			parts = append(parts, Partition{
+				Index: int(i) + 1,
+				Start: start,
+				Size:  clampSize(start, (last-first+1)*sector, size),
+				Type:  formatGUID(e[:16]),
+				Name:  decodeUTF16(e[56:128]),
+			})
+		}
+		return parts, nil
+	}
+	return nil, fmt.Errorf("gpt: no header")
+}
+
+// clampSize trims a partition that runs past the end of a (truncated) image.
+func clampSize(start, length, size int64) int64 {
+	if start+length > size {
+		length = size - start
+	}
+	if length < 0 {
+		return 0
+	}
+	return length
+}
+
+// formatGUID prints a GUID stored in the mixed-endian on-disk layout in its usual text form.
+func formatGUID(b []byte) string {
+	return fmt.Sprintf("%08X-%04X-%04X-%X-%X",
+		binary.LittleEndian.Uint32(b), binary.LittleEndian.Uint16(b[4:]), binary.LittleEndian.Uint16(b[6:]), b[8:10], b[10:16])
+}
+
+// decodeUTF16 decodes little-endian UTF-16, stopping at the first NUL.
+func decodeUTF16(b []byte) string {
+	u := make([]uint16, 0, len(b)/2)
+	for i := 0; i+1 < len(b); i += 2 {
+		c := binary.LittleEndian.Uint16(b[i:])
+		if c == 0 {
+			break
+		}
+		u = append(u, c)
+	}
+	return string(utf16.Decode(u))
+}
@@ -0,0 +1,44 @@
+package diskimage
+
+import (
+	"bytes"
+	"io/fs"
+	"sort"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// checkTree asserts fsys holds exactly the files in want (path → content) and behaves as an fs.FS.
+func checkTree(t *testing.T, fsys fs.FS, want map[string][]byte) {
+	t.Helper()
+	var got []string
+	require.NoError(t, fs.WalkDir(fsys, ".", func(p string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		got = append(got, p)
+		return nil
+	}))
+	var paths []string
+	for p, content := range want {
+		paths = append(paths, p)
+		data, err := fs.ReadFile(fsys, p)
+		require.NoError(t, err, p)
+		assert.True(t, bytes.Equal(content, data), "%s: content differs (%d bytes, want %d)", p, len(data), len(content))
+	}
+	sort.Strings(paths)
+	assert.Equal(t, paths, got)
+	require.NoError(t, fstest.TestFS(fsys, paths...))
+}
+
+// pattern returns n bytes that differ from block to block, so misplaced reads show.
+func pattern(n int, seed byte) []byte {
+	b := make([]byte, n)
+	for i := range b {
+		b[i] = seed + byte(i/512) + byte(i*7)
+	}
+	return b
+}
@@ -0,0 +1,388 @@
+package diskimage
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Volume is a partition with the filesystem on it opened read-only.
+type Volume struct {
+	Partition
+	Filesystem string // "ntfs", "fat" or "ext"
+	FS         fs.FS
+}
+
+// Name labels the volume for paths and logs, e.g. "p2-ntfs" ("disk-fat" for a whole-disk filesystem).
+func (v *Volume) Name() string {
+	if v.Index == 0 {
+		return "disk-" + v.Filesystem
+	}
+	return fmt.Sprintf("p%d-%s", v.Index, v.Filesystem)
+}
+
+// OpenVolume opens the filesystem r holds (the bytes of partition p). A filesystem that is not NTFS,
+// FAT or ext2/3/4 yields an error wrapping ErrUnsupportedFilesystem.
+func OpenVolume(r io.ReaderAt, p Partition) (*Volume, error) {
+	kind, err := detectFS(r)
+	if err != nil {
+		return nil, err
+	}
+	var d driver
+	switch kind {
+	case "ntfs":
+		d, err = openNTFS(r)
+	case "fat":
+		d, err = openFAT(r)
+	case "ext":
+		d, err = openExt(r)
+	case "":
+		return nil, fmt.Errorf("partition %d: %w", p.Index, ErrUnsupportedFilesystem)
+	default:
+		return nil, fmt.Errorf("partition %d: %s: %w", p.Index, kind, ErrUnsupportedFilesystem)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("partition %d: %s: %w", p.Index, kind, err)
+	}
+	return &Volume{Partition: p, Filesystem: kind, FS: newImageFS(d)}, nil
+}
+
+// detectFS names the filesystem whose boot sector or superblock r starts with, or returns "" when
+// none is recognized. APFS and exFAT are recognized so they can be reported, but cannot be opened.
+func detectFS(r io.ReaderAt) (string, error) {
+	boot := make([]byte, 512)
+	if _, err := r.ReadAt(boot, 0); err != nil {
+		return "", fmt.Errorf("read boot sector: %w", err)
+	}
+	switch {
+	case bytes.Equal(boot[3:11], []byte("NTFS    ")):
+		return "ntfs", nil
+	case bytes.Equal(boot[3:11], []byte("EXFAT   ")):
+		return "exfat", nil
+	case bytes.Equal(boot[32:36], []byte("NXSB")):
+		return "apfs", nil
+	case isFATBoot(boot):
+		return "fat", nil
+	}
+	sb := make([]byte, 2)
+	if _, err := r.ReadAt(sb, 1024+56); err == nil && binary.LittleEndian.Uint16(sb) == extMagic {
+		return "ext", nil
+	}
+	return "", nil
+}
+
+// node is one file or directory of a volume; ref is what the driver finds it by (an MFT record, an
+// inode, a first cluster).
+type node struct {
+	name  string
+	ref   uint64
+	size  int64
+	mode  fs.FileMode
+	mtime time.Time
+}
+
+// driver is what a filesystem reader provides to the shared fs.FS implementation.
+type driver interface {
+	root() (node, error)
+	readDir(dir node) ([]node, error)
+	// open returns the file's content and its node refreshed from the file's own metadata.
+	open(file node) (node, io.ReaderAt, error)
+	// foldCase reports whether names match case-insensitively (FAT, NTFS).
+	foldCase() bool
+}
+
+// errDirLoop is a directory whose entry points back at itself or one of its ancestors, which a corrupt
+// volume can hold; following it would make every walk endless.
+var errDirLoop = errors.New("directory loop")
+
+// listingCacheSize is how many directory listings an imageFS keeps; a walk that checks each dir's
+// children for marker files lists the same few dirs over and over.
+const listingCacheSize = 256
+
+// imageFS serves a volume as an fs.FS. Resolved directories are remembered by path, so walking the
+// tree does not re-read every ancestor for each entry, and recent listings are kept.
+type imageFS struct {
+	d driver
+
+	mu       sync.Mutex
+	dirs     map[string]node
+	listings map[uint64][]node
+	order    []uint64
+}
+
+func newImageFS(d driver) *imageFS {
+	return &imageFS{d: d, dirs: make(map[string]node), listings: make(map[uint64][]node)}
+}
+
+// list reads a directory's entries, through the listing cache.
+func (f *imageFS) list(dir node) ([]node, error) {
+	f.mu.Lock()
+	nodes, ok := f.listings[dir.ref]
+	f.mu.Unlock()
+	if ok {
+		return nodes, nil
+	}
+	nodes, err := f.d.readDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if len(f.order) == listingCacheSize {
+		delete(f.listings, f.order[0])
+		f.order = f.order[1:]
+	}
+	f.listings[dir.ref] = nodes
+	f.order = append(f.order, dir.ref)
+	return nodes, nil
+}
+
+// lookup resolves name to its node.
+func (f *imageFS) lookup(op, name string) (node, error) {
+	if !fs.ValidPath(name) {
+		return node{}, &fs.PathError{Op: op, Path: name, Err: fs.ErrInvalid}
+	}
+	if name == "." {
+		return f.dir(op, ".")
+	}
+	dir, err := f.dir(op, path.Dir(name))
+	if err != nil {
+		return node{}, err
+	}
+	entries, err := f.list(dir)
+	if err != nil {
+		return node{}, &fs.PathError{Op: op, Path: name, Err: err}
+	}
+	base := path.Base(name)
+	for _, e := range entries {
+		if e.name == base || (f.d.foldCase() && strings.EqualFold(e.name, base)) {
+			return e, nil
+		}
+	}
+	return node{}, &fs.PathError{Op: op, Path: name, Err: fs.ErrNotExist}
+}
+
+// dir resolves a directory path, through the cache.
+func (f *imageFS) dir(op, name string) (node, error) {
+	key := name
+	if f.d.foldCase() {
+		key = strings.ToLower(name)
+	}
+	f.mu.Lock()
+	n, ok := f.dirs[key]
+	f.mu.Unlock()
+	if ok {
+		return n, nil
+	}
+	if name == "." {
+		var err error
+		if n, err = f.d.root(); err != nil {
+			return node{}, &fs.PathError{Op: op, Path: name, Err: err}
+		}
+	} else {
+		var err error
+		if n, err = f.lookup(op, name); err != nil {
+			return node{}, err
+		}
+		if !n.mode.IsDir() {
+			return node{}, &fs.PathError{Op: op, Path: name, Err: fmt.Errorf("not a directory")}
+		}
+		if f.onPath(path.Dir(name), n.ref) {
+			return node{}, &fs.PathError{Op: op, Path: name, Err: errDirLoop}
+		}
+	}
+	f.mu.Lock()
+	f.dirs[key] = n
+	f.mu.Unlock()
+	return n, nil
+}
+
+// onPath reports whether the directory at p or one of its ancestors is ref. They were all resolved, and
+// so cached, on the way to p's child.
+func (f *imageFS) onPath(p string, ref uint64) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for {
+		key := p
+		if f.d.foldCase() {
+			key = strings.ToLower(p)
+		}
+		if n, ok := f.dirs[key]; ok && n.ref == ref {
+			return true
+		}
+		if p == "." {
+			return false
+		}
+		p = path.Dir(p)
+	}
+}
+
+func (f *imageFS) Open(name string) (fs.File, error) {
+	n, err := f.lookup("open", name)
+	if err != nil {
+		return nil, err
+	}
+	if n.mode.IsDir() {
+		if n, err = f.dir("open", name); err != nil {
+			return nil, err
+		}
+		if name == "." {
+			n.name = "."
+		}
+		return &imageDir{fsys: f, n: n}, nil
+	}
+	if n.mode&fs.ModeType != 0 {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fmt.Errorf("not a regular file")}
+	}
+	n, r, err := f.d.open(n)
+	if err != nil {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: err}
+	}
+	return &imageFile{n: n, SectionReader: io.NewSectionReader(r, 0, n.size)}, nil
+}
+
+func (f *imageFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	dir, err := f.dir("readdir", name)
+	if err != nil {
+		return nil, err
+	}
+	return f.entries(name, dir)
+}
+
+func (f *imageFS) entries(name string, dir node) ([]fs.DirEntry, error) {
+	nodes, err := f.list(dir)
+	if err != nil {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: err}
+	}
+	entries := make([]fs.DirEntry, 0, len(nodes))
+	for _, n := range nodes {
+		entries = append(entries, fs.FileInfoToDirEntry(fileInfo{n}))
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Name() < entries[j].Name() })
+	return entries, nil
+}
+
+// fileInfo is a node as an fs.FileInfo.
+type fileInfo struct{ n node }
+
+func (i fileInfo) Name() string       { return i.n.name }
+func (i fileInfo) Size() int64        { return i.n.size }
+func (i fileInfo) Mode() fs.FileMode  { return i.n.mode }
+func (i fileInfo) ModTime() time.Time { return i.n.mtime }
+func (i fileInfo) IsDir() bool        { return i.n.mode.IsDir() }
+func (i fileInfo) Sys() any           { return nil }
+
+// imageFile is an open regular file; it also serves io.ReaderAt and io.Seeker.
+type imageFile struct {
+	n node
+	*io.SectionReader
+}
+
+func (f *imageFile) Stat() (fs.FileInfo, error) { return fileInfo{f.n}, nil }
+func (f *imageFile) Close() error               { return nil }
+
+// imageDir is an open directory.
+type imageDir struct {
+	fsys    *imageFS
+	n       node
+	entries []fs.DirEntry
+	read    bool
+}
+
+func (d *imageDir) Stat() (fs.FileInfo, error) { return fileInfo{d.n}, nil }
+func (d *imageDir) Close() error               { return nil }
+
+func (d *imageDir) Read([]byte) (int, error) {
+	return 0, &fs.PathError{Op: "read", Path: d.n.name, Err: fmt.Errorf("is a directory")}
+}
+
+func (d *imageDir) ReadDir(count int) ([]fs.DirEntry, error) {
+	if !d.read {
+		entries, err := d.fsys.entries(d.n.name, d.n)
+		if err != nil {
+			return nil, err
+		}
+		d.entries, d.read = entries, true
+	}
+	if count <= 0 {
+		entries := d.entries
+		d.entries = nil
+		return entries, nil
+	}
+	if len(d.entries) == 0 {
+		return nil, io.EOF
+	}
+	if count > len(d.entries) {
+		count = len(d.entries)
+	}
+	entries := d.entries[:count]
+	d.entries = d.entries[count:]
+	return entries, nil
+}
+
+// extent maps a run of a file's bytes onto the volume. A negative phys marks a hole, read as zeros.
+type extent struct {
+	logical, phys, length int64
+}
+
+// extentReader reads a file laid out as extents (sorted by logical offset); bytes no extent covers
+// read as zeros.
+type extentReader struct {
+	r       io.ReaderAt
+	extents []extent
+}
+
+func (e *extentReader) ReadAt(p []byte, off int64) (int, error) {
+	n := 0
+	for n < len(p) {
+		pos := off + int64(n)
+		i := sort.Search(len(e.extents), func(i int) bool {
+			return e.extents[i].logical+e.extents[i].length > pos
+		})
+		chunk := p[n:]
+		if i == len(e.extents) || e.extents[i].logical > pos {
+			// a hole up to the next extent
+			if i < len(e.extents) && int64(len(chunk)) > e.extents[i].logical-pos {
+				chunk = chunk[:e.extents[i].logical-pos]
+			}
+			for j := range chunk {
+				chunk[j] = 0
+			}
+			n += len(chunk)
+			continue
+		}
+		x := e.extents[i]
+		if rest := x.logical + x.length - pos; int64(len(chunk)) > rest {
+			chunk = chunk[:rest]
+		}
+		if x.phys < 0 {
+			for j := range chunk {
+				chunk[j] = 0
+			}
+		} else if _, err := e.r.ReadAt(chunk, x.phys+pos-x.logical); err != nil {
+			return n, err
+		}
+		n += len(chunk)
+	}
+	return n, nil
+}
+
+// appendExtent adds a run to extents, merging it into the last one when they are contiguous.
+func appendExtent(extents []extent, x extent) []extent {
+	if k := len(extents) - 1; k >= 0 {
+		last := &extents[k]
+		if last.logical+last.length == x.logical && ((last.phys < 0 && x.phys < 0) || (last.phys >= 0 && last.phys+last.length == x.phys)) {
+			last.length += x.length
+			return extents
+		}
+	}
+	return append(extents, x)
+}
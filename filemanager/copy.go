@@ -1,7 +1,12 @@
 package filemanager
 
 import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
 	"os"
+	"path/filepath"
 	"strings"
 
 	cp "github.com/otiai10/copy"
@@ -9,22 +14,85 @@ import (
 
 // copyFile copies a single file from src to dst.
 func copyFile(src, dst string) error {
-	data, err := os.ReadFile(src)
+	in, err := os.Open(src)
 	if err != nil {
 		return err
 	}
-	return os.WriteFile(dst, data, 0o600)
+	defer in.Close()
+	return copyReader(in, dst)
 }
 
-// copyDir copies a directory from src to dst, skipping files
+// copyReader streams in to a new file at dst in chunks, so multi-GB History or Cache files never sit in
+// memory whole.
+func copyReader(in io.Reader, dst string) error {
+	out, err := os.OpenFile(dst, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0o600)
+	if err != nil {
+		return err
+	}
+	if _, err := io.CopyBuffer(out, in, make([]byte, copyChunkSize)); err != nil {
+		_ = out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+// copyChunkSize is the buffer streamed copies use.
+const copyChunkSize = 1 << 20
+
+// copyDir copies a directory from src to dst, skipping excluded files and files
 // whose path ends with the skip suffix (e.g. "lock").
 func copyDir(src, dst, skip string) error {
 	opts := cp.Options{Skip: func(info os.FileInfo, src, _ string) (bool, error) {
-		return strings.HasSuffix(strings.ToLower(src), skip), nil
+		return strings.HasSuffix(strings.ToLower(src), skip) || isExcluded(src), nil
 	}}
 	return cp.Copy(src, dst, opts)
 }
 
+// copyFSFile streams src out of fsys into dst.
+func copyFSFile(fsys fs.FS, src, dst string) error {
+	in, err := fsys.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	return copyReader(in, dst)
+}
+
+// maxFSDirDepth bounds how far copyFSDir descends below src; no browser profile nests anywhere near it,
+// and a corrupt image's directory loop stops here instead of growing the path forever.
+const maxFSDirDepth = 32
+
+// copyFSDir copies the tree at src out of fsys into dst, skipping files whose path ends with the skip
+// suffix and special files. A file that cannot be read does not stop the copy; the errors are joined.
+func copyFSDir(fsys fs.FS, src, dst, skip string) error {
+	var errs []error
+	err := fs.WalkDir(fsys, src, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if p == src {
+				return err
+			}
+			errs = append(errs, err)
+			return nil
+		}
+		rel := strings.TrimPrefix(strings.TrimPrefix(p, src), "/")
+		target := filepath.Join(dst, filepath.FromSlash(rel))
+		switch {
+		case d.IsDir() && rel != "" && strings.Count(rel, "/")+1 > maxFSDirDepth:
+			errs = append(errs, fmt.Errorf("%s: deeper than %d levels, skipped", p, maxFSDirDepth))
+			return fs.SkipDir
+		case d.IsDir():
+			return os.MkdirAll(target, 0o700)
+		case !d.Type().IsRegular() || strings.HasSuffix(strings.ToLower(p), skip):
+			return nil
+		}
+		if err := copyFSFile(fsys, p, target); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", p, err))
+		}
+		return nil
+	})
+	return errors.Join(append([]error{err}, errs...)...)
+}
+
 // isFileExists checks if a file (not directory) exists at the given path.
 func isFileExists(path string) bool {
 	info, err := os.Stat(path)
@@ -4,6 +4,7 @@ package filemanager
 
 import (
 	"bytes"
+	"io"
 	"os"
 	"path/filepath"
 	"syscall"
@@ -162,3 +163,26 @@ func openExclusive(t *testing.T, path string) syscall.Handle {
 	require.NoError(t, err)
 	return handle
 }
+
+func TestSession_Open_Locked(t *testing.T) {
+	s, err := NewSession()
+	require.NoError(t, err)
+	defer s.Cleanup()
+
+	src := filepath.Join(t.TempDir(), "Cookies")
+	require.NoError(t, os.WriteFile(src, []byte("locked cookies"), 0o644))
+	handle := openExclusive(t, src)
+	defer syscall.CloseHandle(handle)
+
+	f, err := s.Open(src)
+	require.NoError(t, err)
+	assert.True(t, f.Locked)
+	data, err := io.ReadAll(f)
+	require.NoError(t, err)
+	assert.Equal(t, "locked cookies", string(data))
+	require.NoError(t, f.Close())
+
+	entries, err := os.ReadDir(s.TempDir())
+	require.NoError(t, err)
+	assert.Empty(t, entries, "closing removes the staged copy")
+}
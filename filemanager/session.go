@@ -3,7 +3,9 @@ package filemanager
 import (
 	"errors"
 	"fmt"
+	"io/fs"
 	"os"
+	"path/filepath"
 	"runtime"
 )
 
@@ -14,6 +16,36 @@ type Session struct {
 	tempDir string
 }
 
+// excluded holds the files Acquire treats as absent, by absolute path — set by a restore whose archive
+// manifest marks them as only partly archived, where the data dir is the analyst's and is not modified.
+var excluded map[string]bool
+
+// errExcluded is what Acquire returns for an excluded file; it reads as a missing one.
+var errExcluded = fmt.Errorf("excluded as a partial archive copy: %w", fs.ErrNotExist)
+
+// SetExcluded makes Acquire treat paths as absent for the rest of the process, so a partial file is
+// never read as a whole one; nil clears the set.
+func SetExcluded(paths []string) {
+	excluded = nil
+	for _, p := range paths {
+		if excluded == nil {
+			excluded = make(map[string]bool, len(paths))
+		}
+		excluded[absPath(p)] = true
+	}
+}
+
+func isExcluded(path string) bool {
+	return excluded != nil && excluded[absPath(path)]
+}
+
+func absPath(path string) string {
+	if abs, err := filepath.Abs(path); err == nil {
+		return abs
+	}
+	return filepath.Clean(path)
+}
+
 // NewSession creates a session with a unique temporary directory.
 func NewSession() (*Session, error) {
 	dir, err := os.MkdirTemp("", "hbd-*")
@@ -39,6 +71,9 @@ func (s *Session) Acquire(src, dst string, isDir bool) error {
 	if isDir {
 		return copyDir(src, dst, "lock")
 	}
+	if isExcluded(src) {
+		return fmt.Errorf("copy: %s: %w", src, errExcluded)
+	}
 
 	// Try normal copy first
 	err := copyFile(src, dst)
@@ -60,7 +95,7 @@ func (s *Session) Acquire(src, dst string, isDir bool) error {
 	var walErrs []error
 	for _, suffix := range []string{"-wal", "-shm"} {
 		walSrc := src + suffix
-		if isFileExists(walSrc) {
+		if isFileExists(walSrc) && !isExcluded(walSrc) {
 			if err := copyFile(walSrc, dst+suffix); err != nil {
 				walErrs = append(walErrs, fmt.Errorf("copy %s: %w", suffix, err))
 			}
@@ -69,6 +104,78 @@ func (s *Session) Acquire(src, dst string, isDir bool) error {
 	return errors.Join(walErrs...)
 }
 
+// AcquireFS copies src (a slash path) out of fsys to dst the way Acquire copies from the host: a file
+// with its SQLite WAL and SHM companions, or a directory without its lock files, where a file that
+// cannot be read is reported but does not stop the rest. It is how profile files are pulled from a
+// filesystem read out of a disk image (see diskimage), where nothing holds a lock.
+func (s *Session) AcquireFS(fsys fs.FS, src, dst string, isDir bool) error {
+	if isDir {
+		return copyFSDir(fsys, src, dst, "lock")
+	}
+	if err := copyFSFile(fsys, src, dst); err != nil {
+		return fmt.Errorf("copy: %w", err)
+	}
+	var walErrs []error
+	for _, suffix := range []string{"-wal", "-shm"} {
+		if info, err := fs.Stat(fsys, src+suffix); err == nil && !info.IsDir() {
+			if err := copyFSFile(fsys, src+suffix, dst+suffix); err != nil {
+				walErrs = append(walErrs, fmt.Errorf("copy %s: %w", suffix, err))
+			}
+		}
+	}
+	return errors.Join(walErrs...)
+}
+
+// Source is a file opened for streaming by Open. Locked marks one read from a copy staged through the
+// locked-file fallback; closing it removes that copy.
+type Source struct {
+	*os.File
+	Locked bool
+	staged string
+}
+
+// Close closes the file and removes its staged copy, if any.
+func (f *Source) Close() error {
+	err := f.File.Close()
+	if f.staged != "" {
+		if rmErr := os.Remove(f.staged); rmErr != nil && err == nil {
+			err = rmErr
+		}
+	}
+	return err
+}
+
+// Open opens src for reading in place. Only when that fails on Windows (a file another process holds
+// under an exclusive lock) is src staged into the session through the locked-file fallback and the
+// staged copy opened instead, so streaming callers need disk space only for locked files.
+func (s *Session) Open(src string) (*Source, error) {
+	f, err := os.Open(src)
+	if err == nil {
+		return &Source{File: f}, nil
+	}
+	if runtime.GOOS != "windows" {
+		return nil, err
+	}
+	staged, tmpErr := os.CreateTemp(s.tempDir, "locked-*")
+	if tmpErr != nil {
+		return nil, errors.Join(err, tmpErr)
+	}
+	_ = staged.Close()
+	if err2 := copyLocked(src, staged.Name()); err2 != nil {
+		_ = os.Remove(staged.Name())
+		return nil, errors.Join(
+			fmt.Errorf("open: %w", err),
+			fmt.Errorf("locked copy: %w", err2),
+		)
+	}
+	f, err = os.Open(staged.Name())
+	if err != nil {
+		_ = os.Remove(staged.Name())
+		return nil, err
+	}
+	return &Source{File: f, Locked: true, staged: staged.Name()}, nil
+}
+
 // Cleanup removes the session's temporary directory and all its contents.
 func (s *Session) Cleanup() {
 	os.RemoveAll(s.tempDir)
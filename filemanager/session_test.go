@@ -1,9 +1,13 @@
 package filemanager
 
 import (
+	"io"
+	"io/fs"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
+	"testing/fstest"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -92,6 +96,36 @@ func TestSession_Acquire_Dir(t *testing.T) {
 	// LOCK file skipped (CopyDir skips "lock" suffix)
 }
 
+func TestSession_Acquire_Excluded(t *testing.T) {
+	s, err := NewSession()
+	require.NoError(t, err)
+	defer s.Cleanup()
+
+	srcDir := t.TempDir()
+	cookies := filepath.Join(srcDir, "Cookies")
+	history := filepath.Join(srcDir, "History")
+	leveldb := filepath.Join(srcDir, "leveldb")
+	require.NoError(t, os.MkdirAll(leveldb, 0o755))
+	for _, p := range []string{cookies, history, history + "-wal", filepath.Join(leveldb, "000001.ldb"), filepath.Join(leveldb, "000002.ldb")} {
+		require.NoError(t, os.WriteFile(p, []byte("data"), 0o644))
+	}
+	SetExcluded([]string{cookies, history + "-wal", filepath.Join(leveldb, "000002.ldb")})
+	defer SetExcluded(nil)
+
+	err = s.Acquire(cookies, filepath.Join(s.TempDir(), "Cookies"), false)
+	require.ErrorIs(t, err, fs.ErrNotExist, "an excluded file reads as missing")
+	assert.FileExists(t, cookies, "the source is left alone")
+
+	dst := filepath.Join(s.TempDir(), "History")
+	require.NoError(t, s.Acquire(history, dst, false))
+	assert.NoFileExists(t, dst+"-wal")
+
+	dir := filepath.Join(s.TempDir(), "leveldb")
+	require.NoError(t, s.Acquire(leveldb, dir, true))
+	assert.FileExists(t, filepath.Join(dir, "000001.ldb"))
+	assert.NoFileExists(t, filepath.Join(dir, "000002.ldb"))
+}
+
 func TestSession_Acquire_NotFound(t *testing.T) {
 	s, err := NewSession()
 	require.NoError(t, err)
@@ -101,3 +135,63 @@ func TestSession_Acquire_NotFound(t *testing.T) {
 	err = s.Acquire("/nonexistent/file", dst, false)
 	require.Error(t, err)
 }
+
+func TestSession_Open(t *testing.T) {
+	s, err := NewSession()
+	require.NoError(t, err)
+	defer s.Cleanup()
+
+	src := filepath.Join(t.TempDir(), "History")
+	require.NoError(t, os.WriteFile(src, []byte("history"), 0o644))
+
+	f, err := s.Open(src)
+	require.NoError(t, err)
+	data, err := io.ReadAll(f)
+	require.NoError(t, err)
+	assert.Equal(t, "history", string(data))
+	assert.False(t, f.Locked, "an unlocked file is read in place")
+	require.NoError(t, f.Close())
+
+	entries, err := os.ReadDir(s.TempDir())
+	require.NoError(t, err)
+	assert.Empty(t, entries, "nothing is staged for an unlocked file")
+
+	_, err = s.Open("/nonexistent/file")
+	require.Error(t, err)
+}
+
+func TestSession_AcquireFS(t *testing.T) {
+	s, err := NewSession()
+	require.NoError(t, err)
+	defer s.Cleanup()
+
+	fsys := fstest.MapFS{
+		"Default/Cookies":                          {Data: []byte("cookies")},
+		"Default/Cookies-wal":                      {Data: []byte("wal")},
+		"Default/Local Storage/leveldb/000003.log": {Data: []byte("log")},
+		"Default/Local Storage/leveldb/LOCK":       {Data: []byte("")},
+	}
+
+	dst := filepath.Join(s.TempDir(), "Cookies")
+	require.NoError(t, s.AcquireFS(fsys, "Default/Cookies", dst, false))
+	data, err := os.ReadFile(dst)
+	require.NoError(t, err)
+	assert.Equal(t, "cookies", string(data))
+	assert.FileExists(t, dst+"-wal")
+	assert.NoFileExists(t, dst+"-shm")
+
+	dir := filepath.Join(s.TempDir(), "leveldb")
+	require.NoError(t, s.AcquireFS(fsys, "Default/Local Storage/leveldb", dir, true))
+	assert.FileExists(t, filepath.Join(dir, "000003.log"))
+	assert.NoFileExists(t, filepath.Join(dir, "LOCK"), "lock files are skipped")
+
+	require.Error(t, s.AcquireFS(fsys, "Default/History", filepath.Join(s.TempDir(), "History"), false))
+
+	deep := "Default/IndexedDB/" + strings.Repeat("d/", 40) + "000003.log"
+	fsys[deep] = &fstest.MapFile{Data: []byte("deep")}
+	fsys["Default/IndexedDB/shallow.log"] = &fstest.MapFile{Data: []byte("shallow")}
+	dir = filepath.Join(s.TempDir(), "IndexedDB")
+	require.Error(t, s.AcquireFS(fsys, "Default/IndexedDB", dir, true), "a tree past the depth cap is reported")
+	assert.FileExists(t, filepath.Join(dir, "shallow.log"))
+	assert.NoFileExists(t, filepath.Join(dir, filepath.FromSlash(strings.TrimPrefix(deep, "Default/IndexedDB/"))))
+}
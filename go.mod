@@ -3,13 +3,14 @@ module github.com/moond4rk/hackbrowserdata
 go 1.20
 
 require (
+	github.com/andybalholm/brotli v1.1.1
 	github.com/godbus/dbus/v5 v5.2.2
+	github.com/golang/snappy v0.0.0-20180518054509-2e65f85255db
 	github.com/inconshreveable/mousetrap v1.1.0
 	github.com/moond4rk/binarycookies v1.0.3
 	github.com/moond4rk/keychainbreaker v0.2.6
 	github.com/moond4rk/plist v1.2.2
 	github.com/otiai10/copy v1.14.1
-	github.com/ppacher/go-dbus-keyring v1.0.1
 	github.com/spf13/cobra v1.10.2
 	github.com/spf13/pflag v1.0.10
 	github.com/stretchr/testify v1.12.1
@@ -22,7 +23,6 @@ require (
 
 require (
 	github.com/dustin/go-humanize v1.0.1 // indirect
-	github.com/golang/snappy v0.0.0-20180518054509-2e65f85255db // indirect
 	github.com/google/uuid v1.6.0 // indirect
 	github.com/hashicorp/golang-lru/v2 v2.0.7 // indirect
 	github.com/mattn/go-isatty v0.0.20 // indirect
@@ -0,0 +1,33 @@
+package masterkey
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/moond4rk/hackbrowserdata/crypto/dpapi"
+)
+
+var errNoLocalState = errors.New("no Local State file for this installation")
+
+// OfflineDPAPIRetriever is DPAPIRetriever without the Windows API: it unwraps Local State's
+// os_crypt.encrypted_key with user master keys unlocked offline from a Windows image (Protect dir plus
+// password, NT hash or domain backup key). This recovers the Windows v10 key on any host without a live
+// dumpkeys; v20 (App-Bound) keys still need one.
+type OfflineDPAPIRetriever struct {
+	KeyRing *dpapi.KeyRing
+}
+
+func (r *OfflineDPAPIRetriever) RetrieveKey(hints Hints) ([]byte, error) {
+	if hints.LocalStatePath == "" {
+		return nil, errNoLocalState
+	}
+	blob, err := localStateDPAPIBlob(hints.LocalStatePath)
+	if err != nil {
+		return nil, err
+	}
+	masterKey, err := r.KeyRing.Decrypt(blob)
+	if err != nil {
+		return nil, fmt.Errorf("offline DPAPI decrypt: %w", err)
+	}
+	return masterKey, nil
+}
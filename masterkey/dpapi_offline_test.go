@@ -0,0 +1,51 @@
+package masterkey
+
+import (
+	"bytes"
+	"encoding/base64"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/moond4rk/hackbrowserdata/crypto/dpapi"
+)
+
+const testMasterKeyGUID = "5e9c2b8a-3f1d-4c6e-9a7b-1d2e3f4a5b6c"
+
+func writeLocalState(t *testing.T, encryptedKey []byte) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "Local State")
+	content := `{"os_crypt":{"encrypted_key":"` + base64.StdEncoding.EncodeToString(encryptedKey) + `"}}`
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+	return path
+}
+
+func TestOfflineDPAPIRetriever(t *testing.T) {
+	userKey := bytes.Repeat([]byte{0x5a}, 64)
+	chromeKey := bytes.Repeat([]byte{0xc1}, 32)
+	blob, err := dpapi.Protect(testMasterKeyGUID, userKey, chromeKey)
+	require.NoError(t, err)
+	localState := writeLocalState(t, append([]byte("DPAPI"), blob...))
+
+	ring := dpapi.NewKeyRing()
+	ring.Add(testMasterKeyGUID, userKey)
+	key, err := (&OfflineDPAPIRetriever{KeyRing: ring}).RetrieveKey(Hints{LocalStatePath: localState})
+	require.NoError(t, err)
+	assert.Equal(t, chromeKey, key)
+
+	_, err = (&OfflineDPAPIRetriever{KeyRing: dpapi.NewKeyRing()}).RetrieveKey(Hints{LocalStatePath: localState})
+	assert.ErrorContains(t, err, "not in key ring")
+}
+
+func TestOfflineDPAPIRetriever_Errors(t *testing.T) {
+	r := &OfflineDPAPIRetriever{KeyRing: dpapi.NewKeyRing()}
+
+	_, err := r.RetrieveKey(Hints{})
+	require.ErrorIs(t, err, errNoLocalState)
+
+	_, err = r.RetrieveKey(Hints{LocalStatePath: writeLocalState(t, []byte("v10notdpapi"))})
+	assert.ErrorContains(t, err, "unexpected prefix")
+}
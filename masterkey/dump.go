@@ -12,8 +12,8 @@ import (
 
 const DumpVersion = "2"
 
-// Dump is the portable, cross-host container for Chromium master keys — produce it on one host to
-// decrypt copied profile data on another without DPAPI / ABE / Keychain / D-Bus.
+// Dump is the portable, cross-host container for master keys — produce it on one host to decrypt
+// copied profile data on another without DPAPI / ABE / Keychain / D-Bus / a primary password.
 type Dump struct {
 	Version   string    `json:"version"`
 	CreatedAt time.Time `json:"created_at"`
@@ -29,27 +29,31 @@ type Host struct {
 	User     string `json:"user,omitempty"`
 }
 
-// Vault groups profiles sharing master keys (master keys are per-installation, not per-profile).
-// Browser is the lookup key (e.g. "chrome"); Kind is the engine ("chromium"|"chromium-yandex"|
-// "chromium-opera") so a consumer can rebuild the engine without the local browser table.
+// Vault groups the profiles of one installation with their master keys. Browser is the lookup key
+// (e.g. "chrome"); Kind is the engine ("chromium"|"chromium-yandex"|"chromium-opera"|"firefox") so a
+// consumer can rebuild the engine without the local browser table. Chromium keys are per-installation
+// (Keys); Firefox keys are per-profile (ProfileKeys, by profile name), each the NSS key already
+// unlocked with the primary password where the profile had one.
 type Vault struct {
-	Browser     string     `json:"browser"`
-	Kind        string     `json:"kind"`
-	UserDataDir string     `json:"user_data_dir"`
-	Profiles    []string   `json:"profiles"`
-	Keys        MasterKeys `json:"keys"`
+	Browser     string            `json:"browser"`
+	Kind        string            `json:"kind"`
+	UserDataDir string            `json:"user_data_dir"`
+	Profiles    []string          `json:"profiles"`
+	Keys        MasterKeys        `json:"keys"`
+	ProfileKeys map[string][]byte `json:"profile_keys,omitempty"`
 }
 
 func NewDump() Dump {
 	return Dump{
 		Version:   DumpVersion,
 		CreatedAt: time.Now().UTC(),
-		Host:      currentHost(),
+		Host:      CurrentHost(),
 		Vaults:    []Vault{},
 	}
 }
 
-func currentHost() Host {
+// CurrentHost describes the host this process runs on.
+func CurrentHost() Host {
 	h := Host{OS: runtime.GOOS, Arch: runtime.GOARCH}
 	if name, err := os.Hostname(); err == nil {
 		h.Hostname = name
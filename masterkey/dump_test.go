@@ -68,3 +68,30 @@ func TestDump_VaultKindRoundTrip(t *testing.T) {
 		t.Errorf("Vault.Browser round-trip: got %q, want %q", parsed.Vaults[0].Browser, "chrome")
 	}
 }
+
+// TestDump_V12KeyRoundTrip: the Flatpak tier is an additive, omitempty field, so v2 dumps without it
+// still parse and dumps carrying it restore the key byte-for-byte.
+func TestDump_V12KeyRoundTrip(t *testing.T) {
+	k12 := bytes.Repeat([]byte{0x12}, 32)
+	d := NewDump()
+	d.Vaults = append(d.Vaults, Vault{
+		Browser: "chrome-flatpak",
+		Kind:    "chromium",
+		Keys:    MasterKeys{V10: []byte{0x01}, V12: k12},
+	})
+
+	var buf bytes.Buffer
+	if err := d.WriteJSON(&buf); err != nil {
+		t.Fatalf("WriteJSON: %v", err)
+	}
+	if !strings.Contains(buf.String(), `"v12"`) {
+		t.Fatalf("encoded dump should carry a v12 key: %s", buf.String())
+	}
+	parsed, err := ReadJSON(&buf)
+	if err != nil {
+		t.Fatalf("ReadJSON: %v", err)
+	}
+	if !bytes.Equal(parsed.Vaults[0].Keys.V12, k12) {
+		t.Errorf("V12 round-trip: got %x, want %x", parsed.Vaults[0].Keys.V12, k12)
+	}
+}
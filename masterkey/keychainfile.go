@@ -0,0 +1,95 @@
+package masterkey
+
+import (
+	"crypto/sha1"
+	"fmt"
+	"os"
+	"sync"
+
+	"github.com/moond4rk/keychainbreaker"
+)
+
+// https://source.chromium.org/chromium/chromium/src/+/master:components/os_crypt/os_crypt_mac.mm;l=157
+var darwinParams = pbkdf2Params{
+	salt:       []byte("saltysalt"),
+	iterations: 1003,
+	keySize:    16,
+	hashFunc:   sha1.New,
+}
+
+// KeychainFileRetriever is KeychainPasswordRetriever for a copied login.keychain-db (e.g. from a mounted
+// macOS image): the file is unlocked with the user's login password and each browser's
+// "<label> Safe Storage" secret is derived into its v10 key. It runs on any OS, so a macOS profile can
+// be restored without the source Mac. Records are read once and reused across browsers; a browser
+// without a KeychainLabel returns (nil, nil).
+type KeychainFileRetriever struct {
+	Path     string
+	Password string
+
+	once    sync.Once
+	records []keychainbreaker.GenericPassword
+	err     error
+}
+
+func (r *KeychainFileRetriever) RetrieveKey(hints Hints) ([]byte, error) {
+	keys, err := r.RetrieveCandidates(hints)
+	if len(keys) == 0 {
+		return nil, err
+	}
+	return keys[0], nil
+}
+
+func (r *KeychainFileRetriever) RetrieveCandidates(hints Hints) ([][]byte, error) {
+	if hints.KeychainLabel == "" {
+		return nil, nil
+	}
+	r.once.Do(func() {
+		r.records, r.err = loadKeychainFile(r.Path, r.Password)
+	})
+	if r.err != nil {
+		return nil, r.err
+	}
+	return findStorageKeys(r.records, hints.KeychainLabel)
+}
+
+func loadKeychainFile(path, password string) ([]keychainbreaker.GenericPassword, error) {
+	if password == "" {
+		return nil, fmt.Errorf("keychain password not provided")
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read keychain: %w", err)
+	}
+	kc, err := keychainbreaker.Open(keychainbreaker.WithBytes(data))
+	if err != nil {
+		return nil, fmt.Errorf("open keychain %s: %w", path, err)
+	}
+	if err := kc.Unlock(keychainbreaker.WithPassword(password)); err != nil {
+		return nil, fmt.Errorf("unlock keychain %s: %w", path, err)
+	}
+	return kc.GenericPasswords()
+}
+
+// findStorageKey derives the v10 key from the Safe Storage record whose account is storage.
+func findStorageKey(records []keychainbreaker.GenericPassword, storage string) ([]byte, error) {
+	keys, err := findStorageKeys(records, storage)
+	if err != nil {
+		return nil, err
+	}
+	return keys[0], nil
+}
+
+// findStorageKeys derives a v10 key from every record whose account is storage; a keychain can hold
+// duplicates, e.g. after the browser re-created its Safe Storage item.
+func findStorageKeys(records []keychainbreaker.GenericPassword, storage string) ([][]byte, error) {
+	var keys [][]byte
+	for _, rec := range records {
+		if rec.Account == storage {
+			keys = append(keys, darwinParams.deriveKey(rec.Password))
+		}
+	}
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("%q: %w", storage, errStorageNotFound)
+	}
+	return keys, nil
+}
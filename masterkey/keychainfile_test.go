@@ -0,0 +1,49 @@
+package masterkey
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/moond4rk/keychainbreaker"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFindStorageKey_Found(t *testing.T) {
+	records := []keychainbreaker.GenericPassword{
+		{Account: "Chrome", Password: []byte("mock-secret")},
+		{Account: "Brave", Password: []byte("brave-secret")},
+	}
+
+	key, err := findStorageKey(records, "Chrome")
+	require.NoError(t, err)
+	assert.Equal(t, darwinParams.deriveKey([]byte("mock-secret")), key)
+}
+
+func TestFindStorageKey_NotFound(t *testing.T) {
+	records := []keychainbreaker.GenericPassword{
+		{Account: "Chrome", Password: []byte("mock-secret")},
+	}
+
+	key, err := findStorageKey(records, "Firefox")
+	require.Error(t, err)
+	assert.Nil(t, key)
+	assert.ErrorIs(t, err, errStorageNotFound)
+}
+
+func TestKeychainFileRetriever_NoLabel(t *testing.T) {
+	r := &KeychainFileRetriever{Path: filepath.Join(t.TempDir(), "missing"), Password: "pw"}
+	key, err := r.RetrieveKey(Hints{LocalStatePath: "Local State"})
+	require.NoError(t, err)
+	assert.Nil(t, key)
+}
+
+func TestKeychainFileRetriever_Errors(t *testing.T) {
+	r := &KeychainFileRetriever{Path: filepath.Join(t.TempDir(), "login.keychain-db")}
+	_, err := r.RetrieveKey(Hints{KeychainLabel: "Chrome"})
+	assert.ErrorContains(t, err, "keychain password not provided")
+
+	r = &KeychainFileRetriever{Path: filepath.Join(t.TempDir(), "login.keychain-db"), Password: "pw"}
+	_, err = r.RetrieveKey(Hints{KeychainLabel: "Chrome"})
+	assert.ErrorContains(t, err, "read keychain")
+}
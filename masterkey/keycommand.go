@@ -0,0 +1,76 @@
+package masterkey
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+	"time"
+)
+
+const keyCommandTimeout = 2 * time.Minute
+
+// KeyCommandRetriever runs an operator's program and reads one tier's key from its stdout, as hex or
+// base64 (see ParseKey). It goes through the shell (sh -c, or cmd /C on Windows), so the command can
+// carry arguments and pipes. The program learns what is asked through the environment:
+// HBD_BROWSER (browser key), HBD_TIER ("v10", …), HBD_KEYCHAIN_LABEL and HBD_LOCAL_STATE. Empty output
+// means it has no key for that tier and returns (nil, nil); a non-zero exit is an error.
+type KeyCommandRetriever struct {
+	Command string
+	Browser string
+	Tier    string
+}
+
+func (r *KeyCommandRetriever) RetrieveKey(hints Hints) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), keyCommandTimeout)
+	defer cancel()
+
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		cmd = exec.CommandContext(ctx, "cmd", "/C", r.Command) //nolint:gosec // operator-supplied by design
+	} else {
+		cmd = exec.CommandContext(ctx, "sh", "-c", r.Command) //nolint:gosec // operator-supplied by design
+	}
+	cmd.Env = append(os.Environ(),
+		"HBD_BROWSER="+r.Browser,
+		"HBD_TIER="+r.Tier,
+		"HBD_KEYCHAIN_LABEL="+hints.KeychainLabel,
+		"HBD_LOCAL_STATE="+hints.LocalStatePath,
+	)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return nil, fmt.Errorf("key command timed out after %s", keyCommandTimeout)
+		}
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("key command: %w (%s)", err, msg)
+		}
+		return nil, fmt.Errorf("key command: %w", err)
+	}
+	out := strings.TrimSpace(stdout.String())
+	if out == "" {
+		return nil, nil
+	}
+	key, err := ParseKey(out)
+	if err != nil {
+		return nil, fmt.Errorf("key command output for %s: %w", r.Tier, err)
+	}
+	return key, nil
+}
+
+// KeyCommandRetrievers wires one KeyCommandRetriever per tier for browser.
+func KeyCommandRetrievers(command, browser string) Retrievers {
+	return Retrievers{
+		V10: &KeyCommandRetriever{Command: command, Browser: browser, Tier: "v10"},
+		V11: &KeyCommandRetriever{Command: command, Browser: browser, Tier: "v11"},
+		V12: &KeyCommandRetriever{Command: command, Browser: browser, Tier: "v12"},
+		V20: &KeyCommandRetriever{Command: command, Browser: browser, Tier: "v20"},
+	}
+}
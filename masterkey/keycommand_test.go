@@ -0,0 +1,31 @@
+package masterkey
+
+import (
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestKeyCommandRetriever(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("test commands use sh syntax")
+	}
+	const script = `[ "$HBD_BROWSER/$HBD_TIER/$HBD_KEYCHAIN_LABEL" = "chrome/v10/Chrome" ] && echo 00112233445566778899aabbccddeeff`
+	r := KeyCommandRetrievers(script, "chrome")
+
+	key, err := r.V10.RetrieveKey(Hints{KeychainLabel: "Chrome"})
+	require.NoError(t, err)
+	assert.Equal(t, mustParseKey(t, "00112233445566778899aabbccddeeff"), key)
+
+	key, err = (&KeyCommandRetriever{Command: "true", Tier: "v20"}).RetrieveKey(Hints{})
+	require.NoError(t, err)
+	assert.Nil(t, key, "no output means no key for the tier")
+
+	_, err = (&KeyCommandRetriever{Command: "echo denied >&2; exit 3", Tier: "v10"}).RetrieveKey(Hints{})
+	assert.ErrorContains(t, err, "exit status 3 (denied)")
+
+	_, err = (&KeyCommandRetriever{Command: "echo nope", Tier: "v11"}).RetrieveKey(Hints{})
+	assert.ErrorContains(t, err, "key command output for v11")
+}
@@ -0,0 +1,111 @@
+package masterkey
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/moond4rk/hackbrowserdata/crypto/keyring"
+	"github.com/moond4rk/hackbrowserdata/log"
+)
+
+// keyringDirs are where a Linux home keeps its secret-store files: GNOME Keyring, then KWallet
+// (KDE Frameworks 5+, then the KDE 4 locations).
+var keyringDirs = []string{
+	".local/share/keyrings",
+	".local/share/kwalletd",
+	".kde/share/apps/kwallet",
+	".kde4/share/apps/kwallet",
+}
+
+// FindKeyringFiles lists the GNOME Keyring (*.keyring) and KWallet (*.kwl) files under root, which
+// may be one such file, a directory holding them, or a home directory from a disk image.
+func FindKeyringFiles(root string) ([]string, error) {
+	info, err := os.Stat(root)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return []string{root}, nil
+	}
+	var files []string
+	for _, dir := range append([]string{"."}, keyringDirs...) {
+		for _, pattern := range []string{"*.keyring", "*.kwl"} {
+			matches, _ := filepath.Glob(filepath.Join(root, dir, pattern))
+			files = append(files, matches...)
+		}
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no .keyring or .kwl files under %s", root)
+	}
+	return files, nil
+}
+
+// KeyringFileRetriever is DBusRetriever for secret stores copied off a disk image: each GNOME Keyring
+// or KWallet file is unlocked with the user's login password, and the secret labelled like the
+// browser's KeychainLabel ("Chrome Safe Storage") is derived into its v11 key. It runs on any OS.
+// Files the password does not open are skipped; secrets are read once and reused across browsers,
+// and a browser without a KeychainLabel returns (nil, nil). Every matching secret is a candidate, since
+// several files (or a re-created keyring) may each hold one.
+type KeyringFileRetriever struct {
+	Paths    []string
+	Password string
+
+	once    sync.Once
+	secrets []keyring.Secret
+	err     error
+}
+
+func (r *KeyringFileRetriever) RetrieveKey(hints Hints) ([]byte, error) {
+	keys, err := r.RetrieveCandidates(hints)
+	if len(keys) == 0 {
+		return nil, err
+	}
+	return keys[0], nil
+}
+
+func (r *KeyringFileRetriever) RetrieveCandidates(hints Hints) ([][]byte, error) {
+	if hints.KeychainLabel == "" {
+		return nil, nil
+	}
+	r.once.Do(func() {
+		r.secrets, r.err = loadKeyringFiles(r.Paths, r.Password)
+	})
+	if r.err != nil {
+		return nil, r.err
+	}
+	var keys [][]byte
+	for _, s := range r.secrets {
+		if s.Label == hints.KeychainLabel && len(s.Value) > 0 {
+			keys = append(keys, linuxParams.deriveKey(s.Value))
+		}
+	}
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("%q: %w", hints.KeychainLabel, errStorageNotFound)
+	}
+	return keys, nil
+}
+
+func loadKeyringFiles(paths []string, password string) ([]keyring.Secret, error) {
+	var (
+		secrets []keyring.Secret
+		errs    []error
+		opened  int
+	)
+	for _, path := range paths {
+		s, err := keyring.Open(path, password)
+		if err != nil {
+			log.Debugf("keyring file %s: %v", path, err)
+			errs = append(errs, fmt.Errorf("%s: %w", path, err))
+			continue
+		}
+		opened++
+		secrets = append(secrets, s...)
+	}
+	if opened == 0 {
+		return nil, fmt.Errorf("no keyring file could be opened: %w", errors.Join(errs...))
+	}
+	return secrets, nil
+}
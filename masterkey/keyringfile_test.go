@@ -0,0 +1,41 @@
+package masterkey
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFindKeyringFiles(t *testing.T) {
+	home := t.TempDir()
+	gnome := filepath.Join(home, ".local", "share", "keyrings", "login.keyring")
+	wallet := filepath.Join(home, ".local", "share", "kwalletd", "kdewallet.kwl")
+	for _, p := range []string{gnome, wallet, filepath.Join(home, ".local", "share", "keyrings", "user.keystore")} {
+		require.NoError(t, os.MkdirAll(filepath.Dir(p), 0o755))
+		require.NoError(t, os.WriteFile(p, []byte("x"), 0o600))
+	}
+
+	files, err := FindKeyringFiles(home)
+	require.NoError(t, err)
+	assert.Equal(t, []string{gnome, wallet}, files)
+
+	files, err = FindKeyringFiles(wallet)
+	require.NoError(t, err)
+	assert.Equal(t, []string{wallet}, files)
+
+	_, err = FindKeyringFiles(t.TempDir())
+	assert.ErrorContains(t, err, "no .keyring or .kwl files")
+}
+
+func TestKeyringFileRetriever(t *testing.T) {
+	r := &KeyringFileRetriever{Paths: []string{filepath.Join(t.TempDir(), "login.keyring")}, Password: "pw"}
+	key, err := r.RetrieveKey(Hints{})
+	require.NoError(t, err)
+	assert.Nil(t, key, "no label, no keyring lookup")
+
+	_, err = r.RetrieveKey(Hints{KeychainLabel: "Chrome Safe Storage"})
+	assert.ErrorContains(t, err, "no keyring file could be opened")
+}
@@ -0,0 +1,87 @@
+//go:build linux
+
+package masterkey
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/godbus/dbus/v5"
+)
+
+const (
+	kwalletIface = "org.kde.KWallet"
+	kwalletAppID = "hack-browser-data"
+)
+
+// kwalletDaemons are the KWallet D-Bus services, newest first (Plasma 6, then Plasma 5).
+var kwalletDaemons = []struct {
+	service string
+	path    dbus.ObjectPath
+}{
+	{"org.kde.kwalletd6", "/modules/kwalletd6"},
+	{"org.kde.kwalletd5", "/modules/kwalletd5"},
+}
+
+// KWalletRetriever reads the v11 password straight from kwalletd, for KDE hosts whose wallet is not
+// exposed as a Secret Service. Like Chromium's KWallet backend it opens the network wallet and reads
+// the KeychainLabel entry ("Chrome Safe Storage") from the browser's KWalletFolder ("Chrome Keys").
+// kwalletd6 is tried before kwalletd5; browsers without a KWalletFolder return (nil, nil).
+type KWalletRetriever struct{}
+
+func (r *KWalletRetriever) RetrieveKey(hints Hints) ([]byte, error) {
+	if hints.KWalletFolder == "" || hints.KeychainLabel == "" {
+		return nil, nil
+	}
+	conn, err := sessionBus()
+	if err != nil {
+		return nil, fmt.Errorf("dbus session: %w", err)
+	}
+	var errs []error
+	for _, d := range kwalletDaemons {
+		secret, err := readKWalletPassword(conn.Object(d.service, d.path), hints.KWalletFolder, hints.KeychainLabel)
+		if err == nil {
+			return linuxParams.deriveKey(secret), nil
+		}
+		errs = append(errs, fmt.Errorf("%s: %w", d.service, err))
+	}
+	return nil, errors.Join(errs...)
+}
+
+func readKWalletPassword(obj dbus.BusObject, folder, key string) ([]byte, error) {
+	var enabled bool
+	if err := obj.Call(kwalletIface+".isEnabled", 0).Store(&enabled); err != nil {
+		return nil, err
+	}
+	if !enabled {
+		return nil, fmt.Errorf("KWallet is disabled")
+	}
+	var wallet string
+	if err := obj.Call(kwalletIface+".networkWallet", 0).Store(&wallet); err != nil {
+		return nil, err
+	}
+	var handle int32
+	if err := obj.Call(kwalletIface+".open", 0, wallet, int64(0), kwalletAppID).Store(&handle); err != nil {
+		return nil, err
+	}
+	if handle < 0 {
+		return nil, fmt.Errorf("open wallet %q refused", wallet)
+	}
+	defer obj.Call(kwalletIface+".close", 0, handle, false, kwalletAppID)
+
+	var found bool
+	if err := obj.Call(kwalletIface+".hasEntry", 0, handle, folder, key, kwalletAppID).Store(&found); err != nil {
+		return nil, err
+	}
+	if !found {
+		return nil, fmt.Errorf("%q in %s/%s: %w", key, wallet, folder, errStorageNotFound)
+	}
+	var password string
+	if err := obj.Call(kwalletIface+".readPassword", 0, handle, folder, key, kwalletAppID).Store(&password); err != nil {
+		return nil, err
+	}
+	if password == "" {
+		return nil, fmt.Errorf("%q in %s/%s is empty: %w", key, wallet, folder, errStorageNotFound)
+	}
+	return []byte(password), nil
+}
@@ -0,0 +1,106 @@
+//go:build linux
+
+package masterkey
+
+import (
+	"testing"
+
+	"github.com/godbus/dbus/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeKWallet is a stand-in kwalletd serving one network wallet of folder → key → password.
+type fakeKWallet struct {
+	enabled bool
+	folders map[string]map[string]string
+}
+
+func (k *fakeKWallet) IsEnabled() (bool, *dbus.Error)       { return k.enabled, nil }
+func (k *fakeKWallet) NetworkWallet() (string, *dbus.Error) { return "kdewallet", nil }
+
+func (k *fakeKWallet) Open(wallet string, _ int64, _ string) (int32, *dbus.Error) {
+	if wallet != "kdewallet" {
+		return -1, nil
+	}
+	return 7, nil
+}
+
+func (k *fakeKWallet) HasEntry(handle int32, folder, key, _ string) (bool, *dbus.Error) {
+	_, ok := k.folders[folder][key]
+	return handle == 7 && ok, nil
+}
+
+func (k *fakeKWallet) ReadPassword(_ int32, folder, key, _ string) (string, *dbus.Error) {
+	return k.folders[folder][key], nil
+}
+
+func (k *fakeKWallet) Close(int32, bool, string) (int32, *dbus.Error) { return 0, nil }
+
+// startFakeKWallet serves k as kwalletd5 only, so the retriever must fall past kwalletd6.
+func startFakeKWallet(t *testing.T, k *fakeKWallet) {
+	t.Helper()
+	addr := startPrivateBus(t)
+	server, err := dbus.Connect(addr)
+	require.NoError(t, err)
+	t.Cleanup(func() { server.Close() })
+	// KWallet's methods are lowerCamelCase, which Go can't export; map them explicitly.
+	methods := map[string]interface{}{
+		"isEnabled":     k.IsEnabled,
+		"networkWallet": k.NetworkWallet,
+		"open":          k.Open,
+		"hasEntry":      k.HasEntry,
+		"readPassword":  k.ReadPassword,
+		"close":         k.Close,
+	}
+	require.NoError(t, server.ExportMethodTable(methods, "/modules/kwalletd5", kwalletIface))
+	_, err = server.RequestName("org.kde.kwalletd5", dbus.NameFlagDoNotQueue)
+	require.NoError(t, err)
+
+	client, err := dbus.Connect(addr)
+	require.NoError(t, err)
+	t.Cleanup(func() { client.Close() })
+	orig := sessionBus
+	sessionBus = func() (*dbus.Conn, error) { return client, nil }
+	t.Cleanup(func() { sessionBus = orig })
+}
+
+func TestKWalletRetriever(t *testing.T) {
+	startFakeKWallet(t, &fakeKWallet{enabled: true, folders: map[string]map[string]string{
+		"Chrome Keys":   {"Chrome Safe Storage": "kwallet-chrome"},
+		"Chromium Keys": {"Chromium Safe Storage": ""},
+	}})
+	r := &KWalletRetriever{}
+
+	key, err := r.RetrieveKey(Hints{KeychainLabel: "Chrome Safe Storage", KWalletFolder: "Chrome Keys"})
+	require.NoError(t, err)
+	assert.Equal(t, linuxParams.deriveKey([]byte("kwallet-chrome")), key)
+
+	_, err = r.RetrieveKey(Hints{KeychainLabel: "Brave Safe Storage", KWalletFolder: "Brave Keys"})
+	assert.ErrorIs(t, err, errStorageNotFound)
+	assert.ErrorContains(t, err, "org.kde.kwalletd6", "kwalletd6 is tried first")
+
+	_, err = r.RetrieveKey(Hints{KeychainLabel: "Chromium Safe Storage", KWalletFolder: "Chromium Keys"})
+	assert.ErrorIs(t, err, errStorageNotFound)
+
+	key, err = r.RetrieveKey(Hints{KeychainLabel: "Chrome Safe Storage"})
+	require.NoError(t, err)
+	assert.Nil(t, key, "no KWalletFolder, no KWallet lookup")
+}
+
+func TestKWalletRetriever_Disabled(t *testing.T) {
+	startFakeKWallet(t, &fakeKWallet{})
+	_, err := (&KWalletRetriever{}).RetrieveKey(Hints{KeychainLabel: "Chrome Safe Storage", KWalletFolder: "Chrome Keys"})
+	assert.ErrorContains(t, err, "KWallet is disabled")
+}
+
+// TestKeyringRetriever_FallsBackToKWallet: with no Secret Service on the bus, the v11 chain still
+// finds the password through kwalletd.
+func TestKeyringRetriever_FallsBackToKWallet(t *testing.T) {
+	startFakeKWallet(t, &fakeKWallet{enabled: true, folders: map[string]map[string]string{
+		"Chrome Keys": {"Chrome Safe Storage": "kde-only"},
+	}})
+	key, err := KeyringRetriever("").RetrieveKey(Hints{KeychainLabel: "Chrome Safe Storage", KWalletFolder: "Chrome Keys"})
+	require.NoError(t, err)
+	assert.Equal(t, linuxParams.deriveKey([]byte("kde-only")), key)
+}
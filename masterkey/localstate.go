@@ -0,0 +1,37 @@
+package masterkey
+
+import (
+	"encoding/base64"
+	"fmt"
+	"os"
+
+	"github.com/tidwall/gjson"
+)
+
+// localStateDPAPIBlob reads Chrome's Local State os_crypt.encrypted_key and returns the DPAPI blob
+// inside it, the "DPAPI" prefix stripped. Shared by the live and offline Windows retrievers.
+func localStateDPAPIBlob(localStatePath string) ([]byte, error) {
+	data, err := os.ReadFile(localStatePath)
+	if err != nil {
+		return nil, fmt.Errorf("read Local State: %w", err)
+	}
+
+	encryptedKey := gjson.GetBytes(data, "os_crypt.encrypted_key")
+	if !encryptedKey.Exists() {
+		return nil, fmt.Errorf("os_crypt.encrypted_key not found in Local State")
+	}
+
+	keyBytes, err := base64.StdEncoding.DecodeString(encryptedKey.String())
+	if err != nil {
+		return nil, fmt.Errorf("base64 decode encrypted_key: %w", err)
+	}
+
+	const dpapiPrefix = "DPAPI"
+	if len(keyBytes) <= len(dpapiPrefix) {
+		return nil, fmt.Errorf("encrypted_key too short: %d bytes", len(keyBytes))
+	}
+	if string(keyBytes[:len(dpapiPrefix)]) != dpapiPrefix {
+		return nil, fmt.Errorf("encrypted_key unexpected prefix: got %q, want %q", keyBytes[:len(dpapiPrefix)], dpapiPrefix)
+	}
+	return keyBytes[len(dpapiPrefix):], nil
+}
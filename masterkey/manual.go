@@ -0,0 +1,121 @@
+package masterkey
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+// ParseKey decodes an operator-supplied AES key written as hex (optionally "0x"-prefixed) or base64
+// (standard or URL alphabet, padded or not); hex wins when both read. Only AES key sizes (16, 24 or
+// 32 bytes) are accepted.
+func ParseKey(s string) ([]byte, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return nil, fmt.Errorf("empty key")
+	}
+	if h := strings.TrimPrefix(strings.TrimPrefix(s, "0x"), "0X"); len(h)%2 == 0 {
+		if key, err := hex.DecodeString(h); err == nil && validKeySize(len(key)) {
+			return key, nil
+		}
+	}
+	for _, enc := range []*base64.Encoding{
+		base64.StdEncoding, base64.RawStdEncoding, base64.URLEncoding, base64.RawURLEncoding,
+	} {
+		if key, err := enc.DecodeString(s); err == nil {
+			if !validKeySize(len(key)) {
+				return nil, fmt.Errorf("key is %d bytes, want 16, 24 or 32", len(key))
+			}
+			return key, nil
+		}
+	}
+	return nil, fmt.Errorf("key is neither hex nor base64")
+}
+
+func validKeySize(n int) bool {
+	return n == 16 || n == 24 || n == 32
+}
+
+// tierNames are the MasterKeys tiers in the form operators write them.
+var tierNames = []string{"v10", "v11", "v12", "v20"}
+
+// tier returns the slot for a tier name ("v10", …), or nil for an unknown one.
+func (k *MasterKeys) tier(name string) *[]byte {
+	switch strings.ToLower(name) {
+	case "v10":
+		return &k.V10
+	case "v11":
+		return &k.V11
+	case "v12":
+		return &k.V12
+	case "v20":
+		return &k.V20
+	}
+	return nil
+}
+
+// ManualKeys are master keys an analyst already holds (from a memory dump, an earlier case, a
+// colleague's keys file), by lowercase browser key; the "" entry applies to every browser.
+type ManualKeys map[string]MasterKeys
+
+// Set adds one "[<browser>:]<tier>=<key>" spec, e.g. "chrome:v10=9f86d0…" or "v11=base64…". Without a
+// browser the key applies to every browser in the run.
+func (m ManualKeys) Set(spec string) error {
+	target, value, ok := strings.Cut(spec, "=")
+	if !ok {
+		return fmt.Errorf("key %q: want [<browser>:]<tier>=<hex|base64>", spec)
+	}
+	browser, tier := "", target
+	if b, t, found := strings.Cut(target, ":"); found {
+		browser, tier = strings.ToLower(strings.TrimSpace(b)), t
+	}
+	tier = strings.TrimSpace(tier)
+	key, err := ParseKey(value)
+	if err != nil {
+		return fmt.Errorf("key %q: %w", target, err)
+	}
+	keys := m[browser]
+	slot := keys.tier(tier)
+	if slot == nil {
+		return fmt.Errorf("key %q: unknown tier %q (want %s)", target, tier, strings.Join(tierNames, ", "))
+	}
+	*slot = key
+	m[browser] = keys
+	return nil
+}
+
+// For returns the keys for browser: its own tiers, with tiers given for every browser filling the rest.
+func (m ManualKeys) For(browser string) MasterKeys {
+	keys := m[strings.ToLower(browser)]
+	all := m[""]
+	for _, name := range tierNames {
+		if slot := keys.tier(name); *slot == nil {
+			*slot = *all.tier(name)
+		}
+	}
+	return keys
+}
+
+// Prefer returns r with each non-nil tier of first chained ahead of r's own retriever (chains are
+// flattened into one), so an operator's key is tried before the platform's. With key validation, a
+// supplied key that decrypts nothing still falls through to the platform retriever.
+func (r Retrievers) Prefer(first Retrievers) Retrievers {
+	pick := func(a, b Retriever) Retriever {
+		switch {
+		case a == nil:
+			return b
+		case b == nil:
+			return a
+		default:
+			members := append([]Retriever{}, chainMembers(a)...)
+			return NewChain(append(members, chainMembers(b)...)...)
+		}
+	}
+	return Retrievers{
+		V10: pick(first.V10, r.V10),
+		V11: pick(first.V11, r.V11),
+		V12: pick(first.V12, r.V12),
+		V20: pick(first.V20, r.V20),
+	}
+}
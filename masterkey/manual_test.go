@@ -0,0 +1,78 @@
+package masterkey
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseKey(t *testing.T) {
+	want := bytes.Repeat([]byte{0xfb}, 16)
+	for _, in := range []string{
+		"fbfbfbfbfbfbfbfbfbfbfbfbfbfbfbfb",
+		"0xFBFBFBFBFBFBFBFBFBFBFBFBFBFBFBFB",
+		" +/v7+/v7+/v7+/v7+/v7+w== ",
+		"-_v7-_v7-_v7-_v7-_v7-w",
+	} {
+		key, err := ParseKey(in)
+		require.NoError(t, err, in)
+		assert.Equal(t, want, key, in)
+	}
+
+	key, err := ParseKey("MDEyMzQ1Njc4OWFiY2RlZjAxMjM0NTY3ODlhYmNkZWY=")
+	require.NoError(t, err)
+	assert.Equal(t, []byte("0123456789abcdef0123456789abcdef"), key)
+
+	_, err = ParseKey("abcd")
+	assert.ErrorContains(t, err, "want 16, 24 or 32")
+	_, err = ParseKey("not a key!")
+	assert.ErrorContains(t, err, "neither hex nor base64")
+	_, err = ParseKey("")
+	assert.Error(t, err)
+}
+
+func TestManualKeys(t *testing.T) {
+	k1 := "00112233445566778899aabbccddeeff"
+	k2 := "ffeeddccbbaa99887766554433221100"
+	m := make(ManualKeys)
+	require.NoError(t, m.Set("Chrome:v10="+k1))
+	require.NoError(t, m.Set("v11="+k2))
+	require.NoError(t, m.Set("v10="+k2))
+
+	chrome := m.For("chrome")
+	assert.Equal(t, mustParseKey(t, k1), chrome.V10, "a browser's own key wins over an all-browser one")
+	assert.Equal(t, mustParseKey(t, k2), chrome.V11)
+	edge := m.For("edge")
+	assert.Equal(t, mustParseKey(t, k2), edge.V10)
+	assert.Nil(t, edge.V20)
+	assert.Nil(t, m["chrome"].V11, "For must not write back into the map")
+
+	assert.ErrorContains(t, m.Set("chrome:v30="+k1), `unknown tier "v30"`)
+	assert.ErrorContains(t, m.Set("chrome:v10"), "want [<browser>:]<tier>=<hex|base64>")
+	assert.ErrorContains(t, m.Set("chrome:v10=zz"), `key "chrome:v10"`)
+	assert.Empty(t, ManualKeys(nil).For("chrome").V10)
+}
+
+func mustParseKey(t *testing.T, s string) []byte {
+	t.Helper()
+	key, err := ParseKey(s)
+	require.NoError(t, err)
+	return key
+}
+
+func TestRetrievers_Prefer(t *testing.T) {
+	manual := &mockRetriever{key: []byte("manual")}
+	platform := &mockRetriever{key: []byte("platform")}
+	other := &mockRetriever{key: []byte("other")}
+	base := Retrievers{V10: NewChain(platform, other), V11: platform}
+
+	r := base.Prefer(Retrievers{V10: manual, V20: manual})
+	require.IsType(t, &ChainRetriever{}, r.V10)
+	assert.Equal(t, []Retriever{manual, platform, other}, r.V10.(*ChainRetriever).retrievers, "chains are flattened")
+	assert.Same(t, platform, r.V11)
+	assert.Nil(t, r.V12)
+	assert.Same(t, manual, r.V20)
+	assert.Len(t, base.V10.(*ChainRetriever).retrievers, 2, "base is left untouched")
+}
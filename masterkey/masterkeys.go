@@ -5,22 +5,24 @@ import (
 	"fmt"
 )
 
-// MasterKeys holds one key per cipher tier; a profile can mix tiers (Win v10+v20, Linux v10+v11),
+// MasterKeys holds one key per cipher tier; a profile can mix tiers (Win v10+v20, Linux v10+v11+v12),
 // so each is populated independently. A nil tier = that cipher version can't be decrypted.
 type MasterKeys struct {
 	V10 []byte `json:"v10,omitempty"`
 	V11 []byte `json:"v11,omitempty"`
+	V12 []byte `json:"v12,omitempty"`
 	V20 []byte `json:"v20,omitempty"`
 }
 
 func (k MasterKeys) HasAny() bool {
-	return k.V10 != nil || k.V11 != nil || k.V20 != nil
+	return k.V10 != nil || k.V11 != nil || k.V12 != nil || k.V20 != nil
 }
 
 // Retrievers is the per-tier retriever configuration; unused slots are nil.
 type Retrievers struct {
 	V10 Retriever
 	V11 Retriever
+	V12 Retriever
 	V20 Retriever
 }
 
@@ -37,6 +39,7 @@ func NewMasterKeys(r Retrievers, hints Hints) (MasterKeys, error) {
 	}{
 		{"v10", r.V10, &keys.V10},
 		{"v11", r.V11, &keys.V11},
+		{"v12", r.V12, &keys.V12},
 		{"v20", r.V20, &keys.V20},
 	} {
 		if t.r == nil {
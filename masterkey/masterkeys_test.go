@@ -28,15 +28,18 @@ func (r *recordingRetriever) RetrieveKey(hints Hints) ([]byte, error) {
 func TestNewMasterKeys_Matrix(t *testing.T) {
 	k10 := bytes.Repeat([]byte{0x10}, 32)
 	k11 := bytes.Repeat([]byte{0x11}, 32)
+	k12 := bytes.Repeat([]byte{0x12}, 32)
 	k20 := bytes.Repeat([]byte{0x20}, 32)
 
 	tests := []struct {
 		name         string
 		v10          *recordingRetriever
 		v11          *recordingRetriever
+		v12          *recordingRetriever
 		v20          *recordingRetriever
 		wantV10      []byte
 		wantV11      []byte
+		wantV12      []byte
 		wantV20      []byte
 		wantErrParts []string // substrings that must all appear in the joined error; nil = no error
 	}{
@@ -52,6 +55,20 @@ func TestNewMasterKeys_Matrix(t *testing.T) {
 			v11:     &recordingRetriever{key: k11},
 			wantV10: k10, wantV11: k11,
 		},
+		{
+			name:    "Linux Flatpak (V10+V11+V12 ok, V20 not configured)",
+			v10:     &recordingRetriever{key: k10},
+			v11:     &recordingRetriever{key: k11},
+			v12:     &recordingRetriever{key: k12},
+			wantV10: k10, wantV11: k11, wantV12: k12,
+		},
+		{
+			name:         "V12 portal errors, CBC tiers survive",
+			v10:          &recordingRetriever{key: k10},
+			v12:          &recordingRetriever{err: errors.New("portal failed")},
+			wantV10:      k10,
+			wantErrParts: []string{"v12: portal failed"},
+		},
 		{
 			name:    "macOS happy path (V10 only)",
 			v10:     &recordingRetriever{key: k10},
@@ -109,6 +126,9 @@ func TestNewMasterKeys_Matrix(t *testing.T) {
 			if tt.v11 != nil {
 				r.V11 = tt.v11
 			}
+			if tt.v12 != nil {
+				r.V12 = tt.v12
+			}
 			if tt.v20 != nil {
 				r.V20 = tt.v20
 			}
@@ -116,6 +136,7 @@ func TestNewMasterKeys_Matrix(t *testing.T) {
 			keys, err := NewMasterKeys(r, Hints{KeychainLabel: "chrome", LocalStatePath: "/tmp/Local State"})
 			assert.Equal(t, tt.wantV10, keys.V10)
 			assert.Equal(t, tt.wantV11, keys.V11)
+			assert.Equal(t, tt.wantV12, keys.V12)
 			assert.Equal(t, tt.wantV20, keys.V20)
 
 			if len(tt.wantErrParts) == 0 {
@@ -129,7 +150,7 @@ func TestNewMasterKeys_Matrix(t *testing.T) {
 
 			// Every configured retriever must be called exactly once — this is the property
 			// that prevents any regression where a tier is silently bypassed.
-			for name, mock := range map[string]*recordingRetriever{"V10": tt.v10, "V11": tt.v11, "V20": tt.v20} {
+			for name, mock := range map[string]*recordingRetriever{"V10": tt.v10, "V11": tt.v11, "V12": tt.v12, "V20": tt.v20} {
 				if mock == nil {
 					continue
 				}
@@ -146,6 +167,7 @@ func TestNewMasterKeys_AllNilRetrievers(t *testing.T) {
 	require.NoError(t, err)
 	assert.Nil(t, keys.V10)
 	assert.Nil(t, keys.V11)
+	assert.Nil(t, keys.V12)
 	assert.Nil(t, keys.V20)
 }
 
@@ -0,0 +1,122 @@
+//go:build linux
+
+package masterkey
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/godbus/dbus/v5"
+
+	"github.com/moond4rk/hackbrowserdata/crypto"
+)
+
+const (
+	portalBusName     = "org.freedesktop.portal.Desktop"
+	portalObjectPath  = "/org/freedesktop/portal/desktop"
+	portalSecretCall  = "org.freedesktop.portal.Secret.RetrieveSecret"
+	portalReadTimeout = 10 * time.Second
+)
+
+// flatpakInfoPath is the sandbox metadata file Flatpak bind-mounts into every app; a var for tests.
+var flatpakInfoPath = "/.flatpak-info"
+
+// PortalRetriever asks xdg-desktop-portal for the app secret Chromium's SecretPortalKeyProvider uses
+// and HKDF-derives the v12 key from it. The portal keys its secret by the caller's sandbox identity,
+// so the result is only Chrome's secret when this process runs inside that app's sandbox
+// (`flatpak run --command=… <app-id>`); elsewhere it errors rather than return a wrong key, and the
+// operator supplies the secret through SecretFileRetriever instead.
+type PortalRetriever struct{}
+
+func (r *PortalRetriever) RetrieveKey(hints Hints) ([]byte, error) {
+	if hints.FlatpakAppID == "" {
+		return nil, nil
+	}
+	if app := flatpakAppName(flatpakInfoPath); app != hints.FlatpakAppID {
+		if app == "" {
+			app = "host"
+		}
+		return nil, fmt.Errorf("portal secret belongs to the calling app (%s), not %s; run inside its sandbox or supply the secret file",
+			app, hints.FlatpakAppID)
+	}
+
+	conn, err := dbus.SessionBus()
+	if err != nil {
+		return nil, fmt.Errorf("dbus session: %w", err)
+	}
+	secret, err := retrievePortalSecret(conn)
+	if err != nil {
+		return nil, err
+	}
+	return crypto.DeriveSecretPortalKey(secret), nil
+}
+
+// retrievePortalSecret calls Secret.RetrieveSecret with the write end of a pipe; the portal writes the
+// secret into it and closes its copy, so reading to EOF yields the whole secret.
+func retrievePortalSecret(conn *dbus.Conn) ([]byte, error) {
+	rd, wr, err := os.Pipe()
+	if err != nil {
+		return nil, fmt.Errorf("portal pipe: %w", err)
+	}
+	defer rd.Close()
+
+	var handle dbus.ObjectPath
+	options := map[string]dbus.Variant{"handle_token": dbus.MakeVariant("hackbrowserdata")}
+	err = conn.Object(portalBusName, portalObjectPath).
+		Call(portalSecretCall, 0, dbus.UnixFD(wr.Fd()), options).
+		Store(&handle)
+	// Our copy of the write end must close for the read below to see EOF once the portal is done.
+	wr.Close()
+	if err != nil {
+		return nil, fmt.Errorf("retrieve portal secret: %w", err)
+	}
+
+	type result struct {
+		data []byte
+		err  error
+	}
+	done := make(chan result, 1)
+	go func() {
+		data, err := io.ReadAll(rd)
+		done <- result{data, err}
+	}()
+	select {
+	case res := <-done:
+		if res.err != nil {
+			return nil, fmt.Errorf("read portal secret: %w", res.err)
+		}
+		if len(res.data) == 0 {
+			return nil, errEmptySecret
+		}
+		return res.data, nil
+	case <-time.After(portalReadTimeout):
+		return nil, fmt.Errorf("portal secret: no response from %s within %s", handle, portalReadTimeout)
+	}
+}
+
+// flatpakAppName returns the [Application] name from a .flatpak-info file, or "" outside a sandbox.
+func flatpakAppName(path string) string {
+	f, err := os.Open(path)
+	if err != nil {
+		return ""
+	}
+	defer f.Close()
+
+	inApplication := false
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if strings.HasPrefix(line, "[") {
+			inApplication = line == "[Application]"
+			continue
+		}
+		if name, ok := strings.CutPrefix(line, "name="); ok && inApplication {
+			return name
+		}
+	}
+	return ""
+}
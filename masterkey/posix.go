@@ -0,0 +1,21 @@
+package masterkey
+
+import (
+	"crypto/sha1"
+)
+
+// https://source.chromium.org/chromium/chromium/src/+/main:components/os_crypt/os_crypt_linux.cc
+var linuxParams = pbkdf2Params{
+	salt:       []byte("saltysalt"),
+	iterations: 1,
+	keySize:    16,
+	hashFunc:   sha1.New,
+}
+
+// PosixRetriever derives Chromium's kV10Key via PBKDF2 over the hardcoded "peanuts" password — the
+// deterministic v10 key used when no keyring exists (headless/Docker/CI). Mirrors PosixKeyProvider.
+type PosixRetriever struct{}
+
+func (r *PosixRetriever) RetrieveKey(_ Hints) ([]byte, error) {
+	return linuxParams.deriveKey([]byte("peanuts")), nil
+}
@@ -10,13 +10,15 @@ import (
 )
 
 // errStorageNotFound: the browser's account is absent from the credential store (keychain/keyring).
-var errStorageNotFound = errors.New("not found in credential store") //nolint:unused // only used on darwin and linux
+var errStorageNotFound = errors.New("not found in credential store")
 
 // Hints bundles inputs for Retriever; each retriever reads only the field that applies to it.
 type Hints struct {
 	KeychainLabel  string // macOS Keychain account / Linux D-Bus Secret Service label
 	WindowsABEKey  string // Windows ABE browser key (e.g. "chrome"); "" → ABE not applicable
 	LocalStatePath string // path to (temp-copied) Local State JSON; only used on Windows
+	FlatpakAppID   string // Linux Flatpak app id (e.g. "com.google.Chrome"); "" → v12 portal not applicable
+	KWalletFolder  string // Linux KWallet folder holding KeychainLabel (e.g. "Chrome Keys"); "" → no KWallet lookup
 }
 
 // Retriever obtains a Chromium master key from one platform source (DPAPI, Keychain, D-Bus, …).
@@ -5,7 +5,6 @@ package masterkey
 import (
 	"bytes"
 	"context"
-	"crypto/sha1"
 	"errors"
 	"fmt"
 	"os/exec"
@@ -18,14 +17,6 @@ import (
 	"github.com/moond4rk/hackbrowserdata/log"
 )
 
-// https://source.chromium.org/chromium/chromium/src/+/master:components/os_crypt/os_crypt_mac.mm;l=157
-var darwinParams = pbkdf2Params{
-	salt:       []byte("saltysalt"),
-	iterations: 1003,
-	keySize:    16,
-	hashFunc:   sha1.New,
-}
-
 const securityCmdTimeout = 30 * time.Second
 
 // GcoredumpRetriever extracts keychain secrets via CVE-2025-24204 (dumps securityd memory; needs root).
@@ -66,15 +57,6 @@ func loadKeychainRecords(password string) ([]keychainbreaker.GenericPassword, er
 	return kc.GenericPasswords()
 }
 
-func findStorageKey(records []keychainbreaker.GenericPassword, storage string) ([]byte, error) {
-	for _, rec := range records {
-		if rec.Account == storage {
-			return darwinParams.deriveKey(rec.Password), nil
-		}
-	}
-	return nil, fmt.Errorf("%q: %w", storage, errStorageNotFound)
-}
-
 // KeychainPasswordRetriever unlocks login.keychain-db with the macOS login password (no root).
 // Records are cached once and reused across browsers.
 type KeychainPasswordRetriever struct {
@@ -86,6 +68,14 @@ type KeychainPasswordRetriever struct {
 }
 
 func (r *KeychainPasswordRetriever) RetrieveKey(hints Hints) ([]byte, error) {
+	keys, err := r.RetrieveCandidates(hints)
+	if err != nil {
+		return nil, err
+	}
+	return keys[0], nil
+}
+
+func (r *KeychainPasswordRetriever) RetrieveCandidates(hints Hints) ([][]byte, error) {
 	if r.Password == "" {
 		return nil, fmt.Errorf("keychain password not provided")
 	}
@@ -97,7 +87,7 @@ func (r *KeychainPasswordRetriever) RetrieveKey(hints Hints) ([]byte, error) {
 		return nil, r.err
 	}
 
-	return findStorageKey(r.records, hints.KeychainLabel)
+	return findStorageKeys(r.records, hints.KeychainLabel)
 }
 
 // SecurityCmdRetriever queries Keychain via the macOS `security` CLI (may prompt). Results are
@@ -3,6 +3,8 @@
 package masterkey
 
 import (
+	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -53,8 +55,15 @@ func TestDefaultRetrievers_Linux(t *testing.T) {
 	// V10 slot: peanuts-derived kV10Key — PosixRetriever.
 	assert.IsType(t, &PosixRetriever{}, r.V10, "V10 slot should hold PosixRetriever (peanuts kV10Key)")
 
-	// V11 slot: D-Bus keyring kV11Key — DBusRetriever.
-	assert.IsType(t, &DBusRetriever{}, r.V11, "V11 slot should hold DBusRetriever (keyring kV11Key)")
+	// V11 slot: keyring kV11Key — Secret Service, then kwalletd.
+	require.IsType(t, &ChainRetriever{}, r.V11, "V11 slot should hold the keyring chain (kV11Key)")
+	chain := r.V11.(*ChainRetriever).retrievers
+	require.Len(t, chain, 2)
+	assert.IsType(t, &DBusRetriever{}, chain[0])
+	assert.IsType(t, &KWalletRetriever{}, chain[1])
+
+	// V12 slot: Flatpak secret portal — PortalRetriever.
+	assert.IsType(t, &PortalRetriever{}, r.V12, "V12 slot should hold PortalRetriever (Flatpak secret portal)")
 
 	// V20 slot: ABE is Windows-only, nil on Linux.
 	assert.Nil(t, r.V20, "V20 slot must stay nil on Linux")
@@ -64,3 +73,41 @@ func TestDefaultRetrievers_Linux(t *testing.T) {
 	require.NotNil(t, r.V10)
 	require.NotNil(t, r.V11)
 }
+
+func TestPortalRetriever_NotFlatpak(t *testing.T) {
+	key, err := (&PortalRetriever{}).RetrieveKey(Hints{KeychainLabel: "Chrome Safe Storage"})
+	require.NoError(t, err)
+	assert.Nil(t, key, "browsers without a FlatpakAppID have no v12 tier")
+}
+
+// TestPortalRetriever_WrongSandbox: the portal answers with the caller's own secret, so a process
+// outside the target app's sandbox must refuse instead of deriving a key that can't decrypt anything.
+func TestPortalRetriever_WrongSandbox(t *testing.T) {
+	orig := flatpakInfoPath
+	t.Cleanup(func() { flatpakInfoPath = orig })
+
+	flatpakInfoPath = filepath.Join(t.TempDir(), "missing")
+	_, err := (&PortalRetriever{}).RetrieveKey(Hints{FlatpakAppID: "com.google.Chrome"})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "(host)")
+
+	flatpakInfoPath = filepath.Join(t.TempDir(), ".flatpak-info")
+	require.NoError(t, os.WriteFile(flatpakInfoPath, []byte("[Application]\nname=org.chromium.Chromium\n"), 0o644))
+	_, err = (&PortalRetriever{}).RetrieveKey(Hints{FlatpakAppID: "com.google.Chrome"})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "org.chromium.Chromium")
+}
+
+func TestFlatpakAppName(t *testing.T) {
+	path := filepath.Join(t.TempDir(), ".flatpak-info")
+	require.NoError(t, os.WriteFile(path, []byte(`[Instance]
+name=ignored
+instance-id=123
+
+[Application]
+name=com.google.Chrome
+runtime=runtime/org.freedesktop.Platform/x86_64/23.08
+`), 0o644))
+	assert.Equal(t, "com.google.Chrome", flatpakAppName(path))
+	assert.Empty(t, flatpakAppName(filepath.Join(t.TempDir(), "missing")))
+}
@@ -3,11 +3,7 @@
 package masterkey
 
 import (
-	"encoding/base64"
 	"fmt"
-	"os"
-
-	"github.com/tidwall/gjson"
 
 	"github.com/moond4rk/hackbrowserdata/crypto"
 )
@@ -16,30 +12,11 @@ import (
 type DPAPIRetriever struct{}
 
 func (r *DPAPIRetriever) RetrieveKey(hints Hints) ([]byte, error) {
-	data, err := os.ReadFile(hints.LocalStatePath)
-	if err != nil {
-		return nil, fmt.Errorf("read Local State: %w", err)
-	}
-
-	encryptedKey := gjson.GetBytes(data, "os_crypt.encrypted_key")
-	if !encryptedKey.Exists() {
-		return nil, fmt.Errorf("os_crypt.encrypted_key not found in Local State")
-	}
-
-	keyBytes, err := base64.StdEncoding.DecodeString(encryptedKey.String())
+	blob, err := localStateDPAPIBlob(hints.LocalStatePath)
 	if err != nil {
-		return nil, fmt.Errorf("base64 decode encrypted_key: %w", err)
-	}
-
-	const dpapiPrefix = "DPAPI"
-	if len(keyBytes) <= len(dpapiPrefix) {
-		return nil, fmt.Errorf("encrypted_key too short: %d bytes", len(keyBytes))
+		return nil, err
 	}
-	if string(keyBytes[:len(dpapiPrefix)]) != dpapiPrefix {
-		return nil, fmt.Errorf("encrypted_key unexpected prefix: got %q, want %q", keyBytes[:len(dpapiPrefix)], dpapiPrefix)
-	}
-
-	masterKey, err := crypto.DecryptDPAPI(keyBytes[len(dpapiPrefix):])
+	masterKey, err := crypto.DecryptDPAPI(blob)
 	if err != nil {
 		return nil, fmt.Errorf("DPAPI decrypt: %w", err)
 	}
@@ -0,0 +1,33 @@
+package masterkey
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/moond4rk/hackbrowserdata/crypto"
+)
+
+var errEmptySecret = errors.New("portal secret is empty")
+
+// SecretFileRetriever derives the v12 key from an operator-supplied file holding the raw
+// org.freedesktop.portal.Secret secret, for when the portal can't be reached (host-side runs, copied
+// profiles). The file is read byte-for-byte — the secret is binary, so no whitespace is trimmed.
+// Browsers without a FlatpakAppID return (nil, nil), keeping the key out of non-Flatpak vaults.
+type SecretFileRetriever struct {
+	Path string
+}
+
+func (r *SecretFileRetriever) RetrieveKey(hints Hints) ([]byte, error) {
+	if hints.FlatpakAppID == "" {
+		return nil, nil
+	}
+	secret, err := os.ReadFile(r.Path)
+	if err != nil {
+		return nil, fmt.Errorf("read portal secret: %w", err)
+	}
+	if len(secret) == 0 {
+		return nil, fmt.Errorf("%s: %w", r.Path, errEmptySecret)
+	}
+	return crypto.DeriveSecretPortalKey(secret), nil
+}
@@ -0,0 +1,46 @@
+package masterkey
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/moond4rk/hackbrowserdata/crypto"
+)
+
+func writeSecret(t *testing.T, secret []byte) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "portal.secret")
+	require.NoError(t, os.WriteFile(path, secret, 0o600))
+	return path
+}
+
+func TestSecretFileRetriever(t *testing.T) {
+	// Binary secret with a trailing newline byte: it must be used verbatim, not trimmed.
+	secret := []byte{0x00, 0x9f, 0x42, '\n'}
+	r := &SecretFileRetriever{Path: writeSecret(t, secret)}
+
+	key, err := r.RetrieveKey(Hints{FlatpakAppID: "com.google.Chrome"})
+	require.NoError(t, err)
+	assert.Equal(t, crypto.DeriveSecretPortalKey(secret), key)
+}
+
+func TestSecretFileRetriever_NotFlatpak(t *testing.T) {
+	r := &SecretFileRetriever{Path: writeSecret(t, []byte("secret"))}
+	key, err := r.RetrieveKey(Hints{KeychainLabel: "Chrome Safe Storage"})
+	require.NoError(t, err)
+	assert.Nil(t, key, "non-Flatpak browsers must not pick up a v12 key")
+}
+
+func TestSecretFileRetriever_Errors(t *testing.T) {
+	hints := Hints{FlatpakAppID: "org.chromium.Chromium"}
+
+	_, err := (&SecretFileRetriever{Path: filepath.Join(t.TempDir(), "missing")}).RetrieveKey(hints)
+	require.Error(t, err)
+
+	_, err = (&SecretFileRetriever{Path: writeSecret(t, nil)}).RetrieveKey(hints)
+	require.ErrorIs(t, err, errEmptySecret)
+}
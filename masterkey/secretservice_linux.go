@@ -0,0 +1,278 @@
+//go:build linux
+
+package masterkey
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/godbus/dbus/v5"
+
+	"github.com/moond4rk/hackbrowserdata/log"
+)
+
+// Secret Service names (https://specifications.freedesktop.org/secret-service/).
+const (
+	secretServiceName       = "org.freedesktop.secrets"
+	secretServicePath       = "/org/freedesktop/secrets"
+	secretServiceIface      = "org.freedesktop.Secret.Service"
+	secretCollectionIface   = "org.freedesktop.Secret.Collection"
+	secretItemIface         = "org.freedesktop.Secret.Item"
+	secretSessionIface      = "org.freedesktop.Secret.Session"
+	secretPromptIface       = "org.freedesktop.Secret.Prompt"
+	gnomeKeyringUnlockIface = "org.gnome.keyring.InternalUnsupportedGuiltRiddenInterface"
+
+	secretPromptTimeout = 2 * time.Minute
+)
+
+// chromeSecretSchemas are the libsecret schemas Chromium files its Safe Storage password under, newest
+// first (components/os_crypt/sync/key_storage_libsecret.cc); both carry an "application" attribute.
+var chromeSecretSchemas = []string{"chrome_libsecret_os_crypt_password_v2", "chrome_libsecret_os_crypt_password"}
+
+// sessionBus is the bus DBusRetriever talks to; a var so tests can point it at a private bus.
+var sessionBus = dbus.SessionBus
+
+var errPromptDismissed = errors.New("unlock prompt dismissed")
+
+// DBusRetriever queries GNOME Keyring / KDE Wallet via the D-Bus Secret Service. The Safe Storage item
+// is found by Chromium's libsecret schema and application attributes, falling back to a label match
+// for items written by other backends. A locked collection is unlocked with Password when one is set
+// (gnome-keyring's master-password call), otherwise through the service's own unlock prompt. Every
+// matching item is a candidate; RetrieveKey returns the first one that unlocks and reads.
+type DBusRetriever struct {
+	Password string
+}
+
+func (r *DBusRetriever) RetrieveKey(hints Hints) ([]byte, error) {
+	keys, err := r.retrieve(hints.KeychainLabel, 1)
+	if err != nil {
+		return nil, err
+	}
+	return keys[0], nil
+}
+
+func (r *DBusRetriever) RetrieveCandidates(hints Hints) ([][]byte, error) {
+	return r.retrieve(hints.KeychainLabel, 0)
+}
+
+// retrieve derives keys from matching items in order until it has limit of them (0 = all). Items that
+// fail to unlock or read are skipped while another one yields a key.
+func (r *DBusRetriever) retrieve(storage string, limit int) ([][]byte, error) {
+	conn, err := sessionBus()
+	if err != nil {
+		return nil, fmt.Errorf("dbus session: %w", err)
+	}
+	ss, err := openSecretService(conn)
+	if err != nil {
+		return nil, err
+	}
+	defer ss.close()
+
+	items, err := ss.findItems(storage)
+	if err != nil {
+		return nil, err
+	}
+	var keys [][]byte
+	var errs []error
+	for _, item := range items {
+		if err := ss.unlock(item.path, r.Password); err != nil {
+			errs = append(errs, fmt.Errorf("unlock %s: %w", item.path, err))
+			continue
+		}
+		secret, err := ss.secret(item.path)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("get secret for %s: %w", storage, err))
+			continue
+		}
+		if len(secret) == 0 {
+			errs = append(errs, fmt.Errorf("%q: %w", storage, errStorageNotFound))
+			continue
+		}
+		log.Infof("secret service: %s from %s (matched by %s)", storage, item.path, item.match)
+		keys = append(keys, linuxParams.deriveKey(secret))
+		if len(keys) == limit {
+			break
+		}
+	}
+	if len(keys) == 0 {
+		return nil, errors.Join(errs...)
+	}
+	return keys, nil
+}
+
+// secretItem is a Safe Storage item and how it was matched.
+type secretItem struct {
+	path  dbus.ObjectPath
+	match string
+}
+
+// secretService is one plain-transfer session with the Secret Service.
+type secretService struct {
+	conn    *dbus.Conn
+	svc     dbus.BusObject
+	session dbus.ObjectPath
+}
+
+// secretValue is the Secret struct (oayays) the service hands out and accepts.
+type secretValue struct {
+	Session     dbus.ObjectPath
+	Parameters  []byte
+	Value       []byte
+	ContentType string
+}
+
+func openSecretService(conn *dbus.Conn) (*secretService, error) {
+	ss := &secretService{conn: conn, svc: conn.Object(secretServiceName, secretServicePath)}
+	var output dbus.Variant
+	err := ss.svc.Call(secretServiceIface+".OpenSession", 0, "plain", dbus.MakeVariant("")).Store(&output, &ss.session)
+	if err != nil {
+		return nil, fmt.Errorf("secret service: open session: %w", err)
+	}
+	return ss, nil
+}
+
+func (s *secretService) close() {
+	s.conn.Object(secretServiceName, s.session).Call(secretSessionIface+".Close", 0)
+}
+
+// findItems returns the items holding storage's secret: those carrying Chromium's schema attributes
+// first, then those labelled storage.
+func (s *secretService) findItems(storage string) ([]secretItem, error) {
+	var found []secretItem
+	seen := make(map[dbus.ObjectPath]bool)
+	add := func(item dbus.ObjectPath, match string) {
+		if !seen[item] {
+			seen[item] = true
+			found = append(found, secretItem{path: item, match: match})
+		}
+	}
+
+	app := strings.ToLower(strings.TrimSuffix(storage, " Safe Storage"))
+	for _, schema := range chromeSecretSchemas {
+		var unlocked, locked []dbus.ObjectPath
+		attrs := map[string]string{"xdg:schema": schema, "application": app}
+		if err := s.svc.Call(secretServiceIface+".SearchItems", 0, attrs).Store(&unlocked, &locked); err != nil {
+			return nil, fmt.Errorf("secret service: search items: %w", err)
+		}
+		for _, item := range append(unlocked, locked...) {
+			add(item, fmt.Sprintf("schema %s, application=%s", schema, app))
+		}
+	}
+
+	var collections []dbus.ObjectPath
+	if err := s.property(s.svc, secretServiceIface+".Collections", &collections); err != nil {
+		if len(found) > 0 {
+			return found, nil
+		}
+		return nil, fmt.Errorf("secret service: list collections: %w", err)
+	}
+	for _, col := range collections {
+		var items []dbus.ObjectPath
+		if err := s.property(s.object(col), secretCollectionIface+".Items", &items); err != nil {
+			continue
+		}
+		for _, item := range items {
+			var label string
+			if err := s.property(s.object(item), secretItemIface+".Label", &label); err == nil && label == storage {
+				add(item, "label")
+			}
+		}
+	}
+	if len(found) == 0 {
+		return nil, fmt.Errorf("%q: %w", storage, errStorageNotFound)
+	}
+	return found, nil
+}
+
+// unlock opens item's collection if it is locked: with password when given, else via the service prompt.
+func (s *secretService) unlock(item dbus.ObjectPath, password string) error {
+	var locked bool
+	if err := s.property(s.object(item), secretItemIface+".Locked", &locked); err != nil || !locked {
+		return err
+	}
+	if password != "" {
+		collection := dbus.ObjectPath(path.Dir(string(item)))
+		master := secretValue{Session: s.session, Parameters: []byte{}, Value: []byte(password), ContentType: "text/plain"}
+		if err := s.svc.Call(gnomeKeyringUnlockIface+".UnlockWithMasterPassword", 0, collection, master).Err; err != nil {
+			return fmt.Errorf("unlock with password: %w", err)
+		}
+		if err := s.property(s.object(item), secretItemIface+".Locked", &locked); err != nil {
+			return err
+		}
+		if locked {
+			return fmt.Errorf("collection %s is still locked (wrong password?)", collection)
+		}
+		return nil
+	}
+
+	var unlocked []dbus.ObjectPath
+	var prompt dbus.ObjectPath
+	if err := s.svc.Call(secretServiceIface+".Unlock", 0, []dbus.ObjectPath{item}).Store(&unlocked, &prompt); err != nil {
+		return err
+	}
+	if prompt == "/" {
+		return nil
+	}
+	return s.prompt(prompt)
+}
+
+// prompt shows a Secret Service prompt and waits for its Completed signal.
+func (s *secretService) prompt(prompt dbus.ObjectPath) error {
+	match := []dbus.MatchOption{
+		dbus.WithMatchObjectPath(prompt),
+		dbus.WithMatchInterface(secretPromptIface),
+		dbus.WithMatchMember("Completed"),
+	}
+	if err := s.conn.AddMatchSignal(match...); err != nil {
+		return err
+	}
+	defer s.conn.RemoveMatchSignal(match...) //nolint:errcheck // best-effort cleanup
+	signals := make(chan *dbus.Signal, 4)
+	s.conn.Signal(signals)
+	defer s.conn.RemoveSignal(signals)
+
+	obj := s.object(prompt)
+	if err := obj.Call(secretPromptIface+".Prompt", 0, "").Err; err != nil {
+		return fmt.Errorf("show prompt: %w", err)
+	}
+	timeout := time.NewTimer(secretPromptTimeout)
+	defer timeout.Stop()
+	for {
+		select {
+		case sig := <-signals:
+			if sig.Path != prompt || sig.Name != secretPromptIface+".Completed" || len(sig.Body) == 0 {
+				continue
+			}
+			if dismissed, _ := sig.Body[0].(bool); dismissed {
+				return errPromptDismissed
+			}
+			return nil
+		case <-timeout.C:
+			obj.Call(secretPromptIface+".Dismiss", 0)
+			return fmt.Errorf("no answer to unlock prompt within %s", secretPromptTimeout)
+		}
+	}
+}
+
+func (s *secretService) secret(item dbus.ObjectPath) ([]byte, error) {
+	var v secretValue
+	if err := s.object(item).Call(secretItemIface+".GetSecret", 0, s.session).Store(&v); err != nil {
+		return nil, err
+	}
+	return v.Value, nil
+}
+
+func (s *secretService) object(p dbus.ObjectPath) dbus.BusObject {
+	return s.conn.Object(secretServiceName, p)
+}
+
+func (s *secretService) property(obj dbus.BusObject, name string, dst interface{}) error {
+	v, err := obj.GetProperty(name)
+	if err != nil {
+		return err
+	}
+	return v.Store(dst)
+}
@@ -0,0 +1,314 @@
+//go:build linux
+
+package masterkey
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/godbus/dbus/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	fakeLogin   = dbus.ObjectPath(secretServicePath + "/collection/login")
+	fakeSession = dbus.ObjectPath(secretServicePath + "/session/s1")
+	fakePrompt  = dbus.ObjectPath(secretServicePath + "/prompt/p1")
+)
+
+// fakeItem is one secret in the stand-in service; items sit in the login collection.
+type fakeItem struct {
+	label  string
+	attrs  map[string]string
+	secret string
+	broken bool // GetSecret fails
+}
+
+// fakeSecretService is a stand-in Secret Service (plus gnome-keyring's master-password unlock)
+// serving a single login collection over a private bus.
+type fakeSecretService struct {
+	conn     *dbus.Conn
+	password string // accepted by UnlockWithMasterPassword
+
+	mu      sync.Mutex
+	locked  bool
+	dismiss bool // the unlock prompt is dismissed instead of completed
+	items   []fakeItem
+}
+
+func (f *fakeSecretService) itemPath(i int) dbus.ObjectPath {
+	return dbus.ObjectPath(fmt.Sprintf("%s/%d", fakeLogin, i+1))
+}
+
+func (f *fakeSecretService) isLocked() bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.locked
+}
+
+func (f *fakeSecretService) setLocked(locked bool) {
+	f.mu.Lock()
+	f.locked = locked
+	f.mu.Unlock()
+}
+
+// fakeService is exported at the service path under both the Secret Service and gnome-keyring interfaces.
+type fakeService struct{ f *fakeSecretService }
+
+func (s fakeService) OpenSession(alg string, _ dbus.Variant) (dbus.Variant, dbus.ObjectPath, *dbus.Error) {
+	if alg != "plain" {
+		return dbus.MakeVariant(""), "/", dbus.NewError("org.freedesktop.DBus.Error.NotSupported", nil)
+	}
+	return dbus.MakeVariant(""), fakeSession, nil
+}
+
+func (s fakeService) SearchItems(attrs map[string]string) (unlocked, locked []dbus.ObjectPath, _ *dbus.Error) {
+	unlocked, locked = []dbus.ObjectPath{}, []dbus.ObjectPath{}
+	for i, it := range s.f.items {
+		match := true
+		for k, v := range attrs {
+			if it.attrs[k] != v {
+				match = false
+			}
+		}
+		switch {
+		case !match:
+		case s.f.isLocked():
+			locked = append(locked, s.f.itemPath(i))
+		default:
+			unlocked = append(unlocked, s.f.itemPath(i))
+		}
+	}
+	return unlocked, locked, nil
+}
+
+func (s fakeService) Unlock(objects []dbus.ObjectPath) ([]dbus.ObjectPath, dbus.ObjectPath, *dbus.Error) {
+	if !s.f.isLocked() {
+		return objects, "/", nil
+	}
+	return []dbus.ObjectPath{}, fakePrompt, nil
+}
+
+func (s fakeService) UnlockWithMasterPassword(collection dbus.ObjectPath, master secretValue) *dbus.Error {
+	if collection != fakeLogin || string(master.Value) != s.f.password {
+		return dbus.NewError("org.freedesktop.DBus.Error.InvalidArgs", []interface{}{"The password was invalid"})
+	}
+	s.f.setLocked(false)
+	return nil
+}
+
+type fakeSessionObj struct{}
+
+func (fakeSessionObj) Close() *dbus.Error { return nil }
+
+type fakePromptObj struct{ f *fakeSecretService }
+
+func (p fakePromptObj) Prompt(string) *dbus.Error {
+	p.f.mu.Lock()
+	dismiss := p.f.dismiss
+	if !dismiss {
+		p.f.locked = false
+	}
+	p.f.mu.Unlock()
+	err := p.f.conn.Emit(fakePrompt, secretPromptIface+".Completed", dismiss, dbus.MakeVariant([]dbus.ObjectPath{fakeLogin}))
+	if err != nil {
+		return dbus.MakeFailedError(err)
+	}
+	return nil
+}
+
+func (fakePromptObj) Dismiss() *dbus.Error { return nil }
+
+type fakeItemObj struct {
+	f *fakeSecretService
+	i int
+}
+
+func (o fakeItemObj) GetSecret(session dbus.ObjectPath) (secretValue, *dbus.Error) {
+	if o.f.isLocked() {
+		return secretValue{}, dbus.NewError("org.freedesktop.Secret.Error.IsLocked", nil)
+	}
+	if o.f.items[o.i].broken {
+		return secretValue{}, dbus.NewError("org.freedesktop.DBus.Error.Failed", nil)
+	}
+	return secretValue{Session: session, Parameters: []byte{}, Value: []byte(o.f.items[o.i].secret), ContentType: "text/plain"}, nil
+}
+
+// fakeProps answers org.freedesktop.DBus.Properties.Get for one object.
+type fakeProps struct {
+	f    *fakeSecretService
+	path dbus.ObjectPath
+}
+
+func (p fakeProps) Get(_, name string) (dbus.Variant, *dbus.Error) {
+	switch {
+	case p.path == secretServicePath && name == "Collections":
+		return dbus.MakeVariant([]dbus.ObjectPath{fakeLogin}), nil
+	case p.path == fakeLogin && name == "Items":
+		items := make([]dbus.ObjectPath, len(p.f.items))
+		for i := range items {
+			items[i] = p.f.itemPath(i)
+		}
+		return dbus.MakeVariant(items), nil
+	case name == "Locked":
+		return dbus.MakeVariant(p.f.isLocked()), nil
+	case name == "Label":
+		for i, it := range p.f.items {
+			if p.f.itemPath(i) == p.path {
+				return dbus.MakeVariant(it.label), nil
+			}
+		}
+	}
+	return dbus.MakeVariant(""), dbus.NewError("org.freedesktop.DBus.Error.UnknownProperty", []interface{}{name})
+}
+
+// startFakeSecretService runs a private dbus-daemon, serves f on it and points sessionBus at it.
+func startFakeSecretService(t *testing.T, f *fakeSecretService) {
+	t.Helper()
+	addr := startPrivateBus(t)
+
+	server, err := dbus.Connect(addr)
+	require.NoError(t, err)
+	t.Cleanup(func() { server.Close() })
+	f.conn = server
+
+	svc := fakeService{f}
+	require.NoError(t, server.Export(svc, secretServicePath, secretServiceIface))
+	require.NoError(t, server.Export(svc, secretServicePath, gnomeKeyringUnlockIface))
+	require.NoError(t, server.Export(fakeSessionObj{}, fakeSession, secretSessionIface))
+	require.NoError(t, server.Export(fakePromptObj{f}, fakePrompt, secretPromptIface))
+	paths := []dbus.ObjectPath{secretServicePath, fakeLogin}
+	for i := range f.items {
+		require.NoError(t, server.Export(fakeItemObj{f, i}, f.itemPath(i), secretItemIface))
+		paths = append(paths, f.itemPath(i))
+	}
+	for _, p := range paths {
+		require.NoError(t, server.Export(fakeProps{f, p}, p, "org.freedesktop.DBus.Properties"))
+	}
+	reply, err := server.RequestName(secretServiceName, dbus.NameFlagDoNotQueue)
+	require.NoError(t, err)
+	require.Equal(t, dbus.RequestNameReplyPrimaryOwner, reply)
+
+	client, err := dbus.Connect(addr)
+	require.NoError(t, err)
+	t.Cleanup(func() { client.Close() })
+	orig := sessionBus
+	sessionBus = func() (*dbus.Conn, error) { return client, nil }
+	t.Cleanup(func() { sessionBus = orig })
+}
+
+// startPrivateBus launches a throwaway dbus-daemon and returns its address; the test is skipped where
+// no dbus-daemon is installed.
+func startPrivateBus(t *testing.T) string {
+	t.Helper()
+	daemon, err := exec.LookPath("dbus-daemon")
+	if err != nil {
+		t.Skip("dbus-daemon not installed")
+	}
+	// Kept short: unix socket paths are limited to ~108 bytes.
+	dir, err := os.MkdirTemp("", "hbd-bus")
+	require.NoError(t, err)
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	conf := filepath.Join(dir, "bus.conf")
+	require.NoError(t, os.WriteFile(conf, []byte(`<busconfig>
+  <type>session</type>
+  <listen>unix:path=`+filepath.Join(dir, "bus")+`</listen>
+  <auth>EXTERNAL</auth>
+  <policy context="default">
+    <allow send_destination="*" eavesdrop="true"/>
+    <allow eavesdrop="true"/>
+    <allow own="*"/>
+  </policy>
+</busconfig>
+`), 0o600))
+
+	cmd := exec.Command(daemon, "--config-file="+conf, "--nofork", "--print-address")
+	stdout, err := cmd.StdoutPipe()
+	require.NoError(t, err)
+	require.NoError(t, cmd.Start())
+	t.Cleanup(func() {
+		_ = cmd.Process.Kill()
+		_ = cmd.Wait()
+	})
+	addr, err := bufio.NewReader(stdout).ReadString('\n')
+	require.NoError(t, err)
+	return strings.TrimSpace(addr)
+}
+
+func chromeSchemaItem(secret string) fakeItem {
+	return fakeItem{
+		label:  "Chrome Safe Storage",
+		attrs:  map[string]string{"xdg:schema": "chrome_libsecret_os_crypt_password_v2", "application": "chrome"},
+		secret: secret,
+	}
+}
+
+func TestDBusRetriever_SchemaAttributes(t *testing.T) {
+	startFakeSecretService(t, &fakeSecretService{items: []fakeItem{
+		{label: "Chrome Safe Storage", secret: "decoy-without-schema"},
+		chromeSchemaItem("schema-secret"),
+	}})
+
+	key, err := (&DBusRetriever{}).RetrieveKey(Hints{KeychainLabel: "Chrome Safe Storage"})
+	require.NoError(t, err)
+	assert.Equal(t, linuxParams.deriveKey([]byte("schema-secret")), key)
+}
+
+func TestDBusRetriever_LabelFallback(t *testing.T) {
+	startFakeSecretService(t, &fakeSecretService{items: []fakeItem{
+		chromeSchemaItem("chrome-secret"),
+		{label: "Brave Safe Storage", secret: "brave-secret"},
+	}})
+
+	key, err := (&DBusRetriever{}).RetrieveKey(Hints{KeychainLabel: "Brave Safe Storage"})
+	require.NoError(t, err)
+	assert.Equal(t, linuxParams.deriveKey([]byte("brave-secret")), key)
+
+	_, err = (&DBusRetriever{}).RetrieveKey(Hints{KeychainLabel: "Chromium Safe Storage"})
+	assert.ErrorIs(t, err, errStorageNotFound)
+}
+
+func TestDBusRetriever_LockedWithPassword(t *testing.T) {
+	f := &fakeSecretService{password: "hunter2", locked: true, items: []fakeItem{chromeSchemaItem("locked-secret")}}
+	startFakeSecretService(t, f)
+
+	_, err := (&DBusRetriever{Password: "wrong"}).RetrieveKey(Hints{KeychainLabel: "Chrome Safe Storage"})
+	assert.ErrorContains(t, err, "unlock with password")
+	assert.True(t, f.isLocked())
+
+	key, err := (&DBusRetriever{Password: "hunter2"}).RetrieveKey(Hints{KeychainLabel: "Chrome Safe Storage"})
+	require.NoError(t, err)
+	assert.Equal(t, linuxParams.deriveKey([]byte("locked-secret")), key)
+}
+
+func TestDBusRetriever_LockedPrompt(t *testing.T) {
+	f := &fakeSecretService{locked: true, dismiss: true, items: []fakeItem{chromeSchemaItem("prompt-secret")}}
+	startFakeSecretService(t, f)
+
+	_, err := (&DBusRetriever{}).RetrieveKey(Hints{KeychainLabel: "Chrome Safe Storage"})
+	assert.ErrorIs(t, err, errPromptDismissed)
+
+	f.mu.Lock()
+	f.dismiss = false
+	f.mu.Unlock()
+	key, err := (&DBusRetriever{}).RetrieveKey(Hints{KeychainLabel: "Chrome Safe Storage"})
+	require.NoError(t, err)
+	assert.Equal(t, linuxParams.deriveKey([]byte("prompt-secret")), key)
+}
+
+func TestDBusRetriever_SkipsUnreadableItem(t *testing.T) {
+	broken := chromeSchemaItem("broken-secret")
+	broken.broken = true
+	startFakeSecretService(t, &fakeSecretService{items: []fakeItem{broken, chromeSchemaItem("second-secret")}})
+
+	key, err := (&DBusRetriever{}).RetrieveKey(Hints{KeychainLabel: "Chrome Safe Storage"})
+	require.NoError(t, err)
+	assert.Equal(t, linuxParams.deriveKey([]byte("second-secret")), key)
+}
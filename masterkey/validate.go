@@ -0,0 +1,255 @@
+package masterkey
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/moond4rk/hackbrowserdata/crypto"
+)
+
+// CandidateRetriever is a Retriever that can offer every key it finds rather than only the first, e.g.
+// a keyring holding several "Chrome Safe Storage" items. The first candidate is what RetrieveKey returns.
+type CandidateRetriever interface {
+	Retriever
+	RetrieveCandidates(hints Hints) ([][]byte, error)
+}
+
+// Samples are encrypted values read from an installation's profiles (Login Data password_value,
+// Cookies encrypted_value) for checking key candidates. Values without a v10/v11/v12/v20 prefix are
+// ignored.
+type Samples [][]byte
+
+func (s Samples) of(version crypto.CipherVersion) [][]byte {
+	var out [][]byte
+	for _, v := range s {
+		if crypto.DetectVersion(v) == version {
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
+// emptyKeySource names kEmptyKey in reports.
+const emptyKeySource = "kEmptyKey"
+
+// KeyCheck is how one candidate key fared against a tier's sampled values.
+type KeyCheck struct {
+	Source    string // retriever that produced the key, or "kEmptyKey"
+	Decrypted int
+}
+
+// TierReport records one tier's validation: how many sampled values carry its prefix, what each
+// candidate decrypted, and which candidate was kept (-1 when none was).
+type TierReport struct {
+	Tier    string
+	Samples int
+	Checks  []KeyCheck
+	Chosen  int
+}
+
+func (r TierReport) String() string {
+	tried := make([]string, 0, len(r.Checks))
+	for i, c := range r.Checks {
+		if i != r.Chosen {
+			tried = append(tried, fmt.Sprintf("%s %d/%d", c.Source, c.Decrypted, r.Samples))
+		}
+	}
+	var s string
+	if r.Chosen < 0 {
+		s = fmt.Sprintf("%s: no usable key for %d sampled values", r.Tier, r.Samples)
+	} else {
+		c := r.Checks[r.Chosen]
+		s = fmt.Sprintf("%s: using %s, decrypts %d/%d sampled values", r.Tier, c.Source, c.Decrypted, r.Samples)
+	}
+	if len(tried) > 0 {
+		s += " (also tried: " + strings.Join(tried, ", ") + ")"
+	}
+	return s
+}
+
+// ValidateMasterKeys is NewMasterKeys that tests keys against samples before trusting them. A tier
+// with no sampled values works as in NewMasterKeys. Otherwise each retriever (each member, for a chain)
+// offers its candidates in order, and each candidate is scored by how many of the tier's samples it
+// decrypts: GCM tiers must authenticate, CBC tiers must unpad without the kEmptyKey retry. The walk
+// stops once a candidate decrypts most of the samples kEmptyKey does not, and the best scorer is kept;
+// if nothing decrypts, the first candidate is kept as before. kEmptyKey is scored for CBC tiers so the
+// report explains values it opens, but it is only kept when no retriever produced a key — the
+// decryptor retries it on every CBC value anyway. A report is returned for every tier checked.
+func ValidateMasterKeys(r Retrievers, hints Hints, samples Samples) (MasterKeys, []TierReport, error) {
+	var keys MasterKeys
+	var reports []TierReport
+	var errs []error
+
+	for _, t := range []struct {
+		name    string
+		version crypto.CipherVersion
+		r       Retriever
+		dst     *[]byte
+	}{
+		{"v10", crypto.CipherV10, r.V10, &keys.V10},
+		{"v11", crypto.CipherV11, r.V11, &keys.V11},
+		{"v12", crypto.CipherV12, r.V12, &keys.V12},
+		{"v20", crypto.CipherV20, r.V20, &keys.V20},
+	} {
+		if t.r == nil {
+			continue
+		}
+		blobs := samples.of(t.version)
+		if len(blobs) == 0 {
+			k, err := t.r.RetrieveKey(hints)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", t.name, err))
+				continue
+			}
+			*t.dst = k
+			continue
+		}
+		k, report, err := validateTier(t.name, t.version, t.r, hints, blobs)
+		reports = append(reports, report)
+		if k == nil && err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", t.name, err))
+			continue
+		}
+		*t.dst = k
+	}
+	return keys, reports, errors.Join(errs...)
+}
+
+func validateTier(tier string, version crypto.CipherVersion, r Retriever, hints Hints, blobs [][]byte) ([]byte, TierReport, error) {
+	report := TierReport{Tier: tier, Samples: len(blobs), Chosen: -1}
+	var candidates [][]byte
+	var errs []error
+	emptyOpened := -1 // blobs kEmptyKey opens; -1 until the tier is known to be CBC
+	for _, src := range chainMembers(r) {
+		keys, err := retrieveCandidates(src, hints)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", sourceName(src), err))
+			continue
+		}
+		for i, k := range keys {
+			if containsKey(candidates, k) {
+				continue
+			}
+			name := sourceName(src)
+			if len(keys) > 1 {
+				name = fmt.Sprintf("%s#%d", name, i+1)
+			}
+			candidates = append(candidates, k)
+			report.Checks = append(report.Checks, KeyCheck{Source: name, Decrypted: countDecrypted(version, k, blobs)})
+		}
+		if emptyOpened < 0 && isCBCTier(version, candidates) {
+			emptyOpened = countDecrypted(version, crypto.EmptyKey(), blobs)
+		}
+		if convincing(report.Checks, len(blobs)-maxInt(emptyOpened, 0)) {
+			break
+		}
+	}
+
+	for i, c := range report.Checks {
+		if report.Chosen < 0 || c.Decrypted > report.Checks[report.Chosen].Decrypted {
+			report.Chosen = i
+		}
+	}
+	if emptyOpened < 0 && version == crypto.CipherV11 {
+		emptyOpened = countDecrypted(version, crypto.EmptyKey(), blobs)
+	}
+	if emptyOpened >= 0 {
+		report.Checks = append(report.Checks, KeyCheck{Source: emptyKeySource, Decrypted: emptyOpened})
+		if report.Chosen < 0 && emptyOpened > 0 {
+			report.Chosen = len(report.Checks) - 1
+			return crypto.EmptyKey(), report, nil
+		}
+	}
+	if report.Chosen < 0 {
+		return nil, report, errors.Join(errs...)
+	}
+	return candidates[report.Chosen], report, nil
+}
+
+// convincing reports whether some candidate decrypts most of the remaining (non-kEmptyKey) samples, or
+// there is a candidate and kEmptyKey already opens every sample.
+func convincing(checks []KeyCheck, remaining int) bool {
+	for _, c := range checks {
+		if remaining == 0 || 2*c.Decrypted > remaining {
+			return true
+		}
+	}
+	return false
+}
+
+func containsKey(keys [][]byte, key []byte) bool {
+	for _, k := range keys {
+		if bytes.Equal(k, key) {
+			return true
+		}
+	}
+	return false
+}
+
+func maxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+// isCBCTier reports whether version is sealed with AES-CBC, where kEmptyKey may apply: always for v11,
+// and for v10 when the candidates are 16-byte macOS/Linux keys rather than Windows' 32-byte GCM key.
+func isCBCTier(version crypto.CipherVersion, candidates [][]byte) bool {
+	switch version {
+	case crypto.CipherV11:
+		return true
+	case crypto.CipherV10:
+		for _, c := range candidates {
+			if len(c) == 16 {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// countDecrypted counts the blobs key opens, dispatching like the Chromium decryptor but without the
+// kEmptyKey retry.
+func countDecrypted(version crypto.CipherVersion, key []byte, blobs [][]byte) int {
+	var n int
+	for _, b := range blobs {
+		var err error
+		if version == crypto.CipherV11 || (version == crypto.CipherV10 && len(key) != 32) {
+			_, err = crypto.DecryptChromiumCBCStrict(key, b)
+		} else {
+			_, err = crypto.DecryptChromiumGCM(key, b)
+		}
+		if err == nil {
+			n++
+		}
+	}
+	return n
+}
+
+// chainMembers opens up a ChainRetriever so each member's keys are checked, not just the first to succeed.
+func chainMembers(r Retriever) []Retriever {
+	if c, ok := r.(*ChainRetriever); ok {
+		return c.retrievers
+	}
+	return []Retriever{r}
+}
+
+func retrieveCandidates(r Retriever, hints Hints) ([][]byte, error) {
+	if cr, ok := r.(CandidateRetriever); ok {
+		return cr.RetrieveCandidates(hints)
+	}
+	key, err := r.RetrieveKey(hints)
+	if err != nil || len(key) == 0 {
+		return nil, err
+	}
+	return [][]byte{key}, nil
+}
+
+// sourceName is r's type name without package or pointer, e.g. "DBusRetriever".
+func sourceName(r Retriever) string {
+	name := fmt.Sprintf("%T", r)
+	return name[strings.LastIndexAny(name, ".*")+1:]
+}
@@ -0,0 +1,122 @@
+package masterkey
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/moond4rk/hackbrowserdata/crypto"
+)
+
+// candidateRetriever offers several keys, like a keyring holding duplicate Safe Storage items.
+type candidateRetriever struct{ keys [][]byte }
+
+func (r *candidateRetriever) RetrieveKey(_ Hints) ([]byte, error) { return r.keys[0], nil }
+
+func (r *candidateRetriever) RetrieveCandidates(_ Hints) ([][]byte, error) { return r.keys, nil }
+
+func sealCBC(t *testing.T, prefix string, key []byte, plaintext string) []byte {
+	t.Helper()
+	ct, err := crypto.AESCBCEncrypt(key, bytes.Repeat([]byte{0x20}, 16), []byte(plaintext))
+	require.NoError(t, err)
+	return append([]byte(prefix), ct...)
+}
+
+func sealGCM(t *testing.T, prefix string, key []byte, plaintext string) []byte {
+	t.Helper()
+	nonce := bytes.Repeat([]byte{0x07}, 12)
+	ct, err := crypto.AESGCMEncrypt(key, nonce, []byte(plaintext))
+	require.NoError(t, err)
+	return append(append([]byte(prefix), nonce...), ct...)
+}
+
+func TestValidateMasterKeys_ChainPassesOverStaleKey(t *testing.T) {
+	stale := bytes.Repeat([]byte{0x01}, 16)
+	good := bytes.Repeat([]byte{0x02}, 16)
+	unused := &recordingRetriever{key: bytes.Repeat([]byte{0x03}, 16)}
+	samples := Samples{sealCBC(t, "v11", good, "hunter2"), sealCBC(t, "v11", good, "letmein"), sealCBC(t, "v11", good, "pa55")}
+
+	keys, reports, err := ValidateMasterKeys(Retrievers{
+		V11: NewChain(&mockRetriever{key: stale}, &mockRetriever{key: good}, unused),
+	}, Hints{}, samples)
+	require.NoError(t, err)
+	assert.Equal(t, good, keys.V11)
+	assert.Zero(t, unused.calls, "the walk stops once a key decrypts the samples")
+
+	require.Len(t, reports, 1)
+	assert.Equal(t, "v11", reports[0].Tier)
+	assert.Equal(t, 3, reports[0].Samples)
+	assert.Equal(t, []KeyCheck{
+		{Source: "mockRetriever", Decrypted: 0},
+		{Source: "mockRetriever", Decrypted: 3},
+		{Source: emptyKeySource, Decrypted: 0},
+	}, reports[0].Checks)
+	assert.Equal(t, 1, reports[0].Chosen)
+	assert.Equal(t, "v11: using mockRetriever, decrypts 3/3 sampled values (also tried: mockRetriever 0/3, kEmptyKey 0/3)",
+		reports[0].String())
+}
+
+func TestValidateMasterKeys_Candidates(t *testing.T) {
+	wrong := bytes.Repeat([]byte{0x0a}, 32)
+	right := bytes.Repeat([]byte{0x0b}, 32)
+	samples := Samples{sealGCM(t, "v20", right, "cookie"), []byte("unprefixed dpapi blob")}
+
+	keys, reports, err := ValidateMasterKeys(Retrievers{
+		V20: &candidateRetriever{keys: [][]byte{wrong, right}},
+	}, Hints{}, samples)
+	require.NoError(t, err)
+	assert.Equal(t, right, keys.V20)
+	require.Len(t, reports, 1)
+	assert.Equal(t, []KeyCheck{
+		{Source: "candidateRetriever#1", Decrypted: 0},
+		{Source: "candidateRetriever#2", Decrypted: 1},
+	}, reports[0].Checks, "GCM tiers never score kEmptyKey")
+}
+
+func TestValidateMasterKeys_EmptyKey(t *testing.T) {
+	samples := Samples{sealCBC(t, "v11", crypto.EmptyKey(), "corrupted by crbug.com/40055416")}
+
+	// A real key that opens nothing is kept — the decryptor retries kEmptyKey on its own.
+	stored := bytes.Repeat([]byte{0x05}, 16)
+	keys, reports, err := ValidateMasterKeys(Retrievers{V11: &mockRetriever{key: stored}}, Hints{}, samples)
+	require.NoError(t, err)
+	assert.Equal(t, stored, keys.V11)
+	assert.Equal(t, "v11: using mockRetriever, decrypts 0/1 sampled values (also tried: kEmptyKey 1/1)", reports[0].String())
+
+	// With no key at all, kEmptyKey fills the tier.
+	keys, reports, err = ValidateMasterKeys(Retrievers{V11: &mockRetriever{err: errors.New("no keyring")}}, Hints{}, samples)
+	require.NoError(t, err)
+	assert.Equal(t, crypto.EmptyKey(), keys.V11)
+	assert.Equal(t, "v11: using kEmptyKey, decrypts 1/1 sampled values", reports[0].String())
+}
+
+func TestValidateMasterKeys_NoneDecrypts(t *testing.T) {
+	first := bytes.Repeat([]byte{0x0c}, 16)
+	samples := Samples{sealCBC(t, "v10", bytes.Repeat([]byte{0x0d}, 16), "secret")}
+
+	keys, reports, err := ValidateMasterKeys(Retrievers{
+		V10: NewChain(&mockRetriever{key: first}, &mockRetriever{err: errors.New("prompt dismissed")}),
+	}, Hints{}, samples)
+	require.NoError(t, err, "a kept key is not an error, even if nothing decrypts")
+	assert.Equal(t, first, keys.V10)
+	require.Len(t, reports, 1)
+	assert.Contains(t, reports[0].String(), "decrypts 0/1")
+
+	_, reports, err = ValidateMasterKeys(Retrievers{V20: &mockRetriever{err: errors.New("abe failed")}}, Hints{},
+		Samples{sealGCM(t, "v20", first, "x")})
+	assert.ErrorContains(t, err, "v20: mockRetriever: abe failed")
+	assert.Equal(t, "v20: no usable key for 1 sampled values", reports[0].String())
+}
+
+func TestValidateMasterKeys_NoSamples(t *testing.T) {
+	r := &recordingRetriever{key: []byte("k")}
+	keys, reports, err := ValidateMasterKeys(Retrievers{V10: r}, Hints{KeychainLabel: "Chrome"}, nil)
+	require.NoError(t, err)
+	assert.Equal(t, []byte("k"), keys.V10)
+	assert.Empty(t, reports)
+	assert.Equal(t, 1, r.calls)
+	assert.Equal(t, "Chrome", r.gotHints.KeychainLabel)
+}
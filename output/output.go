@@ -113,6 +113,9 @@ var categories = []struct {
 	{"extension", makeExtractor(func(d *types.BrowserData) []types.ExtensionEntry { return d.Extensions })},
 	{"localstorage", makeExtractor(func(d *types.BrowserData) []types.StorageEntry { return d.LocalStorage })},
 	{"sessionstorage", makeExtractor(func(d *types.BrowserData) []types.StorageEntry { return d.SessionStorage })},
+	{"cache", makeExtractor(func(d *types.BrowserData) []types.CacheEntry { return d.Caches })},
+	{"favicon", makeExtractor(func(d *types.BrowserData) []types.FaviconEntry { return d.Favicons })},
+	{"omnibox", makeExtractor(func(d *types.BrowserData) []types.OmniboxEntry { return d.Omnibox })},
 }
 
 // aggregate merges all results into row slices grouped by category,
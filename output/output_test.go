@@ -88,9 +88,16 @@ func TestWrite_CSV_Password(t *testing.T) {
 	records := readCSV(t, filepath.Join(dir, "password.csv"))
 	require.Len(t, records, 3) // header + 2 rows
 
-	assert.Equal(t, []string{"browser", "profile", "url", "username", "password", "created_at"}, records[0])
-	assert.Equal(t, []string{"Chrome", "Default", "https://example.com", "alice", "secret", "2026-01-15T10:30:00Z"}, records[1])
-	assert.Equal(t, []string{"Firefox", "abc123", "https://reddit.com", "bob", "hunter2", "2026-01-15T10:30:00Z"}, records[2])
+	assert.Equal(t, []string{
+		"browser", "profile", "url", "username", "password", "created_at", "action_url", "signon_realm",
+		"last_used_at", "password_changed_at", "times_used", "blocked_by_user", "note", "insecure", "store", "recovered", "recovered_from",
+	}, records[0])
+	assert.Equal(t, []string{
+		"Chrome", "Default", "https://example.com", "alice", "secret", "2026-01-15T10:30:00Z", "", "", "", "", "0", "false", "", "", "", "false", "",
+	}, records[1])
+	assert.Equal(t, []string{
+		"Firefox", "abc123", "https://reddit.com", "bob", "hunter2", "2026-01-15T10:30:00Z", "", "", "", "", "0", "false", "", "", "", "false", "",
+	}, records[2])
 }
 
 func TestWrite_CSV_Cookie(t *testing.T) {
@@ -106,14 +113,14 @@ func TestWrite_CSV_Cookie(t *testing.T) {
 	assert.Equal(t,
 		[]string{
 			"browser", "profile", "host", "path", "name", "value",
-			"is_secure", "is_http_only", "has_expire", "is_persistent", "expire_at", "created_at", "same_site",
+			"is_secure", "is_http_only", "has_expire", "is_persistent", "expire_at", "created_at", "same_site", "recovered", "recovered_from",
 		},
 		records[0],
 	)
 	assert.Equal(t,
 		[]string{
 			"Chrome", "Default", ".example.com", "/", "session", "abc123",
-			"true", "true", "true", "true", "2026-01-15T10:30:00Z", "2026-01-15T10:30:00Z", "",
+			"true", "true", "true", "true", "2026-01-15T10:30:00Z", "2026-01-15T10:30:00Z", "", "false", "",
 		},
 		records[1],
 	)
@@ -129,8 +136,8 @@ func TestWrite_CSV_History(t *testing.T) {
 	records := readCSV(t, filepath.Join(dir, "history.csv"))
 	require.Len(t, records, 2)
 
-	assert.Equal(t, []string{"browser", "profile", "url", "title", "visit_count", "last_visit"}, records[0])
-	assert.Equal(t, []string{"Chrome", "Profile 1", "https://example.com", "Example", "5", "2026-01-15T10:30:00Z"}, records[1])
+	assert.Equal(t, []string{"browser", "profile", "url", "title", "visit_count", "last_visit", "recovered", "recovered_from"}, records[0])
+	assert.Equal(t, []string{"Chrome", "Profile 1", "https://example.com", "Example", "5", "2026-01-15T10:30:00Z", "false", ""}, records[1])
 }
 
 func TestWrite_CSV_UTF8BOM(t *testing.T) {
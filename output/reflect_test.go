@@ -26,6 +26,9 @@ var allEntryTypes = []any{
 	types.CreditCardEntry{},
 	types.StorageEntry{},
 	types.ExtensionEntry{},
+	types.CacheEntry{},
+	types.FaviconEntry{},
+	types.OmniboxEntry{},
 }
 
 // TestAllEntryFieldsHaveCSVTag verifies that every exported field
@@ -53,14 +56,17 @@ func TestStructCSVHeader(t *testing.T) {
 		entry  any
 		expect []string
 	}{
-		{"LoginEntry", types.LoginEntry{}, []string{"url", "username", "password", "created_at"}},
-		{"CookieEntry", types.CookieEntry{}, []string{"host", "path", "name", "value", "is_secure", "is_http_only", "has_expire", "is_persistent", "expire_at", "created_at", "same_site"}},
+		{"LoginEntry", types.LoginEntry{}, []string{"url", "username", "password", "created_at", "action_url", "signon_realm", "last_used_at", "password_changed_at", "times_used", "blocked_by_user", "note", "insecure", "store", "recovered", "recovered_from"}},
+		{"CookieEntry", types.CookieEntry{}, []string{"host", "path", "name", "value", "is_secure", "is_http_only", "has_expire", "is_persistent", "expire_at", "created_at", "same_site", "recovered", "recovered_from"}},
 		{"BookmarkEntry", types.BookmarkEntry{}, []string{"id", "name", "type", "url", "folder", "created_at"}},
-		{"HistoryEntry", types.HistoryEntry{}, []string{"url", "title", "visit_count", "last_visit"}},
+		{"HistoryEntry", types.HistoryEntry{}, []string{"url", "title", "visit_count", "last_visit", "recovered", "recovered_from"}},
 		{"DownloadEntry", types.DownloadEntry{}, []string{"url", "target_path", "mime_type", "total_bytes", "start_time", "end_time"}},
 		{"CreditCardEntry", types.CreditCardEntry{}, []string{"guid", "name", "number", "exp_month", "exp_year", "nick_name", "address", "cvc", "comment"}},
-		{"StorageEntry", types.StorageEntry{}, []string{"is_meta", "url", "key", "value"}},
+		{"StorageEntry", types.StorageEntry{}, []string{"is_meta", "url", "key", "value", "recovered", "recovered_from"}},
 		{"ExtensionEntry", types.ExtensionEntry{}, []string{"name", "id", "description", "version", "homepage_url", "enabled"}},
+		{"CacheEntry", types.CacheEntry{}, []string{"url", "status", "content_type", "size", "response_time", "body_file", "fetch_count"}},
+		{"FaviconEntry", types.FaviconEntry{}, []string{"page_url", "icon_url", "last_updated", "last_requested", "expires_at", "icon_file"}},
+		{"OmniboxEntry", types.OmniboxEntry{}, []string{"source", "text", "fill_into_edit", "url", "title", "hits", "misses", "rank", "last_access"}},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -78,7 +84,7 @@ func TestStructCSVRow(t *testing.T) {
 		{
 			"LoginEntry",
 			types.LoginEntry{URL: "https://example.com", Username: "alice", Password: "secret", CreatedAt: refTime},
-			[]string{"https://example.com", "alice", "secret", "2026-01-15T10:30:00Z"},
+			[]string{"https://example.com", "alice", "secret", "2026-01-15T10:30:00Z", "", "", "", "", "0", "false", "", "", "", "false", ""},
 		},
 		{
 			"CookieEntry",
@@ -87,12 +93,12 @@ func TestStructCSVRow(t *testing.T) {
 				IsSecure: true, IsHTTPOnly: true, HasExpire: true, IsPersistent: false,
 				ExpireAt: refTime, CreatedAt: refTime,
 			},
-			[]string{".example.com", "/", "session", "abc", "true", "true", "true", "false", "2026-01-15T10:30:00Z", "2026-01-15T10:30:00Z", ""},
+			[]string{".example.com", "/", "session", "abc", "true", "true", "true", "false", "2026-01-15T10:30:00Z", "2026-01-15T10:30:00Z", "", "false", ""},
 		},
 		{
 			"HistoryEntry_int",
-			types.HistoryEntry{URL: "https://a.com", Title: "A", VisitCount: 42, LastVisit: refTime},
-			[]string{"https://a.com", "A", "42", "2026-01-15T10:30:00Z"},
+			types.HistoryEntry{URL: "https://a.com", Title: "A", VisitCount: 42, LastVisit: refTime, Recovered: true, RecoveredFrom: "wal commit 3, frame 12"},
+			[]string{"https://a.com", "A", "42", "2026-01-15T10:30:00Z", "true", "wal commit 3, frame 12"},
 		},
 		{
 			"DownloadEntry_int64",
@@ -107,7 +113,7 @@ func TestStructCSVRow(t *testing.T) {
 		{
 			"zero_time",
 			types.LoginEntry{URL: "https://a.com"},
-			[]string{"https://a.com", "", "", ""},
+			[]string{"https://a.com", "", "", "", "", "", "", "", "0", "false", "", "", "", "false", ""},
 		},
 	}
 	for _, tt := range tests {
@@ -141,7 +147,7 @@ func TestRowMarshalJSON(t *testing.T) {
 		assert.Equal(t, "https://example.com", m["url"])
 		assert.Equal(t, "alice", m["username"])
 		assert.Equal(t, "secret", m["password"])
-		assert.Len(t, m, 6) // browser + profile + 4 entry fields
+		assert.Len(t, m, 17) // browser + profile + 15 entry fields
 
 		// Verify field order: browser, profile come before entry fields.
 		raw := string(data)
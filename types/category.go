@@ -14,12 +14,15 @@ const (
 	Extension
 	LocalStorage
 	SessionStorage
+	Cache
+	Favicon
+	Omnibox
 )
 
 // AllCategories returns all supported data categories.
 var AllCategories = []Category{
 	Password, Cookie, Bookmark, History, Download,
-	CreditCard, Extension, LocalStorage, SessionStorage,
+	CreditCard, Extension, LocalStorage, SessionStorage, Cache, Favicon, Omnibox,
 }
 
 // String returns the human-readable name of the category.
@@ -43,6 +46,12 @@ func (c Category) String() string {
 		return "localstorage"
 	case SessionStorage:
 		return "sessionstorage"
+	case Cache:
+		return "cache"
+	case Favicon:
+		return "favicon"
+	case Omnibox:
+		return "omnibox"
 	default:
 		return "unknown"
 	}
@@ -107,7 +116,19 @@ type BrowserConfig struct {
 	Kind          BrowserKind // engine type
 	KeychainLabel string      // macOS Keychain account / Linux D-Bus Secret Service label; "" = none
 	WindowsABE    bool        // enable Windows App-Bound Encryption v20 (reflective injection)
+	FlatpakAppID  string      // Linux Flatpak app id (e.g. "com.google.Chrome"); enables the v12 secret-portal tier
+	KWalletFolder string      // Linux KWallet folder holding the KeychainLabel entry (e.g. "Chrome Keys"); "" = none
 	UserDataDir   string      // base browser directory
+	CacheDir      string      // Firefox only — local dir mirroring UserDataDir's profiles, holding their cache2; "" = none
+}
+
+// ArchiveSource is one decryption-relevant file or directory plus its path inside the browser's
+// archive layout (forward-slash), so an archive can be re-expanded into a working profile tree. Each
+// engine picks its own layout root: Chromium's User Data, Firefox's Profiles dir, Safari's ~/Library.
+type ArchiveSource struct {
+	AbsPath   string
+	LayoutRel string
+	IsDir     bool
 }
 
 // BrowserData holds all extracted browser data with typed slices.
@@ -121,4 +142,7 @@ type BrowserData struct {
 	Extensions     []ExtensionEntry
 	LocalStorage   []StorageEntry
 	SessionStorage []StorageEntry
+	Caches         []CacheEntry
+	Favicons       []FaviconEntry
+	Omnibox        []OmniboxEntry
 }
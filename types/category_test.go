@@ -20,6 +20,9 @@ func TestCategory_String(t *testing.T) {
 		{Extension, "extension"},
 		{LocalStorage, "localstorage"},
 		{SessionStorage, "sessionstorage"},
+		{Cache, "cache"},
+		{Favicon, "favicon"},
+		{Omnibox, "omnibox"},
 		{Category(999), "unknown"},
 	}
 	for _, tt := range tests {
@@ -50,19 +53,19 @@ func TestCategory_IsSensitive(t *testing.T) {
 		assert.True(t, c.IsSensitive(), "%s should be sensitive", c)
 	}
 
-	notSensitive := []Category{Bookmark, History, Download, Extension, LocalStorage, SessionStorage}
+	notSensitive := []Category{Bookmark, History, Download, Extension, LocalStorage, SessionStorage, Cache}
 	for _, c := range notSensitive {
 		assert.False(t, c.IsSensitive(), "%s should not be sensitive", c)
 	}
 }
 
 func TestAllCategories(t *testing.T) {
-	assert.Len(t, AllCategories, 9)
+	assert.Len(t, AllCategories, 12)
 }
 
 func TestNonSensitiveCategories(t *testing.T) {
 	cats := NonSensitiveCategories()
-	assert.Len(t, cats, 6)
+	assert.Len(t, cats, 9)
 	for _, c := range cats {
 		assert.False(t, c.IsSensitive())
 	}
@@ -2,27 +2,48 @@ package types
 
 import "time"
 
-// LoginEntry represents a single saved login credential.
+// LoginEntry represents a single saved login credential. Recovered marks one carved from the
+// database's free space or read from a row version its write-ahead log superseded (see sqliteutil.Carve
+// and sqliteutil.WAL) rather than read from a live row, and RecoveredFrom says which; CookieEntry and
+// HistoryEntry carry the same fields.
+//
+// The fields from ActionURL to Store are Chromium's; other browsers leave them zero. BlockedByUser
+// marks a site the user chose never to save a password for, Insecure lists the problems the password
+// checkup found (leaked, phished, weak, reused), and Store is the database the login was saved in:
+// "profile" (Login Data) or "account" (Login Data For Account, synced to the Google account only).
 type LoginEntry struct {
-	URL       string    `json:"url" csv:"url"`
-	Username  string    `json:"username" csv:"username"`
-	Password  string    `json:"password" csv:"password"`
-	CreatedAt time.Time `json:"created_at" csv:"created_at"`
+	URL               string    `json:"url" csv:"url"`
+	Username          string    `json:"username" csv:"username"`
+	Password          string    `json:"password" csv:"password"`
+	CreatedAt         time.Time `json:"created_at" csv:"created_at"`
+	ActionURL         string    `json:"action_url" csv:"action_url"`
+	SignonRealm       string    `json:"signon_realm" csv:"signon_realm"`
+	LastUsedAt        time.Time `json:"last_used_at" csv:"last_used_at"`
+	PasswordChangedAt time.Time `json:"password_changed_at" csv:"password_changed_at"`
+	TimesUsed         int       `json:"times_used" csv:"times_used"`
+	BlockedByUser     bool      `json:"blocked_by_user" csv:"blocked_by_user"`
+	Note              string    `json:"note" csv:"note"`
+	Insecure          string    `json:"insecure" csv:"insecure"`
+	Store             string    `json:"store" csv:"store"`
+	Recovered         bool      `json:"recovered" csv:"recovered"`
+	RecoveredFrom     string    `json:"recovered_from" csv:"recovered_from"`
 }
 
 // CookieEntry represents a single browser cookie.
 type CookieEntry struct {
-	Host         string    `json:"host" csv:"host"`
-	Path         string    `json:"path" csv:"path"`
-	Name         string    `json:"name" csv:"name"`
-	Value        string    `json:"value" csv:"value"`
-	IsSecure     bool      `json:"is_secure" csv:"is_secure"`
-	IsHTTPOnly   bool      `json:"is_http_only" csv:"is_http_only"`
-	HasExpire    bool      `json:"has_expire" csv:"has_expire"`
-	IsPersistent bool      `json:"is_persistent" csv:"is_persistent"`
-	ExpireAt     time.Time `json:"expire_at" csv:"expire_at"`
-	CreatedAt    time.Time `json:"created_at" csv:"created_at"`
-	SameSite     string    `json:"same_site" csv:"same_site"`
+	Host          string    `json:"host" csv:"host"`
+	Path          string    `json:"path" csv:"path"`
+	Name          string    `json:"name" csv:"name"`
+	Value         string    `json:"value" csv:"value"`
+	IsSecure      bool      `json:"is_secure" csv:"is_secure"`
+	IsHTTPOnly    bool      `json:"is_http_only" csv:"is_http_only"`
+	HasExpire     bool      `json:"has_expire" csv:"has_expire"`
+	IsPersistent  bool      `json:"is_persistent" csv:"is_persistent"`
+	ExpireAt      time.Time `json:"expire_at" csv:"expire_at"`
+	CreatedAt     time.Time `json:"created_at" csv:"created_at"`
+	SameSite      string    `json:"same_site" csv:"same_site"`
+	Recovered     bool      `json:"recovered" csv:"recovered"`
+	RecoveredFrom string    `json:"recovered_from" csv:"recovered_from"`
 }
 
 // BookmarkEntry represents a single browser bookmark.
@@ -37,10 +58,12 @@ type BookmarkEntry struct {
 
 // HistoryEntry represents a single browser history record.
 type HistoryEntry struct {
-	URL        string    `json:"url" csv:"url"`
-	Title      string    `json:"title" csv:"title"`
-	VisitCount int       `json:"visit_count" csv:"visit_count"`
-	LastVisit  time.Time `json:"last_visit" csv:"last_visit"`
+	URL           string    `json:"url" csv:"url"`
+	Title         string    `json:"title" csv:"title"`
+	VisitCount    int       `json:"visit_count" csv:"visit_count"`
+	LastVisit     time.Time `json:"last_visit" csv:"last_visit"`
+	Recovered     bool      `json:"recovered" csv:"recovered"`
+	RecoveredFrom string    `json:"recovered_from" csv:"recovered_from"`
 }
 
 // DownloadEntry represents a single browser download record.
@@ -69,10 +92,54 @@ type CreditCardEntry struct {
 
 // StorageEntry represents a single key-value pair from local or session storage.
 type StorageEntry struct {
-	IsMeta bool   `json:"is_meta" csv:"is_meta"`
-	URL    string `json:"url" csv:"url"`
-	Key    string `json:"key" csv:"key"`
-	Value  string `json:"value" csv:"value"`
+	IsMeta        bool   `json:"is_meta" csv:"is_meta"`
+	URL           string `json:"url" csv:"url"`
+	Key           string `json:"key" csv:"key"`
+	Value         string `json:"value" csv:"value"`
+	Recovered     bool   `json:"recovered" csv:"recovered"`
+	RecoveredFrom string `json:"recovered_from" csv:"recovered_from"`
+}
+
+// CacheEntry represents a single response from the browser's HTTP disk cache. Size is the body as
+// stored, before any Content-Encoding is undone; BodyFile is where the decoded body was saved, when
+// bodies are carved. FetchCount is Firefox-specific; Chromium leaves it zero.
+type CacheEntry struct {
+	URL          string    `json:"url" csv:"url"`
+	Status       int       `json:"status" csv:"status"`
+	ContentType  string    `json:"content_type" csv:"content_type"`
+	Size         int64     `json:"size" csv:"size"`
+	ResponseTime time.Time `json:"response_time" csv:"response_time"`
+	BodyFile     string    `json:"body_file" csv:"body_file"`
+	FetchCount   int       `json:"fetch_count" csv:"fetch_count"`
+}
+
+// FaviconEntry represents a page and the icon the browser shows for it. The favicon database keeps
+// page URLs after history is cleared. LastUpdated and LastRequested are Chromium-specific; Firefox
+// records only when the icon expires (ExpiresAt). IconFile is where the icon image was saved, when
+// images are saved.
+type FaviconEntry struct {
+	PageURL       string    `json:"page_url" csv:"page_url"`
+	IconURL       string    `json:"icon_url" csv:"icon_url"`
+	LastUpdated   time.Time `json:"last_updated" csv:"last_updated"`
+	LastRequested time.Time `json:"last_requested" csv:"last_requested"`
+	ExpiresAt     time.Time `json:"expires_at" csv:"expires_at"`
+	IconFile      string    `json:"icon_file" csv:"icon_file"`
+}
+
+// OmniboxEntry represents something Chromium's address bar learned from what was typed into it, kept
+// after history is deleted. Source names where it was found: "shortcuts" (Text typed, then the
+// suggestion FillIntoEdit picked, Hits times), "top_sites" (a new-tab tile at Rank) or
+// "network_action_predictor" (Text typed, then URL navigated to Hits times and not Misses times).
+type OmniboxEntry struct {
+	Source       string    `json:"source" csv:"source"`
+	Text         string    `json:"text" csv:"text"`
+	FillIntoEdit string    `json:"fill_into_edit" csv:"fill_into_edit"`
+	URL          string    `json:"url" csv:"url"`
+	Title        string    `json:"title" csv:"title"`
+	Hits         int       `json:"hits" csv:"hits"`
+	Misses       int       `json:"misses" csv:"misses"`
+	Rank         int       `json:"rank" csv:"rank"`
+	LastAccess   time.Time `json:"last_access" csv:"last_access"`
 }
 
 // ExtensionEntry represents a single browser extension.
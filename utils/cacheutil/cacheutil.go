@@ -0,0 +1,163 @@
+// Package cacheutil holds what the browsers' HTTP disk caches have in common once their own formats are
+// decoded: the raw response headers stored with each entry, and the body as the server sent it, still
+// content-encoded, which BodyWriter decodes and saves.
+package cacheutil
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/textproto"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/andybalholm/brotli"
+)
+
+// ParseHeaders parses a raw response header block — the status line, then one header per line — as
+// caches store it: lines end in NUL (Chromium) or CRLF (Firefox). It returns the status code, or 0
+// when the block does not start with an HTTP status line, and the headers.
+func ParseHeaders(raw string) (int, http.Header) {
+	lines := strings.FieldsFunc(raw, func(r rune) bool { return r == 0 || r == '\r' || r == '\n' })
+	header := make(http.Header)
+	if len(lines) == 0 {
+		return 0, header
+	}
+	status := 0
+	if proto, rest, ok := strings.Cut(lines[0], " "); ok && strings.HasPrefix(proto, "HTTP/") {
+		code, _, _ := strings.Cut(rest, " ")
+		status, _ = strconv.Atoi(code)
+		lines = lines[1:]
+	}
+	for _, line := range lines {
+		name, value, ok := strings.Cut(line, ":")
+		if !ok || name == "" {
+			continue
+		}
+		header.Add(textproto.CanonicalMIMEHeaderKey(strings.TrimSpace(name)), strings.TrimSpace(value))
+	}
+	return status, header
+}
+
+// maxDecodedSize caps what Decode inflates a body to: a small entry can decompress to any size.
+var maxDecodedSize int64 = 256 << 20
+
+// ErrTruncated is returned by Decode for a body that decodes past maxDecodedSize.
+var ErrTruncated = errors.New("decoded body truncated")
+
+// Decode undoes a Content-Encoding: the codings are listed in the order they were applied, so they
+// are removed last to first. gzip, deflate and br are supported, identity is a no-op. A body cut
+// short, as a cache often keeps one, decodes as far as it goes, and one decoding past maxDecodedSize
+// is cut there with ErrTruncated: the error is returned together with what was decoded.
+func Decode(body []byte, encoding string) ([]byte, error) {
+	codings := strings.Split(encoding, ",")
+	for i := len(codings) - 1; i >= 0; i-- {
+		var r io.Reader
+		switch c := strings.ToLower(strings.TrimSpace(codings[i])); c {
+		case "", "identity":
+			continue
+		case "gzip", "x-gzip":
+			zr, err := gzip.NewReader(bytes.NewReader(body))
+			if err != nil {
+				return nil, fmt.Errorf("gzip: %w", err)
+			}
+			r = zr
+		case "deflate":
+			// RFC 9110 deflate is zlib-wrapped, but some servers send the raw stream.
+			zr, err := zlib.NewReader(bytes.NewReader(body))
+			if err != nil {
+				r = flate.NewReader(bytes.NewReader(body))
+			} else {
+				r = zr
+			}
+		case "br":
+			r = brotli.NewReader(bytes.NewReader(body))
+		default:
+			return nil, fmt.Errorf("unsupported content encoding %q", c)
+		}
+		decoded, err := io.ReadAll(io.LimitReader(r, maxDecodedSize+1))
+		if err != nil {
+			return decoded, fmt.Errorf("%s: %w", codings[i], err)
+		}
+		if int64(len(decoded)) > maxDecodedSize {
+			return decoded[:maxDecodedSize], fmt.Errorf("%s: %w", codings[i], ErrTruncated)
+		}
+		body = decoded
+	}
+	return body, nil
+}
+
+// BodyWriter saves cached response bodies, decoded, as files in a directory that is created on the
+// first write.
+type BodyWriter struct {
+	dir string
+}
+
+// NewBodyWriter returns a BodyWriter saving into dir.
+func NewBodyWriter(dir string) *BodyWriter {
+	return &BodyWriter{dir: dir}
+}
+
+// Write decodes body by its Content-Encoding and saves it as name plus the extension of its content
+// type, returning the path written. A body that does not decode is saved as far as it decoded, or as
+// stored when nothing did; the path is returned together with the error.
+func (w *BodyWriter) Write(name, contentType, encoding string, body []byte) (string, error) {
+	if err := os.MkdirAll(w.dir, 0o750); err != nil {
+		return "", err
+	}
+	decoded, decodeErr := Decode(body, encoding)
+	if len(decoded) == 0 && decodeErr != nil {
+		decoded = body
+	}
+	path := filepath.Join(w.dir, name+extension(contentType))
+	if err := os.WriteFile(path, decoded, 0o600); err != nil {
+		return "", err
+	}
+	return path, decodeErr
+}
+
+// extensions maps the content types a page is mostly made of to a file extension.
+var extensions = map[string]string{
+	"text/html":                ".html",
+	"text/css":                 ".css",
+	"text/plain":               ".txt",
+	"text/xml":                 ".xml",
+	"text/javascript":          ".js",
+	"application/javascript":   ".js",
+	"application/x-javascript": ".js",
+	"application/json":         ".json",
+	"application/xml":          ".xml",
+	"application/pdf":          ".pdf",
+	"application/wasm":         ".wasm",
+	"image/png":                ".png",
+	"image/jpeg":               ".jpg",
+	"image/gif":                ".gif",
+	"image/webp":               ".webp",
+	"image/avif":               ".avif",
+	"image/svg+xml":            ".svg",
+	"image/bmp":                ".bmp",
+	"image/x-icon":             ".ico",
+	"image/vnd.microsoft.icon": ".ico",
+	"font/woff":                ".woff",
+	"font/woff2":               ".woff2",
+	"font/ttf":                 ".ttf",
+	"audio/mpeg":               ".mp3",
+	"video/mp4":                ".mp4",
+	"video/webm":               ".webm",
+}
+
+// extension returns the file extension for a Content-Type value, ".bin" when it is not a known one.
+func extension(contentType string) string {
+	mediaType, _, _ := strings.Cut(contentType, ";")
+	if ext, ok := extensions[strings.ToLower(strings.TrimSpace(mediaType))]; ok {
+		return ext
+	}
+	return ".bin"
+}
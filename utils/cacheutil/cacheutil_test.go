@@ -0,0 +1,119 @@
+package cacheutil
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/andybalholm/brotli"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseHeaders(t *testing.T) {
+	tests := []struct {
+		name       string
+		raw        string
+		wantStatus int
+		wantType   string
+	}{
+		{"chromium nul lines", "HTTP/1.1 200 OK\x00content-type: text/html\x00\x00", 200, "text/html"},
+		{"firefox crlf lines", "HTTP/2 404 \r\nContent-Type: text/plain; charset=utf-8\r\n", 404, "text/plain; charset=utf-8"},
+		{"no status line", "content-type: image/png\x00", 0, "image/png"},
+		{"empty", "", 0, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, header := ParseHeaders(tt.raw)
+			assert.Equal(t, tt.wantStatus, status)
+			assert.Equal(t, tt.wantType, header.Get("Content-Type"))
+		})
+	}
+}
+
+func encode(t *testing.T, w func(io.Writer) io.WriteCloser, b []byte) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := w(&buf)
+	_, err := zw.Write(b)
+	require.NoError(t, err)
+	require.NoError(t, zw.Close())
+	return buf.Bytes()
+}
+
+func gzipWriter(w io.Writer) io.WriteCloser   { return gzip.NewWriter(w) }
+func zlibWriter(w io.Writer) io.WriteCloser   { return zlib.NewWriter(w) }
+func brotliWriter(w io.Writer) io.WriteCloser { return brotli.NewWriter(w) }
+func flateWriter(w io.Writer) io.WriteCloser {
+	fw, _ := flate.NewWriter(w, flate.DefaultCompression)
+	return fw
+}
+
+func TestDecode(t *testing.T) {
+	body := bytes.Repeat([]byte("<html>cached page</html>"), 100)
+	tests := []struct {
+		name     string
+		encoded  []byte
+		encoding string
+	}{
+		{"identity", body, ""},
+		{"gzip", encode(t, gzipWriter, body), "gzip"},
+		{"zlib deflate", encode(t, zlibWriter, body), "deflate"},
+		{"raw deflate", encode(t, flateWriter, body), "deflate"},
+		{"br", encode(t, brotliWriter, body), "br"},
+		{"stacked", encode(t, brotliWriter, encode(t, gzipWriter, body)), "gzip, br"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Decode(tt.encoded, tt.encoding)
+			require.NoError(t, err)
+			assert.Equal(t, body, got)
+		})
+	}
+
+	t.Run("truncated", func(t *testing.T) {
+		gz := encode(t, gzipWriter, body)
+		got, err := Decode(gz[:len(gz)-10], "gzip")
+		require.Error(t, err)
+		assert.NotEmpty(t, got, "what decoded before the cut")
+		assert.True(t, bytes.HasPrefix(body, got))
+	})
+
+	t.Run("too large", func(t *testing.T) {
+		old := maxDecodedSize
+		maxDecodedSize = 100
+		defer func() { maxDecodedSize = old }()
+		got, err := Decode(encode(t, gzipWriter, body), "gzip")
+		assert.ErrorIs(t, err, ErrTruncated)
+		assert.Equal(t, body[:100], got)
+	})
+
+	t.Run("unsupported", func(t *testing.T) {
+		_, err := Decode(body, "zstd")
+		assert.ErrorContains(t, err, "unsupported content encoding")
+	})
+}
+
+func TestBodyWriter(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "bodies")
+	w := NewBodyWriter(dir)
+
+	path, err := w.Write("abc", "text/html; charset=utf-8", "gzip", encode(t, gzipWriter, []byte("<p>hi</p>")))
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(dir, "abc.html"), path)
+	got, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "<p>hi</p>", string(got))
+
+	path, err = w.Write("def", "", "br", []byte("not brotli"))
+	require.Error(t, err)
+	assert.Equal(t, filepath.Join(dir, "def.bin"), path)
+	got, err = os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "not brotli", string(got), "kept as stored when nothing decodes")
+}
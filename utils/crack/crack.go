@@ -0,0 +1,168 @@
+// Package crack runs password candidates from a wordlist, optionally expanded by mangling rules,
+// against a caller-supplied check in parallel, stopping at the first hit.
+package crack
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"runtime"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ErrNotFound is returned by Run when every candidate was tried without a hit.
+var ErrNotFound = errors.New("password not found in wordlist")
+
+// maxLineSize caps a single wordlist line; real wordlists stay far below it.
+const maxLineSize = 1 << 20
+
+// Options tunes Run. The zero value uses every core, no mangling and no progress reporting.
+type Options struct {
+	Workers  int           // parallel checkers; <= 0 means runtime.NumCPU()
+	Rules    RuleSet       // mangling applied to each wordlist entry
+	Interval time.Duration // how often Progress is called; <= 0 disables progress
+	Progress func(Stats)
+}
+
+// Stats is a progress snapshot.
+type Stats struct {
+	Tried   int64
+	Elapsed time.Duration
+}
+
+// Rate is the number of candidates checked per second so far.
+func (s Stats) Rate() float64 {
+	if s.Elapsed <= 0 {
+		return 0
+	}
+	return float64(s.Tried) / s.Elapsed.Seconds()
+}
+
+// Run feeds each non-empty line of words, expanded by opts.Rules, to check across opts.Workers
+// goroutines and returns the first candidate check accepts. check must be safe for concurrent use.
+// It returns ErrNotFound when the list is exhausted, or ctx's error if ctx ends first.
+func Run(ctx context.Context, words io.Reader, check func(string) bool, opts Options) (string, Stats, error) {
+	workers := opts.Workers
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+	parent := ctx
+	ctx, cancel := context.WithCancel(parent)
+	defer cancel()
+
+	var (
+		tried   int64
+		start   = time.Now()
+		found   string
+		hitOnce sync.Once
+		readErr error
+	)
+	snapshot := func() Stats {
+		return Stats{Tried: atomic.LoadInt64(&tried), Elapsed: time.Since(start)}
+	}
+
+	candidates := make(chan string, workers*64)
+	produced := make(chan struct{})
+	go func() {
+		defer close(produced)
+		defer close(candidates)
+		readErr = produce(ctx, words, opts.Rules, candidates)
+	}()
+
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for candidate := range candidates {
+				if ctx.Err() != nil {
+					return
+				}
+				atomic.AddInt64(&tried, 1)
+				if check(candidate) {
+					hitOnce.Do(func() {
+						found = candidate
+						cancel()
+					})
+					return
+				}
+			}
+		}()
+	}
+
+	stopProgress := reportProgress(opts, snapshot)
+	wg.Wait()
+	cancel() // unblock the producer if every worker returned early
+	<-produced
+	stopProgress()
+
+	stats := snapshot()
+	switch {
+	case found != "":
+		return found, stats, nil
+	case readErr != nil:
+		return "", stats, readErr
+	case parent.Err() != nil:
+		return "", stats, parent.Err()
+	}
+	return "", stats, ErrNotFound
+}
+
+// produce streams the mangled wordlist into out until the list ends or ctx is done.
+func produce(ctx context.Context, words io.Reader, rules RuleSet, out chan<- string) error {
+	send := func(candidate string) bool {
+		select {
+		case out <- candidate:
+			return true
+		case <-ctx.Done():
+			return false
+		}
+	}
+
+	scanner := bufio.NewScanner(words)
+	scanner.Buffer(make([]byte, 64*1024), maxLineSize)
+	for scanner.Scan() {
+		word := strings.TrimSuffix(scanner.Text(), "\r")
+		if word == "" {
+			continue
+		}
+		if !rules.Mangle(word, send) {
+			return nil
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("read wordlist: %w", err)
+	}
+	return nil
+}
+
+// reportProgress calls opts.Progress every opts.Interval until the returned stop func is called.
+func reportProgress(opts Options, snapshot func() Stats) (stop func()) {
+	if opts.Progress == nil || opts.Interval <= 0 {
+		return func() {}
+	}
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		ticker := time.NewTicker(opts.Interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				opts.Progress(snapshot())
+			case <-done:
+				return
+			}
+		}
+	}()
+	return func() {
+		close(done)
+		<-stopped
+	}
+}
@@ -0,0 +1,92 @@
+package crack
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRun_FindsPassword(t *testing.T) {
+	words := strings.NewReader("alpha\r\n\nbeta\ngamma\ndelta\n")
+	got, stats, err := Run(context.Background(), words, func(c string) bool { return c == "gamma" }, Options{Workers: 4})
+	require.NoError(t, err)
+	assert.Equal(t, "gamma", got)
+	assert.GreaterOrEqual(t, stats.Tried, int64(1))
+}
+
+func TestRun_WithRules(t *testing.T) {
+	words := strings.NewReader("letmein\nhunter\n")
+	got, _, err := Run(context.Background(), words, func(c string) bool { return c == "Hunter2019" },
+		Options{Workers: 2, Rules: RulesFull})
+	require.NoError(t, err)
+	assert.Equal(t, "Hunter2019", got)
+}
+
+func TestRun_NotFound(t *testing.T) {
+	var calls int64
+	check := func(string) bool {
+		atomic.AddInt64(&calls, 1)
+		return false
+	}
+	_, stats, err := Run(context.Background(), strings.NewReader("a\nb\nc\n"), check, Options{Workers: 3})
+	require.ErrorIs(t, err, ErrNotFound)
+	assert.Equal(t, int64(3), stats.Tried)
+	assert.Equal(t, int64(3), atomic.LoadInt64(&calls))
+}
+
+// TestRun_StopsAtFirstHit: once a worker hits, the producer must stop expanding the wordlist.
+func TestRun_StopsAtFirstHit(t *testing.T) {
+	var sb strings.Builder
+	for i := 0; i < 10000; i++ {
+		sb.WriteString("filler\n")
+	}
+	words := strings.NewReader("target\n" + sb.String())
+	got, stats, err := Run(context.Background(), words, func(c string) bool { return c == "target" },
+		Options{Workers: 1, Rules: RulesFull})
+	require.NoError(t, err)
+	assert.Equal(t, "target", got)
+	assert.Less(t, stats.Tried, int64(1000))
+}
+
+func TestRun_ContextCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, _, err := Run(ctx, strings.NewReader("a\nb\n"), func(string) bool { return false }, Options{})
+	require.ErrorIs(t, err, context.Canceled)
+}
+
+type errReader struct{}
+
+func (errReader) Read([]byte) (int, error) { return 0, errors.New("disk gone") }
+
+func TestRun_ReadError(t *testing.T) {
+	_, _, err := Run(context.Background(), errReader{}, func(string) bool { return false }, Options{})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "disk gone")
+}
+
+func TestRun_Progress(t *testing.T) {
+	var reports int64
+	slow := func(string) bool {
+		time.Sleep(5 * time.Millisecond)
+		return false
+	}
+	_, _, err := Run(context.Background(), strings.NewReader(strings.Repeat("w\n", 20)), slow, Options{
+		Workers:  1,
+		Interval: 10 * time.Millisecond,
+		Progress: func(Stats) { atomic.AddInt64(&reports, 1) },
+	})
+	require.ErrorIs(t, err, ErrNotFound)
+	assert.Positive(t, atomic.LoadInt64(&reports))
+}
+
+func TestStats_Rate(t *testing.T) {
+	assert.InDelta(t, 50.0, Stats{Tried: 100, Elapsed: 2 * time.Second}.Rate(), 0.001)
+	assert.Zero(t, Stats{Tried: 5}.Rate())
+}
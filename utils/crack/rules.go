@@ -0,0 +1,111 @@
+package crack
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// RuleSet names a built-in mangling rule set applied to every wordlist entry.
+type RuleSet string
+
+const (
+	RulesNone  RuleSet = "none"  // the word as-is
+	RulesBasic RuleSet = "basic" // case variants plus common single-digit / "!" / "123" suffixes
+	RulesFull  RuleSet = "full"  // basic plus two-digit and year suffixes, leetspeak and reversal
+)
+
+// RuleSetNames lists the accepted --rules values.
+const RuleSetNames = "none|basic|full"
+
+// ParseRuleSet maps a flag value to a RuleSet; "" means RulesNone.
+func ParseRuleSet(s string) (RuleSet, error) {
+	switch r := RuleSet(strings.ToLower(strings.TrimSpace(s))); r {
+	case "", RulesNone:
+		return RulesNone, nil
+	case RulesBasic, RulesFull:
+		return r, nil
+	default:
+		return "", fmt.Errorf("unknown rule set %q, available: %s", s, RuleSetNames)
+	}
+}
+
+var (
+	basicSuffixes = []string{"0", "1", "2", "3", "4", "5", "6", "7", "8", "9", "!", "123", "1!", "1234"}
+	leetReplacer  = strings.NewReplacer("a", "4", "A", "4", "e", "3", "E", "3", "i", "1", "I", "1", "o", "0", "O", "0", "s", "5", "S", "5")
+)
+
+// Year suffixes cover birth years and password-rotation years an investigator is likely to meet.
+const (
+	firstYear = 1950
+	lastYear  = 2030
+)
+
+// Mangle emits word and every variant r derives from it, each once. It stops early and returns false
+// as soon as emit does, so a consumer that has found its hit can cut a large expansion short.
+func (r RuleSet) Mangle(word string, emit func(string) bool) bool {
+	if r == RulesNone || r == "" {
+		return emit(word)
+	}
+
+	seen := make(map[string]struct{})
+	try := func(candidate string) bool {
+		if _, ok := seen[candidate]; ok {
+			return true
+		}
+		seen[candidate] = struct{}{}
+		return emit(candidate)
+	}
+
+	bases := caseVariants(word)
+	if r == RulesFull {
+		bases = append(bases, reverse(word), leetReplacer.Replace(word), leetReplacer.Replace(capitalize(word)))
+	}
+	for _, base := range bases {
+		if !try(base) {
+			return false
+		}
+	}
+	for _, base := range bases {
+		for _, suffix := range basicSuffixes {
+			if !try(base + suffix) {
+				return false
+			}
+		}
+		if r != RulesFull {
+			continue
+		}
+		for n := 0; n < 100; n++ {
+			if !try(fmt.Sprintf("%s%02d", base, n)) {
+				return false
+			}
+		}
+		for year := firstYear; year <= lastYear; year++ {
+			if !try(base + strconv.Itoa(year)) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func caseVariants(word string) []string {
+	return []string{word, strings.ToLower(word), strings.ToUpper(word), capitalize(word)}
+}
+
+func capitalize(word string) string {
+	runes := []rune(strings.ToLower(word))
+	if len(runes) > 0 {
+		runes[0] = unicode.ToUpper(runes[0])
+	}
+	return string(runes)
+}
+
+func reverse(word string) string {
+	runes := []rune(word)
+	for i, j := 0, len(runes)-1; i < j; i, j = i+1, j-1 {
+		runes[i], runes[j] = runes[j], runes[i]
+	}
+	return string(runes)
+}
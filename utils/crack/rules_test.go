@@ -0,0 +1,66 @@
+package crack
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func collect(r RuleSet, word string) []string {
+	var out []string
+	r.Mangle(word, func(c string) bool {
+		out = append(out, c)
+		return true
+	})
+	return out
+}
+
+func TestParseRuleSet(t *testing.T) {
+	for in, want := range map[string]RuleSet{"": RulesNone, "none": RulesNone, "Basic": RulesBasic, " full ": RulesFull} {
+		got, err := ParseRuleSet(in)
+		require.NoError(t, err, in)
+		assert.Equal(t, want, got, in)
+	}
+	_, err := ParseRuleSet("best64")
+	require.Error(t, err)
+}
+
+func TestMangle_None(t *testing.T) {
+	assert.Equal(t, []string{"Secret"}, collect(RulesNone, "Secret"))
+}
+
+func TestMangle_Basic(t *testing.T) {
+	got := collect(RulesBasic, "secret")
+	assert.Equal(t, []string{"secret", "SECRET", "Secret"}, got[:3], "case variants come first, deduplicated")
+	assert.Contains(t, got, "Secret1")
+	assert.Contains(t, got, "secret123")
+	assert.Contains(t, got, "SECRET!")
+	assert.NotContains(t, got, "secret2024", "year suffixes are full-only")
+
+	seen := map[string]bool{}
+	for _, c := range got {
+		assert.False(t, seen[c], "duplicate candidate %q", c)
+		seen[c] = true
+	}
+}
+
+func TestMangle_Full(t *testing.T) {
+	got := collect(RulesFull, "password")
+	assert.Contains(t, got, "drowssap")
+	assert.Contains(t, got, "p455w0rd")
+	assert.Contains(t, got, "P455w0rd")
+	assert.Contains(t, got, "Password07")
+	assert.Contains(t, got, "password1987")
+	assert.Contains(t, got, "p455w0rd2030")
+}
+
+func TestMangle_StopsEarly(t *testing.T) {
+	calls := 0
+	ok := RulesFull.Mangle("word", func(string) bool {
+		calls++
+		return calls < 3
+	})
+	assert.False(t, ok)
+	assert.Equal(t, 3, calls)
+}
@@ -1,11 +1,15 @@
 package fileutil
 
 import (
+	"archive/tar"
 	"archive/zip"
+	"bufio"
 	"bytes"
+	"compress/gzip"
 	"errors"
 	"fmt"
 	"io"
+	"io/fs"
 	"os"
 	"path/filepath"
 	"strings"
@@ -23,7 +27,8 @@ func FileExists(filename string) bool {
 	return !info.IsDir()
 }
 
-// CompressDir compresses the directory into a zip file
+// CompressDir compresses the directory into a zip file, removing what it packed. Subdirectories keep
+// their layout in the zip.
 func CompressDir(dir string) error {
 	files, err := os.ReadDir(dir)
 	if err != nil {
@@ -40,8 +45,28 @@ func CompressDir(dir string) error {
 	}()
 
 	for _, file := range files {
-		if err := addFileToZip(zipWriter, filepath.Join(dir, file.Name())); err != nil {
-			return fmt.Errorf("failed to add file to zip: %w", err)
+		path := filepath.Join(dir, file.Name())
+		if !file.IsDir() {
+			if err := addFileToZip(zipWriter, path, file.Name()); err != nil {
+				return fmt.Errorf("failed to add file to zip: %w", err)
+			}
+			continue
+		}
+		err := filepath.WalkDir(path, func(p string, d fs.DirEntry, err error) error {
+			if err != nil || d.IsDir() {
+				return err
+			}
+			rel, err := filepath.Rel(dir, p)
+			if err != nil {
+				return err
+			}
+			return addFileToZip(zipWriter, p, filepath.ToSlash(rel))
+		})
+		if err != nil {
+			return fmt.Errorf("failed to add directory to zip: %w", err)
+		}
+		if err := os.RemoveAll(path); err != nil {
+			return fmt.Errorf("error removing original directory %s: %w", path, err)
 		}
 	}
 
@@ -53,13 +78,13 @@ func CompressDir(dir string) error {
 	return writeFile(buffer, zipFilename)
 }
 
-func addFileToZip(zw *zip.Writer, filename string) error {
+func addFileToZip(zw *zip.Writer, filename, name string) error {
 	content, err := os.ReadFile(filename)
 	if err != nil {
 		return fmt.Errorf("error reading file %s: %w", filename, err)
 	}
 
-	fw, err := zw.Create(filepath.Base(filename))
+	fw, err := zw.Create(name)
 	if err != nil {
 		return fmt.Errorf("error creating zip entry for %s: %w", filename, err)
 	}
@@ -146,9 +171,9 @@ func Unzip(zipPath, destDir string) error {
 
 	root := filepath.Clean(destDir)
 	for _, f := range r.File {
-		target := filepath.Join(root, filepath.FromSlash(f.Name))
-		if target != root && !strings.HasPrefix(target, root+string(os.PathSeparator)) {
-			return fmt.Errorf("zip entry %q escapes destination", f.Name)
+		target, err := entryTarget(root, f.Name)
+		if err != nil {
+			return fmt.Errorf("zip entry %w", err)
 		}
 		if f.FileInfo().IsDir() {
 			if err := os.MkdirAll(target, 0o755); err != nil {
@@ -166,13 +191,67 @@ func Unzip(zipPath, destDir string) error {
 	return nil
 }
 
-func writeZipEntry(f *zip.File, target string) error {
-	rc, err := f.Open()
+// entryTarget resolves an archive entry name under root, rejecting one that would escape it.
+func entryTarget(root, name string) (string, error) {
+	target := filepath.Join(root, filepath.FromSlash(name))
+	if target != root && !strings.HasPrefix(target, root+string(os.PathSeparator)) {
+		return "", fmt.Errorf("%q escapes destination", name)
+	}
+	return target, nil
+}
+
+// Untar extracts a tar or gzip-compressed tar (detected by content, not extension) into destDir with
+// Unzip's Zip-Slip check. Absolute entry names ("/home/alice/...") land under destDir as if relative;
+// only directories and regular files are written — links and devices in a collected tarball are skipped.
+func Untar(tarPath, destDir string) error {
+	f, err := os.Open(tarPath)
 	if err != nil {
-		return err
+		return fmt.Errorf("open tar %s: %w", tarPath, err)
+	}
+	defer func() { _ = f.Close() }()
+
+	br := bufio.NewReader(f)
+	var r io.Reader = br
+	if magic, err := br.Peek(2); err == nil && magic[0] == 0x1f && magic[1] == 0x8b {
+		gz, err := gzip.NewReader(br)
+		if err != nil {
+			return fmt.Errorf("gunzip %s: %w", tarPath, err)
+		}
+		defer func() { _ = gz.Close() }()
+		r = gz
 	}
-	defer func() { _ = rc.Close() }()
 
+	root := filepath.Clean(destDir)
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("read tar %s: %w", tarPath, err)
+		}
+		target, err := entryTarget(root, strings.TrimLeft(hdr.Name, "/"))
+		if err != nil {
+			return fmt.Errorf("tar entry %w", err)
+		}
+		switch hdr.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(target, 0o755); err != nil {
+				return err
+			}
+		case tar.TypeReg:
+			if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+				return err
+			}
+			if err := writeEntry(tr, target); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func writeEntry(r io.Reader, target string) error {
 	out, err := os.OpenFile(target, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0o600)
 	if err != nil {
 		return err
@@ -180,7 +259,7 @@ func writeZipEntry(f *zip.File, target string) error {
 	defer func() { _ = out.Close() }()
 
 	for {
-		_, err := io.CopyN(out, rc, 1<<20)
+		_, err := io.CopyN(out, r, 1<<20)
 		if errors.Is(err, io.EOF) {
 			return nil
 		}
@@ -189,3 +268,12 @@ func writeZipEntry(f *zip.File, target string) error {
 		}
 	}
 }
+
+func writeZipEntry(f *zip.File, target string) error {
+	rc, err := f.Open()
+	if err != nil {
+		return err
+	}
+	defer func() { _ = rc.Close() }()
+	return writeEntry(rc, target)
+}
@@ -0,0 +1,86 @@
+package fileutil
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeTar(t *testing.T, w io.Writer, entries []*tar.Header, data map[string]string) {
+	t.Helper()
+	tw := tar.NewWriter(w)
+	for _, hdr := range entries {
+		hdr.Size = int64(len(data[hdr.Name]))
+		if err := tw.WriteHeader(hdr); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tw.Write([]byte(data[hdr.Name])); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestUntar(t *testing.T) {
+	data := map[string]string{
+		"/home/alice/.config/google-chrome/Local State": "{}",
+		"home/alice/.mozilla/firefox/p.default/key4.db": "nss",
+	}
+	entries := []*tar.Header{
+		{Name: "home/", Typeflag: tar.TypeDir, Mode: 0o755},
+		{Name: "/home/alice/.config/google-chrome/Local State", Typeflag: tar.TypeReg, Mode: 0o600},
+		{Name: "home/alice/.mozilla/firefox/p.default/key4.db", Typeflag: tar.TypeReg, Mode: 0o600},
+		{Name: "home/alice/link", Typeflag: tar.TypeSymlink, Linkname: "/etc/passwd"},
+	}
+
+	for _, compressed := range []bool{false, true} {
+		var buf bytes.Buffer
+		if compressed {
+			gz := gzip.NewWriter(&buf)
+			writeTar(t, gz, entries, data)
+			if err := gz.Close(); err != nil {
+				t.Fatal(err)
+			}
+		} else {
+			writeTar(t, &buf, entries, data)
+		}
+		tarPath := filepath.Join(t.TempDir(), "home.tar.gz")
+		if err := os.WriteFile(tarPath, buf.Bytes(), 0o600); err != nil {
+			t.Fatal(err)
+		}
+
+		dst := t.TempDir()
+		if err := Untar(tarPath, dst); err != nil {
+			t.Fatalf("Untar (gzip=%v): %v", compressed, err)
+		}
+		got, err := os.ReadFile(filepath.Join(dst, "home", "alice", ".config", "google-chrome", "Local State"))
+		if err != nil || string(got) != "{}" {
+			t.Errorf("absolute entry (gzip=%v): %q, %v", compressed, got, err)
+		}
+		if _, err := os.ReadFile(filepath.Join(dst, "home", "alice", ".mozilla", "firefox", "p.default", "key4.db")); err != nil {
+			t.Errorf("relative entry (gzip=%v): %v", compressed, err)
+		}
+		if _, err := os.Lstat(filepath.Join(dst, "home", "alice", "link")); !os.IsNotExist(err) {
+			t.Errorf("symlink entry must be skipped (gzip=%v), got %v", compressed, err)
+		}
+	}
+}
+
+func TestUntar_RejectsTraversal(t *testing.T) {
+	var buf bytes.Buffer
+	writeTar(t, &buf, []*tar.Header{{Name: "../escape.txt", Typeflag: tar.TypeReg, Mode: 0o600}},
+		map[string]string{"../escape.txt": "pwned"})
+	tarPath := filepath.Join(t.TempDir(), "evil.tar")
+	if err := os.WriteFile(tarPath, buf.Bytes(), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := Untar(tarPath, t.TempDir()); err == nil {
+		t.Fatal("Untar must reject an entry that escapes the destination")
+	}
+}
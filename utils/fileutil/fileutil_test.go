@@ -1,6 +1,7 @@
 package fileutil
 
 import (
+	"archive/zip"
 	"os"
 	"path/filepath"
 	"testing"
@@ -36,6 +37,25 @@ func TestCompressDir(t *testing.T) {
 		assert.FileExists(t, zipFile, "zip file should be created")
 	})
 
+	t.Run("Subdirectories", func(t *testing.T) {
+		tempDir := setupTestDir(t, []string{"file1.txt"})
+		defer os.RemoveAll(tempDir)
+		require.NoError(t, os.MkdirAll(filepath.Join(tempDir, "sub", "deeper"), 0o755))
+		require.NoError(t, os.WriteFile(filepath.Join(tempDir, "sub", "deeper", "file2.txt"), []byte("nested"), 0o644))
+
+		require.NoError(t, CompressDir(tempDir))
+		assert.NoDirExists(t, filepath.Join(tempDir, "sub"), "packed directories are removed")
+
+		zr, err := zip.OpenReader(filepath.Join(tempDir, filepath.Base(tempDir)+".zip"))
+		require.NoError(t, err)
+		defer zr.Close()
+		var names []string
+		for _, f := range zr.File {
+			names = append(names, f.Name)
+		}
+		assert.ElementsMatch(t, []string{"file1.txt", "sub/deeper/file2.txt"}, names)
+	})
+
 	t.Run("Directory Does Not Exist", func(t *testing.T) {
 		err := CompressDir("/path/to/nonexistent/directory")
 		require.Error(t, err, "should return an error for non-existent directory")
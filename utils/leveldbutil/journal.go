@@ -0,0 +1,153 @@
+package leveldbutil
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+)
+
+const (
+	journalBlockSize  = 32 * 1024
+	journalHeaderSize = 7 // checksum, length, type
+
+	chunkFull   = 1
+	chunkFirst  = 2
+	chunkMiddle = 3
+	chunkLast   = 4
+
+	batchHeaderSize = 12 // sequence number, count
+
+	kindDeletion = 0
+	kindValue    = 1
+)
+
+var crcTable = crc32.MakeTable(crc32.Castagnoli)
+
+// maskedCRC is LevelDB's stored form of a CRC-32C: rotated and offset so that a checksum of data
+// holding checksums does not degenerate.
+func maskedCRC(b ...[]byte) uint32 {
+	var c uint32
+	for _, p := range b {
+		c = crc32.Update(c, crcTable, p)
+	}
+	return mask(c)
+}
+
+// mask turns a raw CRC-32C into its stored form.
+func mask(c uint32) uint32 {
+	return (c>>15 | c<<17) + 0xa282ead8
+}
+
+// readJournal decodes a journal: 32 KiB blocks of checksummed chunks that reassemble into write
+// batches. A chunk that fails its checksum or runs past its block ends that block, and the batch it
+// belonged to is dropped; reading resumes with the next block, where LevelDB starts a new chunk.
+func readJournal(data []byte) ([]Record, error) {
+	var (
+		records []Record
+		errs    []error
+		batch   []byte
+		inBatch bool
+	)
+	for block := 0; block < len(data); block += journalBlockSize {
+		end := block + journalBlockSize
+		if end > len(data) {
+			end = len(data)
+		}
+		for off := block; off+journalHeaderSize <= end; {
+			h := data[off : off+journalHeaderSize]
+			n := int(binary.LittleEndian.Uint16(h[4:]))
+			typ := h[6]
+			if typ == 0 && n == 0 {
+				break // zero padding, or a preallocated tail never written
+			}
+			if off+journalHeaderSize+n > end {
+				errs = append(errs, fmt.Errorf("chunk at %d runs past its block", off))
+				batch, inBatch = nil, false
+				break
+			}
+			chunk := data[off+journalHeaderSize : off+journalHeaderSize+n]
+			if binary.LittleEndian.Uint32(h) != maskedCRC([]byte{typ}, chunk) {
+				errs = append(errs, fmt.Errorf("chunk at %d: checksum mismatch", off))
+				batch, inBatch = nil, false
+				break
+			}
+			off += journalHeaderSize + n
+
+			switch typ {
+			case chunkFull:
+				records = appendBatch(records, chunk, &errs)
+				batch, inBatch = nil, false
+			case chunkFirst:
+				batch, inBatch = append(batch[:0], chunk...), true
+			case chunkMiddle, chunkLast:
+				if !inBatch {
+					continue // the start of the batch was lost
+				}
+				batch = append(batch, chunk...)
+				if typ == chunkLast {
+					records = appendBatch(records, batch, &errs)
+					batch, inBatch = nil, false
+				}
+			default:
+				errs = append(errs, fmt.Errorf("chunk at %d: unknown type %d", off, typ))
+				batch, inBatch = nil, false
+			}
+		}
+	}
+	return records, errors.Join(errs...)
+}
+
+// appendBatch decodes a write batch — a sequence number and count, then that many puts and deletes
+// numbered from it — and appends its records. A batch cut short keeps the records before the cut.
+func appendBatch(records []Record, b []byte, errs *[]error) []Record {
+	if len(b) < batchHeaderSize {
+		*errs = append(*errs, errors.New("short batch"))
+		return records
+	}
+	seq := binary.LittleEndian.Uint64(b)
+	count := int(binary.LittleEndian.Uint32(b[8:]))
+	pos := batchHeaderSize
+	for i := 0; i < count; i++ {
+		if pos >= len(b) {
+			*errs = append(*errs, fmt.Errorf("batch %d ends after %d of %d records", seq, i, count))
+			return records
+		}
+		kind := b[pos]
+		pos++
+		key, n := lengthPrefixed(b[pos:])
+		if n == 0 {
+			*errs = append(*errs, fmt.Errorf("batch %d: bad key", seq))
+			return records
+		}
+		pos += n
+		r := Record{Key: key, Seq: seq + uint64(i)}
+		switch kind {
+		case kindValue:
+			value, n := lengthPrefixed(b[pos:])
+			if n == 0 {
+				*errs = append(*errs, fmt.Errorf("batch %d: bad value", seq))
+				return records
+			}
+			pos += n
+			r.Value = value
+		case kindDeletion:
+			r.Deleted = true
+		default:
+			*errs = append(*errs, fmt.Errorf("batch %d: unknown record kind %d", seq, kind))
+			return records
+		}
+		records = append(records, r)
+	}
+	return records
+}
+
+// lengthPrefixed reads a varint length and that many bytes, returning a copy of them and the bytes
+// consumed, or 0 when b ends first.
+func lengthPrefixed(b []byte) ([]byte, int) {
+	l, n := binary.Uvarint(b)
+	if n <= 0 || uint64(len(b)-n) < l {
+		return nil, 0
+	}
+	return append([]byte{}, b[n:n+int(l)]...), n + int(l)
+}
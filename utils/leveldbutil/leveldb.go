@@ -0,0 +1,120 @@
+// Package leveldbutil reads a LevelDB directory's files raw, without opening the database: every
+// version of every key its journals and tables still hold, deletions included.
+package leveldbutil
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Record is one version of a key: a value written with sequence number Seq, or with Deleted set the
+// deletion marker that retired the key's earlier values.
+type Record struct {
+	Key     []byte
+	Value   []byte
+	Seq     uint64
+	Deleted bool
+	File    string // base name of the .log or table file the version was read from
+}
+
+// Origin names where the version was found, e.g. "000005.ldb seq 1042".
+func (r Record) Origin() string {
+	return fmt.Sprintf("%s seq %d", r.File, r.Seq)
+}
+
+// ReadDir reads every journal (.log) and table (.ldb, .sst) in dir, live or obsolete, and returns the
+// versions they hold sorted by key, newest first; a version found in more than one file is returned
+// once. LevelDB only drops an overwritten value or a deleted key when a compaction rewrites the table
+// that holds it, and only deletes a file after that, so until then the old versions are all here.
+//
+// A damaged file does not stop the read: a journal is read around its corrupt blocks, a table block
+// that fails its checksum is skipped, and the blocks of a table cut short by a partial copy are found
+// without its index. The problems are returned joined, together with everything that could be read.
+func ReadDir(dir string) ([]Record, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var (
+		records []Record
+		errs    []error
+	)
+	for _, e := range entries {
+		kind := fileKind(e.Name())
+		if kind == "" || e.IsDir() {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(dir, e.Name()))
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		var got []Record
+		if kind == "log" {
+			got, err = readJournal(data)
+		} else {
+			got, err = readTable(data)
+		}
+		for i := range got {
+			got[i].File = e.Name()
+		}
+		records = append(records, got...)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", e.Name(), err))
+		}
+	}
+
+	sort.SliceStable(records, func(i, j int) bool {
+		if c := bytes.Compare(records[i].Key, records[j].Key); c != 0 {
+			return c < 0
+		}
+		return records[i].Seq > records[j].Seq
+	})
+	out := records[:0]
+	for i, r := range records {
+		if i > 0 && r.Seq == records[i-1].Seq && bytes.Equal(r.Key, records[i-1].Key) {
+			continue
+		}
+		out = append(out, r)
+	}
+	return out, errors.Join(errs...)
+}
+
+// Latest keeps the newest version of each key from records sorted as ReadDir sorts them, leaving out
+// deleted keys: the view the database itself gives. It stands in for opening a database LevelDB
+// refuses, with one difference: a table that a compaction made obsolete but that is still on disk can
+// bring back a value whose deletion the compaction dropped.
+func Latest(records []Record) []Record {
+	var live []Record
+	for i, r := range records {
+		if i > 0 && bytes.Equal(r.Key, records[i-1].Key) {
+			continue
+		}
+		if !r.Deleted {
+			live = append(live, r)
+		}
+	}
+	return live
+}
+
+// fileKind classifies a LevelDB file name: "log" for a journal, "table" for a table, "" for the
+// rest (MANIFEST, CURRENT, LOCK and the LOG text files).
+func fileKind(name string) string {
+	ext := filepath.Ext(name)
+	num := strings.TrimSuffix(name, ext)
+	if num == "" || strings.Trim(num, "0123456789") != "" {
+		return ""
+	}
+	switch ext {
+	case ".log":
+		return "log"
+	case ".ldb", ".sst":
+		return "table"
+	}
+	return ""
+}
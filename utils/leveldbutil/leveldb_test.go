@@ -0,0 +1,139 @@
+package leveldbutil
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/syndtr/goleveldb/leveldb"
+	"github.com/syndtr/goleveldb/leveldb/opt"
+)
+
+func versionValue(round int) string { return fmt.Sprintf("v%d-%s", round, strings.Repeat("x", 50)) }
+
+// createTestLevelDB writes three rounds of the same 50 keys and deletes key07. The tiny write buffer
+// flushes the first rounds to a table, and the raised L0 triggers keep a compaction from merging the
+// versions away, so they end up spread over a table and the journals.
+func createTestLevelDB(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	db, err := leveldb.OpenFile(dir, &opt.Options{
+		WriteBuffer:            4096,
+		BlockSize:              256,
+		CompactionL0Trigger:    100,
+		WriteL0SlowdownTrigger: 200,
+		WriteL0PauseTrigger:    300,
+	})
+	require.NoError(t, err)
+	for round := 0; round < 3; round++ {
+		for i := 0; i < 50; i++ {
+			require.NoError(t, db.Put([]byte(fmt.Sprintf("key%02d", i)), []byte(versionValue(round)), nil))
+		}
+	}
+	require.NoError(t, db.Delete([]byte("key07"), nil))
+	require.NoError(t, db.Close())
+	return dir
+}
+
+func filesWithExt(t *testing.T, dir, ext string) []string {
+	t.Helper()
+	matches, err := filepath.Glob(filepath.Join(dir, "*"+ext))
+	require.NoError(t, err)
+	return matches
+}
+
+func TestReadDir(t *testing.T) {
+	dir := createTestLevelDB(t)
+	require.NotEmpty(t, filesWithExt(t, dir, ".ldb"))
+
+	records, err := ReadDir(dir)
+	require.NoError(t, err)
+	assert.Len(t, records, 151, "every put and the delete")
+
+	var key00 []Record
+	for _, r := range records {
+		if string(r.Key) == "key00" {
+			key00 = append(key00, r)
+		}
+	}
+	require.Len(t, key00, 3)
+	for i, r := range key00 {
+		assert.Equal(t, versionValue(2-i), string(r.Value), "newest first")
+	}
+	assert.Greater(t, key00[0].Seq, key00[1].Seq)
+
+	live := Latest(records)
+	require.Len(t, live, 49)
+	db, err := leveldb.OpenFile(dir, &opt.Options{ReadOnly: true})
+	require.NoError(t, err)
+	defer db.Close()
+	for _, r := range live {
+		assert.NotEqual(t, "key07", string(r.Key))
+		v, err := db.Get(r.Key, nil)
+		require.NoError(t, err)
+		assert.Equal(t, v, r.Value, "the same view as the database gives")
+	}
+}
+
+func TestReadDir_Damaged(t *testing.T) {
+	dir := createTestLevelDB(t)
+	for _, path := range filesWithExt(t, dir, ".log") {
+		data, err := os.ReadFile(path)
+		require.NoError(t, err)
+		if len(data) > 100 {
+			data[100] ^= 0xff
+		}
+		require.NoError(t, os.WriteFile(path, data, 0o600))
+	}
+	for _, path := range filesWithExt(t, dir, ".ldb") {
+		data, err := os.ReadFile(path)
+		require.NoError(t, err)
+		require.NoError(t, os.WriteFile(path, data[:len(data)*2/3], 0o600), "a partial copy")
+	}
+	records, err := ReadDir(dir)
+	assert.Error(t, err)
+	var fromTable int
+	for _, r := range records {
+		if filepath.Ext(r.File) == ".ldb" {
+			fromTable++
+		}
+		if !r.Deleted {
+			assert.True(t, strings.HasPrefix(string(r.Value), "v"), "only whole records: %q", r.Value)
+		}
+	}
+	assert.Greater(t, fromTable, 0, "the blocks before the cut are salvaged")
+	assert.Greater(t, len(records), fromTable, "the journal is read around the damage")
+}
+
+func TestReadJournal_SpansBlocks(t *testing.T) {
+	dir := t.TempDir()
+	db, err := leveldb.OpenFile(dir, nil)
+	require.NoError(t, err)
+	big := bytes.Repeat([]byte("0123456789"), 10000) // spans FIRST, MIDDLE and LAST chunks
+	require.NoError(t, db.Put([]byte("big"), big, nil))
+	require.NoError(t, db.Put([]byte("small"), []byte("s"), nil))
+	require.NoError(t, db.Close())
+
+	records, err := ReadDir(dir)
+	require.NoError(t, err)
+	require.Len(t, records, 2)
+	assert.Equal(t, big, records[0].Value)
+	assert.Equal(t, "s", string(records[1].Value))
+	assert.Equal(t, records[0].Seq+1, records[1].Seq)
+	assert.Contains(t, records[1].Origin(), ".log seq ")
+}
+
+func TestFileKind(t *testing.T) {
+	assert.Equal(t, "log", fileKind("000003.log"))
+	assert.Equal(t, "table", fileKind("000005.ldb"))
+	assert.Equal(t, "table", fileKind("000005.sst"))
+	assert.Equal(t, "", fileKind("LOG"))
+	assert.Equal(t, "", fileKind("LOG.old"))
+	assert.Equal(t, "", fileKind("MANIFEST-000001"))
+	assert.Equal(t, "", fileKind(".log"))
+}
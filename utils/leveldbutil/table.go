@@ -0,0 +1,204 @@
+package leveldbutil
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+
+	"github.com/golang/snappy"
+)
+
+const (
+	tableFooterSize   = 48
+	tableMagic        = 0xdb4775248b80fb57
+	blockTrailerSize  = 5 // compression type, checksum
+	compressionNone   = 0
+	compressionSnappy = 1
+	internalKeyTail   = 8 // sequence number << 8 | kind
+
+	// maxSequence is the largest sequence number, which LevelDB gives the separator keys of an index
+	// block and never a written record.
+	maxSequence = 1<<56 - 1
+)
+
+// blockHandle locates a block in a table file.
+type blockHandle struct {
+	offset, size uint64
+}
+
+// decodeHandle reads a block handle (two varints) and returns it with the bytes it took, or 0.
+func decodeHandle(b []byte) (blockHandle, int) {
+	off, n := binary.Uvarint(b)
+	if n <= 0 {
+		return blockHandle{}, 0
+	}
+	size, m := binary.Uvarint(b[n:])
+	if m <= 0 {
+		return blockHandle{}, 0
+	}
+	return blockHandle{off, size}, n + m
+}
+
+// readTable decodes a table: the footer points at the index block, whose entries point at the data
+// blocks, whose keys are internal keys (the user key followed by its sequence number and kind). A data
+// block that fails its checksum or does not decompress is skipped. A table without its footer, cut
+// short by a partial copy, has its data blocks found by salvageBlocks instead.
+func readTable(data []byte) ([]Record, error) {
+	handles, err := tableIndex(data)
+	if err != nil {
+		handles = salvageBlocks(data)
+		err = fmt.Errorf("%w; salvaged %d blocks", err, len(handles))
+	}
+	errs := []error{err}
+
+	var records []Record
+	for _, h := range handles {
+		block, err := readBlock(data, h)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("data block at %d: %w", h.offset, err))
+			continue
+		}
+		if err := walkBlock(block, func(key, value []byte) {
+			if len(key) < internalKeyTail {
+				return
+			}
+			tail := binary.LittleEndian.Uint64(key[len(key)-internalKeyTail:])
+			if tail>>8 == maxSequence {
+				return // an index entry's separator key, met while salvaging
+			}
+			r := Record{
+				Key:     append([]byte{}, key[:len(key)-internalKeyTail]...),
+				Seq:     tail >> 8,
+				Deleted: tail&0xff == kindDeletion,
+			}
+			if !r.Deleted {
+				r.Value = append([]byte{}, value...)
+			}
+			records = append(records, r)
+		}); err != nil {
+			errs = append(errs, fmt.Errorf("data block at %d: %w", h.offset, err))
+		}
+	}
+	return records, errors.Join(errs...)
+}
+
+// tableIndex reads the data block handles out of a table's index block.
+func tableIndex(data []byte) ([]blockHandle, error) {
+	if len(data) < tableFooterSize {
+		return nil, errors.New("too short for a table")
+	}
+	footer := data[len(data)-tableFooterSize:]
+	if binary.LittleEndian.Uint64(footer[tableFooterSize-8:]) != tableMagic {
+		return nil, errors.New("no table footer")
+	}
+	_, n := decodeHandle(footer) // metaindex
+	if n == 0 {
+		return nil, errors.New("bad metaindex handle")
+	}
+	index, m := decodeHandle(footer[n:])
+	if m == 0 {
+		return nil, errors.New("bad index handle")
+	}
+	indexBlock, err := readBlock(data, index)
+	if err != nil {
+		return nil, fmt.Errorf("index block: %w", err)
+	}
+	var handles []blockHandle
+	err = walkBlock(indexBlock, func(_, value []byte) {
+		if h, n := decodeHandle(value); n > 0 {
+			handles = append(handles, h)
+		}
+	})
+	if err != nil {
+		return nil, fmt.Errorf("index block: %w", err)
+	}
+	return handles, nil
+}
+
+// salvageBlocks finds the blocks of a table whose footer is gone. Blocks follow one another from the
+// start of the file, each followed by its trailer, so a block ends at the first position where a
+// valid compression type and the checksum of everything before it follow. The filter, metaindex and
+// index blocks after the data blocks are found too; index entries are told apart by their keys'
+// sequence number (see maxSequence), the others fail to parse as entries.
+func salvageBlocks(data []byte) []blockHandle {
+	var handles []blockHandle
+	for start := 0; start+blockTrailerSize <= len(data); {
+		var crc uint32
+		found := false
+		for e := start; e+blockTrailerSize <= len(data); e++ {
+			if e > start {
+				crc = crc32.Update(crc, crcTable, data[e-1:e])
+			}
+			if data[e] > compressionSnappy {
+				continue
+			}
+			if mask(crc32.Update(crc, crcTable, data[e:e+1])) == binary.LittleEndian.Uint32(data[e+1:]) {
+				handles = append(handles, blockHandle{uint64(start), uint64(e - start)})
+				start, found = e+blockTrailerSize, true
+				break
+			}
+		}
+		if !found {
+			break
+		}
+	}
+	return handles
+}
+
+// readBlock returns the contents of the block at h, checked against its checksum and decompressed.
+func readBlock(data []byte, h blockHandle) ([]byte, error) {
+	end := h.offset + h.size + blockTrailerSize
+	if end < h.offset || end > uint64(len(data)) {
+		return nil, errors.New("out of range")
+	}
+	raw := data[h.offset : h.offset+h.size]
+	trailer := data[h.offset+h.size : end]
+	if binary.LittleEndian.Uint32(trailer[1:]) != maskedCRC(raw, trailer[:1]) {
+		return nil, errors.New("checksum mismatch")
+	}
+	switch trailer[0] {
+	case compressionNone:
+		return raw, nil
+	case compressionSnappy:
+		return snappy.Decode(nil, raw)
+	}
+	return nil, fmt.Errorf("unsupported compression %d", trailer[0])
+}
+
+// walkBlock calls fn with each entry of a block. Entries share key prefixes with the one before them;
+// the block ends with the offsets of its restart points and their count, which bound the entries.
+func walkBlock(block []byte, fn func(key, value []byte)) error {
+	if len(block) < 4 {
+		return errors.New("short block")
+	}
+	restarts := int(binary.LittleEndian.Uint32(block[len(block)-4:]))
+	if restarts > (len(block)-4)/4 {
+		return errors.New("bad restart count")
+	}
+	limit := len(block) - 4 - 4*restarts
+	var key []byte
+	for pos := 0; pos < limit; {
+		shared, n1 := binary.Uvarint(block[pos:limit])
+		if n1 <= 0 {
+			return fmt.Errorf("bad entry at %d", pos)
+		}
+		unshared, n2 := binary.Uvarint(block[pos+n1 : limit])
+		if n2 <= 0 {
+			return fmt.Errorf("bad entry at %d", pos)
+		}
+		valueLen, n3 := binary.Uvarint(block[pos+n1+n2 : limit])
+		if n3 <= 0 {
+			return fmt.Errorf("bad entry at %d", pos)
+		}
+		p := pos + n1 + n2 + n3
+		if shared > uint64(len(key)) || unshared > uint64(limit-p) || valueLen > uint64(limit-p)-unshared {
+			return fmt.Errorf("bad entry at %d", pos)
+		}
+		key = append(key[:shared], block[p:p+int(unshared)]...)
+		p += int(unshared)
+		fn(key, block[p:p+int(valueLen)])
+		pos = p + int(valueLen)
+	}
+	return nil
+}
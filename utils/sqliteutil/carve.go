@@ -0,0 +1,906 @@
+package sqliteutil
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/url"
+	"os"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/moond4rk/hackbrowserdata/log"
+)
+
+// Column is one column of a carving schema.
+type Column struct {
+	Name    string
+	Type    string // declared type; its affinity decides which stored types the column accepts
+	NotNull bool
+	Default bool // has a DEFAULT, so rows written before an ALTER TABLE ADD COLUMN may lack it
+	RowID   bool // INTEGER PRIMARY KEY: the record stores NULL, the value is the cell's rowid
+}
+
+// Schema describes the records Carve looks for: a table's columns in declaration order and the root
+// page of its b-tree (0 when unknown, which limits the scan to freelist pages).
+type Schema struct {
+	Table    string
+	RootPage uint32
+	Columns  []Column
+}
+
+// TableSchema reads table's schema from the database at dbPath.
+func TableSchema(dbPath string, journalOff bool, table string) (Schema, error) {
+	schema := Schema{Table: table}
+	quoted := `"` + strings.ReplaceAll(table, `"`, `""`) + `"`
+	var pkCount int
+	err := QuerySQLite(dbPath, journalOff, "PRAGMA table_info("+quoted+")", func(rows *sql.Rows) error {
+		var (
+			cid, notNull, pk int
+			name, typ        string
+			dflt             sql.NullString
+		)
+		if err := rows.Scan(&cid, &name, &typ, &notNull, &dflt, &pk); err != nil {
+			return err
+		}
+		if pk > 0 {
+			pkCount++
+		}
+		schema.Columns = append(schema.Columns, Column{
+			Name:    name,
+			Type:    typ,
+			NotNull: notNull != 0,
+			Default: dflt.Valid,
+			RowID:   pk > 0 && strings.EqualFold(typ, "INTEGER"),
+		})
+		return nil
+	})
+	if err != nil {
+		return Schema{}, err
+	}
+	if len(schema.Columns) == 0 {
+		return Schema{}, fmt.Errorf("no such table: %s", table)
+	}
+	if pkCount > 1 {
+		// a composite key is not a rowid alias
+		for i := range schema.Columns {
+			schema.Columns[i].RowID = false
+		}
+	}
+	err = QuerySQLite(dbPath, journalOff, "SELECT rootpage FROM sqlite_master WHERE type = 'table' AND name = '"+
+		strings.ReplaceAll(table, "'", "''")+"'", func(rows *sql.Rows) error {
+		return rows.Scan(&schema.RootPage)
+	})
+	return schema, err
+}
+
+// Record is one row carved out of free space or read from a page version the write-ahead log
+// superseded, or a live row read by ScanRecord.
+type Record struct {
+	schema *Schema
+	// Values are in schema column order: int64, float64, string, []byte, or nil for NULL and for the
+	// rowid column of a carved record, whose value does not survive deletion.
+	Values []any
+	// Commit and Frame are the 1-based WAL commit and frame that wrote the page version the record was
+	// read from, both 0 when it came from the database file.
+	Commit, Frame int
+}
+
+// Origin says where the record was found, e.g. "wal commit 3, frame 12" or "database file".
+func (r Record) Origin() string {
+	if r.Frame == 0 {
+		return "database file"
+	}
+	return fmt.Sprintf("wal commit %d, frame %d", r.Commit, r.Frame)
+}
+
+// Value returns the named column's value, or nil when the schema has no such column.
+func (r Record) Value(column string) any {
+	for i, c := range r.schema.Columns {
+		if c.Name == column {
+			return r.Values[i]
+		}
+	}
+	return nil
+}
+
+// Int returns the named column as an integer; other types read as 0.
+func (r Record) Int(column string) int64 {
+	switch v := r.Value(column).(type) {
+	case int64:
+		return v
+	case float64:
+		return int64(v)
+	}
+	return 0
+}
+
+// Text returns the named column as a string; a blob reads as its bytes, other types as "".
+func (r Record) Text(column string) string {
+	switch v := r.Value(column).(type) {
+	case string:
+		return v
+	case []byte:
+		return string(v)
+	}
+	return ""
+}
+
+// Blob returns the named column as bytes; text reads as its bytes, other types as nil.
+func (r Record) Blob(column string) []byte {
+	switch v := r.Value(column).(type) {
+	case []byte:
+		return v
+	case string:
+		return []byte(v)
+	}
+	return nil
+}
+
+// freeblockHeader is how many leading bytes of a deleted cell SQLite overwrites when the cell becomes a
+// freeblock (the next-freeblock offset and the block size).
+const freeblockHeader = 4
+
+// Carve scans the free space of the database at dbPath for records that fit schema: freelist pages,
+// and the unallocated gap and freeblocks of the table's own leaf pages. Deleted rows stay there until
+// SQLite reuses the space (or secure_delete zeroes it). Overflow chains are followed where a freed
+// page still holds the cell's pointer. The rowid column and the values of a record whose header a
+// freeblock overwrote cannot be told apart from other tables' rows with the same shape, so a caller
+// should check what it gets back.
+func Carve(dbPath string, schema Schema) ([]Record, error) {
+	f, err := os.Open(dbPath)
+	if err != nil {
+		return nil, fmt.Errorf("database file: %w", err)
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	c, err := newCarver(f, info.Size(), &schema)
+	if err != nil {
+		return nil, err
+	}
+	c.trunks = c.freelistTrunks()
+	c.scanFreelist()
+	if schema.RootPage != 0 {
+		c.scanTable(schema.RootPage)
+	}
+	return c.records, nil
+}
+
+// Carved is an entry CarveRows recovered, with the Origin of the record it came from.
+type Carved[T any] struct {
+	Item   T
+	Origin string
+}
+
+// CarveRows is the carving counterpart of QueryRows: it reads table's schema from dbPath, carves the
+// records that fit it, adds those of the page versions wal superseded (wal may be nil, see ReadWAL),
+// and converts each with convertFn, skipping those convertFn rejects. Entries equal to one in live, or
+// to one already recovered, are dropped: free pages keep stale copies of rows that SQLite moved rather
+// than deleted, and a row the log rewrote unchanged is the live row. The log's row versions come first,
+// in commit order, so a row found both there and in free space keeps the commit it was written in.
+func CarveRows[T comparable](dbPath string, journalOff bool, wal *WAL, table string, live []T,
+	convertFn func(Record) (T, error),
+) ([]Carved[T], error) {
+	schema, err := TableSchema(dbPath, journalOff, table)
+	if err != nil {
+		return nil, err
+	}
+	carved, err := Carve(dbPath, schema)
+	if err != nil {
+		return nil, err
+	}
+	records, err := wal.Carve(dbPath, schema)
+	if err != nil {
+		log.Debugf("carve %s wal: %v", table, err)
+	}
+	records = append(records, carved...)
+
+	seen := make(map[T]bool, len(live))
+	for _, item := range live {
+		seen[item] = true
+	}
+	var items []Carved[T]
+	for _, rec := range records {
+		item, err := convertFn(rec)
+		if err != nil {
+			log.Debugf("carve %s row: %v", table, err)
+			continue
+		}
+		if seen[item] {
+			continue
+		}
+		seen[item] = true
+		items = append(items, Carved[T]{Item: item, Origin: rec.Origin()})
+	}
+	return items, nil
+}
+
+// LooksLikeURL reports whether s parses as an absolute URL. A record header that fits the schema can
+// still be noise, so converters check carved url columns with it.
+func LooksLikeURL(s string) bool {
+	if s == "" || strings.ContainsAny(s, " \x00\r\n") {
+		return false
+	}
+	u, err := url.Parse(s)
+	return err == nil && u.Scheme != "" && (u.Host != "" || u.Opaque != "" || u.Path != "")
+}
+
+var sqliteMagic = []byte("SQLite format 3\x00")
+
+type carver struct {
+	r        io.ReaderAt
+	pageSize int
+	usable   int
+	pages    uint32
+	schema   *Schema
+	minCols  int // fewest columns a record may carry, see minColumns
+	trunks   []uint32
+	records  []Record
+
+	// view holds page versions read from a write-ahead log in place of the database file's, and
+	// commit and frame place the version being decoded (see WAL.Carve).
+	view          map[uint32][]byte
+	commit, frame int
+}
+
+func newCarver(r io.ReaderAt, size int64, schema *Schema) (*carver, error) {
+	if len(schema.Columns) == 0 {
+		return nil, fmt.Errorf("schema for %q has no columns", schema.Table)
+	}
+	hdr := make([]byte, 100)
+	if _, err := r.ReadAt(hdr, 0); err != nil {
+		return nil, fmt.Errorf("read header: %w", err)
+	}
+	if !bytes.Equal(hdr[:16], sqliteMagic) {
+		return nil, errors.New("not a sqlite database")
+	}
+	pageSize := int(binary.BigEndian.Uint16(hdr[16:]))
+	if pageSize == 1 {
+		pageSize = 65536
+	}
+	if pageSize < 512 || pageSize&(pageSize-1) != 0 {
+		return nil, fmt.Errorf("invalid page size %d", pageSize)
+	}
+	if enc := binary.BigEndian.Uint32(hdr[56:]); enc > 1 {
+		return nil, fmt.Errorf("unsupported text encoding %d", enc)
+	}
+	return &carver{
+		r:        r,
+		pageSize: pageSize,
+		usable:   pageSize - int(hdr[20]),
+		pages:    uint32(size / int64(pageSize)),
+		schema:   schema,
+		minCols:  minColumns(schema.Columns),
+	}, nil
+}
+
+// minColumns is how many columns a record must carry. Rows written before an ALTER TABLE ADD COLUMN
+// keep their shorter record, and an added column is nullable or has a default, so only the leading
+// columns up to the last NOT NULL one without a default are sure to be there. Browsers add a few
+// columns over the years, not most of a table, so at least half are required either way: a two-column
+// header is too easily found in noise.
+func minColumns(cols []Column) int {
+	n := len(cols)
+	for n > (len(cols)+1)/2 && (!cols[n-1].NotNull || cols[n-1].Default) {
+		n--
+	}
+	return n
+}
+
+// sqliteMaxLength is SQLite's default limit on the size of a string, blob or row.
+const sqliteMaxLength = 1000000000
+
+// maxPayload bounds the size of a record or value: none can be larger than the database (or than
+// SQLite allows), so a bigger size read from free space is noise.
+func (c *carver) maxPayload() int {
+	n := int64(c.pages) * int64(c.pageSize)
+	if n > sqliteMaxLength {
+		n = sqliteMaxLength
+	}
+	return int(n)
+}
+
+// page reads page n (1-based) without its reserved tail, or returns nil when n is out of range.
+func (c *carver) page(n uint32) []byte {
+	if n == 0 || n > c.pages {
+		return nil
+	}
+	if p, ok := c.view[n]; ok {
+		if p == nil {
+			return nil
+		}
+		return p[:c.usable]
+	}
+	p := make([]byte, c.pageSize)
+	if _, err := c.r.ReadAt(p, int64(n-1)*int64(c.pageSize)); err != nil {
+		return nil
+	}
+	return p[:c.usable]
+}
+
+// scanFreelist carves every freelist leaf page whole and each trunk page past its leaf list.
+func (c *carver) scanFreelist() {
+	seen := make(map[uint32]bool)
+	for _, trunk := range c.trunks {
+		seen[trunk] = true
+	}
+	for _, trunk := range c.trunks {
+		p := c.page(trunk)
+		n := trunkLeaves(p, c.usable)
+		for i := 0; i < n; i++ {
+			leaf := binary.BigEndian.Uint32(p[8+4*i:])
+			if seen[leaf] {
+				continue
+			}
+			seen[leaf] = true
+			if lp := c.page(leaf); lp != nil {
+				c.scanFormerLeaf(lp)
+			}
+		}
+		c.scan(p, 8+4*n, len(p))
+	}
+}
+
+// freelistTrunks lists the freelist trunk pages. They are known before anything is carved because a
+// page that became a trunk has lost its first bytes to the leaf list and cannot continue an overflow
+// chain.
+func (c *carver) freelistTrunks() []uint32 {
+	hdr := c.page(1)
+	if hdr == nil {
+		return nil
+	}
+	var trunks []uint32
+	seen := make(map[uint32]bool)
+	for trunk := binary.BigEndian.Uint32(hdr[32:]); trunk != 0 && !seen[trunk]; {
+		p := c.page(trunk)
+		if p == nil {
+			break
+		}
+		seen[trunk] = true
+		trunks = append(trunks, trunk)
+		trunk = binary.BigEndian.Uint32(p)
+	}
+	return trunks
+}
+
+// trunkLeaves is how many leaf page numbers a freelist trunk page lists.
+func trunkLeaves(p []byte, usable int) int {
+	n := int(binary.BigEndian.Uint32(p[4:]))
+	if n > usable/4-2 {
+		n = usable/4 - 2
+	}
+	return n
+}
+
+// scanTable walks the table's b-tree from root and carves the free space of each page. An interior
+// page's free space matters too: the root starts as a leaf and keeps that leaf's old cells in its gap
+// once the table outgrows one page.
+func (c *carver) scanTable(root uint32) {
+	c.walkTable(root, func(_ uint32, p []byte, h, hdrSize, cells int) {
+		c.scanPageFree(p, h, hdrSize, cells)
+	})
+}
+
+// walkTable calls fn for each page of the table b-tree under root with the page number, the page,
+// where its header starts, the header's size (12 for an interior page, 8 for a leaf) and its cell
+// count.
+func (c *carver) walkTable(root uint32, fn func(n uint32, p []byte, h, hdrSize, cells int)) {
+	seen := make(map[uint32]bool)
+	stack := []uint32{root}
+	for len(stack) > 0 {
+		n := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if seen[n] {
+			continue
+		}
+		seen[n] = true
+		p := c.page(n)
+		if p == nil {
+			continue
+		}
+		h := 0
+		if n == 1 {
+			h = 100
+		}
+		if len(p) < h+12 {
+			continue
+		}
+		cells := int(binary.BigEndian.Uint16(p[h+3:]))
+		switch p[h] {
+		case 0x05: // interior table page
+			ptrs := h + 12
+			for i := 0; i < cells && ptrs+2*i+2 <= len(p); i++ {
+				off := int(binary.BigEndian.Uint16(p[ptrs+2*i:]))
+				if off+4 <= len(p) {
+					stack = append(stack, binary.BigEndian.Uint32(p[off:]))
+				}
+			}
+			stack = append(stack, binary.BigEndian.Uint32(p[h+8:]))
+			fn(n, p, h, 12, cells)
+		case 0x0d: // leaf table page
+			fn(n, p, h, 8, cells)
+		}
+	}
+}
+
+// scanPageFree carves a b-tree page's unallocated gap (between the cell pointers and the cell content)
+// and its freeblocks, whose first bytes SQLite has overwritten with the freeblock header. The page
+// header starts at p[h] and is hdrSize bytes long.
+func (c *carver) scanPageFree(p []byte, h, hdrSize, cells int) {
+	gapStart := h + hdrSize + 2*cells
+	gapEnd := int(binary.BigEndian.Uint16(p[h+5:]))
+	if gapEnd == 0 {
+		gapEnd = 65536
+	}
+	if gapEnd > len(p) {
+		gapEnd = len(p)
+	}
+	if gapStart < gapEnd {
+		c.scan(p, gapStart, gapEnd)
+	}
+	for _, fb := range freeblocks(p, h) {
+		c.scanFreeblock(p, fb[0], fb[1])
+	}
+}
+
+// scanCells decodes the live cells of a table leaf page whose header starts at p[h]. Unlike a carved
+// record a cell is whole: its payload size is known, and so is its rowid, which fills the rowid column.
+func (c *carver) scanCells(p []byte, h, cells int) {
+	rowidCol := -1
+	for i, col := range c.schema.Columns {
+		if col.RowID {
+			rowidCol = i
+		}
+	}
+	ptrs := h + 8
+	for i := 0; i < cells && ptrs+2*i+2 <= len(p); i++ {
+		off := int(binary.BigEndian.Uint16(p[ptrs+2*i:]))
+		if off >= len(p) {
+			continue
+		}
+		size, n := varint(p[off:])
+		if n == 0 || off+n >= len(p) {
+			continue
+		}
+		rowid, m := varint(p[off+n:])
+		if m == 0 || size > uint64(c.maxPayload()) {
+			continue
+		}
+		payload, ok := c.cellPayload(p, off+n+m, int(size))
+		if !ok || !c.decodeRecord(payload) {
+			continue
+		}
+		if rowidCol >= 0 {
+			c.records[len(c.records)-1].Values[rowidCol] = int64(rowid)
+		}
+	}
+}
+
+// cellPayload returns the size bytes of the payload of a cell whose payload starts at p[start],
+// following its overflow chain when it spills.
+func (c *carver) cellPayload(p []byte, start, size int) ([]byte, bool) {
+	if size < 0 || size > c.maxPayload() {
+		return nil, false
+	}
+	local := c.localPayload(size)
+	if local == size {
+		if start+size > len(p) {
+			return nil, false
+		}
+		return p[start : start+size], true
+	}
+	if start+local+4 > len(p) {
+		return nil, false
+	}
+	rest, ok := c.overflow(binary.BigEndian.Uint32(p[start+local:]), size-local)
+	if !ok {
+		return nil, false
+	}
+	return append(append([]byte{}, p[start:start+local]...), rest...), true
+}
+
+// decodeRecord decodes a whole record (header and body) against the schema and keeps it if it fits.
+func (c *carver) decodeRecord(payload []byte) bool {
+	hdrLen, n := varint(payload)
+	if n == 0 || hdrLen <= uint64(n) || hdrLen > uint64(len(payload)) {
+		return false
+	}
+	types, ok := c.serialTypes(payload[n:hdrLen])
+	return ok && c.emit(types, payload[hdrLen:])
+}
+
+// scanFormerLeaf carves a freelist leaf page. SQLite leaves a freed page's bytes as they were, so a
+// page that was a table leaf still holds its cells and its freeblock chain: the freeblocks get the
+// same header reconstruction as on a live page and the rest of the page is scanned as is.
+func (c *carver) scanFormerLeaf(p []byte) {
+	pos := 0
+	if p[0] == 0x0d {
+		for _, fb := range freeblocks(p, 0) {
+			c.scan(p, pos, fb[0])
+			c.scanFreeblock(p, fb[0], fb[1])
+			pos = fb[1]
+		}
+	}
+	c.scan(p, pos, len(p))
+}
+
+// freeblocks follows the freeblock chain of the b-tree page whose header starts at p[h] and returns
+// each block's [start, end), in page order. A chain that loops or leaves the page ends there.
+func freeblocks(p []byte, h int) [][2]int {
+	var blocks [][2]int
+	prev := 0
+	for fb := int(binary.BigEndian.Uint16(p[h+1:])); fb > prev && fb+freeblockHeader <= len(p); {
+		end := fb + int(binary.BigEndian.Uint16(p[fb+2:]))
+		if end < fb+freeblockHeader || end > len(p) {
+			break
+		}
+		blocks = append(blocks, [2]int{fb, end})
+		prev = end - 1
+		fb = int(binary.BigEndian.Uint16(p[fb:]))
+	}
+	return blocks
+}
+
+// scanFreeblock carves a freeblock: the deleted cell at its start, then whatever else it covers
+// (adjacent freeblocks are merged into one).
+func (c *carver) scanFreeblock(p []byte, start, end int) {
+	pos := start + freeblockHeader
+	if n := c.tryFreeblock(p, start, end); n > 0 {
+		pos = start + n
+	}
+	c.scan(p, pos, end)
+}
+
+// tryFreeblock decodes a deleted cell at buf[fb] whose first bytes a freeblock header overwrote, and
+// returns the bytes it spans from fb, or 0. The cell started with its payload size and rowid varints
+// and then the record header, so the overwritten bytes may include the record's header-size byte and,
+// for a short row, the rowid column's serial type too; those are reconstructed from the schema.
+func (c *carver) tryFreeblock(buf []byte, fb, end int) int {
+	for s := fb + 1; s <= fb+freeblockHeader && s < end; s++ {
+		if n := c.tryImplicit(buf, s, end, false); n > 0 {
+			return s - fb + n
+		}
+		if s == fb+freeblockHeader && c.schema.Columns[0].RowID {
+			if n := c.tryImplicit(buf, s, end, true); n > 0 {
+				return s - fb + n
+			}
+		}
+	}
+	return 0
+}
+
+// scan carves records from buf[start:end], where each may begin at any byte. A record running past
+// end continues on overflow pages when buf holds its overflow pointer. Defragmenting a page leaves
+// its old freeblocks behind in the unallocated gap, so a position that holds a plausible freeblock
+// header is also tried as one.
+func (c *carver) scan(buf []byte, start, end int) {
+	for off := start; off < end; {
+		if n := c.tryRecord(buf, off, end); n > 0 {
+			off += n
+			continue
+		}
+		if off+freeblockHeader <= end {
+			size := int(binary.BigEndian.Uint16(buf[off+2:]))
+			if size >= freeblockHeader && off+size <= len(buf) {
+				if n := c.tryFreeblock(buf, off, end); n > 0 {
+					off += n
+					continue
+				}
+			}
+		}
+		off++
+	}
+}
+
+// tryRecord decodes a record whose header starts at buf[off] and returns the bytes it spans in buf,
+// or 0 when none fits the schema there.
+func (c *carver) tryRecord(buf []byte, off, end int) int {
+	hdrLen, n := varint(buf[off:end])
+	if n == 0 || hdrLen <= uint64(n) || hdrLen > uint64(9*(len(c.schema.Columns)+1)) {
+		return 0
+	}
+	hdrEnd := off + int(hdrLen)
+	if hdrEnd > end {
+		return 0
+	}
+	types, ok := c.serialTypes(buf[off+n : hdrEnd])
+	if !ok {
+		return 0
+	}
+	body, ok := c.bodyLen(types)
+	if !ok {
+		return 0
+	}
+	payload, span, ok := c.payload(buf, off, int(hdrLen)+body, end)
+	if !ok || !c.emit(types, payload[hdrLen:]) {
+		return 0
+	}
+	return span
+}
+
+// tryImplicit decodes a record whose header-size byte was overwritten and whose serial types start at
+// buf[s]; with noRowID the rowid column's serial type was overwritten as well and is taken as NULL.
+// Returns the bytes the record spans from s, or 0.
+func (c *carver) tryImplicit(buf []byte, s, end int, noRowID bool) int {
+	if s >= end {
+		return 0
+	}
+	types, typeBytes, ok := c.leadingSerialTypes(buf[s:end], noRowID)
+	if !ok {
+		return 0
+	}
+	start := s - 1 // the header-size byte
+	if noRowID {
+		start--
+	}
+	hdrLen := s - start + typeBytes
+	body, ok := c.bodyLen(types)
+	if !ok {
+		return 0
+	}
+	payload, span, ok := c.payload(buf, start, hdrLen+body, end)
+	if !ok || !c.emit(types, payload[hdrLen:]) {
+		return 0
+	}
+	return span - (s - start)
+}
+
+// payload returns the size bytes of the record starting at buf[start] and how many bytes of buf it
+// spans. A record running past end is taken to spill to overflow pages: only its local part is in buf,
+// followed by the first overflow page number.
+func (c *carver) payload(buf []byte, start, size, end int) ([]byte, int, bool) {
+	if size < 0 || size > c.maxPayload() {
+		return nil, 0, false
+	}
+	if start+size <= end {
+		return buf[start : start+size], size, true
+	}
+	local := c.localPayload(size)
+	if local >= size || start+local+4 > end {
+		return nil, 0, false
+	}
+	rest, ok := c.overflow(binary.BigEndian.Uint32(buf[start+local:]), size-local)
+	if !ok {
+		return nil, 0, false
+	}
+	return append(append([]byte{}, buf[start:start+local]...), rest...), local + 4, true
+}
+
+// serialTypes parses a complete record header (after its size varint) against the schema; the header
+// may end before the last columns (see minColumns).
+func (c *carver) serialTypes(hdr []byte) ([]uint64, bool) {
+	cols := c.schema.Columns
+	var types []uint64
+	for pos := 0; pos < len(hdr); {
+		if len(types) == len(cols) {
+			return nil, false
+		}
+		t, n := varint(hdr[pos:])
+		if n == 0 || !accepts(cols[len(types)], t) || !c.fits(t) {
+			return nil, false
+		}
+		types = append(types, t)
+		pos += n
+	}
+	return types, len(types) >= c.minCols
+}
+
+// leadingSerialTypes reads one serial type per schema column from the start of buf, where the header
+// length is unknown, checking each against its column, and returns them with the bytes read.
+func (c *carver) leadingSerialTypes(buf []byte, noRowID bool) ([]uint64, int, bool) {
+	cols := c.schema.Columns
+	types := make([]uint64, 0, len(cols))
+	pos := 0
+	for i, col := range cols {
+		if i == 0 && noRowID {
+			types = append(types, 0)
+			continue
+		}
+		t, n := varint(buf[pos:])
+		if n == 0 || !accepts(col, t) || !c.fits(t) {
+			return nil, 0, false
+		}
+		types = append(types, t)
+		pos += n
+	}
+	return types, pos, true
+}
+
+// emit decodes body against types and keeps the record if every value is well formed and at least
+// one is not NULL.
+func (c *carver) emit(types []uint64, body []byte) bool {
+	values := make([]any, len(c.schema.Columns))
+	pos, nonNull := 0, false
+	for i, t := range types {
+		n := serialSize(t)
+		if pos+n > len(body) {
+			return false
+		}
+		v, ok := decodeValue(t, body[pos:pos+n])
+		if !ok {
+			return false
+		}
+		values[i] = v
+		if v != nil && !c.schema.Columns[i].RowID {
+			nonNull = true
+		}
+		pos += n
+	}
+	if !nonNull {
+		return false
+	}
+	c.records = append(c.records, Record{schema: c.schema, Values: values, Commit: c.commit, Frame: c.frame})
+	return true
+}
+
+// localPayload is how many bytes of a table leaf cell's payload SQLite keeps on the page itself.
+func (c *carver) localPayload(payload int) int {
+	u := c.usable
+	maxLocal := u - 35
+	if payload <= maxLocal {
+		return payload
+	}
+	minLocal := (u-12)*32/255 - 23
+	k := minLocal + (payload-minLocal)%(u-4)
+	if k <= maxLocal {
+		return k
+	}
+	return minLocal
+}
+
+// overflow reads need bytes from the overflow chain starting at page first. A chain that loops or
+// runs into a freelist trunk page is lost, and so is one longer than the database has pages for.
+func (c *carver) overflow(first uint32, need int) ([]byte, bool) {
+	if need < 0 || int64(need) > int64(c.pages)*int64(c.usable-4) {
+		return nil, false
+	}
+	var out []byte
+	seen := make(map[uint32]bool)
+	for _, trunk := range c.trunks {
+		seen[trunk] = true
+	}
+	for pg := first; len(out) < need; {
+		if seen[pg] {
+			return nil, false
+		}
+		seen[pg] = true
+		p := c.page(pg)
+		if p == nil {
+			return nil, false
+		}
+		take := need - len(out)
+		if take > len(p)-4 {
+			take = len(p) - 4
+		}
+		out = append(out, p[4:4+take]...)
+		pg = binary.BigEndian.Uint32(p)
+	}
+	return out, true
+}
+
+// affinity is a column's type affinity, derived from its declared type as SQLite does.
+type affinity int
+
+const (
+	affinityBlob affinity = iota
+	affinityInteger
+	affinityText
+	affinityReal
+	affinityNumeric
+)
+
+func columnAffinity(decl string) affinity {
+	d := strings.ToUpper(decl)
+	switch {
+	case strings.Contains(d, "INT"):
+		return affinityInteger
+	case strings.Contains(d, "CHAR"), strings.Contains(d, "CLOB"), strings.Contains(d, "TEXT"):
+		return affinityText
+	case d == "", strings.Contains(d, "BLOB"):
+		return affinityBlob
+	case strings.Contains(d, "REAL"), strings.Contains(d, "FLOA"), strings.Contains(d, "DOUB"):
+		return affinityReal
+	}
+	return affinityNumeric
+}
+
+// accepts reports whether col can hold a value of serial type t. Affinity converts numbers written to
+// a text column into text and numeric text written to a numeric column into numbers, so browsers'
+// tables store one kind of value per column.
+func accepts(col Column, t uint64) bool {
+	switch {
+	case t == 0:
+		return !col.NotNull || col.RowID
+	case col.RowID, t == 10, t == 11:
+		return false
+	}
+	switch columnAffinity(col.Type) {
+	case affinityText:
+		return t >= 13 && t%2 == 1
+	case affinityInteger, affinityReal, affinityNumeric:
+		return t <= 9
+	}
+	return true
+}
+
+// serialSize is the body length of a value of serial type t.
+func serialSize(t uint64) int {
+	switch {
+	case t <= 4:
+		return int(t)
+	case t == 5:
+		return 6
+	case t == 6, t == 7:
+		return 8
+	case t < 12:
+		return 0
+	}
+	return int((t - 12) / 2)
+}
+
+// fits reports whether a value of serial type t can be stored in the database at all.
+func (c *carver) fits(t uint64) bool {
+	return t < 12 || (t-12)/2 <= uint64(c.maxPayload())
+}
+
+// bodyLen is the record body length of types, false when it exceeds maxPayload.
+func (c *carver) bodyLen(types []uint64) (int, bool) {
+	n := 0
+	for _, t := range types {
+		n += serialSize(t)
+		if n > c.maxPayload() {
+			return 0, false
+		}
+	}
+	return n, true
+}
+
+// decodeValue decodes a value of serial type t; text must be valid UTF-8.
+func decodeValue(t uint64, b []byte) (any, bool) {
+	switch {
+	case t == 0:
+		return nil, true
+	case t <= 6:
+		v := int64(int8(b[0]))
+		for _, x := range b[1:] {
+			v = v<<8 | int64(x)
+		}
+		return v, true
+	case t == 7:
+		f := math.Float64frombits(binary.BigEndian.Uint64(b))
+		return f, !math.IsNaN(f)
+	case t == 8:
+		return int64(0), true
+	case t == 9:
+		return int64(1), true
+	case t%2 == 0:
+		return append([]byte{}, b...), true
+	}
+	return string(b), utf8.Valid(b)
+}
+
+// varint decodes a SQLite varint (big-endian, up to 9 bytes) and returns it with its length, or a
+// length of 0 when buf ends first.
+func varint(buf []byte) (uint64, int) {
+	var v uint64
+	for i := 0; i < 9; i++ {
+		if i >= len(buf) {
+			return 0, 0
+		}
+		if i == 8 {
+			return v<<8 | uint64(buf[i]), 9
+		}
+		v = v<<7 | uint64(buf[i]&0x7f)
+		if buf[i] < 0x80 {
+			return v, i + 1
+		}
+	}
+	return v, 9
+}
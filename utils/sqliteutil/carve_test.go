@@ -0,0 +1,231 @@
+package sqliteutil
+
+import (
+	"bytes"
+	"database/sql"
+	"fmt"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type carvedVisit struct {
+	URL    string
+	Title  string
+	Visits int64
+}
+
+func createCarveDB(t *testing.T, stmts ...string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "History")
+	db, err := sql.Open("sqlite", path)
+	require.NoError(t, err)
+	defer db.Close()
+	var secureDelete int
+	require.NoError(t, db.QueryRow("PRAGMA secure_delete").Scan(&secureDelete))
+	if secureDelete != 0 {
+		t.Skip("sqlite built with secure_delete on")
+	}
+	_, err = db.Exec(`CREATE TABLE urls (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		url LONGVARCHAR,
+		title LONGVARCHAR,
+		visit_count INTEGER DEFAULT 0 NOT NULL,
+		last_visit_time INTEGER NOT NULL,
+		hidden INTEGER DEFAULT 0 NOT NULL)`)
+	require.NoError(t, err)
+	for _, stmt := range stmts {
+		_, err := db.Exec(stmt)
+		require.NoError(t, err, stmt)
+	}
+	return path
+}
+
+func insertVisits(from, to int, title func(int) string) []string {
+	var stmts []string
+	for i := from; i < to; i++ {
+		stmts = append(stmts, fmt.Sprintf(
+			"INSERT INTO urls (url, title, visit_count, last_visit_time) VALUES ('https://site%03d.example/', '%s', %d, %d)",
+			i, title(i), i, 13370000000000000+int64(i)))
+	}
+	return stmts
+}
+
+func visitURL(i int) string { return fmt.Sprintf("https://site%03d.example/", i) }
+
+func carveVisits(t *testing.T, path string, wal *WAL) []carvedVisit {
+	t.Helper()
+	live, err := QueryRows(path, false, "SELECT url, title, visit_count FROM urls", func(rows *sql.Rows) (carvedVisit, error) {
+		var v carvedVisit
+		err := rows.Scan(&v.URL, &v.Title, &v.Visits)
+		return v, err
+	})
+	require.NoError(t, err)
+	carved, err := CarveRows(path, false, wal, "urls", live, func(r Record) (carvedVisit, error) {
+		if !strings.HasPrefix(r.Text("url"), "https://") {
+			return carvedVisit{}, fmt.Errorf("not a url: %q", r.Text("url"))
+		}
+		return carvedVisit{URL: r.Text("url"), Title: r.Text("title"), Visits: r.Int("visit_count")}, nil
+	})
+	require.NoError(t, err)
+	var got []carvedVisit
+	for _, c := range carved {
+		got = append(got, c.Item)
+	}
+	return got
+}
+
+func carvedURLs(visits []carvedVisit) []string {
+	var urls []string
+	for _, v := range visits {
+		urls = append(urls, v.URL)
+	}
+	sort.Strings(urls)
+	return urls
+}
+
+func TestTableSchema(t *testing.T) {
+	path := createCarveDB(t)
+	schema, err := TableSchema(path, false, "urls")
+	require.NoError(t, err)
+	assert.NotZero(t, schema.RootPage)
+	require.Len(t, schema.Columns, 6)
+	assert.True(t, schema.Columns[0].RowID)
+	assert.Equal(t, "LONGVARCHAR", schema.Columns[1].Type)
+	assert.True(t, schema.Columns[3].NotNull)
+	assert.True(t, schema.Columns[3].Default)
+	assert.Equal(t, 5, minColumns(schema.Columns), "hidden may be missing from rows older than the column")
+
+	_, err = TableSchema(path, false, "missing")
+	assert.Error(t, err)
+}
+
+func TestCarveRows_Freelist(t *testing.T) {
+	title := func(i int) string { return fmt.Sprintf("Site %d %s", i, strings.Repeat("-", 60)) }
+	stmts := insertVisits(0, 300, title)
+	stmts = append(stmts, "DELETE FROM urls WHERE visit_count >= 100 AND visit_count < 250")
+	path := createCarveDB(t, stmts...)
+
+	got := carveVisits(t, path, nil)
+	// Rebalancing the b-tree after the delete rewrites parts of some pages, so only the deleted rows
+	// whose values are still whole in the file can come back.
+	raw, err := os.ReadFile(path)
+	require.NoError(t, err)
+	var want []string
+	for i := 100; i < 250; i++ {
+		if bytes.Contains(raw, []byte(visitURL(i)+title(i))) {
+			want = append(want, visitURL(i))
+		}
+	}
+	require.Greater(t, len(want), 50)
+	assert.Equal(t, want, carvedURLs(got), "every deleted row left in the file and none of the live ones")
+	for _, v := range got {
+		assert.Equal(t, fmt.Sprintf("https://site%03d.example/", v.Visits), v.URL)
+		assert.Equal(t, title(int(v.Visits)), v.Title)
+	}
+}
+
+func TestCarveRows_Freeblock(t *testing.T) {
+	// Short rows with small rowids: the freeblock header overwrites the cell's size, rowid and
+	// record-header-size bytes, and for the shortest the rowid column's serial type as well.
+	for _, tt := range []struct {
+		name  string
+		title func(int) string
+	}{
+		{"header size overwritten", func(i int) string { return strings.Repeat("t", 100) }},
+		{"rowid type overwritten", func(i int) string { return "t" }},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			stmts := insertVisits(0, 20, tt.title)
+			stmts = append(stmts, "DELETE FROM urls WHERE visit_count IN (3, 11)")
+			path := createCarveDB(t, stmts...)
+
+			got := carveVisits(t, path, nil)
+			assert.Equal(t, []string{visitURL(3), visitURL(11)}, carvedURLs(got))
+			for _, v := range got {
+				assert.Equal(t, tt.title(0), v.Title)
+			}
+		})
+	}
+}
+
+func TestCarveRows_Overflow(t *testing.T) {
+	long := func(i int) string { return strings.Repeat(fmt.Sprintf("%03d", i), 3000) }
+	stmts := insertVisits(0, 12, long)
+	stmts = append(stmts, "DELETE FROM urls WHERE visit_count BETWEEN 2 AND 9")
+	path := createCarveDB(t, stmts...)
+
+	// A freed overflow page that became a freelist trunk has lost the start of its content, so not
+	// every deleted row comes back; those that do come back whole.
+	got := carveVisits(t, path, nil)
+	require.GreaterOrEqual(t, len(got), 3)
+	for _, v := range got {
+		assert.True(t, v.Visits >= 2 && v.Visits <= 9, "only deleted rows: %s", v.URL)
+		assert.Equal(t, visitURL(int(v.Visits)), v.URL)
+		assert.Equal(t, long(int(v.Visits)), v.Title, "title read back across overflow pages")
+	}
+}
+
+func TestCarve_NotSQLite(t *testing.T) {
+	path := createCarveDB(t)
+	schema, err := TableSchema(path, false, "urls")
+	require.NoError(t, err)
+	_, err = Carve(filepath.Join(t.TempDir(), "missing"), schema)
+	assert.Error(t, err)
+	_, err = Carve("carve.go", schema)
+	assert.ErrorContains(t, err, "not a sqlite database")
+}
+
+func TestCarve_Noise(t *testing.T) {
+	path := createCarveDB(t, insertVisits(0, 3, func(int) string { return "t" })...)
+	schema, err := TableSchema(path, false, "urls")
+	require.NoError(t, err)
+	f, err := os.Open(path)
+	require.NoError(t, err)
+	defer f.Close()
+	info, err := f.Stat()
+	require.NoError(t, err)
+	c, err := newCarver(f, info.Size(), &schema)
+	require.NoError(t, err)
+
+	// a text serial type whose size overflows int once the header length is added
+	huge := []byte{14, 0x00, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0x00, 0x01, 0x01}
+	assert.NotPanics(t, func() { c.scan(huge, 0, len(huge)) })
+	// a leaf cell at offset 10 claiming a payload of 2^64-1 bytes
+	cell := []byte{0x0d, 0, 0, 0, 1, 0, 0, 0, 0, 10, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0x01, 0x02}
+	assert.NotPanics(t, func() { c.scanCells(cell, 0, 1) })
+
+	rng := rand.New(rand.NewSource(1))
+	buf := make([]byte, 4096)
+	for i := 0; i < 200; i++ {
+		rng.Read(buf)
+		assert.NotPanics(t, func() {
+			c.scan(buf, 0, len(buf))
+			c.scanFormerLeaf(buf)
+			c.scanCells(buf, 0, 64)
+		})
+	}
+}
+
+func TestVarint(t *testing.T) {
+	for _, tt := range []struct {
+		in   []byte
+		want uint64
+		n    int
+	}{
+		{[]byte{0x05}, 5, 1},
+		{[]byte{0x81, 0x00}, 128, 2},
+		{[]byte{0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff}, 1<<64 - 1, 9},
+		{[]byte{0x81}, 0, 0},
+	} {
+		got, n := varint(tt.in)
+		assert.Equal(t, tt.want, got)
+		assert.Equal(t, tt.n, n)
+	}
+}
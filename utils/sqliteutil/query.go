@@ -49,3 +49,26 @@ func QueryRows[T any](dbPath string, journalOff bool, query string, scanRow func
 	})
 	return items, err
 }
+
+// ScanRecord reads the current row as a Record whose columns are named as the query returned them, so
+// a table whose columns vary across browser versions can be read with SELECT * and the Record
+// accessors, a column the database lacks reading as zero.
+func ScanRecord(rows *sql.Rows) (Record, error) {
+	cols, err := rows.Columns()
+	if err != nil {
+		return Record{}, err
+	}
+	schema := &Schema{Columns: make([]Column, len(cols))}
+	for i, c := range cols {
+		schema.Columns[i].Name = c
+	}
+	values := make([]any, len(cols))
+	dest := make([]any, len(cols))
+	for i := range values {
+		dest[i] = &values[i]
+	}
+	if err := rows.Scan(dest...); err != nil {
+		return Record{}, err
+	}
+	return Record{schema: schema, Values: values}, nil
+}
@@ -209,3 +209,25 @@ func TestQueryRows_Empty(t *testing.T) {
 	require.NoError(t, err)
 	assert.Nil(t, results)
 }
+
+func TestScanRecord(t *testing.T) {
+	dir := t.TempDir()
+	dbPath := filepath.Join(dir, "test.db")
+
+	db, err := sql.Open("sqlite", dbPath)
+	require.NoError(t, err)
+	_, err = db.Exec("CREATE TABLE items (id INTEGER, name TEXT, data BLOB)")
+	require.NoError(t, err)
+	_, err = db.Exec("INSERT INTO items VALUES (7, 'alpha', x'0102'), (8, NULL, NULL)")
+	require.NoError(t, err)
+	require.NoError(t, db.Close())
+
+	records, err := QueryRows(dbPath, false, "SELECT * FROM items ORDER BY id", ScanRecord)
+	require.NoError(t, err)
+	require.Len(t, records, 2)
+	assert.Equal(t, int64(7), records[0].Int("id"))
+	assert.Equal(t, "alpha", records[0].Text("name"))
+	assert.Equal(t, []byte{1, 2}, records[0].Blob("data"))
+	assert.Empty(t, records[1].Text("name"), "NULL")
+	assert.Zero(t, records[0].Int("missing"), "a column the table lacks")
+}
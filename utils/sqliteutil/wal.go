@@ -0,0 +1,214 @@
+package sqliteutil
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"os"
+)
+
+const (
+	walHeaderLen      = 32
+	walFrameHeaderLen = 24
+	walVersion        = 3007000
+)
+
+// WAL is the committed part of a database's write-ahead log, captured together with the database
+// file's own version of each page the log rewrites. SQLite checkpoints the log into the database and
+// deletes it the first time it opens the database, which is also when the file's versions of those
+// pages are overwritten, so ReadWAL has to run before anything queries the database.
+type WAL struct {
+	pageSize int
+	pages    uint32 // the largest database size a commit recorded
+	frames   []walFrame
+	base     map[uint32][]byte
+}
+
+// walFrame is one committed frame: a version of a page and the commit that wrote it.
+type walFrame struct {
+	index  int // 1-based position in the log
+	commit int // 1-based
+	page   uint32
+	data   []byte
+}
+
+// ReadWAL reads the write-ahead log beside the database at dbPath, or returns nil when there is none.
+// Frames are read up to the first one whose salt or checksum does not continue the log; those after
+// the last commit frame belong to a transaction that never committed and are dropped. Frames left
+// over from before the log was last restarted carry an older salt and are not reached.
+func ReadWAL(dbPath string) (*WAL, error) {
+	buf, err := os.ReadFile(dbPath + "-wal")
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("wal file: %w", err)
+	}
+	if len(buf) < walHeaderLen {
+		return nil, nil
+	}
+	w, err := parseWAL(buf)
+	if err != nil || len(w.frames) == 0 {
+		return w, err
+	}
+
+	f, err := os.Open(dbPath)
+	if err != nil {
+		return nil, fmt.Errorf("database file: %w", err)
+	}
+	defer f.Close()
+	w.base = make(map[uint32][]byte)
+	for _, fr := range w.frames {
+		if _, ok := w.base[fr.page]; ok {
+			continue
+		}
+		p := make([]byte, w.pageSize)
+		if _, err := f.ReadAt(p, int64(fr.page-1)*int64(w.pageSize)); err == nil {
+			w.base[fr.page] = p
+		}
+	}
+	return w, nil
+}
+
+// parseWAL validates the log header and collects the committed frames of buf.
+func parseWAL(buf []byte) (*WAL, error) {
+	magic := binary.BigEndian.Uint32(buf)
+	if magic&^1 != 0x377f0682 {
+		return nil, errors.New("not a sqlite wal file")
+	}
+	if v := binary.BigEndian.Uint32(buf[4:]); v != walVersion {
+		return nil, fmt.Errorf("unsupported wal version %d", v)
+	}
+	pageSize := int(binary.BigEndian.Uint32(buf[8:]))
+	if pageSize < 512 || pageSize > 65536 || pageSize&(pageSize-1) != 0 {
+		return nil, fmt.Errorf("invalid wal page size %d", pageSize)
+	}
+	bigEndian := magic&1 == 1
+	s0, s1 := walChecksum(bigEndian, 0, 0, buf[:24])
+	if s0 != binary.BigEndian.Uint32(buf[24:]) || s1 != binary.BigEndian.Uint32(buf[28:]) {
+		return nil, errors.New("wal header checksum mismatch")
+	}
+
+	w := &WAL{pageSize: pageSize}
+	var pending []walFrame
+	commit := 1
+	for off := walHeaderLen; off+walFrameHeaderLen+pageSize <= len(buf); off += walFrameHeaderLen + pageSize {
+		fh := buf[off : off+walFrameHeaderLen]
+		data := buf[off+walFrameHeaderLen : off+walFrameHeaderLen+pageSize]
+		if string(fh[8:16]) != string(buf[16:24]) {
+			break
+		}
+		s0, s1 = walChecksum(bigEndian, s0, s1, fh[:8])
+		s0, s1 = walChecksum(bigEndian, s0, s1, data)
+		if s0 != binary.BigEndian.Uint32(fh[16:]) || s1 != binary.BigEndian.Uint32(fh[20:]) {
+			break
+		}
+		page := binary.BigEndian.Uint32(fh)
+		if page == 0 {
+			break
+		}
+		pending = append(pending, walFrame{
+			index:  (off-walHeaderLen)/(walFrameHeaderLen+pageSize) + 1,
+			commit: commit,
+			page:   page,
+			data:   data,
+		})
+		if dbSize := binary.BigEndian.Uint32(fh[4:]); dbSize != 0 {
+			w.frames = append(w.frames, pending...)
+			pending = pending[:0]
+			if dbSize > w.pages {
+				w.pages = dbSize
+			}
+			commit++
+		}
+	}
+	return w, nil
+}
+
+// walChecksum continues the log's running checksum (s0, s1) over b, read as pairs of 32-bit words in
+// the byte order the log's magic number names.
+func walChecksum(bigEndian bool, s0, s1 uint32, b []byte) (uint32, uint32) {
+	var order binary.ByteOrder = binary.LittleEndian
+	if bigEndian {
+		order = binary.BigEndian
+	}
+	for i := 0; i+8 <= len(b); i += 8 {
+		s0 += order.Uint32(b[i:]) + s1
+		s1 += order.Uint32(b[i+4:]) + s0
+	}
+	return s0, s1
+}
+
+// Carve decodes the rows of schema's table out of every page version the log superseded: the
+// database file's version of each page a commit rewrote, and each committed version a later commit
+// rewrote again. The table's b-tree is walked as it stood before each commit, so only pages that
+// belonged to the table then are decoded; their cells come back whole, rowid included, and their free
+// space is carved as Carve does. Records are returned in commit order. The newest version of each page
+// is what the database now holds and is left to the live query.
+func (w *WAL) Carve(dbPath string, schema Schema) ([]Record, error) {
+	if w == nil || len(w.frames) == 0 || schema.RootPage == 0 {
+		return nil, nil
+	}
+	f, err := os.Open(dbPath)
+	if err != nil {
+		return nil, fmt.Errorf("database file: %w", err)
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	c, err := newCarver(f, info.Size(), &schema)
+	if err != nil {
+		return nil, err
+	}
+	if c.pageSize != w.pageSize {
+		return nil, fmt.Errorf("wal page size %d does not match the database's %d", w.pageSize, c.pageSize)
+	}
+	if w.pages > c.pages {
+		c.pages = w.pages
+	}
+
+	// last holds the final committed frame of each page the log writes, version the frame each page is
+	// read from as the commits are replayed (0 for the database file's version).
+	last := make(map[uint32]int)
+	for _, fr := range w.frames {
+		last[fr.page] = fr.index
+	}
+	version := make(map[uint32]int)
+	// The file holds the newest version of the pages the log wrote; before its first frame, a page
+	// is read as it was before the checkpoint, or not at all when the log added it.
+	c.view = make(map[uint32][]byte, len(last))
+	for n := range last {
+		c.view[n] = w.base[n]
+	}
+	type pageVersion struct {
+		page  uint32
+		frame int
+	}
+	done := make(map[pageVersion]bool)
+	for i := 0; i < len(w.frames); {
+		c.walkTable(schema.RootPage, func(n uint32, p []byte, h, hdrSize, cells int) {
+			v := pageVersion{n, version[n]}
+			if lastFrame, ok := last[n]; !ok || v.frame >= lastFrame || done[v] {
+				return
+			}
+			done[v] = true
+			c.frame, c.commit = v.frame, 0
+			if v.frame > 0 {
+				// the committed frames are a prefix of the log, so a frame's index is its position
+				c.commit = w.frames[v.frame-1].commit
+			}
+			if hdrSize == 8 {
+				c.scanCells(p, h, cells)
+			}
+			c.scanPageFree(p, h, hdrSize, cells)
+		})
+		for commit := w.frames[i].commit; i < len(w.frames) && w.frames[i].commit == commit; i++ {
+			fr := w.frames[i]
+			version[fr.page] = fr.index
+			c.view[fr.page] = fr.data
+		}
+	}
+	return c.records, nil
+}
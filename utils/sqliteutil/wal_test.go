@@ -0,0 +1,149 @@
+package sqliteutil
+
+import (
+	"database/sql"
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// createWALDB writes a urls table and runs stmts in WAL mode, each its own commit, then reads the log
+// while the database is still open; closing it checkpoints the log into the file and deletes it, as
+// the live query does to a copied profile.
+func createWALDB(t *testing.T, stmts ...string) (string, *WAL) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "History")
+	db, err := sql.Open("sqlite", path)
+	require.NoError(t, err)
+	db.SetMaxOpenConns(1)
+	for _, stmt := range append([]string{
+		"PRAGMA journal_mode=WAL",
+		"PRAGMA wal_autocheckpoint=0",
+		`CREATE TABLE urls (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			url LONGVARCHAR,
+			title LONGVARCHAR,
+			visit_count INTEGER DEFAULT 0 NOT NULL,
+			last_visit_time INTEGER NOT NULL,
+			hidden INTEGER DEFAULT 0 NOT NULL)`,
+	}, stmts...) {
+		_, err := db.Exec(stmt)
+		require.NoError(t, err, stmt)
+	}
+	wal, err := ReadWAL(path)
+	require.NoError(t, err)
+	require.NoError(t, db.Close())
+	_, err = os.Stat(path + "-wal")
+	require.True(t, os.IsNotExist(err), "closing the database checkpoints and removes the log")
+	return path, wal
+}
+
+func TestWAL_Carve(t *testing.T) {
+	stmts := insertVisits(0, 20, func(i int) string { return "before" })
+	stmts = append(stmts,
+		"UPDATE urls SET title = 'after' WHERE visit_count = 5",
+		"DELETE FROM urls WHERE visit_count = 7",
+	)
+	path, wal := createWALDB(t, stmts...)
+	require.NotNil(t, wal)
+	assert.NotEmpty(t, wal.frames)
+
+	live, err := QueryRows(path, false, "SELECT url, title, visit_count FROM urls", func(rows *sql.Rows) (carvedVisit, error) {
+		var v carvedVisit
+		err := rows.Scan(&v.URL, &v.Title, &v.Visits)
+		return v, err
+	})
+	require.NoError(t, err)
+	carved, err := CarveRows(path, false, wal, "urls", live, func(r Record) (carvedVisit, error) {
+		return carvedVisit{URL: r.Text("url"), Title: r.Text("title"), Visits: r.Int("visit_count")}, nil
+	})
+	require.NoError(t, err)
+
+	got := make(map[carvedVisit]string)
+	for _, c := range carved {
+		got[c.Item] = c.Origin
+	}
+	assert.Contains(t, got, carvedVisit{visitURL(5), "before", 5}, "the version the update superseded")
+	assert.Contains(t, got, carvedVisit{visitURL(7), "before", 7}, "the deleted row")
+	assert.Len(t, got, 2, "nothing that matches a live row")
+	assert.True(t, strings.HasPrefix(got[carvedVisit{visitURL(5), "before", 5}], "wal commit "))
+}
+
+func TestWAL_CarveRowID(t *testing.T) {
+	path, wal := createWALDB(t,
+		"INSERT INTO urls (url, title, last_visit_time) VALUES ('https://a.example/', 'a', 1)",
+		"DELETE FROM urls",
+	)
+	schema, err := TableSchema(path, false, "urls")
+	require.NoError(t, err)
+	records, err := wal.Carve(path, schema)
+	require.NoError(t, err)
+	require.Len(t, records, 1, "only the version the delete superseded")
+	assert.Equal(t, int64(1), records[0].Value("id"), "a live cell keeps its rowid")
+	assert.Equal(t, "https://a.example/", records[0].Text("url"))
+	assert.Equal(t, 2, records[0].Commit, "written by the insert, the second commit after the schema's")
+	assert.True(t, strings.HasPrefix(records[0].Origin(), "wal commit 2, frame "))
+}
+
+func TestParseWAL(t *testing.T) {
+	const pageSize = 512
+	var s0, s1 uint32
+	hdr := make([]byte, walHeaderLen)
+	binary.BigEndian.PutUint32(hdr, 0x377f0683)
+	binary.BigEndian.PutUint32(hdr[4:], walVersion)
+	binary.BigEndian.PutUint32(hdr[8:], pageSize)
+	copy(hdr[16:24], "saltsalt")
+	s0, s1 = walChecksum(true, 0, 0, hdr[:24])
+	binary.BigEndian.PutUint32(hdr[24:], s0)
+	binary.BigEndian.PutUint32(hdr[28:], s1)
+	buf := hdr
+	frame := func(page, dbSize uint32, salt string) {
+		fh := make([]byte, walFrameHeaderLen)
+		binary.BigEndian.PutUint32(fh, page)
+		binary.BigEndian.PutUint32(fh[4:], dbSize)
+		copy(fh[8:16], salt)
+		data := make([]byte, pageSize)
+		data[0] = byte(page)
+		s0, s1 = walChecksum(true, s0, s1, fh[:8])
+		s0, s1 = walChecksum(true, s0, s1, data)
+		binary.BigEndian.PutUint32(fh[16:], s0)
+		binary.BigEndian.PutUint32(fh[20:], s1)
+		buf = append(append(buf, fh...), data...)
+	}
+	frame(2, 0, "saltsalt")
+	frame(3, 3, "saltsalt") // commit 1
+	frame(2, 3, "saltsalt") // commit 2
+	frame(4, 0, "saltsalt") // never committed
+	frame(5, 5, "oldsalt!")
+
+	w, err := parseWAL(buf)
+	require.NoError(t, err)
+	require.Len(t, w.frames, 3)
+	assert.Equal(t, []int{1, 1, 2}, []int{w.frames[0].commit, w.frames[1].commit, w.frames[2].commit})
+	assert.Equal(t, uint32(2), w.frames[2].page)
+	assert.Equal(t, 3, w.frames[2].index)
+	assert.Equal(t, uint32(3), w.pages)
+
+	buf[walHeaderLen+walFrameHeaderLen+pageSize+walFrameHeaderLen] ^= 0xff // corrupt the second frame
+	w, err = parseWAL(buf)
+	require.NoError(t, err)
+	assert.Empty(t, w.frames, "the log ends at the first frame whose checksum breaks")
+
+	_, err = parseWAL(make([]byte, walHeaderLen))
+	assert.Error(t, err)
+}
+
+func TestReadWAL_None(t *testing.T) {
+	path := createCarveDB(t)
+	wal, err := ReadWAL(path)
+	require.NoError(t, err)
+	assert.Nil(t, wal)
+	records, err := wal.Carve(path, Schema{Table: "urls", RootPage: 2, Columns: []Column{{Name: "url"}}})
+	require.NoError(t, err)
+	assert.Empty(t, records)
+}